@@ -0,0 +1,138 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// AccountIdentity identifies the HVCA account with which a client is
+// authenticated.
+type AccountIdentity struct {
+	// AccountID is the identifier of the account.
+	AccountID string `json:"account_id"`
+
+	// Roles are the roles granted to the API credentials, if reported.
+	Roles []string `json:"roles,omitempty"`
+}
+
+// ErrNoAccountIdentity is returned by AccountIdentity when HVCA did not
+// report the identity of the account.
+var ErrNoAccountIdentity = errors.New("account identity not reported by HVCA")
+
+// AccountIdentity returns the identity of the HVCA account with which the
+// client is authenticated, logging in first if the stored authentication
+// token is believed to have expired. Applications which manage several
+// accounts may use it to check that a client is authenticated with the
+// intended account before issuing certificates.
+//
+// The identity is taken from the account_id and roles fields of the login
+// response if present, or otherwise from claims of the same names in the
+// authentication token if it is a JSON Web Token, the account_id claim
+// falling back to the sub claim. The claims are not verified, since the
+// token was received from HVCA over an authenticated connection.
+// ErrNoAccountIdentity is returned if HVCA reported no account identifier by
+// either means.
+func (c *Client) AccountIdentity(ctx context.Context) (*AccountIdentity, error) {
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+
+	if err := c.loginIfTokenHasExpired(ctx); err != nil {
+		return nil, err
+	}
+
+	c.tokenMtx.RLock()
+	defer c.tokenMtx.RUnlock()
+
+	if c.identity == nil {
+		return nil, ErrNoAccountIdentity
+	}
+
+	return &AccountIdentity{
+		AccountID: c.identity.AccountID,
+		Roles:     append([]string(nil), c.identity.Roles...),
+	}, nil
+}
+
+// HasRole returns true if the specified role is among the roles granted to
+// the API credentials.
+func (id *AccountIdentity) HasRole(role string) bool {
+	for _, r := range id.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// accountIdentity returns the account identity reported in a login
+// response, or nil if none was reported.
+func (r loginResponse) accountIdentity() *AccountIdentity {
+	if r.AccountID != "" {
+		return &AccountIdentity{AccountID: r.AccountID, Roles: r.Roles}
+	}
+
+	return tokenAccountIdentity(r.AccessToken)
+}
+
+// tokenAccountIdentity returns the account identity in the unverified claims
+// of an authentication token which is a JSON Web Token, or nil if the token
+// is not a JSON Web Token or contains no account identifier.
+func tokenAccountIdentity(token string) *AccountIdentity {
+	var parts = strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	var data, err = base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil
+	}
+
+	var claims struct {
+		AccountID string   `json:"account_id"`
+		Subject   string   `json:"sub"`
+		Roles     []string `json:"roles"`
+	}
+
+	if err = json.Unmarshal(data, &claims); err != nil {
+		return nil
+	}
+
+	if claims.AccountID == "" {
+		claims.AccountID = claims.Subject
+	}
+
+	if claims.AccountID == "" {
+		return nil
+	}
+
+	return &AccountIdentity{AccountID: claims.AccountID, Roles: claims.Roles}
+}
+
+// identitySet sets the stored account identity.
+func (c *Client) identitySet(id *AccountIdentity) {
+	c.tokenMtx.Lock()
+	defer c.tokenMtx.Unlock()
+
+	c.identity = id
+}
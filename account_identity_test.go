@@ -0,0 +1,141 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+	"github.com/google/go-cmp/cmp"
+)
+
+// makeTestJWT returns an unsigned JSON Web Token with the specified claims.
+func makeTestJWT(claims string) string {
+	var enc = base64.RawURLEncoding
+
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString([]byte(claims)) + "."
+}
+
+func TestClientAccountIdentity(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		login string
+		want  *hvclient.AccountIdentity
+		err   error
+	}{
+		{
+			name:  "LoginResponse",
+			login: `{"access_token":"mock_token","account_id":"acct-1","roles":["issuer","auditor"]}`,
+			want:  &hvclient.AccountIdentity{AccountID: "acct-1", Roles: []string{"issuer", "auditor"}},
+		},
+		{
+			name:  "TokenClaims",
+			login: fmt.Sprintf(`{"access_token":%q}`, makeTestJWT(`{"account_id":"acct-2","roles":["issuer"]}`)),
+			want:  &hvclient.AccountIdentity{AccountID: "acct-2", Roles: []string{"issuer"}},
+		},
+		{
+			name:  "TokenSubject",
+			login: fmt.Sprintf(`{"access_token":%q}`, makeTestJWT(`{"sub":"acct-3"}`)),
+			want:  &hvclient.AccountIdentity{AccountID: "acct-3"},
+		},
+		{
+			name:  "NotReported",
+			login: `{"access_token":"mock_token"}`,
+			err:   hvclient.ErrNoAccountIdentity,
+		},
+		{
+			name:  "TokenWithoutAccount",
+			login: fmt.Sprintf(`{"access_token":%q}`, makeTestJWT(`{"exp":1600000000}`)),
+			err:   hvclient.ErrNoAccountIdentity,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mock = hvcatest.NewHandler()
+			var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/login" {
+					mock.ServeHTTP(w, r)
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, tc.login)
+			}))
+			defer server.Close()
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+			defer cancel()
+
+			var clnt, err = hvclient.NewClient(ctx, hvcatest.Config(server.URL))
+			if err != nil {
+				t.Fatalf("couldn't create client: %v", err)
+			}
+			defer clnt.Close()
+
+			var got *hvclient.AccountIdentity
+			got, err = clnt.AccountIdentity(ctx)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v, want %v", err, tc.err)
+			}
+
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewClientFromTokenAccountIdentity(t *testing.T) {
+	t.Parallel()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var clnt, err = hvclient.NewClientFromToken(ctx, "https://example.com/v2",
+		makeTestJWT(`{"account_id":"acct-1","roles":["issuer"]}`))
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+	defer clnt.Close()
+
+	var got *hvclient.AccountIdentity
+	if got, err = clnt.AccountIdentity(ctx); err != nil {
+		t.Fatalf("couldn't get account identity: %v", err)
+	}
+
+	if got.AccountID != "acct-1" {
+		t.Errorf("got account ID %q, want %q", got.AccountID, "acct-1")
+	}
+
+	if !got.HasRole("issuer") || got.HasRole("auditor") {
+		t.Errorf("got roles %q, want only issuer", got.Roles)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"sync"
+)
+
+// AccountInfo is a summary of the state of an HVCA account, suitable for
+// display on a dashboard.
+type AccountInfo struct {
+	CertsIssued   int64         `json:"certs_issued"`
+	CertsRevoked  int64         `json:"certs_revoked"`
+	IssuanceQuota int64         `json:"issuance_quota"`
+	Policy        PolicySummary `json:"policy"`
+
+	// TrustChain contains the SHA-256 fingerprints of the certificates in
+	// the account's chain of trust, in the order returned by HVCA.
+	TrustChain []Fingerprint `json:"trust_chain"`
+}
+
+// PolicySummary contains the parts of a validation policy which are most
+// commonly of interest when monitoring an account.
+type PolicySummary struct {
+	Validity           *ValidityPolicy  `json:"validity,omitempty"`
+	PublicKey          *PublicKeyPolicy `json:"public_key,omitempty"`
+	PublicKeySignature Presence         `json:"public_key_signature"`
+}
+
+// AccountInfo returns the issued and revoked certificate counts, remaining
+// issuance quota, a summary of the validation policy, and the trust chain
+// fingerprints for the calling account. The underlying requests are made
+// concurrently, and the first error encountered, if any, is returned.
+func (c *Client) AccountInfo(ctx context.Context) (*AccountInfo, error) {
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+
+	var info AccountInfo
+	var pol *Policy
+	var chain []*x509.Certificate
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+
+	// fetch runs f concurrently, recording the first error encountered
+	// and cancelling the remaining requests.
+	var fetch = func(name string, f func() error) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := f(); err != nil {
+				errOnce.Do(func() {
+					firstErr = fmt.Errorf("couldn't retrieve %s: %w", name, err)
+					cancel()
+				})
+			}
+		}()
+	}
+
+	fetch("issued count", func() (err error) {
+		info.CertsIssued, err = c.CounterCertsIssued(ctx)
+		return err
+	})
+
+	fetch("revoked count", func() (err error) {
+		info.CertsRevoked, err = c.CounterCertsRevoked(ctx)
+		return err
+	})
+
+	fetch("issuance quota", func() (err error) {
+		info.IssuanceQuota, err = c.QuotaIssuance(ctx)
+		return err
+	})
+
+	fetch("validation policy", func() (err error) {
+		pol, err = c.Policy(ctx)
+		return err
+	})
+
+	fetch("trust chain", func() (err error) {
+		chain, err = c.TrustChain(ctx)
+		return err
+	})
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	info.Policy = PolicySummary{
+		Validity:           pol.Validity,
+		PublicKey:          pol.PublicKey,
+		PublicKeySignature: pol.PublicKeySignature,
+	}
+
+	info.TrustChain = make([]Fingerprint, 0, len(chain))
+	for _, cert := range chain {
+		var sum = sha256.Sum256(cert.Raw)
+		info.TrustChain = append(info.TrustChain, Fingerprint(sum[:]))
+	}
+
+	return &info, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClientMockAccountInfo(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var got, err = client.AccountInfo(ctx)
+	if err != nil {
+		t.Fatalf("failed to get account information: %v", err)
+	}
+
+	if got.CertsIssued != mockCounterIssued {
+		t.Errorf("got issued count %d, want %d", got.CertsIssued, mockCounterIssued)
+	}
+
+	if got.CertsRevoked != mockCounterRevoked {
+		t.Errorf("got revoked count %d, want %d", got.CertsRevoked, mockCounterRevoked)
+	}
+
+	if got.IssuanceQuota != mockQuotaIssuance {
+		t.Errorf("got issuance quota %d, want %d", got.IssuanceQuota, mockQuotaIssuance)
+	}
+
+	if !cmp.Equal(got.Policy.PublicKey, mockPolicy.PublicKey) {
+		t.Errorf("got public key policy %v, want %v", got.Policy.PublicKey, mockPolicy.PublicKey)
+	}
+
+	if len(got.TrustChain) != len(mockTrustChainCerts) {
+		t.Fatalf("got %d trust chain fingerprints, want %d", len(got.TrustChain), len(mockTrustChainCerts))
+	}
+
+	for i, cert := range mockTrustChainCerts {
+		var sum = sha256.Sum256(cert.Raw)
+		if !got.TrustChain[i].Equal(sum[:]) {
+			t.Errorf("got fingerprint %v for certificate %d, want %v", got.TrustChain[i], i, hvclient.Fingerprint(sum[:]))
+		}
+	}
+}
+
+func TestAccountInfoFailure(t *testing.T) {
+	t.Parallel()
+
+	var handler = hvcatest.NewHandler()
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/quotas/issuance" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var client, err = hvclient.NewClient(ctx, hvcatest.Config(server.URL))
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+
+	if _, err = client.AccountInfo(ctx); err == nil {
+		t.Fatalf("unexpectedly got account information")
+	}
+
+	var apiErr hvclient.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("got error %v, want API error with status %d", err, http.StatusBadRequest)
+	}
+}
@@ -17,51 +17,297 @@ package hvclient
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"path"
+	"strings"
 
 	"github.com/globalsign/hvclient/internal/httputils"
 )
 
-// APIError is an error returned by the HVCA HTTP API.
+// APIError is an error returned by the HVCA HTTP API. APIError matches
+// ErrUnauthorized, ErrNotFound, ErrPolicyViolation and ErrQuotaExceeded
+// using errors.Is, so callers need not compare status codes.
 type APIError struct {
 	StatusCode  int
 	Description string
+
+	// RequestID is the request ID sent in the X-Request-ID header with the
+	// failed call, if any.
+	RequestID string
+
+	// Type, Title, Detail and Instance are the RFC 7807 problem details
+	// members of the response body, if present. Type is a URI identifying
+	// the kind of problem, which often locates documentation on how to
+	// resolve it, and Instance identifies this occurrence of the problem.
+	Type     string
+	Title    string
+	Detail   string
+	Instance string
+}
+
+// ResponseError is returned when a successful response from an HVCA API
+// endpoint cannot be processed, for example because the body is too large
+// or cannot be decoded.
+type ResponseError struct {
+	Method    string
+	Path      string
+	RequestID string
+	Err       error
+}
+
+// ErrResponseTooLarge is wrapped by a ResponseError when an HVCA response
+// body exceeds the maximum size allowed by the client configuration.
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// ErrUnauthorized is matched, using errors.Is, by an APIError returned when
+// HVCA rejects the account credentials or does not permit the operation.
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrNotFound is matched, using errors.Is, by an APIError returned when the
+// requested certificate, domain claim or other resource does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrPolicyViolation is matched, using errors.Is, by an APIError returned
+// when HVCA rejects a request which does not conform to the validation
+// policy.
+var ErrPolicyViolation = errors.New("request violates validation policy")
+
+// problemTypes maps the final path segment of known problem types to the
+// errors they match. Problems with other types are matched by status code.
+var problemTypes = map[string]error{
+	"unauthorized":     ErrUnauthorized,
+	"forbidden":        ErrUnauthorized,
+	"not-found":        ErrNotFound,
+	"policy-violation": ErrPolicyViolation,
+	"validation-error": ErrPolicyViolation,
 }
 
+// problemStatuses maps status codes to the errors they match when the
+// problem type is not known.
+var problemStatuses = map[int]error{
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusForbidden:           ErrUnauthorized,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusUnprocessableEntity: ErrPolicyViolation,
+}
+
+// ErrQuotaExceeded is matched, using errors.Is, by errors returned when HVCA
+// rejects a certificate request because the account's issuance quota is
+// exhausted. Errors returned by CertificateRequest and related methods in
+// this case are of type *QuotaError.
+var ErrQuotaExceeded = errors.New("issuance quota exceeded")
+
+// QuotaError is returned when HVCA rejects a certificate request because
+// the account's issuance quota is exhausted.
+type QuotaError struct {
+	// Remaining is the remaining issuance quota retrieved from HVCA after
+	// the request was rejected, or -1 if it could not be retrieved.
+	Remaining int64
+
+	// Err is the error returned by HVCA, usually an APIError.
+	Err error
+}
+
+// ErrNotSupported is matched, using errors.Is, by errors returned by methods
+// which use an optional HVCA endpoint, such as revocation with a reason,
+// HTTP and email domain validation and timestamping, when the account or a
+// gateway in front of HVCA does not provide that endpoint.
+// Errors returned in this case are of type *NotSupportedError.
+var ErrNotSupported = errors.New("operation not supported")
+
+// NotSupportedError is returned when an optional HVCA endpoint is not
+// available to the account.
+type NotSupportedError struct {
+	// Operation describes the operation which is not supported.
+	Operation string
+
+	// Err is the error returned by HVCA, usually an APIError.
+	Err error
+}
+
+// unknownAPIError is the description of an APIError created from a
+// response which does not contain HVCA problem details.
+const unknownAPIError = "unknown API error"
+
+// maxAPIErrorSize is the maximum number of bytes of an HVCA error response
+// body which will be read.
+const maxAPIErrorSize = 64 * 1024
+
 // hvcaError is the format of an HVCA error HTTP response body.
 type hvcaError struct {
 	Description string `json:"description"`
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Detail      string `json:"detail"`
+	Instance    string `json:"instance"`
 }
 
 // Error returns a string representation of the error.
 func (e APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("%d: %s (request ID %s)", e.StatusCode, e.Description, e.RequestID)
+	}
+
 	return fmt.Sprintf("%d: %s", e.StatusCode, e.Description)
 }
 
+// Is reports whether the error indicates the account's issuance quota is
+// exhausted, when target is ErrQuotaExceeded, or whether the problem type or,
+// failing that, the status code of the error corresponds to target, when
+// target is ErrUnauthorized, ErrNotFound or ErrPolicyViolation.
+func (e APIError) Is(target error) bool {
+	if target == ErrQuotaExceeded {
+		return e.quotaExceeded()
+	}
+
+	return target != nil && e.problem() == target
+}
+
+// problem returns the error corresponding to the problem type of the error,
+// or to its status code if the type is absent or not known.
+func (e APIError) problem() error {
+	var name = strings.ToLower(path.Base(strings.TrimRight(e.Type, "/")))
+	if err, ok := problemTypes[name]; ok {
+		return err
+	}
+
+	return problemStatuses[e.StatusCode]
+}
+
+// quotaExceeded reports whether the status code, problem type or
+// description of the error indicate the account's issuance quota is
+// exhausted.
+func (e APIError) quotaExceeded() bool {
+	if e.StatusCode == http.StatusPaymentRequired {
+		return true
+	}
+
+	return e.StatusCode >= 400 && e.StatusCode <= 499 &&
+		(strings.Contains(strings.ToLower(e.Type), "quota") ||
+			strings.Contains(strings.ToLower(e.Description), "quota"))
+}
+
+// endpointMissing reports whether the error indicates that the requested
+// endpoint does not exist, as opposed to the resource it addresses. HVCA
+// always returns problem details for resources which do not exist, so a 404
+// response without them is taken to come from a server or gateway which
+// does not route the endpoint at all.
+func (e APIError) endpointMissing() bool {
+	switch e.StatusCode {
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+
+	case http.StatusNotFound:
+		return e.Description == unknownAPIError && e.Type == "" && e.Title == "" && e.Detail == ""
+	}
+
+	return false
+}
+
+// Error returns a string representation of the error.
+func (e *QuotaError) Error() string {
+	if e.Remaining < 0 {
+		return fmt.Sprintf("%v: %v", ErrQuotaExceeded, e.Err)
+	}
+
+	return fmt.Sprintf("%v (remaining quota %d): %v", ErrQuotaExceeded, e.Remaining, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *QuotaError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrQuotaExceeded.
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// Error returns a string representation of the error.
+func (e *NotSupportedError) Error() string {
+	return fmt.Sprintf("%s: %v: %v", e.Operation, ErrNotSupported, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *NotSupportedError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrNotSupported.
+func (e *NotSupportedError) Is(target error) bool {
+	return target == ErrNotSupported
+}
+
+// notSupported returns a *NotSupportedError wrapping err if it indicates
+// that the endpoint for the operation does not exist, or err otherwise.
+func notSupported(operation string, err error) error {
+	var apiErr APIError
+	if errors.As(err, &apiErr) && apiErr.endpointMissing() {
+		return &NotSupportedError{Operation: operation, Err: err}
+	}
+
+	return err
+}
+
+// Error returns a string representation of the error.
+func (e *ResponseError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("invalid response from %s %s (request ID %s): %v", e.Method, e.Path, e.RequestID, e.Err)
+	}
+
+	return fmt.Sprintf("invalid response from %s %s: %v", e.Method, e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
 // newAPIError creates a new APIError object from an HTTP response.
 func newAPIError(r *http.Response) APIError {
 	// All HVCA error response bodies have a problem+json content type, so
 	// return a generic error if that's not the content type we have.
 	var err = httputils.VerifyResponseContentType(r, httputils.ContentTypeProblemJSON)
 	if err != nil {
-		return APIError{StatusCode: r.StatusCode, Description: "unknown API error"}
+		return APIError{StatusCode: r.StatusCode, Description: unknownAPIError, RequestID: requestIDOf(r)}
 	}
 
 	// Read and unmarshal the response body. Return a generic error on
 	// any failure.
 	var data []byte
-	data, err = ioutil.ReadAll(r.Body)
+	data, err = ioutil.ReadAll(io.LimitReader(r.Body, maxAPIErrorSize))
 	if err != nil {
-		return APIError{StatusCode: r.StatusCode, Description: "unknown API error"}
+		return APIError{StatusCode: r.StatusCode, Description: unknownAPIError, RequestID: requestIDOf(r)}
 	}
 
 	var hvErr hvcaError
 	err = json.Unmarshal(data, &hvErr)
 	if err != nil {
-		return APIError{StatusCode: r.StatusCode, Description: "unknown API error"}
+		return APIError{StatusCode: r.StatusCode, Description: unknownAPIError, RequestID: requestIDOf(r)}
 	}
 
-	return APIError{StatusCode: r.StatusCode, Description: hvErr.Description}
+	// Fall back to the standard problem details members for a description
+	// if HVCA didn't provide one.
+	var description = hvErr.Description
+	if description == "" {
+		description = hvErr.Detail
+	}
+
+	if description == "" {
+		description = hvErr.Title
+	}
+
+	return APIError{
+		StatusCode:  r.StatusCode,
+		Description: description,
+		RequestID:   requestIDOf(r),
+		Type:        hvErr.Type,
+		Title:       hvErr.Title,
+		Detail:      hvErr.Detail,
+		Instance:    hvErr.Instance,
+	}
 }
@@ -55,6 +55,40 @@ func TestAPIError(t *testing.T) {
 				Description: "custom message",
 			},
 		},
+		{
+			name: "ProblemDetails",
+			in: &http.Response{
+				Body: ioutil.NopCloser(strings.NewReader(`{"type":"https://example.com/problems/policy-violation",` +
+					`"title":"Policy violation","detail":"subject country not allowed","instance":"/certificates/1234"}`)),
+				Header: http.Header{
+					httputils.ContentTypeHeader: []string{httputils.ContentTypeProblemJSON},
+				},
+				StatusCode: http.StatusUnprocessableEntity,
+			},
+			want: APIError{
+				StatusCode:  http.StatusUnprocessableEntity,
+				Description: "subject country not allowed",
+				Type:        "https://example.com/problems/policy-violation",
+				Title:       "Policy violation",
+				Detail:      "subject country not allowed",
+				Instance:    "/certificates/1234",
+			},
+		},
+		{
+			name: "TitleOnly",
+			in: &http.Response{
+				Body: ioutil.NopCloser(strings.NewReader(`{"title":"Not Found"}`)),
+				Header: http.Header{
+					httputils.ContentTypeHeader: []string{httputils.ContentTypeProblemJSON},
+				},
+				StatusCode: http.StatusNotFound,
+			},
+			want: APIError{
+				StatusCode:  http.StatusNotFound,
+				Description: "Not Found",
+				Title:       "Not Found",
+			},
+		},
 		{
 			name: "BadContentType",
 			in: &http.Response{
@@ -113,6 +147,75 @@ func TestAPIError(t *testing.T) {
 	}
 }
 
+func TestAPIErrorIs(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		in   APIError
+		want error
+	}{
+		{
+			name: "Unauthorized",
+			in:   APIError{StatusCode: http.StatusUnauthorized},
+			want: ErrUnauthorized,
+		},
+		{
+			name: "Forbidden",
+			in:   APIError{StatusCode: http.StatusForbidden},
+			want: ErrUnauthorized,
+		},
+		{
+			name: "NotFound",
+			in:   APIError{StatusCode: http.StatusNotFound},
+			want: ErrNotFound,
+		},
+		{
+			name: "PolicyViolation",
+			in:   APIError{StatusCode: http.StatusUnprocessableEntity},
+			want: ErrPolicyViolation,
+		},
+		{
+			name: "TypeOverridesStatus",
+			in:   APIError{StatusCode: http.StatusBadRequest, Type: "https://example.com/problems/Policy-Violation/"},
+			want: ErrPolicyViolation,
+		},
+		{
+			name: "UnknownTypeUsesStatus",
+			in:   APIError{StatusCode: http.StatusNotFound, Type: "https://example.com/problems/gone-fishing"},
+			want: ErrNotFound,
+		},
+		{
+			name: "AboutBlank",
+			in:   APIError{StatusCode: http.StatusUnauthorized, Type: "about:blank"},
+			want: ErrUnauthorized,
+		},
+		{
+			name: "QuotaExceeded",
+			in:   APIError{StatusCode: http.StatusPaymentRequired},
+			want: ErrQuotaExceeded,
+		},
+		{
+			name: "Other",
+			in:   APIError{StatusCode: http.StatusBadRequest},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, target := range []error{ErrUnauthorized, ErrNotFound, ErrPolicyViolation, ErrQuotaExceeded} {
+				if got := errors.Is(tc.in, target); got != (target == tc.want) {
+					t.Errorf("got errors.Is(%v) %t, want %t", target, got, target == tc.want)
+				}
+			}
+		})
+	}
+}
+
 func TestAPIErrorString(t *testing.T) {
 	t.Parallel()
 
@@ -129,6 +232,15 @@ func TestAPIErrorString(t *testing.T) {
 			},
 			want: "400: custom message",
 		},
+		{
+			name: "RequestID",
+			in: APIError{
+				StatusCode:  http.StatusBadRequest,
+				Description: "custom message",
+				RequestID:   "abc-123",
+			},
+			want: "400: custom message (request ID abc-123)",
+		},
 	}
 
 	for _, tc := range testcases {
@@ -16,12 +16,18 @@ limitations under the License.
 package hvclient
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"math/big"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // headerFromResponse retrieves the value of a header from an HTTP response. If there
@@ -47,6 +53,109 @@ func basePathHeaderFromResponse(r *http.Response, name string) (string, error) {
 	return filepath.Base(location), nil
 }
 
+// ErrInvalidPathSegment is wrapped by errors returned by API methods when a
+// claim ID, domain or serial number cannot safely be included in the path of
+// an HVCA API request.
+var ErrInvalidPathSegment = errors.New("invalid path segment")
+
+// endpointPath returns the path of an API endpoint beneath the specified
+// base endpoint, with each of the specified segments escaped with
+// url.PathEscape and appended in turn. An error wrapping
+// ErrInvalidPathSegment is returned if any segment is empty, is "." or "..",
+// or contains a slash, backslash or control character, since such segments
+// may be interpreted as a different path by HVCA or an intermediate proxy
+// even when escaped.
+func endpointPath(endpoint string, segments ...string) (string, error) {
+	var b strings.Builder
+	b.WriteString(endpoint)
+
+	for _, segment := range segments {
+		if err := checkPathSegment(segment); err != nil {
+			return "", err
+		}
+
+		b.WriteString("/")
+		b.WriteString(url.PathEscape(segment))
+	}
+
+	return b.String(), nil
+}
+
+// checkPathSegment returns an error wrapping ErrInvalidPathSegment if a path
+// segment cannot safely be included in a request path.
+func checkPathSegment(segment string) error {
+	switch segment {
+	case "":
+		return fmt.Errorf("%w: empty value", ErrInvalidPathSegment)
+
+	case ".", "..":
+		return fmt.Errorf("%w: %q", ErrInvalidPathSegment, segment)
+	}
+
+	for _, r := range segment {
+		if r == '/' || r == '\\' || unicode.IsControl(r) {
+			return fmt.Errorf("%w: %q contains %q", ErrInvalidPathSegment, segment, r)
+		}
+	}
+
+	return nil
+}
+
+// certificatePath returns the path of the certificate endpoint for the
+// specified serial number.
+func certificatePath(serial *big.Int) (string, error) {
+	if serial == nil || serial.Sign() < 0 {
+		return "", fmt.Errorf("%w: invalid serial number %v", ErrInvalidPathSegment, serial)
+	}
+
+	return endpointPath(endpointCertificates, fmt.Sprintf("%X", serial))
+}
+
+// endpointURL returns the full URL for an API endpoint path, which may
+// include a query string, by appending it to the HVCA URL in the client
+// configuration. Any base path in the HVCA URL is preserved.
+func (c *Client) endpointURL(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return strings.TrimSuffix(c.url.String(), "/") + path
+}
+
+// relativePath returns the path and query of a URL relative to the HVCA URL
+// in the client configuration, suitable for passing to makeRequest. A
+// relative URL is first resolved against the HVCA URL. An error is returned
+// if the URL is not located beneath the HVCA URL.
+func (c *Client) relativePath(u *url.URL) (string, error) {
+	if u == nil {
+		return "", errors.New("no URL provided")
+	}
+
+	var base = *c.url
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	var resolved = base.ResolveReference(u)
+
+	if !strings.EqualFold(resolved.Scheme, c.url.Scheme) || !strings.EqualFold(resolved.Host, c.url.Host) {
+		return "", fmt.Errorf("URL %s is not on the HVCA server", u)
+	}
+
+	var basePath = strings.TrimSuffix(c.url.EscapedPath(), "/")
+	var resolvedPath = resolved.EscapedPath()
+	if !strings.HasPrefix(resolvedPath, basePath+"/") {
+		return "", fmt.Errorf("URL %s is not beneath the HVCA URL", u)
+	}
+
+	var rel = strings.TrimPrefix(resolvedPath, basePath)
+	if resolved.RawQuery != "" {
+		rel += "?" + resolved.RawQuery
+	}
+
+	return rel, nil
+}
+
 // intHeaderFromResponse retrieves the integer value of a header from an HTTP
 // response. If there is more than one header value, only the first is
 // returned.
@@ -88,3 +197,19 @@ func paginationString(
 
 	return builder.String()
 }
+
+// gzipBytes returns the gzip compression of a byte slice.
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w = gzip.NewWriter(&buf)
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -16,8 +16,11 @@ limitations under the License.
 package hvclient
 
 import (
+	"context"
 	"errors"
+	"math/big"
 	"net/http"
+	"net/url"
 	"testing"
 	"time"
 )
@@ -255,3 +258,223 @@ func TestPaginationString(t *testing.T) {
 		})
 	}
 }
+
+func TestClientRelativePath(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		base string
+		in   string
+		want string
+		err  error
+	}{
+		{
+			name: "Absolute",
+			base: "https://emea.api.hvca.globalsign.com:8443/v2",
+			in:   "https://emea.api.hvca.globalsign.com:8443/v2/certificates/ABCD",
+			want: "/certificates/ABCD",
+		},
+		{
+			name: "AbsolutePath",
+			base: "https://emea.api.hvca.globalsign.com:8443/v2/",
+			in:   "/v2/certificates/ABCD?x=1",
+			want: "/certificates/ABCD?x=1",
+		},
+		{
+			name: "RelativePath",
+			base: "https://emea.api.hvca.globalsign.com:8443/v2",
+			in:   "certificates/ABCD",
+			want: "/certificates/ABCD",
+		},
+		{
+			name: "NoBasePath",
+			base: "http://127.0.0.1:5500",
+			in:   "http://127.0.0.1:5500/certificates/ABCD",
+			want: "/certificates/ABCD",
+		},
+		{
+			name: "OtherHost",
+			base: "https://emea.api.hvca.globalsign.com:8443/v2",
+			in:   "https://example.com:8443/v2/certificates/ABCD",
+			err:  errors.New("other host"),
+		},
+		{
+			name: "OtherScheme",
+			base: "https://emea.api.hvca.globalsign.com:8443/v2",
+			in:   "http://emea.api.hvca.globalsign.com:8443/v2/certificates/ABCD",
+			err:  errors.New("other scheme"),
+		},
+		{
+			name: "OutsideBasePath",
+			base: "https://emea.api.hvca.globalsign.com:8443/v2",
+			in:   "/v3/certificates/ABCD",
+			err:  errors.New("outside base path"),
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var base, err = url.Parse(tc.base)
+			if err != nil {
+				t.Fatalf("couldn't parse base URL: %v", err)
+			}
+
+			var in *url.URL
+			if in, err = url.Parse(tc.in); err != nil {
+				t.Fatalf("couldn't parse URL: %v", err)
+			}
+
+			var got string
+			got, err = (&Client{url: base}).relativePath(in)
+			if (err == nil) != (tc.err == nil) {
+				t.Fatalf("got error %v, want %v", err, tc.err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEndpointPath(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name     string
+		segments []string
+		want     string
+	}{
+		{
+			name: "None",
+			want: endpointClaimsDomains,
+		},
+		{
+			name:     "Plain",
+			segments: []string{"01ED3ZHNVDY1MS5CWKBSH6A3YC"},
+			want:     "/claims/domains/01ED3ZHNVDY1MS5CWKBSH6A3YC",
+		},
+		{
+			name:     "Reserved",
+			segments: []string{"a b?c#d%e"},
+			want:     "/claims/domains/a%20b%3Fc%23d%25e",
+		},
+		{
+			name:     "Unicode",
+			segments: []string{"bücher.example"},
+			want:     "/claims/domains/b%C3%BCcher.example",
+		},
+		{
+			name:     "Multiple",
+			segments: []string{"abc", "def"},
+			want:     "/claims/domains/abc/def",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = endpointPath(endpointClaimsDomains, tc.segments...)
+			if err != nil {
+				t.Fatalf("couldn't build path: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			// The path must survive being parsed as part of a URL.
+			var u *url.URL
+			if u, err = url.Parse("https://example.com/v2" + got); err != nil {
+				t.Fatalf("couldn't parse URL: %v", err)
+			}
+
+			if u.EscapedPath() != "/v2"+tc.want {
+				t.Errorf("got escaped path %q, want %q", u.EscapedPath(), "/v2"+tc.want)
+			}
+		})
+	}
+}
+
+func TestEndpointPathFailure(t *testing.T) {
+	t.Parallel()
+
+	for _, segment := range []string{"", ".", "..", "../certificates", "a/b", "/", `a\b`, "a\x00b", "a\nb", "a\u0085b"} {
+		if got, err := endpointPath(endpointClaimsDomains, "ok", segment); !errors.Is(err, ErrInvalidPathSegment) {
+			t.Errorf("%q: got path %q and error %v, want %v", segment, got, err, ErrInvalidPathSegment)
+		}
+	}
+}
+
+func TestCertificatePath(t *testing.T) {
+	t.Parallel()
+
+	var got, err = certificatePath(big.NewInt(0x741daf9ec2d5f7dc))
+	if err != nil {
+		t.Fatalf("couldn't build path: %v", err)
+	}
+
+	if want := "/certificates/741DAF9EC2D5F7DC"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, serial := range []*big.Int{nil, big.NewInt(-1)} {
+		if _, err = certificatePath(serial); !errors.Is(err, ErrInvalidPathSegment) {
+			t.Errorf("%v: got error %v, want %v", serial, err, ErrInvalidPathSegment)
+		}
+	}
+}
+
+func TestClientInvalidPathSegment(t *testing.T) {
+	t.Parallel()
+
+	// The client has no HTTP client, so any request which is made will
+	// panic rather than being rejected.
+	var clnt = &Client{config: &Config{}}
+	var ctx = context.Background()
+
+	for name, call := range map[string]func() error{
+		"ClaimRetrieve": func() error {
+			var _, err = clnt.ClaimRetrieve(ctx, "../../certificates/ABCD")
+			return err
+		},
+		"ClaimDelete": func() error {
+			return clnt.ClaimDelete(ctx, "")
+		},
+		"ClaimSubmit": func() error {
+			var _, err = clnt.ClaimSubmit(ctx, "example.com/evil")
+			return err
+		},
+		"ClaimDNS": func() error {
+			var _, err = clnt.ClaimDNS(ctx, "..", "")
+			return err
+		},
+		"ClaimEmailRetrieve": func() error {
+			var _, err = clnt.ClaimEmailRetrieve(ctx, "a/b")
+			return err
+		},
+		"ClaimReassert": func() error {
+			var _, err = clnt.ClaimReassert(ctx, ".")
+			return err
+		},
+		"CertificateRetrieve": func() error {
+			var _, err = clnt.CertificateRetrieve(ctx, nil)
+			return err
+		},
+		"CertificateRevoke": func() error {
+			return clnt.CertificateRevoke(ctx, big.NewInt(-1))
+		},
+	} {
+		if err := call(); !errors.Is(err, ErrInvalidPathSegment) {
+			t.Errorf("%s: got error %v, want %v", name, err, ErrInvalidPathSegment)
+		}
+	}
+}
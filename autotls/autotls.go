@@ -0,0 +1,355 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autotls
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/fileutils"
+	"github.com/globalsign/hvclient/internal/pki"
+	"github.com/globalsign/hvclient/watch"
+)
+
+// RequestFunc returns the certificate request with which to obtain a
+// certificate for the specified server names and private key.
+type RequestFunc func(names []string, key crypto.Signer) (*hvclient.Request, error)
+
+// Config is a configuration object for a certificate manager.
+type Config struct {
+	// Client is used to request certificates from HVCA, and is usually an
+	// *hvclient.Client. It is required.
+	Client watch.Requester
+
+	// Names are the DNS names for which certificates are obtained. At least
+	// one name is required. TLS connections for other server names are
+	// rejected.
+	Names []string
+
+	// Request builds the certificate request. If this is omitted, a request
+	// is built with the first name as the subject common name, all names as
+	// SAN DNS names, the maximum validity period allowed by the validation
+	// policy, and the private key for proof of possession.
+	Request RequestFunc
+
+	// Key is the private key for the certificates. If this is omitted, the
+	// key is read from the cache directory if one was previously stored
+	// there, or else a new ECDSA P-256 key is generated.
+	Key crypto.Signer
+
+	// CacheDir, if not empty, is a directory in which the private key and
+	// the current certificate are stored, so they can be reused after a
+	// restart. The directory is created if necessary.
+	CacheDir string
+
+	// RenewFraction is the fraction of a certificate's lifetime after which
+	// a replacement is obtained, and must be between 0 and 1. If this is
+	// omitted or set to zero, a default of two thirds is used.
+	RenewFraction float64
+
+	// Clock, if not nil, is used as the source of the current time when
+	// deciding whether to renew a certificate and when building the default
+	// certificate request, in place of the system clock.
+	Clock hvclient.Clock
+}
+
+// Manager obtains and renews TLS server certificates from HVCA. A manager is
+// safe for concurrent use.
+type Manager struct {
+	config Config
+	mtx    sync.Mutex
+	cert   *tls.Certificate
+}
+
+// Cache file names.
+const (
+	cacheKeyFile  = "key.pem"
+	cacheCertFile = "cert.pem"
+	cacheLockFile = ".lock"
+)
+
+// defaultRenewFraction is the fraction of a certificate's lifetime after
+// which it is renewed if none is specified in the configuration.
+var defaultRenewFraction = 2.0 / 3.0
+
+// New returns a new certificate manager. No certificate is requested until
+// the first call to GetCertificate.
+func New(conf Config) (*Manager, error) {
+	if conf.Client == nil {
+		return nil, errors.New("no client provided")
+	}
+
+	if len(conf.Names) == 0 {
+		return nil, errors.New("no server names provided")
+	}
+
+	if conf.Clock == nil {
+		conf.Clock = hvclient.SystemClock
+	}
+
+	if conf.Request == nil {
+		conf.Request = defaultRequest(conf.Clock)
+	}
+
+	if conf.RenewFraction == 0 {
+		conf.RenewFraction = defaultRenewFraction
+	} else if conf.RenewFraction < 0 || conf.RenewFraction > 1 {
+		return nil, fmt.Errorf("renewal fraction %v is not between 0 and 1", conf.RenewFraction)
+	}
+
+	if conf.Key == nil {
+		var err error
+		if conf.Key, err = loadOrGenerateKey(conf.CacheDir); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Manager{config: conf}, nil
+}
+
+// TLSConfig returns a TLS configuration which obtains its certificates from
+// the manager.
+func (m *Manager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: m.GetCertificate,
+	}
+}
+
+// GetCertificate returns a certificate for a TLS handshake, and is suitable
+// for assigning to the GetCertificate field of a tls.Config. A certificate
+// is requested from HVCA on first use, unless a current certificate is held
+// in the cache directory, and is replaced once the configured fraction of
+// its lifetime has elapsed. If a replacement cannot be obtained, the current
+// certificate continues to be used until it expires.
+func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if hello.ServerName != "" && !m.allowed(hello.ServerName) {
+		return nil, fmt.Errorf("server name %q not configured", hello.ServerName)
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.cert == nil && m.config.CacheDir != "" {
+		if cert, err := m.loadCert(); err == nil {
+			m.cert = cert
+		}
+	}
+
+	var now = m.config.Clock.Now()
+
+	if m.cert != nil && now.Before(watch.RenewalDeadline(m.cert.Leaf, m.config.RenewFraction)) {
+		return m.cert, nil
+	}
+
+	var cert, err = m.obtain(hello)
+	if err != nil {
+		if m.cert != nil && now.Before(m.cert.Leaf.NotAfter) {
+			return m.cert, nil
+		}
+
+		return nil, err
+	}
+
+	m.cert = cert
+
+	return m.cert, nil
+}
+
+// allowed reports whether a server name is one of the configured names.
+func (m *Manager) allowed(name string) bool {
+	for _, allowed := range m.config.Names {
+		if strings.EqualFold(strings.TrimSuffix(name, "."), allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// obtain requests a new certificate from HVCA and stores it in the cache
+// directory, if one is configured.
+func (m *Manager) obtain(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	var req, err = m.config.Request(m.config.Names, m.config.Key)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't build certificate request: %w", err)
+	}
+
+	// The context is only set for hellos received during a handshake.
+	var ctx = hello.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var serial *big.Int
+	if serial, err = m.config.Client.CertificateRequest(ctx, req); err != nil {
+		return nil, fmt.Errorf("couldn't request certificate: %w", err)
+	}
+
+	var info *hvclient.CertInfo
+	if info, err = m.config.Client.CertificateRetrieve(ctx, serial); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve certificate %X: %w", serial, err)
+	}
+
+	if info.X509 == nil {
+		return nil, fmt.Errorf("no certificate returned for serial number %X", serial)
+	}
+
+	if m.config.CacheDir != "" {
+		var lock *fileutils.Lock
+		if lock, err = lockCacheDir(m.config.CacheDir); err != nil {
+			return nil, err
+		}
+
+		err = writeCacheFile(m.config.CacheDir, cacheCertFile, []byte(info.PEM))
+		lock.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{info.X509.Raw},
+		PrivateKey:  m.config.Key,
+		Leaf:        info.X509,
+	}, nil
+}
+
+// loadCert reads the certificate from the cache directory, and checks that
+// it matches the private key.
+func (m *Manager) loadCert() (*tls.Certificate, error) {
+	var cert, err = pki.CertFromFile(filepath.Join(m.config.CacheDir, cacheCertFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var pub, ok = cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(m.config.Key.Public()) {
+		return nil, errors.New("cached certificate does not match private key")
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{cert.Raw},
+		PrivateKey:  m.config.Key,
+		Leaf:        cert,
+	}, nil
+}
+
+// defaultRequest returns a RequestFunc which builds a certificate request
+// with the first name as the subject common name and all names as SAN DNS
+// names, valid from the current time according to the specified clock.
+func defaultRequest(clock hvclient.Clock) RequestFunc {
+	return func(names []string, key crypto.Signer) (*hvclient.Request, error) {
+		return &hvclient.Request{
+			Validity: &hvclient.Validity{
+				NotBefore: clock.Now(),
+				NotAfter:  time.Unix(0, 0),
+			},
+			Subject:    &hvclient.DN{CommonName: names[0]},
+			SAN:        &hvclient.SAN{DNSNames: names},
+			PrivateKey: key,
+		}, nil
+	}
+}
+
+// loadOrGenerateKey reads the private key from the cache directory, if one
+// is configured and contains a key, or otherwise generates a new key and
+// stores it in the cache directory, if one is configured. The cache
+// directory is locked throughout, so that managers in different processes
+// sharing it do not each generate a different key.
+func loadOrGenerateKey(dir string) (crypto.Signer, error) {
+	if dir != "" {
+		var lock, err = lockCacheDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		defer lock.Unlock()
+
+		var path = filepath.Join(dir, cacheKeyFile)
+
+		if _, err := os.Stat(path); err == nil {
+			var key, err = pki.PrivateKeyFromFileWithPassword(path, "")
+			if err != nil {
+				return nil, fmt.Errorf("couldn't read cached private key: %w", err)
+			}
+
+			var signer, ok = key.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("unsupported cached private key type %T", key)
+			}
+
+			return signer, nil
+		}
+	}
+
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't generate private key: %w", err)
+	}
+
+	if dir == "" {
+		return key, nil
+	}
+
+	var der []byte
+	if der, err = x509.MarshalPKCS8PrivateKey(key); err != nil {
+		return nil, fmt.Errorf("couldn't marshal private key: %w", err)
+	}
+
+	var data = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = writeCacheFile(dir, cacheKeyFile, data); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// lockCacheDir creates the cache directory if necessary, and acquires an
+// advisory lock on it. The caller must hold the lock while writing to the
+// directory.
+func lockCacheDir(dir string) (*fileutils.Lock, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("couldn't create cache directory: %w", err)
+	}
+
+	return fileutils.LockFile(filepath.Join(dir, cacheLockFile))
+}
+
+// writeCacheFile writes a file to the cache directory. The file is written
+// to a temporary file which is then renamed, so a partially-written file is
+// never observed.
+func writeCacheFile(dir, name string, data []byte) error {
+	if err := fileutils.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+		return fmt.Errorf("couldn't write cache file: %w", err)
+	}
+
+	return nil
+}
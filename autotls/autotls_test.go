@@ -0,0 +1,270 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autotls_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/autotls"
+)
+
+// fakeCA is a watch.Requester which issues certificates for the public key
+// in each request, with a fixed lifetime.
+type fakeCA struct {
+	t        *testing.T
+	mtx      sync.Mutex
+	key      *ecdsa.PrivateKey
+	lifetime time.Duration
+	age      time.Duration
+	issued   map[string]*hvclient.CertInfo
+	requests int
+	err      error
+}
+
+func newFakeCA(t *testing.T, lifetime, age time.Duration) *fakeCA {
+	t.Helper()
+
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("couldn't generate key: %v", err)
+	}
+
+	return &fakeCA{
+		t:        t,
+		key:      key,
+		lifetime: lifetime,
+		age:      age,
+		issued:   make(map[string]*hvclient.CertInfo),
+	}
+}
+
+func (f *fakeCA) CertificateRequest(_ context.Context, req *hvclient.Request) (*big.Int, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	f.requests++
+
+	var serial = big.NewInt(int64(f.requests))
+	var notBefore = time.Now().Add(-f.age)
+
+	var tmpl = &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: req.Subject.CommonName},
+		DNSNames:     req.SAN.DNSNames,
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(f.lifetime),
+	}
+
+	var der, err = x509.CreateCertificate(rand.Reader, tmpl, tmpl, req.PrivateKey.(crypto.Signer).Public(), f.key)
+	if err != nil {
+		f.t.Fatalf("couldn't create certificate: %v", err)
+	}
+
+	var cert *x509.Certificate
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		f.t.Fatalf("couldn't parse certificate: %v", err)
+	}
+
+	f.issued[serial.String()] = &hvclient.CertInfo{
+		PEM:    string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		X509:   cert,
+		Status: hvclient.StatusIssued,
+	}
+
+	return serial, nil
+}
+
+func (f *fakeCA) CertificateRetrieve(_ context.Context, serial *big.Int) (*hvclient.CertInfo, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	var info, ok = f.issued[serial.String()]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+
+	return info, nil
+}
+
+func TestManagerGetCertificate(t *testing.T) {
+	t.Parallel()
+
+	var ca = newFakeCA(t, time.Hour*90, 0)
+
+	var m, err = autotls.New(autotls.Config{
+		Client: ca,
+		Names:  []string{"www.example.com", "example.com"},
+	})
+	if err != nil {
+		t.Fatalf("couldn't create manager: %v", err)
+	}
+
+	var cert *tls.Certificate
+	for _, name := range []string{"www.example.com", "EXAMPLE.COM.", ""} {
+		if cert, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: name}); err != nil {
+			t.Fatalf("couldn't get certificate for %q: %v", name, err)
+		}
+	}
+
+	if ca.requests != 1 {
+		t.Errorf("got %d requests, want 1", ca.requests)
+	}
+
+	if err = cert.Leaf.VerifyHostname("example.com"); err != nil {
+		t.Errorf("certificate not valid for host name: %v", err)
+	}
+
+	if _, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: "other.example.com"}); err == nil {
+		t.Errorf("unexpectedly got certificate for unconfigured name")
+	}
+
+	if m.TLSConfig().GetCertificate == nil {
+		t.Errorf("no GetCertificate function in TLS configuration")
+	}
+}
+
+func TestManagerRenewal(t *testing.T) {
+	t.Parallel()
+
+	// Certificates are issued already past their renewal deadline, so each
+	// call should obtain a new one.
+	var ca = newFakeCA(t, time.Hour*90, time.Hour*80)
+
+	var m, err = autotls.New(autotls.Config{
+		Client: ca,
+		Names:  []string{"www.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("couldn't create manager: %v", err)
+	}
+
+	var first *tls.Certificate
+	if first, err = m.GetCertificate(&tls.ClientHelloInfo{}); err != nil {
+		t.Fatalf("couldn't get certificate: %v", err)
+	}
+
+	var second *tls.Certificate
+	if second, err = m.GetCertificate(&tls.ClientHelloInfo{}); err != nil {
+		t.Fatalf("couldn't get certificate: %v", err)
+	}
+
+	if ca.requests != 2 || first.Leaf.SerialNumber.Cmp(second.Leaf.SerialNumber) == 0 {
+		t.Fatalf("got %d requests, want certificate to be renewed", ca.requests)
+	}
+
+	// If renewal fails, the current certificate should still be used while
+	// it remains valid.
+	ca.err = errors.New("service unavailable")
+
+	var third *tls.Certificate
+	if third, err = m.GetCertificate(&tls.ClientHelloInfo{}); err != nil {
+		t.Fatalf("couldn't get certificate: %v", err)
+	}
+
+	if third != second {
+		t.Errorf("current certificate not used after failed renewal")
+	}
+}
+
+func TestManagerCacheDir(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var ca = newFakeCA(t, time.Hour*90, 0)
+
+	var first, err = autotls.New(autotls.Config{
+		Client:   ca,
+		Names:    []string{"www.example.com"},
+		CacheDir: dir,
+	})
+	if err != nil {
+		t.Fatalf("couldn't create manager: %v", err)
+	}
+
+	var want *tls.Certificate
+	if want, err = first.GetCertificate(&tls.ClientHelloInfo{}); err != nil {
+		t.Fatalf("couldn't get certificate: %v", err)
+	}
+
+	// A second manager using the same cache directory should reuse both the
+	// key and the certificate.
+	var second *autotls.Manager
+	if second, err = autotls.New(autotls.Config{
+		Client:   ca,
+		Names:    []string{"www.example.com"},
+		CacheDir: dir,
+	}); err != nil {
+		t.Fatalf("couldn't create manager: %v", err)
+	}
+
+	var got *tls.Certificate
+	if got, err = second.GetCertificate(&tls.ClientHelloInfo{}); err != nil {
+		t.Fatalf("couldn't get certificate: %v", err)
+	}
+
+	if ca.requests != 1 {
+		t.Errorf("got %d requests, want 1", ca.requests)
+	}
+
+	if !got.Leaf.Equal(want.Leaf) {
+		t.Errorf("cached certificate not used")
+	}
+}
+
+func TestNewFailure(t *testing.T) {
+	t.Parallel()
+
+	var ca = newFakeCA(t, time.Hour, 0)
+
+	var testcases = []struct {
+		name string
+		conf autotls.Config
+	}{
+		{"NoClient", autotls.Config{Names: []string{"www.example.com"}}},
+		{"NoNames", autotls.Config{Client: ca}},
+		{"BadFraction", autotls.Config{Client: ca, Names: []string{"www.example.com"}, RenewFraction: 2}},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := autotls.New(tc.conf); err == nil {
+				t.Fatalf("unexpectedly created manager")
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package autotls provides TLS server certificates which are obtained from HVCA
+on first use and renewed automatically as they near expiry, in a similar
+manner to golang.org/x/crypto/acme/autocert.
+
+A Manager is created for a fixed set of server names, and its
+GetCertificate method is assigned to the GetCertificate field of a
+tls.Config. Certificates and the private key may optionally be cached on
+disk so they survive restarts. Cached files are replaced atomically while
+holding an advisory lock on the cache directory, so managers in more than one
+process may share it.
+*/
+package autotls
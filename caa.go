@@ -0,0 +1,484 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ErrCAANotAuthorized is matched, using errors.Is, by errors returned when
+// the CAA records for a DNS name do not authorize GlobalSign to issue
+// certificates for it.
+var ErrCAANotAuthorized = errors.New("CAA records do not authorize issuance")
+
+// defaultCAAIdentifiers are the issuer domain names by which GlobalSign is
+// identified in the issue and issuewild properties of CAA records, used if
+// none are configured.
+var defaultCAAIdentifiers = []string{"globalsign.com"}
+
+// CAARecord is a DNS Certification Authority Authorization resource record,
+// as described in RFC 8659.
+type CAARecord struct {
+	Flags uint8
+	Tag   string
+	Value string
+}
+
+// CAAError is returned when the CAA records for a DNS name do not authorize
+// issuance by any of the specified issuers.
+type CAAError struct {
+	// DNSName is the DNS name for which issuance is not authorized.
+	DNSName string
+
+	// Domain is the domain name at which the relevant CAA records were
+	// found, which is either DNSName or one of its parent domains.
+	Domain string
+
+	// Records are the relevant CAA records.
+	Records []CAARecord
+}
+
+// typeCAA is the DNS resource record type for CAA records.
+const typeCAA = dnsmessage.Type(257)
+
+// caaCriticalFlag is the issuer critical flag in a CAA record.
+const caaCriticalFlag = 128
+
+// caaKnownTags are the CAA property tags which do not prevent issuance when
+// marked as critical, because their meaning is understood.
+var caaKnownTags = []string{"issue", "issuewild", "iodef", "issuemail", "contactemail", "contactphone"}
+
+// resolvConfPath is the path of the resolver configuration file from which
+// the DNS servers used by LookupCAA are read if none are specified.
+var resolvConfPath = "/etc/resolv.conf"
+
+// dnsTimeout is the time allowed for each DNS server to respond if the
+// context has no earlier deadline.
+var dnsTimeout = time.Second * 5
+
+// lookupCAA is the function used to look up CAA records. It is a variable
+// so that it may be replaced in tests.
+var lookupCAA = LookupCAA
+
+// Critical reports whether the issuer critical flag is set.
+func (r CAARecord) Critical() bool {
+	return r.Flags&caaCriticalFlag != 0
+}
+
+// String returns a string representation of the record in the DNS zone file
+// presentation format.
+func (r CAARecord) String() string {
+	return fmt.Sprintf("%d %s %q", r.Flags, r.Tag, r.Value)
+}
+
+// CheckCAA checks that the CAA records for each of the specified DNS names
+// authorize issuance by one of the issuer domain names in the client's
+// CAAIdentifiers, following the procedure in RFC 8659. The records are
+// looked up with LookupCAA using the client's DNSServers. DNS names
+// containing Unicode characters are converted to A-labels before they are
+// looked up. The relevant records for a name are those at the closest of
+// the name and its parent domains which has any CAA records, and issuance
+// is authorized if there are no such records. The issuewild property is
+// used for wildcard names if present, and the issue property otherwise.
+//
+// A *CAAError is returned for the first name for which issuance is not
+// authorized, and an error wrapping ErrInvalidDNSName for a name which
+// cannot be converted to A-labels. Any other error is returned if the CAA
+// records could not be retrieved.
+func (c *Client) CheckCAA(ctx context.Context, dnsNames []string) error {
+	var lookup = func(ctx context.Context, name string) ([]CAARecord, error) {
+		return lookupCAA(ctx, c.config.DNSServers, name)
+	}
+
+	return checkCAA(ctx, lookup, dnsNames, c.config.CAAIdentifiers)
+}
+
+// checkCAA checks the CAA records for each of the DNS names using the
+// specified lookup function.
+func checkCAA(
+	ctx context.Context,
+	lookup func(context.Context, string) ([]CAARecord, error),
+	dnsNames []string,
+	identifiers []string,
+) error {
+	if len(identifiers) == 0 {
+		identifiers = defaultCAAIdentifiers
+	}
+
+	for _, name := range dnsNames {
+		var wildcard = strings.HasPrefix(name, "*.")
+
+		var domain, err = dnsNameProfile.ToASCII(strings.TrimSuffix(strings.TrimPrefix(name, "*."), "."))
+		if err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidDNSName, name, err)
+		}
+
+		var records []CAARecord
+		for ; domain != ""; domain = parentDomain(domain) {
+			if records, err = lookup(ctx, domain); err != nil {
+				return fmt.Errorf("couldn't look up CAA records for %s: %w", domain, err)
+			}
+
+			if len(records) > 0 {
+				break
+			}
+		}
+
+		if !caaAuthorizes(records, wildcard, identifiers) {
+			return &CAAError{DNSName: name, Domain: domain, Records: records}
+		}
+	}
+
+	return nil
+}
+
+// parentDomain returns the parent of a domain name, or the empty string if
+// the domain name has a single label.
+func parentDomain(domain string) string {
+	if i := strings.IndexByte(domain, '.'); i != -1 {
+		return domain[i+1:]
+	}
+
+	return ""
+}
+
+// caaAuthorizes reports whether a set of CAA records authorizes issuance by
+// any of the specified issuers.
+func caaAuthorizes(records []CAARecord, wildcard bool, identifiers []string) bool {
+	var issue, issuewild []CAARecord
+
+	for _, record := range records {
+		switch tag := strings.ToLower(record.Tag); tag {
+		case "issue":
+			issue = append(issue, record)
+
+		case "issuewild":
+			issuewild = append(issuewild, record)
+
+		default:
+			if record.Critical() && !containsFold(caaKnownTags, tag) {
+				return false
+			}
+		}
+	}
+
+	var relevant = issue
+	if wildcard && len(issuewild) > 0 {
+		relevant = issuewild
+	}
+
+	if len(relevant) == 0 {
+		return true
+	}
+
+	for _, record := range relevant {
+		var issuer = record.Value
+		if i := strings.IndexByte(issuer, ';'); i != -1 {
+			issuer = issuer[:i]
+		}
+
+		if issuer = strings.TrimSpace(issuer); issuer != "" && containsFold(identifiers, issuer) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsFold reports whether a list contains a string, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LookupCAA returns the CAA records at the specified domain name, without
+// following the domain name to its parents. An empty list is returned if
+// the domain name has no CAA records or does not exist. The query is sent
+// to the specified DNS servers in turn until one responds, and is retried
+// over TCP if the UDP response is truncated. A port number may be included
+// in each server address, and defaults to 53. If no servers are specified,
+// the nameservers in the system resolver configuration file,
+// /etc/resolv.conf, are used, and an error is returned if it has none or
+// does not exist, as on Windows.
+func LookupCAA(ctx context.Context, dnsServers []string, name string) ([]CAARecord, error) {
+	var servers, err = resolverServers(dnsServers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, server := range servers {
+		var records []CAARecord
+		if records, err = lookupCAAServer(ctx, server, name); err == nil {
+			return records, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, err
+}
+
+// resolverServers returns the addresses of the specified DNS servers, with
+// the default port added where none is included, or, if none are specified,
+// of the DNS servers in the system resolver configuration file.
+func resolverServers(dnsServers []string) ([]string, error) {
+	var servers []string
+
+	if len(dnsServers) > 0 {
+		for _, server := range dnsServers {
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				server = net.JoinHostPort(server, "53")
+			}
+
+			servers = append(servers, server)
+		}
+
+		return servers, nil
+	}
+
+	var f, err = os.Open(resolvConfPath)
+	if err != nil {
+		return nil, fmt.Errorf("no DNS servers configured and couldn't read %s: %w", resolvConfPath, err)
+	}
+	defer f.Close()
+
+	var scanner = bufio.NewScanner(f)
+	for scanner.Scan() {
+		var fields = strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("couldn't read DNS servers: %w", err)
+	}
+
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no DNS servers configured in %s", resolvConfPath)
+	}
+
+	return servers, nil
+}
+
+// lookupCAAServer queries a single DNS server for the CAA records at the
+// specified domain name.
+func lookupCAAServer(ctx context.Context, server, name string) ([]CAARecord, error) {
+	var qname, err = dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain name %q: %w", name, err)
+	}
+
+	var id [2]byte
+	if _, err = rand.Read(id[:]); err != nil {
+		return nil, fmt.Errorf("couldn't generate query ID: %w", err)
+	}
+
+	var query = dnsmessage.Message{
+		Header: dnsmessage.Header{ID: binary.BigEndian.Uint16(id[:]), RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: qname, Type: typeCAA, Class: dnsmessage.ClassINET},
+		},
+	}
+
+	var packed []byte
+	if packed, err = query.Pack(); err != nil {
+		return nil, fmt.Errorf("couldn't build DNS query: %w", err)
+	}
+
+	var resp []byte
+	if resp, err = exchangeDNS(ctx, "udp", server, packed); err != nil {
+		return nil, err
+	}
+
+	var records []CAARecord
+	var truncated bool
+	if records, truncated, err = parseCAAResponse(resp, query.Header.ID); err != nil || !truncated {
+		return records, err
+	}
+
+	if resp, err = exchangeDNS(ctx, "tcp", server, packed); err != nil {
+		return nil, err
+	}
+
+	records, _, err = parseCAAResponse(resp, query.Header.ID)
+
+	return records, err
+}
+
+// exchangeDNS sends a DNS query to a server over the specified network and
+// returns the response. Messages sent over TCP are prefixed with their
+// length, as required by RFC 1035.
+func exchangeDNS(ctx context.Context, network, server string, query []byte) ([]byte, error) {
+	var dialer net.Dialer
+	var conn, err = dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't connect to DNS server: %w", err)
+	}
+	defer conn.Close()
+
+	var deadline, ok = ctx.Deadline()
+	if !ok || time.Until(deadline) > dnsTimeout {
+		deadline = time.Now().Add(dnsTimeout)
+	}
+
+	if err = conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if network == "tcp" {
+		var msg = make([]byte, 2+len(query))
+		binary.BigEndian.PutUint16(msg, uint16(len(query)))
+		copy(msg[2:], query)
+
+		if _, err = conn.Write(msg); err != nil {
+			return nil, fmt.Errorf("couldn't send DNS query: %w", err)
+		}
+
+		var length [2]byte
+		if _, err = io.ReadFull(conn, length[:]); err != nil {
+			return nil, fmt.Errorf("couldn't read DNS response: %w", err)
+		}
+
+		var resp = make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err = io.ReadFull(conn, resp); err != nil {
+			return nil, fmt.Errorf("couldn't read DNS response: %w", err)
+		}
+
+		return resp, nil
+	}
+
+	if _, err = conn.Write(query); err != nil {
+		return nil, fmt.Errorf("couldn't send DNS query: %w", err)
+	}
+
+	var resp = make([]byte, 65535)
+	var n int
+	if n, err = conn.Read(resp); err != nil {
+		return nil, fmt.Errorf("couldn't read DNS response: %w", err)
+	}
+
+	return resp[:n], nil
+}
+
+// parseCAAResponse parses the CAA records from a DNS response, and reports
+// whether the response was truncated.
+func parseCAAResponse(resp []byte, id uint16) ([]CAARecord, bool, error) {
+	var p dnsmessage.Parser
+	var header, err = p.Start(resp)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid DNS response: %w", err)
+	}
+
+	switch {
+	case !header.Response || header.ID != id:
+		return nil, false, errors.New("DNS response does not match query")
+
+	case header.Truncated:
+		return nil, true, nil
+
+	case header.RCode == dnsmessage.RCodeNameError:
+		return nil, false, nil
+
+	case header.RCode != dnsmessage.RCodeSuccess:
+		return nil, false, fmt.Errorf("DNS server returned %v", header.RCode)
+	}
+
+	if err = p.SkipAllQuestions(); err != nil {
+		return nil, false, fmt.Errorf("invalid DNS response: %w", err)
+	}
+
+	var records []CAARecord
+	for {
+		var rh dnsmessage.ResourceHeader
+		if rh, err = p.AnswerHeader(); errors.Is(err, dnsmessage.ErrSectionDone) {
+			break
+		} else if err != nil {
+			return nil, false, fmt.Errorf("invalid DNS response: %w", err)
+		}
+
+		// Answers may include CNAME records leading to the CAA records.
+		if rh.Type != typeCAA {
+			if err = p.SkipAnswer(); err != nil {
+				return nil, false, fmt.Errorf("invalid DNS response: %w", err)
+			}
+
+			continue
+		}
+
+		var res dnsmessage.UnknownResource
+		if res, err = p.UnknownResource(); err != nil {
+			return nil, false, fmt.Errorf("invalid DNS response: %w", err)
+		}
+
+		var record CAARecord
+		if record, err = parseCAARecord(res.Data); err != nil {
+			return nil, false, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, false, nil
+}
+
+// parseCAARecord parses the RDATA of a CAA record, which consists of a flags
+// octet, a tag length octet, the tag, and the value.
+func parseCAARecord(data []byte) (CAARecord, error) {
+	if len(data) < 2 || int(data[1]) == 0 || len(data) < 2+int(data[1]) {
+		return CAARecord{}, errors.New("invalid CAA record")
+	}
+
+	return CAARecord{
+		Flags: data[0],
+		Tag:   string(data[2 : 2+int(data[1])]),
+		Value: string(data[2+int(data[1]):]),
+	}, nil
+}
+
+// Error returns a string representation of the error.
+func (e *CAAError) Error() string {
+	var records = make([]string, 0, len(e.Records))
+	for _, record := range e.Records {
+		records = append(records, record.String())
+	}
+
+	return fmt.Sprintf("%v for %s: records at %s are %s", ErrCAANotAuthorized, e.DNSName, e.Domain, strings.Join(records, ", "))
+}
+
+// Is reports whether target is ErrCAANotAuthorized.
+func (e *CAAError) Is(target error) bool {
+	return target == ErrCAANotAuthorized
+}
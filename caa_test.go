@@ -0,0 +1,421 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeCAALookup returns a CAA lookup function which returns the records in
+// the specified map.
+func fakeCAALookup(zone map[string][]CAARecord) func(context.Context, string) ([]CAARecord, error) {
+	return func(_ context.Context, name string) ([]CAARecord, error) {
+		if records, ok := zone[name]; ok && records == nil {
+			return nil, errors.New("SERVFAIL")
+		}
+
+		return zone[name], nil
+	}
+}
+
+func TestCheckCAA(t *testing.T) {
+	t.Parallel()
+
+	var zone = map[string][]CAARecord{
+		"example.com": {
+			{Tag: "issue", Value: "globalsign.com"},
+			{Tag: "issuewild", Value: ";"},
+			{Tag: "iodef", Value: "mailto:security@example.com"},
+		},
+		"other.example.com": {
+			{Tag: "issue", Value: "ca.example.net; account=1234"},
+		},
+		"params.example.com": {
+			{Tag: "issue", Value: " GlobalSign.com ; validationmethods=dns-01"},
+		},
+		"critical.example.com": {
+			{Tag: "issue", Value: "globalsign.com"},
+			{Flags: 128, Tag: "tbs", Value: "unknown"},
+		},
+		"noncritical.example.com": {
+			{Tag: "issue", Value: "globalsign.com"},
+			{Tag: "tbs", Value: "unknown"},
+		},
+		"iodefonly.example.org": {
+			{Tag: "iodef", Value: "mailto:security@example.org"},
+		},
+		"wild.example.org": {
+			{Tag: "issue", Value: "ca.example.net"},
+			{Tag: "issuewild", Value: "globalsign.com"},
+		},
+		"xn--bcher-kva.example": {
+			{Tag: "issue", Value: "ca.example.net"},
+		},
+		"fail.example.org": nil,
+	}
+
+	var testcases = []struct {
+		name        string
+		dnsNames    []string
+		identifiers []string
+		wantDomain  string
+		wantErr     error
+	}{
+		{
+			name:     "Authorized",
+			dnsNames: []string{"example.com", "WWW.Example.COM."},
+		},
+		{
+			name:     "NoRecords",
+			dnsNames: []string{"www.example.net"},
+		},
+		{
+			name:     "NoIssueProperty",
+			dnsNames: []string{"www.iodefonly.example.org"},
+		},
+		{
+			name:     "Parameters",
+			dnsNames: []string{"params.example.com"},
+		},
+		{
+			name:     "NonCriticalUnknownTag",
+			dnsNames: []string{"noncritical.example.com"},
+		},
+		{
+			name:     "Wildcard",
+			dnsNames: []string{"*.wild.example.org"},
+		},
+		{
+			name:        "OtherIdentifier",
+			dnsNames:    []string{"www.other.example.com"},
+			identifiers: []string{"ca.example.net"},
+		},
+		{
+			name:       "OtherIssuer",
+			dnsNames:   []string{"example.com", "www.other.example.com"},
+			wantDomain: "other.example.com",
+			wantErr:    ErrCAANotAuthorized,
+		},
+		{
+			name:       "WildcardForbidden",
+			dnsNames:   []string{"*.example.com"},
+			wantDomain: "example.com",
+			wantErr:    ErrCAANotAuthorized,
+		},
+		{
+			name:       "WildcardIssueOnly",
+			dnsNames:   []string{"wild.example.org"},
+			wantDomain: "wild.example.org",
+			wantErr:    ErrCAANotAuthorized,
+		},
+		{
+			name:       "InternationalizedName",
+			dnsNames:   []string{"www.Bücher.example"},
+			wantDomain: "xn--bcher-kva.example",
+			wantErr:    ErrCAANotAuthorized,
+		},
+		{
+			name:       "CriticalUnknownTag",
+			dnsNames:   []string{"critical.example.com"},
+			wantDomain: "critical.example.com",
+			wantErr:    ErrCAANotAuthorized,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var err = checkCAA(context.Background(), fakeCAALookup(zone), tc.dnsNames, tc.identifiers)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tc.wantErr)
+			}
+
+			if tc.wantErr == nil {
+				return
+			}
+
+			var caaErr *CAAError
+			if !errors.As(err, &caaErr) {
+				t.Fatalf("got error of type %T, want %T", err, caaErr)
+			}
+
+			if caaErr.Domain != tc.wantDomain {
+				t.Errorf("got domain %q, want %q", caaErr.Domain, tc.wantDomain)
+			}
+		})
+	}
+
+	var err = checkCAA(context.Background(), fakeCAALookup(zone), []string{"www.fail.example.org"}, nil)
+	if err == nil || errors.Is(err, ErrCAANotAuthorized) {
+		t.Errorf("got error %v, want lookup failure", err)
+	}
+
+	err = checkCAA(context.Background(), fakeCAALookup(zone), []string{strings.Repeat("ü", 64) + ".example"}, nil)
+	if !errors.Is(err, ErrInvalidDNSName) {
+		t.Errorf("got error %v, want %v", err, ErrInvalidDNSName)
+	}
+}
+
+func TestParseCAARecord(t *testing.T) {
+	t.Parallel()
+
+	var got, err = parseCAARecord([]byte("\x80\x05issueglobalsign.com"))
+	if err != nil {
+		t.Fatalf("couldn't parse CAA record: %v", err)
+	}
+
+	if want := (CAARecord{Flags: 128, Tag: "issue", Value: "globalsign.com"}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, data := range [][]byte{nil, {0}, {0, 0}, []byte("\x00\x09issue")} {
+		if _, err := parseCAARecord(data); err == nil {
+			t.Errorf("unexpectedly parsed CAA record %q", data)
+		}
+	}
+}
+
+// newCAAServer starts a UDP DNS server which answers CAA queries from the
+// specified zone, returning NXDOMAIN for names not in the zone, and returns
+// its address.
+func newCAAServer(t *testing.T, zone map[string][]CAARecord) string {
+	t.Helper()
+
+	var conn, err = net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("couldn't listen: %v", err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		var buf = make([]byte, 512)
+		for {
+			var n, addr, err = conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var query dnsmessage.Message
+			if err = query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+
+			var q = query.Questions[0]
+			var resp = dnsmessage.Message{
+				Header: dnsmessage.Header{
+					ID:       query.Header.ID,
+					Response: true,
+					RCode:    dnsmessage.RCodeSuccess,
+				},
+				Questions: query.Questions,
+			}
+
+			var records, ok = zone[q.Name.String()]
+			if !ok {
+				resp.Header.RCode = dnsmessage.RCodeNameError
+			}
+
+			for _, record := range records {
+				resp.Answers = append(resp.Answers, dnsmessage.Resource{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: typeCAA, Class: dnsmessage.ClassINET, TTL: 300},
+					Body: &dnsmessage.UnknownResource{
+						Type: typeCAA,
+						Data: append([]byte{record.Flags, byte(len(record.Tag))}, record.Tag+record.Value...),
+					},
+				})
+			}
+
+			var packed []byte
+			if packed, err = resp.Pack(); err != nil {
+				continue
+			}
+
+			conn.WriteTo(packed, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestLookupCAAServer(t *testing.T) {
+	t.Parallel()
+
+	var want = []CAARecord{
+		{Tag: "issue", Value: "globalsign.com"},
+		{Flags: 128, Tag: "iodef", Value: "mailto:security@example.com"},
+	}
+
+	var server = newCAAServer(t, map[string][]CAARecord{
+		"example.com.":     want,
+		"www.example.com.": {},
+	})
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var testcases = []struct {
+		name string
+		want []CAARecord
+	}{
+		{"example.com", want},
+		{"www.example.com", nil},
+		{"missing.example.com", nil},
+	}
+
+	for _, tc := range testcases {
+		var got, err = lookupCAAServer(ctx, server, tc.name)
+		if err != nil {
+			t.Fatalf("couldn't look up CAA records for %s: %v", tc.name, err)
+		}
+
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestResolverServers replaces the resolver configuration file path, so it
+// must not run in parallel with other tests.
+func TestResolverServers(t *testing.T) {
+	var saved = resolvConfPath
+	defer func() { resolvConfPath = saved }()
+
+	var dir = t.TempDir()
+
+	var writeConf = func(name, contents string) string {
+		var path = filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatalf("couldn't write resolver configuration: %v", err)
+		}
+
+		return path
+	}
+
+	var testcases = []struct {
+		name     string
+		servers  []string
+		confPath string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "Configured",
+			servers:  []string{"192.0.2.1", "192.0.2.2:5353", "2001:db8::1"},
+			confPath: filepath.Join(dir, "missing"),
+			want:     []string{"192.0.2.1:53", "192.0.2.2:5353", "[2001:db8::1]:53"},
+		},
+		{
+			name:     "ResolvConf",
+			confPath: writeConf("resolv.conf", "search example.com\nnameserver 192.0.2.1\nnameserver 2001:db8::1\n"),
+			want:     []string{"192.0.2.1:53", "[2001:db8::1]:53"},
+		},
+		{
+			name:     "NoNameservers",
+			confPath: writeConf("empty.conf", "search example.com\n"),
+			wantErr:  true,
+		},
+		{
+			name:     "MissingFile",
+			confPath: filepath.Join(dir, "missing"),
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		resolvConfPath = tc.confPath
+
+		var got, err = resolverServers(tc.servers)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: got error %v, want error %t", tc.name, err, tc.wantErr)
+			continue
+		}
+
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestClientCheckCAA replaces the package CAA lookup function, so it must
+// not run in parallel with other tests.
+func TestClientCheckCAA(t *testing.T) {
+	var saved = lookupCAA
+	defer func() { lookupCAA = saved }()
+
+	var lookup = fakeCAALookup(map[string][]CAARecord{
+		"example.com": {{Tag: "issue", Value: "ca.example.net"}},
+	})
+
+	lookupCAA = func(ctx context.Context, servers []string, name string) ([]CAARecord, error) {
+		if !cmp.Equal(servers, []string{"192.0.2.1"}) {
+			t.Errorf("got DNS servers %v, want [192.0.2.1]", servers)
+		}
+
+		return lookup(ctx, name)
+	}
+
+	var requests int
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Location", r.URL.String()+"/01")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	var warnings []error
+	var newClient = func(onFailure func(error)) *Client {
+		var clnt, _ = newTestClient(t, handler, &Config{
+			CheckCAA:     true,
+			OnCAAFailure: onFailure,
+			DNSServers:   []string{"192.0.2.1"},
+		})
+
+		return clnt
+	}
+
+	var req = &Request{SAN: &SAN{DNSNames: []string{"www.example.com"}}}
+
+	if _, err := newClient(nil).CertificateRequest(context.Background(), req); !errors.Is(err, ErrCAANotAuthorized) {
+		t.Fatalf("got error %v, want %v", err, ErrCAANotAuthorized)
+	}
+
+	if requests != 0 {
+		t.Fatalf("request was submitted despite CAA failure")
+	}
+
+	var clnt = newClient(func(err error) { warnings = append(warnings, err) })
+	if _, err := clnt.CertificateRequest(context.Background(), req); err != nil {
+		t.Fatalf("couldn't request certificate: %v", err)
+	}
+
+	if requests != 1 || len(warnings) != 1 || !errors.Is(warnings[0], ErrCAANotAuthorized) {
+		t.Errorf("got %d requests and warnings %v, want 1 request and 1 warning", requests, warnings)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// Descriptions of operations which use optional HVCA endpoints, as reported
+// in a NotSupportedError.
+const (
+	opRevokeWithReason = "certificate revocation"
+	opClaimHTTP        = "HTTP domain validation"
+	opClaimEmail       = "email domain validation"
+	opTimestamp        = "timestamping"
+)
+
+// probeClaimID is the domain claim ID used when probing the domain
+// validation endpoints. No claim has this ID, so a probe can never assert
+// control of a domain.
+const probeClaimID = "0"
+
+// Capabilities summarises which optional HVCA endpoints are available to
+// the calling account. Methods which use an unavailable endpoint return an
+// error matching ErrNotSupported.
+type Capabilities struct {
+	// RevokeWithReason indicates whether certificates may be revoked, with
+	// or without a reason, using CertificateRevoke and
+	// CertificateRevokeWithReason.
+	RevokeWithReason bool `json:"revoke_with_reason"`
+
+	// ClaimHTTP indicates whether domain control may be asserted using
+	// ClaimHTTP.
+	ClaimHTTP bool `json:"claim_http"`
+
+	// ClaimEmail indicates whether domain control may be asserted using
+	// ClaimEmail and ClaimEmailRetrieve.
+	ClaimEmail bool `json:"claim_email"`
+
+	// Timestamp indicates whether timestamp tokens may be requested using
+	// Timestamp.
+	Timestamp bool `json:"timestamp"`
+}
+
+// Capabilities probes each optional HVCA endpoint and reports which are
+// available. The probes address a certificate serial number and a domain
+// claim ID which cannot exist, and send no request bodies. Endpoints which
+// accept only state-changing requests, for revocation and HTTP domain
+// validation, are probed with OPTIONS requests, so no probe has any effect
+// on the account. Such an endpoint is reported as unavailable if the
+// response to the OPTIONS request has an Allow header which does not list
+// the endpoint's method, or if the server does not answer OPTIONS requests
+// for it. An error is returned if a probe fails for any reason other than
+// the endpoint being missing, being forbidden to the account, or rejecting
+// the probe.
+func (c *Client) Capabilities(ctx context.Context) (*Capabilities, error) {
+	var certPath, err = certificatePath(big.NewInt(0))
+	if err != nil {
+		return nil, err
+	}
+
+	var claimPath string
+	if claimPath, err = endpointPath(endpointClaimsDomains, probeClaimID); err != nil {
+		return nil, err
+	}
+
+	var caps Capabilities
+
+	for _, probe := range []struct {
+		path   string
+		method string
+		to     *bool
+	}{
+		{certPath, http.MethodPatch, &caps.RevokeWithReason},
+		{claimPath + pathHTTP, http.MethodPost, &caps.ClaimHTTP},
+		{claimPath + pathEmail, http.MethodGet, &caps.ClaimEmail},
+		{endpointTimestamp + "/00", http.MethodGet, &caps.Timestamp},
+	} {
+		if *probe.to, err = c.probeEndpoint(ctx, probe.path, probe.method); err != nil {
+			return nil, err
+		}
+	}
+
+	return &caps, nil
+}
+
+// probeEndpoint reports whether an HVCA endpoint is available for the
+// specified method. Only GET requests are sent as they are, and other methods
+// are probed with an OPTIONS request. A forbidden response is taken to mean
+// that the account may not use the endpoint, and any other client error
+// response, other than one indicating that the endpoint is missing, to mean
+// that the endpoint exists and rejected the probe.
+func (c *Client) probeEndpoint(ctx context.Context, path, method string) (bool, error) {
+	var probeMethod = method
+	if method != http.MethodGet {
+		probeMethod = http.MethodOptions
+	}
+
+	var resp, err = c.makeRequest(ctx, path, probeMethod, nil, nil)
+	if err == nil {
+		return probeMethod == method || allowsMethod(resp.Header, method), nil
+	}
+
+	var apiErr APIError
+	if !errors.As(err, &apiErr) {
+		return false, err
+	}
+
+	switch {
+	case apiErr.endpointMissing(), apiErr.StatusCode == http.StatusForbidden:
+		return false, nil
+
+	case apiErr.StatusCode == http.StatusUnauthorized, apiErr.StatusCode >= 500:
+		return false, err
+	}
+
+	return true, nil
+}
+
+// allowsMethod reports whether the Allow header of a response to an OPTIONS
+// request lists the specified method. A missing Allow header is taken to
+// allow every method.
+func allowsMethod(header http.Header, method string) bool {
+	var values = header.Values("Allow")
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, value := range values {
+		for _, allowed := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(allowed), method) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
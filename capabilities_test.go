@@ -0,0 +1,234 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+)
+
+// withoutOptionalEndpoints wraps an HVCA handler to behave like a gateway
+// which does not route revocation, certificate retrieval or email domain
+// validation requests.
+func withoutOptionalEndpoints(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPatch:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+		case strings.HasSuffix(strings.TrimRight(r.URL.Path, "/"), "/email"),
+			(r.Method == http.MethodGet || r.Method == http.MethodOptions) && strings.HasPrefix(r.URL.Path, "/certificates/"):
+			http.NotFound(w, r)
+
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// withoutRevocation wraps an HVCA handler to behave like a server which
+// answers OPTIONS requests for certificates without allowing revocation.
+func withoutRevocation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions && strings.HasPrefix(r.URL.Path, "/certificates/") {
+			w.Header().Set("Allow", "GET, OPTIONS")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestClientCapabilities(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name    string
+		handler http.Handler
+		want    hvclient.Capabilities
+	}{
+		{
+			name:    "Mock",
+			handler: hvcatest.NewHandler(),
+			want: hvclient.Capabilities{
+				RevokeWithReason: true,
+				ClaimHTTP:        true,
+				ClaimEmail:       true,
+			},
+		},
+		{
+			name:    "AllowHeader",
+			handler: withoutRevocation(hvcatest.NewHandler()),
+			want: hvclient.Capabilities{
+				ClaimHTTP:  true,
+				ClaimEmail: true,
+			},
+		},
+		{
+			name:    "Gateway",
+			handler: withoutOptionalEndpoints(hvcatest.NewHandler()),
+			want: hvclient.Capabilities{
+				ClaimHTTP: true,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var server = httptest.NewServer(tc.handler)
+			defer server.Close()
+
+			var ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+
+			var client, err = hvclient.NewClient(ctx, hvcatest.Config(server.URL))
+			if err != nil {
+				t.Fatalf("couldn't create client: %v", err)
+			}
+			defer client.Close()
+
+			var got *hvclient.Capabilities
+			if got, err = client.Capabilities(ctx); err != nil {
+				t.Fatalf("couldn't get capabilities: %v", err)
+			}
+
+			if *got != tc.want {
+				t.Errorf("got %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClientCapabilitiesNoStateChange(t *testing.T) {
+	t.Parallel()
+
+	var next = hvcatest.NewHandler()
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodOptions && !strings.HasPrefix(r.URL.Path, "/login") {
+			t.Errorf("unexpected %s request for %s", r.Method, r.URL.Path)
+		}
+
+		next.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var client, err = hvclient.NewClient(ctx, hvcatest.Config(server.URL))
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+	defer client.Close()
+
+	if _, err = client.Capabilities(ctx); err != nil {
+		t.Fatalf("couldn't get capabilities: %v", err)
+	}
+}
+
+func TestClientNotSupported(t *testing.T) {
+	t.Parallel()
+
+	var server = httptest.NewServer(withoutOptionalEndpoints(hvcatest.NewHandler()))
+	defer server.Close()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var client, err = hvclient.NewClient(ctx, hvcatest.Config(server.URL))
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+	defer client.Close()
+
+	var testcases = []struct {
+		name string
+		call func() error
+	}{
+		{
+			name: "Revoke",
+			call: func() error {
+				return client.CertificateRevokeWithReason(ctx, hvcatest.Cert.SerialNumber,
+					hvclient.RevocationReasonKeyCompromise, 0)
+			},
+		},
+		{
+			name: "ClaimEmail",
+			call: func() error {
+				var _, err = client.ClaimEmail(ctx, "1234", "admin@fake.com")
+				return err
+			},
+		},
+		{
+			name: "ClaimEmailRetrieve",
+			call: func() error {
+				var _, err = client.ClaimEmailRetrieve(ctx, "1234")
+				return err
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		// Subtests aren't run in parallel since they share the client, which
+		// is closed when the test returns.
+		t.Run(tc.name, func(t *testing.T) {
+			var err = tc.call()
+			if !errors.Is(err, hvclient.ErrNotSupported) {
+				t.Fatalf("got error %v, want %v", err, hvclient.ErrNotSupported)
+			}
+
+			var nsErr *hvclient.NotSupportedError
+			if !errors.As(err, &nsErr) {
+				t.Fatalf("got error of type %T, want %T", err, nsErr)
+			}
+
+			var apiErr hvclient.APIError
+			if !errors.As(err, &apiErr) {
+				t.Errorf("NotSupportedError does not wrap an APIError")
+			}
+		})
+	}
+}
+
+func TestClientNotFoundIsSupported(t *testing.T) {
+	t.Parallel()
+
+	var client, cleanup = newMockClient(t)
+	defer cleanup()
+
+	var err = client.CertificateRevoke(context.Background(), hvcatest.SerialNotFound)
+	if !errors.Is(err, hvclient.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, hvclient.ErrNotFound)
+	}
+
+	if errors.Is(err, hvclient.ErrNotSupported) {
+		t.Errorf("missing certificate unexpectedly reported as not supported")
+	}
+}
@@ -16,54 +16,115 @@ limitations under the License.
 package hvclient
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/globalsign/hvclient/internal/oids"
 )
 
-// CertStatus is the issued/revoked status of a certificate.
+// CertStatus is the status of a certificate, such as issued or revoked.
 type CertStatus int
 
 // CertInfo contains a certificate and associated information.
 type CertInfo struct {
 	PEM       string            // The PEM-encoded certificate
 	X509      *x509.Certificate // The parsed certificate
-	Status    CertStatus        // Issued or revoked
+	Status    CertStatus        // Issued, revoked, pending or expired
 	UpdatedAt time.Time         // When the certificate was last updated
+
+	// RawStatus is the status exactly as reported by HVCA. It is mainly
+	// useful when Status is StatusUnknown because HVCA reported a status
+	// which this package does not recognise.
+	RawStatus string
+
+	// IsPrecertificate is true if the certificate contains the certificate
+	// transparency poison extension, indicating it is a precertificate
+	// retrieved before the final certificate was issued. Precertificates
+	// are not accepted by relying parties and must not be deployed.
+	IsPrecertificate bool
+
+	// RevokedAt and RevocationReason are the time at which and the reason
+	// for which the certificate was revoked, if known. HVCA does not report
+	// them when a certificate is retrieved, but they are preserved by
+	// MarshalJSON and UnmarshalJSON, so callers which record them when
+	// revoking a certificate can persist them with the certificate.
+	RevokedAt        time.Time
+	RevocationReason RevocationReason
 }
 
+// Fingerprint is a hash of a DER-encoded certificate, such as a SHA-1 or
+// SHA-256 fingerprint. Certificate inventory systems commonly identify
+// certificates by fingerprint rather than by serial number.
+type Fingerprint []byte
+
+// KeyID is a subject or authority key identifier.
+type KeyID []byte
+
+// ErrPrecertificate is wrapped by errors returned when HVCA returns a
+// precertificate and the client is configured to await the final
+// certificate, but it does not become available.
+var ErrPrecertificate = errors.New("certificate is a precertificate")
+
 // jsonCertInfo is used internally for JSON marshalling/unmarshalling.
 type jsonCertInfo struct {
-	PEM       string     `json:"certificate"`
-	Status    CertStatus `json:"status"`
-	UpdatedAt int64      `json:"updated_at"`
+	PEM              string           `json:"certificate"`
+	Status           string           `json:"status"`
+	UpdatedAt        int64            `json:"updated_at"`
+	RevocationTime   int64            `json:"revocation_time,omitempty"`
+	RevocationReason RevocationReason `json:"revocation_reason,omitempty"`
 }
 
-// Certificate status values.
+// Certificate status values. StatusUnknown is the zero value, and is used
+// for any status reported by HVCA which is not otherwise recognised.
+// StatusPendingIssuance is so named to distinguish it from the domain claim
+// status StatusPending.
 const (
-	StatusIssued CertStatus = iota + 1
+	StatusUnknown CertStatus = iota
+	StatusIssued
 	StatusRevoked
+	StatusPendingIssuance
+	StatusExpired
 )
 
 // certStatusNames maps certificate status values to their string descriptions.
 var certStatusNames = [...]string{
-	StatusIssued:  "ISSUED",
-	StatusRevoked: "REVOKED",
+	StatusIssued:          "ISSUED",
+	StatusRevoked:         "REVOKED",
+	StatusPendingIssuance: "PENDING",
+	StatusExpired:         "EXPIRED",
 }
 
 // certStatusCodes maps certificate status string descriptions to their values.
 var certStatusCodes = map[string]CertStatus{
 	"ISSUED":  StatusIssued,
 	"REVOKED": StatusRevoked,
+	"PENDING": StatusPendingIssuance,
+	"EXPIRED": StatusExpired,
+}
+
+// ParseCertStatus returns the certificate status with the specified
+// description, ignoring case.
+func ParseCertStatus(s string) (CertStatus, error) {
+	var result, ok = certStatusCodes[strings.ToUpper(s)]
+	if !ok {
+		return StatusUnknown, fmt.Errorf("invalid certificate status value: %s", s)
+	}
+
+	return result, nil
 }
 
 // isValid checks if a certificate status value is within a valid range.
 func (s CertStatus) isValid() bool {
-	return s >= StatusIssued && s <= StatusRevoked
+	return s >= StatusIssued && s <= StatusExpired
 }
 
 // String returns a description of the certificate status.
@@ -85,7 +146,9 @@ func (s CertStatus) MarshalJSON() ([]byte, error) {
 }
 
 // UnmarshalJSON parses a JSON-encoded certificate status value and stores the
-// result in the object.
+// result in the object. An unrecognised status is stored as StatusUnknown
+// rather than causing an error, so that new statuses introduced by HVCA do
+// not prevent the surrounding data from being parsed.
 func (s *CertStatus) UnmarshalJSON(b []byte) error {
 	var data string
 	var err = json.Unmarshal(b, &data)
@@ -93,17 +156,14 @@ func (s *CertStatus) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	var result, ok = certStatusCodes[strings.ToUpper(data)]
-	if !ok {
-		return fmt.Errorf("invalid certificate status value: %s", data)
-	}
-
-	*s = result
+	*s, _ = ParseCertStatus(data)
 
 	return nil
 }
 
-// Equal checks if two certificate metadata objects are equivalent.
+// Equal checks if two certificate metadata objects are equivalent. All
+// fields are compared, except that statuses reported by HVCA are compared
+// without regard to case, and times are compared with time.Time.Equal.
 func (s CertInfo) Equal(other CertInfo) bool {
 	if (s.X509 == nil) != (other.X509 == nil) {
 		return false
@@ -115,22 +175,52 @@ func (s CertInfo) Equal(other CertInfo) bool {
 
 	return s.PEM == other.PEM &&
 		s.Status == other.Status &&
-		s.UpdatedAt.Equal(other.UpdatedAt)
+		strings.EqualFold(s.status(), other.status()) &&
+		s.UpdatedAt.Equal(other.UpdatedAt) &&
+		s.IsPrecertificate == other.IsPrecertificate &&
+		s.RevokedAt.Equal(other.RevokedAt) &&
+		s.RevocationReason == other.RevocationReason
 }
 
-// MarshalJSON returns the JSON encoding of certificate metadata.
+// status returns the description of the certificate status, or the raw
+// status reported by HVCA if the status is not recognised.
+func (s CertInfo) status() string {
+	if !s.Status.isValid() {
+		return s.RawStatus
+	}
+
+	return s.Status.String()
+}
+
+// MarshalJSON returns the JSON encoding of certificate metadata, in the
+// format returned by HVCA. An unrecognised status is encoded as reported by
+// HVCA, and the revocation time and reason are included if set, so that
+// certificate metadata survives a round trip. Times are encoded with a
+// precision of one second, as reported by HVCA.
 func (s CertInfo) MarshalJSON() ([]byte, error) {
-	return json.Marshal(jsonCertInfo{
-		PEM:       s.PEM,
-		Status:    s.Status,
-		UpdatedAt: s.UpdatedAt.Unix(),
-	})
+	var status = s.status()
+	if status == "" {
+		return nil, fmt.Errorf("invalid certificate status value: %d", s.Status)
+	}
+
+	var data = jsonCertInfo{
+		PEM:              s.PEM,
+		Status:           status,
+		UpdatedAt:        s.UpdatedAt.Unix(),
+		RevocationReason: s.RevocationReason,
+	}
+
+	if !s.RevokedAt.IsZero() {
+		data.RevocationTime = s.RevokedAt.Unix()
+	}
+
+	return json.Marshal(data)
 }
 
 // UnmarshalJSON parses JSON-encoded certificate metadata and stores the
 // result in the object.
 func (s *CertInfo) UnmarshalJSON(b []byte) error {
-	var data *jsonCertInfo
+	var data jsonCertInfo
 	var err = json.Unmarshal(b, &data)
 	if err != nil {
 		return err
@@ -147,12 +237,170 @@ func (s *CertInfo) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
+	// Don't fail on statuses introduced by HVCA after this package was
+	// written, but retain the raw status for the caller.
+	var status, _ = ParseCertStatus(data.Status)
+
 	*s = CertInfo{
-		PEM:       data.PEM,
-		X509:      cert,
-		Status:    data.Status,
-		UpdatedAt: time.Unix(data.UpdatedAt, 0).UTC(),
+		PEM:              data.PEM,
+		X509:             cert,
+		Status:           status,
+		RawStatus:        data.Status,
+		UpdatedAt:        time.Unix(data.UpdatedAt, 0).UTC(),
+		IsPrecertificate: isPrecertificate(cert),
+		RevocationReason: data.RevocationReason,
+	}
+
+	if data.RevocationTime != 0 {
+		s.RevokedAt = time.Unix(data.RevocationTime, 0).UTC()
 	}
 
 	return nil
 }
+
+// isPrecertificate reports whether a certificate contains the certificate
+// transparency poison extension.
+func isPrecertificate(cert *x509.Certificate) bool {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oids.OIDCTPoison) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SerialNumber returns the serial number of the certificate, or the zero
+// value if the certificate is not present.
+func (s CertInfo) SerialNumber() SerialNumber {
+	if s.X509 == nil {
+		return SerialNumber{}
+	}
+
+	return NewSerialNumber(s.X509.SerialNumber)
+}
+
+// SHA1Fingerprint returns the SHA-1 fingerprint of the certificate, or nil
+// if the certificate is not present.
+func (s CertInfo) SHA1Fingerprint() Fingerprint {
+	if s.X509 == nil {
+		return nil
+	}
+
+	var sum = sha1.Sum(s.X509.Raw)
+
+	return Fingerprint(sum[:])
+}
+
+// SHA256Fingerprint returns the SHA-256 fingerprint of the certificate, or
+// nil if the certificate is not present.
+func (s CertInfo) SHA256Fingerprint() Fingerprint {
+	if s.X509 == nil {
+		return nil
+	}
+
+	var sum = sha256.Sum256(s.X509.Raw)
+
+	return Fingerprint(sum[:])
+}
+
+// MatchesFingerprint returns true if the specified SHA-1 or SHA-256
+// fingerprint is the fingerprint of the certificate.
+func (s CertInfo) MatchesFingerprint(fp Fingerprint) bool {
+	switch len(fp) {
+	case sha1.Size:
+		return fp.Equal(s.SHA1Fingerprint())
+
+	case sha256.Size:
+		return fp.Equal(s.SHA256Fingerprint())
+	}
+
+	return false
+}
+
+// SubjectKeyID returns the subject key identifier of the certificate, or
+// nil if the certificate is not present or has no subject key identifier.
+func (s CertInfo) SubjectKeyID() KeyID {
+	if s.X509 == nil || len(s.X509.SubjectKeyId) == 0 {
+		return nil
+	}
+
+	return KeyID(s.X509.SubjectKeyId)
+}
+
+// AuthorityKeyID returns the authority key identifier of the certificate,
+// or nil if the certificate is not present or has no authority key
+// identifier.
+func (s CertInfo) AuthorityKeyID() KeyID {
+	if s.X509 == nil || len(s.X509.AuthorityKeyId) == 0 {
+		return nil
+	}
+
+	return KeyID(s.X509.AuthorityKeyId)
+}
+
+// ParseFingerprint parses a fingerprint from a hexadecimal string. Colon
+// and space separators between bytes, as output by many tools, are
+// accepted, and case is ignored.
+func ParseFingerprint(s string) (Fingerprint, error) {
+	var digits = strings.NewReplacer(":", "", " ", "").Replace(strings.TrimSpace(s))
+
+	var fp, err = hex.DecodeString(digits)
+	if err != nil || len(fp) == 0 {
+		return nil, fmt.Errorf("invalid fingerprint: %q", s)
+	}
+
+	return Fingerprint(fp), nil
+}
+
+// Equal returns true if two fingerprints are identical.
+func (f Fingerprint) Equal(other Fingerprint) bool {
+	return len(f) != 0 && bytes.Equal(f, other)
+}
+
+// String returns the fingerprint as colon-separated uppercase hexadecimal
+// bytes.
+func (f Fingerprint) String() string {
+	return colonHex(f)
+}
+
+// MarshalText returns the fingerprint as colon-separated uppercase
+// hexadecimal bytes, so that it is encoded as a string in JSON.
+func (f Fingerprint) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText parses a fingerprint in any format accepted by
+// ParseFingerprint.
+func (f *Fingerprint) UnmarshalText(text []byte) error {
+	var fp, err = ParseFingerprint(string(text))
+	if err != nil {
+		return err
+	}
+
+	*f = fp
+
+	return nil
+}
+
+// Equal returns true if two key identifiers are identical.
+func (k KeyID) Equal(other KeyID) bool {
+	return len(k) != 0 && bytes.Equal(k, other)
+}
+
+// String returns the key identifier as colon-separated uppercase
+// hexadecimal bytes.
+func (k KeyID) String() string {
+	return colonHex(k)
+}
+
+// colonHex returns a byte slice as colon-separated uppercase hexadecimal
+// bytes.
+func colonHex(b []byte) string {
+	var parts = make([]string, len(b))
+	for i := range b {
+		parts[i] = fmt.Sprintf("%02X", b[i])
+	}
+
+	return strings.Join(parts, ":")
+}
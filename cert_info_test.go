@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -150,6 +151,64 @@ func TestCertInfoEqual(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "RawStatus/CaseInsensitive",
+			first: hvclient.CertInfo{
+				RawStatus: "SUSPENDED",
+			},
+			second: hvclient.CertInfo{
+				RawStatus: "suspended",
+			},
+			want: true,
+		},
+		{
+			name: "RawStatus",
+			first: hvclient.CertInfo{
+				RawStatus: "SUSPENDED",
+			},
+			second: hvclient.CertInfo{
+				RawStatus: "ON_HOLD",
+			},
+			want: false,
+		},
+		{
+			name: "IsPrecertificate",
+			first: hvclient.CertInfo{
+				IsPrecertificate: true,
+			},
+			second: hvclient.CertInfo{},
+			want:   false,
+		},
+		{
+			name: "RevokedAt",
+			first: hvclient.CertInfo{
+				RevokedAt: time.Date(2021, 6, 21, 18, 43, 30, 0, time.UTC),
+			},
+			second: hvclient.CertInfo{
+				RevokedAt: time.Date(2021, 6, 21, 18, 43, 30, 0, time.FixedZone("SGT", 8*3600)),
+			},
+			want: false,
+		},
+		{
+			name: "RevokedAt/Location",
+			first: hvclient.CertInfo{
+				RevokedAt: time.Date(2021, 6, 21, 18, 43, 30, 0, time.UTC),
+			},
+			second: hvclient.CertInfo{
+				RevokedAt: time.Date(2021, 6, 22, 2, 43, 30, 0, time.FixedZone("SGT", 8*3600)),
+			},
+			want: true,
+		},
+		{
+			name: "RevocationReason",
+			first: hvclient.CertInfo{
+				RevocationReason: hvclient.RevocationReasonKeyCompromise,
+			},
+			second: hvclient.CertInfo{
+				RevocationReason: hvclient.RevocationReasonSuperseded,
+			},
+			want: false,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -194,6 +253,17 @@ func TestCertInfoMarshalJSON(t *testing.T) {
 			want: []byte(fmt.Sprintf(`{"certificate":"%s","status":"REVOKED","updated_at":1477958400}`,
 				strings.Replace(testPEM, "\n", "\\n", -1))),
 		},
+		{
+			name: "UnknownStatus",
+			info: hvclient.CertInfo{
+				PEM:       testPEM,
+				Status:    hvclient.StatusUnknown,
+				RawStatus: "SUSPENDED",
+				UpdatedAt: time.Unix(1477958400, 0),
+			},
+			want: []byte(fmt.Sprintf(`{"certificate":"%s","status":"SUSPENDED","updated_at":1477958400}`,
+				strings.Replace(testPEM, "\n", "\\n", -1))),
+		},
 		{
 			name: "BadStatus",
 			info: hvclient.CertInfo{
@@ -223,6 +293,66 @@ func TestCertInfoMarshalJSON(t *testing.T) {
 	}
 }
 
+func TestCertInfoJSONGolden(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		want hvclient.CertInfo
+	}{
+		{
+			name: "cert_info_issued.json",
+			want: hvclient.CertInfo{
+				Status:    hvclient.StatusIssued,
+				RawStatus: "ISSUED",
+				UpdatedAt: time.Unix(1624301010, 0),
+			},
+		},
+		{
+			name: "cert_info_revoked.json",
+			want: hvclient.CertInfo{
+				Status:           hvclient.StatusRevoked,
+				RawStatus:        "REVOKED",
+				UpdatedAt:        time.Unix(1624301010, 0),
+				RevokedAt:        time.Unix(1624301000, 0),
+				RevocationReason: hvclient.RevocationReasonKeyCompromise,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var golden = testhelpers.MustReadFile(t, filepath.Join("testdata", tc.name))
+
+			var want = tc.want
+			want.X509 = testhelpers.MustGetCertFromFile(t, "testdata/test_cert.pem")
+			want.PEM = string(testhelpers.MustReadFile(t, "testdata/test_cert.pem"))
+
+			var got hvclient.CertInfo
+			if err := json.Unmarshal(golden, &got); err != nil {
+				t.Fatalf("couldn't unmarshal certificate info: %v", err)
+			}
+
+			if !got.Equal(want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+
+			var data, err = json.MarshalIndent(got, "", "    ")
+			if err != nil {
+				t.Fatalf("couldn't marshal certificate info: %v", err)
+			}
+
+			if !bytes.Equal(append(data, '\n'), golden) {
+				t.Errorf("got %s, want %s", data, golden)
+			}
+		})
+	}
+}
+
 func TestCertInfoUnmarshalJSON(t *testing.T) {
 	t.Parallel()
 
@@ -255,10 +385,38 @@ func TestCertInfoUnmarshalJSON(t *testing.T) {
 			},
 		},
 		{
-			name: "BadStatusValue",
-			data: []byte(fmt.Sprintf(`{"certificate":"%s","status":"BAD STATUS","updated_at":1477958400}`,
+			name: "Pending",
+			data: []byte(fmt.Sprintf(`{"certificate":"%s","status":"PENDING","updated_at":1477958400}`,
 				strings.Replace(testPEM, "\n", "\\n", -1))),
-			err: errors.New("bad status value"),
+			want: hvclient.CertInfo{
+				PEM:       testPEM,
+				X509:      testhelpers.MustParseCert(t, testPEM),
+				Status:    hvclient.StatusPendingIssuance,
+				UpdatedAt: time.Unix(1477958400, 0),
+			},
+		},
+		{
+			name: "Expired",
+			data: []byte(fmt.Sprintf(`{"certificate":"%s","status":"expired","updated_at":1477958400}`,
+				strings.Replace(testPEM, "\n", "\\n", -1))),
+			want: hvclient.CertInfo{
+				PEM:       testPEM,
+				X509:      testhelpers.MustParseCert(t, testPEM),
+				Status:    hvclient.StatusExpired,
+				UpdatedAt: time.Unix(1477958400, 0),
+			},
+		},
+		{
+			name: "UnknownStatusValue",
+			data: []byte(fmt.Sprintf(`{"certificate":"%s","status":"SUSPENDED","updated_at":1477958400}`,
+				strings.Replace(testPEM, "\n", "\\n", -1))),
+			want: hvclient.CertInfo{
+				PEM:       testPEM,
+				X509:      testhelpers.MustParseCert(t, testPEM),
+				Status:    hvclient.StatusUnknown,
+				RawStatus: "SUSPENDED",
+				UpdatedAt: time.Unix(1477958400, 0),
+			},
 		},
 		{
 			name: "BadStatusType",
@@ -303,6 +461,71 @@ func TestCertInfoUnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestParseCertStatus(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		value string
+		want  hvclient.CertStatus
+		err   bool
+	}{
+		{"ISSUED", hvclient.StatusIssued, false},
+		{"revoked", hvclient.StatusRevoked, false},
+		{"Pending", hvclient.StatusPendingIssuance, false},
+		{"EXPIRED", hvclient.StatusExpired, false},
+		{"SUSPENDED", hvclient.StatusUnknown, true},
+		{"", hvclient.StatusUnknown, true},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = hvclient.ParseCertStatus(tc.value)
+			if (err != nil) != tc.err {
+				t.Fatalf("got error %v, want error %t", err, tc.err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+
+			if err != nil {
+				return
+			}
+
+			var data []byte
+			if data, err = json.Marshal(got); err != nil {
+				t.Fatalf("couldn't marshal status: %v", err)
+			}
+
+			var roundtrip hvclient.CertStatus
+			if err = json.Unmarshal(data, &roundtrip); err != nil {
+				t.Fatalf("couldn't unmarshal status: %v", err)
+			}
+
+			if roundtrip != got {
+				t.Errorf("got %v after round trip, want %v", roundtrip, got)
+			}
+		})
+	}
+}
+
+func TestCertStatusUnmarshalJSONUnknown(t *testing.T) {
+	t.Parallel()
+
+	var got = hvclient.StatusIssued
+	if err := json.Unmarshal([]byte(`"SUSPENDED"`), &got); err != nil {
+		t.Fatalf("couldn't unmarshal status: %v", err)
+	}
+
+	if got != hvclient.StatusUnknown {
+		t.Errorf("got %v, want %v", got, hvclient.StatusUnknown)
+	}
+}
+
 func TestCertStatusStringInvalidValue(t *testing.T) {
 	t.Parallel()
 
@@ -311,3 +534,183 @@ func TestCertStatusStringInvalidValue(t *testing.T) {
 		t.Errorf("got %s, want %s", got, want)
 	}
 }
+
+func TestCertInfoIdentifiers(t *testing.T) {
+	t.Parallel()
+
+	var info = hvclient.CertInfo{
+		X509: testhelpers.MustGetCertFromFile(t, "testdata/test_cert.pem"),
+	}
+
+	var testcases = []struct {
+		name string
+		got  fmt.Stringer
+		want string
+	}{
+		{
+			name: "SerialNumber",
+			got:  info.SerialNumber(),
+			want: "741DAF9EC2D5F7DC",
+		},
+		{
+			name: "SHA1Fingerprint",
+			got:  info.SHA1Fingerprint(),
+			want: "5C:DB:18:91:BC:E6:6E:A4:51:41:AE:67:C2:6B:51:5D:3D:89:F5:0F",
+		},
+		{
+			name: "SHA256Fingerprint",
+			got:  info.SHA256Fingerprint(),
+			want: "5C:6C:3C:10:7C:73:D1:5E:83:7C:67:26:E1:B4:09:81:98:69:86:05:34:5A:FF:95:13:3D:AC:4E:6A:18:CB:86",
+		},
+		{
+			name: "SubjectKeyID",
+			got:  info.SubjectKeyID(),
+			want: "CB:93:7B:3F:9E:D8:8B:B9:9B:59:40:BB:B3:FE:BD:41:E5:0F:91:22",
+		},
+		{
+			name: "AuthorityKeyID",
+			got:  info.AuthorityKeyID(),
+			want: "CB:93:7B:3F:9E:D8:8B:B9:9B:59:40:BB:B3:FE:BD:41:E5:0F:91:22",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.got.String(); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCertInfoIdentifiersNoCertificate(t *testing.T) {
+	t.Parallel()
+
+	var info hvclient.CertInfo
+
+	if got := info.SerialNumber().Hex(); got != "" {
+		t.Errorf("got serial number %q, want empty", got)
+	}
+
+	if got := info.SHA1Fingerprint(); got != nil {
+		t.Errorf("got SHA-1 fingerprint %v, want nil", got)
+	}
+
+	if got := info.SHA256Fingerprint(); got != nil {
+		t.Errorf("got SHA-256 fingerprint %v, want nil", got)
+	}
+
+	if got := info.SubjectKeyID(); got != nil {
+		t.Errorf("got subject key ID %v, want nil", got)
+	}
+
+	if got := info.AuthorityKeyID(); got != nil {
+		t.Errorf("got authority key ID %v, want nil", got)
+	}
+
+	if info.MatchesFingerprint(hvclient.Fingerprint{}) {
+		t.Errorf("empty fingerprint unexpectedly matched")
+	}
+}
+
+func TestCertInfoMatchesFingerprint(t *testing.T) {
+	t.Parallel()
+
+	var info = hvclient.CertInfo{
+		X509: testhelpers.MustGetCertFromFile(t, "testdata/test_cert.pem"),
+	}
+
+	var testcases = []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{
+			name:  "SHA1",
+			value: "5C:DB:18:91:BC:E6:6E:A4:51:41:AE:67:C2:6B:51:5D:3D:89:F5:0F",
+			want:  true,
+		},
+		{
+			name:  "SHA1LowerCaseNoColons",
+			value: "5cdb1891bce66ea45141ae67c26b515d3d89f50f",
+			want:  true,
+		},
+		{
+			name:  "SHA256Spaces",
+			value: "5C 6C 3C 10 7C 73 D1 5E 83 7C 67 26 E1 B4 09 81 98 69 86 05 34 5A FF 95 13 3D AC 4E 6A 18 CB 86",
+			want:  true,
+		},
+		{
+			name:  "SHA1Mismatch",
+			value: "5C:DB:18:91:BC:E6:6E:A4:51:41:AE:67:C2:6B:51:5D:3D:89:F5:10",
+			want:  false,
+		},
+		{
+			name:  "BadLength",
+			value: "5C:DB:18:91",
+			want:  false,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var fp, err = hvclient.ParseFingerprint(tc.value)
+			if err != nil {
+				t.Fatalf("couldn't parse fingerprint: %v", err)
+			}
+
+			if got := info.MatchesFingerprint(fp); got != tc.want {
+				t.Errorf("got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseFingerprintFailure(t *testing.T) {
+	t.Parallel()
+
+	for _, value := range []string{"", "  ", "5C:DB:1", "not hex"} {
+		if _, err := hvclient.ParseFingerprint(value); err == nil {
+			t.Errorf("unexpectedly parsed fingerprint %q", value)
+		}
+	}
+}
+
+func TestFingerprintJSON(t *testing.T) {
+	t.Parallel()
+
+	var fp, err = hvclient.ParseFingerprint("5c:db:18:91")
+	if err != nil {
+		t.Fatalf("couldn't parse fingerprint: %v", err)
+	}
+
+	var data []byte
+	if data, err = json.Marshal(fp); err != nil {
+		t.Fatalf("couldn't marshal fingerprint: %v", err)
+	}
+
+	if want := `"5C:DB:18:91"`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	var got hvclient.Fingerprint
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("couldn't unmarshal fingerprint: %v", err)
+	}
+
+	if !got.Equal(fp) {
+		t.Errorf("got %v, want %v", got, fp)
+	}
+
+	if err = json.Unmarshal([]byte(`"not hex"`), &got); err == nil {
+		t.Errorf("unexpectedly unmarshalled invalid fingerprint")
+	}
+}
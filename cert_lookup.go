@@ -0,0 +1,151 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ErrNotIssuedByAccount is wrapped by errors returned by
+// FindSerialByCertificate when a certificate was not issued for the calling
+// account.
+var ErrNotIssuedByAccount = errors.New("certificate not issued for this account")
+
+// SerialIndex maps the SHA-1 and SHA-256 fingerprints of certificates to
+// their serial numbers, for certificate inventory systems which identify
+// certificates by fingerprint.
+type SerialIndex struct {
+	serials map[string]*big.Int
+	count   int
+}
+
+// FindSerialByCertificate returns the serial number of a certificate, such
+// as one read from a local PEM file, after checking that it was issued for
+// the calling account, so that it may be revoked or otherwise managed by
+// serial number. The certificate must be signed by a certificate in the
+// account's chain of trust, and HVCA must return an identical certificate
+// for its serial number, otherwise an error wrapping ErrNotIssuedByAccount
+// is returned.
+func (c *Client) FindSerialByCertificate(ctx context.Context, cert *x509.Certificate) (*big.Int, error) {
+	var chain, err = c.TrustChain(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't retrieve trust chain: %w", err)
+	}
+
+	if !signedByAny(cert, chain) {
+		return nil, fmt.Errorf("%w: not signed by a certificate in the trust chain", ErrNotIssuedByAccount)
+	}
+
+	var info *CertInfo
+	if info, err = c.CertificateRetrieve(ctx, cert.SerialNumber); errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: serial number %X not found", ErrNotIssuedByAccount, cert.SerialNumber)
+	} else if err != nil {
+		return nil, fmt.Errorf("couldn't retrieve certificate %X: %w", cert.SerialNumber, err)
+	}
+
+	if info.X509 == nil || !bytes.Equal(info.X509.Raw, cert.Raw) {
+		return nil, fmt.Errorf("%w: HVCA holds a different certificate with serial number %X",
+			ErrNotIssuedByAccount, cert.SerialNumber)
+	}
+
+	return new(big.Int).Set(cert.SerialNumber), nil
+}
+
+// SerialIndex retrieves every certificate issued during the specified time
+// window and returns an index of their serial numbers by fingerprint.
+// Certificates are retrieved concurrently if the PageConcurrency
+// configuration field is set, and each retrieval is reported to any
+// progress reporter in the client configuration.
+func (c *Client) SerialIndex(ctx context.Context, from, to time.Time) (*SerialIndex, error) {
+	var metas, err = c.StatsIssuedAll(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't retrieve issued certificates: %w", err)
+	}
+
+	var serials = make([]*big.Int, len(metas))
+	for i := range metas {
+		serials[i] = metas[i].SerialNumber
+	}
+
+	var raws = make([][]byte, len(serials))
+	if err = c.retrieveEach(ctx, serials, c.config.PageConcurrency, func(i int, info *CertInfo) {
+		raws[i] = info.X509.Raw
+	}); err != nil {
+		return nil, err
+	}
+
+	var index = &SerialIndex{serials: make(map[string]*big.Int, len(serials)*2)}
+	for i, raw := range raws {
+		index.Add(raw, serials[i])
+	}
+
+	return index, nil
+}
+
+// Add adds the DER-encoded certificate with the specified serial number to
+// the index.
+func (x *SerialIndex) Add(der []byte, serial *big.Int) {
+	if x.serials == nil {
+		x.serials = make(map[string]*big.Int)
+	}
+
+	var sum1 = sha1.Sum(der)
+	var sum256 = sha256.Sum256(der)
+
+	if _, ok := x.serials[string(sum256[:])]; !ok {
+		x.count++
+	}
+
+	x.serials[string(sum1[:])] = serial
+	x.serials[string(sum256[:])] = serial
+}
+
+// Lookup returns the serial number of the certificate with the specified
+// SHA-1 or SHA-256 fingerprint, and whether it is in the index.
+func (x *SerialIndex) Lookup(fp Fingerprint) (*big.Int, bool) {
+	if len(fp) != sha1.Size && len(fp) != sha256.Size {
+		return nil, false
+	}
+
+	var serial, ok = x.serials[string(fp)]
+
+	return serial, ok
+}
+
+// Len returns the number of certificates in the index.
+func (x *SerialIndex) Len() int {
+	return x.count
+}
+
+// signedByAny returns true if the certificate is signed by any of the
+// specified certificates.
+func signedByAny(cert *x509.Certificate, issuers []*x509.Certificate) bool {
+	for _, issuer := range issuers {
+		if bytes.Equal(cert.RawIssuer, issuer.RawSubject) && cert.CheckSignatureFrom(issuer) == nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,140 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+	"github.com/globalsign/hvclient/internal/testhelpers"
+)
+
+func TestClientFindSerialByCertificate(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var got, err = client.FindSerialByCertificate(ctx, hvcatest.Cert)
+	if err != nil {
+		t.Fatalf("couldn't find serial number: %v", err)
+	}
+
+	if got.Cmp(hvcatest.Cert.SerialNumber) != 0 {
+		t.Errorf("got serial number %X, want %X", got, hvcatest.Cert.SerialNumber)
+	}
+}
+
+func TestClientFindSerialByCertificateFailure(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var testcases = []struct {
+		name string
+		path string
+	}{
+		{
+			name: "NotInTrustChain",
+			path: "testdata/tls.cert",
+		},
+		{
+			name: "DifferentCertificate",
+			path: "hvcatest/certs/root_cert.pem",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			var cert = testhelpers.MustGetCertFromFile(t, tc.path)
+
+			var _, err = client.FindSerialByCertificate(ctx, cert)
+			if !errors.Is(err, hvclient.ErrNotIssuedByAccount) {
+				t.Errorf("got error %v, want %v", err, hvclient.ErrNotIssuedByAccount)
+			}
+		})
+	}
+}
+
+func TestClientSerialIndex(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var index, err = client.SerialIndex(ctx, time.Now().Add(-time.Hour), time.Now())
+	if err != nil {
+		t.Fatalf("couldn't build serial index: %v", err)
+	}
+
+	// The mock HVCA server returns the same certificate for every serial
+	// number.
+	if got := index.Len(); got != 1 {
+		t.Errorf("got length %d, want 1", got)
+	}
+
+	var sum1 = sha1.Sum(hvcatest.Cert.Raw)
+	var sum256 = sha256.Sum256(hvcatest.Cert.Raw)
+
+	for _, fp := range []hvclient.Fingerprint{sum1[:], sum256[:]} {
+		if _, ok := index.Lookup(fp); !ok {
+			t.Errorf("fingerprint %s not found", fp)
+		}
+	}
+
+	if _, ok := index.Lookup(hvclient.Fingerprint{0x01, 0x02}); ok {
+		t.Errorf("unexpectedly found invalid fingerprint")
+	}
+}
+
+func TestSerialIndexAdd(t *testing.T) {
+	t.Parallel()
+
+	var index hvclient.SerialIndex
+	var cert = testhelpers.MustGetCertFromFile(t, "testdata/test_cert.pem")
+
+	index.Add(cert.Raw, cert.SerialNumber)
+	index.Add(cert.Raw, cert.SerialNumber)
+
+	if got := index.Len(); got != 1 {
+		t.Errorf("got length %d, want 1", got)
+	}
+
+	var sum = sha256.Sum256(cert.Raw)
+
+	var got, ok = index.Lookup(sum[:])
+	if !ok || got.Cmp(cert.SerialNumber) != 0 {
+		t.Errorf("got serial number %X, %t, want %X, true", got, ok, cert.SerialNumber)
+	}
+}
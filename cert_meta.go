@@ -16,24 +16,33 @@ limitations under the License.
 package hvclient
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"sync"
 	"time"
 )
 
-// CertMeta contains certificate metadata.
+// CertMeta contains certificate metadata. HVCA lists only the serial
+// number and validity period of each certificate, so the subject common name
+// and SAN DNS names are empty unless they have been retrieved with
+// Client.CertMetaDetails.
 type CertMeta struct {
 	SerialNumber *big.Int  // Certificate serial number
 	NotBefore    time.Time // Certificate not valid before this time
 	NotAfter     time.Time // Certificate not valid after this time
+	CommonName   string    // Certificate subject common name
+	DNSNames     []string  // Certificate SAN DNS names
 }
 
 // jsonCertMeta is used internally for JSON marshalling/unmarshalling.
 type jsonCertMeta struct {
-	SerialNumber string `json:"serial_number"`
-	NotBefore    int64  `json:"not_before"`
-	NotAfter     int64  `json:"not_after"`
+	SerialNumber string   `json:"serial_number"`
+	NotBefore    int64    `json:"not_before"`
+	NotAfter     int64    `json:"not_after"`
+	CommonName   string   `json:"common_name,omitempty"`
+	DNSNames     []string `json:"dns_names,omitempty"`
 }
 
 // Equal checks if two certificate metadata objects are equivalent.
@@ -46,16 +55,29 @@ func (c CertMeta) Equal(other CertMeta) bool {
 		return false
 	}
 
+	if len(c.DNSNames) != len(other.DNSNames) {
+		return false
+	}
+
+	for i := range c.DNSNames {
+		if c.DNSNames[i] != other.DNSNames[i] {
+			return false
+		}
+	}
+
 	return c.NotBefore.Equal(other.NotBefore) &&
-		c.NotAfter.Equal(other.NotAfter)
+		c.NotAfter.Equal(other.NotAfter) &&
+		c.CommonName == other.CommonName
 }
 
 // MarshalJSON returns the JSON encoding of a certificate metadata object.
 func (c CertMeta) MarshalJSON() ([]byte, error) {
 	return json.Marshal(jsonCertMeta{
-		SerialNumber: fmt.Sprintf("%X", c.SerialNumber),
+		SerialNumber: NewSerialNumber(c.SerialNumber).Hex(),
 		NotBefore:    c.NotBefore.Unix(),
 		NotAfter:     c.NotAfter.Unix(),
+		CommonName:   c.CommonName,
+		DNSNames:     c.DNSNames,
 	})
 }
 
@@ -67,16 +89,104 @@ func (c *CertMeta) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	var sn, ok = big.NewInt(0).SetString(data.SerialNumber, 16)
-	if !ok {
-		return fmt.Errorf("invalid serial number: %s", data.SerialNumber)
+	var sn, err = ParseSerialNumber(data.SerialNumber)
+	if err != nil {
+		return err
 	}
 
 	*c = CertMeta{
-		SerialNumber: sn,
+		SerialNumber: sn.Big(),
 		NotBefore:    time.Unix(data.NotBefore, 0).UTC(),
 		NotAfter:     time.Unix(data.NotAfter, 0).UTC(),
+		CommonName:   data.CommonName,
+		DNSNames:     data.DNSNames,
 	}
 
 	return nil
 }
+
+// CertMetaDetails retrieves each of the listed certificates from HVCA, and
+// fills in the subject common name and SAN DNS names of its metadata. At
+// most the specified number of certificates are retrieved concurrently, or
+// one at a time if the number is less than one. Each retrieval is reported
+// to any progress reporter in the client configuration. The first error
+// encountered is returned, in which case the remaining certificates may not
+// have been retrieved.
+func (c *Client) CertMetaDetails(ctx context.Context, metas []CertMeta, concurrency int) error {
+	var serials = make([]*big.Int, len(metas))
+	for i := range metas {
+		serials[i] = metas[i].SerialNumber
+	}
+
+	return c.retrieveEach(ctx, serials, concurrency, func(i int, info *CertInfo) {
+		metas[i].CommonName = info.X509.Subject.CommonName
+		metas[i].DNSNames = info.X509.DNSNames
+	})
+}
+
+// retrieveEach retrieves each of the certificates with the specified serial
+// numbers from HVCA, and calls a function with the index and details of
+// each. At most the specified number of certificates are retrieved
+// concurrently, or one at a time if the number is less than one, so the
+// function may be called concurrently for different indices. Each retrieval
+// is reported to any progress reporter in the client configuration. The
+// first error encountered is returned, in which case the remaining
+// certificates may not have been retrieved.
+func (c *Client) retrieveEach(ctx context.Context, serials []*big.Int, concurrency int, fn func(int, *CertInfo)) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+
+	var progress = newProgressTracker(c.config.Progress, len(serials))
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	var sem = make(chan struct{}, concurrency)
+
+	for i := range serials {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var info, err = c.CertificateRetrieve(ctx, serials[i])
+			if err == nil {
+				fn(i, info)
+			} else {
+				err = fmt.Errorf("couldn't retrieve certificate %X: %w", serials[i], err)
+			}
+
+			progress.done(err)
+
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return ctx.Err()
+}
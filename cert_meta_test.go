@@ -17,6 +17,7 @@ package hvclient_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"math/big"
@@ -24,6 +25,8 @@ import (
 	"time"
 
 	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestCertMetaMarshalJSON(t *testing.T) {
@@ -43,6 +46,18 @@ func TestCertMetaMarshalJSON(t *testing.T) {
 			},
 			want: []byte(`{"serial_number":"1234","not_before":1477958400,"not_after":1478958400}`),
 		},
+		{
+			name: "Details",
+			entry: hvclient.CertMeta{
+				SerialNumber: big.NewInt(0x1234),
+				NotBefore:    time.Unix(1477958400, 0),
+				NotAfter:     time.Unix(1478958400, 0),
+				CommonName:   "example.com",
+				DNSNames:     []string{"example.com", "www.example.com"},
+			},
+			want: []byte(`{"serial_number":"1234","not_before":1477958400,"not_after":1478958400,` +
+				`"common_name":"example.com","dns_names":["example.com","www.example.com"]}`),
+		},
 	}
 
 	for _, tc := range testcases {
@@ -81,6 +96,18 @@ func TestCertMetaUnmarshalJSON(t *testing.T) {
 				NotAfter:     time.Unix(1478958400, 0),
 			},
 		},
+		{
+			name: "Details",
+			json: []byte(`{"serial_number":"1234","not_before":1477958400,"not_after":1478958400,` +
+				`"common_name":"example.com","dns_names":["example.com"]}`),
+			want: hvclient.CertMeta{
+				SerialNumber: big.NewInt(0x1234),
+				NotBefore:    time.Unix(1477958400, 0),
+				NotAfter:     time.Unix(1478958400, 0),
+				CommonName:   "example.com",
+				DNSNames:     []string{"example.com"},
+			},
+		},
 		{
 			name: "BadType",
 			json: []byte(`{"serial_number":1234}`),
@@ -111,3 +138,54 @@ func TestCertMetaUnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestCertMetaDetails(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var metas, _, err = client.StatsIssued(ctx, 1, 100, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("failed to get stats issued: %v", err)
+	}
+
+	if err = client.CertMetaDetails(ctx, metas, 2); err != nil {
+		t.Fatalf("failed to get certificate details: %v", err)
+	}
+
+	for _, meta := range metas {
+		if meta.CommonName != hvcatest.Cert.Subject.CommonName {
+			t.Errorf("%X: got common name %q, want %q", meta.SerialNumber, meta.CommonName, hvcatest.Cert.Subject.CommonName)
+		}
+
+		if !cmp.Equal(meta.DNSNames, hvcatest.Cert.DNSNames) {
+			t.Errorf("%X: got DNS names %q, want %q", meta.SerialNumber, meta.DNSNames, hvcatest.Cert.DNSNames)
+		}
+	}
+}
+
+func TestCertMetaDetailsFailure(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var metas = []hvclient.CertMeta{
+		{SerialNumber: big.NewInt(0x1234)},
+		{SerialNumber: hvcatest.SerialNotFound},
+	}
+
+	var err = client.CertMetaDetails(ctx, metas, 0)
+
+	var apiErr hvclient.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got error %v, want %T", err, apiErr)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+)
+
+// WatchCertificate polls HVCA for the status of the certificate with the
+// specified serial number at the specified interval, and calls fn with the
+// status when it is first retrieved and again whenever it changes, so that
+// monitoring agents can detect certificates revoked out of band. It returns
+// nil once the certificate is revoked, since its status cannot change
+// again, or the context's error when the context is cancelled.
+//
+// Errors retrieving the status, such as network failures, are ignored and
+// the status is retrieved again at the next interval, except that an error
+// is returned immediately if HVCA reports that the certificate does not
+// exist.
+func (c *Client) WatchCertificate(
+	ctx context.Context,
+	serial *big.Int,
+	interval time.Duration,
+	fn func(CertStatus),
+) error {
+	if serial == nil {
+		return errors.New("no serial number provided")
+	}
+
+	if interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+
+	var last CertStatus
+
+	for {
+		var info, err = c.CertificateRetrieve(ctx, serial)
+
+		if errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		if err == nil && info.Status != last {
+			last = info.Status
+			fn(last)
+
+			if last == StatusRevoked {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-c.clock().After(interval):
+		}
+	}
+}
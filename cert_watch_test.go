@@ -0,0 +1,153 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWatchCertificate(t *testing.T) {
+	t.Parallel()
+
+	var mtx sync.Mutex
+	var retrievals int
+
+	// Report the certificate as issued for the first two retrievals, fail
+	// the third, and report it as revoked thereafter.
+	var handler = hvcatest.NewHandler()
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, "/certificates/") {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		mtx.Lock()
+		retrievals++
+		var n = retrievals
+		mtx.Unlock()
+
+		if n == 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+
+		var body = rec.Body.Bytes()
+		if n > 3 {
+			body = bytes.Replace(body, []byte(`"ISSUED"`), []byte(`"REVOKED"`), 1)
+		}
+
+		for key, values := range rec.Header() {
+			w.Header()[key] = values
+		}
+
+		w.WriteHeader(rec.Code)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var clnt, err = hvclient.NewClient(ctx, hvcatest.Config(server.URL))
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+
+	var got []hvclient.CertStatus
+	if err = clnt.WatchCertificate(ctx, mockCert.SerialNumber, time.Millisecond, func(status hvclient.CertStatus) {
+		got = append(got, status)
+	}); err != nil {
+		t.Fatalf("couldn't watch certificate: %v", err)
+	}
+
+	var want = []hvclient.CertStatus{hvclient.StatusIssued, hvclient.StatusRevoked}
+	if !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if retrievals != 4 {
+		t.Errorf("got %d retrievals, want 4", retrievals)
+	}
+}
+
+func TestWatchCertificateFailure(t *testing.T) {
+	t.Parallel()
+
+	var clnt, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var called bool
+	var fn = func(hvclient.CertStatus) { called = true }
+
+	var err = clnt.WatchCertificate(ctx, hvcatest.SerialNotFound, time.Millisecond, fn)
+
+	var apiErr hvclient.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got error %v, want not found", err)
+	}
+
+	if err = clnt.WatchCertificate(ctx, mockCert.SerialNumber, 0, fn); err == nil {
+		t.Errorf("unexpectedly watched certificate with zero interval")
+	}
+
+	if called {
+		t.Errorf("callback unexpectedly called")
+	}
+}
+
+func TestWatchCertificateCancel(t *testing.T) {
+	t.Parallel()
+
+	var clnt, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+
+	var got []hvclient.CertStatus
+	var err = clnt.WatchCertificate(ctx, mockCert.SerialNumber, time.Millisecond, func(status hvclient.CertStatus) {
+		got = append(got, status)
+		cancel()
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+
+	if want := []hvclient.CertStatus{hvclient.StatusIssued}; !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,35 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package certmanager adapts HVCA to the signing step of a cert-manager external
+issuer, so that Kubernetes users can obtain certificates from HVCA
+declaratively through cert-manager Certificate and CertificateRequest
+resources.
+
+cert-manager delegates certificates for external issuers to a controller
+which watches CertificateRequest resources referring to its issuer kind. This
+package provides the part of such a controller which does not depend on the
+Kubernetes API: an Issuer which accepts the PEM-encoded PKCS#10 certificate
+signing request, requested duration, usages and CA flag from the
+CertificateRequest spec, and returns the values for its status. A controller
+built with controller-runtime, for example, calls Issuer.Sign from its
+reconcile function for each approved CertificateRequest which has not yet been
+signed, and copies the Certificate and CA fields of the result into the
+status.certificate and status.ca fields of the resource. Errors which wrap
+ErrInvalidRequest should mark the resource as failed, while other errors may
+be retried.
+*/
+package certmanager
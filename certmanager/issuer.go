@@ -0,0 +1,274 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+// Client is the subset of HVCA client functionality used to sign
+// certificate requests. It is satisfied by *hvclient.Client.
+type Client interface {
+	Policy(ctx context.Context) (*hvclient.Policy, error)
+	CertificateRequest(ctx context.Context, req *hvclient.Request) (*big.Int, error)
+	CertificateRetrieve(ctx context.Context, serial *big.Int) (*hvclient.CertInfo, error)
+	TrustChain(ctx context.Context) ([]*x509.Certificate, error)
+}
+
+// CertificateRequest contains the fields of a cert-manager CertificateRequest
+// spec which are used when signing it.
+type CertificateRequest struct {
+	// Request is the PEM-encoded PKCS#10 certificate signing request, from
+	// spec.request.
+	Request []byte
+
+	// Duration is the requested validity duration, from spec.duration. If it
+	// is zero, the maximum duration allowed by the validation policy is
+	// requested.
+	Duration time.Duration
+
+	// Usages are the requested key usages, from spec.usages, such as
+	// "server auth" and "client auth". Usages which correspond to extended
+	// key usages are requested as such, and others are ignored since HVCA
+	// sets key usages according to the validation policy.
+	Usages []string
+
+	// IsCA is the requested CA flag, from spec.isCA. HVCA does not issue CA
+	// certificates, so a request with this flag set is rejected.
+	IsCA bool
+}
+
+// Result contains the values for the status of a signed cert-manager
+// CertificateRequest.
+type Result struct {
+	// Certificate is the PEM-encoded issued certificate followed by any
+	// intermediate CA certificates, for status.certificate.
+	Certificate []byte
+
+	// CA is the PEM-encoded root CA certificate, for status.ca. It is empty
+	// if the trust chain does not include a self-signed root.
+	CA []byte
+
+	// SerialNumber is the serial number of the issued certificate, which
+	// may be recorded in an annotation to allow later revocation.
+	SerialNumber *big.Int
+}
+
+// Config is a configuration object for an issuer.
+type Config struct {
+	// Client is used to request certificates from HVCA, and is usually an
+	// *hvclient.Client. It is required.
+	Client Client
+
+	// NoPolicyDefaults, if true, prevents values which are fixed by the
+	// validation policy, such as static subject attributes, from being
+	// added to requests.
+	NoPolicyDefaults bool
+
+	// Clock, if not nil, is used as the source of the current time for the
+	// not-before time of requested certificates, in place of the system
+	// clock.
+	Clock hvclient.Clock
+}
+
+// Issuer signs cert-manager certificate requests using HVCA. An issuer is
+// safe for concurrent use.
+type Issuer struct {
+	config Config
+}
+
+// ErrInvalidRequest is wrapped by errors returned by Issuer.Sign when the
+// certificate request cannot be signed however often it is retried.
+var ErrInvalidRequest = errors.New("invalid certificate request")
+
+// usageEKUs maps cert-manager key usage names to extended key usage OIDs.
+var usageEKUs = map[string]asn1.ObjectIdentifier{
+	"server auth":      {1, 3, 6, 1, 5, 5, 7, 3, 1},
+	"client auth":      {1, 3, 6, 1, 5, 5, 7, 3, 2},
+	"code signing":     {1, 3, 6, 1, 5, 5, 7, 3, 3},
+	"email protection": {1, 3, 6, 1, 5, 5, 7, 3, 4},
+	"ipsec end system": {1, 3, 6, 1, 5, 5, 7, 3, 5},
+	"ipsec tunnel":     {1, 3, 6, 1, 5, 5, 7, 3, 6},
+	"ipsec user":       {1, 3, 6, 1, 5, 5, 7, 3, 7},
+	"timestamping":     {1, 3, 6, 1, 5, 5, 7, 3, 8},
+	"ocsp signing":     {1, 3, 6, 1, 5, 5, 7, 3, 9},
+	"microsoft sgc":    {1, 3, 6, 1, 4, 1, 311, 10, 3, 3},
+	"netscape sgc":     {2, 16, 840, 1, 113730, 4, 1},
+}
+
+// New returns a new issuer.
+func New(conf Config) (*Issuer, error) {
+	if conf.Client == nil {
+		return nil, errors.New("no client provided")
+	}
+
+	if conf.Clock == nil {
+		conf.Clock = hvclient.SystemClock
+	}
+
+	return &Issuer{config: conf}, nil
+}
+
+// Sign requests a certificate from HVCA for a cert-manager certificate
+// request, and returns the issued certificate and its chain of trust. The
+// subject and subject alternative names are taken from the PKCS#10
+// certificate signing request. The request itself is submitted if the
+// validation policy requires PKCS#10 proof-of-possession, and otherwise only
+// its public key is submitted, after its signature has been checked.
+func (i *Issuer) Sign(ctx context.Context, cr CertificateRequest) (*Result, error) {
+	var csr, err = parseCSR(cr.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	if cr.IsCA {
+		return nil, fmt.Errorf("%w: CA certificates are not supported", ErrInvalidRequest)
+	}
+
+	var pol *hvclient.Policy
+	if pol, err = i.config.Client.Policy(ctx); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve validation policy: %w", err)
+	}
+
+	var req *hvclient.Request
+	if req, err = i.newRequest(csr, cr, pol); err != nil {
+		return nil, err
+	}
+
+	var serial *big.Int
+	if serial, err = i.config.Client.CertificateRequest(ctx, req); err != nil {
+		return nil, fmt.Errorf("couldn't request certificate: %w", err)
+	}
+
+	var info *hvclient.CertInfo
+	if info, err = i.config.Client.CertificateRetrieve(ctx, serial); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve certificate %X: %w", serial, err)
+	}
+
+	if info.X509 == nil {
+		return nil, fmt.Errorf("no certificate returned for serial number %X", serial)
+	}
+
+	var chain []*x509.Certificate
+	if chain, err = i.config.Client.TrustChain(ctx); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve trust chain: %w", err)
+	}
+
+	var result = &Result{
+		Certificate:  []byte(pki.CertToPEMString(info.X509)),
+		SerialNumber: serial,
+	}
+
+	for _, cert := range chain {
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+			result.CA = append(result.CA, pki.CertToPEMString(cert)...)
+		} else {
+			result.Certificate = append(result.Certificate, pki.CertToPEMString(cert)...)
+		}
+	}
+
+	return result, nil
+}
+
+// newRequest builds an HVCA certificate request from a cert-manager
+// certificate request and its parsed PKCS#10 certificate signing request.
+func (i *Issuer) newRequest(csr *x509.CertificateRequest, cr CertificateRequest, pol *hvclient.Policy) (*hvclient.Request, error) {
+	var now = i.config.Clock.Now()
+
+	var req = &hvclient.Request{
+		Validity: &hvclient.Validity{
+			NotBefore: now,
+			NotAfter:  time.Unix(0, 0),
+		},
+	}
+
+	if cr.Duration < 0 {
+		return nil, fmt.Errorf("%w: negative duration %v", ErrInvalidRequest, cr.Duration)
+	} else if cr.Duration > 0 {
+		req.Validity.NotAfter = now.Add(cr.Duration)
+	}
+
+	if len(csr.Subject.Names) > 0 {
+		var dn, err = hvclient.ParseDN(csr.Subject.String())
+		if err != nil {
+			return nil, fmt.Errorf("%w: unsupported subject: %v", ErrInvalidRequest, err)
+		}
+
+		req.Subject = dn
+	}
+
+	if len(csr.DNSNames) > 0 || len(csr.EmailAddresses) > 0 ||
+		len(csr.IPAddresses) > 0 || len(csr.URIs) > 0 {
+		req.SAN = &hvclient.SAN{
+			DNSNames:    csr.DNSNames,
+			Emails:      csr.EmailAddresses,
+			IPAddresses: csr.IPAddresses,
+			URIs:        csr.URIs,
+		}
+	}
+
+	for _, usage := range cr.Usages {
+		if oid, ok := usageEKUs[strings.ToLower(usage)]; ok {
+			req.EKUs = append(req.EKUs, oid)
+		}
+	}
+
+	if pol.PublicKey != nil && pol.PublicKey.KeyFormat == hvclient.PKCS10 {
+		req.CSR = csr
+	} else {
+		req.PublicKey = csr.PublicKey
+	}
+
+	if !i.config.NoPolicyDefaults {
+		if err := req.ApplyPolicyDefaults(pol); err != nil {
+			return nil, fmt.Errorf("couldn't apply validation policy defaults: %w", err)
+		}
+	}
+
+	return req, nil
+}
+
+// parseCSR parses and checks the signature of a PEM-encoded PKCS#10
+// certificate signing request.
+func parseCSR(data []byte) (*x509.CertificateRequest, error) {
+	var block, _ = pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("%w: no PEM-encoded certificate signing request found", ErrInvalidRequest)
+	}
+
+	var csr, err = x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	if err = csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: bad signature: %v", ErrInvalidRequest, err)
+	}
+
+	return csr, nil
+}
@@ -0,0 +1,317 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certmanager_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/certmanager"
+	"github.com/globalsign/hvclient/hvcatest"
+	"github.com/google/go-cmp/cmp"
+)
+
+// recordingClient is a certmanager.Client which records the last
+// certificate request before passing it to an HVCA client.
+type recordingClient struct {
+	*hvclient.Client
+	policy *hvclient.Policy
+	req    *hvclient.Request
+}
+
+func (c *recordingClient) Policy(ctx context.Context) (*hvclient.Policy, error) {
+	if c.policy != nil {
+		return c.policy, nil
+	}
+
+	return c.Client.Policy(ctx)
+}
+
+func (c *recordingClient) CertificateRequest(ctx context.Context, req *hvclient.Request) (*big.Int, error) {
+	c.req = req
+
+	return c.Client.CertificateRequest(ctx, req)
+}
+
+func newRecordingClient(t *testing.T) *recordingClient {
+	t.Helper()
+
+	var server = httptest.NewServer(hvcatest.NewHandler())
+	t.Cleanup(server.Close)
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var clnt, err = hvclient.NewClient(ctx, hvcatest.Config(server.URL))
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+
+	return &recordingClient{Client: clnt}
+}
+
+// mustCreateCSR returns a PEM-encoded PKCS#10 certificate signing request
+// from the specified template, signed with a new key.
+func mustCreateCSR(t *testing.T, tmpl *x509.CertificateRequest) []byte {
+	t.Helper()
+
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("couldn't generate key: %v", err)
+	}
+
+	var der []byte
+	if der, err = x509.CreateCertificateRequest(rand.Reader, tmpl, key); err != nil {
+		t.Fatalf("couldn't create certificate signing request: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func TestIssuerSign(t *testing.T) {
+	t.Parallel()
+
+	var clnt = newRecordingClient(t)
+
+	var now = time.Date(2021, 6, 18, 16, 29, 51, 0, time.UTC)
+	var issuer, err = certmanager.New(certmanager.Config{
+		Client: clnt,
+		Clock:  fakeClock{now},
+	})
+	if err != nil {
+		t.Fatalf("couldn't create issuer: %v", err)
+	}
+
+	var result *certmanager.Result
+	if result, err = issuer.Sign(context.Background(), certmanager.CertificateRequest{
+		Request:  mustCreateCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "JohnDoe"}}),
+		Duration: 24 * time.Hour,
+		Usages:   []string{"digital signature", "client auth"},
+	}); err != nil {
+		t.Fatalf("couldn't sign certificate request: %v", err)
+	}
+
+	if result.SerialNumber.Cmp(hvcatest.Cert.SerialNumber) != 0 {
+		t.Errorf("got serial number %X, want %X", result.SerialNumber, hvcatest.Cert.SerialNumber)
+	}
+
+	// The certificate should be followed by the intermediate, with the root
+	// as the CA.
+	var certs = mustParseCerts(t, result.Certificate)
+	if len(certs) != 2 || !certs[0].Equal(hvcatest.Cert) || !certs[1].Equal(hvcatest.TrustChain[0]) {
+		t.Errorf("got %d certificates, want the issued certificate and intermediate", len(certs))
+	}
+
+	var roots = mustParseCerts(t, result.CA)
+	if len(roots) != 1 || !roots[0].Equal(hvcatest.TrustChain[1]) {
+		t.Errorf("got %d CA certificates, want the root", len(roots))
+	}
+
+	// The mock policy requires PKCS#10 proof-of-possession.
+	if clnt.req.CSR == nil || clnt.req.PublicKey != nil {
+		t.Errorf("got CSR %v and public key %v, want CSR only", clnt.req.CSR, clnt.req.PublicKey)
+	}
+
+	if clnt.req.Subject == nil || clnt.req.Subject.CommonName != "JohnDoe" {
+		t.Errorf("got subject %v, want common name JohnDoe", clnt.req.Subject)
+	}
+
+	var wantEKUs = []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 2}}
+	if !cmp.Equal(clnt.req.EKUs, wantEKUs) {
+		t.Errorf("got EKUs %v, want %v", clnt.req.EKUs, wantEKUs)
+	}
+
+	if want := now.Add(24 * time.Hour); !clnt.req.Validity.NotAfter.Equal(want) {
+		t.Errorf("got not-after time %v, want %v", clnt.req.Validity.NotAfter, want)
+	}
+}
+
+func TestIssuerSignPublicKey(t *testing.T) {
+	t.Parallel()
+
+	var clnt = newRecordingClient(t)
+	clnt.policy = &hvclient.Policy{
+		SubjectDN: &hvclient.SubjectDNPolicy{
+			Organization: &hvclient.StringPolicy{Presence: hvclient.Static, Format: "ACME"},
+		},
+		PublicKey: &hvclient.PublicKeyPolicy{KeyType: hvclient.ECDSA, KeyFormat: hvclient.PKCS8},
+	}
+
+	var issuer, err = certmanager.New(certmanager.Config{Client: clnt})
+	if err != nil {
+		t.Fatalf("couldn't create issuer: %v", err)
+	}
+
+	if _, err = issuer.Sign(context.Background(), certmanager.CertificateRequest{
+		Request: mustCreateCSR(t, &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: "JohnDoe"},
+			DNSNames: []string{"example.com"},
+		}),
+	}); err != nil {
+		t.Fatalf("couldn't sign certificate request: %v", err)
+	}
+
+	if clnt.req.CSR != nil || clnt.req.PublicKey == nil {
+		t.Errorf("got CSR %v and public key %v, want public key only", clnt.req.CSR, clnt.req.PublicKey)
+	}
+
+	if clnt.req.Subject == nil || clnt.req.Subject.Organization != "ACME" {
+		t.Errorf("got subject %v, want static organization from policy", clnt.req.Subject)
+	}
+
+	if clnt.req.SAN == nil || !cmp.Equal(clnt.req.SAN.DNSNames, []string{"example.com"}) {
+		t.Errorf("got SAN %v, want DNS name example.com", clnt.req.SAN)
+	}
+
+	if !clnt.req.Validity.NotAfter.Equal(time.Unix(0, 0)) {
+		t.Errorf("got not-after time %v, want maximum allowed", clnt.req.Validity.NotAfter)
+	}
+}
+
+func TestIssuerSignFailure(t *testing.T) {
+	t.Parallel()
+
+	var csr = mustCreateCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "JohnDoe"}})
+
+	var testcases = []struct {
+		name string
+		cr   certmanager.CertificateRequest
+		err  error
+	}{
+		{
+			name: "NoRequest",
+			err:  certmanager.ErrInvalidRequest,
+		},
+		{
+			name: "NotCSR",
+			cr:   certmanager.CertificateRequest{Request: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1}})},
+			err:  certmanager.ErrInvalidRequest,
+		},
+		{
+			name: "BadSignature",
+			cr:   certmanager.CertificateRequest{Request: mustCorruptCSR(t, csr)},
+			err:  certmanager.ErrInvalidRequest,
+		},
+		{
+			name: "IsCA",
+			cr:   certmanager.CertificateRequest{Request: csr, IsCA: true},
+			err:  certmanager.ErrInvalidRequest,
+		},
+		{
+			name: "NegativeDuration",
+			cr:   certmanager.CertificateRequest{Request: csr, Duration: -time.Hour},
+			err:  certmanager.ErrInvalidRequest,
+		},
+		{
+			name: "Rejected",
+			cr: certmanager.CertificateRequest{
+				Request: mustCreateCSR(t, &x509.CertificateRequest{Subject: pkix.Name{CommonName: hvcatest.TriggerError}}),
+			},
+		},
+	}
+
+	var issuer, err = certmanager.New(certmanager.Config{Client: newRecordingClient(t)})
+	if err != nil {
+		t.Fatalf("couldn't create issuer: %v", err)
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var _, err = issuer.Sign(context.Background(), tc.cr)
+			if err == nil {
+				t.Fatalf("unexpectedly signed certificate request")
+			}
+
+			if errors.Is(err, certmanager.ErrInvalidRequest) != (tc.err != nil) {
+				t.Errorf("got error %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestNewFailure(t *testing.T) {
+	t.Parallel()
+
+	if _, err := certmanager.New(certmanager.Config{}); err == nil {
+		t.Fatalf("unexpectedly created issuer with no client")
+	}
+}
+
+// fakeClock is an hvclient.Clock which always reports the same time.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c fakeClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// mustParseCerts parses a series of PEM-encoded certificates.
+func mustParseCerts(t *testing.T, data []byte) []*x509.Certificate {
+	t.Helper()
+
+	var certs []*x509.Certificate
+
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			return certs
+		}
+
+		var cert, err = x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("couldn't parse certificate: %v", err)
+		}
+
+		certs = append(certs, cert)
+	}
+}
+
+// mustCorruptCSR returns a copy of a PEM-encoded certificate signing request
+// with its signature altered.
+func mustCorruptCSR(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var block, _ = pem.Decode(data)
+	if block == nil {
+		t.Fatalf("couldn't decode certificate signing request")
+	}
+
+	var der = append([]byte(nil), block.Bytes...)
+	der[len(der)-1] ^= 0xff
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
@@ -0,0 +1,27 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package claimmonitor waits for the DNS records for pending domain claims to
+become visible before asserting domain control, so that assertion attempts
+are not used up while DNS changes are still propagating.
+
+A Monitor periodically looks up the TXT record containing each claim's token
+and calls ClaimDNS only once the record can be resolved locally, recording
+the number of checks and assertions made for each claim. HVCA does not
+return the token for an existing claim, so the token returned when the claim
+was submitted or reasserted must be provided.
+*/
+package claimmonitor
@@ -0,0 +1,340 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimmonitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/globalsign/hvclient"
+)
+
+// Claimer is the subset of HVCA client functionality used to assert domain
+// control. It is satisfied by *hvclient.Client.
+type Claimer interface {
+	ClaimDNS(ctx context.Context, id, authDomain string) (bool, error)
+}
+
+// Claim is a pending domain claim to be monitored.
+type Claim struct {
+	// ID is the ID of the domain claim. It is required.
+	ID string
+
+	// Domain is the claimed domain. It is required.
+	Domain string
+
+	// AuthDomain, if not empty, is the authorization domain at which the
+	// TXT record is created, and is passed to ClaimDNS.
+	AuthDomain string
+
+	// Token is the domain claim token which the TXT record must contain.
+	// It is required.
+	Token string
+}
+
+// Attempts records the checks and assertions made for a monitored claim.
+type Attempts struct {
+	Checks      int       // Number of times the TXT record was looked up
+	Assertions  int       // Number of times ClaimDNS was called
+	LastChecked time.Time // When the TXT record was last looked up
+	LastError   error     // The most recent error, if any
+	Verified    bool      // True if HVCA verified the claim
+	Exhausted   bool      // True if the maximum assertions were made without verification
+}
+
+// Config is a configuration object for a claim monitor.
+type Config struct {
+	// Client is used to assert domain control, and is usually an
+	// *hvclient.Client. It is required.
+	Client Claimer
+
+	// Verify checks whether a TXT record containing the token is visible
+	// at the specified domain, returning an error wrapping
+	// hvclient.ErrClaimTokenNotVisible if it is not. If this is nil,
+	// hvclient.ClaimVerifyDNSLocally is used.
+	Verify func(ctx context.Context, domain, token string) error
+
+	// Interval is the period between checks when the monitor is run. If
+	// this is omitted or set to zero, a default of one minute is used.
+	Interval time.Duration
+
+	// MaxAssertions is the maximum number of times ClaimDNS is called for
+	// each claim. If this is omitted or set to zero, there is no maximum.
+	MaxAssertions int
+
+	// OnVerified, if not nil, is called with the ID of each claim when HVCA
+	// verifies it while the monitor is run.
+	OnVerified func(id string)
+
+	// OnError, if not nil, is called with any error encountered when
+	// checking or asserting a claim while the monitor is run. It is not
+	// called while a TXT record is merely not yet visible.
+	OnError func(id string, err error)
+
+	// Clock, if not nil, is used as the source of the current time and of
+	// the timer between checks, in place of the system clock.
+	Clock hvclient.Clock
+}
+
+// Monitor monitors pending domain claims and asserts domain control for
+// each one once its TXT record is visible. A monitor is safe for concurrent
+// use.
+type Monitor struct {
+	config Config
+	mtx    sync.Mutex
+	claims map[string]*entry
+}
+
+// entry is a monitored claim and the attempts made for it.
+type entry struct {
+	claim    Claim
+	attempts Attempts
+}
+
+// ErrMaxAssertions is wrapped by the error reported when the maximum number
+// of assertions has been made for a claim without HVCA verifying it.
+var ErrMaxAssertions = errors.New("maximum domain control assertions made")
+
+// defaultInterval is the period between checks if none is specified in the
+// configuration.
+var defaultInterval = time.Minute
+
+// New returns a new claim monitor with no monitored claims.
+func New(conf Config) (*Monitor, error) {
+	if conf.Client == nil {
+		return nil, errors.New("no client provided")
+	}
+
+	if conf.Verify == nil {
+		conf.Verify = hvclient.ClaimVerifyDNSLocally
+	}
+
+	if conf.Clock == nil {
+		conf.Clock = hvclient.SystemClock
+	}
+
+	if conf.Interval == 0 {
+		conf.Interval = defaultInterval
+	} else if conf.Interval < 0 {
+		return nil, errors.New("interval cannot be negative")
+	}
+
+	if conf.MaxAssertions < 0 {
+		return nil, errors.New("maximum assertions cannot be negative")
+	}
+
+	return &Monitor{
+		config: conf,
+		claims: make(map[string]*entry),
+	}, nil
+}
+
+// Add monitors a domain claim, replacing any claim already monitored with
+// the same ID and resetting its attempts.
+func (m *Monitor) Add(claim Claim) error {
+	switch {
+	case claim.ID == "":
+		return errors.New("no claim ID provided")
+
+	case claim.Domain == "":
+		return errors.New("no domain provided")
+
+	case claim.Token == "":
+		return errors.New("no token provided")
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.claims[claim.ID] = &entry{claim: claim}
+
+	return nil
+}
+
+// Remove stops monitoring the claim with the specified ID.
+func (m *Monitor) Remove(id string) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	delete(m.claims, id)
+}
+
+// Attempts returns the attempts made for the claim with the specified ID,
+// and false if no such claim is monitored.
+func (m *Monitor) Attempts(id string) (Attempts, bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var e, ok = m.claims[id]
+	if !ok {
+		return Attempts{}, false
+	}
+
+	return e.attempts, true
+}
+
+// Pending returns the IDs of the monitored claims, in order, which have
+// been neither verified nor exhausted.
+func (m *Monitor) Pending() []string {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var ids []string
+	for id, e := range m.claims {
+		if !e.attempts.done() {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Check checks each pending claim once, in order of ID, and asserts domain
+// control for any whose TXT record is visible. The first error encountered
+// is returned after all claims have been checked. A TXT record which is not
+// yet visible is not an error.
+func (m *Monitor) Check(ctx context.Context) error {
+	var first error
+
+	m.check(ctx, func(id string, err error) {
+		if first == nil {
+			first = fmt.Errorf("%s: %w", id, err)
+		}
+	})
+
+	return first
+}
+
+// Run checks the pending claims immediately and then at the configured
+// interval until none remain pending, at which point nil is returned, or
+// until the context is cancelled, at which point the context's error is
+// returned. Errors encountered when checking or asserting claims are passed
+// to the configured error function, if any.
+func (m *Monitor) Run(ctx context.Context) error {
+	var onError = m.config.OnError
+	if onError == nil {
+		onError = func(string, error) {}
+	}
+
+	for {
+		m.check(ctx, onError)
+
+		if len(m.Pending()) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-m.config.Clock.After(m.config.Interval):
+		}
+	}
+}
+
+// check checks each pending claim once.
+func (m *Monitor) check(ctx context.Context, onError func(string, error)) {
+	for _, id := range m.Pending() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		m.mtx.Lock()
+		var e, ok = m.claims[id]
+		m.mtx.Unlock()
+
+		if !ok {
+			continue
+		}
+
+		var verified, err = m.checkClaim(ctx, e)
+		if err != nil {
+			onError(id, err)
+		} else if verified && m.config.OnVerified != nil {
+			m.config.OnVerified(id)
+		}
+	}
+}
+
+// checkClaim looks up the TXT record for a single claim, and asserts domain
+// control if it is visible. It returns true if HVCA verified the claim.
+func (m *Monitor) checkClaim(ctx context.Context, e *entry) (bool, error) {
+	m.mtx.Lock()
+	var claim = e.claim
+	e.attempts.Checks++
+	e.attempts.LastChecked = m.config.Clock.Now()
+	m.mtx.Unlock()
+
+	var domain = claim.Domain
+	if claim.AuthDomain != "" {
+		domain = claim.AuthDomain
+	}
+
+	var err = m.config.Verify(ctx, domain, claim.Token)
+	if err != nil {
+		m.record(e, func(a *Attempts) { a.LastError = err })
+
+		// Keep waiting for the record to propagate.
+		if errors.Is(err, hvclient.ErrClaimTokenNotVisible) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	var verified bool
+	verified, err = m.config.Client.ClaimDNS(ctx, claim.ID, claim.AuthDomain)
+	if err != nil {
+		err = fmt.Errorf("couldn't assert domain control: %w", err)
+	} else if !verified {
+		err = errors.New("domain control not verified by HVCA")
+	}
+
+	var exhausted bool
+
+	m.record(e, func(a *Attempts) {
+		a.Assertions++
+		a.LastError = err
+		a.Verified = verified
+		a.Exhausted = !verified && m.config.MaxAssertions > 0 && a.Assertions >= m.config.MaxAssertions
+		exhausted = a.Exhausted
+	})
+
+	if exhausted {
+		return false, fmt.Errorf("%w: %v", ErrMaxAssertions, err)
+	}
+
+	return verified, err
+}
+
+// record updates the attempts for a claim.
+func (m *Monitor) record(e *entry, fn func(*Attempts)) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	fn(&e.attempts)
+}
+
+// done returns true if no further attempts will be made for a claim.
+func (a Attempts) done() bool {
+	return a.Verified || a.Exhausted
+}
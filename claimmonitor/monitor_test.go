@@ -0,0 +1,337 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package claimmonitor_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/claimmonitor"
+	"github.com/google/go-cmp/cmp"
+)
+
+// claimRecorder is a Claimer which records the IDs and authorization
+// domains of the claims it is asked to assert, and verifies each claim once
+// it has been asserted the number of times in verifyAfter.
+type claimRecorder struct {
+	mtx         sync.Mutex
+	asserted    []string
+	verifyAfter map[string]int
+	fail        map[string]bool
+}
+
+func (r *claimRecorder) ClaimDNS(_ context.Context, id, authDomain string) (bool, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.fail[id] {
+		return false, errors.New("assertion failed")
+	}
+
+	r.asserted = append(r.asserted, id+":"+authDomain)
+
+	var count int
+	for _, a := range r.asserted {
+		if a == id+":"+authDomain {
+			count++
+		}
+	}
+
+	return count >= r.verifyAfter[id], nil
+}
+
+// fakeDNS is a verification function which treats the tokens in visible
+// as visible at their domains.
+type fakeDNS struct {
+	mtx     sync.Mutex
+	visible map[string]string
+	err     error
+}
+
+func (d *fakeDNS) verify(_ context.Context, domain, token string) error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.err != nil {
+		return d.err
+	}
+
+	if d.visible[domain] != token {
+		return fmt.Errorf("%w: no TXT records found for %s", hvclient.ErrClaimTokenNotVisible, domain)
+	}
+
+	return nil
+}
+
+func (d *fakeDNS) publish(domain, token string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.visible[domain] = token
+}
+
+func newTestMonitor(t *testing.T, clnt claimmonitor.Claimer, dns *fakeDNS, max int) *claimmonitor.Monitor {
+	t.Helper()
+
+	var m, err = claimmonitor.New(claimmonitor.Config{
+		Client:        clnt,
+		Verify:        dns.verify,
+		Interval:      time.Millisecond * 5,
+		MaxAssertions: max,
+	})
+	if err != nil {
+		t.Fatalf("couldn't create monitor: %v", err)
+	}
+
+	return m
+}
+
+func TestNewFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		conf claimmonitor.Config
+	}{
+		{
+			name: "NoClient",
+			conf: claimmonitor.Config{},
+		},
+		{
+			name: "NegativeInterval",
+			conf: claimmonitor.Config{Client: &claimRecorder{}, Interval: -time.Second},
+		},
+		{
+			name: "NegativeMaxAssertions",
+			conf: claimmonitor.Config{Client: &claimRecorder{}, MaxAssertions: -1},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := claimmonitor.New(tc.conf); err == nil {
+				t.Fatalf("unexpectedly created monitor")
+			}
+		})
+	}
+}
+
+func TestMonitorAddFailure(t *testing.T) {
+	t.Parallel()
+
+	var m = newTestMonitor(t, &claimRecorder{}, &fakeDNS{}, 0)
+
+	for _, claim := range []claimmonitor.Claim{
+		{Domain: "example.com", Token: "token"},
+		{ID: "1", Token: "token"},
+		{ID: "1", Domain: "example.com"},
+	} {
+		if err := m.Add(claim); err == nil {
+			t.Errorf("unexpectedly added claim %+v", claim)
+		}
+	}
+}
+
+func TestMonitorCheck(t *testing.T) {
+	t.Parallel()
+
+	var recorder = &claimRecorder{verifyAfter: map[string]int{"1": 1, "2": 1}}
+	var dns = &fakeDNS{visible: map[string]string{}}
+	var m = newTestMonitor(t, recorder, dns, 0)
+
+	for _, claim := range []claimmonitor.Claim{
+		{ID: "1", Domain: "example.com", Token: "token1"},
+		{ID: "2", Domain: "example.org", AuthDomain: "auth.example.org", Token: "token2"},
+	} {
+		if err := m.Add(claim); err != nil {
+			t.Fatalf("couldn't add claim: %v", err)
+		}
+	}
+
+	// Nothing should be asserted while no records are visible.
+	if err := m.Check(context.Background()); err != nil {
+		t.Fatalf("couldn't check claims: %v", err)
+	}
+
+	if len(recorder.asserted) != 0 {
+		t.Fatalf("got asserted %v, want none", recorder.asserted)
+	}
+
+	// Only the claim whose record has propagated should be asserted, and
+	// at its authorization domain.
+	dns.publish("auth.example.org", "token2")
+
+	if err := m.Check(context.Background()); err != nil {
+		t.Fatalf("couldn't check claims: %v", err)
+	}
+
+	if want := []string{"2:auth.example.org"}; !cmp.Equal(recorder.asserted, want) {
+		t.Errorf("got asserted %v, want %v", recorder.asserted, want)
+	}
+
+	if want := []string{"1"}; !cmp.Equal(m.Pending(), want) {
+		t.Errorf("got pending %v, want %v", m.Pending(), want)
+	}
+
+	var attempts, ok = m.Attempts("2")
+	if !ok {
+		t.Fatalf("no attempts recorded")
+	}
+
+	if attempts.Checks != 2 || attempts.Assertions != 1 || !attempts.Verified || attempts.LastError != nil {
+		t.Errorf("got attempts %+v, want 2 checks, 1 assertion and verified", attempts)
+	}
+
+	if attempts, _ = m.Attempts("1"); attempts.Checks != 2 || attempts.Assertions != 0 ||
+		!errors.Is(attempts.LastError, hvclient.ErrClaimTokenNotVisible) {
+		t.Errorf("got attempts %+v, want 2 checks, no assertions and token not visible", attempts)
+	}
+
+	if _, ok = m.Attempts("3"); ok {
+		t.Errorf("unexpectedly found attempts for unknown claim")
+	}
+}
+
+func TestMonitorCheckFailure(t *testing.T) {
+	t.Parallel()
+
+	var recorder = &claimRecorder{fail: map[string]bool{"1": true}}
+	var dns = &fakeDNS{visible: map[string]string{"example.com": "token"}}
+	var m = newTestMonitor(t, recorder, dns, 0)
+
+	if err := m.Add(claimmonitor.Claim{ID: "1", Domain: "example.com", Token: "token"}); err != nil {
+		t.Fatalf("couldn't add claim: %v", err)
+	}
+
+	if err := m.Check(context.Background()); err == nil {
+		t.Errorf("unexpectedly checked claims")
+	}
+
+	// DNS failures other than the token not being visible are errors.
+	dns.err = errors.New("resolver unavailable")
+
+	if err := m.Check(context.Background()); !errors.Is(err, dns.err) {
+		t.Errorf("got error %v, want %v", err, dns.err)
+	}
+
+	if want := []string{"1"}; !cmp.Equal(m.Pending(), want) {
+		t.Errorf("got pending %v, want %v", m.Pending(), want)
+	}
+}
+
+func TestMonitorMaxAssertions(t *testing.T) {
+	t.Parallel()
+
+	var recorder = &claimRecorder{verifyAfter: map[string]int{"1": 5}}
+	var dns = &fakeDNS{visible: map[string]string{"example.com": "token"}}
+	var m = newTestMonitor(t, recorder, dns, 2)
+
+	if err := m.Add(claimmonitor.Claim{ID: "1", Domain: "example.com", Token: "token"}); err != nil {
+		t.Fatalf("couldn't add claim: %v", err)
+	}
+
+	if err := m.Check(context.Background()); err == nil || errors.Is(err, claimmonitor.ErrMaxAssertions) {
+		t.Fatalf("got error %v, want unverified claim", err)
+	}
+
+	if err := m.Check(context.Background()); !errors.Is(err, claimmonitor.ErrMaxAssertions) {
+		t.Fatalf("got error %v, want %v", err, claimmonitor.ErrMaxAssertions)
+	}
+
+	// An exhausted claim is no longer checked.
+	if err := m.Check(context.Background()); err != nil {
+		t.Fatalf("couldn't check claims: %v", err)
+	}
+
+	if len(recorder.asserted) != 2 {
+		t.Errorf("got %d assertions, want 2", len(recorder.asserted))
+	}
+
+	var attempts, _ = m.Attempts("1")
+	if !attempts.Exhausted || attempts.Verified {
+		t.Errorf("got attempts %+v, want exhausted", attempts)
+	}
+
+	if len(m.Pending()) != 0 {
+		t.Errorf("got pending %v, want none", m.Pending())
+	}
+}
+
+func TestMonitorRun(t *testing.T) {
+	t.Parallel()
+
+	var recorder = &claimRecorder{verifyAfter: map[string]int{"1": 1}}
+	var dns = &fakeDNS{visible: map[string]string{}}
+
+	var verified []string
+	var m, err = claimmonitor.New(claimmonitor.Config{
+		Client:     recorder,
+		Verify:     dns.verify,
+		Interval:   time.Millisecond * 5,
+		OnVerified: func(id string) { verified = append(verified, id) },
+	})
+	if err != nil {
+		t.Fatalf("couldn't create monitor: %v", err)
+	}
+
+	if err = m.Add(claimmonitor.Claim{ID: "1", Domain: "example.com", Token: "token"}); err != nil {
+		t.Fatalf("couldn't add claim: %v", err)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	time.AfterFunc(time.Millisecond*20, func() { dns.publish("example.com", "token") })
+
+	if err = m.Run(ctx); err != nil {
+		t.Fatalf("couldn't run monitor: %v", err)
+	}
+
+	if want := []string{"1"}; !cmp.Equal(verified, want) {
+		t.Errorf("got verified %v, want %v", verified, want)
+	}
+
+	var attempts, _ = m.Attempts("1")
+	if attempts.Checks < 2 || attempts.Assertions != 1 {
+		t.Errorf("got attempts %+v, want at least 2 checks and 1 assertion", attempts)
+	}
+}
+
+func TestMonitorRunCancelled(t *testing.T) {
+	t.Parallel()
+
+	var m = newTestMonitor(t, &claimRecorder{}, &fakeDNS{visible: map[string]string{}}, 0)
+
+	if err := m.Add(claimmonitor.Claim{ID: "1", Domain: "example.com", Token: "token"}); err != nil {
+		t.Fatalf("couldn't add claim: %v", err)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*30)
+	defer cancel()
+
+	if err := m.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
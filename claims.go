@@ -85,6 +85,10 @@ const (
 	StatusVerified
 )
 
+// StatusAll may be passed to ClaimsDomains to list domain claims regardless
+// of their status. It is never the status of a claim itself.
+const StatusAll ClaimStatus = -1
+
 // Claim log entry status constants.
 const (
 	VerificationSuccess ClaimLogEntryStatus = iota + 1
@@ -120,6 +124,9 @@ var claimLogEntryStatusCodes = map[string]ClaimLogEntryStatus{
 	"INFO":    VerificationInfo,
 }
 
+// claimStatusAllName is the description of StatusAll.
+const claimStatusAllName = "ALL"
+
 // isValid checks if a claims status value is within a valid range.
 func (s ClaimStatus) isValid() bool {
 	return s >= StatusPending && s <= StatusVerified
@@ -127,6 +134,10 @@ func (s ClaimStatus) isValid() bool {
 
 // String returns a description of the claim status.
 func (s ClaimStatus) String() string {
+	if s == StatusAll {
+		return claimStatusAllName
+	}
+
 	if !s.isValid() {
 		return "ERROR: UNKNOWN STATUS"
 	}
@@ -134,9 +145,24 @@ func (s ClaimStatus) String() string {
 	return claimStatusNames[s]
 }
 
+// ParseClaimStatus parses a case-insensitive claim status description, as
+// returned by the String method, such as "verified", "pending" or "all".
+func ParseClaimStatus(s string) (ClaimStatus, error) {
+	if strings.EqualFold(s, claimStatusAllName) {
+		return StatusAll, nil
+	}
+
+	var result, ok = claimStatusCodes[strings.ToUpper(s)]
+	if !ok {
+		return 0, fmt.Errorf("invalid claim status value: %s", s)
+	}
+
+	return result, nil
+}
+
 // MarshalJSON returns the JSON encoding of a claim status value.
 func (s ClaimStatus) MarshalJSON() ([]byte, error) {
-	if !s.isValid() {
+	if !s.isValid() && s != StatusAll {
 		return nil, fmt.Errorf("invalid claim status value: %d", s)
 	}
 
@@ -152,9 +178,9 @@ func (s *ClaimStatus) UnmarshalJSON(b []byte) error {
 		return err
 	}
 
-	var result, ok = claimStatusCodes[strings.ToUpper(data)]
-	if !ok {
-		return fmt.Errorf("invalid claim status value: %s", data)
+	var result ClaimStatus
+	if result, err = ParseClaimStatus(data); err != nil {
+		return err
 	}
 
 	*s = result
@@ -295,6 +321,21 @@ func (l *ClaimLogEntry) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// DNSRecord returns the owner name, type and value of the DNS record which
+// should be created to assert control of the specified domain (or of the
+// authorization domain, if one will be specified when calling ClaimDNS)
+// using the DNS validation method. The owner name is fully qualified, with
+// a trailing dot, and any leading wildcard label is removed.
+func (c ClaimAssertionInfo) DNSRecord(domain string) (name, rrtype, value string) {
+	name = DomainToASCII(strings.TrimPrefix(strings.TrimSpace(domain), "*."))
+
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	return name, "TXT", c.Token
+}
+
 // Equal checks if two domain claim assertion info objects are equivalent.
 func (c ClaimAssertionInfo) Equal(other ClaimAssertionInfo) bool {
 	return c.Token == other.Token &&
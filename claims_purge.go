@@ -0,0 +1,117 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ClaimsPurge deletes the domain claims with any of the specified statuses
+// which were created more than olderThan ago, and returns the claims which
+// were deleted. If no statuses are specified, only pending claims are
+// purged, and StatusAll selects claims regardless of their status. Every
+// page of claims is first retrieved, as described for ClaimsDomainsAll, and
+// the selected claims are then deleted one at a time, with each deletion
+// reported to the progress reporter in the configuration, if any. Deletion
+// stops at the first error or when the context is cancelled, in which case
+// the claims deleted so far are returned along with the error.
+func (c *Client) ClaimsPurge(ctx context.Context, olderThan time.Duration, statuses ...ClaimStatus) ([]Claim, error) {
+	var stale, err = c.staleClaims(ctx, olderThan, statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	var progress = newProgressTracker(c.config.Progress, len(stale))
+	var deleted = make([]Claim, 0, len(stale))
+
+	for _, claim := range stale {
+		if err = ctx.Err(); err != nil {
+			return deleted, err
+		}
+
+		err = c.ClaimDelete(ctx, claim.ID)
+		progress.done(err)
+
+		if err != nil {
+			return deleted, fmt.Errorf("couldn't delete domain claim %s after deleting %d of %d: %w",
+				claim.ID, len(deleted), len(stale), err)
+		}
+
+		deleted = append(deleted, claim)
+	}
+
+	return deleted, nil
+}
+
+// ClaimsPurgeDryRun returns the domain claims which ClaimsPurge would delete
+// with the same arguments, without deleting them.
+func (c *Client) ClaimsPurgeDryRun(ctx context.Context, olderThan time.Duration, statuses ...ClaimStatus) ([]Claim, error) {
+	return c.staleClaims(ctx, olderThan, statuses)
+}
+
+// staleClaims returns the domain claims with any of the specified statuses
+// which were created more than olderThan ago.
+func (c *Client) staleClaims(ctx context.Context, olderThan time.Duration, statuses []ClaimStatus) ([]Claim, error) {
+	if olderThan < 0 {
+		return nil, errors.New("age cannot be negative")
+	}
+
+	if len(statuses) == 0 {
+		statuses = []ClaimStatus{StatusPending}
+	}
+
+	// List claims of a single status if only one is selected, and otherwise
+	// list all claims and select them by status afterwards.
+	var listStatus = statuses[0]
+	var selected = make(map[ClaimStatus]bool, len(statuses))
+
+	for _, status := range statuses {
+		if status != StatusAll && !status.isValid() {
+			return nil, fmt.Errorf("invalid claim status: %d", status)
+		}
+
+		if status != listStatus {
+			listStatus = StatusAll
+		}
+
+		selected[status] = true
+	}
+
+	var claims, err = c.ClaimsDomainsAll(ctx, listStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	var cutoff = c.now().Add(-olderThan)
+	var stale []Claim
+
+	for _, claim := range claims {
+		if !claim.CreatedAt.Before(cutoff) {
+			continue
+		}
+
+		if !selected[StatusAll] && !selected[claim.Status] {
+			continue
+		}
+
+		stale = append(stale, claim)
+	}
+
+	return stale, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClientMockClaimsPurge(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name      string
+		olderThan time.Duration
+		statuses  []hvclient.ClaimStatus
+		want      []string
+	}{
+		{
+			name:      "DefaultPending",
+			olderThan: 24 * time.Hour,
+			want:      []string{"pending1", "pending2"},
+		},
+		{
+			name:      "Verified",
+			olderThan: 24 * time.Hour,
+			statuses:  []hvclient.ClaimStatus{hvclient.StatusVerified},
+			want:      []string{mockClaimID},
+		},
+		{
+			name:      "Multiple",
+			olderThan: 24 * time.Hour,
+			statuses:  []hvclient.ClaimStatus{hvclient.StatusPending, hvclient.StatusVerified},
+			want:      []string{mockClaimID, "pending1", "pending2"},
+		},
+		{
+			name:      "All",
+			olderThan: 24 * time.Hour,
+			statuses:  []hvclient.ClaimStatus{hvclient.StatusAll},
+			want:      []string{mockClaimID, "pending1", "pending2"},
+		},
+		{
+			name:      "TooRecent",
+			olderThan: 100 * 365 * 24 * time.Hour,
+			statuses:  []hvclient.ClaimStatus{hvclient.StatusAll},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var client, closefunc = newMockClient(t)
+			defer closefunc()
+
+			var ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+
+			var dry, err = client.ClaimsPurgeDryRun(ctx, tc.olderThan, tc.statuses...)
+			if err != nil {
+				t.Fatalf("failed to list stale claims: %v", err)
+			}
+
+			if got := claimIDs(dry); !cmp.Equal(got, tc.want) {
+				t.Errorf("got dry run claims %q, want %q", got, tc.want)
+			}
+
+			var deleted []hvclient.Claim
+			if deleted, err = client.ClaimsPurge(ctx, tc.olderThan, tc.statuses...); err != nil {
+				t.Fatalf("failed to purge claims: %v", err)
+			}
+
+			if got := claimIDs(deleted); !cmp.Equal(got, tc.want) {
+				t.Errorf("got deleted claims %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClientMockClaimsPurgeFailure(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := client.ClaimsPurge(ctx, -time.Hour); err == nil {
+		t.Errorf("unexpectedly purged claims with negative age")
+	}
+
+	if _, err := client.ClaimsPurge(ctx, time.Hour, hvclient.ClaimStatus(99)); err == nil {
+		t.Errorf("unexpectedly purged claims with invalid status")
+	}
+}
+
+// claimIDs returns the IDs of a list of domain claims.
+func claimIDs(claims []hvclient.Claim) []string {
+	var ids []string
+	for _, claim := range claims {
+		ids = append(ids, claim.ID)
+	}
+
+	return ids
+}
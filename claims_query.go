@@ -0,0 +1,171 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ClaimsQuery specifies the domain claims returned by ClaimsDomainsQuery.
+type ClaimsQuery struct {
+	// Status selects claims with the specified status. If this is omitted,
+	// claims are listed regardless of their status.
+	Status ClaimStatus
+
+	// Domain, if not empty, selects only claims for the specified domain.
+	// Domain names are compared without regard to case or to any trailing
+	// dot.
+	Domain string
+
+	// IncludeSubdomains, if true, also selects claims for subdomains of
+	// Domain.
+	IncludeSubdomains bool
+
+	// SortBy is the field by which the claims are sorted. If this is
+	// omitted, claims are returned in the order in which HVCA lists them.
+	SortBy ClaimSortField
+
+	// Descending, if true, sorts the claims in descending rather than
+	// ascending order.
+	Descending bool
+}
+
+// ClaimSortField is a field by which domain claims may be sorted.
+type ClaimSortField int
+
+// Domain claim sort field constants.
+const (
+	SortByDomain ClaimSortField = iota + 1
+	SortByCreatedAt
+	SortByExpiresAt
+	SortByAssertBy
+)
+
+// claimSortFieldNames maps claim sort field values to their descriptions.
+var claimSortFieldNames = [...]string{
+	SortByDomain:    "domain",
+	SortByCreatedAt: "created",
+	SortByExpiresAt: "expires",
+	SortByAssertBy:  "assertby",
+}
+
+// claimSortFieldCodes maps claim sort field descriptions to their values.
+var claimSortFieldCodes = map[string]ClaimSortField{
+	"domain":   SortByDomain,
+	"created":  SortByCreatedAt,
+	"expires":  SortByExpiresAt,
+	"assertby": SortByAssertBy,
+}
+
+// String returns a description of the claim sort field.
+func (f ClaimSortField) String() string {
+	if f < SortByDomain || f > SortByAssertBy {
+		return fmt.Sprintf("UNKNOWN CLAIM SORT FIELD (%d)", f)
+	}
+
+	return claimSortFieldNames[f]
+}
+
+// ParseClaimSortField parses a case-insensitive claim sort field
+// description, as returned by the String method, such as "domain" or
+// "created".
+func ParseClaimSortField(s string) (ClaimSortField, error) {
+	var result, ok = claimSortFieldCodes[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("invalid claim sort field: %s", s)
+	}
+
+	return result, nil
+}
+
+// ClaimsDomainsQuery returns the domain claims selected by the query, in the
+// order it specifies. HVCA filters domain claims only by status, so every
+// page of claims with the requested status is retrieved, as described for
+// ClaimsDomainsAll, and the domain filter and sort order are then applied by
+// the client.
+func (c *Client) ClaimsDomainsQuery(ctx context.Context, query ClaimsQuery) ([]Claim, error) {
+	var status = query.Status
+	if status == 0 {
+		status = StatusAll
+	}
+
+	var claims, err = c.ClaimsDomainsAll(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+
+	if query.Domain != "" {
+		var selected = make([]Claim, 0, len(claims))
+		for _, claim := range claims {
+			if query.matchesDomain(claim.Domain) {
+				selected = append(selected, claim)
+			}
+		}
+
+		claims = selected
+	}
+
+	if query.SortBy != 0 {
+		sortClaims(claims, query.SortBy, query.Descending)
+	}
+
+	return claims, nil
+}
+
+// matchesDomain checks if a domain is selected by the query.
+func (q ClaimsQuery) matchesDomain(domain string) bool {
+	var want = normalizeClaimDomain(q.Domain)
+	var got = normalizeClaimDomain(domain)
+
+	return got == want || (q.IncludeSubdomains && strings.HasSuffix(got, "."+want))
+}
+
+// normalizeClaimDomain returns a domain name in lower case, with any
+// U-labels converted to A-labels and without any trailing dot, for
+// comparison.
+func normalizeClaimDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(DomainToASCII(domain), "."))
+}
+
+// sortClaims sorts domain claims by the specified field. Claims with equal
+// values for the field remain in their original order.
+func sortClaims(claims []Claim, field ClaimSortField, descending bool) {
+	var less = func(i, j int) bool {
+		switch field {
+		case SortByCreatedAt:
+			return claims[i].CreatedAt.Before(claims[j].CreatedAt)
+
+		case SortByExpiresAt:
+			return claims[i].ExpiresAt.Before(claims[j].ExpiresAt)
+
+		case SortByAssertBy:
+			return claims[i].AssertBy.Before(claims[j].AssertBy)
+		}
+
+		return normalizeClaimDomain(claims[i].Domain) < normalizeClaimDomain(claims[j].Domain)
+	}
+
+	sort.SliceStable(claims, func(i, j int) bool {
+		if descending {
+			return less(j, i)
+		}
+
+		return less(i, j)
+	})
+}
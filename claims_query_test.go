@@ -0,0 +1,124 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClaimsDomainsQuery(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		query hvclient.ClaimsQuery
+		want  []string
+	}{
+		{
+			name: "All",
+			want: []string{mockClaimID, "pending1", "pending2"},
+		},
+		{
+			name:  "Status",
+			query: hvclient.ClaimsQuery{Status: hvclient.StatusPending},
+			want:  []string{"pending1", "pending2"},
+		},
+		{
+			name:  "Domain",
+			query: hvclient.ClaimsQuery{Domain: "PENDING2.com"},
+			want:  []string{"pending2"},
+		},
+		{
+			name:  "DomainNotFound",
+			query: hvclient.ClaimsQuery{Domain: "com"},
+			want:  []string{},
+		},
+		{
+			name:  "Subdomains",
+			query: hvclient.ClaimsQuery{Domain: "com.", IncludeSubdomains: true},
+			want:  []string{mockClaimID, "pending1", "pending2"},
+		},
+		{
+			name:  "SortDescending",
+			query: hvclient.ClaimsQuery{SortBy: hvclient.SortByDomain, Descending: true},
+			want:  []string{"pending2", "pending1", mockClaimID},
+		},
+		{
+			name: "SortStable",
+			query: hvclient.ClaimsQuery{
+				Status: hvclient.StatusPending,
+				SortBy: hvclient.SortByCreatedAt,
+			},
+			want: []string{"pending1", "pending2"},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var clnt, closefunc = newMockClient(t)
+			defer closefunc()
+
+			var ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+
+			var claims, err = clnt.ClaimsDomainsQuery(ctx, tc.query)
+			if err != nil {
+				t.Fatalf("couldn't list domain claims: %v", err)
+			}
+
+			var got = make([]string, 0, len(claims))
+			for _, claim := range claims {
+				got = append(got, claim.ID)
+			}
+
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseClaimSortField(t *testing.T) {
+	t.Parallel()
+
+	for _, want := range []hvclient.ClaimSortField{
+		hvclient.SortByDomain,
+		hvclient.SortByCreatedAt,
+		hvclient.SortByExpiresAt,
+		hvclient.SortByAssertBy,
+	} {
+		var got, err = hvclient.ParseClaimSortField(want.String())
+		if err != nil {
+			t.Fatalf("couldn't parse claim sort field %q: %v", want, err)
+		}
+
+		if got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	if _, err := hvclient.ParseClaimSortField("bogus"); err == nil {
+		t.Errorf("unexpectedly parsed claim sort field")
+	}
+}
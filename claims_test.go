@@ -364,6 +364,73 @@ func TestClaimStatusStringInvalidValue(t *testing.T) {
 	}
 }
 
+func TestParseClaimStatus(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		in   string
+		want hvclient.ClaimStatus
+		err  bool
+	}{
+		{in: "pending", want: hvclient.StatusPending},
+		{in: "VERIFIED", want: hvclient.StatusVerified},
+		{in: "All", want: hvclient.StatusAll},
+		{in: "unknown", err: true},
+		{in: "", err: true},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = hvclient.ParseClaimStatus(tc.in)
+			if (err != nil) != tc.err {
+				t.Fatalf("got error %v, want error %t", err, tc.err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClaimStatusJSONRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, status := range []hvclient.ClaimStatus{
+		hvclient.StatusPending,
+		hvclient.StatusVerified,
+		hvclient.StatusAll,
+	} {
+		var status = status
+
+		t.Run(status.String(), func(t *testing.T) {
+			t.Parallel()
+
+			var data, err = json.Marshal(status)
+			if err != nil {
+				t.Fatalf("couldn't marshal JSON: %v", err)
+			}
+
+			var got hvclient.ClaimStatus
+			if err = json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("couldn't unmarshal JSON: %v", err)
+			}
+
+			if got != status {
+				t.Errorf("got %v, want %v", got, status)
+			}
+		})
+	}
+
+	if _, err := json.Marshal(hvclient.ClaimStatus(0)); err == nil {
+		t.Errorf("unexpectedly marshalled invalid claim status")
+	}
+}
+
 func TestClaimLogEntryStatusStringInvalidValue(t *testing.T) {
 	var want = "ERROR: UNKNOWN STATUS"
 
@@ -452,3 +519,55 @@ func TestClaimAssertionInfoUnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestClaimAssertionInfoDNSRecord(t *testing.T) {
+	t.Parallel()
+
+	var info = hvclient.ClaimAssertionInfo{
+		Token: "_globalsign-domain-verification=1234",
+	}
+
+	var testcases = []struct {
+		domain string
+		want   string
+	}{
+		{
+			domain: "example.com",
+			want:   "example.com.",
+		},
+		{
+			domain: "example.com.",
+			want:   "example.com.",
+		},
+		{
+			domain: "*.example.com",
+			want:   "example.com.",
+		},
+		{
+			domain: " sub.example.com ",
+			want:   "sub.example.com.",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.domain, func(t *testing.T) {
+			t.Parallel()
+
+			var name, rrtype, value = info.DNSRecord(tc.domain)
+
+			if name != tc.want {
+				t.Errorf("got name %q, want %q", name, tc.want)
+			}
+
+			if rrtype != "TXT" {
+				t.Errorf("got type %q, want %q", rrtype, "TXT")
+			}
+
+			if value != info.Token {
+				t.Errorf("got value %q, want %q", value, info.Token)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClaimHTTPPath is the path at which the token must be placed to assert
+// control of a domain using the HTTP validation method.
+const ClaimHTTPPath = "/.well-known/pki-validation/gsdv.txt"
+
+// ErrClaimTokenNotVisible is returned by ClaimVerifyDNSLocally and
+// ClaimVerifyHTTPLocally if the domain claim token could not be found.
+var ErrClaimTokenNotVisible = errors.New("domain claim token not visible")
+
+// claimHTTPMaxSize is the maximum number of bytes read from the token file
+// when verifying the HTTP validation method locally.
+const claimHTTPMaxSize = 64 * 1024
+
+// ClaimVerifyDNSLocally checks, using the local DNS resolver, whether a TXT
+// record containing the domain claim token is visible at the specified
+// domain, which should be the authorization domain if one will be specified
+// when calling ClaimDNS. It is intended as a pre-flight check before calling
+// ClaimDNS, to give immediate feedback if the record has not yet been
+// created or has not yet propagated. Success does not guarantee that HVCA
+// will see the same record, since it may use different resolvers. An error
+// wrapping ErrClaimTokenNotVisible is returned if the token was not found.
+func ClaimVerifyDNSLocally(ctx context.Context, domain, token string) error {
+	return verifyDNSLocally(ctx, net.DefaultResolver.LookupTXT, domain, token)
+}
+
+// verifyDNSLocally checks whether a TXT record containing the domain claim
+// token is visible at the specified domain, using the specified lookup
+// function.
+func verifyDNSLocally(
+	ctx context.Context,
+	lookup func(ctx context.Context, name string) ([]string, error),
+	domain string,
+	token string,
+) error {
+	var name, _, _ = ClaimAssertionInfo{}.DNSRecord(domain)
+
+	var records, err = lookup(ctx, name)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return fmt.Errorf("%w: no TXT records found for %s", ErrClaimTokenNotVisible, name)
+		}
+
+		return fmt.Errorf("couldn't look up TXT records for %s: %w", name, err)
+	}
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == token {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: none of the %d TXT records for %s contain the token", ErrClaimTokenNotVisible, len(records), name)
+}
+
+// ClaimVerifyHTTPLocally checks whether the domain claim token can be
+// fetched from ClaimHTTPPath at the specified domain using the specified
+// scheme, which should be "http" or "https". It is intended as a pre-flight
+// check before calling ClaimHTTP, to give immediate feedback if the token
+// has not yet been placed. An error wrapping ErrClaimTokenNotVisible is
+// returned if the token was not found.
+func ClaimVerifyHTTPLocally(ctx context.Context, domain, token, scheme string) error {
+	return verifyHTTPLocally(ctx, http.DefaultClient, domain, token, scheme)
+}
+
+// verifyHTTPLocally checks whether the domain claim token can be fetched
+// from the specified domain, using the specified HTTP client.
+func verifyHTTPLocally(ctx context.Context, client *http.Client, domain, token, scheme string) error {
+	scheme = strings.ToLower(scheme)
+	if scheme != "http" && scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", scheme)
+	}
+
+	var host = DomainToASCII(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(domain), "*."), "."))
+	var target = scheme + "://" + host + ClaimHTTPPath
+
+	var request, err = http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't create request for %s: %w", target, err)
+	}
+
+	var response *http.Response
+	if response, err = client.Do(request); err != nil {
+		return fmt.Errorf("couldn't fetch %s: %w", target, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: fetching %s returned status %s", ErrClaimTokenNotVisible, target, response.Status)
+	}
+
+	var scanner = bufio.NewScanner(io.LimitReader(response.Body, claimHTTPMaxSize))
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == token {
+			return nil
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("couldn't read %s: %w", target, err)
+	}
+
+	return fmt.Errorf("%w: %s does not contain the token", ErrClaimTokenNotVisible, target)
+}
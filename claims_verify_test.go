@@ -0,0 +1,164 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyDNSLocally(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name     string
+		domain   string
+		records  []string
+		err      error
+		wantName string
+		want     error
+	}{
+		{
+			name:     "Found",
+			domain:   "*.example.com",
+			records:  []string{"v=spf1 -all", "token123"},
+			wantName: "example.com.",
+		},
+		{
+			name:     "NotFound",
+			domain:   "example.com.",
+			records:  []string{"v=spf1 -all"},
+			wantName: "example.com.",
+			want:     ErrClaimTokenNotVisible,
+		},
+		{
+			name:     "NXDOMAIN",
+			domain:   "example.com",
+			err:      &net.DNSError{Err: "no such host", Name: "example.com.", IsNotFound: true},
+			wantName: "example.com.",
+			want:     ErrClaimTokenNotVisible,
+		},
+		{
+			name:     "LookupFailure",
+			domain:   "example.com",
+			err:      &net.DNSError{Err: "server misbehaving", Name: "example.com."},
+			wantName: "example.com.",
+			want:     errors.New("lookup failure"),
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var lookup = func(_ context.Context, name string) ([]string, error) {
+				if name != tc.wantName {
+					t.Errorf("got lookup for %q, want %q", name, tc.wantName)
+				}
+
+				return tc.records, tc.err
+			}
+
+			var err = verifyDNSLocally(context.Background(), lookup, tc.domain, "token123")
+			if (err == nil) != (tc.want == nil) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+
+			if errors.Is(tc.want, ErrClaimTokenNotVisible) != errors.Is(err, ErrClaimTokenNotVisible) {
+				t.Errorf("got error %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerifyHTTPLocally(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name    string
+		scheme  string
+		handler http.HandlerFunc
+		want    error
+	}{
+		{
+			name:   "Found",
+			scheme: "HTTP",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != ClaimHTTPPath {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				w.Write([]byte("some other token\r\ntoken123\r\n"))
+			},
+		},
+		{
+			name:   "WrongToken",
+			scheme: "http",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("some other token\n"))
+			},
+			want: ErrClaimTokenNotVisible,
+		},
+		{
+			name:    "NotFound",
+			scheme:  "http",
+			handler: http.NotFound,
+			want:    ErrClaimTokenNotVisible,
+		},
+		{
+			name:   "BadScheme",
+			scheme: "ftp",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("token123\n"))
+			},
+			want: errors.New("bad scheme"),
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var server = httptest.NewServer(tc.handler)
+			defer server.Close()
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+
+			var domain = strings.TrimPrefix(server.URL, "http://")
+
+			var err = verifyHTTPLocally(ctx, server.Client(), domain, "token123", tc.scheme)
+			if (err == nil) != (tc.want == nil) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+
+			if errors.Is(tc.want, ErrClaimTokenNotVisible) != errors.Is(err, ErrClaimTokenNotVisible) {
+				t.Errorf("got error %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
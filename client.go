@@ -20,9 +20,11 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -43,24 +45,41 @@ import (
 // expires. In the event of a HTTP 503 service unavailable response, or a
 // response indicating that a request has been accepted but the corresponding
 // resource is not yet available, the client will automatically wait and retry
-// the call a predetermined number of times. The maximum wait time for this
-// process may be controlled through the context passed to each API call.
+// the call the number of times specified in the configuration. The maximum
+// wait time for this process may be controlled through the context passed to
+// each API call.
 //
-// It is safe to make concurrent API calls from a single client object.
+// It is safe to make concurrent API calls from a single client object, and
+// high-throughput applications should share one client between goroutines
+// rather than creating one for each, so that logins and TCP connections are
+// reused. The size of the underlying connection pool may be tuned through the
+// MaxIdleConns, MaxConnsPerHost, IdleConnTimeout and KeepAlive configuration
+// fields. A client must not be copied after first use.
+//
+// A client which is no longer needed should be closed with Close, which
+// releases its idle connections. Applications which create many short-lived
+// clients should close each one to avoid leaking sockets.
 type Client struct {
 	config     *Config
 	url        *url.URL
 	httpClient *http.Client
 	token      string
 	lastLogin  time.Time
+	identity   *AccountIdentity
 	tokenMtx   sync.RWMutex
 	loginMtx   sync.Mutex
+	dumpMtx    sync.Mutex
+	cache      responseCache
+	ownsHTTP   bool
+	done       chan struct{}
+	closeOnce  sync.Once
 }
 
-const (
-	// numberOfRetries is the number of times to retry a request.
-	numberOfRetries = 5
+// ErrClientClosed is returned by API calls made with a client after it has
+// been closed.
+var ErrClientClosed = errors.New("client is closed")
 
+const (
 	// Initial time to wait before retrying. Subsequent retries will be more
 	// widely spaced
 	retryWaitDuration = time.Second
@@ -76,22 +95,117 @@ func (c *Client) makeRequest(
 	in interface{},
 	out interface{},
 ) (*http.Response, error) {
+	return c.makeRequestWithHeaders(ctx, path, method, nil, in, out)
+}
+
+// makeRequestWithHeaders is the same as makeRequest, but it also adds the
+// specified headers to the request. The call is reported to the configured
+// observer, if any.
+func (c *Client) makeRequestWithHeaders(
+	ctx context.Context,
+	path string,
+	method string,
+	headers http.Header,
+	in interface{},
+	out interface{},
+) (*http.Response, error) {
+	if c.config.Observer == nil {
+		return c.sendRequest(ctx, path, method, headers, in, out)
+	}
+
+	var start = c.now()
+	var response, err = c.sendRequest(ctx, path, method, headers, in, out)
+
+	var info = CallInfo{
+		Method:   method,
+		Path:     path,
+		Duration: c.now().Sub(start),
+		Err:      err,
+	}
+
+	if i := strings.IndexByte(info.Path, '?'); i != -1 {
+		info.Path = info.Path[:i]
+	}
+
+	info.Endpoint = endpointTemplate(info.Path)
+
+	var apiErr APIError
+	switch {
+	case response != nil:
+		info.StatusCode = response.StatusCode
+
+	case errors.As(err, &apiErr):
+		info.StatusCode = apiErr.StatusCode
+	}
+
+	c.config.Observer.ObserveCall(info)
+
+	return response, err
+}
+
+// sendRequest is the same as makeRequestWithHeaders, but does not report
+// the call to the observer.
+func (c *Client) sendRequest(
+	ctx context.Context,
+	path string,
+	method string,
+	headers http.Header,
+	in interface{},
+	out interface{},
+) (*http.Response, error) {
+	if c.isClosed() {
+		return nil, ErrClientClosed
+	}
+
+	var numberOfRetries = c.config.Retries
+	if numberOfRetries < 0 || ctx.Value(noRetriesKey{}) != nil {
+		numberOfRetries = 0
+	}
+
 	var retriesRemaining = numberOfRetries
 	var response *http.Response
 
+	// Send the same request ID with every attempt, and with any login made
+	// on behalf of this call.
+	var requestID = RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = newRequestID()
+		ctx = WithRequestID(ctx, requestID)
+	}
+
+	// Query parameters supplied for this call apply only to its own
+	// endpoint, not to any login made on its behalf.
+	var opts = requestOptionsFromContext(ctx)
+	var target = path
+	if !strings.HasPrefix(path, endpointLogin) {
+		target = opts.addQuery(path)
+	}
+
 	// Loop so we can retry requests if necessary.
 	for {
 		var body io.Reader
+		var data []byte
 		if in != nil {
-			var data, err = json.Marshal(in)
-			if err != nil {
+			var err error
+			if data, err = json.Marshal(in); err != nil {
 				return nil, fmt.Errorf("failed to marshal request body: %w", err)
 			}
 
 			body = bytes.NewReader(data)
 		}
 
-		var request, err = http.NewRequestWithContext(ctx, method, c.url.String()+path, body)
+		var compressed bool
+		if c.config.CompressRequestsOver > 0 && int64(len(data)) >= c.config.CompressRequestsOver {
+			var gzipped, err = gzipBytes(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compress request body: %w", err)
+			}
+
+			body = bytes.NewReader(gzipped)
+			compressed = true
+		}
+
+		var request, err = http.NewRequestWithContext(ctx, method, c.endpointURL(target), body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create new HTTP request: %w", err)
 		}
@@ -103,12 +217,38 @@ func (c *Client) makeRequest(
 			request.Header.Set(httputils.ContentTypeHeader, httputils.ContentTypeJSONUTF8)
 		}
 
+		if compressed {
+			request.Header.Set(httputils.ContentEncodingHeader, httputils.ContentEncodingGzip)
+		}
+
 		// Add any extra headers to the request first, so they can't override
 		// any headers we add ourselves.
 		for key, value := range c.config.ExtraHeaders {
 			request.Header.Add(key, value)
 		}
 
+		for key, values := range headers {
+			for _, value := range values {
+				request.Header.Add(key, value)
+			}
+		}
+
+		if opts != nil {
+			for key, values := range opts.header {
+				for _, value := range values {
+					request.Header.Add(key, value)
+				}
+			}
+		}
+
+		if c.config.UserAgent != "" {
+			request.Header.Set(httputils.UserAgentHeader, c.config.UserAgent)
+		}
+
+		if requestID != "" {
+			request.Header.Set(RequestIDHeader, requestID)
+		}
+
 		// Perform specific processing for non-login requests.
 		if !strings.HasPrefix(path, endpointLogin) {
 			// Since this is not a login request, preemptively login again if
@@ -128,10 +268,20 @@ func (c *Client) makeRequest(
 		}
 		defer httputils.ConsumeAndCloseResponseBody(response)
 
+		// A conditional request is satisfied by a not modified response, which
+		// has no body.
+		if response.StatusCode == http.StatusNotModified && request.Header.Get(httputils.IfNoneMatchHeader) != "" {
+			return response, nil
+		}
+
 		// HVCA doesn't return any 3XX HTTP status codes, so treat everything outside
 		// of the 2XX range as an error. Also treat 202 status codes as "errors",
 		// because we want to retry in that event.
 		if response.StatusCode < 200 || response.StatusCode > 299 || response.StatusCode == http.StatusAccepted {
+			if response.StatusCode != http.StatusAccepted {
+				c.dumpRequest(request, data, response)
+			}
+
 			var apiErr = newAPIError(response)
 
 			// Depending on the status code, we may want to retry the request.
@@ -169,7 +319,12 @@ func (c *Client) makeRequest(
 				// remaining retries and pause for a progressively increasing
 				// period of time.
 				retriesRemaining--
-				time.Sleep(retryWaitDuration * time.Duration((numberOfRetries - retriesRemaining)))
+
+				select {
+				case <-c.clock().After(retryWaitDuration * time.Duration((numberOfRetries - retriesRemaining))):
+				case <-c.done:
+					return nil, ErrClientClosed
+				}
 
 			default:
 				// Return the error on any other status code.
@@ -189,26 +344,191 @@ func (c *Client) makeRequest(
 		return response, nil
 	}
 
+	// Callers of Do receive the raw response body, whatever its content
+	// type, and unmarshal it themselves.
+	if raw, ok := out.(*rawBody); ok {
+		var data, err = c.readResponseBody(method, path, response)
+		if err != nil {
+			return nil, err
+		}
+
+		*raw = data
+
+		return response, nil
+	}
+
 	// All response bodies from successful HVCA requests have a JSON content
 	// type, so verify that's what we have before reading the body.
 	var err = httputils.VerifyResponseContentType(response, httputils.ContentTypeJSON)
 	if err != nil {
+		return nil, &ResponseError{Method: method, Path: path, RequestID: requestID, Err: err}
+	}
+
+	if err = c.decodeResponseBody(method, path, requestID, response, out); err != nil {
 		return nil, err
 	}
 
-	// Read and unmarshal the response body.
-	var data []byte
-	data, err = ioutil.ReadAll(response.Body)
+	return response, nil
+}
+
+// rawBody is passed as the out parameter to makeRequest to receive the raw
+// response body rather than unmarshalling it.
+type rawBody []byte
+
+// responseChunkSize is the maximum number of bytes of an HTTP response body
+// read at once, between which the request context is checked.
+var responseChunkSize = 32 * 1024
+
+// responseReader reads an HTTP response body in chunks of at most
+// responseChunkSize bytes, up to one byte more than a maximum size so that
+// a larger body can be detected without reading all of it. Once the request
+// context is done, its error is returned in place of further data, so that
+// reading a large body stops promptly at the context's deadline.
+type responseReader struct {
+	ctx  context.Context
+	body io.Reader
+	n    int64
+	err  error
+}
+
+// newResponseReader returns a reader for the body of an HTTP response.
+func (c *Client) newResponseReader(response *http.Response) *responseReader {
+	var ctx = context.Background()
+	if response.Request != nil {
+		ctx = response.Request.Context()
+	}
+
+	return &responseReader{
+		ctx:  ctx,
+		body: io.LimitReader(response.Body, c.config.MaxResponseSize+1),
+	}
+}
+
+// Read reads the next chunk of the response body.
+func (r *responseReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		r.err = err
+		return 0, err
+	}
+
+	if len(p) > responseChunkSize {
+		p = p[:responseChunkSize]
+	}
+
+	var n, err = r.body.Read(p)
+	r.n += int64(n)
+
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+
+	return n, err
+}
+
+// readResponseBody reads the body of an HTTP response, up to the maximum
+// size specified in the configuration.
+func (c *Client) readResponseBody(method, path string, response *http.Response) ([]byte, error) {
+	var r = c.newResponseReader(response)
+
+	var data, err = ioutil.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read HTTP response body: %w", err)
+		return nil, &ResponseError{
+			Method:    method,
+			Path:      path,
+			RequestID: requestIDOf(response),
+			Err:       fmt.Errorf("failed to read HTTP response body: %w", err),
+		}
+	}
+
+	if r.n > c.config.MaxResponseSize {
+		return nil, &ResponseError{Method: method, Path: path, RequestID: requestIDOf(response), Err: ErrResponseTooLarge}
+	}
+
+	return data, nil
+}
+
+// decodeResponseBody decodes a JSON response body as it is read, rather
+// than reading it in full first, so that a large body such as a validation
+// policy with many custom extensions is not held in memory twice. Unknown
+// fields are disallowed if strict decoding is specified in the
+// configuration, and a body larger than the maximum size specified in the
+// configuration is rejected.
+func (c *Client) decodeResponseBody(method, path, requestID string, response *http.Response, out interface{}) error {
+	var r = c.newResponseReader(response)
+
+	var decoder = json.NewDecoder(r)
+	if c.config.StrictDecoding {
+		decoder.DisallowUnknownFields()
+	}
+
+	var err = decoder.Decode(out)
+	if err == nil {
+		// Read to the end of the body, so that trailing data and bodies
+		// which are too large are detected.
+		if _, err = decoder.Token(); err == io.EOF {
+			err = nil
+		} else if err == nil {
+			err = errors.New("unexpected data after JSON value")
+		}
+	}
+
+	switch {
+	case r.n > c.config.MaxResponseSize:
+		err = ErrResponseTooLarge
+
+	case r.err != nil:
+		err = fmt.Errorf("failed to read HTTP response body: %w", r.err)
+
+	case err != nil:
+		err = fmt.Errorf("failed to unmarshal HTTP response body: %w", err)
+
+	default:
+		return nil
+	}
+
+	return &ResponseError{Method: method, Path: path, RequestID: requestID, Err: err}
+}
+
+// unmarshalResponseBody unmarshals a JSON response body, disallowing unknown
+// fields if strict decoding is specified in the configuration.
+func (c *Client) unmarshalResponseBody(method, path, requestID string, data []byte, out interface{}) error {
+	var err error
+	if c.config.StrictDecoding {
+		err = unmarshalStrict(data, out)
+	} else {
+		err = json.Unmarshal(data, out)
 	}
 
-	err = json.Unmarshal(data, out)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal HTTP response body: %w", err)
+		return &ResponseError{
+			Method:    method,
+			Path:      path,
+			RequestID: requestID,
+			Err:       fmt.Errorf("failed to unmarshal HTTP response body: %w", err),
+		}
 	}
 
-	return response, nil
+	return nil
+}
+
+// unmarshalStrict unmarshals JSON data, returning an error if it contains
+// any object keys which do not match a field in the destination, or if there
+// is any data following the JSON value. Note that types which implement
+// json.Unmarshaler are responsible for their own decoding, and may not be
+// checked for unknown fields.
+func unmarshalStrict(data []byte, out interface{}) error {
+	var decoder = json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(out); err != nil {
+		return err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+
+	return nil
 }
 
 // DefaultTimeout returns the timeout specified in the configuration object or
@@ -220,6 +540,41 @@ func (c *Client) DefaultTimeout() time.Duration {
 	return c.config.Timeout
 }
 
+// Close closes the client, stopping any retry wait in progress and releasing
+// the idle connections held by its HTTP transport. The idle connections of
+// an HTTP client supplied with WithHTTPClient are left open, since that
+// client may be shared with other code. Subsequent API calls
+// return ErrClientClosed, but calls already in flight are not interrupted
+// and should be cancelled through their contexts if necessary. Closing a
+// client more than once has no effect. Close always returns nil, and is
+// provided in this form so that a client satisfies io.Closer.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		if c.done != nil {
+			close(c.done)
+		}
+
+		c.tokenReset()
+
+		if c.ownsHTTP {
+			c.httpClient.CloseIdleConnections()
+		}
+	})
+
+	return nil
+}
+
+// isClosed returns true if the client has been closed.
+func (c *Client) isClosed() bool {
+	select {
+	case <-c.done:
+		return true
+
+	default:
+		return false
+	}
+}
+
 // NewClient creates a new HVCA client from a configuration object. An initial
 // login is made, and the returned client is immediately ready to make API
 // calls.
@@ -230,14 +585,101 @@ func NewClient(ctx context.Context, conf *Config) (*Client, error) {
 		return nil, err
 	}
 
-	// Build an HTTP transport using any proxy settings from the environment.
-	// Experimentation suggests that the other values seem to reasonably
-	// maximally encourage the sharing of TCP connections.
+	return newClient(ctx, conf, nil, true)
+}
+
+// NewClientFromToken creates a new HVCA client which authenticates with an
+// existing authentication token, such as one returned by Client.AccessToken,
+// rather than with an API key and secret. The options are applied as for
+// New, except that no API credentials are required, and WithTLSKeyPair or
+// WithHTTPClient will usually be needed to supply the mutual TLS credentials
+// required by HVCA. No login is made. Since the client cannot log in again,
+// API calls return an error wrapping ErrTokenExpired once HVCA no longer
+// accepts the token. The context is currently unused, and is accepted for
+// consistency with the other constructors.
+func NewClientFromToken(ctx context.Context, url, token string, opts ...Option) (*Client, error) {
+	if token == "" {
+		return nil, errors.New("no authentication token provided")
+	}
+
+	var o clientOptions
+	o.config.URL = url
+
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	o.config.fromToken = true
+
+	if err := o.config.Validate(); err != nil {
+		return nil, err
+	}
+
+	var httpClient, ownsHTTP = o.buildHTTPClient()
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: newTransport(&o.config)}
+	}
+
+	var clnt = &Client{
+		config:     &o.config,
+		url:        o.config.url,
+		httpClient: httpClient,
+		ownsHTTP:   ownsHTTP,
+		done:       make(chan struct{}),
+	}
+
+	clnt.tokenSet(token)
+	clnt.identitySet(tokenAccountIdentity(token))
+
+	return clnt, nil
+}
+
+// newClient creates a new HVCA client from a validated configuration object
+// and performs the initial login. If httpClient is nil, an HTTP client is
+// built from the connection settings in the configuration. If ownsHTTP is
+// true, the idle connections of the HTTP client are closed when the client
+// is closed.
+func newClient(ctx context.Context, conf *Config, httpClient *http.Client, ownsHTTP bool) (*Client, error) {
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: newTransport(conf)}
+	}
+
+	// Build a new client.
+	var newClient = Client{
+		config:     conf,
+		url:        conf.url,
+		httpClient: httpClient,
+		ownsHTTP:   ownsHTTP,
+		done:       make(chan struct{}),
+	}
+
+	// Perform the initial login and return the new client.
+	if err := newClient.login(ctx); err != nil {
+		return nil, err
+	}
+
+	return &newClient, nil
+}
+
+// newTransport builds an HTTP transport from the connection settings in a
+// validated configuration object.
+func newTransport(conf *Config) *http.Transport {
+	// Build an HTTP transport using the connection settings and any proxy
+	// settings from the configuration or the environment. Since all
+	// connections are to the same host, the idle connection limit applies
+	// equally per host.
 	var tnspt = &http.Transport{
-		MaxIdleConnsPerHost: 1024,
-		MaxIdleConns:        1024,
-		MaxConnsPerHost:     1024,
-		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConnsPerHost: conf.MaxIdleConns,
+		MaxIdleConns:        conf.MaxIdleConns,
+		MaxConnsPerHost:     conf.MaxConnsPerHost,
+		IdleConnTimeout:     conf.IdleConnTimeout,
+		Proxy:               conf.proxyFunc(),
+	}
+
+	if conf.KeepAlive != 0 {
+		tnspt.DialContext = (&net.Dialer{KeepAlive: conf.KeepAlive}).DialContext
 	}
 
 	if conf.url.Scheme == "https" {
@@ -260,20 +702,7 @@ func NewClient(ctx context.Context, conf *Config) (*Client, error) {
 		}
 	}
 
-	// Build a new client.
-	var newClient = Client{
-		config:     conf,
-		url:        conf.url,
-		httpClient: &http.Client{Transport: tnspt},
-	}
-
-	// Perform the initial login and return the new client.
-	err = newClient.login(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	return &newClient, nil
+	return tnspt
 }
 
 // NewClientFromFile returns a new HVCA client from a configuration file. An
@@ -287,3 +716,16 @@ func NewClientFromFile(ctx context.Context, filename string) (*Client, error) {
 
 	return NewClient(ctx, conf)
 }
+
+// NewClientFromFileProfile returns a new HVCA client from the named profile
+// in a configuration file, as described for NewConfigFromFileProfile. An
+// initial login is made, and the returned client is immediately ready to
+// make API calls.
+func NewClientFromFileProfile(ctx context.Context, filename, name string) (*Client, error) {
+	var conf, err = NewConfigFromFileProfile(filename, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClient(ctx, conf)
+}
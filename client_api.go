@@ -24,6 +24,7 @@ import (
 	"math/big"
 	"net/http"
 	"net/url"
+	"path"
 	"time"
 )
 
@@ -81,6 +82,7 @@ const (
 	RevocationReasonSuperseded           = RevocationReason("superseded")
 	RevocationReasonCessationOfOperation = RevocationReason("cessationOfOperation")
 	RevocationReasonPrivilegeWithdrawn   = RevocationReason("privilegeWithdrawn")
+	RevocationReasonCertificateHold      = RevocationReason("certificateHold")
 )
 
 const (
@@ -109,6 +111,7 @@ const (
 	endpointStatsRevoked                = "/stats/revoked"
 	endpointTrustChain                  = "/trustchain"
 	endpointPolicy                      = "/validationpolicy"
+	endpointTimestamp                   = "/timestamp"
 	pathReassert                        = "/reassert"
 	pathDNS                             = "/dns"
 	pathHTTP                            = "/http"
@@ -123,29 +126,168 @@ func (c *Client) CertificateRequest(
 	ctx context.Context,
 	req *Request,
 ) (*big.Int, error) {
-	var r, err = c.makeRequest(
+	var sn, _, err = c.CertificateRequestWithLocation(ctx, req)
+
+	return sn, err
+}
+
+// CertificateRequestWithLocation is the same as CertificateRequest, but it
+// also returns the absolute URL of the new certificate as returned by HVCA
+// in the Location header. After a short delay, the certificate itself may be
+// retrieved via the CertificateRetrieveByURL method.
+//
+// If the client was configured with StrictDNSNames, the SAN DNS names in
+// the request are checked and converted before it is submitted, and if it
+// was configured with StrictEmails, the SAN email addresses are likewise
+// checked and converted. If it was configured with CheckCAA, the CAA
+// records of the DNS names are checked before the request is submitted.
+//
+// If the client was configured with a non-zero DeduplicationWindow and an
+// identical request was successfully made within that window, the serial
+// number and location of the previously issued certificate are returned
+// without submitting the request to HVCA again.
+func (c *Client) CertificateRequestWithLocation(
+	ctx context.Context,
+	req *Request,
+) (*big.Int, *url.URL, error) {
+	if c.config.StrictDNSNames && req != nil && req.SAN != nil {
+		var names, err = checkDNSNames(req.SAN.DNSNames)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var checked = *req
+		var san = *req.SAN
+		san.DNSNames = names
+		checked.SAN = &san
+		req = &checked
+	}
+
+	if c.config.StrictEmails && req != nil && req.SAN != nil {
+		var emails, err = checkEmails(req.SAN.Emails)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var checked = *req
+		var san = *req.SAN
+		san.Emails = emails
+		checked.SAN = &san
+		req = &checked
+	}
+
+	if c.config.CheckCAA && req != nil && req.SAN != nil {
+		if err := c.CheckCAA(ctx, req.SAN.DNSNames); err != nil {
+			if c.config.OnCAAFailure == nil {
+				return nil, nil, err
+			}
+
+			c.config.OnCAAFailure(err)
+		}
+	}
+
+	var key string
+	var headers http.Header
+
+	if c.config.DeduplicationWindow > 0 {
+		var err error
+		if key, err = requestKey(req); err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+
+		if sn, location := c.recentRequest(key); sn != nil {
+			return sn, location, nil
+		}
+	}
+
+	// A new idempotency key is generated for each call, and the same headers
+	// are sent with every retry of the request made on its behalf.
+	if c.config.IdempotencyKeyHeader != "" {
+		var id = newRequestID()
+		if id == "" {
+			return nil, nil, errors.New("failed to generate idempotency key")
+		}
+
+		headers = http.Header{}
+		headers.Set(c.config.IdempotencyKeyHeader, id)
+	}
+
+	var r, err = c.makeRequestWithHeaders(
 		ctx,
 		endpointCertificates,
 		http.MethodPost,
+		headers,
 		req,
 		nil,
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, c.checkQuota(ctx, err)
 	}
 
-	var snString string
-	snString, err = basePathHeaderFromResponse(r, certSNHeaderName)
+	var location string
+	location, err = headerFromResponse(r, certSNHeaderName)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	var locURL *url.URL
+	if locURL, err = url.Parse(location); err != nil {
+		return nil, nil, fmt.Errorf("invalid location returned: %w", err)
+	}
+
+	// The location may be relative to the URL of the request.
+	if r.Request != nil && r.Request.URL != nil {
+		locURL = r.Request.URL.ResolveReference(locURL)
 	}
 
-	var sn, ok = big.NewInt(0).SetString(snString, 16)
-	if !ok {
-		return nil, fmt.Errorf("invalid serial number returned: %s", snString)
+	var serial SerialNumber
+	if serial, err = ParseSerialNumber(path.Base(locURL.Path)); err != nil {
+		return nil, nil, fmt.Errorf("invalid location returned: %w", err)
 	}
 
-	return sn, nil
+	var sn = serial.Big()
+
+	if key != "" {
+		c.rememberRequest(key, sn, locURL)
+	}
+
+	return sn, locURL, nil
+}
+
+// checkQuota returns a *QuotaError wrapping an error returned by HVCA in
+// response to a certificate request, if the error indicates that the
+// issuance quota is exhausted, and otherwise returns the error unchanged.
+// The remaining quota is retrieved only in the former case, to be reported
+// in the *QuotaError.
+func (c *Client) checkQuota(ctx context.Context, err error) error {
+	var apiErr APIError
+	if !errors.As(err, &apiErr) || !apiErr.quotaExceeded() {
+		return err
+	}
+
+	var remaining, qerr = c.QuotaIssuance(ctx)
+	if qerr != nil {
+		remaining = -1
+	}
+
+	return &QuotaError{Remaining: remaining, Err: err}
+}
+
+// CertificateRetrieveByURL retrieves a certificate from a URL, such as one
+// returned by CertificateRequestWithLocation. A relative URL is resolved
+// against the HVCA URL in the client configuration. To avoid disclosing the
+// authentication token to a third party, the URL must have the same scheme
+// and host as the HVCA URL, and a path beneath it.
+func (c *Client) CertificateRetrieveByURL(
+	ctx context.Context,
+	location *url.URL,
+) (*CertInfo, error) {
+	var relPath, err = c.relativePath(location)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.certificateRetrieve(ctx, relPath)
 }
 
 // CertificateRetrieve retrieves a certificate.
@@ -153,19 +295,52 @@ func (c *Client) CertificateRetrieve(
 	ctx context.Context,
 	serial *big.Int,
 ) (*CertInfo, error) {
-	var r CertInfo
-	var _, err = c.makeRequest(
-		ctx,
-		endpointCertificates+"/"+url.QueryEscape(fmt.Sprintf("%X", serial)),
-		http.MethodGet,
-		nil,
-		&r,
-	)
+	var endpoint, err = certificatePath(serial)
 	if err != nil {
 		return nil, err
 	}
 
-	return &r, nil
+	return c.certificateRetrieve(ctx, endpoint)
+}
+
+// certificateRetrieve retrieves the certificate at the specified endpoint. If
+// the client is configured to await final certificates, a precertificate is
+// retrieved again, with progressively increasing waits, until the final
+// certificate is returned or the retries are exhausted.
+func (c *Client) certificateRetrieve(ctx context.Context, endpoint string) (*CertInfo, error) {
+	var retries = c.config.Retries
+	if retries < 0 {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		var r CertInfo
+		if _, err := c.makeRequest(
+			ctx,
+			endpoint,
+			http.MethodGet,
+			nil,
+			&r,
+		); err != nil {
+			return nil, err
+		}
+
+		if !r.IsPrecertificate || !c.config.AwaitFinalCertificate {
+			return &r, nil
+		}
+
+		if attempt >= retries {
+			return nil, fmt.Errorf("%w: final certificate %X not available after %d retries",
+				ErrPrecertificate, r.X509.SerialNumber, retries)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-c.clock().After(retryWaitDuration * time.Duration(attempt+1)):
+		}
+	}
 }
 
 // CertificateRevoke revokes a certificate.
@@ -179,13 +354,20 @@ func (c *Client) CertificateRevoke(
 // CertificateRevokeWithReason revokes a certificate with a specified reason
 // and UTC UNIX timestamp indicating when the private key was compromised if
 // supported by the HVCA server. A special case holds when time is 0 which
-// indicates that the current time should be used.
+// indicates that the current time should be used. An
+// *InvalidRevocationReasonError is returned, without calling HVCA, if the
+// reason is not one of the allowed revocation reasons, and a
+// *NotSupportedError if the revocation endpoint is not available.
 func (c *Client) CertificateRevokeWithReason(
 	ctx context.Context,
 	serial *big.Int,
 	reason RevocationReason,
 	time int64,
 ) error {
+	if err := reason.Validate(); err != nil {
+		return err
+	}
+
 	type certificatePatch struct {
 		RevocationReason RevocationReason `json:"revocation_reason"`
 		RevocationTime   int64            `json:"revocation_time,omitempty"`
@@ -196,29 +378,34 @@ func (c *Client) CertificateRevokeWithReason(
 		RevocationTime:   time,
 	}
 
-	var _, err = c.makeRequest(
+	var endpoint, err = certificatePath(serial)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.makeRequest(
 		ctx,
-		endpointCertificates+"/"+url.QueryEscape(fmt.Sprintf("%X", serial)),
+		endpoint,
 		http.MethodPatch,
 		&patch,
 		nil,
 	)
 
-	return err
+	return notSupported(opRevokeWithReason, err)
 }
 
 // TrustChain returns the chain of trust for the certificates issued
-// by the calling account.
+// by the calling account. If the client was configured with a non-zero
+// CachePolicyTTL, a cached copy of the chain may be returned. If it was
+// configured with a CacheDir, the chain is only transferred if it has
+// changed since it was cached on disk.
 func (c *Client) TrustChain(ctx context.Context) ([]*x509.Certificate, error) {
+	if certs := c.cachedTrustChain(); certs != nil {
+		return certs, nil
+	}
+
 	var chain []string
-	var _, err = c.makeRequest(
-		ctx,
-		endpointTrustChain,
-		http.MethodGet,
-		nil,
-		&chain,
-	)
-	if err != nil {
+	if err := c.getCacheable(ctx, endpointTrustChain, &chain); err != nil {
 		return nil, err
 	}
 
@@ -239,23 +426,28 @@ func (c *Client) TrustChain(ctx context.Context) ([]*x509.Certificate, error) {
 		certs = append(certs, cert)
 	}
 
+	c.cacheTrustChain(certs)
+
 	return certs, nil
 }
 
-// Policy returns the calling account's validation policy.
+// Policy returns the calling account's validation policy. If the client was
+// configured with a non-zero CachePolicyTTL, a cached copy of the policy may
+// be returned, in which case the same object may be shared between callers
+// and should not be modified. If it was configured with a CacheDir, the
+// policy is only transferred if it has changed since it was cached on disk.
 func (c *Client) Policy(ctx context.Context) (*Policy, error) {
+	if pol := c.cachedPolicy(); pol != nil {
+		return pol, nil
+	}
+
 	var pol Policy
-	var _, err = c.makeRequest(
-		ctx,
-		endpointPolicy,
-		http.MethodGet,
-		nil,
-		&pol,
-	)
-	if err != nil {
+	if err := c.getCacheable(ctx, endpointPolicy, &pol); err != nil {
 		return nil, err
 	}
 
+	c.cachePolicy(&pol)
+
 	return &pol, nil
 }
 
@@ -304,7 +496,7 @@ func (c *Client) StatsExpiring(
 	page, perPage int,
 	from, to time.Time,
 ) ([]CertMeta, int64, error) {
-	return c.statsCommon(ctx, endpointStatsExpiring, page, perPage, from, to)
+	return statsCount(c.StatsExpiringWithPagination(ctx, page, perPage, from, to))
 }
 
 // StatsIssued returns a slice of the certificates which were issued during
@@ -320,7 +512,7 @@ func (c *Client) StatsIssued(
 	page, perPage int,
 	from, to time.Time,
 ) ([]CertMeta, int64, error) {
-	return c.statsCommon(ctx, endpointStatsIssued, page, perPage, from, to)
+	return statsCount(c.StatsIssuedWithPagination(ctx, page, perPage, from, to))
 }
 
 // StatsRevoked returns a slice of the certificates which were revoked during
@@ -336,6 +528,38 @@ func (c *Client) StatsRevoked(
 	page, perPage int,
 	from, to time.Time,
 ) ([]CertMeta, int64, error) {
+	return statsCount(c.StatsRevokedWithPagination(ctx, page, perPage, from, to))
+}
+
+// StatsExpiringWithPagination is the same as StatsExpiring, but it returns
+// the pagination of the listing in place of the total count. The NextPage
+// method of the pagination returns the number of the next page to request,
+// if any.
+func (c *Client) StatsExpiringWithPagination(
+	ctx context.Context,
+	page, perPage int,
+	from, to time.Time,
+) ([]CertMeta, *Pagination, error) {
+	return c.statsCommon(ctx, endpointStatsExpiring, page, perPage, from, to)
+}
+
+// StatsIssuedWithPagination is the same as StatsIssued, but it returns the
+// pagination of the listing in place of the total count.
+func (c *Client) StatsIssuedWithPagination(
+	ctx context.Context,
+	page, perPage int,
+	from, to time.Time,
+) ([]CertMeta, *Pagination, error) {
+	return c.statsCommon(ctx, endpointStatsIssued, page, perPage, from, to)
+}
+
+// StatsRevokedWithPagination is the same as StatsRevoked, but it returns the
+// pagination of the listing in place of the total count.
+func (c *Client) StatsRevokedWithPagination(
+	ctx context.Context,
+	page, perPage int,
+	from, to time.Time,
+) ([]CertMeta, *Pagination, error) {
 	return c.statsCommon(ctx, endpointStatsRevoked, page, perPage, from, to)
 }
 
@@ -345,7 +569,7 @@ func (c *Client) statsCommon(
 	path string,
 	page, perPage int,
 	from, to time.Time,
-) ([]CertMeta, int64, error) {
+) ([]CertMeta, *Pagination, error) {
 	var stats []CertMeta
 	var r, err = c.makeRequest(
 		ctx,
@@ -355,16 +579,26 @@ func (c *Client) statsCommon(
 		&stats,
 	)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
 	}
 
 	var count int64
 	count, err = intHeaderFromResponse(r, totalCountHeaderName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return stats, newPagination(page, perPage, len(stats), count), nil
+}
+
+// statsCount converts the results of a /stats method which returns the
+// pagination into results with the total count in its place.
+func statsCount(stats []CertMeta, p *Pagination, err error) ([]CertMeta, int64, error) {
 	if err != nil {
 		return nil, 0, err
 	}
 
-	return stats, count, nil
+	return stats, p.TotalCount, nil
 }
 
 // ClaimsDomains returns a slice of either pending or verified domain claims
@@ -374,42 +608,72 @@ func (c *Client) statsCommon(
 // enforces a maximum number of claims per page. If the total count is higher
 // than the number of claims in the slice, the remaining claims may be
 // retrieved by incrementing the page number in subsequent calls of this
-// method.
+// method. Specify StatusAll to list claims regardless of their status.
 func (c *Client) ClaimsDomains(
 	ctx context.Context,
 	page, perPage int,
 	status ClaimStatus,
 ) ([]Claim, int64, error) {
+	var claims, p, err = c.ClaimsDomainsWithPagination(ctx, page, perPage, status)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return claims, p.TotalCount, nil
+}
+
+// ClaimsDomainsWithPagination is the same as ClaimsDomains, but it returns
+// the pagination of the listing in place of the total count, as described
+// for StatsExpiringWithPagination.
+func (c *Client) ClaimsDomainsWithPagination(
+	ctx context.Context,
+	page, perPage int,
+	status ClaimStatus,
+) ([]Claim, *Pagination, error) {
+	// Omit the status to list claims regardless of status.
+	var statusQuery string
+	if status != StatusAll {
+		statusQuery = fmt.Sprintf("&status=%s", status)
+	}
+
 	var claims []Claim
 	var r, err = c.makeRequest(
 		ctx,
 		endpointClaimsDomains+
 			paginationString(page, perPage, time.Time{}, time.Time{})+
-			fmt.Sprintf("&status=%s", status),
+			statusQuery,
 		http.MethodGet,
 		nil,
 		&claims,
 	)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
 	}
 
 	var count int64
 	count, err = intHeaderFromResponse(r, totalCountHeaderName)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
 	}
 
-	return claims, count, nil
+	return claims, newPagination(page, perPage, len(claims), count), nil
 }
 
 // ClaimSubmit submits a new domain claim and returns the token value that
-// should be used to verify control of that domain.
+// should be used to verify control of that domain. A domain name containing
+// Unicode characters is converted to A-labels before it is submitted, and
+// HVCA reports the claimed domain in that form.
 func (c *Client) ClaimSubmit(ctx context.Context, domain string) (*ClaimAssertionInfo, error) {
+	var endpoint, err = endpointPath(endpointClaimsDomains, DomainToASCII(domain))
+	if err != nil {
+		return nil, err
+	}
+
 	var info ClaimAssertionInfo
-	var r, err = c.makeRequest(
+	var r *http.Response
+	r, err = c.makeRequest(
 		ctx,
-		endpointClaimsDomains+"/"+url.QueryEscape(domain),
+		endpoint,
 		http.MethodPost,
 		nil,
 		&info,
@@ -431,10 +695,15 @@ func (c *Client) ClaimSubmit(ctx context.Context, domain string) (*ClaimAssertio
 
 // ClaimRetrieve returns a domain claim.
 func (c *Client) ClaimRetrieve(ctx context.Context, id string) (*Claim, error) {
+	var endpoint, err = endpointPath(endpointClaimsDomains, id)
+	if err != nil {
+		return nil, err
+	}
+
 	var claim Claim
-	var _, err = c.makeRequest(
+	_, err = c.makeRequest(
 		ctx,
-		endpointClaimsDomains+"/"+url.QueryEscape(id),
+		endpoint,
 		http.MethodGet,
 		nil,
 		&claim,
@@ -448,9 +717,14 @@ func (c *Client) ClaimRetrieve(ctx context.Context, id string) (*Claim, error) {
 
 // ClaimDelete deletes a domain claim.
 func (c *Client) ClaimDelete(ctx context.Context, id string) error {
-	var _, err = c.makeRequest(
+	var endpoint, err = endpointPath(endpointClaimsDomains, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.makeRequest(
 		ctx,
-		endpointClaimsDomains+"/"+url.QueryEscape(id),
+		endpoint,
 		http.MethodDelete,
 		nil,
 		nil,
@@ -471,7 +745,7 @@ func (c *Client) ClaimDNS(ctx context.Context, id, authDomain string) (bool, err
 	// an authorization domain was provided.
 	//
 	if authDomain != "" {
-		body = claimsDNSRequest{AuthorizationDomain: authDomain}
+		body = claimsDNSRequest{AuthorizationDomain: DomainToASCII(authDomain)}
 	}
 
 	return c.claimAssert(ctx, body, id, pathDNS)
@@ -483,11 +757,13 @@ func (c *Client) ClaimDNS(ctx context.Context, id, authDomain string) (bool, err
 // indicates that domain control was verified.
 func (c *Client) ClaimHTTP(ctx context.Context, id, authDomain, scheme string) (bool, error) {
 	var body = claimsHTTPRequest{
-		AuthorizationDomain: authDomain,
+		AuthorizationDomain: DomainToASCII(authDomain),
 		Scheme:              scheme,
 	}
 
-	return c.claimAssert(ctx, body, id, pathHTTP)
+	var verified, err = c.claimAssert(ctx, body, id, pathHTTP)
+
+	return verified, notSupported(opClaimHTTP, err)
 }
 
 // ClaimEmail requests for an email with a verification link be sent to the
@@ -500,22 +776,30 @@ func (c *Client) ClaimEmail(ctx context.Context, id, emailAddress string) (bool,
 		EmailAddress: emailAddress,
 	}
 
-	return c.claimAssert(ctx, body, id, pathEmail)
+	var verified, err = c.claimAssert(ctx, body, id, pathEmail)
+
+	return verified, notSupported(opClaimEmail, err)
 }
 
 // ClaimEmailRetrieve retrieves a list of email addresses authorized to perform
 // Email validation.
 func (c *Client) ClaimEmailRetrieve(ctx context.Context, id string) (*AuthorisedEmails, error) {
+	var endpoint, err = endpointPath(endpointClaimsDomains, id)
+	if err != nil {
+		return nil, err
+	}
+
 	var authorisedEmails AuthorisedEmails
-	var response, err = c.makeRequest(
+	var response *http.Response
+	response, err = c.makeRequest(
 		ctx,
-		endpointClaimsDomains+"/"+url.QueryEscape(id)+pathEmail,
+		endpoint+pathEmail,
 		http.MethodGet,
 		nil,
 		&authorisedEmails,
 	)
 	if err != nil {
-		return nil, err
+		return nil, notSupported(opClaimEmail, err)
 	}
 
 	switch response.StatusCode {
@@ -529,10 +813,16 @@ func (c *Client) ClaimEmailRetrieve(ctx context.Context, id string) (*Authorised
 // ClaimReassert reasserts an existing domain claim, for example if the
 // assert-by time of a previous assertion request has expired.
 func (c *Client) ClaimReassert(ctx context.Context, id string) (*ClaimAssertionInfo, error) {
+	var endpoint, err = endpointPath(endpointClaimsDomains, id)
+	if err != nil {
+		return nil, err
+	}
+
 	var info ClaimAssertionInfo
-	var r, err = c.makeRequest(
+	var r *http.Response
+	r, err = c.makeRequest(
 		ctx,
-		endpointClaimsDomains+"/"+url.QueryEscape(id)+pathReassert,
+		endpoint+pathReassert,
 		http.MethodPost,
 		nil,
 		&info,
@@ -553,9 +843,15 @@ func (c *Client) ClaimReassert(ctx context.Context, id string) (*ClaimAssertionI
 }
 
 func (c *Client) claimAssert(ctx context.Context, body interface{}, id, path string) (bool, error) {
-	var response, err = c.makeRequest(
+	var endpoint, err = endpointPath(endpointClaimsDomains, id)
+	if err != nil {
+		return false, err
+	}
+
+	var response *http.Response
+	response, err = c.makeRequest(
 		ctx,
-		endpointClaimsDomains+"/"+url.QueryEscape(id)+path,
+		endpoint+path,
 		http.MethodPost,
 		body,
 		nil,
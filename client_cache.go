@@ -0,0 +1,204 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// responseCache holds cached copies of HVCA responses which change rarely,
+// such as the validation policy and the trust chain, and the results of
+// recent certificate requests.
+type responseCache struct {
+	mtx               sync.RWMutex
+	policy            *Policy
+	policyFetched     time.Time
+	trustChain        []*x509.Certificate
+	trustChainFetched time.Time
+	requests          map[string]rememberedRequest
+}
+
+// rememberedRequest is the result of a recent successful certificate request.
+type rememberedRequest struct {
+	serial   *big.Int
+	location *url.URL
+	issued   time.Time
+}
+
+// InvalidateCaches discards any cached validation policy and trust chain,
+// forcing the next call to Policy or TrustChain to fetch a fresh copy from
+// HVCA. It also discards any remembered certificate requests, so the next
+// request will be submitted to HVCA even if it is identical to a recent one.
+// It is a no-op if caching is not enabled.
+func (c *Client) InvalidateCaches() {
+	c.cache.mtx.Lock()
+	defer c.cache.mtx.Unlock()
+
+	c.cache.policy = nil
+	c.cache.policyFetched = time.Time{}
+	c.cache.trustChain = nil
+	c.cache.trustChainFetched = time.Time{}
+	c.cache.requests = nil
+}
+
+// cachedPolicy returns the cached validation policy, or nil if caching is
+// disabled or if no unexpired policy is cached.
+func (c *Client) cachedPolicy() *Policy {
+	if c.config.CachePolicyTTL <= 0 {
+		return nil
+	}
+
+	c.cache.mtx.RLock()
+	defer c.cache.mtx.RUnlock()
+
+	if c.cache.policy == nil || c.now().Sub(c.cache.policyFetched) > c.config.CachePolicyTTL {
+		return nil
+	}
+
+	return c.cache.policy
+}
+
+// cachePolicy stores a validation policy in the cache, if caching is enabled.
+func (c *Client) cachePolicy(pol *Policy) {
+	if c.config.CachePolicyTTL <= 0 {
+		return
+	}
+
+	c.cache.mtx.Lock()
+	defer c.cache.mtx.Unlock()
+
+	c.cache.policy = pol
+	c.cache.policyFetched = c.now()
+}
+
+// cachedTrustChain returns a copy of the cached trust chain, or nil if
+// caching is disabled or if no unexpired trust chain is cached.
+func (c *Client) cachedTrustChain() []*x509.Certificate {
+	if c.config.CachePolicyTTL <= 0 {
+		return nil
+	}
+
+	c.cache.mtx.RLock()
+	defer c.cache.mtx.RUnlock()
+
+	if c.cache.trustChain == nil || c.now().Sub(c.cache.trustChainFetched) > c.config.CachePolicyTTL {
+		return nil
+	}
+
+	return append([]*x509.Certificate(nil), c.cache.trustChain...)
+}
+
+// cacheTrustChain stores a trust chain in the cache, if caching is enabled.
+func (c *Client) cacheTrustChain(certs []*x509.Certificate) {
+	if c.config.CachePolicyTTL <= 0 {
+		return
+	}
+
+	c.cache.mtx.Lock()
+	defer c.cache.mtx.Unlock()
+
+	c.cache.trustChain = append([]*x509.Certificate(nil), certs...)
+	c.cache.trustChainFetched = c.now()
+}
+
+// requestKey returns a key which uniquely identifies the contents of a
+// certificate request, for use in deduplicating requests. The proof of
+// possession signature is excluded, since an ECDSA signature differs each
+// time a request is marshalled, and the public key it signs identifies the
+// key in any case.
+func requestKey(req *Request) (string, error) {
+	var data, err = json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return "", err
+	}
+
+	delete(fields, "public_key_signature")
+
+	// Map keys are marshalled in sorted order, so the encoding is canonical.
+	if data, err = json.Marshal(fields); err != nil {
+		return "", err
+	}
+
+	var sum = sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// recentRequest returns the serial number and location of the certificate
+// issued in response to a recent request with the specified key, or nil if
+// deduplication is disabled or if no such request was made within the
+// deduplication window.
+func (c *Client) recentRequest(key string) (*big.Int, *url.URL) {
+	if c.config.DeduplicationWindow <= 0 {
+		return nil, nil
+	}
+
+	c.cache.mtx.RLock()
+	defer c.cache.mtx.RUnlock()
+
+	var recent, ok = c.cache.requests[key]
+	if !ok || c.now().Sub(recent.issued) > c.config.DeduplicationWindow {
+		return nil, nil
+	}
+
+	var location = *recent.location
+
+	return big.NewInt(0).Set(recent.serial), &location
+}
+
+// rememberRequest stores the serial number and location of the certificate
+// issued in response to a request with the specified key, if deduplication
+// is enabled. Any expired requests are discarded at the same time.
+func (c *Client) rememberRequest(key string, serial *big.Int, location *url.URL) {
+	if c.config.DeduplicationWindow <= 0 {
+		return
+	}
+
+	c.cache.mtx.Lock()
+	defer c.cache.mtx.Unlock()
+
+	var now = c.now()
+
+	for k, recent := range c.cache.requests {
+		if now.Sub(recent.issued) > c.config.DeduplicationWindow {
+			delete(c.cache.requests, k)
+		}
+	}
+
+	if c.cache.requests == nil {
+		c.cache.requests = make(map[string]rememberedRequest)
+	}
+
+	var loc = *location
+
+	c.cache.requests[key] = rememberedRequest{
+		serial:   big.NewInt(0).Set(serial),
+		location: &loc,
+		issued:   now,
+	}
+}
@@ -0,0 +1,247 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testECDSAKey is a private key used to sign the public key in requests.
+var testECDSAKey = mustGenerateECDSAKey()
+
+// mustGenerateECDSAKey generates a new ECDSA private key, and panics on
+// failure.
+func mustGenerateECDSAKey() *ecdsa.PrivateKey {
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	return key
+}
+
+func TestCertificateRequestDeduplication(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name   string
+		window time.Duration
+		header string
+		req1   *Request
+		req2   *Request
+		want   int32
+	}{
+		{
+			name: "Disabled",
+			req2: &Request{Subject: &DN{CommonName: "John Doe"}},
+			want: 2,
+		},
+		{
+			name:   "Identical",
+			window: time.Hour,
+			header: "Idempotency-Key",
+			req2:   &Request{Subject: &DN{CommonName: "John Doe"}},
+			want:   1,
+		},
+		{
+			name:   "IdenticalECDSA",
+			window: time.Hour,
+			header: "Idempotency-Key",
+			req1:   &Request{Subject: &DN{CommonName: "John Doe"}, PrivateKey: testECDSAKey},
+			req2:   &Request{Subject: &DN{CommonName: "John Doe"}, PrivateKey: testECDSAKey},
+			want:   1,
+		},
+		{
+			name:   "Different",
+			window: time.Hour,
+			req2:   &Request{Subject: &DN{CommonName: "Jane Doe"}},
+			want:   2,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var count int32
+			var keys = make(chan string, 2)
+			var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var n = atomic.AddInt32(&count, 1)
+				select {
+				case keys <- r.Header.Get("Idempotency-Key"):
+				default:
+				}
+				w.Header().Set(certSNHeaderName, fmt.Sprintf("%s/certificates/%X", endpointCertificates, n))
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			var clnt, _ = newTestClient(t, handler, &Config{
+				DeduplicationWindow:  tc.window,
+				IdempotencyKeyHeader: tc.header,
+			})
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+
+			var req1 = tc.req1
+			if req1 == nil {
+				req1 = &Request{Subject: &DN{CommonName: "John Doe"}}
+			}
+
+			var first, second *big.Int
+			var err error
+			if first, err = clnt.CertificateRequest(ctx, req1); err != nil {
+				t.Fatalf("couldn't request certificate: %v", err)
+			}
+
+			if second, err = clnt.CertificateRequest(ctx, tc.req2); err != nil {
+				t.Fatalf("couldn't request certificate: %v", err)
+			}
+
+			if got := atomic.LoadInt32(&count); got != tc.want {
+				t.Fatalf("got %d requests, want %d", got, tc.want)
+			}
+
+			if (first.Cmp(second) == 0) != (tc.want == 1) {
+				t.Errorf("got serial numbers %X and %X", first, second)
+			}
+
+			if tc.header != "" {
+				if key := <-keys; len(key) != 32 {
+					t.Errorf("got idempotency key %q, want 32 hex digits", key)
+				}
+			}
+
+			// Invalidating the caches should cause the request to be made
+			// again.
+			clnt.InvalidateCaches()
+
+			if _, err := clnt.CertificateRequest(ctx, tc.req2); err != nil {
+				t.Fatalf("couldn't request certificate: %v", err)
+			}
+
+			if got := atomic.LoadInt32(&count); got != tc.want+1 {
+				t.Errorf("got %d requests after invalidating caches, want %d", got, tc.want+1)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	var keys []string
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+
+		// Fail the first request, so that it is retried.
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set(certSNHeaderName, fmt.Sprintf("%s/certificates/%X", endpointCertificates, len(keys)))
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	var clnt, _ = newTestClient(t, handler, &Config{Retries: 1, IdempotencyKeyHeader: "Idempotency-Key"})
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var req = &Request{Subject: &DN{CommonName: "John Doe"}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := clnt.CertificateRequest(ctx, req); err != nil {
+			t.Fatalf("couldn't request certificate: %v", err)
+		}
+	}
+
+	if len(keys) != 3 {
+		t.Fatalf("got %d requests, want 3", len(keys))
+	}
+
+	if keys[0] == "" || keys[1] != keys[0] {
+		t.Errorf("got idempotency keys %q and %q for a request and its retry, want the same key", keys[0], keys[1])
+	}
+
+	if keys[2] == keys[0] {
+		t.Errorf("got the same idempotency key %q for separate calls", keys[2])
+	}
+}
+
+func TestRequestKey(t *testing.T) {
+	t.Parallel()
+
+	var otherKey = mustGenerateECDSAKey()
+
+	var testcases = []struct {
+		name  string
+		first *Request
+		other *Request
+		equal bool
+	}{
+		{
+			name:  "SameKey",
+			first: &Request{Subject: &DN{CommonName: "John Doe"}, PrivateKey: testECDSAKey},
+			other: &Request{Subject: &DN{CommonName: "John Doe"}, PrivateKey: testECDSAKey},
+			equal: true,
+		},
+		{
+			name:  "DifferentKey",
+			first: &Request{Subject: &DN{CommonName: "John Doe"}, PrivateKey: testECDSAKey},
+			other: &Request{Subject: &DN{CommonName: "John Doe"}, PrivateKey: otherKey},
+		},
+		{
+			name:  "DifferentSubject",
+			first: &Request{Subject: &DN{CommonName: "John Doe"}, PrivateKey: testECDSAKey},
+			other: &Request{Subject: &DN{CommonName: "Jane Doe"}, PrivateKey: testECDSAKey},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var first, err = requestKey(tc.first)
+			if err != nil {
+				t.Fatalf("couldn't compute request key: %v", err)
+			}
+
+			var other string
+			if other, err = requestKey(tc.other); err != nil {
+				t.Fatalf("couldn't compute request key: %v", err)
+			}
+
+			if (first == other) != tc.equal {
+				t.Errorf("got keys %s and %s, want equal %t", first, other, tc.equal)
+			}
+		})
+	}
+}
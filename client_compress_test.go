@@ -0,0 +1,100 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMakeRequestCompression(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name      string
+		threshold int64
+		value     string
+		want      bool
+	}{
+		{
+			name:  "Disabled",
+			value: strings.Repeat("A", 1024),
+		},
+		{
+			name:      "BelowThreshold",
+			threshold: 1024,
+			value:     "short",
+		},
+		{
+			name:      "AboveThreshold",
+			threshold: 1024,
+			value:     strings.Repeat("A", 1024),
+			want:      true,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotEncoding, gotBody string
+
+			var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotEncoding = r.Header.Get("Content-Encoding")
+
+				var body = r.Body
+				if gotEncoding == "gzip" {
+					var zr, err = gzip.NewReader(r.Body)
+					if err != nil {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+
+					body = zr
+				}
+
+				var data, _ = ioutil.ReadAll(body)
+				gotBody = string(data)
+
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			var clnt, _ = newTestClient(t, handler, &Config{CompressRequestsOver: tc.threshold})
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+			defer cancel()
+
+			if _, err := clnt.makeRequest(ctx, endpointCertificates, http.MethodPost, map[string]string{"value": tc.value}, nil); err != nil {
+				t.Fatalf("couldn't make request: %v", err)
+			}
+
+			if (gotEncoding == "gzip") != tc.want {
+				t.Errorf("got content encoding %q, want compressed %t", gotEncoding, tc.want)
+			}
+
+			if want := `{"value":"` + tc.value + `"}`; gotBody != want {
+				t.Errorf("got body %q, want %q", gotBody, want)
+			}
+		})
+	}
+}
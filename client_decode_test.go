@@ -0,0 +1,216 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalStrict(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		data  string
+		valid bool
+	}{
+		{
+			name:  "OK",
+			data:  `{"access_token":"token"}`,
+			valid: true,
+		},
+		{
+			name:  "TrailingWhitespace",
+			data:  "{\"access_token\":\"token\"}\n",
+			valid: true,
+		},
+		{
+			name: "UnknownField",
+			data: `{"access_token":"token","refresh_token":"token"}`,
+		},
+		{
+			name: "TrailingData",
+			data: `{"access_token":"token"}{}`,
+		},
+		{
+			name: "BadJSON",
+			data: `{"access_token":`,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got loginResponse
+			var err = unmarshalStrict([]byte(tc.data), &got)
+			if (err == nil) != tc.valid {
+				t.Fatalf("got error %v, want valid %t", err, tc.valid)
+			}
+
+			if tc.valid && got.AccessToken != "token" {
+				t.Errorf("got token %q, want %q", got.AccessToken, "token")
+			}
+		})
+	}
+}
+
+func TestDecodeResponseBody(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name   string
+		data   string
+		strict bool
+		max    int64
+		err    error
+		valid  bool
+	}{
+		{
+			name:  "OK",
+			data:  `{"access_token":"token"}`,
+			max:   1024,
+			valid: true,
+		},
+		{
+			name:  "ExactlyMaximumSize",
+			data:  `{"access_token":"token"}`,
+			max:   int64(len(`{"access_token":"token"}`)),
+			valid: true,
+		},
+		{
+			name:  "UnknownFieldNotStrict",
+			data:  `{"access_token":"token","refresh_token":"token"}`,
+			max:   1024,
+			valid: true,
+		},
+		{
+			name:   "UnknownFieldStrict",
+			data:   `{"access_token":"token","refresh_token":"token"}`,
+			strict: true,
+			max:    1024,
+		},
+		{
+			name: "TrailingData",
+			data: `{"access_token":"token"}{}`,
+			max:  1024,
+		},
+		{
+			name: "TooLarge",
+			data: `{"access_token":"token"}`,
+			max:  16,
+			err:  ErrResponseTooLarge,
+		},
+		{
+			name: "TrailingWhitespaceTooLarge",
+			data: `{"access_token":"token"}` + strings.Repeat(" ", 1024),
+			max:  512,
+			err:  ErrResponseTooLarge,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var clnt = &Client{config: &Config{MaxResponseSize: tc.max, StrictDecoding: tc.strict}}
+			var response = &http.Response{Body: ioutil.NopCloser(strings.NewReader(tc.data))}
+
+			var got loginResponse
+			var err = clnt.decodeResponseBody(http.MethodGet, "/login", "", response, &got)
+			if (err == nil) != tc.valid {
+				t.Fatalf("got error %v, want valid %t", err, tc.valid)
+			}
+
+			if tc.err != nil && !errors.Is(err, tc.err) {
+				t.Errorf("got error %v, want %v", err, tc.err)
+			}
+
+			if tc.valid && got.AccessToken != "token" {
+				t.Errorf("got token %q, want %q", got.AccessToken, "token")
+			}
+		})
+	}
+}
+
+// chunkCheckReader fails a test if it is asked to read more than
+// responseChunkSize bytes at once, and cancels a context once a specified
+// number of bytes have been read.
+type chunkCheckReader struct {
+	t      *testing.T
+	r      io.Reader
+	n      int
+	after  int
+	cancel context.CancelFunc
+}
+
+func (r *chunkCheckReader) Read(p []byte) (int, error) {
+	if len(p) > responseChunkSize {
+		r.t.Errorf("got read of %d bytes, want at most %d", len(p), responseChunkSize)
+	}
+
+	var n, err = r.r.Read(p)
+	if r.n += n; r.n >= r.after {
+		r.cancel()
+	}
+
+	return n, err
+}
+
+func TestDecodeResponseBodyCancelled(t *testing.T) {
+	t.Parallel()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var request, err = http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com/policy", nil)
+	if err != nil {
+		t.Fatalf("couldn't create request: %v", err)
+	}
+
+	var data = `{"access_token":"` + strings.Repeat("a", responseChunkSize*4) + `"}`
+	var response = &http.Response{
+		Request: request,
+		Body: ioutil.NopCloser(&chunkCheckReader{
+			t:      t,
+			r:      strings.NewReader(data),
+			after:  responseChunkSize,
+			cancel: cancel,
+		}),
+	}
+
+	var clnt = &Client{config: &Config{MaxResponseSize: 1 << 20}}
+
+	var got loginResponse
+	if err = clnt.decodeResponseBody(http.MethodGet, "/policy", "", response, &got); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		t.Errorf("got error type %T, want %T", err, respErr)
+	}
+}
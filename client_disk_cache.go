@@ -0,0 +1,128 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/globalsign/hvclient/internal/fileutils"
+	"github.com/globalsign/hvclient/internal/httputils"
+)
+
+// diskCacheEntry is an HVCA response cached on disk with its entity tag.
+type diskCacheEntry struct {
+	ETag string          `json:"etag"`
+	Body json.RawMessage `json:"body"`
+}
+
+// getCacheable makes a GET request to an endpoint whose response changes
+// rarely, such as the validation policy, and unmarshals the response body
+// into out. If a cache directory is configured, the request is conditional
+// on the response having changed since it was cached on disk, and the cache
+// is updated with any changed response which has an entity tag.
+func (c *Client) getCacheable(ctx context.Context, path string, out interface{}) error {
+	if c.config.CacheDir == "" {
+		var _, err = c.makeRequest(ctx, path, http.MethodGet, nil, out)
+		return err
+	}
+
+	var filename = c.diskCacheFile(path)
+	var cached = readDiskCache(filename)
+
+	var headers http.Header
+	if cached != nil {
+		headers = http.Header{httputils.IfNoneMatchHeader: []string{cached.ETag}}
+	}
+
+	var body rawBody
+	var response, err = c.makeRequestWithHeaders(ctx, path, http.MethodGet, headers, nil, &body)
+	if err != nil {
+		return err
+	}
+
+	var requestID = response.Request.Header.Get(RequestIDHeader)
+
+	if response.StatusCode == http.StatusNotModified {
+		return c.unmarshalResponseBody(http.MethodGet, path, requestID, cached.Body, out)
+	}
+
+	if err = httputils.VerifyResponseContentType(response, httputils.ContentTypeJSON); err != nil {
+		return &ResponseError{Method: http.MethodGet, Path: path, RequestID: requestID, Err: err}
+	}
+
+	if err = c.unmarshalResponseBody(http.MethodGet, path, requestID, body, out); err != nil {
+		return err
+	}
+
+	if etag := response.Header.Get(httputils.ETagHeader); etag != "" {
+		writeDiskCache(filename, &diskCacheEntry{ETag: etag, Body: json.RawMessage(body)})
+	}
+
+	return nil
+}
+
+// diskCacheFile returns the name of the file in which the response from an
+// endpoint is cached. The name is derived from the HVCA URL and API key as
+// well as the endpoint, so that clients for different accounts sharing a
+// cache directory don't overwrite each other's responses.
+func (c *Client) diskCacheFile(path string) string {
+	var h = sha256.New()
+	for _, s := range []string{c.config.URL, c.config.APIKey, path} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+
+	return filepath.Join(c.config.CacheDir, hex.EncodeToString(h.Sum(nil)[:16])+".json")
+}
+
+// readDiskCache returns the response cached in the specified file, or nil
+// if there is none or it cannot be read.
+func readDiskCache(filename string) *diskCacheEntry {
+	var data, err = ioutil.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+
+	var entry diskCacheEntry
+	if err = json.Unmarshal(data, &entry); err != nil || entry.ETag == "" || len(entry.Body) == 0 {
+		return nil
+	}
+
+	return &entry
+}
+
+// writeDiskCache writes a response to the specified cache file, replacing
+// it atomically so that concurrent processes never read a partial entry.
+// Failures are ignored, since the cache is only an optimisation.
+func writeDiskCache(filename string, entry *diskCacheEntry) {
+	var data, err = json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return
+	}
+
+	fileutils.WriteFile(filename, data, 0600)
+}
@@ -0,0 +1,158 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+	"github.com/google/go-cmp/cmp"
+)
+
+// statusRecorder records the status codes of the responses to requests for
+// the validation policy and trust chain.
+type statusRecorder struct {
+	mtx      sync.Mutex
+	statuses []int
+}
+
+func (s *statusRecorder) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rec = httptest.NewRecorder()
+		next.ServeHTTP(rec, r)
+
+		if r.URL.Path == "/validationpolicy" || r.URL.Path == "/trustchain" {
+			s.mtx.Lock()
+			s.statuses = append(s.statuses, rec.Code)
+			s.mtx.Unlock()
+		}
+
+		for key, values := range rec.Header() {
+			w.Header()[key] = values
+		}
+
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(rec.Body.Bytes())
+	})
+}
+
+func (s *statusRecorder) take() []int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var statuses = s.statuses
+	s.statuses = nil
+
+	return statuses
+}
+
+func TestClientDiskCache(t *testing.T) {
+	t.Parallel()
+
+	var rec statusRecorder
+	var server = httptest.NewServer(rec.wrap(hvcatest.NewHandler()))
+	defer server.Close()
+
+	var dir = t.TempDir()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var fetch = func(t *testing.T) {
+		t.Helper()
+
+		var conf = hvcatest.Config(server.URL)
+		conf.CacheDir = dir
+
+		var client, err = hvclient.NewClient(ctx, conf)
+		if err != nil {
+			t.Fatalf("couldn't create client: %v", err)
+		}
+		defer client.Close()
+
+		var pol *hvclient.Policy
+		if pol, err = client.Policy(ctx); err != nil {
+			t.Fatalf("couldn't get policy: %v", err)
+		}
+
+		if !cmp.Equal(*pol, hvcatest.Policy) {
+			t.Errorf("got policy %v, want %v", *pol, hvcatest.Policy)
+		}
+
+		var certs []*x509.Certificate
+		if certs, err = client.TrustChain(ctx); err != nil {
+			t.Fatalf("couldn't get trust chain: %v", err)
+		}
+
+		if len(certs) != len(hvcatest.TrustChain) {
+			t.Fatalf("got %d certificates, want %d", len(certs), len(hvcatest.TrustChain))
+		}
+
+		for i := range certs {
+			if !certs[i].Equal(hvcatest.TrustChain[i]) {
+				t.Errorf("certificate %d does not match", i)
+			}
+		}
+	}
+
+	// The first client populates the cache.
+	fetch(t)
+
+	if got, want := rec.take(), []int{http.StatusOK, http.StatusOK}; !cmp.Equal(got, want) {
+		t.Errorf("got statuses %v, want %v", got, want)
+	}
+
+	var files, err = filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("got cache files %q, %v, want 2 files", files, err)
+	}
+
+	// A second client revalidates the cached responses, which are unchanged.
+	fetch(t)
+
+	if got, want := rec.take(), []int{http.StatusNotModified, http.StatusNotModified}; !cmp.Equal(got, want) {
+		t.Errorf("got statuses %v, want %v", got, want)
+	}
+
+	// Stale or corrupt cache entries are replaced.
+	if err = ioutil.WriteFile(files[0], []byte(`{"etag":"\"stale\"","body":{}}`), 0600); err != nil {
+		t.Fatalf("couldn't write cache file: %v", err)
+	}
+
+	if err = ioutil.WriteFile(files[1], []byte(`not JSON`), 0600); err != nil {
+		t.Fatalf("couldn't write cache file: %v", err)
+	}
+
+	fetch(t)
+
+	if got, want := rec.take(), []int{http.StatusOK, http.StatusOK}; !cmp.Equal(got, want) {
+		t.Errorf("got statuses %v, want %v", got, want)
+	}
+
+	fetch(t)
+
+	if got, want := rec.take(), []int{http.StatusNotModified, http.StatusNotModified}; !cmp.Equal(got, want) {
+		t.Errorf("got statuses %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/globalsign/hvclient/internal/httputils"
+)
+
+// APIResponse is the response to an HVCA API call made with Client.Do.
+type APIResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Do makes an arbitrary HVCA API call, for endpoints which the client does
+// not otherwise wrap. The path is relative to the HVCA URL in the client
+// configuration, e.g. "/quotas/issuance", and may include a query string.
+// If in is non-nil, it is marshalled to JSON and sent as the request body.
+// If out is non-nil and the response has a body, the body is unmarshalled
+// into it.
+//
+// The client logs in, retries and reports errors exactly as it does for
+// the wrapped API calls, so a response with a status code outside of the
+// 2XX range is returned as an *APIError. The returned response contains the
+// status code, headers and raw body of the successful response, giving
+// access to information such as the Location and Total-Count headers.
+func (c *Client) Do(ctx context.Context, method, path string, in, out interface{}) (*APIResponse, error) {
+	var body rawBody
+
+	var response, err = c.makeRequest(ctx, path, method, in, &body)
+	if err != nil {
+		return nil, err
+	}
+
+	if out != nil && len(body) > 0 {
+		if err = httputils.VerifyResponseContentType(response, httputils.ContentTypeJSON); err != nil {
+			return nil, &ResponseError{Method: method, Path: path, RequestID: requestIDOf(response), Err: err}
+		}
+
+		if err = c.unmarshalResponseBody(method, path, requestIDOf(response), body, out); err != nil {
+			return nil, err
+		}
+	}
+
+	return &APIResponse{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       body,
+	}, nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+)
+
+func TestClientDo(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name       string
+		method     string
+		path       string
+		in         interface{}
+		wantStatus int
+		wantHeader string
+		wantValue  string
+	}{
+		{
+			name:       "Quota",
+			method:     http.MethodGet,
+			path:       "/quotas/issuance",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Header",
+			method:     http.MethodGet,
+			path:       "/stats/expiring?page=1&per_page=100&from=0&to=1",
+			wantStatus: http.StatusOK,
+			wantHeader: "Total-Count",
+			wantValue:  "4",
+		},
+		{
+			name:   "NoContent",
+			method: http.MethodPatch,
+			path:   "/certificates/" + mockCertSerial,
+			in: map[string]interface{}{
+				"revocation_reason": "unspecified",
+				"revocation_time":   0,
+			},
+			wantStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var clnt, closefunc = newMockClient(t)
+			defer closefunc()
+
+			var ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+
+			var got, err = clnt.Do(ctx, tc.method, tc.path, tc.in, nil)
+			if err != nil {
+				t.Fatalf("couldn't make API call: %v", err)
+			}
+
+			if got.StatusCode != tc.wantStatus {
+				t.Errorf("got status code %d, want %d", got.StatusCode, tc.wantStatus)
+			}
+
+			if tc.wantHeader != "" {
+				if value := got.Header.Get(tc.wantHeader); value != tc.wantValue {
+					t.Errorf("got %s header %q, want %q", tc.wantHeader, value, tc.wantValue)
+				}
+			}
+		})
+	}
+}
+
+func TestClientDoUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	var clnt, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var out struct {
+		Value int64 `json:"value"`
+	}
+
+	var got, err = clnt.Do(ctx, http.MethodGet, "/quotas/issuance", nil, &out)
+	if err != nil {
+		t.Fatalf("couldn't make API call: %v", err)
+	}
+
+	if out.Value != hvcatest.QuotaIssuance {
+		t.Errorf("got value %d, want %d", out.Value, hvcatest.QuotaIssuance)
+	}
+
+	if len(got.Body) == 0 {
+		t.Errorf("got empty response body")
+	}
+}
+
+func TestClientDoFailure(t *testing.T) {
+	t.Parallel()
+
+	var clnt, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var _, err = clnt.Do(ctx, http.MethodPatch, "/certificates/"+hvcatest.SerialNotFound.Text(16),
+		map[string]interface{}{"revocation_reason": "unspecified", "revocation_time": 0}, nil)
+
+	var apiErr hvclient.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got error %v, want an APIError", err)
+	}
+
+	if apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("got status code %d, want %d", apiErr.StatusCode, http.StatusNotFound)
+	}
+}
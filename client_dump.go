@@ -0,0 +1,143 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/globalsign/hvclient/internal/httputils"
+)
+
+// redacted replaces sensitive values in request and response dumps.
+const redacted = "REDACTED"
+
+// dumpedHeaders are the names of the HTTP headers whose values are written
+// unredacted in request and response dumps. The values of all other headers,
+// including the authorization header and any extra headers from the
+// configuration or request options, are redacted.
+var dumpedHeaders = []string{
+	"Accept",
+	httputils.ContentEncodingHeader,
+	httputils.ContentTypeHeader,
+	RequestIDHeader,
+	httputils.UserAgentHeader,
+}
+
+// redactedFields are the names of JSON object fields in request and
+// response bodies whose values are redacted in dumps.
+var redactedFields = []string{"api_key", "api_secret", "access_token"}
+
+// dumpRequest writes the HTTP request and response to the writer specified
+// in the configuration, if any. The request body must be supplied separately
+// since it will already have been consumed. The response body is read and
+// replaced, so it can still be read by the caller.
+func (c *Client) dumpRequest(request *http.Request, body []byte, response *http.Response) {
+	if c.config.DumpRequests == nil {
+		return
+	}
+
+	var respBody, err = ioutil.ReadAll(io.LimitReader(response.Body, maxAPIErrorSize))
+	if err != nil {
+		respBody = []byte(fmt.Sprintf("<failed to read response body: %v>", err))
+	}
+
+	response.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "--- HVCA request ---\n%s %s\n", request.Method, request.URL)
+	writeDumpHeaders(&buf, request.Header)
+	writeDumpBody(&buf, body)
+
+	fmt.Fprintf(&buf, "--- HVCA response ---\n%s\n", response.Status)
+	writeDumpHeaders(&buf, response.Header)
+	writeDumpBody(&buf, respBody)
+
+	c.dumpMtx.Lock()
+	defer c.dumpMtx.Unlock()
+
+	c.config.DumpRequests.Write(buf.Bytes())
+}
+
+// writeDumpHeaders writes HTTP headers in sorted order, with the values of
+// headers other than those in dumpedHeaders redacted.
+func writeDumpHeaders(buf *bytes.Buffer, header http.Header) {
+	var keys = make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, value := range header[key] {
+			if !containsFold(dumpedHeaders, key) {
+				value = redacted
+			}
+
+			fmt.Fprintf(buf, "%s: %s\n", key, value)
+		}
+	}
+}
+
+// writeDumpBody writes an HTTP body followed by a blank line, with any
+// sensitive JSON object fields redacted.
+func writeDumpBody(buf *bytes.Buffer, body []byte) {
+	buf.WriteString("\n")
+
+	if len(body) == 0 {
+		return
+	}
+
+	buf.Write(redactBody(body))
+	buf.WriteString("\n\n")
+}
+
+// redactBody returns a copy of a body with the values of any sensitive
+// top-level JSON object fields redacted, and with certificate requests
+// redacted as by Request.Redacted. Bodies which are not JSON objects are
+// returned unchanged.
+func redactBody(body []byte) []byte {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return body
+	}
+
+	var changed = redactRequestFields(obj)
+	for _, field := range redactedFields {
+		if _, ok := obj[field]; ok {
+			obj[field] = json.RawMessage(`"` + redacted + `"`)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return body
+	}
+
+	var data, err = json.Marshal(obj)
+	if err != nil {
+		return body
+	}
+
+	return data
+}
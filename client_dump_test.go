@@ -0,0 +1,197 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/testhelpers"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMakeRequestDump(t *testing.T) {
+	t.Parallel()
+
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"status":422,"description":"subject_dn.common_name is required"}`))
+	})
+
+	var buf bytes.Buffer
+	var clnt, server = newTestClient(t, handler, &Config{
+		DumpRequests: &buf,
+		UserAgent:    "hvclient-test",
+		ExtraHeaders: map[string]string{"X-SSL-Client-Serial": "clientserial"},
+	})
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	ctx = WithRequestHeader(WithRequestID(ctx, "request1234"), "X-Gateway-Key", "gatewaykey")
+
+	var in = map[string]string{"api_secret": "topsecret", "organization": "ACME Inc"}
+
+	var _, err = clnt.makeRequest(ctx, endpointCertificates, http.MethodPost, in, nil)
+
+	// The response body must still be available to build the API error.
+	var apiErr APIError
+	if !errors.As(err, &apiErr) || apiErr.Description != "subject_dn.common_name is required" {
+		t.Fatalf("got error %v, want API error with description", err)
+	}
+
+	var got = buf.String()
+
+	for _, want := range []string{
+		"--- HVCA request ---\nPOST " + server.URL + endpointCertificates + "\n",
+		"Authorization: REDACTED\n",
+		"User-Agent: hvclient-test\n",
+		"X-Request-Id: request1234\n",
+		"X-Gateway-Key: REDACTED\n",
+		"X-Ssl-Client-Serial: REDACTED\n",
+		`{"api_secret":"REDACTED","organization":"ACME Inc"}`,
+		"--- HVCA response ---\n422 Unprocessable Entity\n",
+		`{"status":422,"description":"subject_dn.common_name is required"}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("dump does not contain %q:\n%s", want, got)
+		}
+	}
+
+	for _, secret := range []string{testhelpers.LoginToken, "topsecret", "clientserial", "gatewaykey"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("dump contains unredacted secret %q:\n%s", secret, got)
+		}
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "Login",
+			body: `{"api_key":"key","api_secret":"secret"}`,
+			want: `{"api_key":"REDACTED","api_secret":"REDACTED"}`,
+		},
+		{
+			name: "NothingSensitive",
+			body: `{"b": 1, "a": 2}`,
+			want: `{"b": 1, "a": 2}`,
+		},
+		{
+			name: "NotObject",
+			body: `["api_secret"]`,
+			want: `["api_secret"]`,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := string(redactBody([]byte(tc.body))); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRedactBodyRequest(t *testing.T) {
+	t.Parallel()
+
+	var da = &DA{
+		Gender:             "F",
+		DateOfBirth:        time.Date(1985, 6, 1, 12, 0, 0, 0, time.UTC),
+		PlaceOfBirth:       "Paris",
+		CountryOfResidence: []string{"FR"},
+	}
+
+	var testcases = []struct {
+		name    string
+		request Request
+	}{
+		{
+			name: "PublicKey",
+			request: Request{
+				Subject:    &DN{CommonName: "Jane Doe"},
+				DA:         da,
+				PrivateKey: testhelpers.MustGetPrivateKeyFromFile(t, "testdata/ec_priv.key"),
+			},
+		},
+		{
+			name: "CSR",
+			request: Request{
+				Subject: &DN{CommonName: "Jane Doe"},
+				CSR:     testhelpers.MustGetCSRFromFile(t, "testdata/test_csr.pem"),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var body, err = json.Marshal(tc.request)
+			if err != nil {
+				t.Fatalf("couldn't marshal request: %v", err)
+			}
+
+			var want []byte
+			if want, err = json.Marshal(tc.request.Redacted()); err != nil {
+				t.Fatalf("couldn't marshal redacted request: %v", err)
+			}
+
+			var gotFields, wantFields map[string]interface{}
+			if err = json.Unmarshal(redactBody(body), &gotFields); err != nil {
+				t.Fatalf("couldn't unmarshal redacted body: %v", err)
+			}
+
+			if err = json.Unmarshal(want, &wantFields); err != nil {
+				t.Fatalf("couldn't unmarshal redacted request: %v", err)
+			}
+
+			// The body includes a public key signature, which is redacted
+			// rather than removed.
+			if sig, ok := gotFields["public_key_signature"]; ok {
+				if sig != redacted {
+					t.Errorf("got public key signature %v, want %s", sig, redacted)
+				}
+
+				delete(gotFields, "public_key_signature")
+			}
+
+			if !cmp.Equal(gotFields, wantFields) {
+				t.Errorf("got %v, want %v", gotFields, wantFields)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ package hvclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -30,7 +31,9 @@ type loginRequest struct {
 
 // loginResponse is an HVCA POST /login response body.
 type loginResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken string   `json:"access_token"`
+	AccountID   string   `json:"account_id"`
+	Roles       []string `json:"roles"`
 }
 
 const (
@@ -42,6 +45,11 @@ const (
 	tokenLifetime = time.Minute * 9
 )
 
+// ErrTokenExpired is returned by API calls made with a client created with
+// NewClientFromToken once HVCA no longer accepts its authentication token,
+// since such a client has no credentials with which to log in again.
+var ErrTokenExpired = errors.New("authentication token has expired")
+
 // HVCA API endpoints.
 const (
 	endpointLogin = "/login"
@@ -49,6 +57,10 @@ const (
 
 // login logs into the HVCA server and stores the authentication token.
 func (c *Client) login(ctx context.Context) error {
+	if c.config.fromToken {
+		return ErrTokenExpired
+	}
+
 	var req = loginRequest{
 		APIKey:    c.config.APIKey,
 		APISecret: c.config.APISecret,
@@ -68,7 +80,14 @@ func (c *Client) login(ctx context.Context) error {
 		return fmt.Errorf("failed to login: %w", err)
 	}
 
+	// Don't store a token obtained by a login which was in flight when the
+	// client was closed.
+	if c.isClosed() {
+		return ErrClientClosed
+	}
+
 	c.tokenSet(resp.AccessToken)
+	c.identitySet(resp.accountIdentity())
 
 	return nil
 }
@@ -109,16 +128,51 @@ func (c *Client) tokenHasExpired() bool {
 	c.tokenMtx.RLock()
 	defer c.tokenMtx.RUnlock()
 
-	return time.Since(c.lastLogin) > tokenLifetime
+	// The lifetime of a token supplied to NewClientFromToken is not known,
+	// so it is used until HVCA rejects it.
+	if c.config.fromToken {
+		return c.token == ""
+	}
+
+	return c.now().Sub(c.lastLogin) > tokenLifetime
+}
+
+// AccessToken returns a current HVCA authentication token for the account,
+// logging in first if the stored token is believed to have expired, together
+// with the time at which the token is believed to expire. The token may be
+// passed to NewClientFromToken, for example to hand a short-lived credential
+// to a build system instead of the account API key and secret. HVCA does not
+// support tokens with restricted scopes, so the token grants the same access
+// as the API key and secret, but only for the lifetime of the token, which
+// is currently documented to be ten minutes.
+func (c *Client) AccessToken(ctx context.Context) (string, time.Time, error) {
+	if c.isClosed() {
+		return "", time.Time{}, ErrClientClosed
+	}
+
+	if err := c.loginIfTokenHasExpired(ctx); err != nil {
+		return "", time.Time{}, err
+	}
+
+	c.tokenMtx.RLock()
+	defer c.tokenMtx.RUnlock()
+
+	if c.config.fromToken {
+		return c.token, time.Time{}, nil
+	}
+
+	return c.token, c.lastLogin.Add(tokenLifetime), nil
 }
 
-// tokenReset clears the stored authentication token and the last login time.
+// tokenReset clears the stored authentication token, the last login time
+// and the account identity.
 func (c *Client) tokenReset() {
 	c.tokenMtx.Lock()
 	defer c.tokenMtx.Unlock()
 
 	c.token = ""
 	c.lastLogin = time.Time{}
+	c.identity = nil
 }
 
 // tokenSet sets the stored authentication token and sets the last login time
@@ -128,7 +182,7 @@ func (c *Client) tokenSet(token string) {
 	defer c.tokenMtx.Unlock()
 
 	c.token = token
-	c.lastLogin = time.Now()
+	c.lastLogin = c.now()
 }
 
 // tokenRead performs a synchronized read of the stored authentication token.
@@ -17,10 +17,14 @@ package hvclient_test
 
 import (
 	"context"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"math/big"
 	"net/http"
+	"net/url"
+	"path"
+	"sync"
 	"testing"
 	"time"
 
@@ -170,6 +174,163 @@ func TestClientMockCertificatesRequest(t *testing.T) {
 	}
 }
 
+func TestClientMockCertificatesRequestWithLocation(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var csr, err = pki.CSRFromFile("testdata/test_csr.pem")
+	if err != nil {
+		t.Fatalf("failed to read CSR: %v", err)
+	}
+
+	var sn *big.Int
+	var location *url.URL
+	sn, location, err = client.CertificateRequestWithLocation(
+		ctx,
+		&hvclient.Request{
+			Subject: &hvclient.DN{CommonName: "John Doe"},
+			CSR:     csr,
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to request certificate: %v", err)
+	}
+
+	if fmt.Sprintf("%X", sn) != mockCertSerial {
+		t.Fatalf("got %X, want %s", sn, mockCertSerial)
+	}
+
+	if !location.IsAbs() || path.Base(location.Path) != mockCertSerial {
+		t.Fatalf("got location %s, want absolute URL ending in %s", location, mockCertSerial)
+	}
+
+	var info *hvclient.CertInfo
+	if info, err = client.CertificateRetrieveByURL(ctx, location); err != nil {
+		t.Fatalf("failed to retrieve certificate by URL: %v", err)
+	}
+
+	if !info.X509.Equal(mockCert) {
+		t.Fatalf("got certificate %v, want %v", info.X509, mockCert)
+	}
+}
+
+func TestClientMockCertificateRekey(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var csr, err = pki.CSRFromFile("testdata/test_csr.pem")
+	if err != nil {
+		t.Fatalf("failed to read CSR: %v", err)
+	}
+
+	var sn *big.Int
+	if sn, err = client.CertificateRekey(ctx, mockCert.SerialNumber, &hvclient.CertificateRekeyRequest{CSR: csr}); err != nil {
+		t.Fatalf("failed to rekey certificate: %v", err)
+	}
+
+	if fmt.Sprintf("%X", sn) != mockCertSerial {
+		t.Fatalf("got %X, want %s", sn, mockCertSerial)
+	}
+
+	if _, err = client.CertificateRekey(ctx, mockBigIntNotFound, &hvclient.CertificateRekeyRequest{CSR: csr}); err == nil {
+		t.Fatalf("unexpectedly rekeyed nonexistent certificate")
+	}
+}
+
+func TestClientMockCertificateRekeyKeyFailure(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var rsaKey, err = pki.PublicKeyFromFile("testdata/rsa_pub.key")
+	if err != nil {
+		t.Fatalf("failed to read public key: %v", err)
+	}
+
+	var testcases = []struct {
+		name string
+		key  interface{}
+		want error
+	}{
+		{
+			name: "SameKey",
+			key:  mockCert.PublicKey,
+			want: hvclient.ErrRekeySameKey,
+		},
+		{
+			name: "NotAllowedByPolicy",
+			key:  rsaKey,
+			want: hvclient.ErrPublicKeyNotAllowed,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			var _, err = client.CertificateRekey(ctx, mockCert.SerialNumber, &hvclient.CertificateRekeyRequest{PublicKey: tc.key})
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got error %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestClientMockCertificatesRetrieveByURLFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name     string
+		location string
+	}{
+		{
+			name:     "OtherHost",
+			location: "https://example.com/certificates/" + mockCertSerial,
+		},
+		{
+			name:     "NotFound",
+			location: "/certificates/" + fmt.Sprintf("%X", mockBigIntNotFound),
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var client, closefunc = newMockClient(t)
+			defer closefunc()
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			var location, err = url.Parse(tc.location)
+			if err != nil {
+				t.Fatalf("failed to parse URL: %v", err)
+			}
+
+			if _, err = client.CertificateRetrieveByURL(ctx, location); err == nil {
+				t.Fatalf("unexpectedly retrieved certificate")
+			}
+		})
+	}
+}
+
 func TestClientMockCertificatesRetrieve(t *testing.T) {
 	t.Parallel()
 
@@ -225,6 +386,34 @@ func TestClientMockCertificatesRetrieve(t *testing.T) {
 	}
 }
 
+func TestClientMockCertificateHistory(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var got, err = client.CertificateHistory(ctx, big.NewInt(0x741daf9ec2d5f7dc))
+	if err != nil {
+		t.Fatalf("couldn't get certificate history: %v", err)
+	}
+
+	var want = []hvclient.CertEvent{
+		{Type: hvclient.EventIssued, Time: mockCert.NotBefore},
+		{Type: hvclient.EventExpiry, Time: mockCert.NotAfter},
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if _, err = client.CertificateHistory(ctx, mockBigIntNotFound); err == nil {
+		t.Fatalf("unexpectedly got history for missing certificate")
+	}
+}
+
 func TestClientMockCertificatesRevoke(t *testing.T) {
 	t.Parallel()
 
@@ -288,6 +477,7 @@ func TestClientMockCertificatesRevokeWithReason(t *testing.T) {
 		{
 			name:   "NotFound",
 			serial: mockBigIntNotFound,
+			reason: hvclient.RevocationReasonKeyCompromise,
 			err:    hvclient.APIError{StatusCode: http.StatusNotFound},
 		},
 	}
@@ -416,6 +606,34 @@ func TestClientMockClaimsDomains(t *testing.T) {
 	}
 }
 
+func TestClientMockClaimsDomainsAll(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var got, count, err = client.ClaimsDomains(ctx, 1, 100, hvclient.StatusAll)
+	if err != nil {
+		t.Fatalf("failed to get domain claims: %v", err)
+	}
+
+	if count != 3 || len(got) != 3 {
+		t.Fatalf("got %d claims and count %d, want 3", len(got), count)
+	}
+
+	var statuses = make(map[hvclient.ClaimStatus]int)
+	for _, claim := range got {
+		statuses[claim.Status]++
+	}
+
+	if statuses[hvclient.StatusVerified] != 1 || statuses[hvclient.StatusPending] != 2 {
+		t.Errorf("got statuses %v, want 1 verified and 2 pending", statuses)
+	}
+}
+
 func TestClientMockClaimDelete(t *testing.T) {
 	t.Parallel()
 
@@ -1158,3 +1376,289 @@ func mustParseBigInt(t *testing.T, s string, base int) *big.Int {
 
 	return n
 }
+
+func TestClientMockPolicyCache(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name   string
+		ttl    time.Duration
+		cached bool
+	}{
+		{
+			name:   "Disabled",
+			ttl:    0,
+			cached: false,
+		},
+		{
+			name:   "Enabled",
+			ttl:    time.Hour,
+			cached: true,
+		},
+		{
+			name:   "Expired",
+			ttl:    time.Nanosecond,
+			cached: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var client, closefunc = newMockClientWithConfig(t, func(c *hvclient.Config) {
+				c.CachePolicyTTL = tc.ttl
+			})
+			defer closefunc()
+
+			var ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+
+			var first, err = client.Policy(ctx)
+			if err != nil {
+				t.Fatalf("failed to get validation policy: %v", err)
+			}
+
+			var second *hvclient.Policy
+			if second, err = client.Policy(ctx); err != nil {
+				t.Fatalf("failed to get validation policy: %v", err)
+			}
+
+			if (first == second) != tc.cached {
+				t.Fatalf("got cached %t, want %t", first == second, tc.cached)
+			}
+
+			if !cmp.Equal(second, &mockPolicy) {
+				t.Fatalf("got %v, want %v", second, mockPolicy)
+			}
+
+			var chain []*x509.Certificate
+			if chain, err = client.TrustChain(ctx); err != nil {
+				t.Fatalf("failed to get trust chain: %v", err)
+			}
+
+			if !cmp.Equal(chain, mockTrustChainCerts) {
+				t.Fatalf("got %v, want %v", chain, mockTrustChainCerts)
+			}
+
+			// A fresh policy should always be fetched after the caches
+			// are invalidated.
+			client.InvalidateCaches()
+
+			var third *hvclient.Policy
+			if third, err = client.Policy(ctx); err != nil {
+				t.Fatalf("failed to get validation policy: %v", err)
+			}
+
+			if third == second {
+				t.Fatalf("got cached policy after invalidating caches")
+			}
+		})
+	}
+}
+
+func TestClientMockStrictDecoding(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClientWithConfig(t, func(c *hvclient.Config) {
+		c.StrictDecoding = true
+	})
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var pol, err = client.Policy(ctx)
+	if err != nil {
+		t.Fatalf("failed to get validation policy: %v", err)
+	}
+
+	if !cmp.Equal(pol, &mockPolicy) {
+		t.Fatalf("got %v, want %v", pol, mockPolicy)
+	}
+
+	if _, err = client.TrustChain(ctx); err != nil {
+		t.Fatalf("failed to get trust chain: %v", err)
+	}
+
+	if _, err = client.CounterCertsIssued(ctx); err != nil {
+		t.Fatalf("failed to get issued certificates count: %v", err)
+	}
+}
+
+func TestClientMockMaxResponseSize(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClientWithConfig(t, func(c *hvclient.Config) {
+		c.MaxResponseSize = 32
+	})
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var _, err = client.Policy(ctx)
+	if !errors.Is(err, hvclient.ErrResponseTooLarge) {
+		t.Fatalf("got error %v, want %v", err, hvclient.ErrResponseTooLarge)
+	}
+
+	var respErr *hvclient.ResponseError
+	if !errors.As(err, &respErr) {
+		t.Fatalf("got error type %T, want %T", err, respErr)
+	}
+
+	if respErr.Method != http.MethodGet || respErr.Path != "/validationpolicy" {
+		t.Errorf("got endpoint %s %s, want %s %s", respErr.Method, respErr.Path, http.MethodGet, "/validationpolicy")
+	}
+}
+
+func TestClientMockConcurrent(t *testing.T) {
+	t.Parallel()
+
+	// Limit the number of connections to verify that requests queue for
+	// an available connection rather than failing.
+	var client, closefunc = newMockClientWithConfig(t, func(c *hvclient.Config) {
+		c.MaxConnsPerHost = 2
+		c.MaxIdleConns = 2
+		c.IdleConnTimeout = time.Minute
+		c.KeepAlive = time.Second * 30
+	})
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	const numGoroutines = 32
+
+	var errs = make(chan error, numGoroutines)
+	var wg sync.WaitGroup
+
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var info, err = client.CertificateRetrieve(ctx, mockCert.SerialNumber)
+			if err == nil && !info.X509.Equal(mockCert) {
+				err = errors.New("retrieved certificate did not match")
+			}
+
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("failed to retrieve certificate: %v", err)
+		}
+	}
+}
+
+func BenchmarkClientMockCertificateRetrieve(b *testing.B) {
+	var testcases = []struct {
+		name     string
+		maxConns int
+	}{
+		{
+			name:     "MaxConns1",
+			maxConns: 1,
+		},
+		{
+			name:     "MaxConns8",
+			maxConns: 8,
+		},
+		{
+			name: "MaxConnsDefault",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		b.Run(tc.name, func(b *testing.B) {
+			var client, closefunc = newMockClientWithConfig(b, func(c *hvclient.Config) {
+				c.MaxConnsPerHost = tc.maxConns
+			})
+			defer closefunc()
+
+			var ctx = context.Background()
+
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := client.CertificateRetrieve(ctx, mockCert.SerialNumber); err != nil {
+						b.Errorf("failed to retrieve certificate: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestClientMockReconcile(t *testing.T) {
+	t.Parallel()
+
+	var mockCertNotAfter = time.Date(2021, 9, 16, 16, 29, 51, 0, time.UTC)
+
+	var testcases = []struct {
+		name     string
+		deployed []hvclient.DeployedCertificate
+		want     []hvclient.ReconcileFinding
+	}{
+		{
+			name: "NotDeployed",
+			want: []hvclient.ReconcileFinding{
+				{
+					Problem:      hvclient.IssuedNotDeployed,
+					SerialNumber: mockCert.SerialNumber,
+					NotAfter:     mockCertNotAfter,
+				},
+			},
+		},
+		{
+			name: "Expired",
+			deployed: []hvclient.DeployedCertificate{
+				{Path: "/certs/cert.pem", Certificate: mockCert},
+			},
+			want: []hvclient.ReconcileFinding{
+				{
+					Problem:      hvclient.NearExpiry,
+					SerialNumber: mockCert.SerialNumber,
+					NotAfter:     mockCertNotAfter,
+					Path:         "/certs/cert.pem",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var client, closefunc = newMockClient(t)
+			defer closefunc()
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+
+			var got, err = client.Reconcile(ctx, tc.deployed, time.Time{}, time.Time{}, time.Hour*24*30)
+			if err != nil {
+				t.Fatalf("failed to reconcile: %v", err)
+			}
+
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// allPagesPageSize is the number of items to request per page when
+// retrieving every page of a listing.
+const allPagesPageSize = 100
+
+// pageFetcher retrieves a single page of a listing, storing the items
+// internally, and returns the number of items on the page and the total
+// count of items in the listing.
+type pageFetcher func(ctx context.Context, page int) (int, int64, error)
+
+// fetchAllPages retrieves every page of a listing. The first page is
+// retrieved alone to learn the total count and the page size actually used
+// by HVCA, which may be smaller than the size requested, and the remaining
+// pages are then retrieved with up to the configured number of concurrent
+// requests. The first error encountered cancels any outstanding requests
+// and is returned. Each page retrieved is reported to the configured
+// progress reporter, if any.
+func (c *Client) fetchAllPages(ctx context.Context, fetch pageFetcher) error {
+	var n, count, err = fetch(ctx, 1)
+	if err != nil {
+		newProgressTracker(c.config.Progress, 0).done(err)
+		return err
+	}
+
+	if n == 0 || int64(n) >= count {
+		newProgressTracker(c.config.Progress, 1).done(nil)
+		return nil
+	}
+
+	var pages = int((count + int64(n) - 1) / int64(n))
+
+	var progress = newProgressTracker(c.config.Progress, pages)
+	progress.done(nil)
+
+	var concurrency = c.config.PageConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+	var sem = make(chan struct{}, concurrency)
+
+	for page := 2; page <= pages; page++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+
+		go func(page int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var _, _, err = fetch(ctx, page)
+			progress.done(err)
+
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(page)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return ctx.Err()
+}
+
+// statsAll retrieves every page of certificates from a /stats endpoint.
+func (c *Client) statsAll(ctx context.Context, path string, from, to time.Time) ([]CertMeta, error) {
+	var mtx sync.Mutex
+	var pages = make(map[int][]CertMeta)
+
+	var err = c.fetchAllPages(ctx, func(ctx context.Context, page int) (int, int64, error) {
+		var stats, p, err = c.statsCommon(ctx, path, page, allPagesPageSize, from, to)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		mtx.Lock()
+		pages[page] = stats
+		mtx.Unlock()
+
+		return len(stats), p.TotalCount, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var all []CertMeta
+	for page := 1; page <= len(pages); page++ {
+		all = append(all, pages[page]...)
+	}
+
+	return all, nil
+}
+
+// StatsExpiringAll returns every certificate which expired or which will
+// expire during the specified time window, retrieving as many pages as
+// necessary. Pages after the first are retrieved concurrently if the
+// PageConcurrency configuration field is set, but the certificates are
+// returned in the same order as if the pages had been retrieved one by one.
+func (c *Client) StatsExpiringAll(ctx context.Context, from, to time.Time) ([]CertMeta, error) {
+	return c.statsAll(ctx, endpointStatsExpiring, from, to)
+}
+
+// StatsIssuedAll returns every certificate which was issued during the
+// specified time window, retrieving as many pages as necessary. Pages are
+// retrieved as described for StatsExpiringAll.
+func (c *Client) StatsIssuedAll(ctx context.Context, from, to time.Time) ([]CertMeta, error) {
+	return c.statsAll(ctx, endpointStatsIssued, from, to)
+}
+
+// StatsRevokedAll returns every certificate which was revoked during the
+// specified time window, retrieving as many pages as necessary. Pages are
+// retrieved as described for StatsExpiringAll.
+func (c *Client) StatsRevokedAll(ctx context.Context, from, to time.Time) ([]CertMeta, error) {
+	return c.statsAll(ctx, endpointStatsRevoked, from, to)
+}
+
+// ClaimsDomainsAll returns every domain claim with the specified status,
+// retrieving as many pages as necessary. Specify StatusAll to list claims
+// regardless of their status. Pages are retrieved as described for
+// StatsExpiringAll.
+func (c *Client) ClaimsDomainsAll(ctx context.Context, status ClaimStatus) ([]Claim, error) {
+	var mtx sync.Mutex
+	var pages = make(map[int][]Claim)
+
+	var err = c.fetchAllPages(ctx, func(ctx context.Context, page int) (int, int64, error) {
+		var claims, count, err = c.ClaimsDomains(ctx, page, allPagesPageSize, status)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		mtx.Lock()
+		pages[page] = claims
+		mtx.Unlock()
+
+		return len(claims), count, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var all []Claim
+	for page := 1; page <= len(pages); page++ {
+		all = append(all, pages[page]...)
+	}
+
+	return all, nil
+}
@@ -0,0 +1,176 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newPagingHandler returns a test handler which lists the specified number of
+// certificates from the /stats/issued endpoint, returning at most pageSize
+// certificates per page regardless of the page size requested. If failPage
+// is non-zero, requests for that page fail.
+func newPagingHandler(total, pageSize, failPage int, inFlight, maxInFlight *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n = atomic.AddInt32(inFlight, 1)
+		defer atomic.AddInt32(inFlight, -1)
+
+		for {
+			var max = atomic.LoadInt32(maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(maxInFlight, max, n) {
+				break
+			}
+		}
+
+		var page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+		if page == failPage {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Delay earlier pages more than later ones, so that concurrent
+		// responses arrive out of order.
+		time.Sleep(time.Millisecond * time.Duration(20-page%20))
+
+		var metas []jsonCertMeta
+		for i := (page - 1) * pageSize; i < page*pageSize && i < total; i++ {
+			metas = append(metas, jsonCertMeta{SerialNumber: fmt.Sprintf("%X", i+1)})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(totalCountHeaderName, strconv.Itoa(total))
+		json.NewEncoder(w).Encode(metas)
+	})
+}
+
+func TestStatsAllPaging(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name        string
+		total       int
+		concurrency int
+		failPage    int
+	}{
+		{
+			name:  "Sequential",
+			total: 50,
+		},
+		{
+			name:        "Concurrent",
+			total:       50,
+			concurrency: 4,
+		},
+		{
+			name:        "SinglePage",
+			total:       5,
+			concurrency: 4,
+		},
+		{
+			name:        "Empty",
+			concurrency: 4,
+		},
+		{
+			name:        "Failure",
+			total:       50,
+			concurrency: 4,
+			failPage:    3,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var inFlight, maxInFlight int32
+			var completed, total int
+			var lastErr error
+			var progress = ProgressFunc(func(c, t int, err error) {
+				if c != completed+1 {
+					lastErr = fmt.Errorf("progress reported %d completed after %d", c, completed)
+				} else if err != nil {
+					lastErr = err
+				}
+
+				completed, total = c, t
+			})
+
+			var clnt, _ = newTestClient(t, newPagingHandler(tc.total, 7, tc.failPage, &inFlight, &maxInFlight), &Config{
+				PageConcurrency: tc.concurrency,
+				Progress:        progress,
+			})
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+			defer cancel()
+
+			var got, err = clnt.StatsIssuedAll(ctx, time.Time{}, time.Time{})
+
+			if tc.failPage != 0 {
+				if err == nil {
+					t.Fatalf("unexpectedly succeeded")
+				}
+
+				if lastErr == nil {
+					t.Errorf("no error reported to progress reporter")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("couldn't list certificates: %v", err)
+			}
+
+			if len(got) != tc.total {
+				t.Fatalf("got %d certificates, want %d", len(got), tc.total)
+			}
+
+			for i, meta := range got {
+				if meta.SerialNumber.Cmp(big.NewInt(int64(i+1))) != 0 {
+					t.Fatalf("certificate %d has serial number %X, want %X", i, meta.SerialNumber, i+1)
+				}
+			}
+
+			var pages = (tc.total + 6) / 7
+			if pages < 1 {
+				pages = 1
+			}
+
+			if lastErr != nil || completed != pages || total != pages {
+				t.Errorf("got progress %d of %d with error %v, want %d of %d", completed, total, lastErr, pages, pages)
+			}
+
+			var limit = int32(tc.concurrency)
+			if limit < 1 {
+				limit = 1
+			}
+
+			if maxInFlight > limit {
+				t.Errorf("got %d concurrent requests, want at most %d", maxInFlight, limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/oids"
+)
+
+// newTestCertPEM returns a PEM-encoded self-signed certificate, which is a
+// precertificate if precert is true.
+func newTestCertPEM(t *testing.T, precert bool) string {
+	t.Helper()
+
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("couldn't generate key: %v", err)
+	}
+
+	var tmpl = &x509.Certificate{
+		SerialNumber: big.NewInt(0x741daf9ec2d5f7dc),
+		Subject:      pkix.Name{CommonName: "John Doe"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	if precert {
+		tmpl.ExtraExtensions = []pkix.Extension{
+			{Id: oids.OIDCTPoison, Critical: true, Value: []byte{0x05, 0x00}},
+		}
+	}
+
+	var der []byte
+	if der, err = x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key); err != nil {
+		t.Fatalf("couldn't create certificate: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// newPrecertTestClient returns a client of a test server which returns a
+// precertificate for the specified number of retrievals, followed by the
+// final certificate.
+func newPrecertTestClient(t *testing.T, precerts int, await bool) (*Client, *fakeClock) {
+	t.Helper()
+
+	var precert, final = newTestCertPEM(t, true), newTestCertPEM(t, false)
+
+	var mtx sync.Mutex
+	var count int
+
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		count++
+		var body = final
+		if count <= precerts {
+			body = precert
+		}
+		mtx.Unlock()
+
+		var data, _ = json.Marshal(jsonCertInfo{PEM: body, Status: StatusIssued.String(), UpdatedAt: 1477958400})
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "%s", data)
+	})
+
+	var clock = &fakeClock{now: time.Now()}
+
+	var clnt, _ = newTestClient(t, handler, &Config{
+		Retries:               3,
+		AwaitFinalCertificate: await,
+		Clock:                 clock,
+	})
+
+	return clnt, clock
+}
+
+func TestCertificateRetrievePrecertificate(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name     string
+		precerts int
+		await    bool
+		want     bool
+		delays   int
+	}{
+		{"Final", 0, true, false, 0},
+		{"PrecertNoWait", 1, false, true, 0},
+		{"PrecertAwaited", 2, true, false, 2},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var clnt, clock = newPrecertTestClient(t, tc.precerts, tc.await)
+
+			var info, err = clnt.CertificateRetrieve(context.Background(), big.NewInt(0x741daf9ec2d5f7dc))
+			if err != nil {
+				t.Fatalf("couldn't retrieve certificate: %v", err)
+			}
+
+			if info.IsPrecertificate != tc.want {
+				t.Errorf("got precertificate %t, want %t", info.IsPrecertificate, tc.want)
+			}
+
+			if len(clock.delays) != tc.delays {
+				t.Errorf("got %d waits, want %d", len(clock.delays), tc.delays)
+			}
+		})
+	}
+}
+
+func TestCertificateRetrievePrecertificateFailure(t *testing.T) {
+	t.Parallel()
+
+	var clnt, clock = newPrecertTestClient(t, 10, true)
+
+	var _, err = clnt.CertificateRetrieve(context.Background(), big.NewInt(0x741daf9ec2d5f7dc))
+	if !errors.Is(err, ErrPrecertificate) {
+		t.Fatalf("got error %v, want %v", err, ErrPrecertificate)
+	}
+
+	if len(clock.delays) != clnt.config.Retries {
+		t.Errorf("got %d waits, want %d", len(clock.delays), clnt.config.Retries)
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAPIErrorIsQuotaExceeded(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		err  APIError
+		want bool
+	}{
+		{
+			name: "PaymentRequired",
+			err:  APIError{StatusCode: http.StatusPaymentRequired, Description: "payment required"},
+			want: true,
+		},
+		{
+			name: "QuotaDescription",
+			err:  APIError{StatusCode: http.StatusForbidden, Description: "Issuance Quota exhausted"},
+			want: true,
+		},
+		{
+			name: "QuotaProblemType",
+			err:  APIError{StatusCode: http.StatusTooManyRequests, Type: "https://example.com/problems/quota-exceeded", Description: "too many requests"},
+			want: true,
+		},
+		{
+			name: "Forbidden",
+			err:  APIError{StatusCode: http.StatusForbidden, Description: "forbidden"},
+		},
+		{
+			name: "ServerError",
+			err:  APIError{StatusCode: http.StatusInternalServerError, Description: "quota service unavailable"},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := errors.Is(tc.err, ErrQuotaExceeded); got != tc.want {
+				t.Errorf("got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCertificateRequestQuotaExceeded(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name        string
+		status      int
+		problemType string
+		description string
+		quota       int
+		want        bool
+		remaining   int64
+	}{
+		{
+			name:        "QuotaProblemType",
+			status:      http.StatusTooManyRequests,
+			problemType: "https://example.com/problems/quota-exceeded",
+			description: "too many requests",
+			quota:       0,
+			want:        true,
+			remaining:   0,
+		},
+		{
+			name:        "Forbidden",
+			status:      http.StatusForbidden,
+			description: "forbidden",
+			quota:       0,
+		},
+		{
+			name:        "RateLimited",
+			status:      http.StatusTooManyRequests,
+			description: "too many requests",
+			quota:       0,
+		},
+		{
+			name:        "QuotaDescription",
+			status:      http.StatusUnprocessableEntity,
+			description: "quota exceeded",
+			quota:       -1,
+			want:        true,
+			remaining:   -1,
+		},
+		{
+			name:        "ValidationFailure",
+			status:      http.StatusUnprocessableEntity,
+			description: "common name not allowed",
+			quota:       0,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var lookups int32
+			var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == endpointQuotasIssuance {
+					atomic.AddInt32(&lookups, 1)
+				}
+
+				switch {
+				case r.URL.Path == endpointCertificates:
+					w.Header().Set("Content-Type", "application/problem+json")
+					w.WriteHeader(tc.status)
+					fmt.Fprintf(w, `{"type":%q,"description":%q}`, tc.problemType, tc.description)
+
+				case r.URL.Path == endpointQuotasIssuance && tc.quota >= 0:
+					w.Header().Set("Content-Type", "application/json")
+					fmt.Fprintf(w, `{"value":%d}`, tc.quota)
+
+				default:
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			})
+
+			var clnt, _ = newTestClient(t, handler, nil)
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+			defer cancel()
+
+			var _, err = clnt.CertificateRequest(ctx, &Request{})
+			if err == nil {
+				t.Fatalf("unexpectedly requested certificate")
+			}
+
+			if got := errors.Is(err, ErrQuotaExceeded); got != tc.want {
+				t.Fatalf("got quota exceeded %t, want %t: %v", got, tc.want, err)
+			}
+
+			var apiErr APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tc.status {
+				t.Errorf("got error %v, want API error with status %d", err, tc.status)
+			}
+
+			// The quota is only looked up for errors indicating that it is
+			// exhausted.
+			var wantLookups int32
+			if tc.want {
+				wantLookups = 1
+			}
+
+			if got := atomic.LoadInt32(&lookups); got != wantLookups {
+				t.Errorf("got %d quota lookups, want %d", got, wantLookups)
+			}
+
+			if !tc.want {
+				return
+			}
+
+			var quotaErr *QuotaError
+			if !errors.As(err, &quotaErr) {
+				t.Fatalf("got error %T, want %T", err, quotaErr)
+			}
+
+			if quotaErr.Remaining != tc.remaining {
+				t.Errorf("got remaining quota %d, want %d", quotaErr.Remaining, tc.remaining)
+			}
+		})
+	}
+}
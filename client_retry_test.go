@@ -0,0 +1,102 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/testhelpers"
+)
+
+// newTestClient returns a client logged into a test server which passes all
+// requests other than logins to handler. The URL and credentials in conf are
+// set before it is validated, and a nil conf uses the defaults.
+func newTestClient(t *testing.T, handler http.Handler, conf *Config) (*Client, *httptest.Server) {
+	t.Helper()
+
+	var server = testhelpers.NewLoginServer(t, handler)
+
+	if conf == nil {
+		conf = &Config{}
+	}
+
+	conf.URL = server.URL
+	conf.APIKey = "key"
+	conf.APISecret = "secret"
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var clnt, err = NewClient(ctx, conf)
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+
+	t.Cleanup(func() { clnt.Close() })
+
+	return clnt, server
+}
+
+func TestMakeRequestRetries(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name    string
+		retries int
+		want    int32
+	}{
+		{
+			name:    "Disabled",
+			retries: -1,
+			want:    1,
+		},
+		{
+			name:    "One",
+			retries: 1,
+			want:    2,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var count int32
+			var clnt, _ = newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&count, 1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}), &Config{Retries: tc.retries})
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+			defer cancel()
+
+			if _, err := clnt.makeRequest(ctx, endpointPolicy, http.MethodGet, nil, nil); err == nil {
+				t.Fatalf("unexpectedly succeeded")
+			}
+
+			if got := atomic.LoadInt32(&count); got != tc.want {
+				t.Errorf("got %d requests, want %d", got, tc.want)
+			}
+		})
+	}
+}
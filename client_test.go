@@ -17,9 +17,14 @@ package hvclient_test
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
 	"github.com/globalsign/hvclient/internal/testhelpers"
 )
 
@@ -126,3 +131,85 @@ func TestNewClientFromConfigError(t *testing.T) {
 		})
 	}
 }
+
+func TestClientClose(t *testing.T) {
+	t.Parallel()
+
+	var clnt, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	if _, err := clnt.QuotaIssuance(ctx); err != nil {
+		t.Fatalf("couldn't get quota: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := clnt.Close(); err != nil {
+			t.Fatalf("couldn't close client: %v", err)
+		}
+	}
+
+	if _, err := clnt.QuotaIssuance(ctx); !errors.Is(err, hvclient.ErrClientClosed) {
+		t.Errorf("got error %v, want %v", err, hvclient.ErrClientClosed)
+	}
+}
+
+// stoppedClock is a Clock whose timers never fire.
+type stoppedClock struct{}
+
+func (stoppedClock) Now() time.Time {
+	return time.Now()
+}
+
+func (stoppedClock) After(d time.Duration) <-chan time.Time {
+	return make(chan time.Time)
+}
+
+func TestClientCloseStopsRetryWait(t *testing.T) {
+	t.Parallel()
+
+	var injector = hvcatest.NewFaultInjector(hvcatest.NewHandler())
+	var server = httptest.NewServer(injector)
+	defer server.Close()
+
+	var conf = hvcatest.Config(server.URL)
+	conf.Clock = stoppedClock{}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var clnt, err = hvclient.NewClient(ctx, conf)
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+
+	injector.Add(hvcatest.Fault{Path: "/quotas", Status: http.StatusServiceUnavailable})
+
+	var done = make(chan error, 1)
+	go func() {
+		var _, err = clnt.QuotaIssuance(ctx)
+		done <- err
+	}()
+
+	// Wait until the call has reached its first retry wait, which never
+	// ends since the clock is stopped.
+	for injector.Injected() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err = clnt.Close(); err != nil {
+		t.Fatalf("couldn't close client: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, hvclient.ErrClientClosed) {
+			t.Errorf("got error %v, want %v", err, hvclient.ErrClientClosed)
+		}
+
+	case <-ctx.Done():
+		t.Fatalf("call not stopped by closing client")
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+)
+
+func TestNewClientFromToken(t *testing.T) {
+	t.Parallel()
+
+	var faults = hvcatest.NewFaultInjector(hvcatest.NewHandler())
+	var server = httptest.NewServer(faults)
+	defer server.Close()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var clnt, err = hvclient.NewClient(ctx, hvcatest.Config(server.URL))
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+
+	var token string
+	var expiry time.Time
+	if token, expiry, err = clnt.AccessToken(ctx); err != nil {
+		t.Fatalf("couldn't get access token: %v", err)
+	}
+
+	if token != hvcatest.Token {
+		t.Errorf("got token %q, want %q", token, hvcatest.Token)
+	}
+
+	if until := time.Until(expiry); until <= 0 || until > time.Minute*10 {
+		t.Errorf("got token expiry in %v, want within ten minutes", until)
+	}
+
+	var tokenClient *hvclient.Client
+	if tokenClient, err = hvclient.NewClientFromToken(ctx, server.URL, token,
+		hvclient.WithHeader(hvcatest.SSLClientSerialHeader, hvcatest.SSLClientSerial),
+	); err != nil {
+		t.Fatalf("couldn't create client from token: %v", err)
+	}
+	defer tokenClient.Close()
+
+	if _, err = tokenClient.QuotaIssuance(ctx); err != nil {
+		t.Fatalf("couldn't get quota: %v", err)
+	}
+
+	faults.Add(hvcatest.Fault{ExpireToken: true})
+
+	if _, err = tokenClient.QuotaIssuance(ctx); !errors.Is(err, hvclient.ErrTokenExpired) {
+		t.Errorf("got error %v, want %v", err, hvclient.ErrTokenExpired)
+	}
+}
+
+func TestNewClientFromTokenFailure(t *testing.T) {
+	t.Parallel()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var testcases = []struct {
+		name  string
+		url   string
+		token string
+		opts  []hvclient.Option
+	}{
+		{
+			name: "NoToken",
+			url:  "http://example.com/v2",
+		},
+		{
+			name:  "NoURL",
+			token: hvcatest.Token,
+		},
+		{
+			name:  "BadOption",
+			url:   "http://example.com/v2",
+			token: hvcatest.Token,
+			opts:  []hvclient.Option{hvclient.WithTimeout(-time.Second)},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := hvclient.NewClientFromToken(ctx, tc.url, tc.token, tc.opts...); err == nil {
+				t.Fatalf("unexpectedly created client")
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import "time"
+
+// Clock is a source of the current time and of timers. A Clock may be
+// provided in the configuration object to control time-dependent behavior,
+// such as authentication token expiry, cache expiry, retry delays and the
+// default validity period of rekey requests, in tests and replay tooling.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for the specified duration to elapse and then sends the
+	// current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is a Clock which uses the system time, and is used if no
+// Clock is provided in the configuration object.
+var SystemClock Clock = systemClock{}
+
+// systemClock is a Clock which uses the system time.
+type systemClock struct{}
+
+// Now returns the current system time.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After returns time.After(d).
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// clock returns the configured clock, or SystemClock if none was configured.
+func (c *Client) clock() Clock {
+	if c.config.Clock == nil {
+		return SystemClock
+	}
+
+	return c.config.Clock
+}
+
+// now returns the current time according to the configured clock.
+func (c *Client) now() time.Time {
+	return c.clock().Now()
+}
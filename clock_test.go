@@ -0,0 +1,123 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeClock is a Clock whose time only changes when advanced, and whose
+// timers fire immediately after recording the requested duration.
+type fakeClock struct {
+	mtx    sync.Mutex
+	now    time.Time
+	delays []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.delays = append(c.delays, d)
+
+	var ch = make(chan time.Time, 1)
+	ch <- c.now.Add(d)
+
+	return ch
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestClockTokenExpiry(t *testing.T) {
+	t.Parallel()
+
+	var clock = &fakeClock{now: time.Date(2021, 6, 18, 16, 29, 51, 0, time.UTC)}
+	var clnt = &Client{config: &Config{Clock: clock}}
+
+	clnt.tokenSet("token")
+
+	if clnt.tokenHasExpired() {
+		t.Fatalf("token unexpectedly expired immediately after login")
+	}
+
+	clock.advance(tokenLifetime - time.Second)
+
+	if clnt.tokenHasExpired() {
+		t.Fatalf("token unexpectedly expired before end of lifetime")
+	}
+
+	clock.advance(time.Second * 2)
+
+	if !clnt.tokenHasExpired() {
+		t.Fatalf("token unexpectedly not expired after end of lifetime")
+	}
+}
+
+func TestClockRetryDelays(t *testing.T) {
+	t.Parallel()
+
+	var server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var u, err = url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("couldn't parse server URL: %v", err)
+	}
+
+	var clock = &fakeClock{now: time.Date(2021, 6, 18, 16, 29, 51, 0, time.UTC)}
+	var clnt = &Client{
+		config:     &Config{Retries: 3, Clock: clock},
+		url:        u,
+		httpClient: server.Client(),
+	}
+
+	clnt.tokenSet("token")
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	if _, err = clnt.makeRequest(ctx, endpointPolicy, http.MethodGet, nil, nil); err == nil {
+		t.Fatalf("unexpectedly succeeded")
+	}
+
+	var want = []time.Duration{retryWaitDuration, retryWaitDuration * 2, retryWaitDuration * 3}
+
+	if !cmp.Equal(clock.delays, want) {
+		t.Errorf("got delays %v, want %v", clock.delays, want)
+	}
+}
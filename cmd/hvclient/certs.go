@@ -17,11 +17,18 @@ package main
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
+	"os"
+	"time"
 
 	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/pki"
+	"github.com/globalsign/hvclient/revoke"
 )
 
 // retrieveCert outputs the certificate with the specified serial
@@ -30,36 +37,82 @@ func retrieveCert(clnt *hvclient.Client, serialNumber string) {
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	var sn, ok = big.NewInt(0).SetString(serialNumber, 16)
-	if !ok {
-		log.Fatalf("invalid serial number: %s", serialNumber)
+	var sn, err = parseSerial(serialNumber)
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
 
-	var cert, err = clnt.CertificateRetrieve(ctx, sn)
-	if err != nil {
+	var cert *hvclient.CertInfo
+	if cert, err = clnt.CertificateRetrieve(ctx, sn); err != nil {
 		log.Fatalf("%v", err)
 	}
 
+	if *fDetails {
+		writeCertDetails(os.Stdout, cert)
+	}
+
 	fmt.Printf("%s", cert.PEM)
+
+	if *fP12Out == "" {
+		return
+	}
+
+	var key interface{}
+	if *fPrivateKey != "" {
+		if _, key, _, err = getKeys("", *fPrivateKey, "", getPasswordFromTerminal); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
+	if err = exportPKCS12(ctx, clnt, key, cert.X509); err != nil {
+		log.Fatalf("%v", err)
+	}
 }
 
-// retrieveCertStatus outputs the issued/revoked status for the
+// retrieveCertStatus outputs the status, e.g. issued or revoked, of the
 // certificate with the specified serial number.
 func retrieveCertStatus(clnt *hvclient.Client, serialNumber string) {
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	var sn, ok = big.NewInt(0).SetString(serialNumber, 16)
-	if !ok {
-		log.Fatalf("invalid serial number: %s", sn)
+	var sn, err = parseSerial(serialNumber)
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
 
-	var cert, err = clnt.CertificateRetrieve(ctx, sn)
-	if err != nil {
+	var cert *hvclient.CertInfo
+	if cert, err = clnt.CertificateRetrieve(ctx, sn); err != nil {
 		log.Fatalf("%v", err)
 	}
 
-	fmt.Printf("%s\n", cert.Status)
+	// Output any status HVCA reports, even if it isn't one we recognise.
+	if cert.Status == hvclient.StatusUnknown && cert.RawStatus != "" {
+		fmt.Printf("%s\n", cert.RawStatus)
+	} else {
+		fmt.Printf("%s\n", cert.Status)
+	}
+
+	if *fDetails {
+		writeCertDetails(os.Stdout, cert)
+	}
+}
+
+// writeCertDetails writes the serial number, fingerprints and any key
+// identifiers of a certificate, by which certificate inventory systems
+// commonly identify certificates.
+func writeCertDetails(w io.Writer, info *hvclient.CertInfo) {
+	fmt.Fprintf(w, "Serial number:       %s\n", info.SerialNumber())
+	fmt.Fprintf(w, "SHA-1 fingerprint:   %s\n", info.SHA1Fingerprint())
+	fmt.Fprintf(w, "SHA-256 fingerprint: %s\n", info.SHA256Fingerprint())
+
+	// Key identifiers are optional, so omit any which are not present.
+	if skid := info.SubjectKeyID(); skid != nil {
+		fmt.Fprintf(w, "Subject key ID:      %s\n", skid)
+	}
+
+	if akid := info.AuthorityKeyID(); akid != nil {
+		fmt.Fprintf(w, "Authority key ID:    %s\n", akid)
+	}
 }
 
 // retrieveCertUpdatedAt outputs the updated-at time for the
@@ -68,30 +121,163 @@ func retrieveCertUpdatedAt(clnt *hvclient.Client, serialNumber string) {
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	var sn, ok = big.NewInt(0).SetString(serialNumber, 16)
-	if !ok {
-		log.Fatalf("invalid serial number: %s", serialNumber)
+	var sn, err = parseSerial(serialNumber)
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
 
-	var cert, err = clnt.CertificateRetrieve(ctx, sn)
-	if err != nil {
+	var cert *hvclient.CertInfo
+	if cert, err = clnt.CertificateRetrieve(ctx, sn); err != nil {
 		log.Fatalf("%v", err)
 	}
 
 	fmt.Printf("%v\n", cert.UpdatedAt)
 }
 
-// revokeCert revokes the certificate with the specified serial number.
-func revokeCert(clnt *hvclient.Client, serialNumber string) {
+// retrieveCertHistory outputs the known lifecycle events for the certificate
+// with the specified serial number, in chronological order.
+func retrieveCertHistory(clnt *hvclient.Client, serialNumber string) {
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	var sn, ok = big.NewInt(0).SetString(serialNumber, 16)
-	if !ok {
-		log.Fatalf("invalid serial number: %s", serialNumber)
+	var sn, err = parseSerial(serialNumber)
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
 
-	if err := clnt.CertificateRevoke(ctx, sn); err != nil {
+	var events []hvclient.CertEvent
+	if events, err = clnt.CertificateHistory(ctx, sn); err != nil {
 		log.Fatalf("%v", err)
 	}
+
+	for _, event := range events {
+		fmt.Printf("%v,%s\n", event.Time, event.Type)
+	}
+}
+
+// revokeCert revokes the certificate with the specified serial number, or
+// the certificate in the specified PEM file, for the specified reason. If a
+// time is specified, the revocation is instead queued in the specified state
+// file, to be made by -revokedue once that time has passed.
+func revokeCert(clnt *hvclient.Client, serialNumber, reason, at, queue string) error {
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var rr, err = hvclient.ParseRevocationReason(reason)
+	if err != nil {
+		return err
+	}
+
+	var sn *big.Int
+	if sn, err = serialFromArg(ctx, clnt, serialNumber); err != nil {
+		return err
+	}
+
+	if at == "" {
+		return clnt.CertificateRevokeWithReason(ctx, sn, rr, 0)
+	}
+
+	var when time.Time
+	if when, err = time.Parse(defaultTimeLayout, at); err != nil {
+		return fmt.Errorf("couldn't parse revocation time: %v", err)
+	}
+
+	var sched *revoke.Scheduler
+	if sched, err = newRevokeScheduler(clnt, queue); err != nil {
+		return err
+	}
+
+	return sched.Schedule(sn, rr, when)
+}
+
+// serialFromArg returns the serial number specified by a command line
+// argument, which is either the path to a file containing a PEM-encoded
+// certificate issued for the account, or a hexadecimal serial number.
+func serialFromArg(ctx context.Context, clnt *hvclient.Client, arg string) (*big.Int, error) {
+	if info, err := os.Stat(arg); err == nil && info.Mode().IsRegular() {
+		var cert *x509.Certificate
+		if cert, err = pki.CertFromFile(arg); err != nil {
+			return nil, fmt.Errorf("couldn't read certificate: %w", err)
+		}
+
+		return clnt.FindSerialByCertificate(ctx, cert)
+	}
+
+	return parseSerial(arg)
+}
+
+// parseSerial parses a hexadecimal serial number specified at the command
+// line.
+func parseSerial(s string) (*big.Int, error) {
+	var sn, err = hvclient.ParseSerialNumber(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return sn.Big(), nil
+}
+
+// revokeDue revokes the certificates queued in the specified state file
+// whose scheduled revocation time has passed.
+func revokeDue(clnt *hvclient.Client, queue string) error {
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var sched, err = newRevokeScheduler(clnt, queue)
+	if err != nil {
+		return err
+	}
+
+	return sched.RevokeDue(ctx)
+}
+
+// newRevokeScheduler returns a revocation scheduler using the specified
+// state file.
+func newRevokeScheduler(clnt *hvclient.Client, queue string) (*revoke.Scheduler, error) {
+	if queue == "" {
+		return nil, errors.New("no revocation queue file specified with -revokequeue")
+	}
+
+	return revoke.New(revoke.Config{
+		Client:    clnt,
+		StateFile: queue,
+	})
+}
+
+// rekeyCert requests a new certificate to replace the certificate with the
+// specified serial number, using the key specified at the command line, and
+// outputs the new certificate in PEM format.
+func rekeyCert(clnt *hvclient.Client, serialNumber string) error {
+	var sn, err = parseSerial(serialNumber)
+	if err != nil {
+		return err
+	}
+
+	var rekey hvclient.CertificateRekeyRequest
+
+	if rekey.PublicKey, rekey.PrivateKey, rekey.CSR, err = getKeys(
+		*fPublicKey,
+		*fPrivateKey,
+		*fCSR,
+		getPasswordFromTerminal,
+	); err != nil {
+		return err
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var newSN *big.Int
+	if newSN, err = clnt.CertificateRekey(ctx, sn, &rekey); err != nil {
+		return fmt.Errorf("couldn't rekey certificate: %v", err)
+	}
+
+	var info *hvclient.CertInfo
+	if info, err = clnt.CertificateRetrieve(ctx, newSN); err != nil {
+		return fmt.Errorf("couldn't retrieve certificate %X: %v", newSN, err)
+	}
+
+	fmt.Printf("%s", info.PEM)
+
+	return exportPKCS12(ctx, clnt, rekey.PrivateKey, info.X509)
 }
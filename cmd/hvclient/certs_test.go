@@ -0,0 +1,100 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/testhelpers"
+)
+
+func TestWriteCertDetails(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		file string
+		want string
+	}{
+		{
+			name: "NoKeyIDs",
+			file: "testdata/cert.pem",
+			want: "Serial number:       8472EA925A2CBCD89D9F5C4AB1DECC30\n" +
+				"SHA-1 fingerprint:   7F:41:8B:AF:F0:E4:B1:69:88:EF:78:82:13:EA:1A:85:8F:FC:E3:E3\n" +
+				"SHA-256 fingerprint: A6:FA:DD:3E:22:8E:1E:4E:02:91:0E:A4:E4:D6:13:5C:8B:84:E6:CD:BC:67:54:A6:5E:DE:3D:45:EE:3D:3C:B1\n",
+		},
+		{
+			name: "KeyIDs",
+			file: "../../testdata/test_cert.pem",
+			want: "Serial number:       741DAF9EC2D5F7DC\n" +
+				"SHA-1 fingerprint:   5C:DB:18:91:BC:E6:6E:A4:51:41:AE:67:C2:6B:51:5D:3D:89:F5:0F\n" +
+				"SHA-256 fingerprint: 5C:6C:3C:10:7C:73:D1:5E:83:7C:67:26:E1:B4:09:81:98:69:86:05:34:5A:FF:95:13:3D:AC:4E:6A:18:CB:86\n" +
+				"Subject key ID:      CB:93:7B:3F:9E:D8:8B:B9:9B:59:40:BB:B3:FE:BD:41:E5:0F:91:22\n" +
+				"Authority key ID:    CB:93:7B:3F:9E:D8:8B:B9:9B:59:40:BB:B3:FE:BD:41:E5:0F:91:22\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			writeCertDetails(&buf, &hvclient.CertInfo{X509: testhelpers.MustGetCertFromFile(t, tc.file)})
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSerial(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "741DAF9EC2D5F7DC", want: "741DAF9EC2D5F7DC"},
+		{in: "0x741daf9ec2d5f7dc", want: "741DAF9EC2D5F7DC"},
+		{in: "74:1d:af:9e:c2:d5:f7:dc", want: "741DAF9EC2D5F7DC"},
+		{in: "not hex", err: true},
+		{in: "", err: true},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = parseSerial(tc.in)
+			if (err != nil) != tc.err {
+				t.Fatalf("got error %v, want error %t", err, tc.err)
+			}
+
+			if err == nil && fmt.Sprintf("%X", got) != tc.want {
+				t.Errorf("got %X, want %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -17,26 +17,55 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
 
 	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/claimmonitor"
 )
 
 // claimsDomains lists the ID, status, domain, created-at and assert-by times (or the
-// total count) for either pending or verified domain hvclient.
-func claimsDomains(clnt *hvclient.Client, page, pagesize int, pending bool) {
+// total count) for pending, verified or all domain claims.
+func claimsDomains(clnt *hvclient.Client, page, pagesize int, pending bool, statusName string) {
+	var status, err = claimsStatus(pending, statusName)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	var status hvclient.ClaimStatus
-	if pending {
-		status = hvclient.StatusPending
+	var clms []hvclient.Claim
+	var count int64
+	if *fClaimDomain != "" || *fSort != "" {
+		var query = hvclient.ClaimsQuery{
+			Status:            status,
+			Domain:            *fClaimDomain,
+			IncludeSubdomains: *fSubdomains,
+			Descending:        *fDescending,
+		}
+
+		if *fSort != "" {
+			if query.SortBy, err = hvclient.ParseClaimSortField(*fSort); err != nil {
+				log.Fatalf("%v", err)
+			}
+		}
+
+		clms, err = clnt.ClaimsDomainsQuery(ctx, query)
+		count = int64(len(clms))
+	} else if *fAllPages {
+		clms, err = clnt.ClaimsDomainsAll(ctx, status)
+		count = int64(len(clms))
 	} else {
-		status = hvclient.StatusVerified
+		clms, count, err = clnt.ClaimsDomains(ctx, page, pagesize, status)
 	}
 
-	var clms, count, err = clnt.ClaimsDomains(ctx, page, pagesize, status)
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
@@ -45,9 +74,120 @@ func claimsDomains(clnt *hvclient.Client, page, pagesize int, pending bool) {
 		fmt.Printf("%d\n", count)
 	} else {
 		for _, clm := range clms {
-			fmt.Printf("%s,%s,%s,%v,%v\n", clm.ID, clm.Status, clm.Domain, clm.CreatedAt, clm.AssertBy)
+			fmt.Printf("%s,%s,%s,%v,%v\n", clm.ID, clm.Status, hvclient.DomainToUnicode(clm.Domain), clm.CreatedAt, clm.AssertBy)
+		}
+	}
+}
+
+// claimsStatus returns the domain claim status specified by the -status
+// option, or by the -pending option if -status was not specified.
+func claimsStatus(pending bool, statusName string) (hvclient.ClaimStatus, error) {
+	if statusName == "" {
+		if pending {
+			return hvclient.StatusPending, nil
+		}
+
+		return hvclient.StatusVerified, nil
+	}
+
+	if pending {
+		return 0, errors.New("you cannot specify both -pending and -status")
+	}
+
+	return hvclient.ParseClaimStatus(statusName)
+}
+
+// claimsPurge deletes the pending domain claims, or those with the status
+// specified by the -status option, created longer ago than the specified age,
+// and lists the claims deleted. If dryRun is true, the claims are listed but
+// not deleted.
+func claimsPurge(clnt *hvclient.Client, age, statusName string, dryRun bool) error {
+	var olderThan, err = hvclient.ParseDuration(age)
+	if err != nil {
+		return fmt.Errorf("invalid age: %v", err)
+	}
+
+	var statuses []hvclient.ClaimStatus
+	if statusName != "" {
+		var status hvclient.ClaimStatus
+		if status, err = hvclient.ParseClaimStatus(statusName); err != nil {
+			return err
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var clms []hvclient.Claim
+	if dryRun {
+		clms, err = clnt.ClaimsPurgeDryRun(ctx, olderThan, statuses...)
+	} else {
+		clms, err = clnt.ClaimsPurge(ctx, olderThan, statuses...)
+	}
+
+	// List any claims deleted before an error occurred.
+	for _, clm := range clms {
+		fmt.Printf("%s,%s,%s,%v,%v\n", clm.ID, clm.Status, hvclient.DomainToUnicode(clm.Domain), clm.CreatedAt, clm.AssertBy)
+	}
+
+	return err
+}
+
+// canIssue checks whether certificates may be issued for the DNS names in a
+// comma-separated list, according to the validation policy and domain
+// claims, and outputs the result and matching domain claim for each. An
+// error is returned if a certificate may not be issued for any of them.
+func canIssue(clnt *hvclient.Client, names string) error {
+	var list []string
+	for _, name := range strings.Split(names, ",") {
+		var trimmed = strings.TrimSpace(name)
+		if trimmed == "" {
+			return fmt.Errorf("missing DNS name: %q", names)
+		}
+
+		list = append(list, trimmed)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var checks, err = clnt.CheckDomains(ctx, list...)
+	if err != nil {
+		return err
+	}
+
+	return writeDomainChecks(os.Stdout, checks)
+}
+
+// writeDomainChecks writes the result of each domain check, and returns an
+// error if a certificate may not be issued for any of the DNS names.
+func writeDomainChecks(w io.Writer, checks []hvclient.DomainCheck) error {
+	var failed int
+
+	for _, check := range checks {
+		var claimed = "-"
+		if check.Claim != nil {
+			claimed = hvclient.DomainToUnicode(strings.TrimSuffix(check.Claim.Domain, "."))
 		}
+
+		var result = "issuable"
+		if err := check.Err(); err != nil {
+			result = "not issuable: " + err.Error()
+			failed++
+		} else if check.CoveredBy != "" {
+			result = "issuable, also covered by " + check.CoveredBy
+		}
+
+		fmt.Fprintf(w, "%s,%s,%s\n", check.Name, claimed, result)
 	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d DNS names not issuable", failed, len(checks))
+	}
+
+	return nil
 }
 
 // claimRetrieve lists the ID, status, domain, created-at and assert-by times for the domain
@@ -61,7 +201,7 @@ func claimRetrieve(clnt *hvclient.Client, id string) {
 		log.Fatalf("%v", err)
 	}
 
-	fmt.Printf("%s,%s,%s,%v,%v\n", clm.ID, clm.Status, clm.Domain, clm.CreatedAt, clm.AssertBy)
+	fmt.Printf("%s,%s,%s,%v,%v\n", clm.ID, clm.Status, hvclient.DomainToUnicode(clm.Domain), clm.CreatedAt, clm.AssertBy)
 }
 
 // claimSubmit submits a domain claim for the specified domain and
@@ -76,6 +216,23 @@ func claimSubmit(clnt *hvclient.Client, domain string) {
 	}
 
 	fmt.Printf("%s,%v,%s\n", clm.Token, clm.AssertBy, clm.ID)
+
+	// Output the DNS record to create and a command to verify it to
+	// standard error, so as not to interfere with parsing of the output.
+	var name, rrtype, value = clm.DNSRecord(domain)
+
+	fmt.Fprintf(os.Stderr, "\nTo assert domain control using DNS, create the following record:\n\n")
+	fmt.Fprintf(os.Stderr, "    %s\n\n", bindRecord(name, rrtype, value))
+	fmt.Fprintf(os.Stderr, "To verify the record is visible before asserting the claim, run:\n\n")
+	fmt.Fprintf(os.Stderr, "    dig +short %s %s\n\n", rrtype, name)
+}
+
+// bindRecord formats a DNS record as a line in a BIND zone file, quoting
+// the value as a character string.
+func bindRecord(name, rrtype, value string) string {
+	var quoted = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+
+	return fmt.Sprintf("%s\tIN\t%s\t\"%s\"", name, rrtype, quoted)
 }
 
 // revokeCert revokes the certificate with the specified serial number.
@@ -89,11 +246,18 @@ func claimDelete(clnt *hvclient.Client, id string) {
 }
 
 // claimDNS requests assertion of domain control using DNS for
-// the specified claim ID.
-func claimDNS(clnt *hvclient.Client, id, authDomain string) {
+// the specified claim ID. If a token is specified, the DNS record is first
+// checked locally.
+func claimDNS(clnt *hvclient.Client, id, authDomain, token string) {
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	if token != "" {
+		if err := verifyClaimLocally(ctx, clnt, id, authDomain, token, hvclient.ClaimVerifyDNSLocally); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
 	var clm, err = clnt.ClaimDNS(ctx, id, authDomain)
 	if err != nil {
 		log.Fatalf("%v", err)
@@ -106,12 +270,89 @@ func claimDNS(clnt *hvclient.Client, id, authDomain string) {
 	}
 }
 
+// claimMonitor checks the DNS record for the specified claim ID at the
+// specified interval, and requests assertion of domain control using DNS
+// once the token is visible locally, until HVCA verifies the claim or the
+// maximum number of assertions has been made.
+func claimMonitor(
+	clnt *hvclient.Client,
+	id, authDomain, token string,
+	interval time.Duration,
+	maxAssertions int,
+) error {
+	if token == "" {
+		return errors.New("you must specify the domain claim token with -token")
+	}
+
+	if maxAssertions < 1 {
+		return errors.New("-maxassertions must be at least 1")
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	var clm, err = clnt.ClaimRetrieve(ctx, id)
+	cancel()
+
+	if err != nil {
+		return fmt.Errorf("couldn't retrieve domain claim: %w", err)
+	}
+
+	var monitor *claimmonitor.Monitor
+	if monitor, err = claimmonitor.New(claimmonitor.Config{
+		Client:        clnt,
+		Interval:      interval,
+		MaxAssertions: maxAssertions,
+		OnError: func(id string, err error) {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", id, err)
+		},
+	}); err != nil {
+		return err
+	}
+
+	if err = monitor.Add(claimmonitor.Claim{
+		ID:         id,
+		Domain:     clm.Domain,
+		AuthDomain: authDomain,
+		Token:      token,
+	}); err != nil {
+		return err
+	}
+
+	// Run until the claim is verified or exhausted, or until interrupted.
+	ctx, cancel = signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err = monitor.Run(ctx); err != nil {
+		return err
+	}
+
+	var attempts, _ = monitor.Attempts(id)
+	if !attempts.Verified {
+		return fmt.Errorf("domain control not verified after %d checks and %d assertions",
+			attempts.Checks, attempts.Assertions)
+	}
+
+	fmt.Printf("VERIFIED\n")
+
+	return nil
+}
+
 // claimHTTP requests assertion of domain control using HTTP for
-// the specified claim ID.
-func claimHTTP(clnt *hvclient.Client, id, scheme, authDomain string) {
+// the specified claim ID. If a token is specified, the token file is first
+// checked locally.
+func claimHTTP(clnt *hvclient.Client, id, scheme, authDomain, token string) {
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	if token != "" {
+		var check = func(ctx context.Context, domain, token string) error {
+			return hvclient.ClaimVerifyHTTPLocally(ctx, domain, token, scheme)
+		}
+
+		if err := verifyClaimLocally(ctx, clnt, id, authDomain, token, check); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
 	var clm, err = clnt.ClaimHTTP(ctx, id, authDomain, scheme)
 	if err != nil {
 		log.Fatalf("%v", err)
@@ -124,6 +365,34 @@ func claimHTTP(clnt *hvclient.Client, id, scheme, authDomain string) {
 	}
 }
 
+// verifyClaimLocally uses the specified check function to verify the token
+// is visible at the authorization domain, or at the claimed domain if no
+// authorization domain is specified, before domain control is asserted.
+func verifyClaimLocally(
+	ctx context.Context,
+	clnt *hvclient.Client,
+	id string,
+	authDomain string,
+	token string,
+	check func(ctx context.Context, domain, token string) error,
+) error {
+	var domain = authDomain
+	if domain == "" {
+		var clm, err = clnt.ClaimRetrieve(ctx, id)
+		if err != nil {
+			return fmt.Errorf("couldn't retrieve domain claim: %w", err)
+		}
+
+		domain = clm.Domain
+	}
+
+	if err := check(ctx, domain, token); err != nil {
+		return fmt.Errorf("%v (omit -token to assert domain control without checking)", err)
+	}
+
+	return nil
+}
+
 // claimEmail requests assertion of domain control using Email for
 // the specified claim ID.
 func claimEmail(clnt *hvclient.Client, id, emailAddress string) {
@@ -0,0 +1,145 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+)
+
+func TestBINDRecord(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "Plain",
+			value: "_globalsign-domain-verification=1234",
+			want:  "example.com.\tIN\tTXT\t\"_globalsign-domain-verification=1234\"",
+		},
+		{
+			name:  "Escaped",
+			value: `a "quoted" \ value`,
+			want:  "example.com.\tIN\tTXT\t\"a \\\"quoted\\\" \\\\ value\"",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := bindRecord("example.com.", "TXT", tc.value); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClaimsStatus(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name    string
+		pending bool
+		status  string
+		want    hvclient.ClaimStatus
+		err     bool
+	}{
+		{
+			name: "Default",
+			want: hvclient.StatusVerified,
+		},
+		{
+			name:    "PendingFlag",
+			pending: true,
+			want:    hvclient.StatusPending,
+		},
+		{
+			name:   "All",
+			status: "all",
+			want:   hvclient.StatusAll,
+		},
+		{
+			name:   "Pending",
+			status: "pending",
+			want:   hvclient.StatusPending,
+		},
+		{
+			name:    "Both",
+			pending: true,
+			status:  "verified",
+			err:     true,
+		},
+		{
+			name:   "Invalid",
+			status: "expired",
+			err:    true,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = claimsStatus(tc.pending, tc.status)
+			if (err != nil) != tc.err {
+				t.Fatalf("got error %v, want error %t", err, tc.err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteDomainChecks(t *testing.T) {
+	t.Parallel()
+
+	var verified = &hvclient.Claim{Status: hvclient.StatusVerified, Domain: "example.com."}
+
+	var checks = []hvclient.DomainCheck{
+		{Name: "*.example.com", Claim: verified},
+		{Name: "www.example.com", Claim: verified, CoveredBy: "*.example.com"},
+		{Name: "example.org"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeDomainChecks(&buf, checks); err == nil {
+		t.Errorf("unexpectedly reported all DNS names issuable")
+	}
+
+	var want = "*.example.com,example.com,issuable\n" +
+		"www.example.com,example.com,issuable, also covered by *.example.com\n" +
+		"example.org,-,not issuable: no verified domain claim covers example.org\n"
+
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if err := writeDomainChecks(&bytes.Buffer{}, checks[:2]); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// command is a subcommand, which is translated into the equivalent legacy
+// command line flag before the flags are parsed.
+type command struct {
+	Name     string
+	Summary  string
+	flag     string
+	arg      string
+	Children []command
+}
+
+// commands are the available subcommands. A command with children requires
+// one of its children to be selected. A command with an arg requires a
+// single positional argument, which is used as the value of its flag.
+var commands = []command{
+	{Name: "request", Summary: "request a new certificate"},
+	{Name: "interactive", Summary: "prompt for request values and request a new certificate", flag: "interactive"},
+	{Name: "smime", Summary: "request an S/MIME certificate and write it to a PKCS#12 file", flag: "smime"},
+	{Name: "retrieve", Summary: "retrieve a certificate", flag: "retrieve", arg: "serial"},
+	{Name: "status", Summary: "show the status of a certificate", flag: "status", arg: "serial"},
+	{Name: "updated", Summary: "show the updated-at time of a certificate", flag: "updated", arg: "serial"},
+	{Name: "history", Summary: "show the lifecycle events of a certificate", flag: "history", arg: "serial"},
+	{Name: "revoke", Summary: "revoke a certificate", flag: "revoke", arg: "serial"},
+	{Name: "revokedue", Summary: "revoke queued certificates whose revocation time has passed", flag: "revokedue"},
+	{Name: "rekey", Summary: "request a new certificate to replace an existing one", flag: "rekey", arg: "serial"},
+	{Name: "trustchain", Summary: "retrieve the chain of trust for issued certificates", flag: "trustchain"},
+	{Name: "policy", Summary: "retrieve the validation policy", flag: "policy"},
+	{Name: "quota", Summary: "show the remaining issuance quota", flag: "quota"},
+	{Name: "account", Summary: "show a summary of the account", flag: "account"},
+	{Name: "capabilities", Summary: "show which optional HVCA endpoints are available", flag: "capabilities"},
+	{Name: "counters", Summary: "show certificate counts", Children: []command{
+		{Name: "issued", Summary: "show the count of certificates issued", flag: "countissued"},
+		{Name: "revoked", Summary: "show the count of certificates revoked", flag: "countrevoked"},
+	}},
+	{Name: "stats", Summary: "list certificates during the time window", Children: []command{
+		{Name: "issued", Summary: "list certificates issued", flag: "certsissued"},
+		{Name: "revoked", Summary: "list certificates revoked", flag: "certsrevoked"},
+		{Name: "expiring", Summary: "list certificates expiring", flag: "certsexpiring"},
+	}},
+	{Name: "claims", Summary: "manage domain claims", Children: []command{
+		{Name: "list", Summary: "list domain claims", flag: "claims"},
+		{Name: "submit", Summary: "submit a domain claim", flag: "claimsubmit", arg: "domain"},
+		{Name: "retrieve", Summary: "retrieve a domain claim", flag: "claimretrieve", arg: "id"},
+		{Name: "delete", Summary: "delete a domain claim", flag: "claimdelete", arg: "id"},
+		{Name: "purge", Summary: "delete stale domain claims", flag: "claimspurge", arg: "age"},
+		{Name: "can-issue", Summary: "check whether certificates may be issued for DNS names", flag: "canissue", arg: "names"},
+		{Name: "dns", Summary: "assert domain control using DNS", flag: "claimdns", arg: "id"},
+		{Name: "monitor", Summary: "wait for the DNS record to be visible, then assert domain control", flag: "claimmonitor", arg: "id"},
+		{Name: "http", Summary: "assert domain control using HTTP", flag: "claimhttp", arg: "id"},
+		{Name: "email", Summary: "assert domain control using email", flag: "claimemail", arg: "id"},
+		{Name: "emaillist", Summary: "list email addresses authorised for email validation", flag: "claimemaillist", arg: "id"},
+		{Name: "reassert", Summary: "reassert a domain claim", flag: "claimreassert", arg: "id"},
+	}},
+	{Name: "reconcile", Summary: "compare deployed certificates with those issued and revoked", flag: "reconcile", arg: "directory"},
+	{Name: "selftest", Summary: "check the account and configuration", flag: "selftest"},
+	{Name: "lint", Summary: "check a template against the validation policy", flag: "lint"},
+	{Name: "configinit", Summary: "create a new configuration file", flag: "configinit"},
+	{Name: "config", Summary: "manage the configuration", Children: []command{
+		{Name: "init", Summary: "create a new configuration file", flag: "configinit"},
+		{Name: "store-secret", Summary: "store a configuration secret in the OS keychain", flag: "storesecret", arg: "name"},
+	}},
+	{Name: "sampletemplate", Summary: "output a sample certificate request template", flag: "sampletemplate"},
+	{Name: "genrsa", Summary: "generate an RSA private key", flag: "genrsa", arg: "bits"},
+	{Name: "completion", Summary: "output a shell completion script", Children: []command{
+		{Name: "bash", Summary: "output a bash completion script"},
+		{Name: "zsh", Summary: "output a zsh completion script"},
+		{Name: "fish", Summary: "output a fish completion script"},
+	}},
+	{Name: "help", Summary: "show online help", flag: "h"},
+	{Name: "version", Summary: "show version information", flag: "v"},
+}
+
+// findCommand returns the command with the specified name, or nil if there
+// is no such command.
+func findCommand(cmds []command, name string) *command {
+	for i := range cmds {
+		if cmds[i].Name == name {
+			return &cmds[i]
+		}
+	}
+
+	return nil
+}
+
+// commandNames returns a space-separated list of the names of the commands.
+func commandNames(cmds []command) string {
+	var names = make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name)
+	}
+
+	return strings.Join(names, " ")
+}
+
+// translateArgs translates command line arguments which begin with a
+// subcommand into the equivalent legacy flags, so that both forms may be
+// parsed with the same flag set. For example, "claims submit example.com
+// -config=hvclient.conf" is translated into "-claimsubmit=example.com
+// -config=hvclient.conf". Arguments which do not begin with a subcommand are
+// returned unchanged. The names of the selected subcommands are also
+// returned.
+func translateArgs(args []string) ([]string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return args, nil, nil
+	}
+
+	var cmds = commands
+	var path []string
+
+	for {
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			return nil, nil, fmt.Errorf("%s requires one of: %s",
+				strings.Join(path, " "), commandNames(cmds))
+		}
+
+		var cmd = findCommand(cmds, args[0])
+		if cmd == nil {
+			if len(path) == 0 {
+				return nil, nil, fmt.Errorf("unknown command %q", args[0])
+			}
+
+			return nil, nil, fmt.Errorf("unknown %s command %q", strings.Join(path, " "), args[0])
+		}
+
+		path = append(path, cmd.Name)
+		args = args[1:]
+
+		switch {
+		case cmd.Children != nil:
+			cmds = cmd.Children
+			continue
+
+		case cmd.flag == "":
+			return args, path, nil
+
+		case cmd.arg == "":
+			return append([]string{"-" + cmd.flag}, args...), path, nil
+
+		case len(args) == 0 || strings.HasPrefix(args[0], "-"):
+			return nil, nil, fmt.Errorf("%s requires argument <%s>", strings.Join(path, " "), cmd.arg)
+		}
+
+		return append([]string{"-" + cmd.flag + "=" + args[0]}, args[1:]...), path, nil
+	}
+}
+
+// completionFlag is a command line flag as presented to a completion
+// script template.
+type completionFlag struct {
+	Name    string
+	Summary string
+}
+
+// completionTemplates are the completion script templates for each
+// supported shell.
+var completionTemplates = map[string]*template.Template{
+	"bash": template.Must(template.New("bash").Funcs(completionFuncs).Parse(bashCompletion)),
+	"zsh":  template.Must(template.New("zsh").Funcs(completionFuncs).Parse(zshCompletion)),
+	"fish": template.Must(template.New("fish").Funcs(completionFuncs).Parse(fishCompletion)),
+}
+
+// completionFuncs are the functions available to completion script
+// templates.
+var completionFuncs = template.FuncMap{
+	"names": commandNames,
+	"quote": func(s string) string {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	},
+	"fishquote": func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+	},
+	"zshdesc": strings.NewReplacer("[", "(", "]", ")", ":", " -", "'", "").Replace,
+}
+
+const bashCompletion = `# bash completion for hvclient
+
+_hvclient() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W '{{range $i, $f := .Flags}}{{if $i}} {{end}}-{{.Name}}{{end}}' -- "$cur"))
+        return
+    fi
+
+    if [[ $COMP_CWORD -eq 1 ]]; then
+        COMPREPLY=($(compgen -W '{{names .Commands}}' -- "$cur"))
+        return
+    fi
+
+    if [[ $COMP_CWORD -eq 2 ]]; then
+        case "${COMP_WORDS[1]}" in
+{{- range .Commands}}{{if .Children}}
+        {{.Name}}) COMPREPLY=($(compgen -W '{{names .Children}}' -- "$cur")); return ;;
+{{- end}}{{end}}
+        esac
+    fi
+
+    COMPREPLY=($(compgen -f -- "$cur"))
+}
+
+complete -o filenames -F _hvclient hvclient
+`
+
+const zshCompletion = `#compdef hvclient
+
+_hvclient() {
+    local -a commands
+
+    if [[ $words[CURRENT] == -* ]]; then
+        _values 'flag'{{range .Flags}} \
+            {{quote (print "-" .Name "[" (zshdesc .Summary) "]")}}
+{{- end}}
+        return
+    fi
+
+    if (( CURRENT == 2 )); then
+        commands=({{range .Commands}}
+            {{quote (print .Name ":" (zshdesc .Summary))}}
+{{- end}}
+        )
+        _describe 'command' commands
+        return
+    fi
+
+    if (( CURRENT == 3 )); then
+        case $words[2] in
+{{- range .Commands}}{{if .Children}}
+        {{.Name}})
+            commands=({{range .Children}}
+                {{quote (print .Name ":" (zshdesc .Summary))}}
+{{- end}}
+            )
+            _describe 'command' commands
+            return
+            ;;
+{{- end}}{{end}}
+        esac
+    fi
+
+    _files
+}
+
+compdef _hvclient hvclient
+`
+
+const fishCompletion = `# fish completion for hvclient
+{{range .Commands}}
+complete -c hvclient -n __fish_use_subcommand -f -a {{.Name}} -d {{fishquote .Summary}}
+{{- end}}
+{{- range $cmd := .Commands}}{{range .Children}}
+complete -c hvclient -n '__fish_seen_subcommand_from {{$cmd.Name}}' -f -a {{.Name}} -d {{fishquote .Summary}}
+{{- end}}{{end}}
+{{- range .Flags}}
+complete -c hvclient -o {{.Name}} -d {{fishquote .Summary}}
+{{- end}}
+`
+
+// writeCompletion writes a completion script for the specified shell,
+// covering the subcommands and the flags in the flag set.
+func writeCompletion(w io.Writer, shell string, fs *flag.FlagSet) error {
+	var tmpl, ok = completionTemplates[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q", shell)
+	}
+
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, completionFlag{Name: f.Name, Summary: f.Usage})
+	})
+
+	return tmpl.Execute(w, struct {
+		Commands []command
+		Flags    []completionFlag
+	}{
+		Commands: commands,
+		Flags:    flags,
+	})
+}
@@ -0,0 +1,198 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTranslateArgs(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name     string
+		args     []string
+		want     []string
+		wantCmds []string
+	}{
+		{
+			name: "Empty",
+		},
+		{
+			name: "Legacy",
+			args: []string{"-retrieve", "741DAF9EC2D5F7DC", "-config", "hvclient.conf"},
+			want: []string{"-retrieve", "741DAF9EC2D5F7DC", "-config", "hvclient.conf"},
+		},
+		{
+			name:     "NoArgument",
+			args:     []string{"policy", "-config", "hvclient.conf"},
+			want:     []string{"-policy", "-config", "hvclient.conf"},
+			wantCmds: []string{"policy"},
+		},
+		{
+			name:     "Argument",
+			args:     []string{"retrieve", "741DAF9EC2D5F7DC", "-format", "pem"},
+			want:     []string{"-retrieve=741DAF9EC2D5F7DC", "-format", "pem"},
+			wantCmds: []string{"retrieve"},
+		},
+		{
+			name:     "Nested",
+			args:     []string{"claims", "submit", "example.com"},
+			want:     []string{"-claimsubmit=example.com"},
+			wantCmds: []string{"claims", "submit"},
+		},
+		{
+			name:     "NestedNoArgument",
+			args:     []string{"stats", "issued", "-since", "24h"},
+			want:     []string{"-certsissued", "-since", "24h"},
+			wantCmds: []string{"stats", "issued"},
+		},
+		{
+			name:     "NoFlag",
+			args:     []string{"request", "-privatekey", "key.pem", "-commonname", "John Doe"},
+			want:     []string{"-privatekey", "key.pem", "-commonname", "John Doe"},
+			wantCmds: []string{"request"},
+		},
+		{
+			name:     "SMIME",
+			args:     []string{"smime", "-email", "alice@example.com", "-p12out", "alice.p12"},
+			want:     []string{"-smime", "-email", "alice@example.com", "-p12out", "alice.p12"},
+			wantCmds: []string{"smime"},
+		},
+		{
+			name:     "ConfigInit",
+			args:     []string{"config", "init", "-config", "hvclient.conf"},
+			want:     []string{"-configinit", "-config", "hvclient.conf"},
+			wantCmds: []string{"config", "init"},
+		},
+		{
+			name:     "Completion",
+			args:     []string{"completion", "bash"},
+			want:     []string{},
+			wantCmds: []string{"completion", "bash"},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got, gotCmds, err = translateArgs(tc.args)
+			if err != nil {
+				t.Fatalf("couldn't translate arguments: %v", err)
+			}
+
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("got arguments %q, want %q", got, tc.want)
+			}
+
+			if !cmp.Equal(gotCmds, tc.wantCmds) {
+				t.Errorf("got commands %q, want %q", gotCmds, tc.wantCmds)
+			}
+		})
+	}
+}
+
+func TestTranslateArgsFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "Unknown",
+			args: []string{"frobnicate"},
+			want: `unknown command "frobnicate"`,
+		},
+		{
+			name: "UnknownNested",
+			args: []string{"claims", "frobnicate"},
+			want: `unknown claims command "frobnicate"`,
+		},
+		{
+			name: "MissingNested",
+			args: []string{"stats", "-since", "24h"},
+			want: "stats requires one of: issued revoked expiring",
+		},
+		{
+			name: "MissingArgument",
+			args: []string{"revoke"},
+			want: "revoke requires argument <serial>",
+		},
+		{
+			name: "FlagInsteadOfArgument",
+			args: []string{"claims", "dns", "-authdomain", "example.com"},
+			want: "claims dns requires argument <id>",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var _, _, err = translateArgs(tc.args)
+			if err == nil {
+				t.Fatalf("unexpectedly translated arguments")
+			}
+
+			if err.Error() != tc.want {
+				t.Errorf("got error %q, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	t.Parallel()
+
+	var fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("config", "", "path to configuration file")
+	fs.Bool("quota", false, "show remaining quota")
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var shell = shell
+
+		t.Run(shell, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := writeCompletion(&buf, shell, fs); err != nil {
+				t.Fatalf("couldn't write completion script: %v", err)
+			}
+
+			for _, want := range []string{"hvclient", "claims", "reassert", "config", "quota"} {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("completion script does not contain %q", want)
+				}
+			}
+		})
+	}
+
+	if err := writeCompletion(&bytes.Buffer{}, "tcsh", fs); err == nil {
+		t.Errorf("unexpectedly wrote completion script for unsupported shell")
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/globalsign/hvclient/internal/config"
+	"github.com/globalsign/hvclient/internal/fileutils"
+)
+
+const (
+	configEnvVar     = "HVCLIENT_CONFIG"
+	profileEnvVar    = "HVCLIENT_PROFILE"
+	configDirName    = "hvclient"
+	configFileName   = "hvclient.conf"
+	defaultConfigURL = "https://emea.api.hvca.globalsign.com:8443/v2"
+
+	defaultConfigTimeout = 60
+)
+
+// configFileCandidates returns the paths at which to look for a configuration
+// file, in order of preference. If the HVCLIENT_CONFIG environment variable
+// is set, its value is the only candidate. Otherwise the candidates are the
+// platform-specific configuration directory, i.e. %APPDATA% on Windows and
+// $XDG_CONFIG_HOME (or $HOME/.config) elsewhere, followed by the legacy
+// $HOME/.hvclient directory.
+func configFileCandidates(getenv func(string) string, goos string) []string {
+	if path := getenv(configEnvVar); path != "" {
+		return []string{path}
+	}
+
+	var home = getenv("HOME")
+	if home == "" && goos == "windows" {
+		home = getenv("USERPROFILE")
+	}
+
+	var candidates []string
+
+	if goos == "windows" {
+		if appData := getenv("APPDATA"); appData != "" {
+			candidates = append(candidates, filepath.Join(appData, configDirName, configFileName))
+		}
+	} else if xdg := getenv("XDG_CONFIG_HOME"); xdg != "" {
+		candidates = append(candidates, filepath.Join(xdg, configDirName, configFileName))
+	} else if home != "" {
+		candidates = append(candidates, filepath.Join(home, ".config", configDirName, configFileName))
+	}
+
+	if home != "" {
+		candidates = append(candidates, filepath.Join(home, defaultConfigFile))
+	}
+
+	return candidates
+}
+
+// findConfigFile returns the first of the candidate paths at which a file
+// exists.
+func findConfigFile(candidates []string, exists func(string) bool) (string, error) {
+	if len(candidates) == 0 {
+		return "", errors.New("you must specify a configuration file")
+	}
+
+	for _, candidate := range candidates {
+		if exists(candidate) {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("no configuration file found at %s; specify one with -config or create one with -configinit",
+		strings.Join(candidates, " or "))
+}
+
+// defaultConfigFilePath returns the path of the configuration file to use
+// when none is specified at the command line.
+func defaultConfigFilePath() (string, error) {
+	return findConfigFile(configFileCandidates(os.Getenv, runtime.GOOS), fileExists)
+}
+
+// fileExists returns true if a file exists at the specified path.
+func fileExists(path string) bool {
+	var _, err = os.Stat(path)
+
+	return err == nil
+}
+
+// configInit prompts for account details and writes a new configuration file
+// to the specified path, or to the preferred platform-specific location if
+// no path is specified. An existing file is never overwritten.
+func configInit(path string) error {
+	if path == "" {
+		var candidates = configFileCandidates(os.Getenv, runtime.GOOS)
+		if len(candidates) == 0 {
+			return errors.New("couldn't determine where to create configuration file; specify one with -config")
+		}
+
+		path = candidates[0]
+	}
+
+	if fileExists(path) {
+		return fmt.Errorf("configuration file %s already exists", path)
+	}
+
+	var conf, err = promptConfig(newPrompter(os.Stdin, os.Stderr), func(prompt string) (string, error) {
+		return getPasswordFromTerminal(prompt, false)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = writeConfigFile(path, conf); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Configuration written to %s\n", path)
+
+	return nil
+}
+
+// promptConfig prompts for the values in a configuration file. Secret values
+// are read with the secret function, so they need not be echoed.
+func promptConfig(p *prompter, secret func(prompt string) (string, error)) (*config.Config, error) {
+	var conf = &config.Config{}
+	var err error
+
+	if conf.URL, err = p.readLineDefault("HVCA URL", defaultConfigURL); err != nil {
+		return nil, err
+	}
+
+	if conf.APIKey, err = p.readLineRequired("API key"); err != nil {
+		return nil, err
+	}
+
+	for conf.APISecret == "" {
+		if conf.APISecret, err = secret("API secret (required)"); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, field := range []struct {
+		prompt string
+		to     *string
+	}{
+		{"Path to mTLS certificate file", &conf.CertFile},
+		{"Path to mTLS private key file", &conf.KeyFile},
+	} {
+		var path string
+		if path, err = p.readLineRequired(field.prompt); err != nil {
+			return nil, err
+		}
+
+		// Store absolute paths, since relative paths would be interpreted
+		// relative to the working directory when the file is later used.
+		if *field.to, err = filepath.Abs(path); err != nil {
+			return nil, fmt.Errorf("couldn't resolve path %s: %v", path, err)
+		}
+	}
+
+	if conf.KeyPassphrase, err = secret("mTLS private key passphrase (optional)"); err != nil {
+		return nil, err
+	}
+
+	for {
+		var value string
+		if value, err = p.readLineDefault("Timeout in seconds", strconv.Itoa(defaultConfigTimeout)); err != nil {
+			return nil, err
+		}
+
+		if conf.Timeout, err = strconv.Atoi(value); err == nil && conf.Timeout > 0 {
+			break
+		}
+
+		fmt.Fprintf(p.out, "Timeout must be a positive integer.\n")
+	}
+
+	return conf, nil
+}
+
+// writeConfigFile writes a configuration file, creating any necessary
+// directories. Since the file contains credentials, it is readable only by
+// the current user.
+func writeConfigFile(path string, conf *config.Config) error {
+	var data, err = json.MarshalIndent(conf, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("couldn't create configuration directory: %v", err)
+	}
+
+	if err = fileutils.WriteFileLocked(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("couldn't write configuration file: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/globalsign/hvclient/internal/config"
+)
+
+func TestConfigFileCandidates(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		env  map[string]string
+		goos string
+		want []string
+	}{
+		{
+			name: "EnvVar",
+			env:  map[string]string{"HVCLIENT_CONFIG": "/etc/hvclient.conf", "HOME": "/home/jdoe"},
+			goos: "linux",
+			want: []string{"/etc/hvclient.conf"},
+		},
+		{
+			name: "XDG",
+			env:  map[string]string{"XDG_CONFIG_HOME": "/home/jdoe/.xdg", "HOME": "/home/jdoe"},
+			goos: "linux",
+			want: []string{
+				filepath.Join("/home/jdoe/.xdg", "hvclient", "hvclient.conf"),
+				filepath.Join("/home/jdoe", ".hvclient", "hvclient.conf"),
+			},
+		},
+		{
+			name: "XDGDefault",
+			env:  map[string]string{"HOME": "/home/jdoe"},
+			goos: "darwin",
+			want: []string{
+				filepath.Join("/home/jdoe", ".config", "hvclient", "hvclient.conf"),
+				filepath.Join("/home/jdoe", ".hvclient", "hvclient.conf"),
+			},
+		},
+		{
+			name: "Windows",
+			env:  map[string]string{"APPDATA": "/Users/jdoe/AppData/Roaming", "USERPROFILE": "/Users/jdoe"},
+			goos: "windows",
+			want: []string{
+				filepath.Join("/Users/jdoe/AppData/Roaming", "hvclient", "hvclient.conf"),
+				filepath.Join("/Users/jdoe", ".hvclient", "hvclient.conf"),
+			},
+		},
+		{
+			name: "NoEnvironment",
+			goos: "linux",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got = configFileCandidates(func(key string) string { return tc.env[key] }, tc.goos)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	t.Parallel()
+
+	var candidates = []string{"first.conf", "second.conf"}
+
+	var got, err = findConfigFile(candidates, func(path string) bool { return path == "second.conf" })
+	if err != nil {
+		t.Fatalf("couldn't find configuration file: %v", err)
+	}
+
+	if got != "second.conf" {
+		t.Errorf("got %s, want second.conf", got)
+	}
+
+	if _, err = findConfigFile(candidates, func(string) bool { return false }); err == nil {
+		t.Errorf("unexpectedly found configuration file")
+	}
+
+	if _, err = findConfigFile(nil, func(string) bool { return true }); err == nil {
+		t.Errorf("unexpectedly found configuration file with no candidates")
+	}
+}
+
+func TestPromptConfig(t *testing.T) {
+	t.Parallel()
+
+	var input = strings.Join([]string{
+		"",
+		"",
+		"my_api_key",
+		"/path/to/cert.pem",
+		"/path/to/key.pem",
+		"soon",
+		"30",
+	}, "\n")
+
+	var secrets = []string{"", "my_api_secret", "my_passphrase"}
+	var secret = func(prompt string) (string, error) {
+		var s = secrets[0]
+		secrets = secrets[1:]
+
+		return s, nil
+	}
+
+	var got, err = promptConfig(newPrompter(strings.NewReader(input), ioutil.Discard), secret)
+	if err != nil {
+		t.Fatalf("couldn't prompt for configuration: %v", err)
+	}
+
+	var want = &config.Config{
+		URL:           defaultConfigURL,
+		APIKey:        "my_api_key",
+		APISecret:     "my_api_secret",
+		CertFile:      "/path/to/cert.pem",
+		KeyFile:       "/path/to/key.pem",
+		KeyPassphrase: "my_passphrase",
+		Timeout:       30,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteConfigFile(t *testing.T) {
+	t.Parallel()
+
+	var path = filepath.Join(t.TempDir(), "hvclient", "hvclient.conf")
+	var conf = &config.Config{
+		URL:     defaultConfigURL,
+		APIKey:  "my_api_key",
+		Timeout: 60,
+	}
+
+	if err := writeConfigFile(path, conf); err != nil {
+		t.Fatalf("couldn't write configuration file: %v", err)
+	}
+
+	var info, err = os.Stat(path)
+	if err != nil {
+		t.Fatalf("couldn't stat configuration file: %v", err)
+	}
+
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("got permissions %o, want 600", perm)
+	}
+
+	var got *config.Config
+	if got, err = config.NewFromFile(path); err != nil {
+		t.Fatalf("couldn't read configuration file: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, conf) {
+		t.Errorf("got %+v, want %+v", got, conf)
+	}
+}
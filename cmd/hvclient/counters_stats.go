@@ -17,8 +17,10 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/globalsign/hvclient"
@@ -50,6 +52,43 @@ func quota(clnt *hvclient.Client) {
 	outputCount(clnt.QuotaIssuance(ctx))
 }
 
+// accountInfo outputs a summary of the account in JSON format.
+func accountInfo(clnt *hvclient.Client) {
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var info, err = clnt.AccountInfo(ctx)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	var data []byte
+	if data, err = json.MarshalIndent(info, "", "   "); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	fmt.Printf("%s\n", string(data))
+}
+
+// capabilities outputs which optional HVCA endpoints are available to the
+// account in JSON format.
+func capabilities(clnt *hvclient.Client) {
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var caps, err = clnt.Capabilities(ctx)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	var data []byte
+	if data, err = json.MarshalIndent(caps, "", "   "); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	fmt.Printf("%s\n", string(data))
+}
+
 // outputCount outputs a count.
 func outputCount(count int64, err error) {
 	if err != nil {
@@ -65,7 +104,15 @@ func certsExpiring(clnt *hvclient.Client, from, to time.Time, page, pagesize int
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	outputCertsMeta(clnt.StatsExpiring(ctx, page, pagesize, from, to))
+	if *fAllPages {
+		var metas, err = clnt.StatsExpiringAll(ctx, from, to)
+		outputCertsMeta(ctx, clnt, metas, int64(len(metas)), err)
+
+		return
+	}
+
+	var metas, count, err = clnt.StatsExpiring(ctx, page, pagesize, from, to)
+	outputCertsMeta(ctx, clnt, metas, count, err)
 }
 
 // certsIssued lists the serial numbers, not-before times, and not-after times of
@@ -74,7 +121,15 @@ func certsIssued(clnt *hvclient.Client, from, to time.Time, page, pagesize int)
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	outputCertsMeta(clnt.StatsIssued(ctx, page, pagesize, from, to))
+	if *fAllPages {
+		var metas, err = clnt.StatsIssuedAll(ctx, from, to)
+		outputCertsMeta(ctx, clnt, metas, int64(len(metas)), err)
+
+		return
+	}
+
+	var metas, count, err = clnt.StatsIssued(ctx, page, pagesize, from, to)
+	outputCertsMeta(ctx, clnt, metas, count, err)
 }
 
 // certsRevoked lists the serial numbers, not-before times, and not-after times of
@@ -83,21 +138,49 @@ func certsRevoked(clnt *hvclient.Client, from, to time.Time, page, pagesize int)
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	outputCertsMeta(clnt.StatsRevoked(ctx, page, pagesize, from, to))
+	if *fAllPages {
+		var metas, err = clnt.StatsRevokedAll(ctx, from, to)
+		outputCertsMeta(ctx, clnt, metas, int64(len(metas)), err)
+
+		return
+	}
+
+	var metas, count, err = clnt.StatsRevoked(ctx, page, pagesize, from, to)
+	outputCertsMeta(ctx, clnt, metas, count, err)
 }
 
 // outputCertsMeta outputs an array of certificate metadata, or a total count if
-// the -totalcount flag is set.
-func outputCertsMeta(metas []hvclient.CertMeta, count int64, err error) {
+// the -totalcount flag is set. If the -details flag is set, each certificate
+// is first retrieved to show its subject common name and SAN DNS names.
+func outputCertsMeta(ctx context.Context, clnt *hvclient.Client, metas []hvclient.CertMeta, count int64, err error) {
 	if err != nil {
 		log.Fatalf("%v", err)
 	}
 
 	if *fTotalCount {
 		fmt.Printf("%d\n", count)
-	} else {
-		for _, meta := range metas {
-			fmt.Printf("%x,%v,%v\n", meta.SerialNumber, meta.NotBefore, meta.NotAfter)
+		return
+	}
+
+	if *fDetails {
+		if err = clnt.CertMetaDetails(ctx, metas, *fConcurrency); err != nil {
+			log.Fatalf("%v", err)
 		}
 	}
+
+	for _, meta := range metas {
+		fmt.Println(formatCertMeta(meta, *fDetails))
+	}
+}
+
+// formatCertMeta formats certificate metadata as a comma-separated line,
+// optionally including the subject common name and the SAN DNS names, which
+// are separated by semicolons.
+func formatCertMeta(meta hvclient.CertMeta, details bool) string {
+	var line = fmt.Sprintf("%x,%v,%v", meta.SerialNumber, meta.NotBefore, meta.NotAfter)
+	if details {
+		line += "," + meta.CommonName + "," + strings.Join(meta.DNSNames, ";")
+	}
+
+	return line
 }
@@ -15,7 +15,10 @@ limitations under the License.
 
 package main
 
-import "flag"
+import (
+	"flag"
+	"time"
+)
 
 const (
 	flagNamePublicKey  = "publickey"
@@ -26,8 +29,12 @@ const (
 
 // General flags.
 var (
-	fHelp    = flag.Bool("h", false, "show online help")
-	fVersion = flag.Bool("v", false, "show version information")
+	fHelp      = flag.Bool("h", false, "show online help")
+	fVersion   = flag.Bool("v", false, "show version information")
+	fTimeout   = flag.Duration("timeout", 0, "timeout for each HVCA request e.g. 30s, 2m (default: from configuration file)")
+	fRetries   = flag.Int("retries", -1, "maximum number of times to retry a temporarily failed HVCA request (default: from configuration file)")
+	fDebugHTTP = flag.Bool("debughttp", false, "write redacted HTTP requests and responses for failed HVCA requests to standard error")
+	fNoCache   = flag.Bool("nocache", false, "don't cache the validation policy and trust chain in the user cache directory")
 )
 
 // PKI flags.
@@ -44,9 +51,16 @@ var (
 	fGenCSR         = flag.Bool("gencsr", false, "generate a PKCS#10 certificate signing request from a -privatekey")
 	fTemplate       = flag.String(flagNameTemplate, "", "path to certificate request template file")
 	fSampleTemplate = flag.Bool("sampletemplate", false, "output sample certificate request template file")
-	fConfigFile     = flag.String("config", "", "path to configuration file (default: $HOME/.hvclient/hvclient.conf)")
+	fConfigFile     = flag.String("config", "", "path to configuration file (default: $HVCLIENT_CONFIG, or found in the platform configuration directory or $HOME/.hvclient)")
+	fProfile        = flag.String("profile", "", "name of the profile to use from the configuration file (default: $HVCLIENT_PROFILE)")
+	fConfigInit     = flag.Bool("configinit", false, "prompt for account details and create a new configuration file")
+	fStoreSecret    = flag.String("storesecret", "", "prompt for the named secret (api_secret or key_passphrase) and store it in the OS keychain")
 	fGenerate       = flag.Bool("generate", false, "output request JSON without making request")
 	fCSROut         = flag.Bool("csrout", false, "output PKCS#10 certificate signing request without making request")
+	fDryRun         = flag.Bool("dryrun", false, "check the request against the validation policy and report every problem found without making request")
+	fInteractive    = flag.Bool("interactive", false, "prompt for certificate request values allowed by the validation policy")
+	fSMIME          = flag.Bool("smime", false, "generate a key and request an S/MIME certificate for -email, writing it to the -p12out PKCS#12 file")
+	fNoDefaults     = flag.Bool("nodefaults", false, "don't fill static values from the validation policy into the request")
 )
 
 // Validity flags.
@@ -58,6 +72,7 @@ var (
 
 // Subject distinguished name flags.
 var (
+	fSubject                   = flag.String("subject", "", "subject distinguished name as an RFC 4514 string, e.g. \"CN=John Doe,O=ACME,C=GB\"")
 	fSubjectCommonName         = flag.String("commonname", "", "subject common name")
 	fSubjectSerialNumber       = flag.String("serialnumber", "", "subject serial number (distinct from certificate serial number)")
 	fSubjectOrganization       = flag.String("organization", "", "subject organization")
@@ -72,6 +87,8 @@ var (
 	fSubjectJOICountry         = flag.String("joicountry", "", "subject jurisdiction country")
 	fSubjectBusinessCategory   = flag.String("businesscategory", "", "subject business category")
 	fSubjectExtraAttributes    = flag.String("extraattributes", "", "subject extra attributes in format \"2.5.4.4=surname,2.5.4.5=serial_number")
+
+	fReplaceSubject = flag.Bool("replacesubject", false, "replace the subject in the -template with the subject values specified at the command line, rather than merging them")
 )
 
 // SAN values flags.
@@ -80,6 +97,13 @@ var (
 	fEmails   = flag.String("emails", "", "comma-separated list of SAN email addresses")
 	fIPs      = flag.String("ips", "", "comma-separated list of SAN IP addresses")
 	fURIs     = flag.String("uris", "", "comma-separated list of SAN URIs")
+	fSPIFFEID = flag.String("spiffeid", "", "SPIFFE ID to include as the single spiffe:// SAN URI, e.g. spiffe://example.org/ns/prod/sa/web")
+
+	fReplaceSANs   = flag.Bool("replacesans", false, "replace the SANs in the -template with the SAN values specified at the command line, rather than appending them")
+	fCheckDNSNames = flag.Bool("checkdnsnames", false, "check SAN DNS names before submitting the request, converting Unicode names to punycode and rejecting names HVCA would reject")
+	fCheckEmails   = flag.Bool("checkemails", false, "check SAN email addresses before submitting the request, normalizing their domains and checking them against the validation policy")
+	fCheckCAA      = flag.String("checkcaa", "", `check the CAA records of SAN DNS names before submitting the request, and either "warn" or "fail" if they do not authorize GlobalSign`)
+	fDNSServers    = flag.String("dnsservers", "", "comma-separated list of DNS servers used by -checkcaa, rather than those in /etc/resolv.conf, which must be specified on Windows")
 )
 
 // Other certificate request flags.
@@ -94,24 +118,53 @@ var (
 
 // Time window flags.
 var (
-	fFrom  = flag.String("from", "", "start of the time window in layout "+defaultTimeLayout+" (default: 30 days ago)")
-	fTo    = flag.String("to", "", "end of the time window in layout "+defaultTimeLayout+" (default: current time)")
+	fFrom  = flag.String("from", "", "start of the time window in layout "+defaultTimeLayout+", a date, or relative e.g. -30d (default: 30 days ago)")
+	fTo    = flag.String("to", "", "end of the time window in layout "+defaultTimeLayout+", a date, or relative e.g. +1w (default: current time)")
 	fSince = flag.String("since", "", "duration of time window back from current time e.g. 60m, 24h, 30d")
 )
 
 // Pagination flags.
 var (
-	fPage       = flag.Int("page", 1, "page number for list-producing APIs")
-	fPageSize   = flag.Int("pagesize", 100, "page size for list-producing APIs")
-	fTotalCount = flag.Bool("totalcount", false, "show total count for list-producing APIs")
+	fPage        = flag.Int("page", 1, "page number for list-producing APIs")
+	fPageSize    = flag.Int("pagesize", 100, "page size for list-producing APIs")
+	fTotalCount  = flag.Bool("totalcount", false, "show total count for list-producing APIs")
+	fAllPages    = flag.Bool("allpages", false, "list every page for list-producing APIs, ignoring -page and -pagesize")
+	fConcurrency = flag.Int("concurrency", 0, "use with -allpages or -details to set the maximum number of pages or certificates to request concurrently (default: 1)")
+	fProgress    = flag.Bool("progress", false, "use with -allpages to show the number of pages retrieved on standard error")
 )
 
 // Certificate flags.
 var (
 	fRetrieve = flag.String("retrieve", "", "retrieve the certificate with the specified serial number")
-	fStatus   = flag.String("status", "", "show the status of the certificate with the specified serial number")
+	fStatus   = flag.String("status", "", "show the status of the certificate with the specified serial number, or with -claims, show domain claims with the specified status (verified, pending or all)")
 	fUpdated  = flag.String("updated", "", "show the updated-at time for the certificate with the specified serial number")
+	fHistory  = flag.String("history", "", "show the known lifecycle events for the certificate with the specified serial number")
 	fRevoke   = flag.String("revoke", "", "revoke the certificate with the specified serial number")
+	fReason   = flag.String("reason", "unspecified", "use with -revoke to set the revocation reason")
+	fRevokeAt = flag.String("revokeat", "", "use with -revoke and -revokequeue to queue the revocation until the specified time in layout "+defaultTimeLayout)
+	fDetails  = flag.Bool("details", false, "use with -retrieve or -status to also show the serial number, SHA-1 and SHA-256 fingerprints, and subject and authority key identifiers, or with -certsissued, -certsrevoked or -certsexpiring to also show the subject common name and SAN DNS names")
+	fRekey    = flag.String("rekey", "", "request a new certificate to replace the certificate with the specified serial number, using the key from -publickey, -privatekey or -csr")
+	fWatch    = flag.String("watch", "", "watch the certificate in the specified file and renew it at two thirds of its lifetime with a request built from the request options, until interrupted")
+)
+
+// Revocation queue flags.
+var (
+	fRevokeQueue = flag.String("revokequeue", "", "use with -revokeat or -revokedue to set the file in which queued revocations are stored")
+	fRevokeDue   = flag.Bool("revokedue", false, "revoke the certificates queued in -revokequeue whose revocation time has passed")
+)
+
+// PKCS#12 output flags.
+var (
+	fP12Out  = flag.String("p12out", "", "write the issued or retrieved certificate, the private key from -privatekey and the trust chain to the specified PKCS#12 file")
+	fP12Pass = flag.String("p12pass", "", "use with -p12out to set the PKCS#12 file password (default: prompt)")
+)
+
+// Reconciliation and output format flags.
+var (
+	fReconcile    = flag.String("reconcile", "", "compare the certificates in the specified directory with those issued and revoked during the time window")
+	fExpiryWindow = flag.String("expirywindow", "30d", "use with -reconcile to report deployed certificates expiring within this duration e.g. 24h, 30d")
+	fFormat       = flag.String("format", "", "use with -reconcile to select the report format, csv (default) or json, or with -trustchain to select the output format, pem (default), pemfiles, p7b or der")
+	fOut          = flag.String("out", "", "use with -trustchain to write the output to the specified file, or for the pemfiles and der formats, to files with the specified prefix")
 )
 
 // Account statistics and information flags.
@@ -123,17 +176,30 @@ var (
 	fCertsExpiring = flag.Bool("certsexpiring", false, "list certificates expiring during the time window")
 	fTrustChain    = flag.Bool("trustchain", false, "retrieve chain of trust for issued certificates")
 	fQuota         = flag.Bool("quota", false, "show remaining quota of certificate issuances")
+	fAccount       = flag.Bool("account", false, "show counts, remaining quota, validation policy summary and trust chain fingerprints")
+	fCapabilities  = flag.Bool("capabilities", false, "show which optional HVCA endpoints are available")
 	fPolicy        = flag.Bool("policy", false, "retrieve validation policy")
+	fSelftest      = flag.Bool("selftest", false, "check login, policy, trust chain, counters and quota, and validate any -template against the policy")
+	fLint          = flag.Bool("lint", false, "check the -template against the validation policy and report every problem found, without submitting it")
 )
 
 // Domain claim flags.
 var (
 	fClaims         = flag.Bool("claims", false, "show pending or verified domain claims")
 	fPending        = flag.Bool("pending", false, "use with -claims to show pending rather than verified domain claims")
+	fClaimDomain    = flag.String("claimdomain", "", "use with -claims to show only domain claims for the specified domain")
+	fSubdomains     = flag.Bool("subdomains", false, "use with -claimdomain to also show domain claims for subdomains")
+	fSort           = flag.String("sort", "", "use with -claims to sort domain claims by domain, created, expires or assertby")
+	fDescending     = flag.Bool("desc", false, "use with -sort to sort in descending order")
 	fClaimRetrieve  = flag.String("claimretrieve", "", "retrieve the domain claim with the specified ID")
 	fClaimSubmit    = flag.String("claimsubmit", "", "submit a domain claim for the specified domain")
 	fClaimDelete    = flag.String("claimdelete", "", "delete the domain claim with the specified ID")
+	fClaimsPurge    = flag.String("claimspurge", "", "delete pending domain claims, or with -status those with the specified status, created longer ago than the specified age e.g. 90d")
+	fCanIssue       = flag.String("canissue", "", "check whether certificates may be issued for the specified comma-separated DNS names, according to the validation policy and domain claims")
 	fClaimDNS       = flag.String("claimdns", "", "request assertion of domain control using DNS for the domain claim with the specified ID")
+	fClaimMonitor   = flag.String("claimmonitor", "", "wait until the DNS record for the domain claim with the specified ID is visible locally, then request assertion of domain control using DNS; requires -token")
+	fInterval       = flag.Duration("interval", time.Minute, "use with -claimmonitor or -watch to set the period between checks of the DNS record or the certificate")
+	fMaxAssertions  = flag.Int("maxassertions", 3, "use with -claimmonitor to set the maximum number of times to request assertion of domain control")
 	fClaimHTTP      = flag.String("claimhttp", "", "request assertion of domain control using HTTP for the domain claim with the specified ID")
 	fClaimEmail     = flag.String("claimemail", "", "request assertion of domain control using Email for the domain claim with the specified ID")
 	fClaimEmailList = flag.String("claimemaillist", "", "request list of emails authorised to perform email validation for the domain claims with the specified ID")
@@ -141,4 +207,10 @@ var (
 	fScheme         = flag.String("scheme", "https", "protocol used to verify assertion of domain control using HTTP method for the domain claim")
 	fAuthDomain     = flag.String("authdomain", "", "authorization domain name used to verify assertion of domain control for the domain claim")
 	fClaimReassert  = flag.String("claimreassert", "", "reassert the domain claim with the specified ID")
+	fToken          = flag.String("token", "", "use with -claimdns, -claimhttp or -claimmonitor to check locally that the specified domain claim token is visible before asserting domain control")
+)
+
+// Metrics flags.
+var (
+	fMetrics = flag.String("metrics", "", "use with -claimmonitor or -watch to serve Prometheus metrics at /metrics on the specified address e.g. :9100")
 )
@@ -18,6 +18,7 @@ package main
 import "fmt"
 
 var helpDoc = `Usage: hvclient [options]
+       hvclient <command> [<argument>] [options]
 
 HVClient is a command-line interface to the GlobalSign Atlas Certificate
 Management API (HVCA). 
@@ -26,13 +27,91 @@ Access to HVCA requires an account. At the time of account setup, GlobalSign
 will provide a mutual TLS certificate, an API key, and an API secret which can
 be provided to HVClient via a configuration file.
 
+Commands:
+
+  Each command is equivalent to the option shown, with any argument used as
+  the option's value, e.g. "hvclient claims submit example.com" is equivalent
+  to "hvclient -claimsubmit=example.com".
+
+  request                       Request a certificate using the options below
+  interactive                   -interactive
+  smime                         -smime
+  retrieve|status|updated|history|revoke|rekey <serial>
+                                -retrieve, -status, -updated, -history,
+                                -revoke or -rekey
+  revokedue                     -revokedue
+  trustchain|policy|quota|account|capabilities
+                                -trustchain, -policy, -quota, -account or
+                                -capabilities
+  counters issued|revoked       -countissued or -countrevoked
+  stats issued|revoked|expiring -certsissued, -certsrevoked or -certsexpiring
+  claims list                   -claims
+  claims submit <domain>        -claimsubmit
+  claims purge <age>            -claimspurge
+  claims can-issue <names>      -canissue
+  claims retrieve|delete|dns|monitor|http|email|emaillist|reassert <id>
+                                -claimretrieve, -claimdelete, -claimdns,
+                                -claimmonitor, -claimhttp, -claimemail,
+                                -claimemaillist or -claimreassert
+  reconcile <directory>         -reconcile
+  selftest|lint|configinit|sampletemplate
+                                -selftest, -lint, -configinit or
+                                -sampletemplate
+  config init                   -configinit
+  config store-secret <name>    -storesecret
+  genrsa <bits>                 -genrsa
+  completion bash|zsh|fish      Output a shell completion script
+  help|version                  -h or -v
+
 General options:
 
   -config=<file>        File containing configuration options and HVCA account
-                        credentials. Defaults to $HOME/.hvclient/hvclient.conf.
+                        credentials. Defaults to $HVCLIENT_CONFIG if set, or
+                        else the first of hvclient/hvclient.conf in the
+                        platform configuration directory (%APPDATA% on
+                        Windows, $XDG_CONFIG_HOME or $HOME/.config elsewhere)
+                        and $HOME/.hvclient/hvclient.conf which exists.
+
+  -profile=<name>       Name of the profile to use from the configuration file,
+                        for configuration files containing settings for more
+                        than one HVCA account. Defaults to $HVCLIENT_PROFILE
+                        if set, or else the top level settings in the file.
+
+  -configinit           Prompt for HVCA account details and create a new
+                        configuration file at the path specified with -config,
+                        or in the platform configuration directory. The
+                        "hvclient config init" subcommand is equivalent.
+
+  -storesecret=<name>   Prompt for the named secret, either api_secret or
+                        key_passphrase, and store it in the OS keychain (macOS
+                        Keychain, Windows Credential Manager or the Secret
+                        Service on Linux and BSD) for the API key in the
+                        selected profile. A secret omitted from the
+                        configuration file is retrieved from the keychain.
+
+  -timeout=<duration>   Timeout for each HVCA request, e.g. 30s, 2m, overriding
+                        the timeout in the configuration file.
+
+  -retries=<n>          Maximum number of times to retry an HVCA request which
+                        fails with a temporary error, overriding the default.
+                        Specify 0 to disable retries.
+
+  -debughttp            For any HVCA request which fails, write the HTTP
+                        request and response to standard error, with the
+                        authentication token and API credentials redacted.
+
+  -nocache              Don't cache the validation policy and trust chain in
+                        the hvclient directory in the user cache directory.
+                        When cached, each is transferred again only if HVCA
+                        reports that it has changed.
 
 Certificate request options:
 
+  The issued certificate is written to standard output, and any prompts,
+  warnings and errors to standard error, so the output may be redirected or
+  piped to another tool. Standard input may be read by only one of -template,
+  -publickey, -privatekey and -csr.
+
   Key options:
 
     One (and only one) of -publickey, -privatekey or -csr must be specified to
@@ -44,6 +123,13 @@ Certificate request options:
     -privatekey=<file>  Private key to use for HVCA accounts which require
                         proof-of-possession by signing the public key.
 
+                        Keys for -publickey and -privatekey may be PEM-encoded
+                        or in JSON Web Key (JWK) format.
+
+                        A file of "-" reads the key, or for -csr the CSR, from
+                        standard input, e.g. when piped from another tool. An
+                        encrypted private key cannot be read this way.
+
         -gencsr         Generate a PKCS#10 certificate signing request (CSR)
                         for HVCA accounts which require proof-of-possession
                         with a signed PKCS#10 CSR. Useful when a user has an
@@ -62,13 +148,46 @@ Certificate request options:
                         verifying the contents of a request before submitting
                         it.
 
+    -dryrun             Use with -publickey, -privatekey or -csr to check the
+                        certificate request against the validation policy and
+                        report every problem found, without submitting it to
+                        HVCA or consuming any quota. Exits with a non-zero
+                        status if any problems are found, for use in CI
+                        pipelines.
+
+    -interactive        Retrieve the validation policy and prompt for each
+                        field it allows to be supplied, validating each value
+                        against the policy before submitting the request.
+                        Fields specified with other options are not prompted
+                        for. May be combined with -generate or -csrout.
+
+    -smime              Generate a new private key of a type allowed by the
+                        validation policy, request an S/MIME certificate for
+                        the email address specified with -email, with the
+                        emailProtection extended key usage and any name
+                        specified with -commonname, wait for it to be issued
+                        and write it with the private key and trust chain to
+                        the PKCS#12 file specified with -p12out, for import
+                        into a mail client. The certificate is also output.
+                        Values fixed by the validation policy are filled in.
+
+    -nodefaults         Don't fill in values which are fixed by the
+                        validation policy, such as static subject DN
+                        attributes, SANs and extended key usages, before
+                        submitting the request. By default, such values are
+                        filled in for any fields not otherwise specified,
+                        so only the values which vary need to be given.
+
   Validity period options:
 
     If all of these options are omitted, the request will default to a
     not-before time of the current time, and a not-after time of the maximum
     allowed by the account validation policy. Providing only -duration will
     default to a not-before time of the current time, and a not-after time
-    of the current time plus the specified duration.
+    of the current time plus the specified duration. If the configuration
+    file specifies a default_duration, such as "90d", it is used in place
+    of -duration when neither -notafter nor -duration is provided, limited
+    to the maximum allowed by the account validation policy.
 
     -notbefore=<time>   The time before which the certificate is not valid. The
                         time format is 2016-01-02T15:04:05UTC. Defaults to the
@@ -79,12 +198,17 @@ Certificate request options:
     -duration=<value>   An alternative to -notafter. The not-after time will be
                         calculated at the not-before time plus the specified
                         duration value, which should be in a flexible format
-                        such as 10d, 30days, 24hrs, 8wk, 12w.
+                        such as 10d, 30days, 24hrs, 8wk, 12w, 1y. A year is
+                        365 days.
 
   Certificate attribute value options:
 
     At least one of these options should normally be selected.
 
+    -subject=<string>             Complete subject distinguished name (DN) as
+                                  an RFC 4514 string, for example
+                                  "CN=John Doe,O=ACME,C=GB". Any of the
+                                  options below override its values.
     -commonname=<string>          Subject distinguished name (DN) common name
     -serialnumber=<string>        Subject DN serial number
     -organization=<string>        Subject DN organization
@@ -103,12 +227,41 @@ Certificate request options:
     -extraattributes=<string>     Comma-separated list of subject DN extra
                                   attributes in format OID=value, for example
                                   "2.5.4.4=surname,2.5.4.5=serial_number"
+    -replacesubject               Discard the subject DN in the -template if
+                                  any of the subject options above are
+                                  specified, rather than merging them with it
 
     -dnsnames=<string>            Comma-separated list of subject alternative
                                   Names (SAN) domain names
+    -checkdnsnames                Check the SAN domain names, from -dnsnames or
+                                  a template, before submitting the request.
+                                  Unicode names are converted to punycode, and
+                                  names with trailing dots, misplaced wildcards
+                                  or IP addresses are rejected.
+    -checkcaa=<string>            Check the CAA records of the SAN domain names
+                                  before submitting the request, and either
+                                  "warn" or "fail" if they do not authorize
+                                  GlobalSign to issue certificates
+    -dnsservers=<string>          Comma-separated list of DNS servers used by
+                                  -checkcaa, rather than those in
+                                  /etc/resolv.conf, which must be specified on
+                                  Windows
     -emails=<string>              Comma-separated list of SAN email addresses
+    -checkemails                  Check the SAN email addresses, from -emails
+                                  or a template, before submitting the
+                                  request. Domains are converted to lower case
+                                  and punycode, addresses with display names
+                                  or address literals are rejected, and the
+                                  addresses are checked against the validation
+                                  policy.
     -ips=<string>                 Comma-separated list of SAN IP addresses
     -uris=<string>                Comma-separated list of SAN URIs
+    -spiffeid=<string>            SPIFFE ID to include as the single spiffe://
+                                  SAN URI, replacing any in the -template, e.g.
+                                  "spiffe://example.org/ns/prod/sa/web"
+    -replacesans                  Discard the SANs in the -template if any of
+                                  the SAN options above are specified, rather
+                                  than appending to them
 
     -ekus=<string>                Comma-separated list of extended key usage
                                   OIDs, e.g. "1.3.6.1.5.5.7.3.2"
@@ -122,19 +275,68 @@ Certificate request options:
     -template=<file>              Read values from the specified JSON-encoded
                                   file. Options specified at the command line
                                   override or append to the values in this
-                                  template, as appropriate.
+                                  template, as appropriate. A file of "-"
+                                  reads the template from standard input.
     -sampletemplate               Output an example template which can be
                                   modified and used with the -template option
 
+  PKCS#12 output options:
+
+    -p12out=<file>      After the certificate is issued, also write it to
+                        the specified PKCS#12 file together with the private
+                        key from -privatekey and the trust chain. May also be
+                        used with -rekey and -retrieve.
+    -p12pass=<string>   The password to protect the PKCS#12 file. If not
+                        specified, it is prompted for.
+
 Certificate and account information options:
 
   -retrieve=<serial>    Retrieve the previously-issued certificate with the
                         specified serial number
-  -revoke=<serial>      Revoke the certificate with the specified serial number
+  -revoke=<serial>      Revoke the certificate with the specified serial number.
+                        The path to a PEM-encoded certificate file may be
+                        given instead, in which case the certificate must
+                        have been issued for this account.
+    -reason=<reason>    Used with -revoke, the revocation reason, one of
+                        unspecified (default), keyCompromise,
+                        affiliationChanged, superseded, cessationOfOperation,
+                        certificateHold or privilegeWithdrawn
+    -revokeat=<time>    Used with -revoke and -revokequeue, queue the
+                        revocation until the specified time in
+                        2006-01-02T15:04:05MST layout rather than revoking
+                        the certificate immediately
+  -revokedue            Revoke the certificates queued in -revokequeue whose
+                        revocation time has passed. Run this periodically,
+                        e.g. from cron, to make queued revocations.
+    -revokequeue=<file> The file in which queued revocations are stored
+  -rekey=<serial>       Request a new certificate with the same subject, SANs,
+                        extended key usages and duration as the certificate
+                        with the specified serial number, but with the new key
+                        specified with one of -publickey, -privatekey or -csr.
+                        The existing certificate is not revoked. The new key
+                        must differ from the existing certificate's key and be
+                        allowed by the validation policy.
+  -watch=<file>         Watch the PEM-encoded certificate in the specified
+                        file and, once two thirds of its lifetime has passed,
+                        request a replacement built from the request options,
+                        e.g. -privatekey and -template, and write it to the
+                        file. The certificate is checked every -interval
+                        (default 1m) until interrupted, and -metrics may be
+                        used as with -claimmonitor
   -status=<serial>      Show the issued/revoked status for the certificate with
                         the specified serial number
+    -details            Used with -retrieve or -status, also show the serial
+                        number, the SHA-1 and SHA-256 fingerprints, and the
+                        subject and authority key identifiers of the
+                        certificate, by which inventory systems commonly
+                        identify certificates.
   -updated=<serial>     Show the last-updated time for the certificate with the
                         specified serial number
+  -history=<serial>     Show the known lifecycle events for the certificate
+                        with the specified serial number, in chronological
+                        order. Events are derived from the certificate and its
+                        current status, and a revocation time is approximated
+                        by the last-updated time.
 
   -certsissued          List the certificates issued during a specified time
                         window. See the "List-producing API options" section
@@ -145,6 +347,11 @@ Certificate and account information options:
   -certsexpiring        List the certificates that expired or that will expire
                         during a specified time window. See the "List-producing
                         API options" section below.
+    -details            Used with -certsissued, -certsrevoked or
+                        -certsexpiring, also retrieve each listed certificate
+                        and show its subject common name and its SAN DNS names,
+                        separated by semicolons. Up to -concurrency
+                        certificates are retrieved at the same time.
 
   -countissued          Show the total count of certificates issued by this
                         HVCA account
@@ -152,12 +359,56 @@ Certificate and account information options:
                         HVCA account
   -quota                Show the remaining quota of certificate issuances for
                         this HVCA account
+  -account              Show the counts, the remaining quota, a summary of the
+                        validation policy and the SHA-256 fingerprints of the
+                        trust chain for this HVCA account in JSON format,
+                        retrieved concurrently
+  -capabilities         Show which optional HVCA endpoints, for revocation,
+                        HTTP and email domain validation and timestamping,
+                        are available to this HVCA account in JSON format.
+                        The revocation and HTTP domain validation endpoints
+                        are probed with OPTIONS requests, so nothing is
+                        changed
 
   -trustchain           Show the chain of trust for certificates issued by this
                         HVCA account. The output is one or more PEM-encoded
                         certificates containing the root and any intermediate
                         Certificate Authority certificates.
+
+      -format=<format>  Used with -trustchain, the output format: pem for a
+                        single PEM bundle, pemfiles for a separate PEM file
+                        per certificate, p7b for a DER-encoded PKCS#7 bundle,
+                        or der for a separate DER file per certificate, as
+                        accepted by keytool -importcert. Defaults to pem.
+      -out=<path>       Used with -trustchain, the file to write pem or p7b
+                        output to instead of standard output. For pemfiles
+                        and der, required, and each certificate is written to
+                        <path>-1.pem, <path>-2.pem and so on.
   -policy               Show the validation policy for this HVCA account
+  -selftest             Check that login, the validation policy, the trust
+                        chain, the counters and the quota can all be retrieved,
+                        and output a pass/fail report. If -template is also
+                        specified, the request it contains is validated against
+                        the policy without being submitted. Useful when setting
+                        up new credentials or diagnosing permission problems.
+  -lint                 Check the request in the file specified with -template
+                        against the validation policy without submitting it,
+                        and output a warning for every forbidden field, missing
+                        required field and value which does not match the
+                        policy.
+
+  -reconcile=<dir>      Compare the PEM-encoded certificates deployed in the
+                        specified directory with the certificates issued and
+                        revoked during a specified time window, and report any
+                        certificates issued but not deployed, deployed but
+                        revoked, or deployed and near expiry. The time window
+                        is specified as for the list-producing APIs below.
+
+      -expirywindow=<duration>
+                        Used with -reconcile, report deployed certificates
+                        expiring within this duration. Defaults to 30d.
+      -format=<format>  Used with -reconcile, the report format, either csv
+                        or json. Defaults to csv.
 
 Domain claim options:
 
@@ -166,6 +417,18 @@ Domain claim options:
 
       -pending          Used with -claims, list all pending rather than
                         verified domain claims
+      -status=<status>  Used with -claims, list domain claims with the
+                        specified status, one of verified, pending or all
+      -claimdomain=<domain>
+                        Used with -claims, list only domain claims for the
+                        specified domain. Every page of claims is retrieved,
+                        since HVCA cannot filter claims by domain.
+        -subdomains     Used with -claimdomain, also list domain claims for
+                        subdomains of the specified domain
+      -sort=<field>     Used with -claims, sort domain claims by domain,
+                        created, expires or assertby. Every page of claims is
+                        retrieved.
+        -desc           Used with -sort, sort in descending order
 
   -claimsubmit=<domain> Submit a new domain claim
   -claimretrieve=<id>   Show the details of the domain claim with the specified
@@ -173,8 +436,40 @@ Domain claim options:
   -claimreassert=<id>   Reassert an existing domain claim, for example when the
                         assert-by time of the existing claim has passed
   -claimdelete=<id>     Delete the domain claim with the specified ID
+  -claimspurge=<age>    Delete the pending domain claims created longer ago
+                        than the specified age, e.g. 90d, and list the claims
+                        deleted
+      -status=<status>  Used with -claimspurge, delete domain claims with the
+                        specified status, one of verified, pending or all,
+                        rather than pending claims
+      -dryrun           Used with -claimspurge, list the domain claims which
+                        would be deleted without deleting them
+  -canissue=<names>     Check whether certificates may be issued for the
+                        specified comma-separated DNS names, which may include
+                        wildcards, according to the validation policy and the
+                        domain claims. For each name, show the matching
+                        claimed domain, and whether it is issuable or why not.
+                        Names covered by a wildcard also specified are noted.
   -claimdns=<id>        Request assertion of domain control using DNS for the
                         claim with the specified ID
+  -claimmonitor=<id>    Check the DNS record for the claim with the specified ID
+                        at intervals, and request assertion of domain control
+                        using DNS only once the record is visible locally, so
+                        that assertions are not wasted while DNS changes
+                        propagate. Requires -token
+      -interval=<duration>
+                        Used with -claimmonitor, the period between checks of
+                        the DNS record (default 1m)
+      -maxassertions=<n>
+                        Used with -claimmonitor, the maximum number of times
+                        to request assertion of domain control (default 3)
+      -metrics=<address>
+                        Used with -claimmonitor or -watch, serve Prometheus
+                        metrics counting HVCA API calls and errors by method
+                        and endpoint and certificate renewals, and reporting
+                        the number of watched certificates and the remaining
+                        issuance quota, refreshed every -interval, at
+                        /metrics on the specified address, e.g. :9100
   -claimhttp=<id>       Request assertion of domain control using HTTP for the
                         claim with the specified ID
       -scheme=<scheme>  Used with -claimhttp, specifies the protocol used to verify assertion of domain control
@@ -183,6 +478,10 @@ Domain claim options:
       -address=<email>  Used with -claimemail, specifies the email address to send the verification email to verify assertion of domain control to.
   -claimemaillist=<id>  Get a list of emails authorized to perform email validation for the claim with the specified ID
   -authdomain=<authdomain> Used with -claimhttp and -claimsdns, specifies the authorization domain used to verify assertion of domain control
+  -token=<token>        Used with -claimdns, -claimhttp and -claimmonitor,
+                        checks locally that the specified claim token is
+                        visible in DNS or over HTTP before requesting
+                        assertion of domain control
 
 List-producing API options:
 
@@ -195,10 +494,12 @@ List-producing API options:
   The following options control the pagination:
 
   -from=<time>          The beginning of the time window, with a time format of
-                        2016-01-02T15:04:05UTC. Defaults to 30 days prior to
-                        the current time.
-  -to=<time>            The end of the time window, with a time format of
-                        2016-01-02T15:04:05UTC. Defaults to the current time.
+                        2016-01-02T15:04:05UTC, a date such as 2016-01-02 in
+                        UTC, or a duration relative to the current time with
+                        a leading sign such as -30d or +1w. Defaults to 30
+                        days prior to the current time.
+  -to=<time>            The end of the time window, in the same formats as
+                        for -from. Defaults to the current time.
   -since=<duration>     Used instead of -from and -to, this signifies a time
                         window from the specified duration in the past through
                         to the current time. The format is the same as for the
@@ -208,6 +509,16 @@ List-producing API options:
   -pagesize=<int>       The number of items per page. Defaults to 100.
   -totalcount           Show the total count of items in the population instead
                         of listing them.
+  -allpages             List every item in the population, retrieving as many
+                        pages as necessary. -page and -pagesize are ignored.
+                        Note that the -timeout applies to the whole listing.
+      -concurrency=<int>
+                        Used with -allpages, the maximum number of pages to
+                        retrieve concurrently, or with -details, the maximum
+                        number of certificates to retrieve concurrently. Items
+                        are listed in the same order regardless. Defaults to 1.
+      -progress         Used with -allpages, show the number of pages
+                        retrieved so far on standard error.
 
 Convenience options:
 
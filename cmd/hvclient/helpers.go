@@ -19,11 +19,14 @@ import (
 	"encoding/asn1"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/url"
 	"os"
 	"strings"
 	"syscall"
+	"time"
 
 	"golang.org/x/crypto/ssh/terminal"
 
@@ -89,6 +92,48 @@ func checkAllEmpty(s ...string) bool {
 	return true
 }
 
+// stdinName is the file name which selects standard input in place of a
+// file for the -template, -publickey, -privatekey and -csr options.
+const stdinName = "-"
+
+// readInput reads the contents of the named file, or all of stdin if the
+// name is stdinName.
+func readInput(name string, stdin io.Reader) ([]byte, error) {
+	if name == stdinName {
+		var data, err = ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read standard input: %v", err)
+		}
+
+		return data, nil
+	}
+
+	return ioutil.ReadFile(name)
+}
+
+// checkStdinUse returns an error if standard input is selected in place of
+// a file by more than one of the provided names, since it can be read only
+// once, or by any of them if standard input is also needed for prompts.
+func checkStdinUse(prompting bool, names ...string) error {
+	var count int
+
+	for _, name := range names {
+		if name == stdinName {
+			count++
+		}
+	}
+
+	switch {
+	case count > 1:
+		return errors.New("standard input may be read by only one of -template, -publickey, -privatekey and -csr")
+
+	case count > 0 && prompting:
+		return errors.New("standard input cannot be read by -template, -publickey, -privatekey or -csr when prompting for values")
+	}
+
+	return nil
+}
+
 // stringToOIDs converts a comma-separated list of string representations
 // of OIDs to a slice of asn1.ObjectIdentifier objects.
 func stringToOIDs(s string) ([]asn1.ObjectIdentifier, error) {
@@ -186,3 +231,27 @@ func stringToOIDAndStrings(s string) ([]hvclient.OIDAndString, error) {
 
 	return result, nil
 }
+
+// applyConfigOverrides overrides the timeout and number of retries in the
+// configuration with the values specified at the command line. A zero
+// timeout or a negative number of retries leaves the configured value
+// unchanged.
+func applyConfigOverrides(conf *hvclient.Config, timeout time.Duration, retries int) error {
+	if timeout < 0 {
+		return errors.New("timeout cannot be negative")
+	} else if timeout > 0 {
+		conf.Timeout = timeout
+	}
+
+	switch {
+	case retries == 0:
+		// A negative value in the configuration disables retries, since zero
+		// selects the default.
+		conf.Retries = -1
+
+	case retries > 0:
+		conf.Retries = retries
+	}
+
+	return nil
+}
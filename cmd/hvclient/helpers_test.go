@@ -16,11 +16,13 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"encoding/asn1"
 	"fmt"
 	"net"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/globalsign/hvclient"
 	"github.com/globalsign/hvclient/internal/testhelpers"
@@ -115,6 +117,68 @@ func TestCheckAllEmpty(t *testing.T) {
 	}
 }
 
+func TestReadInput(t *testing.T) {
+	t.Parallel()
+
+	var want = testhelpers.MustReadFile(t, "testdata/ec_pub.key")
+
+	for _, tc := range []struct {
+		name  string
+		value string
+	}{
+		{"File", "testdata/ec_pub.key"},
+		{"Stdin", "-"},
+	} {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = readInput(tc.value, bytes.NewReader(want))
+			if err != nil {
+				t.Fatalf("couldn't read input: %v", err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+
+	if _, err := readInput("no_such_file", bytes.NewReader(want)); err == nil {
+		t.Errorf("unexpectedly read missing file")
+	}
+}
+
+func TestCheckStdinUse(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name      string
+		prompting bool
+		values    []string
+		err       bool
+	}{
+		{"None", false, []string{"", "key.pem", ""}, false},
+		{"One", false, []string{"", "-", ""}, false},
+		{"PromptingWithoutStdin", true, []string{"base.tmpl", "", ""}, false},
+		{"Two", false, []string{"-", "-", ""}, true},
+		{"Prompting", true, []string{"-", "", ""}, true},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := checkStdinUse(tc.prompting, tc.values...); (err != nil) != tc.err {
+				t.Errorf("got error %v, want error %t", err, tc.err)
+			}
+		})
+	}
+}
+
 func TestStringToOIDs(t *testing.T) {
 	t.Parallel()
 
@@ -372,3 +436,67 @@ func TestStringToOIDAndStringsFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyConfigOverrides(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name    string
+		timeout time.Duration
+		retries int
+		want    hvclient.Config
+		err     bool
+	}{
+		{
+			name:    "None",
+			retries: -1,
+			want:    hvclient.Config{Timeout: time.Minute, Retries: 5},
+		},
+		{
+			name:    "Timeout",
+			timeout: time.Second * 90,
+			retries: -1,
+			want:    hvclient.Config{Timeout: time.Second * 90, Retries: 5},
+		},
+		{
+			name:    "Retries",
+			retries: 10,
+			want:    hvclient.Config{Timeout: time.Minute, Retries: 10},
+		},
+		{
+			name:    "NoRetries",
+			retries: 0,
+			want:    hvclient.Config{Timeout: time.Minute, Retries: -1},
+		},
+		{
+			name:    "NegativeTimeout",
+			timeout: -time.Second,
+			retries: -1,
+			err:     true,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got = hvclient.Config{Timeout: time.Minute, Retries: 5}
+
+			var err = applyConfigOverrides(&got, tc.timeout, tc.retries)
+			if (err != nil) != tc.err {
+				t.Fatalf("got error %v, want error %t", err, tc.err)
+			}
+
+			if tc.err {
+				return
+			}
+
+			if got.Timeout != tc.want.Timeout || got.Retries != tc.want.Retries {
+				t.Errorf("got timeout %v and retries %d, want %v and %d",
+					got.Timeout, got.Retries, tc.want.Timeout, tc.want.Retries)
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/globalsign/hvclient"
+)
+
+// prompter reads responses to interactive prompts.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// newPrompter returns a new prompter which reads responses from in and
+// writes prompts to out.
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+// readLine outputs a prompt and reads a single line of input, with leading
+// and trailing whitespace removed.
+func (p *prompter) readLine(prompt string) (string, error) {
+	fmt.Fprintf(p.out, "%s: ", prompt)
+
+	var line, err = p.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("couldn't read input: %w", err)
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+// readLineDefault prompts for a single line of input, returning the default
+// value if the input is empty.
+func (p *prompter) readLineDefault(prompt, def string) (string, error) {
+	var value, err = p.readLine(fmt.Sprintf("%s (default: %s)", prompt, def))
+	if err != nil {
+		return "", err
+	}
+
+	if value == "" {
+		return def, nil
+	}
+
+	return value, nil
+}
+
+// readLineRequired prompts for a single line of input until a non-empty
+// value is provided.
+func (p *prompter) readLineRequired(prompt string) (string, error) {
+	for {
+		var value, err = p.readLine(prompt + " (required)")
+		if err != nil || value != "" {
+			return value, err
+		}
+
+		fmt.Fprintf(p.out, "A value is required.\n")
+	}
+}
+
+// askString prompts for a single value constrained by a string policy. An
+// empty string is returned without prompting if the policy does not allow
+// the value to be supplied.
+func (p *prompter) askString(label string, pol *hvclient.StringPolicy) (string, error) {
+	if pol == nil || (pol.Presence != hvclient.Required && pol.Presence != hvclient.Optional) {
+		return "", nil
+	}
+
+	var re *regexp.Regexp
+	if pol.Format != "" {
+		var err error
+		if re, err = regexp.Compile(pol.Format); err != nil {
+			return "", fmt.Errorf("invalid format %q in policy for %s: %v", pol.Format, label, err)
+		}
+	}
+
+	for {
+		var value, err = p.readLine(fmt.Sprintf("%s (%s)", label, strings.ToLower(pol.Presence.String())))
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case value == "" && pol.Presence == hvclient.Optional:
+			return "", nil
+
+		case value == "":
+			fmt.Fprintf(p.out, "A value is required.\n")
+
+		case re != nil && !re.MatchString(value):
+			fmt.Fprintf(p.out, "Value must match the format %q.\n", pol.Format)
+
+		default:
+			return value, nil
+		}
+	}
+}
+
+// askList prompts for a comma-separated list of values constrained by a list
+// policy, and returns them as a comma-separated string. An empty string is
+// returned without prompting if the policy does not allow values to be
+// supplied.
+func (p *prompter) askList(label string, pol *hvclient.ListPolicy) (string, error) {
+	if pol == nil || pol.Static || pol.MaxCount == 0 {
+		return "", nil
+	}
+
+	var res = make([]*regexp.Regexp, 0, len(pol.List))
+	for _, format := range pol.List {
+		var re, err = regexp.Compile(format)
+		if err != nil {
+			return "", fmt.Errorf("invalid format %q in policy for %s: %v", format, label, err)
+		}
+
+		res = append(res, re)
+	}
+
+	var presence = "optional"
+	if pol.MinCount > 0 {
+		presence = "required"
+	}
+
+	for {
+		var line, err = p.readLine(fmt.Sprintf("%s, comma-separated (%s)", label, presence))
+		if err != nil {
+			return "", err
+		}
+
+		var values []string
+		if line != "" {
+			for _, value := range strings.Split(line, ",") {
+				values = append(values, strings.TrimSpace(value))
+			}
+		}
+
+		if len(values) < pol.MinCount || (pol.MaxCount > 0 && len(values) > pol.MaxCount) {
+			fmt.Fprintf(p.out, "Between %d and %d values must be provided.\n", pol.MinCount, pol.MaxCount)
+			continue
+		}
+
+		if bad := firstUnmatched(values, res); bad != "" {
+			fmt.Fprintf(p.out, "Value %q does not match any of the allowed formats %q.\n", bad, pol.List)
+			continue
+		}
+
+		return strings.Join(values, ","), nil
+	}
+}
+
+// askYesNo prompts for a yes or no answer, and returns true if the answer
+// was yes.
+func (p *prompter) askYesNo(prompt string) (bool, error) {
+	var answer, err = p.readLine(prompt + " [y/N]")
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// firstUnmatched returns the first value which does not match any of the
+// regular expressions, or the empty string if all values match or if there
+// are no regular expressions.
+func firstUnmatched(values []string, res []*regexp.Regexp) string {
+	if len(res) == 0 {
+		return ""
+	}
+
+outer:
+	for _, value := range values {
+		if value == "" {
+			return value
+		}
+
+		for _, re := range res {
+			if re.MatchString(value) {
+				continue outer
+			}
+		}
+
+		return value
+	}
+
+	return ""
+}
+
+// promptRequestValues populates the request values by prompting the user
+// for each field which the validation policy allows to be supplied.
+func promptRequestValues(p *prompter, pol *hvclient.Policy, values *requestValues) error {
+	var err error
+
+	// Fields already specified at the command line are not prompted for.
+	if pol.Validity != nil && checkAllEmpty(values.validity.notBefore, values.validity.notAfter, values.validity.duration) {
+		var def = "maximum allowed by policy"
+		if values.validity.defaultDuration != "" {
+			def = values.validity.defaultDuration
+		}
+
+		for {
+			if values.validity.duration, err = p.readLine("Certificate duration e.g. 24h, 30d (optional, default: " + def + ")"); err != nil {
+				return err
+			}
+
+			if values.validity.duration == "" {
+				break
+			}
+
+			if _, err = hvclient.ParseDuration(values.validity.duration); err == nil {
+				break
+			}
+
+			fmt.Fprintf(p.out, "Invalid duration: %v.\n", err)
+		}
+	}
+
+	// A complete subject DN specified at the command line is used as is.
+	if dn := pol.SubjectDN; dn != nil && values.subject.subject == "" {
+		for _, field := range []struct {
+			label string
+			pol   *hvclient.StringPolicy
+			to    *string
+		}{
+			{"Subject common name", dn.CommonName, &values.subject.commonName},
+			{"Subject serial number", dn.SerialNumber, &values.subject.serialNumber},
+			{"Subject organization", dn.Organization, &values.subject.organization},
+			{"Subject street address", dn.StreetAddress, &values.subject.streetAddress},
+			{"Subject locality", dn.Locality, &values.subject.locality},
+			{"Subject state", dn.State, &values.subject.state},
+			{"Subject country", dn.Country, &values.subject.country},
+			{"Subject email address", dn.Email, &values.subject.email},
+			{"Subject jurisdiction locality", dn.JOILocality, &values.subject.joiLocality},
+			{"Subject jurisdiction state or province", dn.JOIState, &values.subject.joiState},
+			{"Subject jurisdiction country", dn.JOICountry, &values.subject.joiCountry},
+			{"Subject business category", dn.BusinessCategory, &values.subject.businessCategory},
+		} {
+			if *field.to != "" {
+				continue
+			}
+
+			if *field.to, err = p.askString(field.label, field.pol); err != nil {
+				return err
+			}
+		}
+
+		if values.subject.organizationalUnit == "" {
+			if values.subject.organizationalUnit, err = p.askList("Subject organizational units", dn.OrganizationalUnit); err != nil {
+				return err
+			}
+		}
+	}
+
+	if san := pol.SAN; san != nil {
+		for _, field := range []struct {
+			label string
+			pol   *hvclient.ListPolicy
+			to    *string
+		}{
+			{"SAN DNS names", san.DNSNames, &values.san.dnsNames},
+			{"SAN email addresses", san.Emails, &values.san.emails},
+			{"SAN IP addresses", san.IPAddresses, &values.san.ips},
+			{"SAN URIs", san.URIs, &values.san.uris},
+		} {
+			if *field.to != "" {
+				continue
+			}
+
+			if *field.to, err = p.askList(field.label, field.pol); err != nil {
+				return err
+			}
+		}
+	}
+
+	if pol.EKUs != nil && values.ekus == "" {
+		if values.ekus, err = p.askList("Extended key usage OIDs", &pol.EKUs.EKUs); err != nil {
+			return err
+		}
+	}
+
+	// Only prompt for a key if none was specified at the command line.
+	if checkAllEmpty(values.publickey, values.privatekey, values.csr) {
+		for values.privatekey == "" {
+			if values.privatekey, err = p.readLine("Path to private key (required)"); err != nil {
+				return err
+			}
+		}
+
+		if pol.PublicKey != nil && pol.PublicKey.KeyFormat == hvclient.PKCS10 {
+			values.gencsr = true
+		}
+	}
+
+	return nil
+}
+
+// interactiveRequest fetches the validation policy, prompts the user for
+// the values allowed by it, and then requests a certificate.
+func interactiveRequest(clnt *hvclient.Client) error {
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var pol, err = clnt.Policy(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't retrieve validation policy: %v", err)
+	}
+
+	var values = requestValuesFromFlags()
+	values.validity.maxDuration = policyMaxDuration(pol)
+
+	var p = newPrompter(os.Stdin, os.Stderr)
+
+	if err = promptRequestValues(p, pol, values); err != nil {
+		return err
+	}
+
+	var request *hvclient.Request
+	if request, err = buildRequest(values); err != nil {
+		return err
+	}
+
+	if !*fNoDefaults {
+		if err = request.ApplyPolicyDefaults(pol); err != nil {
+			return fmt.Errorf("couldn't apply validation policy defaults: %v", err)
+		}
+	}
+
+	// Only ask for confirmation if we're actually going to make the request.
+	if !*fGenerate && !*fCSROut && !*fDryRun {
+		var proceed bool
+		if proceed, err = p.askYesNo("Submit certificate request"); err != nil {
+			return err
+		} else if !proceed {
+			return errors.New("certificate request cancelled")
+		}
+	}
+
+	return submitRequest(clnt, request)
+}
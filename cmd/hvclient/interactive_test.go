@@ -0,0 +1,232 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+)
+
+var testInteractivePolicy = hvclient.Policy{
+	Validity: &hvclient.ValidityPolicy{
+		SecondsMin: 3600,
+		SecondsMax: 86400 * 90,
+	},
+	SubjectDN: &hvclient.SubjectDNPolicy{
+		CommonName: &hvclient.StringPolicy{
+			Presence: hvclient.Required,
+			Format:   "^[A-Za-z ]+$",
+		},
+		Organization: &hvclient.StringPolicy{
+			Presence: hvclient.Optional,
+		},
+		Country: &hvclient.StringPolicy{
+			Presence: hvclient.Static,
+			Format:   "GB",
+		},
+		OrganizationalUnit: &hvclient.ListPolicy{
+			List:     []string{"^.*$"},
+			MinCount: 0,
+			MaxCount: 2,
+		},
+	},
+	SAN: &hvclient.SANPolicy{
+		DNSNames: &hvclient.ListPolicy{
+			List:     []string{`^.*\.acme\.com$`},
+			MinCount: 1,
+			MaxCount: 3,
+		},
+	},
+	PublicKey: &hvclient.PublicKeyPolicy{
+		KeyType:   hvclient.RSA,
+		KeyFormat: hvclient.PKCS10,
+	},
+}
+
+func TestPromptRequestValues(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name   string
+		input  string
+		values requestValues
+		want   requestValues
+	}{
+		{
+			name: "Valid",
+			input: strings.Join([]string{
+				"30d",
+				"John Doe",
+				"ACME Inc",
+				"Sales, Marketing",
+				"www.acme.com,mail.acme.com",
+				"testdata/rsa_priv.key",
+			}, "\n"),
+			want: requestValues{
+				validity: validityValues{duration: "30d"},
+				subject: subjectValues{
+					commonName:         "John Doe",
+					organization:       "ACME Inc",
+					organizationalUnit: "Sales,Marketing",
+				},
+				san:        sanValues{dnsNames: "www.acme.com,mail.acme.com"},
+				privatekey: "testdata/rsa_priv.key",
+				gencsr:     true,
+			},
+		},
+		{
+			name: "Reprompt",
+			input: strings.Join([]string{
+				"not a duration",
+				"",
+				"",
+				"John Doe 3rd",
+				"John Doe",
+				"",
+				"A,B,C",
+				"",
+				"",
+				"www.example.com",
+				"www.acme.com",
+				"",
+				"testdata/rsa_priv.key",
+			}, "\n"),
+			want: requestValues{
+				subject: subjectValues{
+					commonName: "John Doe",
+				},
+				san:        sanValues{dnsNames: "www.acme.com"},
+				privatekey: "testdata/rsa_priv.key",
+				gencsr:     true,
+			},
+		},
+		{
+			name:  "FromFlags",
+			input: "ACME Inc\n\n",
+			values: requestValues{
+				validity:  validityValues{notBefore: "2021-01-01T00:00:00UTC"},
+				subject:   subjectValues{commonName: "Jane Doe"},
+				san:       sanValues{dnsNames: "www.acme.com"},
+				publickey: "testdata/rsa_pub.key",
+			},
+			want: requestValues{
+				validity: validityValues{notBefore: "2021-01-01T00:00:00UTC"},
+				subject: subjectValues{
+					commonName:   "Jane Doe",
+					organization: "ACME Inc",
+				},
+				san:       sanValues{dnsNames: "www.acme.com"},
+				publickey: "testdata/rsa_pub.key",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var p = newPrompter(strings.NewReader(tc.input), ioutil.Discard)
+
+			var got = tc.values
+			if err := promptRequestValues(p, &testInteractivePolicy, &got); err != nil {
+				t.Fatalf("couldn't prompt for request values: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPromptRequestValuesFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name   string
+		input  string
+		policy hvclient.Policy
+	}{
+		{
+			name:   "EOF",
+			input:  "30d\n",
+			policy: testInteractivePolicy,
+		},
+		{
+			name:  "BadFormat",
+			input: "John Doe\n",
+			policy: hvclient.Policy{
+				SubjectDN: &hvclient.SubjectDNPolicy{
+					CommonName: &hvclient.StringPolicy{
+						Presence: hvclient.Required,
+						Format:   "^[A-Z",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var p = newPrompter(strings.NewReader(tc.input), ioutil.Discard)
+
+			if err := promptRequestValues(p, &tc.policy, &requestValues{}); err == nil {
+				t.Fatalf("unexpectedly prompted for request values")
+			}
+		})
+	}
+}
+
+func TestPrompterAskYesNo(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"maybe\n", false},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(strings.TrimSpace(tc.input), func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = newPrompter(strings.NewReader(tc.input), ioutil.Discard).askYesNo("Proceed")
+			if err != nil {
+				t.Fatalf("couldn't ask question: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
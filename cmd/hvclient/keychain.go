@@ -0,0 +1,149 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/config"
+	"github.com/globalsign/hvclient/internal/keychain"
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+const (
+	keychainService     = "hvclient"
+	secretAPISecret     = "api_secret"
+	secretKeyPassphrase = "key_passphrase"
+)
+
+// keychainAccount returns the keychain account under which the named secret
+// is stored. Secrets are stored per API key, so that different profiles in
+// the same configuration file may use different secrets.
+func keychainAccount(name, apiKey string) string {
+	return name + ":" + apiKey
+}
+
+// readConfigFile reads the named profile from a configuration file.
+func readConfigFile(configFile, profile string) (*config.Config, error) {
+	var conf, err = config.NewFromFileProfile(configFile, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf == nil {
+		return nil, fmt.Errorf("no configuration in %s", configFile)
+	}
+
+	return conf, nil
+}
+
+// clientConfig converts settings read from a configuration file into a
+// client configuration. If the settings do not contain the API secret, or
+// the passphrase for an encrypted mTLS private key, it is retrieved from the
+// OS keychain with the get function. The settings are not modified.
+func clientConfig(
+	fileConf *config.Config,
+	get func(service, account string) (string, error),
+) (*hvclient.Config, error) {
+	var conf = *fileConf
+	var err error
+
+	if conf.APISecret == "" {
+		if conf.APISecret, err = keychainSecret(get, secretAPISecret, conf.APIKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.KeyFile != "" && conf.KeyPassphrase == "" && pki.FileIsEncryptedPEMBlock(conf.KeyFile) {
+		if conf.KeyPassphrase, err = keychainSecret(get, secretKeyPassphrase, conf.APIKey); err != nil {
+			return nil, err
+		}
+	}
+
+	// The public configuration type reads its keys and validates itself
+	// when unmarshaled from the file format.
+	conf.Profiles = nil
+
+	var data []byte
+	if data, err = json.Marshal(conf); err != nil {
+		return nil, err
+	}
+
+	var newconf *hvclient.Config
+	if err = json.Unmarshal(data, &newconf); err != nil {
+		return nil, err
+	}
+
+	return newconf, nil
+}
+
+// keychainSecret retrieves the named secret from the OS keychain. An empty
+// string is returned if the secret is not stored or the platform has no
+// supported keychain, in which case the configuration will fail validation
+// in the usual way.
+func keychainSecret(get func(service, account string) (string, error), name, apiKey string) (string, error) {
+	var secret, err = get(keychainService, keychainAccount(name, apiKey))
+	if errors.Is(err, keychain.ErrNotFound) || errors.Is(err, keychain.ErrUnsupported) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("couldn't retrieve %s from keychain: %w", name, err)
+	}
+
+	return secret, nil
+}
+
+// storeSecret prompts for the named secret and stores it in the OS keychain
+// with the set function, for use with the API key in the named profile of a
+// configuration file.
+func storeSecret(
+	configFile, profile, name string,
+	secret func(prompt string) (string, error),
+	set func(service, account, secret string) error,
+) error {
+	if name != secretAPISecret && name != secretKeyPassphrase {
+		return fmt.Errorf("unknown secret %q; must be %s or %s", name, secretAPISecret, secretKeyPassphrase)
+	}
+
+	var conf, err = readConfigFile(configFile, profile)
+	if err != nil {
+		return err
+	}
+
+	if conf.APIKey == "" {
+		return fmt.Errorf("no API key in %s", configFile)
+	}
+
+	var value string
+	if value, err = secret(fmt.Sprintf("Enter %s for API key %s", name, conf.APIKey)); err != nil {
+		return err
+	}
+
+	if value == "" {
+		return fmt.Errorf("%s cannot be empty", name)
+	}
+
+	if err = set(keychainService, keychainAccount(name, conf.APIKey), value); err != nil {
+		return fmt.Errorf("couldn't store %s in keychain: %w", name, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Stored %s in keychain. Remove it from %s to use the stored value.\n", name, configFile)
+
+	return nil
+}
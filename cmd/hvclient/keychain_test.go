@@ -0,0 +1,195 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/keychain"
+)
+
+// fakeKeychain is an in-memory keychain.
+type fakeKeychain map[string]string
+
+func (k fakeKeychain) get(service, account string) (string, error) {
+	var secret, ok = k[service+"/"+account]
+	if !ok {
+		return "", keychain.ErrNotFound
+	}
+
+	return secret, nil
+}
+
+func (k fakeKeychain) set(service, account, secret string) error {
+	k[service+"/"+account] = secret
+
+	return nil
+}
+
+func writeTestConfig(t *testing.T, contents string) string {
+	t.Helper()
+
+	var path = filepath.Join(t.TempDir(), "hvclient.conf")
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatalf("couldn't write configuration file: %v", err)
+	}
+
+	return path
+}
+
+func TestClientConfigKeychain(t *testing.T) {
+	t.Parallel()
+
+	var path = writeTestConfig(t, `{
+		"url": "https://example.com/v2",
+		"api_key": "file_key",
+		"cert_file": "testdata/cert.pem",
+		"key_file": "testdata/rsa_priv_enc.key",
+		"profiles": {
+			"plaintext": {
+				"api_secret": "file_secret",
+				"key_passphrase": "strongpassword"
+			}
+		}
+	}`)
+
+	var testcases = []struct {
+		name       string
+		profile    string
+		keys       fakeKeychain
+		wantSecret string
+		wantErr    string
+	}{
+		{
+			name: "Keychain",
+			keys: fakeKeychain{
+				"hvclient/api_secret:file_key":     "keychain_secret",
+				"hvclient/key_passphrase:file_key": "strongpassword",
+			},
+			wantSecret: "keychain_secret",
+		},
+		{
+			name:       "FileTakesPrecedence",
+			profile:    "plaintext",
+			keys:       fakeKeychain{"hvclient/api_secret:file_key": "keychain_secret"},
+			wantSecret: "file_secret",
+		},
+		{
+			name:    "NoSecret",
+			keys:    fakeKeychain{"hvclient/key_passphrase:file_key": "strongpassword"},
+			wantErr: "no API secret",
+		},
+		{
+			name:    "NoPassphrase",
+			keys:    fakeKeychain{"hvclient/api_secret:file_key": "keychain_secret"},
+			wantErr: "mTLS private key",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var fileConf, err = readConfigFile(path, tc.profile)
+			if err != nil {
+				t.Fatalf("couldn't read configuration file: %v", err)
+			}
+
+			var conf *hvclient.Config
+			conf, err = clientConfig(fileConf, tc.keys.get)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("couldn't load configuration: %v", err)
+			}
+
+			if conf.APISecret != tc.wantSecret {
+				t.Errorf("got API secret %q, want %q", conf.APISecret, tc.wantSecret)
+			}
+
+			if conf.TLSKey == nil || conf.TLSCert == nil {
+				t.Errorf("mTLS key and certificate not loaded")
+			}
+		})
+	}
+}
+
+func TestClientConfigKeychainFailure(t *testing.T) {
+	t.Parallel()
+
+	var path = writeTestConfig(t, `{"url": "https://example.com/v2", "api_key": "file_key"}`)
+	var errKeychain = errors.New("keychain locked")
+
+	var fileConf, err = readConfigFile(path, "")
+	if err != nil {
+		t.Fatalf("couldn't read configuration file: %v", err)
+	}
+
+	_, err = clientConfig(fileConf, func(string, string) (string, error) {
+		return "", errKeychain
+	})
+	if !errors.Is(err, errKeychain) {
+		t.Errorf("got error %v, want %v", err, errKeychain)
+	}
+}
+
+func TestStoreSecret(t *testing.T) {
+	t.Parallel()
+
+	var path = writeTestConfig(t, `{
+		"api_key": "top_key",
+		"profiles": {"staging": {"api_key": "staging_key"}}
+	}`)
+
+	var secret = func(string) (string, error) { return "s3cret", nil }
+	var keys = fakeKeychain{}
+
+	if err := storeSecret(path, "staging", "api_secret", secret, keys.set); err != nil {
+		t.Fatalf("couldn't store secret: %v", err)
+	}
+
+	if err := storeSecret(path, "", "key_passphrase", secret, keys.set); err != nil {
+		t.Fatalf("couldn't store secret: %v", err)
+	}
+
+	for _, account := range []string{"api_secret:staging_key", "key_passphrase:top_key"} {
+		if got, err := keys.get("hvclient", account); err != nil || got != "s3cret" {
+			t.Errorf("got %q, %v for account %s, want %q", got, err, account, "s3cret")
+		}
+	}
+
+	if err := storeSecret(path, "", "api_key", secret, keys.set); err == nil {
+		t.Errorf("unexpectedly stored unknown secret")
+	}
+
+	var empty = func(string) (string, error) { return "", nil }
+	if err := storeSecret(path, "", "api_secret", empty, keys.set); err == nil {
+		t.Errorf("unexpectedly stored empty secret")
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/globalsign/hvclient"
+)
+
+// lintTemplate fetches the validation policy and checks the request in the
+// specified template file against it, outputting a warning for each problem
+// found. Nothing is submitted to HVCA. An error is returned if any problems
+// were found.
+func lintTemplate(clnt *hvclient.Client, template string) error {
+	if template == "" {
+		return errors.New("no -template specified")
+	}
+
+	var request, err = getRequestFromTemplateOrNew(template)
+	if err != nil {
+		return err
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var pol *hvclient.Policy
+	if pol, err = clnt.Policy(ctx); err != nil {
+		return fmt.Errorf("couldn't retrieve validation policy: %v", err)
+	}
+
+	return writeLintReport(os.Stdout, template, pol.LintTemplate(request))
+}
+
+// dryRunRequest checks whether a certificate request would be accepted,
+// without submitting it to HVCA, outputting a warning for each problem
+// found. An error is returned if any problems were found.
+func dryRunRequest(clnt *hvclient.Client, request *hvclient.Request) error {
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var issues, err = clnt.CertificateRequestDryRun(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	return writeLintReport(os.Stdout, "request", issues)
+}
+
+// writeLintReport writes a warning for each lint issue found in a template,
+// and returns an error if there were any.
+func writeLintReport(w io.Writer, template string, issues []hvclient.LintIssue) error {
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s: warning: %s: %s\n", template, issue.Field, issue.Message)
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("%d problems found in %s", len(issues), template)
+	}
+
+	return nil
+}
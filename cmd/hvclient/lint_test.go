@@ -0,0 +1,66 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+)
+
+func TestWriteLintReport(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name   string
+		issues []hvclient.LintIssue
+		want   string
+		err    bool
+	}{
+		{
+			name: "Clean",
+		},
+		{
+			name: "Issues",
+			issues: []hvclient.LintIssue{
+				{Field: "subject_dn.common_name", Message: "invalid subject common name: value is required"},
+				{Field: "san.emails", Message: "invalid SAN email addresses: values not allowed by policy"},
+			},
+			want: "req.tmpl: warning: subject_dn.common_name: invalid subject common name: value is required\n" +
+				"req.tmpl: warning: san.emails: invalid SAN email addresses: values not allowed by policy\n",
+			err: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			var err = writeLintReport(&buf, "req.tmpl", tc.issues)
+			if (err != nil) != tc.err {
+				t.Fatalf("got error %v, want error %t", err, tc.err)
+			}
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -20,10 +20,14 @@ import (
 	"flag"
 	"log"
 	"os"
-	"path"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/config"
+	"github.com/globalsign/hvclient/internal/keychain"
+	"github.com/globalsign/hvclient/metrics"
 )
 
 const (
@@ -34,17 +38,37 @@ const (
 
 var timeout = time.Second * 5
 
-func main() {
-	// Parse flags and set logger.
-	flag.Parse()
+// defaultDuration is the certificate duration from the configuration file,
+// used for requests which specify neither a not-after time nor a duration.
+var defaultDuration string
 
+func main() {
 	log.SetFlags(0)
 	log.SetPrefix("hvclient: ")
 
-	// Handle any non-request options.
-	var err error
+	// Translate any subcommand into the equivalent legacy flags, and then
+	// parse the flags.
+	var args, cmds, err = translateArgs(os.Args[1:])
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
 
+	if err = flag.CommandLine.Parse(args); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if err = checkStdinUse(*fInteractive, *fTemplate, *fPublicKey, *fPrivateKey, *fCSR); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// Handle any non-request options.
 	switch {
+	case len(cmds) == 2 && cmds[0] == "completion":
+		if err = writeCompletion(os.Stdout, cmds[1], flag.CommandLine); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+
 	case *fHelp:
 		showHelp()
 		return
@@ -57,12 +81,19 @@ func main() {
 		showSampleTemplate()
 		return
 
-	case *fGenerate, *fCSROut:
+	case (*fGenerate || *fCSROut) && !*fInteractive:
 		if err = requestCert(nil); err != nil {
 			log.Fatalf("%v", err)
 		}
 		return
 
+	case *fConfigInit:
+		if err = configInit(*fConfigFile); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		return
+
 	case *fGenRSA > 0:
 		if _, err = generateRSAKey(*fGenRSA, *fEncrypt); err != nil {
 			log.Fatalf("%v", err)
@@ -85,35 +116,143 @@ func main() {
 	}
 
 	// Validate that configuration file is specified or default is available.
-	var configFile string
-	if *fConfigFile == "" {
-		var homeDir = os.Getenv("HOME")
+	var configFile = *fConfigFile
+	if configFile == "" {
+		if configFile, err = defaultConfigFilePath(); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
 
-		if homeDir == "" {
-			log.Fatalf("you must specify a configuration file")
+	// Read the configuration file, using the selected profile if any, and
+	// apply any overrides specified at the command line.
+	var profile = *fProfile
+	if profile == "" {
+		profile = os.Getenv(profileEnvVar)
+	}
+
+	if *fStoreSecret != "" {
+		if err = storeSecret(configFile, profile, *fStoreSecret, func(prompt string) (string, error) {
+			return getPasswordFromTerminal(prompt, true)
+		}, keychain.Set); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		return
+	}
+
+	var fileConf *config.Config
+	if fileConf, err = readConfigFile(configFile, profile); err != nil {
+		log.Fatalf("couldn't create client: %v", err)
+	}
+
+	if fileConf.DefaultDuration != "" {
+		if _, err = hvclient.ParseDuration(fileConf.DefaultDuration); err != nil {
+			log.Fatalf("invalid default_duration %q in %s: %v", fileConf.DefaultDuration, configFile, err)
 		}
 
-		configFile = path.Join(homeDir, defaultConfigFile)
-	} else {
-		configFile = *fConfigFile
+		defaultDuration = fileConf.DefaultDuration
+	}
+
+	var conf *hvclient.Config
+	if conf, err = clientConfig(fileConf, keychain.Get); err != nil {
+		log.Fatalf("couldn't create client: %v", err)
+	}
+
+	if err = applyConfigOverrides(conf, *fTimeout, *fRetries); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if *fDebugHTTP {
+		conf.DumpRequests = os.Stderr
+	}
+
+	conf.PageConcurrency = *fConcurrency
+
+	if *fDNSServers != "" {
+		conf.DNSServers = strings.Split(*fDNSServers, ",")
+	}
+
+	// Cache the validation policy and trust chain between invocations, if
+	// the platform has a user cache directory.
+	if !*fNoCache {
+		if dir, err := os.UserCacheDir(); err == nil {
+			conf.CacheDir = filepath.Join(dir, "hvclient")
+		}
+	}
+
+	// Never output a precertificate in place of the final certificate.
+	conf.AwaitFinalCertificate = true
+
+	if *fProgress {
+		conf.Progress = newProgressWriter(os.Stderr)
+	}
+
+	// Count API calls for export as metrics by long-running modes.
+	var collector *metrics.Collector
+	if *fMetrics != "" {
+		collector = metrics.New()
+		conf.Observer = collector
+	}
+
+	// Create HVCA client, using any timeout specified at the command line
+	// for the initial login.
+	if *fTimeout > 0 {
+		timeout = *fTimeout
+	}
+
+	// The self-test performs its own login so that a failure can be
+	// reported in the results.
+	if *fSelftest {
+		if err = selftest(conf, *fTemplate); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		return
 	}
 
-	// Create HVCA client.
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	var clnt *hvclient.Client
-	if clnt, err = hvclient.NewClientFromFile(ctx, configFile); err != nil {
+	if clnt, err = hvclient.NewClient(ctx, conf); err != nil {
 		log.Fatalf("couldn't create client: %v", err)
 	}
+	defer clnt.Close()
 
-	// Set the timeout based on the configuration file.
+	// Set the timeout based on the configuration file and any override.
 	timeout = clnt.DefaultTimeout()
 
 	// Select and execute desired operation.
 	var willRequest = !(*fPublicKey == "" && *fPrivateKey == "" && *fCSR == "")
 
 	switch {
+	case *fLint:
+		if err = lintTemplate(clnt, *fTemplate); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+	case *fInteractive:
+		if err = interactiveRequest(clnt); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+	case *fSMIME:
+		if err = smimeCert(clnt, *fSubjectEmail, *fSubjectCommonName); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+	case *fRekey != "":
+		if err = rekeyCert(clnt, *fRekey); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+	case *fWatch != "":
+		if err = withMetrics(collector, clnt, func() error {
+			return watchCert(clnt, *fWatch, *fInterval, collector)
+		}); err != nil {
+			log.Fatalf("%v", err)
+		}
+
 	case willRequest:
 		if err = requestCert(clnt); err != nil {
 			log.Fatalf("%v", err)
@@ -123,16 +262,36 @@ func main() {
 		retrieveCert(clnt, *fRetrieve)
 
 	case *fRevoke != "":
-		revokeCert(clnt, *fRevoke)
+		if err = revokeCert(clnt, *fRevoke, *fReason, *fRevokeAt, *fRevokeQueue); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+	case *fRevokeDue:
+		if err = revokeDue(clnt, *fRevokeQueue); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+	case *fClaimsPurge != "":
+		if err = claimsPurge(clnt, *fClaimsPurge, *fStatus, *fDryRun); err != nil {
+			log.Fatalf("%v", err)
+		}
 
-	case *fStatus != "":
+	case *fCanIssue != "":
+		if err = canIssue(clnt, *fCanIssue); err != nil {
+			log.Fatalf("%v", err)
+		}
+
+	case *fStatus != "" && !*fClaims:
 		retrieveCertStatus(clnt, *fStatus)
 
 	case *fUpdated != "":
 		retrieveCertUpdatedAt(clnt, *fUpdated)
 
+	case *fHistory != "":
+		retrieveCertHistory(clnt, *fHistory)
+
 	case *fTrustChain:
-		trustChain(clnt)
+		trustChain(clnt, *fFormat, *fOut)
 
 	case *fPolicy:
 		validationPolicy(clnt)
@@ -155,8 +314,19 @@ func main() {
 	case *fQuota:
 		quota(clnt)
 
+	case *fAccount:
+		accountInfo(clnt)
+
+	case *fCapabilities:
+		capabilities(clnt)
+
+	case *fReconcile != "":
+		if err = reconcileCerts(clnt, *fReconcile, from, to, *fExpiryWindow, *fFormat); err != nil {
+			log.Fatalf("%v", err)
+		}
+
 	case *fClaims:
-		claimsDomains(clnt, *fPage, *fPageSize, *fPending)
+		claimsDomains(clnt, *fPage, *fPageSize, *fPending, *fStatus)
 
 	case *fClaimSubmit != "":
 		claimSubmit(clnt, *fClaimSubmit)
@@ -168,10 +338,17 @@ func main() {
 		claimDelete(clnt, *fClaimDelete)
 
 	case *fClaimDNS != "":
-		claimDNS(clnt, *fClaimDNS, *fAuthDomain)
+		claimDNS(clnt, *fClaimDNS, *fAuthDomain, *fToken)
+
+	case *fClaimMonitor != "":
+		if err = withMetrics(collector, clnt, func() error {
+			return claimMonitor(clnt, *fClaimMonitor, *fAuthDomain, *fToken, *fInterval, *fMaxAssertions)
+		}); err != nil {
+			log.Fatalf("%v", err)
+		}
 
 	case *fClaimHTTP != "":
-		claimHTTP(clnt, *fClaimHTTP, *fScheme, *fAuthDomain)
+		claimHTTP(clnt, *fClaimHTTP, *fScheme, *fAuthDomain, *fToken)
 
 	case *fClaimEmail != "":
 		claimEmail(clnt, *fClaimEmail, *fEmailAddress)
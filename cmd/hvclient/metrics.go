@@ -0,0 +1,105 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/globalsign/hvclient/metrics"
+)
+
+// metricsPath is the path at which metrics are served.
+const metricsPath = "/metrics"
+
+// serveMetrics serves the metrics collected by the collector at /metrics on
+// the specified address, and updates the remaining issuance quota from the
+// quota source immediately and then at the specified interval. The returned
+// function stops both, and waits for the quota updates to finish.
+func serveMetrics(
+	collector *metrics.Collector,
+	src metrics.QuotaSource,
+	addr string,
+	interval time.Duration,
+) (func(), error) {
+	var listener, err = net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't listen for metrics requests: %w", err)
+	}
+
+	var mux = http.NewServeMux()
+	mux.Handle(metricsPath, collector)
+
+	var server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: timeout,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintf(os.Stderr, "metrics server: %v\n", err)
+		}
+	}()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var done = make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		var ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			var quotaCtx, quotaCancel = context.WithTimeout(ctx, timeout)
+			if err := collector.UpdateQuota(quotaCtx, src); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "metrics: %v\n", err)
+			}
+			quotaCancel()
+
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		_ = server.Close()
+	}, nil
+}
+
+// withMetrics runs a long-running operation, serving metrics while it runs
+// if an address was specified with -metrics.
+func withMetrics(collector *metrics.Collector, src metrics.QuotaSource, run func() error) error {
+	if collector != nil {
+		var stop, err = serveMetrics(collector, src, *fMetrics, *fInterval)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	return run()
+}
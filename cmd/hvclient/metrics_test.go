@@ -0,0 +1,79 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient/metrics"
+)
+
+// fakeQuotaSource is a quota source which counts the times it is queried.
+type fakeQuotaSource struct {
+	calls int32
+}
+
+func (s *fakeQuotaSource) QuotaIssuance(context.Context) (int64, error) {
+	atomic.AddInt32(&s.calls, 1)
+
+	return 42, nil
+}
+
+func TestServeMetrics(t *testing.T) {
+	t.Parallel()
+
+	var collector = metrics.New()
+	var src fakeQuotaSource
+
+	var stop, err = serveMetrics(collector, &src, "127.0.0.1:0", time.Millisecond*10)
+	if err != nil {
+		t.Fatalf("couldn't serve metrics: %v", err)
+	}
+
+	var deadline = time.Now().Add(time.Second * 5)
+	for atomic.LoadInt32(&src.calls) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// Stopping waits for the quota updates to finish, so there should be no
+	// further queries afterwards.
+	stop()
+
+	var calls = atomic.LoadInt32(&src.calls)
+	if calls < 2 {
+		t.Fatalf("got %d quota queries, want at least 2", calls)
+	}
+
+	time.Sleep(time.Millisecond * 50)
+
+	if got := atomic.LoadInt32(&src.calls); got != calls {
+		t.Errorf("got %d quota queries after stopping, want %d", got, calls)
+	}
+
+	var buf bytes.Buffer
+	if err = collector.Write(&buf); err != nil {
+		t.Fatalf("couldn't write metrics: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "hvclient_issuance_quota_remaining 42\n") {
+		t.Errorf("quota not reported in metrics:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/fileutils"
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+// exportPKCS12 writes the certificate, the private key and the trust chain
+// to the PKCS#12 file specified at the command line, if any. The password is
+// taken from the command line, or prompted for if not specified.
+func exportPKCS12(ctx context.Context, clnt *hvclient.Client, key interface{}, cert *x509.Certificate) error {
+	if *fP12Out == "" {
+		return nil
+	}
+
+	if key == nil {
+		return fmt.Errorf("-p12out requires the private key to be specified with -%s", flagNamePrivateKey)
+	}
+
+	var chain, err = clnt.TrustChain(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't retrieve trust chain: %v", err)
+	}
+
+	var password = *fP12Pass
+	if password == "" {
+		if password, err = getPasswordFromTerminal("Enter passphrase to protect PKCS#12 file", true); err != nil {
+			return err
+		}
+	}
+
+	return writePKCS12(*fP12Out, key, cert, chain, password)
+}
+
+// writePKCS12 writes the certificate, private key and certificate chain to
+// a password-protected PKCS#12 file. The file is readable only by its owner,
+// since it contains a private key.
+func writePKCS12(filename string, key interface{}, cert *x509.Certificate, chain []*x509.Certificate, password string) error {
+	if filename == "" {
+		return errors.New("no PKCS#12 output file specified")
+	}
+
+	var der, err = pki.BuildPKCS12(key, cert, chain, password)
+	if err != nil {
+		return fmt.Errorf("couldn't build PKCS#12 file: %v", err)
+	}
+
+	if err = fileutils.WriteFileLocked(filename, der, 0600); err != nil {
+		return fmt.Errorf("couldn't write PKCS#12 file: %v", err)
+	}
+
+	return nil
+}
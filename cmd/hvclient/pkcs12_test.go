@@ -0,0 +1,98 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/pkcs12"
+
+	"github.com/globalsign/hvclient/internal/pki"
+	"github.com/globalsign/hvclient/internal/testhelpers"
+)
+
+func TestWritePKCS12(t *testing.T) {
+	t.Parallel()
+
+	var key, err = pki.PrivateKeyFromFileWithPassword("testdata/rsa_priv.key", "")
+	if err != nil {
+		t.Fatalf("couldn't read private key: %v", err)
+	}
+
+	var cert = testhelpers.MustGetCertFromFile(t, "testdata/cert.pem")
+	var root = testhelpers.MustGetCertFromFile(t, "../../testdata/test_root_cert.pem")
+
+	var out = filepath.Join(t.TempDir(), "bundle.p12")
+	if err = writePKCS12(out, key, cert, []*x509.Certificate{root}, "secret"); err != nil {
+		t.Fatalf("couldn't write PKCS#12 file: %v", err)
+	}
+
+	var info os.FileInfo
+	if info, err = os.Stat(out); err != nil {
+		t.Fatalf("couldn't stat PKCS#12 file: %v", err)
+	}
+
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("got file permissions %o, want %o", perm, 0600)
+	}
+
+	var blocks []*pem.Block
+	if blocks, err = pkcs12.ToPEM(testhelpers.MustReadFile(t, out), "secret"); err != nil {
+		t.Fatalf("couldn't decode PKCS#12 file: %v", err)
+	}
+
+	if len(blocks) != 3 {
+		t.Fatalf("got %d PEM blocks, want 3", len(blocks))
+	}
+}
+
+func TestWritePKCS12Failure(t *testing.T) {
+	t.Parallel()
+
+	var key, err = pki.PrivateKeyFromFileWithPassword("testdata/rsa_priv.key", "")
+	if err != nil {
+		t.Fatalf("couldn't read private key: %v", err)
+	}
+
+	var cert = testhelpers.MustGetCertFromFile(t, "testdata/cert.pem")
+	var dir = t.TempDir()
+
+	var testcases = []struct {
+		name     string
+		filename string
+		key      interface{}
+	}{
+		{"NoFilename", "", key},
+		{"NoKey", filepath.Join(dir, "nokey.p12"), nil},
+		{"BadDirectory", filepath.Join(dir, "missing", "bundle.p12"), key},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := writePKCS12(tc.filename, tc.key, cert, nil, "secret"); err == nil {
+				t.Fatalf("unexpectedly wrote PKCS#12 file")
+			}
+		})
+	}
+}
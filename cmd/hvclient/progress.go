@@ -0,0 +1,40 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/globalsign/hvclient"
+)
+
+// newProgressWriter returns a progress reporter which writes a progress
+// line to w, overwriting the previous one, and ends the line when the work
+// is complete.
+func newProgressWriter(w io.Writer) hvclient.ProgressReporter {
+	return hvclient.ProgressFunc(func(completed, total int, err error) {
+		if total == 0 {
+			fmt.Fprintf(w, "\rRetrieved %d pages", completed)
+		} else {
+			fmt.Fprintf(w, "\rRetrieved %d of %d pages", completed, total)
+		}
+
+		if err != nil || completed >= total {
+			fmt.Fprintf(w, "\n")
+		}
+	})
+}
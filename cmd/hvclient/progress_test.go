@@ -0,0 +1,41 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestProgressWriter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	var progress = newProgressWriter(&buf)
+
+	progress.Progress(1, 3, nil)
+	progress.Progress(2, 3, nil)
+	progress.Progress(3, 3, nil)
+	progress.Progress(1, 0, errors.New("failed"))
+
+	const want = "\rRetrieved 1 of 3 pages\rRetrieved 2 of 3 pages\rRetrieved 3 of 3 pages\n" +
+		"\rRetrieved 1 pages\n"
+
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/globalsign/hvclient"
+)
+
+// reconcileCerts compares the certificates deployed in the specified
+// directory with the certificates issued and revoked during the specified
+// time window, and outputs a report of any discrepancies.
+func reconcileCerts(clnt *hvclient.Client, dir string, from, to time.Time, expiryWindow, format string) error {
+	var window, err = hvclient.ParseDuration(expiryWindow)
+	if err != nil {
+		return fmt.Errorf("invalid expiry window: %v", err)
+	}
+
+	var deployed []hvclient.DeployedCertificate
+	if deployed, err = hvclient.DeployedCertificatesFromDir(dir); err != nil {
+		return fmt.Errorf("couldn't read deployed certificates: %v", err)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var findings []hvclient.ReconcileFinding
+	if findings, err = clnt.Reconcile(ctx, deployed, from, to, window); err != nil {
+		return fmt.Errorf("couldn't reconcile certificates: %v", err)
+	}
+
+	return writeReconcileReport(os.Stdout, findings, format)
+}
+
+// writeReconcileReport writes reconciliation findings in CSV or JSON format.
+func writeReconcileReport(w io.Writer, findings []hvclient.ReconcileFinding, format string) error {
+	switch strings.ToLower(format) {
+	case "", "csv":
+		var writer = csv.NewWriter(w)
+
+		if err := writer.Write([]string{"problem", "serial_number", "not_after", "path"}); err != nil {
+			return err
+		}
+
+		for _, finding := range findings {
+			if err := writer.Write([]string{
+				finding.Problem.String(),
+				fmt.Sprintf("%X", finding.SerialNumber),
+				finding.NotAfter.UTC().Format(time.RFC3339),
+				finding.Path,
+			}); err != nil {
+				return err
+			}
+		}
+
+		writer.Flush()
+
+		return writer.Error()
+
+	case "json":
+		// Output an empty array rather than null if there are no findings.
+		if findings == nil {
+			findings = []hvclient.ReconcileFinding{}
+		}
+
+		var encoder = json.NewEncoder(w)
+		encoder.SetIndent("", "    ")
+
+		return encoder.Encode(findings)
+	}
+
+	return fmt.Errorf("unknown report format: %s", format)
+}
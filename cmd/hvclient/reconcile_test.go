@@ -0,0 +1,99 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+)
+
+func TestWriteReconcileReport(t *testing.T) {
+	t.Parallel()
+
+	var findings = []hvclient.ReconcileFinding{
+		{
+			Problem:      hvclient.IssuedNotDeployed,
+			SerialNumber: big.NewInt(0x741DAF9EC2D5F7DC),
+			NotAfter:     time.Date(2021, 9, 16, 16, 29, 51, 0, time.UTC),
+		},
+		{
+			Problem:      hvclient.NearExpiry,
+			SerialNumber: big.NewInt(0x47BC1DC5524A2B18),
+			NotAfter:     time.Date(2021, 9, 17, 12, 5, 37, 0, time.UTC),
+			Path:         "certs/www.pem",
+		},
+	}
+
+	var testcases = []struct {
+		name     string
+		findings []hvclient.ReconcileFinding
+		format   string
+		want     string
+	}{
+		{
+			name:     "CSV",
+			findings: findings,
+			format:   "csv",
+			want: "problem,serial_number,not_after,path\n" +
+				"ISSUED_NOT_DEPLOYED,741DAF9EC2D5F7DC,2021-09-16T16:29:51Z,\n" +
+				"NEAR_EXPIRY,47BC1DC5524A2B18,2021-09-17T12:05:37Z,certs/www.pem\n",
+		},
+		{
+			name:     "JSON",
+			findings: findings[1:],
+			format:   "JSON",
+			want: `[
+    {
+        "problem": "NEAR_EXPIRY",
+        "serial_number": "47BC1DC5524A2B18",
+        "not_after": 1631880337,
+        "path": "certs/www.pem"
+    }
+]
+`,
+		},
+		{
+			name:   "JSONEmpty",
+			format: "json",
+			want:   "[]\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := writeReconcileReport(&buf, tc.findings, tc.format); err != nil {
+				t.Fatalf("couldn't write report: %v", err)
+			}
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	if err := writeReconcileReport(&bytes.Buffer{}, findings, "xml"); err == nil {
+		t.Errorf("unexpectedly wrote report in unknown format")
+	}
+}
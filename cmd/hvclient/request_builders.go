@@ -21,9 +21,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"net/url"
+	"os"
 	"strings"
 	"time"
 
@@ -49,11 +49,21 @@ type validityValues struct {
 	notBefore string
 	notAfter  string
 	duration  string
+
+	// defaultDuration is used as the duration if neither a not-after time
+	// nor a duration is specified, either at the command line or in a
+	// template.
+	defaultDuration string
+
+	// maxDuration, if non-zero, is the maximum duration allowed by the
+	// validation policy, to which the default duration is limited.
+	maxDuration time.Duration
 }
 
 // subjectValues is used to aggregate subject distinguished name fields
 // specified at the command line for ease of passing to functions.
 type subjectValues struct {
+	subject            string
 	commonName         string
 	serialNumber       string
 	organization       string
@@ -68,18 +78,24 @@ type subjectValues struct {
 	businessCategory   string
 	email              string
 	extraAttributes    string
+	replace            bool
 }
 
 type sanValues struct {
-	dnsNames string
-	emails   string
-	ips      string
-	uris     string
+	dnsNames    string
+	emails      string
+	ips         string
+	uris        string
+	spiffeID    string
+	check       bool
+	checkEmails bool
+	replace     bool
 }
 
-// IsEmpty returns true if all the fields are the empty string.
+// IsEmpty returns true if all the string fields are the empty string.
 func (s subjectValues) isEmpty() bool {
 	return checkAllEmpty(
+		s.subject,
 		s.commonName,
 		s.serialNumber,
 		s.organization,
@@ -107,11 +123,16 @@ func buildRequest(reqinfo *requestValues) (*hvclient.Request, error) {
 	}
 
 	// Populate certificate request with values specified at the command line.
+	var duration string
+	if duration, err = validityDuration(reqinfo.validity, request.Validity); err != nil {
+		return nil, err
+	}
+
 	if request.Validity, err = buildValidity(
 		request.Validity,
 		reqinfo.validity.notBefore,
 		reqinfo.validity.notAfter,
-		reqinfo.validity.duration,
+		duration,
 	); err != nil {
 		return nil, err
 	}
@@ -129,10 +150,33 @@ func buildRequest(reqinfo *requestValues) (*hvclient.Request, error) {
 		reqinfo.san.emails,
 		reqinfo.san.ips,
 		reqinfo.san.uris,
+		reqinfo.san.replace,
 	); err != nil {
 		return nil, err
 	}
 
+	if reqinfo.san.spiffeID != "" {
+		if request.SAN == nil {
+			request.SAN = &hvclient.SAN{}
+		}
+
+		if err = request.SAN.SetSPIFFEID(reqinfo.san.spiffeID); err != nil {
+			return nil, err
+		}
+	}
+
+	if reqinfo.san.check && request.SAN != nil {
+		if err = request.SAN.CheckDNSNames(); err != nil {
+			return nil, err
+		}
+	}
+
+	if reqinfo.san.checkEmails && request.SAN != nil {
+		if err = request.SAN.CheckEmails(); err != nil {
+			return nil, err
+		}
+	}
+
 	if request.EKUs, err = buildEKUs(
 		request.EKUs,
 		reqinfo.ekus,
@@ -143,10 +187,21 @@ func buildRequest(reqinfo *requestValues) (*hvclient.Request, error) {
 	// Only add the signature hash algorithm if specified, otherwise we don't
 	// want to bother sending out an object.
 	if reqinfo.sigAlg != "" || reqinfo.sigHash != "" {
-		request.Signature = &hvclient.Signature{
-			Algorithm:     reqinfo.sigAlg,
-			HashAlgorithm: reqinfo.sigHash,
+		var alg hvclient.SignatureAlgorithm
+		if reqinfo.sigAlg != "" {
+			if alg, err = hvclient.ParseSignatureAlgorithm(reqinfo.sigAlg); err != nil {
+				return nil, err
+			}
+		}
+
+		var hash hvclient.HashAlgorithm
+		if reqinfo.sigHash != "" {
+			if hash, err = hvclient.ParseHashAlgorithm(reqinfo.sigHash); err != nil {
+				return nil, err
+			}
 		}
+
+		request.Signature = hvclient.NewSignature(alg, hash)
 	}
 
 	if request.PublicKey, request.PrivateKey, request.CSR, err = getKeys(
@@ -177,7 +232,7 @@ func getRequestFromTemplateOrNew(template string) (*hvclient.Request, error) {
 
 	// Initialize request with values from template, if present.
 	if template != "" {
-		var data, err = ioutil.ReadFile(template)
+		var data, err = readInput(template, os.Stdin)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't read template file: %v", err)
 		}
@@ -191,6 +246,28 @@ func getRequestFromTemplateOrNew(template string) (*hvclient.Request, error) {
 	return request, nil
 }
 
+// validityDuration returns the certificate duration with which to build the
+// validity period. If neither a not-after time nor a duration was specified,
+// either at the command line or in the template, the default duration is
+// used, limited to the maximum duration allowed by the validation policy.
+func validityDuration(values validityValues, initial *hvclient.Validity) (string, error) {
+	if values.duration != "" || values.notAfter != "" || values.defaultDuration == "" ||
+		(initial != nil && !initial.NotAfter.IsZero()) {
+		return values.duration, nil
+	}
+
+	var d, err = hvclient.ParseDuration(values.defaultDuration)
+	if err != nil {
+		return "", fmt.Errorf("invalid default duration %q: %v", values.defaultDuration, err)
+	}
+
+	if values.maxDuration > 0 && d > values.maxDuration {
+		d = values.maxDuration
+	}
+
+	return fmt.Sprintf("%ds", d/time.Second), nil
+}
+
 // buildValidity takes an existing Validity object, and overrides its values
 // with any specified at the command line, calculating any default values as
 // necessary.
@@ -222,7 +299,7 @@ func buildValidity(
 
 	var timeDuration time.Duration
 	if duration != "" {
-		if timeDuration, err = parseDuration(duration); err != nil {
+		if timeDuration, err = hvclient.ParseDuration(duration); err != nil {
 			return nil, fmt.Errorf("invalid duration time %q: %v", duration, err)
 		}
 	}
@@ -292,19 +369,29 @@ func buildValidity(
 
 // buildDN takes an existing DN object, appends to its fields any
 // values specified at the command line, and returns the address of the
-// modified object. If the existing DN object is nil, a new DN
-// object is created and populated and its address is returned.
+// modified object. If the existing DN object is nil, or if values.replace is
+// true, a new DN object is created and populated and its address is
+// returned.
 func buildDN(dn *hvclient.DN, values subjectValues) (*hvclient.DN, error) {
 	// Return initial value without changes if no other values are specified.
 	if values.isEmpty() {
 		return dn, nil
 	}
 
-	// Create the DN object if the initial value is nil.
-	if dn == nil {
+	// Create the DN object if the initial value is nil or is to be replaced.
+	if dn == nil || values.replace {
 		dn = &hvclient.DN{}
 	}
 
+	// Merge in any complete subject DN, so the individual fields below can
+	// override its values.
+	if values.subject != "" {
+		var err error
+		if dn, err = mergeSubjectDN(dn, values.subject); err != nil {
+			return nil, err
+		}
+	}
+
 	// Set or override any single-value fields as required.
 	for _, field := range []struct {
 		from string
@@ -354,24 +441,65 @@ func buildDN(dn *hvclient.DN, values subjectValues) (*hvclient.DN, error) {
 	return dn, nil
 }
 
+// mergeSubjectDN parses an RFC 4514 string representation of a subject
+// distinguished name, and returns a copy of the DN object with any fields
+// specified in the string set or overridden, and with any organizational
+// units and extra attributes appended.
+func mergeSubjectDN(dn *hvclient.DN, subject string) (*hvclient.DN, error) {
+	var parsed, err = hvclient.ParseDN(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var merged = *dn
+
+	for _, field := range []struct {
+		from string
+		to   *string
+	}{
+		{parsed.SerialNumber, &merged.SerialNumber},
+		{parsed.CommonName, &merged.CommonName},
+		{parsed.Organization, &merged.Organization},
+		{parsed.StreetAddress, &merged.StreetAddress},
+		{parsed.Locality, &merged.Locality},
+		{parsed.State, &merged.State},
+		{parsed.Country, &merged.Country},
+		{parsed.Email, &merged.Email},
+		{parsed.JOILocality, &merged.JOILocality},
+		{parsed.JOIState, &merged.JOIState},
+		{parsed.JOICountry, &merged.JOICountry},
+		{parsed.BusinessCategory, &merged.BusinessCategory},
+	} {
+		if field.from != "" {
+			*field.to = field.from
+		}
+	}
+
+	merged.OrganizationalUnit = append(append([]string(nil), dn.OrganizationalUnit...), parsed.OrganizationalUnit...)
+	merged.ExtraAttributes = append(append([]hvclient.OIDAndString(nil), dn.ExtraAttributes...), parsed.ExtraAttributes...)
+
+	return &merged, nil
+}
+
 // buildSAN takes an existing SAN object, appends to its fields any values
 // specified at the command line, and returns the address of the modified
-// object. If the existing SAN object is nil, a new SAN object is created
-// and populated and its address is returned.
+// object. If the existing SAN object is nil, or if replace is true, a new
+// SAN object is created and populated and its address is returned.
 func buildSAN(
 	san *hvclient.SAN,
 	dnsnames string,
 	emails string,
 	ips string,
 	uris string,
+	replace bool,
 ) (*hvclient.SAN, error) {
 	// Return initial value without changes if no other values are specified.
 	if checkAllEmpty(dnsnames, emails, ips, uris) {
 		return san, nil
 	}
 
-	// Create the SAN object if the initial value is nil.
-	if san == nil {
+	// Create the SAN object if the initial value is nil or is to be replaced.
+	if san == nil || replace {
 		san = &hvclient.SAN{}
 	}
 
@@ -470,29 +598,48 @@ func getKeys(
 				flagNamePublicKey, flagNamePrivateKey, flagNameCSR)
 	}
 
+	var data []byte
+
 	if public != "" {
-		if publickey, err = pki.PublicKeyFromFile(public); err != nil {
+		if data, err = readInput(public, os.Stdin); err != nil {
+			return nil, nil, nil, err
+		}
+
+		if publickey, err = pki.PublicKeyFromBytes(data); err != nil {
 			return nil, nil, nil, err
 		}
 	}
 
 	if private != "" {
+		if data, err = readInput(private, os.Stdin); err != nil {
+			return nil, nil, nil, fmt.Errorf("couldn't read private key file: %v", err)
+		}
+
 		var password string
 
-		if pki.FileIsEncryptedPEMBlock(private) {
+		if pki.IsEncryptedPEMBlock(data) {
+			// The passphrase prompt reads from the terminal on standard
+			// input, which has already been consumed.
+			if private == stdinName {
+				return nil, nil, nil, errors.New("an encrypted private key cannot be read from standard input")
+			}
+
 			if password, err = passwordFunc("Enter passphrase to decrypt private key", false); err != nil {
 				return nil, nil, nil, err
 			}
 		}
 
-		if privatekey, err = pki.PrivateKeyFromFileWithPassword(private,
-			password); err != nil {
+		if privatekey, err = pki.PrivateKeyFromBytesWithPassword(data, password); err != nil {
 			return nil, nil, nil, fmt.Errorf("couldn't read private key file: %v", err)
 		}
 	}
 
 	if csr != "" {
-		if request, err = pki.CSRFromFile(csr); err != nil {
+		if data, err = readInput(csr, os.Stdin); err != nil {
+			return nil, nil, nil, err
+		}
+
+		if request, err = pki.CSRFromPEM(data); err != nil {
 			return nil, nil, nil, err
 		}
 	}
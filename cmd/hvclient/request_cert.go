@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"time"
 
 	"github.com/globalsign/hvclient"
 	"github.com/globalsign/hvclient/internal/pki"
@@ -29,50 +30,145 @@ import (
 // requestCert requests a new certificate from HVCA and retrieves and outputs
 // it, if successful.
 func requestCert(clnt *hvclient.Client) error {
-	// Build a request from the information supplied via the command line.
-	var request, err = buildRequest(
-		&requestValues{
-			template: *fTemplate,
-			validity: validityValues{
-				notBefore: *fNotBefore,
-				notAfter:  *fNotAfter,
-				duration:  *fDuration,
-			},
-			subject: subjectValues{
-				commonName:         *fSubjectCommonName,
-				serialNumber:       *fSubjectSerialNumber,
-				organization:       *fSubjectOrganization,
-				organizationalUnit: *fSubjectOrganizationalUnit,
-				streetAddress:      *fSubjectStreetAddress,
-				locality:           *fSubjectLocality,
-				state:              *fSubjectState,
-				country:            *fSubjectCountry,
-				email:              *fSubjectEmail,
-				joiLocality:        *fSubjectJOILocality,
-				joiState:           *fSubjectJOIState,
-				joiCountry:         *fSubjectJOICountry,
-				businessCategory:   *fSubjectBusinessCategory,
-				extraAttributes:    *fSubjectExtraAttributes,
-			},
-			san: sanValues{
-				dnsNames: *fDNSNames,
-				emails:   *fEmails,
-				ips:      *fIPs,
-				uris:     *fURIs,
-			},
-			ekus:       *fEKUs,
-			sigAlg:     *fSigAlg,
-			sigHash:    *fSigHash,
-			publickey:  *fPublicKey,
-			privatekey: *fPrivateKey,
-			csr:        *fCSR,
-			gencsr:     *fGenCSR,
-		},
-	)
+	var request, err = requestFromFlags(clnt)
 	if err != nil {
 		return err
 	}
 
+	return submitRequest(clnt, request)
+}
+
+// requestFromFlags builds a certificate request from the information
+// supplied via the command line, applying any validation policy defaults
+// and checking any SAN email addresses against the policy.
+func requestFromFlags(clnt *hvclient.Client) (*hvclient.Request, error) {
+	var values = requestValuesFromFlags()
+	var err error
+
+	// The client is nil if the request is only to be output, in which case
+	// the validation policy is not available. Otherwise it is needed to fill
+	// in policy defaults, to limit any default duration and to check SAN
+	// email addresses.
+	var pol *hvclient.Policy
+	if clnt != nil && (!*fNoDefaults || values.validity.defaultDuration != "" || values.san.checkEmails) {
+		var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		if pol, err = clnt.Policy(ctx); err != nil {
+			return nil, fmt.Errorf("couldn't retrieve validation policy: %v", err)
+		}
+
+		values.validity.maxDuration = policyMaxDuration(pol)
+	}
+
+	// Build a request from the information supplied via the command line.
+	var request *hvclient.Request
+	if request, err = buildRequest(values); err != nil {
+		return nil, err
+	}
+
+	if pol != nil && !*fNoDefaults {
+		if err = request.ApplyPolicyDefaults(pol); err != nil {
+			return nil, fmt.Errorf("couldn't apply validation policy defaults: %v", err)
+		}
+	}
+
+	if pol != nil && values.san.checkEmails {
+		if err = checkRequestEmails(request, pol); err != nil {
+			return nil, err
+		}
+	}
+
+	return request, nil
+}
+
+// checkRequestEmails checks the SAN email addresses in a certificate request
+// against the validation policy, so that addresses which HVCA would reject
+// are reported before the request is submitted.
+func checkRequestEmails(request *hvclient.Request, pol *hvclient.Policy) error {
+	var emails []string
+	if request.SAN != nil {
+		emails = request.SAN.Emails
+	}
+
+	var emailPol *hvclient.ListPolicy
+	if pol.SAN != nil {
+		emailPol = pol.SAN.Emails
+	}
+
+	if err := emailPol.Validate(emails); err != nil {
+		return fmt.Errorf("invalid SAN email addresses: %v", err)
+	}
+
+	return nil
+}
+
+// requestValuesFromFlags collects the certificate request values specified
+// at the command line.
+func requestValuesFromFlags() *requestValues {
+	return &requestValues{
+		template: *fTemplate,
+		validity: validityValues{
+			notBefore: *fNotBefore,
+			notAfter:  *fNotAfter,
+			duration:  *fDuration,
+
+			defaultDuration: defaultDuration,
+		},
+		subject: subjectValues{
+			subject:            *fSubject,
+			commonName:         *fSubjectCommonName,
+			serialNumber:       *fSubjectSerialNumber,
+			organization:       *fSubjectOrganization,
+			organizationalUnit: *fSubjectOrganizationalUnit,
+			streetAddress:      *fSubjectStreetAddress,
+			locality:           *fSubjectLocality,
+			state:              *fSubjectState,
+			country:            *fSubjectCountry,
+			email:              *fSubjectEmail,
+			joiLocality:        *fSubjectJOILocality,
+			joiState:           *fSubjectJOIState,
+			joiCountry:         *fSubjectJOICountry,
+			businessCategory:   *fSubjectBusinessCategory,
+			extraAttributes:    *fSubjectExtraAttributes,
+			replace:            *fReplaceSubject,
+		},
+		san: sanValues{
+			dnsNames:    *fDNSNames,
+			emails:      *fEmails,
+			ips:         *fIPs,
+			uris:        *fURIs,
+			spiffeID:    *fSPIFFEID,
+			check:       *fCheckDNSNames,
+			checkEmails: *fCheckEmails,
+			replace:     *fReplaceSANs,
+		},
+		ekus:       *fEKUs,
+		sigAlg:     *fSigAlg,
+		sigHash:    *fSigHash,
+		publickey:  *fPublicKey,
+		privatekey: *fPrivateKey,
+		csr:        *fCSR,
+		gencsr:     *fGenCSR,
+	}
+}
+
+// policyMaxDuration returns the maximum certificate duration allowed by a
+// validation policy, or zero if the policy does not specify one.
+func policyMaxDuration(pol *hvclient.Policy) time.Duration {
+	if pol == nil || pol.Validity == nil {
+		return 0
+	}
+
+	return time.Duration(pol.Validity.SecondsMax) * time.Second
+}
+
+// submitRequest outputs the request JSON or PKCS#10 certificate signing
+// request if requested at the command line, or otherwise requests a new
+// certificate from HVCA and retrieves and outputs it, if successful.
+func submitRequest(clnt *hvclient.Client, request *hvclient.Request) error {
+	var err error
+
 	// If the user requested to output the certificate request JSON without
 	// actually making the request, then do so.
 	if *fGenerate {
@@ -99,10 +195,20 @@ func requestCert(clnt *hvclient.Client) error {
 		return nil
 	}
 
+	// If the user requested a dry run, check the request without actually
+	// making it.
+	if *fDryRun {
+		return dryRunRequest(clnt, request)
+	}
+
 	// Otherwise, request new certificate and obtain its serial number.
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	if err = checkRequestCAA(ctx, clnt, request, *fCheckCAA); err != nil {
+		return err
+	}
+
 	var serialNumber *big.Int
 	if serialNumber, err = clnt.CertificateRequest(ctx, request); err != nil {
 		return fmt.Errorf("couldn't obtain certificate: %v", err)
@@ -115,8 +221,35 @@ func requestCert(clnt *hvclient.Client) error {
 		return fmt.Errorf("couldn't retrieve certificate %s: %v", serialNumber, err)
 	}
 
-	// Output the PEM-encoded certificate.
+	// Output the PEM-encoded certificate, and write the PKCS#12 file if
+	// requested.
 	fmt.Printf("%s", info.PEM)
 
+	return exportPKCS12(ctx, clnt, request.PrivateKey, info.X509)
+}
+
+// checkRequestCAA checks the CAA records of the SAN DNS names in a request
+// if requested with the -checkcaa option. If the mode is "warn", a problem is
+// output as a warning, and if it is "fail", it is returned as an error.
+func checkRequestCAA(ctx context.Context, clnt *hvclient.Client, request *hvclient.Request, mode string) error {
+	if mode != "" && mode != "warn" && mode != "fail" {
+		return fmt.Errorf("invalid -checkcaa value %q: must be warn or fail", mode)
+	}
+
+	if mode == "" || request.SAN == nil || len(request.SAN.DNSNames) == 0 {
+		return nil
+	}
+
+	var err = clnt.CheckCAA(ctx, request.SAN.DNSNames)
+	if err == nil {
+		return nil
+	}
+
+	if mode == "fail" {
+		return fmt.Errorf("CAA check failed: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+
 	return nil
 }
@@ -16,6 +16,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/asn1"
 	"errors"
@@ -378,6 +379,72 @@ func testValiditiesAlmostEqual(t *testing.T, first, second *hvclient.Validity) {
 	}
 }
 
+func TestValidityDuration(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name    string
+		values  validityValues
+		initial *hvclient.Validity
+		want    string
+	}{
+		{
+			name:   "NoDefault",
+			values: validityValues{},
+		},
+		{
+			name:   "Default",
+			values: validityValues{defaultDuration: "90d"},
+			want:   "7776000s",
+		},
+		{
+			name:   "DefaultLimited",
+			values: validityValues{defaultDuration: "90d", maxDuration: time.Hour * 24 * 30},
+			want:   "2592000s",
+		},
+		{
+			name:   "DefaultWithinLimit",
+			values: validityValues{defaultDuration: "7d", maxDuration: time.Hour * 24 * 30},
+			want:   "604800s",
+		},
+		{
+			name:   "Duration",
+			values: validityValues{duration: "30d", defaultDuration: "90d"},
+			want:   "30d",
+		},
+		{
+			name:   "NotAfter",
+			values: validityValues{notAfter: "2021-01-01T00:00:00UTC", defaultDuration: "90d"},
+		},
+		{
+			name:    "TemplateNotAfter",
+			values:  validityValues{defaultDuration: "90d"},
+			initial: &hvclient.Validity{NotAfter: time.Unix(0, 0)},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = validityDuration(tc.values, tc.initial)
+			if err != nil {
+				t.Fatalf("couldn't get duration: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := validityDuration(validityValues{defaultDuration: "90"}, nil); err == nil {
+		t.Errorf("unexpectedly accepted invalid default duration")
+	}
+}
+
 func TestBuildDN(t *testing.T) {
 	t.Parallel()
 
@@ -473,6 +540,56 @@ func TestBuildDN(t *testing.T) {
 				Country:            "IM",
 			},
 		},
+		{
+			"SubjectString",
+			&hvclient.DN{
+				CommonName:         "John Doe",
+				OrganizationalUnit: []string{"Operations"},
+				Country:            "GB",
+			},
+			subjectValues{
+				subject:  "CN=Jane Doe,OU=Sales,O=ACME\\, Inc,C=IM",
+				locality: "Douglas",
+				country:  "GB",
+			},
+			&hvclient.DN{
+				CommonName:         "Jane Doe",
+				Organization:       "ACME, Inc",
+				OrganizationalUnit: []string{"Operations", "Sales"},
+				Locality:           "Douglas",
+				Country:            "GB",
+			},
+		},
+		{
+			"Replace",
+			&hvclient.DN{
+				CommonName:         "John Doe",
+				Organization:       "ACME Inc",
+				OrganizationalUnit: []string{"Operations"},
+				Country:            "GB",
+			},
+			subjectValues{
+				commonName:         "Jane Doe",
+				organizationalUnit: "Sales",
+				replace:            true,
+			},
+			&hvclient.DN{
+				CommonName:         "Jane Doe",
+				OrganizationalUnit: []string{"Sales"},
+			},
+		},
+		{
+			"ReplaceNoValues",
+			&hvclient.DN{
+				CommonName: "John Doe",
+			},
+			subjectValues{
+				replace: true,
+			},
+			&hvclient.DN{
+				CommonName: "John Doe",
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -508,6 +625,13 @@ func TestBuildDNFailure(t *testing.T) {
 				organizationalUnit: "Marketing, Sales, ",
 			},
 		},
+		{
+			"BadSubjectString",
+			nil,
+			subjectValues{
+				subject: "CN=Jane Doe,XX=Unknown",
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -649,7 +773,7 @@ func TestBuildSAN(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			var got, err = buildSAN(tc.initial, tc.dnsnames, tc.emails, tc.ips, tc.uris)
+			var got, err = buildSAN(tc.initial, tc.dnsnames, tc.emails, tc.ips, tc.uris, false)
 			if err != nil {
 				t.Fatalf("couldn't build SAN: %v", err)
 			}
@@ -661,6 +785,33 @@ func TestBuildSAN(t *testing.T) {
 	}
 }
 
+func TestBuildSANReplace(t *testing.T) {
+	t.Parallel()
+
+	var initial = &hvclient.SAN{
+		DNSNames: []string{"www.example.com"},
+		Emails:   []string{"jane@example.com"},
+	}
+
+	var got, err = buildSAN(initial, "api.example.com", "", "", "", true)
+	if err != nil {
+		t.Fatalf("couldn't build SAN: %v", err)
+	}
+
+	var want = &hvclient.SAN{DNSNames: []string{"api.example.com"}}
+	if !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got, err = buildSAN(initial, "", "", "", "", true); err != nil {
+		t.Fatalf("couldn't build SAN: %v", err)
+	}
+
+	if got != initial {
+		t.Errorf("got %v, want unchanged %v", got, initial)
+	}
+}
+
 func TestBuildSANFailure(t *testing.T) {
 	t.Parallel()
 
@@ -725,7 +876,7 @@ func TestBuildSANFailure(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			if got, err := buildSAN(tc.initial, tc.dnsnames, tc.emails, tc.ips, tc.uris); err == nil {
+			if got, err := buildSAN(tc.initial, tc.dnsnames, tc.emails, tc.ips, tc.uris, false); err == nil {
 				t.Fatalf("unexpectedly built SAN: %v", got)
 			}
 		})
@@ -929,6 +1080,30 @@ func TestGetKeys(t *testing.T) {
 			testhelpers.MustGetPrivateKeyFromFileWithPassword(t, "testdata/ec_priv_enc.key", "somesecret"),
 			nil,
 		},
+		{
+			"JWKPublicKey",
+			"testdata/rsa_pub.jwk",
+			"",
+			"",
+			func(s string, b bool) (string, error) {
+				return "", nil
+			},
+			testhelpers.MustGetPublicKeyFromFile(t, "testdata/rsa_pub.key"),
+			nil,
+			nil,
+		},
+		{
+			"JWKPrivateKey",
+			"",
+			"testdata/rsa_priv.jwk",
+			"",
+			func(s string, b bool) (string, error) {
+				return "", nil
+			},
+			nil,
+			testhelpers.MustGetPrivateKeyFromFile(t, "testdata/rsa_priv.key"),
+			nil,
+		},
 		{
 			"CSR",
 			"",
@@ -1257,6 +1432,30 @@ func TestBuildRequest(t *testing.T) {
 	}
 }
 
+func TestBuildRequestSPIFFEID(t *testing.T) {
+	t.Parallel()
+
+	var request, err = buildRequest(&requestValues{
+		san: sanValues{
+			uris:     "spiffe://example.org/old, https://example.org/",
+			spiffeID: "spiffe://example.org/ns/prod/sa/web",
+		},
+		privatekey: "testdata/rsa_priv.key",
+	})
+	if err != nil {
+		t.Fatalf("couldn't build request: %v", err)
+	}
+
+	var want = []*url.URL{
+		testhelpers.MustParseURI(t, "https://example.org/"),
+		testhelpers.MustParseURI(t, "spiffe://example.org/ns/prod/sa/web"),
+	}
+
+	if !reflect.DeepEqual(request.SAN.URIs, want) {
+		t.Errorf("got URIs %v, want %v", request.SAN.URIs, want)
+	}
+}
+
 func TestBuildRequestFailure(t *testing.T) {
 	t.Parallel()
 
@@ -1295,12 +1494,50 @@ func TestBuildRequestFailure(t *testing.T) {
 				},
 			},
 		},
+		{
+			"CheckDNSNames",
+			&requestValues{
+				san: sanValues{
+					dnsNames: "www.example.com.",
+					check:    true,
+				},
+			},
+		},
+		{
+			"CheckEmails",
+			&requestValues{
+				san: sanValues{
+					emails:      "John Doe <john@example.com>",
+					checkEmails: true,
+				},
+			},
+		},
+		{
+			"BadSPIFFEID",
+			&requestValues{
+				san: sanValues{
+					spiffeID: "spiffe://example.org/ns/prod?x=1",
+				},
+			},
+		},
 		{
 			"BadEKUs",
 			&requestValues{
 				ekus: ".",
 			},
 		},
+		{
+			"BadSigAlg",
+			&requestValues{
+				sigAlg: "DSA",
+			},
+		},
+		{
+			"BadSigHash",
+			&requestValues{
+				sigHash: "MD5",
+			},
+		},
 		{
 			"BadKey",
 			&requestValues{
@@ -1328,3 +1565,83 @@ func TestBuildRequestFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckRequestCAA(t *testing.T) {
+	t.Parallel()
+
+	var request = &hvclient.Request{SAN: &hvclient.SAN{DNSNames: []string{"example.com"}}}
+
+	// No lookups are made if no check is requested or there are no DNS names.
+	for _, tc := range []struct {
+		mode    string
+		request *hvclient.Request
+	}{
+		{"", request},
+		{"fail", &hvclient.Request{}},
+		{"warn", &hvclient.Request{SAN: &hvclient.SAN{}}},
+	} {
+		if err := checkRequestCAA(context.Background(), nil, tc.request, tc.mode); err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.mode, err)
+		}
+	}
+
+	if err := checkRequestCAA(context.Background(), nil, request, "ignore"); err == nil {
+		t.Errorf("unexpectedly accepted invalid mode")
+	}
+}
+
+func TestCheckRequestEmails(t *testing.T) {
+	t.Parallel()
+
+	var pol = &hvclient.Policy{
+		SAN: &hvclient.SANPolicy{
+			Emails: &hvclient.ListPolicy{
+				List:     []string{`@example\.com$`},
+				MaxCount: 2,
+			},
+		},
+	}
+
+	var testcases = []struct {
+		name    string
+		request *hvclient.Request
+		pol     *hvclient.Policy
+		ok      bool
+	}{
+		{
+			name:    "Valid",
+			request: &hvclient.Request{SAN: &hvclient.SAN{Emails: []string{"john@example.com"}}},
+			pol:     pol,
+			ok:      true,
+		},
+		{
+			name:    "NoEmails",
+			request: &hvclient.Request{},
+			pol:     pol,
+			ok:      true,
+		},
+		{
+			name:    "NoMatch",
+			request: &hvclient.Request{SAN: &hvclient.SAN{Emails: []string{"john@example.org"}}},
+			pol:     pol,
+		},
+		{
+			name:    "NotAllowed",
+			request: &hvclient.Request{SAN: &hvclient.SAN{Emails: []string{"john@example.com"}}},
+			pol:     &hvclient.Policy{},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var err = checkRequestEmails(tc.request, tc.pol)
+			if (err == nil) != tc.ok {
+				t.Fatalf("got error %v, want success %t", err, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/globalsign/hvclient"
+)
+
+// selftestClient is the subset of HVCA client operations exercised by the
+// self-test, abstracted to allow testing.
+type selftestClient interface {
+	Policy(ctx context.Context) (*hvclient.Policy, error)
+	TrustChain(ctx context.Context) ([]*x509.Certificate, error)
+	CounterCertsIssued(ctx context.Context) (int64, error)
+	CounterCertsRevoked(ctx context.Context) (int64, error)
+	QuotaIssuance(ctx context.Context) (int64, error)
+}
+
+// selftestResult is the outcome of a single self-test check.
+type selftestResult struct {
+	name    string
+	detail  string
+	err     error
+	skipped bool
+}
+
+// selftest logs in to HVCA with the specified configuration and exercises
+// each of the read-only API operations, outputting a pass/fail matrix. If a
+// template is specified, the request it contains is validated against the
+// validation policy without being submitted. An error is returned if any
+// check fails.
+func selftest(conf *hvclient.Config, template string) error {
+	var results []selftestResult
+
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var clnt, err = hvclient.NewClient(ctx, conf)
+	results = append(results, selftestResult{name: "login", err: err})
+
+	if err == nil {
+		defer clnt.Close()
+
+		results = append(results, runSelftest(clnt, template)...)
+	} else {
+		for _, name := range selftestChecks {
+			results = append(results, selftestResult{name: name, detail: "login failed", skipped: true})
+		}
+	}
+
+	return writeSelftestReport(os.Stdout, results)
+}
+
+// selftestChecks are the names of the checks performed by runSelftest, in
+// order.
+var selftestChecks = []string{"policy", "trust chain", "counters", "quota", "request validation"}
+
+// runSelftest performs each check after login, and returns the results.
+func runSelftest(clnt selftestClient, template string) []selftestResult {
+	var results = make([]selftestResult, 0, len(selftestChecks))
+
+	var run = func(name string, check func(ctx context.Context) (string, error)) {
+		var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		var detail, err = check(ctx)
+		results = append(results, selftestResult{name: name, detail: detail, err: err})
+	}
+
+	var pol *hvclient.Policy
+	run(selftestChecks[0], func(ctx context.Context) (string, error) {
+		var err error
+		if pol, err = clnt.Policy(ctx); err != nil {
+			return "", err
+		}
+
+		return "", nil
+	})
+
+	run(selftestChecks[1], func(ctx context.Context) (string, error) {
+		var certs, err = clnt.TrustChain(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%d certificates", len(certs)), nil
+	})
+
+	run(selftestChecks[2], func(ctx context.Context) (string, error) {
+		var issued, err = clnt.CounterCertsIssued(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		var revoked int64
+		if revoked, err = clnt.CounterCertsRevoked(ctx); err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%d issued, %d revoked", issued, revoked), nil
+	})
+
+	run(selftestChecks[3], func(ctx context.Context) (string, error) {
+		var remaining, err = clnt.QuotaIssuance(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%d remaining", remaining), nil
+	})
+
+	switch {
+	case template == "":
+		results = append(results, selftestResult{name: selftestChecks[4], detail: "no -template specified", skipped: true})
+
+	case pol == nil:
+		results = append(results, selftestResult{name: selftestChecks[4], detail: "no validation policy", skipped: true})
+
+	default:
+		var request, err = getRequestFromTemplateOrNew(template)
+		if err == nil {
+			err = pol.Validate(request)
+		}
+
+		results = append(results, selftestResult{name: selftestChecks[4], err: err})
+	}
+
+	return results
+}
+
+// writeSelftestReport writes a table of self-test results, and returns an
+// error if any check failed.
+func writeSelftestReport(w io.Writer, results []selftestResult) error {
+	var width = len("CHECK")
+	for _, result := range results {
+		if len(result.name) > width {
+			width = len(result.name)
+		}
+	}
+
+	var rows = [][3]string{{"CHECK", "RESULT", "DETAIL"}}
+	var failed int
+
+	for _, result := range results {
+		var status, detail = "PASS", result.detail
+
+		switch {
+		case result.skipped:
+			status = "SKIP"
+
+		case result.err != nil:
+			status, detail = "FAIL", result.err.Error()
+			failed++
+		}
+
+		rows = append(rows, [3]string{result.name, status, detail})
+	}
+
+	for _, row := range rows {
+		var line = fmt.Sprintf("%-*s  %-6s  %s", width, row[0], row[1], row[2])
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+)
+
+// fakeSelftestClient returns fixed results for each self-test check.
+type fakeSelftestClient struct {
+	policy *hvclient.Policy
+	err    error
+}
+
+func (c fakeSelftestClient) Policy(ctx context.Context) (*hvclient.Policy, error) {
+	return c.policy, c.err
+}
+
+func (c fakeSelftestClient) TrustChain(ctx context.Context) ([]*x509.Certificate, error) {
+	return []*x509.Certificate{{}, {}}, c.err
+}
+
+func (c fakeSelftestClient) CounterCertsIssued(ctx context.Context) (int64, error) {
+	return 12, c.err
+}
+
+func (c fakeSelftestClient) CounterCertsRevoked(ctx context.Context) (int64, error) {
+	return 3, c.err
+}
+
+func (c fakeSelftestClient) QuotaIssuance(ctx context.Context) (int64, error) {
+	return 88, c.err
+}
+
+func TestRunSelftest(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name     string
+		clnt     fakeSelftestClient
+		template string
+		want     string
+		ok       bool
+	}{
+		{
+			name: "NoTemplate",
+			clnt: fakeSelftestClient{policy: &hvclient.Policy{}},
+			want: `CHECK               RESULT  DETAIL
+policy              PASS
+trust chain         PASS    2 certificates
+counters            PASS    12 issued, 3 revoked
+quota               PASS    88 remaining
+request validation  SKIP    no -template specified
+`,
+			ok: true,
+		},
+		{
+			name: "ValidTemplate",
+			clnt: fakeSelftestClient{
+				policy: &hvclient.Policy{
+					SubjectDN: &hvclient.SubjectDNPolicy{
+						Organization: &hvclient.StringPolicy{Presence: hvclient.Optional},
+					},
+				},
+			},
+			template: "testdata/test.tmpl",
+			want: `CHECK               RESULT  DETAIL
+policy              PASS
+trust chain         PASS    2 certificates
+counters            PASS    12 issued, 3 revoked
+quota               PASS    88 remaining
+request validation  PASS
+`,
+			ok: true,
+		},
+		{
+			name:     "InvalidTemplate",
+			clnt:     fakeSelftestClient{policy: &hvclient.Policy{}},
+			template: "testdata/test.tmpl",
+			want: `CHECK               RESULT  DETAIL
+policy              PASS
+trust chain         PASS    2 certificates
+counters            PASS    12 issued, 3 revoked
+quota               PASS    88 remaining
+request validation  FAIL    invalid subject organization: value not allowed by policy
+`,
+		},
+		{
+			name:     "Forbidden",
+			clnt:     fakeSelftestClient{err: errors.New("403 forbidden")},
+			template: "testdata/test.tmpl",
+			want: `CHECK               RESULT  DETAIL
+policy              FAIL    403 forbidden
+trust chain         FAIL    403 forbidden
+counters            FAIL    403 forbidden
+quota               FAIL    403 forbidden
+request validation  SKIP    no validation policy
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			var err = writeSelftestReport(&buf, runSelftest(tc.clnt, tc.template))
+			if (err == nil) != tc.ok {
+				t.Fatalf("got error %v, want success %t", err, tc.ok)
+			}
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteSelftestReportSkippedLogin(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	var err = writeSelftestReport(&buf, []selftestResult{
+		{name: "login", err: errors.New("bad credentials")},
+		{name: "policy", detail: "login failed", skipped: true},
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2") {
+		t.Fatalf("got error %v, want 1 of 2 checks failed", err)
+	}
+}
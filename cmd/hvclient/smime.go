@@ -0,0 +1,61 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+// smimeCert generates a new private key, requests an S/MIME certificate for
+// the specified email address and name, and writes the certificate, the
+// private key and the trust chain to the PKCS#12 file specified at the
+// command line for import into a mail client. The certificate is also
+// output. The password is taken from the command line, or prompted for
+// before the request is made if not specified.
+func smimeCert(clnt *hvclient.Client, email, name string) error {
+	if email == "" {
+		return errors.New("-smime requires an email address to be specified with -email")
+	}
+
+	if *fP12Out == "" {
+		return errors.New("-smime requires a PKCS#12 output file to be specified with -p12out")
+	}
+
+	var password = *fP12Pass
+	if password == "" {
+		var err error
+		if password, err = getPasswordFromTerminal("Enter passphrase to protect PKCS#12 file", true); err != nil {
+			return err
+		}
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cert, err = clnt.IssueSMIME(ctx, email, name)
+	if err != nil {
+		return fmt.Errorf("couldn't obtain S/MIME certificate: %v", err)
+	}
+
+	fmt.Printf("%s", pki.CertToPEMString(cert.Certificate))
+
+	return writePKCS12(*fP12Out, cert.PrivateKey, cert.Certificate, cert.Chain, password)
+}
@@ -17,45 +17,56 @@ package main
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
-	"unicode"
+
+	"github.com/globalsign/hvclient"
 )
 
-// parseTimeWindow takes two strings representing from- and to-times in
-// 2006-01-02T15:04:05MST layout, and returns two time.Time objects
+// windowTimeLayouts are the layouts accepted for absolute -from and -to
+// times, in addition to the default layout.
+var windowTimeLayouts = []string{
+	defaultTimeLayout,
+	time.RFC3339,
+	"2006-01-02",
+}
+
+// parseTimeWindow takes two strings representing from- and to-times, as
+// accepted by parseWindowTime, and returns two time.Time objects
 // representing those two times. If the strings are empty, then defaults
 // representing a 30-day time period to the current moment are returned.
 func parseTimeWindow(from, to, since string) (time.Time, time.Time, error) {
 	var timeFrom, timeTo time.Time
 	var err error
 
+	var now = time.Now()
+
 	// Set to-time to now if -to flag was not specified (since now is
 	// the default) or if -since flag was specified (as the -since flag
 	// always denotes a period of time up to the present time). Parsing
 	// of command-line arguments will prevent both -to and -since from
 	// being specified.
 	if to != "" && since == "" {
-		if timeTo, err = time.Parse(defaultTimeLayout, to); err != nil {
-			return timeTo, timeFrom, fmt.Errorf("couldn't parse 'to' time string: %v", err)
+		if timeTo, err = parseWindowTime(to, now); err != nil {
+			return timeTo, timeFrom, fmt.Errorf("couldn't parse -to time: %v", err)
 		}
 	} else {
-		timeTo = time.Now()
+		timeTo = now
 	}
 
 	if from != "" {
 		// -from flag was specified, so calculate it.
-		if timeFrom, err = time.Parse(defaultTimeLayout, from); err != nil {
-			return timeTo, timeFrom, fmt.Errorf("couldn't parse 'from' time string: %v", err)
+		if timeFrom, err = parseWindowTime(from, now); err != nil {
+			return timeTo, timeFrom, fmt.Errorf("couldn't parse -from time: %v", err)
 		}
 	} else if since != "" {
 
 		// -since flag was specified, so set from-time to the to-time less
-		// the since duration.
-		var duration, err = parseDuration(since)
+		// the since duration. A leading minus sign is accepted, since the
+		// duration always reaches into the past.
+		var duration, err = hvclient.ParseDuration(strings.TrimPrefix(since, "-"))
 		if err != nil {
-			return timeTo, timeFrom, err
+			return timeTo, timeFrom, fmt.Errorf("couldn't parse -since duration: %v", err)
 		}
 
 		timeFrom = timeTo.Add(duration * -1)
@@ -68,37 +79,29 @@ func parseTimeWindow(from, to, since string) (time.Time, time.Time, error) {
 	return timeFrom, timeTo, nil
 }
 
-func parseDuration(d string) (time.Duration, error) {
-	// Break string into duration value and units.
-	var n string
-	var unit string
-	for i := 0; i < len(d); i++ {
-		if !unicode.IsDigit(rune(d[i])) {
-			n = d[:i]
-			unit = d[i:]
-			break
+// parseWindowTime parses a -from or -to time, which may be in the default
+// layout, in RFC 3339 layout, a date alone in UTC, or a duration relative to
+// the specified current time with a leading sign, such as -30d or +1w.
+func parseWindowTime(s string, now time.Time) (time.Time, error) {
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		var duration, err = hvclient.ParseDuration(s[1:])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time %q: %v", s, err)
+		}
+
+		if s[0] == '-' {
+			duration = -duration
 		}
-	}
 
-	// Parse duration value.
-	var extent, err = strconv.ParseInt(n, 0, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid duration quantity: %s", n)
+		return now.Add(duration), nil
 	}
 
-	// Parse units.
-	switch strings.ToUpper(unit) {
-	case "S", "SEC", "SECS", "SECOND", "SECONDS":
-		return time.Second * time.Duration(extent), nil
-	case "M", "MIN", "MINS", "MINUTE", "MINUTES":
-		return time.Minute * time.Duration(extent), nil
-	case "H", "HR", "HRS", "HOUR", "HOURS":
-		return time.Hour * time.Duration(extent), nil
-	case "D", "DAY", "DAYS":
-		return time.Hour * time.Duration(extent) * 24, nil
-	case "W", "WK", "WKS", "WEEK", "WEEKS":
-		return time.Hour * time.Duration(extent) * 24 * 7, nil
+	for _, layout := range windowTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
 	}
 
-	return 0, fmt.Errorf("invalid duration unit: %s", unit)
+	return time.Time{}, fmt.Errorf("invalid time %q: use the layout %s, a date such as 2006-01-02, or a relative duration such as -30d or +1w",
+		s, defaultTimeLayout)
 }
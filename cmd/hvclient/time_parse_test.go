@@ -16,6 +16,7 @@ limitations under the License.
 package main
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -58,6 +59,19 @@ func TestParseTimeWindow(t *testing.T) {
 			wantfrom: time.Now().Add(time.Hour * 24 * -10),
 			wantto:   time.Now(),
 		},
+		{
+			name:     "SinceNegative",
+			since:    "-60s",
+			wantfrom: time.Now().Add(time.Second * -60),
+			wantto:   time.Now(),
+		},
+		{
+			name:     "Dates",
+			from:     "2024-01-01",
+			to:       "2024-01-31",
+			wantfrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			wantto:   time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		},
 	}
 
 	for _, tc := range testcases {
@@ -115,6 +129,14 @@ func TestParseTimeWindowFailure(t *testing.T) {
 			name:  "BadSince",
 			since: "not a duration",
 		},
+		{
+			name: "BadRelative",
+			from: "-30x",
+		},
+		{
+			name: "NoUnit",
+			from: "-30",
+		},
 	}
 
 	for _, tc := range testcases {
@@ -130,99 +152,70 @@ func TestParseTimeWindowFailure(t *testing.T) {
 	}
 }
 
-func TestTimeParse(t *testing.T) {
+func TestParseWindowTime(t *testing.T) {
 	t.Parallel()
 
+	var now = time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
 	var testcases = []struct {
-		str  string
-		want time.Duration
+		value string
+		want  time.Time
 	}{
-		{"1s", time.Second * 1},
-		{"2S", time.Second * 2},
-		{"3sec", time.Second * 3},
-		{"4SEC", time.Second * 4},
-		{"5secs", time.Second * 5},
-		{"6SECS", time.Second * 6},
-		{"7second", time.Second * 7},
-		{"8SECOND", time.Second * 8},
-		{"9seconds", time.Second * 9},
-		{"10SECONDS", time.Second * 10},
-		{"1m", time.Minute * 1},
-		{"2M", time.Minute * 2},
-		{"3min", time.Minute * 3},
-		{"4MIN", time.Minute * 4},
-		{"5mins", time.Minute * 5},
-		{"6MINS", time.Minute * 6},
-		{"7minute", time.Minute * 7},
-		{"8MINUTE", time.Minute * 8},
-		{"9minutes", time.Minute * 9},
-		{"10MINUTES", time.Minute * 10},
-		{"1h", time.Hour * 1},
-		{"2H", time.Hour * 2},
-		{"3hr", time.Hour * 3},
-		{"4HR", time.Hour * 4},
-		{"5hrs", time.Hour * 5},
-		{"6HRS", time.Hour * 6},
-		{"7hour", time.Hour * 7},
-		{"8HOUR", time.Hour * 8},
-		{"9hours", time.Hour * 9},
-		{"10HOURS", time.Hour * 10},
-		{"1d", time.Hour * 24 * 1},
-		{"2D", time.Hour * 24 * 2},
-		{"3day", time.Hour * 24 * 3},
-		{"4DAY", time.Hour * 24 * 4},
-		{"5days", time.Hour * 24 * 5},
-		{"6DAYS", time.Hour * 24 * 6},
-		{"1w", time.Hour * 24 * 7 * 1},
-		{"2W", time.Hour * 24 * 7 * 2},
-		{"3wk", time.Hour * 24 * 7 * 3},
-		{"4WK", time.Hour * 24 * 7 * 4},
-		{"5wks", time.Hour * 24 * 7 * 5},
-		{"6WKS", time.Hour * 24 * 7 * 6},
-		{"7week", time.Hour * 24 * 7 * 7},
-		{"8WEEK", time.Hour * 24 * 7 * 8},
-		{"9weeks", time.Hour * 24 * 7 * 9},
-		{"10WEEKS", time.Hour * 24 * 7 * 10},
+		{"2024-01-02T15:04:05UTC", time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2024-01-02T15:04:05Z", time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"-30d", now.Add(time.Hour * 24 * -30)},
+		{"+1w", now.Add(time.Hour * 24 * 7)},
+		{"-2hours", now.Add(time.Hour * -2)},
 	}
 
 	for _, tc := range testcases {
 		var tc = tc
 
-		t.Run(tc.str, func(t *testing.T) {
+		t.Run(tc.value, func(t *testing.T) {
 			t.Parallel()
 
-			var got, err = parseDuration(tc.str)
+			var got, err = parseWindowTime(tc.value, now)
 			if err != nil {
-				t.Fatalf("couldn't parse duration: %v", err)
+				t.Fatalf("couldn't parse time: %v", err)
 			}
 
-			if got != tc.want {
+			if !got.Equal(tc.want) {
 				t.Errorf("got %v, want %v", got, tc.want)
 			}
 		})
 	}
 }
 
-func TestTimeParseFailure(t *testing.T) {
+func TestParseWindowTimeFailure(t *testing.T) {
 	t.Parallel()
 
-	var testcases = []string{
-		"5",
-		"s",
-		"s5",
-		"5x",
-		"5 s",
-		"word",
+	var testcases = []struct {
+		value string
+		want  string
+	}{
+		{"", `invalid time ""`},
+		{"2024-13-01", `invalid time "2024-13-01"`},
+		{"30d", `invalid time "30d"`},
+		{"-30", `missing duration unit in "30"`},
+		{"-30x", `invalid duration unit "x"`},
+		{"-d", `invalid duration quantity in "d"`},
+		{"+", "missing duration"},
 	}
 
 	for _, tc := range testcases {
 		var tc = tc
 
-		t.Run(tc, func(t *testing.T) {
+		t.Run(tc.value, func(t *testing.T) {
 			t.Parallel()
 
-			if _, err := parseDuration(tc); err == nil {
-				t.Errorf("unexpectedly parsed duration")
+			var _, err = parseWindowTime(tc.value, time.Now())
+			if err == nil {
+				t.Fatalf("unexpectedly parsed time")
+			}
+
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got error %q, want it to contain %q", err, tc.want)
 			}
 		})
 	}
@@ -16,17 +16,23 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/x509"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/fileutils"
 	"github.com/globalsign/hvclient/internal/pki"
 )
 
 // trustChain outputs the chain of trust for the certificates issued
-// by the calling account, in PEM format.
-func trustChain(clnt *hvclient.Client) {
+// by the calling account, in the specified format.
+func trustChain(clnt *hvclient.Client, format, out string) {
 	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
@@ -35,7 +41,77 @@ func trustChain(clnt *hvclient.Client) {
 		log.Fatalf("%v", err)
 	}
 
-	for _, cert := range certs {
-		fmt.Printf("%s", pki.CertToPEMString(cert))
+	if err = writeTrustChain(os.Stdout, certs, format, out); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+// writeTrustChain writes a chain of certificates in the specified format.
+// The pem and p7b formats are written to the file specified by out, or to w
+// if out is empty. The pemfiles and der formats write each certificate to a
+// separate file, named by appending a sequence number and an extension to
+// out, which is required.
+func writeTrustChain(w io.Writer, certs []*x509.Certificate, format, out string) error {
+	switch format = strings.ToLower(format); format {
+	case "", "pem":
+		var buf bytes.Buffer
+		for _, cert := range certs {
+			buf.WriteString(pki.CertToPEMString(cert))
+		}
+
+		return writeOutput(w, out, buf.Bytes())
+
+	case "p7b":
+		var der, err = pki.CertsToPKCS7(certs)
+		if err != nil {
+			return fmt.Errorf("couldn't encode PKCS#7: %w", err)
+		}
+
+		return writeOutput(w, out, der)
+
+	case "pemfiles", "der":
+		if out == "" {
+			return fmt.Errorf("you must specify -out with -format %s", format)
+		}
+
+		// Hold a single lock while writing all the files, so that concurrent
+		// invocations never leave a mixture of old and new chains.
+		var lock, err = fileutils.LockFile(out + fileutils.LockSuffix)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock()
+
+		for i, cert := range certs {
+			var data, ext = cert.Raw, ".der"
+			if format == "pemfiles" {
+				data, ext = []byte(pki.CertToPEMString(cert)), ".pem"
+			}
+
+			var filename = fmt.Sprintf("%s-%d%s", out, i+1, ext)
+			if err := fileutils.WriteFile(filename, data, 0644); err != nil {
+				return fmt.Errorf("couldn't write certificate: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown trust chain format: %s", format)
+}
+
+// writeOutput writes data to the specified file, or to w if the filename is
+// empty. The file is replaced atomically while holding an advisory lock.
+func writeOutput(w io.Writer, filename string, data []byte) error {
+	if filename == "" {
+		var _, err = w.Write(data)
+
+		return err
 	}
+
+	if err := fileutils.WriteFileLocked(filename, data, 0644); err != nil {
+		return fmt.Errorf("couldn't write output: %w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,143 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/globalsign/hvclient/internal/pki"
+	"github.com/globalsign/hvclient/internal/testhelpers"
+)
+
+func TestWriteTrustChain(t *testing.T) {
+	t.Parallel()
+
+	var root = testhelpers.MustGetCertFromFile(t, "../../testdata/test_root_cert.pem")
+	var ica = testhelpers.MustGetCertFromFile(t, "../../testdata/test_ica_cert.pem")
+	var certs = []*x509.Certificate{ica, root}
+
+	var p7b, err = pki.CertsToPKCS7(certs)
+	if err != nil {
+		t.Fatalf("couldn't encode PKCS#7: %v", err)
+	}
+
+	var bundle = pki.CertToPEMString(ica) + pki.CertToPEMString(root)
+
+	var testcases = []struct {
+		format string
+		want   []byte
+	}{
+		{"", []byte(bundle)},
+		{"PEM", []byte(bundle)},
+		{"p7b", p7b},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			if err := writeTrustChain(&buf, certs, tc.format, ""); err != nil {
+				t.Fatalf("couldn't write trust chain: %v", err)
+			}
+
+			if !bytes.Equal(buf.Bytes(), tc.want) {
+				t.Errorf("got %q, want %q", buf.Bytes(), tc.want)
+			}
+
+			var out = filepath.Join(t.TempDir(), "chain")
+			if err := writeTrustChain(&buf, certs, tc.format, out); err != nil {
+				t.Fatalf("couldn't write trust chain to file: %v", err)
+			}
+
+			if got := testhelpers.MustReadFile(t, out); !bytes.Equal(got, tc.want) {
+				t.Errorf("got file contents %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteTrustChainFiles(t *testing.T) {
+	t.Parallel()
+
+	var root = testhelpers.MustGetCertFromFile(t, "../../testdata/test_root_cert.pem")
+	var ica = testhelpers.MustGetCertFromFile(t, "../../testdata/test_ica_cert.pem")
+	var certs = []*x509.Certificate{ica, root}
+
+	var testcases = []struct {
+		format string
+		ext    string
+		encode func(*x509.Certificate) []byte
+	}{
+		{"pemfiles", ".pem", func(c *x509.Certificate) []byte { return []byte(pki.CertToPEMString(c)) }},
+		{"der", ".der", func(c *x509.Certificate) []byte { return c.Raw }},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+
+			var out = filepath.Join(t.TempDir(), "hvca")
+			if err := writeTrustChain(ioutil.Discard, certs, tc.format, out); err != nil {
+				t.Fatalf("couldn't write trust chain: %v", err)
+			}
+
+			for i, cert := range certs {
+				var filename = fmt.Sprintf("%s-%d%s", out, i+1, tc.ext)
+				if got := testhelpers.MustReadFile(t, filename); !bytes.Equal(got, tc.encode(cert)) {
+					t.Errorf("got unexpected contents in %s", filename)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteTrustChainFailure(t *testing.T) {
+	t.Parallel()
+
+	var certs = []*x509.Certificate{testhelpers.MustGetCertFromFile(t, "../../testdata/test_root_cert.pem")}
+
+	var testcases = []struct {
+		format string
+		out    string
+	}{
+		{"pemfiles", ""},
+		{"der", ""},
+		{"jks", ""},
+		{"pem", filepath.Join("no", "such", "directory", "chain.pem")},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+
+			if err := writeTrustChain(ioutil.Discard, certs, tc.format, tc.out); err == nil {
+				t.Fatalf("unexpectedly wrote trust chain")
+			}
+		})
+	}
+}
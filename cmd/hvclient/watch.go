@@ -0,0 +1,95 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/fileutils"
+	"github.com/globalsign/hvclient/metrics"
+	"github.com/globalsign/hvclient/watch"
+)
+
+// watchCert watches the certificate in the specified file and, each time it
+// reaches its renewal deadline, requests a replacement built from the
+// command line options and writes it to the file, until interrupted. If
+// collector is not nil, the number of watched certificates and the result
+// of each renewal are recorded in it.
+func watchCert(clnt *hvclient.Client, path string, interval time.Duration, collector *metrics.Collector) error {
+	var renew = watch.ClientRenewer(
+		clnt,
+		func(string, *x509.Certificate) (*hvclient.Request, error) {
+			return requestFromFlags(clnt)
+		},
+		func(_ string, info *hvclient.CertInfo) error {
+			return fileutils.WriteFile(path, []byte(info.PEM), 0644)
+		},
+	)
+
+	var watcher, err = watch.New(watch.Config{
+		Renew: func(ctx context.Context, name string, cert *x509.Certificate) (*x509.Certificate, error) {
+			var renewCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			var renewed, err = renew(renewCtx, name, cert)
+			if collector != nil {
+				collector.RecordRenewal(err)
+			}
+
+			if err == nil {
+				fmt.Fprintf(os.Stderr, "%s: renewed, new serial number %X\n", name, renewed.SerialNumber)
+			}
+
+			return renewed, err
+		},
+		Interval: interval,
+		OnError: func(name string, err error) {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Fail at once if the certificate can't be read, rather than reporting
+	// the same error at every check.
+	watcher.AddFile(path, path)
+
+	if _, err = watcher.Certificate(path); err != nil {
+		return err
+	}
+
+	if collector != nil {
+		collector.SetManaged(1)
+	}
+
+	// Run until interrupted.
+	var ctx, cancel = signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err = watcher.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return nil
+}
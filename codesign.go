@@ -0,0 +1,264 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+// OIDCodeSigning is the extended key usage OID for code signing, which code
+// signing certificates must include. See RFC 5280 4.2.1.12.
+var OIDCodeSigning = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 3}
+
+// ErrKeyNotProtected is wrapped by errors returned by IssueCodeSigning when
+// the private key is held in a file which is accessible to users other than
+// its owner.
+var ErrKeyNotProtected = errors.New("private key not protected")
+
+// CodeSigningOptions are the options for IssueCodeSigning. Exactly one of
+// Signer and KeyFile must be specified.
+type CodeSigningOptions struct {
+	// Subject is the subject distinguished name of the certificate, usually
+	// including the name of the organization which publishes the software.
+	// Values fixed by the validation policy are filled in.
+	Subject *DN
+
+	// Signer is the private key to certify, such as one held in a hardware
+	// security module, as the CA/Browser Forum code signing requirements
+	// expect.
+	Signer crypto.Signer
+
+	// KeyFile is the path of a PEM-encoded file holding the private key to
+	// certify. If the file does not exist, a new private key of a type and
+	// length allowed by the validation policy is generated and written to
+	// it, readable and writable only by its owner. An existing file must
+	// not be accessible to users other than its owner, except on Windows,
+	// where file permissions are not checked.
+	KeyFile string
+
+	// KeyPassword is the password with which an existing encrypted key file
+	// is decrypted.
+	KeyPassword string
+
+	// Timestamp causes a sample signature made with the private key to be
+	// timestamped once the certificate is issued, to check that the account
+	// is licensed for timestamping and that the key can sign.
+	Timestamp bool
+}
+
+// CodeSigningCertificate is an issued code signing certificate together
+// with its private key and the chain of trust for it.
+type CodeSigningCertificate struct {
+	Signer      crypto.Signer
+	Certificate *x509.Certificate
+	Chain       []*x509.Certificate
+
+	// Timestamp is the timestamp token for the SHA-256 digest of the sample
+	// signature, if one was requested.
+	Timestamp *TimestampToken
+}
+
+// IssueCodeSigning requests a code signing certificate for a private key
+// which is held either by a crypto.Signer or in a file protected by its
+// permissions, as the CA/Browser Forum code signing requirements expect,
+// and waits for the certificate to be issued. The key must be allowed by
+// the validation policy. The request includes the code signing extended key
+// usage and asks for the maximum validity period allowed by the validation
+// policy, values fixed by the policy are filled in, and a PKCS#10
+// certificate signing request is submitted if the policy requires one.
+func (c *Client) IssueCodeSigning(ctx context.Context, opts CodeSigningOptions) (*CodeSigningCertificate, error) {
+	switch {
+	case opts.Signer == nil && opts.KeyFile == "":
+		return nil, errors.New("no signer or key file specified")
+
+	case opts.Signer != nil && opts.KeyFile != "":
+		return nil, errors.New("only one of signer and key file may be specified")
+	}
+
+	var pol, err = c.Policy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't retrieve validation policy: %w", err)
+	}
+
+	var signer = opts.Signer
+	if signer == nil {
+		if signer, err = loadOrGenerateKeyFile(opts.KeyFile, opts.KeyPassword, pol.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = pol.PublicKey.Validate(signer.Public()); err != nil {
+		return nil, err
+	}
+
+	var req = &Request{
+		Validity: &Validity{
+			NotBefore: c.now(),
+			NotAfter:  time.Unix(0, 0),
+		},
+		EKUs:       []asn1.ObjectIdentifier{OIDCodeSigning},
+		PrivateKey: signer,
+	}
+
+	if opts.Subject != nil {
+		var subject = *opts.Subject
+		req.Subject = &subject
+	}
+
+	if err = req.ApplyPolicyDefaults(pol); err != nil {
+		return nil, fmt.Errorf("couldn't apply validation policy defaults: %w", err)
+	}
+
+	if pol.PublicKey != nil && pol.PublicKey.KeyFormat == PKCS10 {
+		if req.CSR, err = req.PKCS10(); err != nil {
+			return nil, fmt.Errorf("couldn't generate PKCS#10 request: %w", err)
+		}
+
+		req.PrivateKey = nil
+	}
+
+	var serial *big.Int
+	if serial, err = c.CertificateRequest(ctx, req); err != nil {
+		return nil, fmt.Errorf("couldn't request certificate: %w", err)
+	}
+
+	var info *CertInfo
+	if info, err = c.CertificateRetrieve(ctx, serial); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve certificate %X: %w", serial, err)
+	}
+
+	if info.X509 == nil {
+		return nil, fmt.Errorf("no certificate returned for serial number %X", serial)
+	}
+
+	var result = &CodeSigningCertificate{
+		Signer:      signer,
+		Certificate: info.X509,
+	}
+
+	if result.Chain, err = c.TrustChain(ctx); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve trust chain: %w", err)
+	}
+
+	if opts.Timestamp {
+		if result.Timestamp, err = c.timestampSample(ctx, signer, info.X509); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// timestampSample signs the SHA-256 digest of a certificate with a private
+// key, and requests a timestamp token for the SHA-256 digest of the
+// signature.
+func (c *Client) timestampSample(ctx context.Context, signer crypto.Signer, cert *x509.Certificate) (*TimestampToken, error) {
+	var digest = sha256.Sum256(cert.Raw)
+
+	var sig, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't make sample signature: %w", err)
+	}
+
+	digest = sha256.Sum256(sig)
+
+	var token *TimestampToken
+	if token, err = c.Timestamp(ctx, digest[:], crypto.SHA256); err != nil {
+		return nil, fmt.Errorf("couldn't timestamp sample signature: %w", err)
+	}
+
+	return token, nil
+}
+
+// loadOrGenerateKeyFile reads the private key from a PEM-encoded file after
+// checking that it is accessible only to its owner or, if the file does not
+// exist, generates a new private key allowed by the public key policy and
+// writes it to a new file accessible only to its owner.
+func loadOrGenerateKeyFile(path, password string, pol *PublicKeyPolicy) (crypto.Signer, error) {
+	var fi, err = os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return generateKeyFile(path, pol)
+	} else if err != nil {
+		return nil, fmt.Errorf("couldn't read private key file: %w", err)
+	}
+
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("private key file %s is not a regular file", path)
+	}
+
+	if perm := fi.Mode().Perm(); runtime.GOOS != "windows" && perm&0077 != 0 {
+		return nil, fmt.Errorf("%w: %s has permissions %04o, want 0600 or stricter", ErrKeyNotProtected, path, perm)
+	}
+
+	var key crypto.PrivateKey
+	if key, err = pki.PrivateKeyFromFileWithPassword(path, password); err != nil {
+		return nil, fmt.Errorf("couldn't read private key file: %w", err)
+	}
+
+	var signer, ok = key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	return signer, nil
+}
+
+// generateKeyFile generates a new private key allowed by the public key
+// policy, and writes it to a new PEM-encoded file which is readable and
+// writable only by its owner. The file must not already exist.
+func generateKeyFile(path string, pol *PublicKeyPolicy) (crypto.Signer, error) {
+	var key, err = newPolicyKey(pol)
+	if err != nil {
+		return nil, err
+	}
+
+	var der []byte
+	if der, err = x509.MarshalPKCS8PrivateKey(key); err != nil {
+		return nil, fmt.Errorf("couldn't marshal private key: %w", err)
+	}
+
+	var f *os.File
+	if f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600); err != nil {
+		return nil, fmt.Errorf("couldn't create private key file: %w", err)
+	}
+
+	if err = pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("couldn't write private key file: %w", err)
+	}
+
+	if err = f.Close(); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("couldn't write private key file: %w", err)
+	}
+
+	return key, nil
+}
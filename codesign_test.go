@@ -0,0 +1,167 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/testhelpers"
+)
+
+func TestClientMockIssueCodeSigning(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var path = filepath.Join(t.TempDir(), "codesign.key")
+	var opts = hvclient.CodeSigningOptions{
+		Subject: &hvclient.DN{CommonName: "Example Software Ltd"},
+		KeyFile: path,
+	}
+
+	var got, err = client.IssueCodeSigning(ctx, opts)
+	if err != nil {
+		t.Fatalf("couldn't issue code signing certificate: %v", err)
+	}
+
+	if !got.Certificate.Equal(mockCert) {
+		t.Errorf("got certificate with serial number %X, want %X",
+			got.Certificate.SerialNumber, mockCert.SerialNumber)
+	}
+
+	if len(got.Chain) != len(mockTrustChainCerts) {
+		t.Errorf("got %d chain certificates, want %d", len(got.Chain), len(mockTrustChainCerts))
+	}
+
+	if got.Timestamp != nil {
+		t.Errorf("got timestamp token when none requested")
+	}
+
+	var fi os.FileInfo
+	if fi, err = os.Stat(path); err != nil {
+		t.Fatalf("couldn't stat key file: %v", err)
+	}
+
+	if perm := fi.Mode().Perm(); runtime.GOOS != "windows" && perm != 0600 {
+		t.Errorf("got key file permissions %04o, want 0600", perm)
+	}
+
+	// The key generated by the first request is used by the second.
+	var again *hvclient.CodeSigningCertificate
+	if again, err = client.IssueCodeSigning(ctx, opts); err != nil {
+		t.Fatalf("couldn't issue code signing certificate with existing key: %v", err)
+	}
+
+	var pub, ok = got.Signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(again.Signer.Public()) {
+		t.Errorf("existing key file not used")
+	}
+}
+
+func TestClientMockIssueCodeSigningFailure(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("couldn't generate key: %v", err)
+	}
+
+	var rsaKey = testhelpers.MustGetPrivateKeyFromFile(t, "testdata/rsa_priv.key")
+
+	var data []byte
+	if data, err = ioutil.ReadFile("testdata/ec_priv.key"); err != nil {
+		t.Fatalf("couldn't read key file: %v", err)
+	}
+
+	var dir = t.TempDir()
+
+	var loosePath = filepath.Join(dir, "loose.key")
+	if err = ioutil.WriteFile(loosePath, data, 0644); err != nil {
+		t.Fatalf("couldn't write key file: %v", err)
+	}
+
+	if err = os.Chmod(loosePath, 0644); err != nil {
+		t.Fatalf("couldn't change key file permissions: %v", err)
+	}
+
+	var testcases = []struct {
+		name string
+		opts hvclient.CodeSigningOptions
+		want error
+	}{
+		{
+			name: "NoKey",
+		},
+		{
+			name: "SignerAndKeyFile",
+			opts: hvclient.CodeSigningOptions{Signer: ecKey, KeyFile: filepath.Join(dir, "new.key")},
+		},
+		{
+			name: "NotAllowedByPolicy",
+			opts: hvclient.CodeSigningOptions{Signer: rsaKey.(crypto.Signer)},
+			want: hvclient.ErrPublicKeyNotAllowed,
+		},
+	}
+
+	if runtime.GOOS != "windows" {
+		testcases = append(testcases, struct {
+			name string
+			opts hvclient.CodeSigningOptions
+			want error
+		}{
+			name: "KeyFileNotProtected",
+			opts: hvclient.CodeSigningOptions{KeyFile: loosePath},
+			want: hvclient.ErrKeyNotProtected,
+		})
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			var _, err = client.IssueCodeSigning(ctx, tc.opts)
+			if err == nil {
+				t.Fatalf("unexpectedly issued code signing certificate")
+			}
+
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Errorf("got error %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -20,10 +20,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"net/url"
-	"path/filepath"
+	"path"
+	"strconv"
+	"strings"
 	"time"
 
+	"golang.org/x/net/http/httpproxy"
+
 	"github.com/globalsign/hvclient/internal/config"
 	"github.com/globalsign/hvclient/internal/pki"
 )
@@ -31,6 +38,8 @@ import (
 // Config is a configuration object for an HVCA client.
 type Config struct {
 	// URL is the URL of the HVCA service, including any version number.
+	// The URL may include a port and an arbitrary base path, such as a prefix
+	// added by an API gateway, but not a query or a fragment.
 	URL string
 
 	// version is the major version number of the HVCA service located at
@@ -42,6 +51,10 @@ type Config struct {
 	// url is a parsed form of the URL.
 	url *url.URL
 
+	// fromToken is true if the configuration is for a client created with
+	// NewClientFromToken, which needs no API key or API secret.
+	fromToken bool
+
 	// TLSCert is the certificate to use for mutual TLS authentication to HVCA,
 	// provided by GlobalSign when the HVCA account was set up.
 	TLSCert *x509.Certificate
@@ -64,10 +77,31 @@ type Config struct {
 	// server certificate. If nil, the system pool will be used.
 	TLSRoots *x509.CertPool
 
+	// TLSRootsFile is the path to a file containing one or more PEM-encoded
+	// root certificates used to validate HVCA's TLS server certificate. It
+	// is an alternative to TLSRoots, and at most one of the two may be set.
+	TLSRootsFile string
+
+	// HTTPProxy is the URL of a proxy server to use for connections to HVCA.
+	// If this is omitted, any proxy specified by the HTTP_PROXY, HTTPS_PROXY
+	// and NO_PROXY environment variables will be used.
+	HTTPProxy string
+
+	// NoProxy is a comma-separated list of host names, domain names, IP
+	// addresses and CIDR ranges for which no proxy should be used, in the
+	// same format as the NO_PROXY environment variable. If this is omitted,
+	// the value of the NO_PROXY environment variable will be used.
+	NoProxy string
+
 	// ExtraHeaders contains custom HTTP request headers to be passed to the
 	// HVCA server with each request.
 	ExtraHeaders map[string]string
 
+	// UserAgent is the value of the User-Agent header sent with each HVCA
+	// request, overriding any value in ExtraHeaders. If this is omitted, the
+	// default of the Go HTTP client is used.
+	UserAgent string
+
 	// If InsecureSkipVerify is true, TLS accepts any certificate
 	// presented by the server and any host name in that certificate.
 	// In this mode, TLS is susceptible to man-in-the-middle attacks.
@@ -78,6 +112,175 @@ type Config struct {
 	// request. If this is omitted or set to zero, a reasonable default will
 	// be used.
 	Timeout time.Duration
+
+	// Retries is the maximum number of times to retry an HVCA API request
+	// which fails with a temporary error, such as a 503 Service Unavailable
+	// status. If this is omitted or set to zero, a reasonable default will
+	// be used. A negative value disables retries.
+	Retries int
+
+	// MaxIdleConns is the maximum number of idle (keep-alive) connections
+	// to HVCA which the client will keep open for reuse. If this is omitted
+	// or set to zero, a reasonable default will be used.
+	MaxIdleConns int
+
+	// MaxConnsPerHost is the maximum number of simultaneous connections to
+	// HVCA, including connections in the dialing, active and idle states.
+	// Requests made when the limit is reached will block until a connection
+	// becomes available. If this is omitted or set to zero, a reasonable
+	// default will be used.
+	MaxConnsPerHost int
+
+	// IdleConnTimeout is the maximum length of time an idle connection to
+	// HVCA will remain open before closing itself. If this is omitted or set
+	// to zero, idle connections will remain open indefinitely.
+	IdleConnTimeout time.Duration
+
+	// KeepAlive is the interval between TCP keep-alive probes on connections
+	// to HVCA. If this is omitted or set to zero, the operating system or
+	// Go runtime default will be used. A negative value disables keep-alive
+	// probes.
+	KeepAlive time.Duration
+
+	// PageConcurrency is the maximum number of pages which the methods that
+	// retrieve every page of a listing, such as StatsIssuedAll, will request
+	// from HVCA concurrently once the total count is known. If this is
+	// omitted or set to zero, pages will be requested one at a time.
+	PageConcurrency int
+
+	// CachePolicyTTL is the length of time for which the validation policy
+	// and trust chain will be cached by the client after being retrieved.
+	// Both change rarely, so caching them can reduce latency and API load
+	// when they are requested repeatedly. If this is omitted or set to zero,
+	// no caching will be performed.
+	CachePolicyTTL time.Duration
+
+	// CacheDir is a directory in which the validation policy and trust chain
+	// are cached on disk, along with the entity tags returned for them by
+	// HVCA. If set, each is requested with an If-None-Match header, and the
+	// copy on disk is used if HVCA responds that it has not been modified,
+	// so that short-lived processes, such as command line invocations, need
+	// not transfer them again. The directory is created if necessary, and
+	// failures to read or write the cache are ignored. If this is omitted,
+	// no caching to disk will be performed.
+	CacheDir string
+
+	// DeduplicationWindow is the length of time for which the client will
+	// remember successful certificate requests. If a request identical to
+	// one remembered is made again within this period, the serial number
+	// and location of the certificate issued in response to the first
+	// request will be returned instead of submitting the request to HVCA
+	// again. This prevents deliberate retries from issuing duplicate
+	// certificates and consuming quota. If this is omitted or set to zero,
+	// no deduplication will be performed.
+	DeduplicationWindow time.Duration
+
+	// IdempotencyKeyHeader is the name of an HTTP request header in which
+	// to send an idempotency key with each certificate request. A random key
+	// is generated for each call to CertificateRequest, and is sent
+	// unchanged with any retries of that request, allowing an HVCA
+	// deployment or intermediate gateway which supports such a header to
+	// suppress duplicates caused by retries. Identical requests made in
+	// separate calls carry different keys. If this is omitted, no
+	// idempotency key will be sent.
+	IdempotencyKeyHeader string
+
+	// StrictDecoding causes HVCA responses containing fields unknown to the
+	// client, or trailing data after the response body, to be rejected with
+	// a ResponseError rather than silently accepted. This is useful for
+	// detecting changes to the HVCA API.
+	StrictDecoding bool
+
+	// StrictDNSNames causes the SAN DNS names in certificate requests to be
+	// checked with CheckDNSName, and converted to the form HVCA expects,
+	// before the requests are submitted. Requests containing DNS names which
+	// HVCA would reject are instead rejected by the client with an error
+	// wrapping ErrInvalidDNSName. The caller's request is not modified.
+	StrictDNSNames bool
+
+	// StrictEmails causes the SAN email addresses in certificate requests
+	// to be checked with CheckEmail, and converted to the form HVCA
+	// expects, before the requests are submitted. Requests containing email
+	// addresses which HVCA would reject are instead rejected by the client
+	// with an error wrapping ErrInvalidEmail. The caller's request is not
+	// modified.
+	StrictEmails bool
+
+	// CheckCAA causes the CAA records of the SAN DNS names in certificate
+	// requests to be checked with CheckCAA before the requests are
+	// submitted. Requests for DNS names whose CAA records do not authorize
+	// GlobalSign to issue certificates are rejected by the client with an
+	// error wrapping ErrCAANotAuthorized, unless OnCAAFailure is set.
+	CheckCAA bool
+
+	// OnCAAFailure, if non-nil, is called with the error when the check
+	// enabled by CheckCAA fails, and the certificate request is then
+	// submitted anyway, so that CAA problems are reported as warnings
+	// rather than preventing the request.
+	OnCAAFailure func(error)
+
+	// DNSServers are the addresses of the DNS servers queried by CheckCAA,
+	// in turn until one responds. A port number may be included, and
+	// defaults to 53. If empty, the nameservers in /etc/resolv.conf are
+	// used. Since that file does not exist on Windows, DNS servers must be
+	// specified there for CAA checks to succeed.
+	DNSServers []string
+
+	// CAAIdentifiers are the issuer domain names by which the CA is
+	// identified in the issue and issuewild properties of CAA records
+	// checked by CheckCAA. If empty, "globalsign.com" is used.
+	CAAIdentifiers []string
+
+	// AwaitFinalCertificate causes CertificateRetrieve and
+	// CertificateRetrieveByURL to retry, in the same way as for a
+	// certificate which is not yet available, when HVCA returns a
+	// precertificate because final issuance following certificate
+	// transparency logging has not completed. If the final certificate is
+	// still not available when the retries are exhausted, an error wrapping
+	// ErrPrecertificate is returned.
+	AwaitFinalCertificate bool
+
+	// MaxResponseSize is the maximum size in bytes of an HVCA response body.
+	// Larger responses are rejected with a ResponseError wrapping
+	// ErrResponseTooLarge, without being read in full. JSON response bodies
+	// are decoded as they are read rather than buffered first, and reading
+	// stops once the context passed to the API call is done. If this is
+	// omitted or set to zero, a reasonable default will be used.
+	MaxResponseSize int64
+
+	// CompressRequestsOver, if positive, is the size in bytes at or above
+	// which HVCA request bodies, such as certificate requests with large
+	// custom extensions, are gzip-compressed and sent with a
+	// Content-Encoding header. The HVCA deployment or any intermediate
+	// gateway must support compressed request bodies. If this is omitted or
+	// set to zero, request bodies are never compressed.
+	CompressRequestsOver int64
+
+	// DumpRequests, if non-nil, receives a dump of the HTTP request and
+	// response for every HVCA API call which fails with an error status,
+	// which is useful for diagnosing why HVCA rejected a request. The
+	// values of all headers other than Accept, Content-Encoding,
+	// Content-Type, User-Agent and X-Request-ID are redacted, as are the
+	// API key and API secret, and certificate requests are redacted as by
+	// Request.Redacted, but the dump may contain other sensitive
+	// information from the request, such as subject names. Writes are
+	// serialized, so it is safe to share a writer between concurrent API
+	// calls.
+	DumpRequests io.Writer
+
+	// Progress, if non-nil, receives progress updates from operations
+	// which make a number of HVCA API calls, such as StatsIssuedAll and
+	// ClaimsDomainsAll, which report each page retrieved.
+	Progress ProgressReporter
+
+	// Observer, if non-nil, is notified after each HVCA API call completes,
+	// for example to export call and error rates as metrics.
+	Observer Observer
+
+	// Clock, if non-nil, is used as the source of the current time and of
+	// retry timers, in place of the system clock. This is useful for
+	// controlling time-dependent behavior deterministically in tests.
+	Clock Clock
 }
 
 const (
@@ -88,6 +291,20 @@ const (
 
 var defaultTimeout = time.Second * 60
 
+// defaultMaxConns is the maximum number of idle connections and of
+// connections per host if none is specified in the configuration. These
+// values seem to reasonably maximally encourage the sharing of TCP
+// connections.
+var defaultMaxConns = 1024
+
+// defaultRetries is the number of times to retry a request if none is
+// specified in the configuration.
+var defaultRetries = 5
+
+// defaultMaxResponseSize is the maximum size of an HVCA response body if
+// none is specified in the configuration.
+var defaultMaxResponseSize int64 = 10 * 1024 * 1024
+
 // Validate returns an error if any fields in the configuration object are
 // missing or malformed. It also calculates a default timeout, if the Timeout
 // field is zero.
@@ -104,7 +321,21 @@ func (c *Config) Validate() error {
 		return err
 	}
 
-	var versionstring = filepath.Base(c.url.Path)
+	if c.url.Scheme == "" || c.url.Host == "" {
+		return fmt.Errorf("URL must include a scheme and a host: %s", c.URL)
+	}
+
+	if c.url.RawQuery != "" || c.url.Fragment != "" {
+		return fmt.Errorf("URL must not include a query or a fragment: %s", c.URL)
+	}
+
+	// The URL may include an arbitrary base path, for instance when HVCA is
+	// fronted by an API gateway which adds a path prefix. Trailing slashes
+	// are removed so endpoint paths can be appended to it directly.
+	c.url.Path = strings.TrimRight(c.url.Path, "/")
+	c.url.RawPath = strings.TrimRight(c.url.RawPath, "/")
+
+	var versionstring = path.Base(c.url.Path)
 
 	switch versionstring {
 	case "v2":
@@ -118,12 +349,54 @@ func (c *Config) Validate() error {
 		c.Timeout = defaultTimeout
 	}
 
-	// Ensure API key and secret were provided.
-	if c.APIKey == "" {
+	// Calculate default number of retries.
+	if c.Retries == 0 {
+		c.Retries = defaultRetries
+	}
+
+	// Calculate default connection limits.
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = defaultMaxConns
+	} else if c.MaxIdleConns < 0 {
+		return errors.New("maximum idle connections cannot be negative")
+	}
+
+	if c.MaxConnsPerHost == 0 {
+		c.MaxConnsPerHost = defaultMaxConns
+	} else if c.MaxConnsPerHost < 0 {
+		return errors.New("maximum connections per host cannot be negative")
+	}
+
+	if c.IdleConnTimeout < 0 {
+		return errors.New("idle connection timeout cannot be negative")
+	}
+
+	if c.PageConcurrency < 0 {
+		return errors.New("page concurrency cannot be negative")
+	}
+
+	if c.DeduplicationWindow < 0 {
+		return errors.New("deduplication window cannot be negative")
+	}
+
+	// Calculate default maximum response size.
+	if c.MaxResponseSize == 0 {
+		c.MaxResponseSize = defaultMaxResponseSize
+	} else if c.MaxResponseSize < 0 {
+		return errors.New("maximum response size cannot be negative")
+	}
+
+	if c.CompressRequestsOver < 0 {
+		return errors.New("request compression threshold cannot be negative")
+	}
+
+	// Ensure API key and secret were provided, unless the client will use
+	// an existing authentication token.
+	if c.APIKey == "" && !c.fromToken {
 		return errors.New("no API key provided")
 	}
 
-	if c.APISecret == "" {
+	if c.APISecret == "" && !c.fromToken {
 		return errors.New("no API secret provided")
 	}
 
@@ -134,24 +407,103 @@ func (c *Config) Validate() error {
 		return errors.New("mTLS certificate not provided but mTLS private key provided")
 	}
 
+	// Load TLS root certificates from file, if provided.
+	if c.TLSRootsFile != "" {
+		if c.TLSRoots != nil {
+			return errors.New("only one of TLS roots and TLS roots file may be provided")
+		}
+
+		if c.TLSRoots, err = pki.CertPoolFromFile(c.TLSRootsFile); err != nil {
+			return fmt.Errorf("couldn't get TLS root certificates: %v", err)
+		}
+	}
+
+	// Ensure any DNS servers and CAA identifiers used for CAA checks are
+	// valid.
+	for _, server := range c.DNSServers {
+		var host, port, err = net.SplitHostPort(server)
+		if err != nil {
+			host, port = server, "53"
+		}
+
+		if n, err := strconv.Atoi(port); host == "" || err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid DNS server address: %q", server)
+		}
+	}
+
+	for _, id := range c.CAAIdentifiers {
+		if id == "" || strings.ContainsAny(id, "; \t") {
+			return fmt.Errorf("invalid CAA identifier: %q", id)
+		}
+	}
+
+	// Ensure proxy URL is valid, if provided.
+	if c.HTTPProxy != "" {
+		if _, err = url.Parse(c.HTTPProxy); err != nil {
+			return fmt.Errorf("invalid proxy URL: %v", err)
+		}
+	}
+
 	return nil
 }
 
+// proxyFunc returns a function suitable for use as the Proxy field of an
+// http.Transport, which applies any proxy settings in the configuration on
+// top of those specified in the environment.
+func (c *Config) proxyFunc() func(*http.Request) (*url.URL, error) {
+	if c.HTTPProxy == "" && c.NoProxy == "" {
+		return http.ProxyFromEnvironment
+	}
+
+	var proxyConfig = httpproxy.FromEnvironment()
+
+	if c.HTTPProxy != "" {
+		proxyConfig.HTTPProxy = c.HTTPProxy
+		proxyConfig.HTTPSProxy = c.HTTPProxy
+	}
+
+	if c.NoProxy != "" {
+		proxyConfig.NoProxy = c.NoProxy
+	}
+
+	var proxy = proxyConfig.ProxyFunc()
+
+	return func(r *http.Request) (*url.URL, error) {
+		return proxy(r.URL)
+	}
+}
+
 // NewConfigFromFile creates a new HVCA client configuration object from
 // a configuration file.
 func NewConfigFromFile(filename string) (*Config, error) {
-	var fileconf, err = config.NewFromFile(filename)
+	return NewConfigFromFileProfile(filename, "")
+}
+
+// NewConfigFromFileProfile creates a new HVCA client configuration object
+// from the named profile in a configuration file. Settings at the top level
+// of the file are used as defaults for any settings not specified in the
+// profile. If name is empty, only the top level settings are used.
+func NewConfigFromFileProfile(filename, name string) (*Config, error) {
+	var fileconf, err = config.NewFromFileProfile(filename, name)
 	if err != nil {
 		return nil, err
 	}
 
+	if fileconf == nil {
+		return nil, fmt.Errorf("no configuration in %s", filename)
+	}
+
 	var newconf = &Config{
 		URL:                fileconf.URL,
 		APIKey:             fileconf.APIKey,
 		APISecret:          fileconf.APISecret,
 		ExtraHeaders:       fileconf.ExtraHeaders,
+		UserAgent:          fileconf.UserAgent,
 		InsecureSkipVerify: fileconf.InsecureSkipVerify,
 		Timeout:            time.Second * time.Duration(fileconf.Timeout),
+		TLSRootsFile:       fileconf.CAFile,
+		HTTPProxy:          fileconf.HTTPProxy,
+		NoProxy:            fileconf.NoProxy,
 	}
 
 	// Get mTLS private key from file, if provided.
@@ -178,7 +530,7 @@ func NewConfigFromFile(filename string) (*Config, error) {
 // UnmarshalJSON parses a JSON encoded configuration and stores the result
 // in the object.
 func (c *Config) UnmarshalJSON(b []byte) error {
-	var jsonConfig *config.Config
+	var jsonConfig config.Config
 	var err = json.Unmarshal(b, &jsonConfig)
 	if err != nil {
 		return err
@@ -189,8 +541,12 @@ func (c *Config) UnmarshalJSON(b []byte) error {
 		APIKey:             jsonConfig.APIKey,
 		APISecret:          jsonConfig.APISecret,
 		ExtraHeaders:       jsonConfig.ExtraHeaders,
+		UserAgent:          jsonConfig.UserAgent,
 		InsecureSkipVerify: jsonConfig.InsecureSkipVerify,
 		Timeout:            time.Second * time.Duration(jsonConfig.Timeout),
+		TLSRootsFile:       jsonConfig.CAFile,
+		HTTPProxy:          jsonConfig.HTTPProxy,
+		NoProxy:            jsonConfig.NoProxy,
 	}
 
 	// Get mTLS private key from file.
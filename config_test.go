@@ -17,9 +17,12 @@ package hvclient
 
 import (
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
+	"net/http"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
@@ -44,6 +47,7 @@ func TestConfigNewFromFile(t *testing.T) {
 				APIKey:    "1234",
 				APISecret: "abcdefgh",
 				Timeout:   time.Second * 60,
+				Retries:   5,
 			},
 			keyType: reflect.TypeOf((*rsa.PrivateKey)(nil)),
 		},
@@ -55,6 +59,7 @@ func TestConfigNewFromFile(t *testing.T) {
 				APIKey:    "5678",
 				APISecret: "stuvwxyz",
 				Timeout:   time.Second * 5,
+				Retries:   5,
 			},
 			keyType: reflect.TypeOf((*rsa.PrivateKey)(nil)),
 		},
@@ -66,6 +71,21 @@ func TestConfigNewFromFile(t *testing.T) {
 				APIKey:    "1234",
 				APISecret: "abcdefgh",
 				Timeout:   time.Second * 60,
+				Retries:   5,
+			},
+		},
+		{
+			filename: "testdata/config_test_with_proxy.conf",
+			want: Config{
+				URL:          "https://emea.api.hvca.globalsign.com:8443/v2",
+				version:      2,
+				APIKey:       "1234",
+				APISecret:    "abcdefgh",
+				Timeout:      time.Second * 60,
+				Retries:      5,
+				TLSRootsFile: "testdata/test_root_cert.pem",
+				HTTPProxy:    "http://proxy.example.com:3128",
+				NoProxy:      "localhost,.internal.example.com",
 			},
 		},
 		{
@@ -117,9 +137,99 @@ func TestConfigNewFromFile(t *testing.T) {
 				t.Fatalf("got API secret %s, want %s", conf.APISecret, tc.want.APISecret)
 			}
 
+			if conf.Retries != tc.want.Retries {
+				t.Fatalf("got retries %d, want %d", conf.Retries, tc.want.Retries)
+			}
+
 			if (conf.TLSKey == nil) != (tc.keyType == nil) {
 				t.Fatalf("got key type %T, want %v", conf.TLSKey, tc.keyType)
 			}
+
+			if (conf.TLSRoots == nil) != (tc.want.TLSRootsFile == "") {
+				t.Fatalf("got TLS roots %v, want from file %q", conf.TLSRoots, tc.want.TLSRootsFile)
+			}
+
+			if conf.HTTPProxy != tc.want.HTTPProxy {
+				t.Fatalf("got HTTP proxy %s, want %s", conf.HTTPProxy, tc.want.HTTPProxy)
+			}
+
+			if conf.NoProxy != tc.want.NoProxy {
+				t.Fatalf("got no proxy %s, want %s", conf.NoProxy, tc.want.NoProxy)
+			}
+		})
+	}
+}
+
+func TestConfigNewFromFileProfile(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		want Config
+		err  error
+	}{
+		{
+			name: "prod",
+			want: Config{
+				URL:       "https://emea.api.hvca.globalsign.com:8443/v2",
+				APIKey:    "1234",
+				APISecret: "abcdefgh",
+				Timeout:   time.Second * 60,
+			},
+		},
+		{
+			name: "staging",
+			want: Config{
+				URL:       "http://127.0.0.1:5500/v2",
+				APIKey:    "5678",
+				APISecret: "stuvwxyz",
+				Timeout:   time.Second * 5,
+			},
+		},
+		{
+			name: "",
+			err:  errors.New("no API key at top level"),
+		},
+		{
+			name: "missing",
+			err:  errors.New("no such profile"),
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var conf, err = NewConfigFromFileProfile("testdata/config_test_profiles.conf", tc.name)
+			if (err == nil) != (tc.err == nil) {
+				t.Fatalf("got error %v, want %v", err, tc.err)
+			}
+
+			if tc.err != nil {
+				return
+			}
+
+			if conf.URL != tc.want.URL {
+				t.Fatalf("got URL %s, want %s", conf.URL, tc.want.URL)
+			}
+
+			if conf.APIKey != tc.want.APIKey {
+				t.Fatalf("got API key %s, want %s", conf.APIKey, tc.want.APIKey)
+			}
+
+			if conf.APISecret != tc.want.APISecret {
+				t.Fatalf("got API secret %s, want %s", conf.APISecret, tc.want.APISecret)
+			}
+
+			if conf.Timeout != tc.want.Timeout {
+				t.Fatalf("got timeout %v, want %v", conf.Timeout, tc.want.Timeout)
+			}
+
+			if conf.TLSKey == nil || conf.TLSCert == nil {
+				t.Fatalf("got TLS key %T and certificate %v, want both from top level", conf.TLSKey, conf.TLSCert)
+			}
 		})
 	}
 }
@@ -178,6 +288,120 @@ func TestConfigUnmarshalJSON(t *testing.T) {
 	}
 }
 
+func TestConfigValidateURL(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name    string
+		url     string
+		want    string
+		version int
+	}{
+		{
+			name:    "Documented",
+			url:     "https://emea.api.hvca.globalsign.com:8443/v2",
+			want:    "https://emea.api.hvca.globalsign.com:8443/v2/certificates/ABCD",
+			version: 2,
+		},
+		{
+			name:    "TrailingSlash",
+			url:     "https://emea.api.hvca.globalsign.com:8443/v2/",
+			want:    "https://emea.api.hvca.globalsign.com:8443/v2/certificates/ABCD",
+			version: 2,
+		},
+		{
+			name:    "TrailingSlashes",
+			url:     "https://emea.api.hvca.globalsign.com:8443/v2//",
+			want:    "https://emea.api.hvca.globalsign.com:8443/v2/certificates/ABCD",
+			version: 2,
+		},
+		{
+			name:    "NoPath",
+			url:     "http://127.0.0.1:5500",
+			want:    "http://127.0.0.1:5500/certificates/ABCD",
+			version: defaultVersion,
+		},
+		{
+			name:    "RootPath",
+			url:     "http://127.0.0.1:5500/",
+			want:    "http://127.0.0.1:5500/certificates/ABCD",
+			version: defaultVersion,
+		},
+		{
+			name:    "NoPort",
+			url:     "https://gateway.example.com/v2",
+			want:    "https://gateway.example.com/v2/certificates/ABCD",
+			version: 2,
+		},
+		{
+			name:    "GatewayPrefix",
+			url:     "https://gateway.example.com:9443/pki/hvca/v2/",
+			want:    "https://gateway.example.com:9443/pki/hvca/v2/certificates/ABCD",
+			version: 2,
+		},
+		{
+			name:    "GatewayPrefixNoVersion",
+			url:     "https://gateway.example.com/pki/hvca",
+			want:    "https://gateway.example.com/pki/hvca/certificates/ABCD",
+			version: defaultVersion,
+		},
+		{
+			name:    "EscapedPrefix",
+			url:     "https://gateway.example.com/pki%2Fhvca/v2",
+			want:    "https://gateway.example.com/pki%2Fhvca/v2/certificates/ABCD",
+			version: 2,
+		},
+		{
+			name:    "IPv6",
+			url:     "https://[::1]:8443/v2",
+			want:    "https://[::1]:8443/v2/certificates/ABCD",
+			version: 2,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var conf = Config{
+				URL:       tc.url,
+				APIKey:    "1234",
+				APISecret: "abcdefgh",
+			}
+
+			if err := conf.Validate(); err != nil {
+				t.Fatalf("couldn't validate configuration: %v", err)
+			}
+
+			if conf.version != tc.version {
+				t.Errorf("got version %d, want %d", conf.version, tc.version)
+			}
+
+			var client = &Client{url: conf.url}
+
+			if got := client.endpointURL("/certificates/ABCD"); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+
+			var u, err = url.Parse(tc.want)
+			if err != nil {
+				t.Fatalf("couldn't parse URL: %v", err)
+			}
+
+			var rel string
+			if rel, err = client.relativePath(u); err != nil {
+				t.Fatalf("couldn't get relative path: %v", err)
+			}
+
+			if rel != "/certificates/ABCD" {
+				t.Errorf("got relative path %q, want %q", rel, "/certificates/ABCD")
+			}
+		})
+	}
+}
+
 func TestConfigValidateFailure(t *testing.T) {
 	t.Parallel()
 
@@ -195,6 +419,22 @@ func TestConfigValidateFailure(t *testing.T) {
 				TLSCert:   testhelpers.MustGetCertFromFile(t, "testdata/tls.cert"),
 			},
 		},
+		{
+			name: "NoScheme",
+			conf: Config{
+				URL:       "emea.api.hvca.globalsign.com/v2",
+				APIKey:    "1234",
+				APISecret: "abcdefgh",
+			},
+		},
+		{
+			name: "Query",
+			conf: Config{
+				URL:       "https://gateway.example.com/v2?tenant=acme",
+				APIKey:    "1234",
+				APISecret: "abcdefgh",
+			},
+		},
 		{
 			name: "NoAPIKey",
 			conf: Config{
@@ -235,6 +475,124 @@ func TestConfigValidateFailure(t *testing.T) {
 				TLSCert:   nil,
 			},
 		},
+		{
+			name: "NoTLSRootsFile",
+			conf: Config{
+				URL:          "http://example.com/v2",
+				APIKey:       "1234",
+				APISecret:    "abcdefgh",
+				TLSRootsFile: "testdata/no_such_file.pem",
+			},
+		},
+		{
+			name: "TLSRootsAndTLSRootsFile",
+			conf: Config{
+				URL:          "http://example.com/v2",
+				APIKey:       "1234",
+				APISecret:    "abcdefgh",
+				TLSRoots:     x509.NewCertPool(),
+				TLSRootsFile: "testdata/test_root_cert.pem",
+			},
+		},
+		{
+			name: "NegativeMaxResponseSize",
+			conf: Config{
+				URL:             "http://example.com/v2",
+				APIKey:          "1234",
+				APISecret:       "abcdefgh",
+				MaxResponseSize: -1,
+			},
+		},
+		{
+			name: "NegativeCompressRequestsOver",
+			conf: Config{
+				URL:                  "http://example.com/v2",
+				APIKey:               "1234",
+				APISecret:            "abcdefgh",
+				CompressRequestsOver: -1,
+			},
+		},
+		{
+			name: "BadDNSServerPort",
+			conf: Config{
+				URL:        "http://example.com/v2",
+				APIKey:     "1234",
+				APISecret:  "abcdefgh",
+				DNSServers: []string{"192.0.2.1:dns"},
+			},
+		},
+		{
+			name: "EmptyDNSServer",
+			conf: Config{
+				URL:        "http://example.com/v2",
+				APIKey:     "1234",
+				APISecret:  "abcdefgh",
+				DNSServers: []string{""},
+			},
+		},
+		{
+			name: "BadCAAIdentifier",
+			conf: Config{
+				URL:            "http://example.com/v2",
+				APIKey:         "1234",
+				APISecret:      "abcdefgh",
+				CAAIdentifiers: []string{"globalsign.com; account=1"},
+			},
+		},
+		{
+			name: "NegativeMaxIdleConns",
+			conf: Config{
+				URL:          "http://example.com/v2",
+				APIKey:       "1234",
+				APISecret:    "abcdefgh",
+				MaxIdleConns: -1,
+			},
+		},
+		{
+			name: "NegativeMaxConnsPerHost",
+			conf: Config{
+				URL:             "http://example.com/v2",
+				APIKey:          "1234",
+				APISecret:       "abcdefgh",
+				MaxConnsPerHost: -1,
+			},
+		},
+		{
+			name: "NegativeIdleConnTimeout",
+			conf: Config{
+				URL:             "http://example.com/v2",
+				APIKey:          "1234",
+				APISecret:       "abcdefgh",
+				IdleConnTimeout: -time.Second,
+			},
+		},
+		{
+			name: "NegativePageConcurrency",
+			conf: Config{
+				URL:             "http://example.com/v2",
+				APIKey:          "1234",
+				APISecret:       "abcdefgh",
+				PageConcurrency: -1,
+			},
+		},
+		{
+			name: "NegativeDeduplicationWindow",
+			conf: Config{
+				URL:                 "http://example.com/v2",
+				APIKey:              "1234",
+				APISecret:           "abcdefgh",
+				DeduplicationWindow: -time.Second,
+			},
+		},
+		{
+			name: "BadProxy",
+			conf: Config{
+				URL:       "http://example.com/v2",
+				APIKey:    "1234",
+				APISecret: "abcdefgh",
+				HTTPProxy: "://proxy.example.com",
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -249,3 +607,59 @@ func TestConfigValidateFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigProxyFunc(t *testing.T) {
+	t.Parallel()
+
+	var conf = Config{
+		HTTPProxy: "http://proxy.example.com:3128",
+		NoProxy:   "localhost,.internal.example.com",
+	}
+
+	var proxy = conf.proxyFunc()
+
+	var testcases = []struct {
+		url  string
+		want string
+	}{
+		{
+			url:  "https://emea.api.hvca.globalsign.com:8443/v2/login",
+			want: "http://proxy.example.com:3128",
+		},
+		{
+			url:  "http://emea.api.hvca.globalsign.com/v2/login",
+			want: "http://proxy.example.com:3128",
+		},
+		{
+			url:  "https://hvca.internal.example.com/v2/login",
+			want: "",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.url, func(t *testing.T) {
+			t.Parallel()
+
+			var req, err = http.NewRequest(http.MethodGet, tc.url, nil)
+			if err != nil {
+				t.Fatalf("couldn't create request: %v", err)
+			}
+
+			var got *url.URL
+			if got, err = proxy(req); err != nil {
+				t.Fatalf("couldn't get proxy: %v", err)
+			}
+
+			var gotString string
+			if got != nil {
+				gotString = got.String()
+			}
+
+			if gotString != tc.want {
+				t.Errorf("got %q, want %q", gotString, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"sort"
+)
+
+// RDNOption is an option controlling how the attributes of a subject
+// distinguished name are arranged into relative distinguished names (RDNs).
+// By default, the attributes are encoded in the same order as the standard
+// library's pkix.Name, i.e. country, state, locality, street address,
+// organization, organizational unit, common name and serial number, followed
+// by any other attributes in the order in which they appear in the DN, with
+// multiple organizational units combined into a single multi-valued RDN.
+type RDNOption func(*rdnOptions)
+
+// rdnOptions holds the settings accumulated from RDN options.
+type rdnOptions struct {
+	order        []asn1.ObjectIdentifier
+	singleValued bool
+	multiValued  [][]asn1.ObjectIdentifier
+}
+
+// WithRDNOrder encodes the RDNs containing attributes of the specified types
+// first, in the order given, followed by any other RDNs in the default
+// order. For example, WithRDNOrder(asn1.ObjectIdentifier{2, 5, 4, 3}) encodes
+// the common name first.
+func WithRDNOrder(types ...asn1.ObjectIdentifier) RDNOption {
+	return func(o *rdnOptions) {
+		o.order = append(o.order, types...)
+	}
+}
+
+// WithSingleValuedRDNs encodes every attribute as an RDN of its own,
+// including multiple organizational units, unless it is combined into a
+// multi-valued RDN with WithMultiValuedRDN. Many CAs and verifiers expect
+// every RDN to contain exactly one attribute.
+func WithSingleValuedRDNs() RDNOption {
+	return func(o *rdnOptions) {
+		o.singleValued = true
+	}
+}
+
+// WithMultiValuedRDN combines all attributes of the specified types into a
+// single multi-valued RDN, at the position of the first RDN which contained
+// any of them. It may be specified more than once to create several
+// multi-valued RDNs.
+func WithMultiValuedRDN(types ...asn1.ObjectIdentifier) RDNOption {
+	return func(o *rdnOptions) {
+		if len(types) > 0 {
+			o.multiValued = append(o.multiValued, types)
+		}
+	}
+}
+
+// RDNSequence converts a subject distinguished name into a sequence of
+// relative distinguished names, arranged according to the specified
+// options. With no options, the result is the same as that of calling
+// ToRDNSequence on the result of PKIXName.
+func (n *DN) RDNSequence(opts ...RDNOption) pkix.RDNSequence {
+	var o rdnOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var seq = n.PKIXName().ToRDNSequence()
+
+	if o.singleValued {
+		var split pkix.RDNSequence
+		for _, rdn := range seq {
+			for _, atv := range rdn {
+				split = append(split, pkix.RelativeDistinguishedNameSET{atv})
+			}
+		}
+
+		seq = split
+	}
+
+	for _, types := range o.multiValued {
+		seq = combineRDNs(seq, types)
+	}
+
+	if len(o.order) > 0 {
+		sort.SliceStable(seq, func(i, j int) bool {
+			return rdnRank(seq[i], o.order) < rdnRank(seq[j], o.order)
+		})
+	}
+
+	return seq
+}
+
+// combineRDNs moves all attributes of the specified types into a single RDN
+// at the position of the first RDN which contained any of them, removing any
+// RDNs left empty.
+func combineRDNs(seq pkix.RDNSequence, types []asn1.ObjectIdentifier) pkix.RDNSequence {
+	var combined pkix.RelativeDistinguishedNameSET
+	var result pkix.RDNSequence
+	var at = -1
+
+	for _, rdn := range seq {
+		var rest pkix.RelativeDistinguishedNameSET
+		for _, atv := range rdn {
+			if oidIndex(types, atv.Type) >= 0 {
+				combined = append(combined, atv)
+			} else {
+				rest = append(rest, atv)
+			}
+		}
+
+		if at < 0 && len(combined) > 0 {
+			at = len(result)
+		}
+
+		if len(rest) > 0 {
+			result = append(result, rest)
+		}
+	}
+
+	if at < 0 {
+		return seq
+	}
+
+	result = append(result, nil)
+	copy(result[at+1:], result[at:])
+	result[at] = combined
+
+	return result
+}
+
+// rdnRank returns the position in the specified order of the first listed
+// attribute type in an RDN, or the length of the order if it contains none.
+func rdnRank(rdn pkix.RelativeDistinguishedNameSET, order []asn1.ObjectIdentifier) int {
+	var rank = len(order)
+
+	for _, atv := range rdn {
+		if i := oidIndex(order, atv.Type); i >= 0 && i < rank {
+			rank = i
+		}
+	}
+
+	return rank
+}
+
+// oidIndex returns the index of an OID in a list, or -1 if it is not
+// present.
+func oidIndex(list []asn1.ObjectIdentifier, oid asn1.ObjectIdentifier) int {
+	for i := range list {
+		if list[i].Equal(oid) {
+			return i
+		}
+	}
+
+	return -1
+}
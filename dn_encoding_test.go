@@ -0,0 +1,208 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/testhelpers"
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	testOIDCN = asn1.ObjectIdentifier{2, 5, 4, 3}
+	testOIDC  = asn1.ObjectIdentifier{2, 5, 4, 6}
+	testOIDO  = asn1.ObjectIdentifier{2, 5, 4, 10}
+	testOIDOU = asn1.ObjectIdentifier{2, 5, 4, 11}
+)
+
+// testRDNDN is a subject distinguished name used for RDN ordering tests.
+var testRDNDN = &hvclient.DN{
+	CommonName:         "John Doe",
+	Organization:       "ACME",
+	OrganizationalUnit: []string{"Sales", "Marketing"},
+	Country:            "GB",
+}
+
+// rdnStrings returns a simple string representation of an RDN sequence,
+// with one slice of type=value strings for each RDN.
+func rdnStrings(seq pkix.RDNSequence) [][]string {
+	var result [][]string
+	for _, rdn := range seq {
+		var atvs []string
+		for _, atv := range rdn {
+			atvs = append(atvs, fmt.Sprintf("%s=%v", atv.Type, atv.Value))
+		}
+
+		result = append(result, atvs)
+	}
+
+	return result
+}
+
+func TestDNRDNSequence(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		opts []hvclient.RDNOption
+		want [][]string
+	}{
+		{
+			name: "Default",
+			want: [][]string{
+				{"2.5.4.6=GB"},
+				{"2.5.4.10=ACME"},
+				{"2.5.4.11=Sales", "2.5.4.11=Marketing"},
+				{"2.5.4.3=John Doe"},
+			},
+		},
+		{
+			name: "Order",
+			opts: []hvclient.RDNOption{hvclient.WithRDNOrder(testOIDCN, testOIDOU)},
+			want: [][]string{
+				{"2.5.4.3=John Doe"},
+				{"2.5.4.11=Sales", "2.5.4.11=Marketing"},
+				{"2.5.4.6=GB"},
+				{"2.5.4.10=ACME"},
+			},
+		},
+		{
+			name: "SingleValued",
+			opts: []hvclient.RDNOption{hvclient.WithSingleValuedRDNs()},
+			want: [][]string{
+				{"2.5.4.6=GB"},
+				{"2.5.4.10=ACME"},
+				{"2.5.4.11=Sales"},
+				{"2.5.4.11=Marketing"},
+				{"2.5.4.3=John Doe"},
+			},
+		},
+		{
+			name: "MultiValued",
+			opts: []hvclient.RDNOption{
+				hvclient.WithSingleValuedRDNs(),
+				hvclient.WithMultiValuedRDN(testOIDCN, testOIDO),
+			},
+			want: [][]string{
+				{"2.5.4.6=GB"},
+				{"2.5.4.10=ACME", "2.5.4.3=John Doe"},
+				{"2.5.4.11=Sales"},
+				{"2.5.4.11=Marketing"},
+			},
+		},
+		{
+			name: "MultiValuedAbsent",
+			opts: []hvclient.RDNOption{hvclient.WithMultiValuedRDN(asn1.ObjectIdentifier{2, 5, 4, 7})},
+			want: [][]string{
+				{"2.5.4.6=GB"},
+				{"2.5.4.10=ACME"},
+				{"2.5.4.11=Sales", "2.5.4.11=Marketing"},
+				{"2.5.4.3=John Doe"},
+			},
+		},
+		{
+			name: "All",
+			opts: []hvclient.RDNOption{
+				hvclient.WithSingleValuedRDNs(),
+				hvclient.WithMultiValuedRDN(testOIDO, testOIDC),
+				hvclient.WithRDNOrder(testOIDCN, testOIDOU),
+			},
+			want: [][]string{
+				{"2.5.4.3=John Doe"},
+				{"2.5.4.11=Sales"},
+				{"2.5.4.11=Marketing"},
+				{"2.5.4.6=GB", "2.5.4.10=ACME"},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := rdnStrings(testRDNDN.RDNSequence(tc.opts...)); !cmp.Equal(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDNPKIXNameOrder(t *testing.T) {
+	t.Parallel()
+
+	var got = rdnStrings(testRDNDN.PKIXName(hvclient.WithRDNOrder(testOIDCN)).ToRDNSequence())
+	var want = [][]string{
+		{"2.5.4.3=John Doe"},
+		{"2.5.4.6=GB"},
+		{"2.5.4.10=ACME"},
+		{"2.5.4.11=Sales"},
+		{"2.5.4.11=Marketing"},
+	}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := rdnStrings(testRDNDN.PKIXName().ToRDNSequence()); !cmp.Equal(got, rdnStrings(testRDNDN.RDNSequence())) {
+		t.Errorf("got %q, want %q", got, rdnStrings(testRDNDN.RDNSequence()))
+	}
+}
+
+func TestRequestPKCS10RDNOptions(t *testing.T) {
+	t.Parallel()
+
+	var request = &hvclient.Request{
+		Subject:    testRDNDN,
+		PrivateKey: testhelpers.MustGetPrivateKeyFromFile(t, "testdata/rsa_priv.key"),
+	}
+
+	var csr, err = request.PKCS10(hvclient.WithSingleValuedRDNs(), hvclient.WithRDNOrder(testOIDCN))
+	if err != nil {
+		t.Fatalf("couldn't build PKCS10 request: %v", err)
+	}
+
+	if err = csr.CheckSignature(); err != nil {
+		t.Errorf("signature check failed: %v", err)
+	}
+
+	var seq pkix.RDNSequence
+	if _, err = asn1.Unmarshal(csr.RawSubject, &seq); err != nil {
+		t.Fatalf("couldn't unmarshal subject: %v", err)
+	}
+
+	var want = [][]string{
+		{"2.5.4.3=John Doe"},
+		{"2.5.4.6=GB"},
+		{"2.5.4.10=ACME"},
+		{"2.5.4.11=Sales"},
+		{"2.5.4.11=Marketing"},
+	}
+
+	if got := rdnStrings(seq); !cmp.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if csr.Subject.CommonName != testRDNDN.CommonName {
+		t.Errorf("got common name %q, want %q", csr.Subject.CommonName, testRDNDN.CommonName)
+	}
+}
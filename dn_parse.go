@@ -0,0 +1,269 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/globalsign/hvclient/internal/oids"
+)
+
+// dnAttribute is a single attribute type and value parsed from a string
+// representation of a distinguished name.
+type dnAttribute struct {
+	typ   string
+	value string
+}
+
+// dnEscapable contains the characters which may be escaped with a backslash
+// in an attribute value.
+const dnEscapable = ` "#+,;<=>\`
+
+// dnAttributeNames maps the upper case short names recognized by ParseDN to
+// the object identifiers of the corresponding attribute types.
+var dnAttributeNames = map[string]asn1.ObjectIdentifier{
+	"CN":                              oids.OIDSubjectCommonName,
+	"SERIALNUMBER":                    oids.OIDSubjectSerialNumber,
+	"C":                               oids.OIDSubjectCountry,
+	"L":                               oids.OIDSubjectLocality,
+	"ST":                              oids.OIDSubjectState,
+	"S":                               oids.OIDSubjectState,
+	"STREET":                          oids.OIDSubjectStreetAddress,
+	"O":                               oids.OIDSubjectOrganization,
+	"OU":                              oids.OIDSubjectOrganizationalUnit,
+	"E":                               oids.OIDSubjectEmail,
+	"EMAILADDRESS":                    oids.OIDSubjectEmail,
+	"BUSINESSCATEGORY":                oids.OIDSubjectBusinessCategory,
+	"JURISDICTIONL":                   oids.OIDSubjectJOILocality,
+	"JURISDICTIONLOCALITYNAME":        oids.OIDSubjectJOILocality,
+	"JURISDICTIONST":                  oids.OIDSubjectJOIState,
+	"JURISDICTIONSTATEORPROVINCENAME": oids.OIDSubjectJOIState,
+	"JURISDICTIONC":                   oids.OIDSubjectJOICountry,
+	"JURISDICTIONCOUNTRYNAME":         oids.OIDSubjectJOICountry,
+}
+
+// ParseDN parses a string representation of a distinguished name as
+// described in RFC 4514, e.g. "CN=John Doe,O=ACME,C=GB". Attribute types may
+// be specified by their usual short names, matched without regard to case,
+// or as dotted-decimal object identifiers. Attributes with types which do
+// not correspond to a field in DN are added to its ExtraAttributes. Since DN
+// does not record the order of its attributes, the order of the attributes
+// in the string is not significant, and multi-valued RDNs separated by "+"
+// are treated as separate attributes. Every attribute other than the
+// organizational unit and extra attributes may be specified only once.
+func ParseDN(s string) (*DN, error) {
+	var attrs, err = parseDNAttributes(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var dn = &DN{}
+
+	for _, attr := range attrs {
+		var oid, ok = dnAttributeNames[strings.ToUpper(attr.typ)]
+		if !ok {
+			if oid, err = oids.StringToOID(attr.typ); err != nil || len(oid) < 2 {
+				return nil, fmt.Errorf("unknown attribute type %q in distinguished name", attr.typ)
+			}
+		}
+
+		if oid.Equal(oids.OIDSubjectOrganizationalUnit) {
+			dn.OrganizationalUnit = append(dn.OrganizationalUnit, attr.value)
+			continue
+		}
+
+		var field = dn.fieldForOID(oid)
+		if field == nil {
+			dn.ExtraAttributes = append(dn.ExtraAttributes, OIDAndString{OID: oid, Value: attr.value})
+			continue
+		}
+
+		if *field != "" {
+			return nil, fmt.Errorf("duplicate attribute type %q in distinguished name", attr.typ)
+		}
+
+		*field = attr.value
+	}
+
+	return dn, nil
+}
+
+// fieldForOID returns the address of the single-valued field of the DN
+// corresponding to an attribute type, or nil if there is none.
+func (n *DN) fieldForOID(oid asn1.ObjectIdentifier) *string {
+	for _, field := range []struct {
+		oid asn1.ObjectIdentifier
+		to  *string
+	}{
+		{oids.OIDSubjectCommonName, &n.CommonName},
+		{oids.OIDSubjectSerialNumber, &n.SerialNumber},
+		{oids.OIDSubjectCountry, &n.Country},
+		{oids.OIDSubjectLocality, &n.Locality},
+		{oids.OIDSubjectState, &n.State},
+		{oids.OIDSubjectStreetAddress, &n.StreetAddress},
+		{oids.OIDSubjectOrganization, &n.Organization},
+		{oids.OIDSubjectEmail, &n.Email},
+		{oids.OIDSubjectBusinessCategory, &n.BusinessCategory},
+		{oids.OIDSubjectJOILocality, &n.JOILocality},
+		{oids.OIDSubjectJOIState, &n.JOIState},
+		{oids.OIDSubjectJOICountry, &n.JOICountry},
+	} {
+		if field.oid.Equal(oid) {
+			return field.to
+		}
+	}
+
+	return nil
+}
+
+// parseDNAttributes splits a string representation of a distinguished name
+// into its attribute types and values, unescaping the values.
+func parseDNAttributes(s string) ([]dnAttribute, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, errors.New("empty distinguished name")
+	}
+
+	var attrs []dnAttribute
+
+	for pos := 0; pos <= len(s); pos++ {
+		if strings.TrimSpace(s[pos:]) == "" {
+			return nil, errors.New("trailing separator in distinguished name")
+		}
+
+		var eq = strings.IndexByte(s[pos:], '=')
+		if eq == -1 {
+			return nil, fmt.Errorf("missing value for attribute %q in distinguished name", strings.TrimSpace(s[pos:]))
+		}
+
+		var typ = strings.TrimSpace(s[pos : pos+eq])
+		if typ == "" || strings.ContainsAny(typ, ",+;") {
+			return nil, fmt.Errorf("missing attribute type in distinguished name at position %d", pos)
+		}
+
+		var value string
+		var n int
+		var err error
+		if value, n, err = parseDNValue(s[pos+eq+1:]); err != nil {
+			return nil, fmt.Errorf("invalid value for attribute %q in distinguished name: %w", typ, err)
+		}
+
+		if value == "" {
+			return nil, fmt.Errorf("empty value for attribute %q in distinguished name", typ)
+		}
+
+		attrs = append(attrs, dnAttribute{typ: typ, value: value})
+
+		pos += eq + 1 + n
+	}
+
+	return attrs, nil
+}
+
+// parseDNValue parses and unescapes an attribute value at the start of a
+// string, and returns it along with the number of bytes consumed, which
+// excludes any terminating separator. Unescaped leading and trailing spaces
+// are removed.
+func parseDNValue(s string) (string, int, error) {
+	var start = len(s) - len(strings.TrimLeft(s, " "))
+
+	if strings.HasPrefix(s[start:], "#") {
+		var end = start + strings.IndexAny(s[start:]+",", ",+;")
+
+		var value, err = decodeDNHexValue(strings.TrimRight(s[start+1:end], " "))
+		if err != nil {
+			return "", 0, err
+		}
+
+		return value, end, nil
+	}
+
+	var value []byte
+	var keep int // length of value ending with the last character to keep
+
+	var i = start
+	for ; i < len(s); i++ {
+		var c = s[i]
+
+		switch {
+		case c == ',' || c == '+' || c == ';':
+			return finishDNValue(value[:keep], i)
+
+		case c == '\\':
+			if i+1 >= len(s) {
+				return "", 0, errors.New("trailing backslash")
+			}
+
+			if i+2 < len(s) && isHexDigit(s[i+1]) && isHexDigit(s[i+2]) {
+				var b, _ = hex.DecodeString(s[i+1 : i+3])
+				value = append(value, b[0])
+				i += 2
+			} else if strings.IndexByte(dnEscapable, s[i+1]) != -1 {
+				value = append(value, s[i+1])
+				i++
+			} else {
+				return "", 0, fmt.Errorf("invalid escape sequence %q", s[i:i+2])
+			}
+
+			keep = len(value)
+
+		default:
+			value = append(value, c)
+			if c != ' ' {
+				keep = len(value)
+			}
+		}
+	}
+
+	return finishDNValue(value[:keep], i)
+}
+
+// finishDNValue checks an unescaped attribute value is valid UTF-8.
+func finishDNValue(value []byte, n int) (string, int, error) {
+	if !utf8.Valid(value) {
+		return "", 0, errors.New("value is not valid UTF-8")
+	}
+
+	return string(value), n, nil
+}
+
+// isHexDigit reports whether a character is a hexadecimal digit.
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// decodeDNHexValue decodes the hex-encoded DER encoding of an attribute
+// value, which must be a string.
+func decodeDNHexValue(s string) (string, error) {
+	var der, err = hex.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid hex encoding: %w", err)
+	}
+
+	var value string
+	var rest []byte
+	if rest, err = asn1.Unmarshal(der, &value); err != nil {
+		return "", fmt.Errorf("invalid DER-encoded string: %w", err)
+	} else if len(rest) > 0 {
+		return "", errors.New("trailing data after DER-encoded string")
+	}
+
+	return value, nil
+}
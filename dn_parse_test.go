@@ -0,0 +1,138 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"encoding/asn1"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseDN(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		in   string
+		want hvclient.DN
+	}{
+		{
+			name: "Simple",
+			in:   "CN=foo,O=ACME,C=GB",
+			want: hvclient.DN{CommonName: "foo", Organization: "ACME", Country: "GB"},
+		},
+		{
+			name: "AllFields",
+			in: "cn=John Doe,serialNumber=1234,OU=Sales,OU=Marketing,O=ACME Inc.,street=1 Main St," +
+				"L=London,ST=England,C=GB,emailAddress=jdoe@acme.com,businessCategory=Private Organization," +
+				"jurisdictionL=Leeds,jurisdictionST=Yorkshire,jurisdictionC=GB",
+			want: hvclient.DN{
+				CommonName:         "John Doe",
+				SerialNumber:       "1234",
+				OrganizationalUnit: []string{"Sales", "Marketing"},
+				Organization:       "ACME Inc.",
+				StreetAddress:      "1 Main St",
+				Locality:           "London",
+				State:              "England",
+				Country:            "GB",
+				Email:              "jdoe@acme.com",
+				BusinessCategory:   "Private Organization",
+				JOILocality:        "Leeds",
+				JOIState:           "Yorkshire",
+				JOICountry:         "GB",
+			},
+		},
+		{
+			name: "Escapes",
+			in:   `CN=Doe\, John \+ Co\ ,O=\#1 \"Best\" \3Cwidgets\3E,L=Z\C3\BCrich`,
+			want: hvclient.DN{CommonName: "Doe, John + Co ", Organization: `#1 "Best" <widgets>`, Locality: "Zürich"},
+		},
+		{
+			name: "Spaces",
+			in:   " CN = foo , O = ACME ",
+			want: hvclient.DN{CommonName: "foo", Organization: "ACME"},
+		},
+		{
+			name: "MultiValuedRDN",
+			in:   "CN=foo+serialNumber=42;C=GB",
+			want: hvclient.DN{CommonName: "foo", SerialNumber: "42", Country: "GB"},
+		},
+		{
+			name: "OIDs",
+			in:   "2.5.4.3=foo,2.5.4.4=Doe,2.5.4.42=#0C044A6F686E",
+			want: hvclient.DN{
+				CommonName: "foo",
+				ExtraAttributes: []hvclient.OIDAndString{
+					{OID: asn1.ObjectIdentifier{2, 5, 4, 4}, Value: "Doe"},
+					{OID: asn1.ObjectIdentifier{2, 5, 4, 42}, Value: "John"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = hvclient.ParseDN(tc.in)
+			if err != nil {
+				t.Fatalf("couldn't parse DN: %v", err)
+			}
+
+			if !cmp.Equal(*got, tc.want) {
+				t.Errorf("got %v, want %v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseDNFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		in   string
+	}{
+		{"Empty", ""},
+		{"NoValue", "CN"},
+		{"EmptyValue", "CN=,O=ACME"},
+		{"NoType", "=foo"},
+		{"TrailingSeparator", "CN=foo,"},
+		{"UnknownType", "XX=foo"},
+		{"Duplicate", "CN=foo,CN=bar"},
+		{"BadEscape", `CN=foo\q`},
+		{"TrailingBackslash", `CN=foo\`},
+		{"BadHex", "CN=#0C0"},
+		{"NotString", "CN=#020101"},
+		{"InvalidUTF8", `CN=\FF`},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, err := hvclient.ParseDN(tc.in); err == nil {
+				t.Errorf("unexpectedly parsed DN: %v", *got)
+			}
+		})
+	}
+}
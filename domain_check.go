@@ -0,0 +1,197 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoClaim is wrapped by the errors returned by DomainCheck.Err when no
+// verified domain claim covers a DNS name.
+var ErrNoClaim = errors.New("no verified domain claim")
+
+// DomainCheck is the result of checking whether a certificate may be issued
+// for a DNS name.
+type DomainCheck struct {
+	// Name is the DNS name in the form HVCA expects.
+	Name string
+
+	// PolicyErr is the reason the validation policy does not allow the DNS
+	// name as a SAN DNS name, or nil if it does.
+	PolicyErr error
+
+	// Claim is the most specific domain claim covering the DNS name,
+	// preferring verified claims over others, or nil if there is none.
+	Claim *Claim
+
+	// CoveredBy is another wildcard DNS name checked at the same time which
+	// also covers the DNS name, making it redundant in a multi-SAN
+	// certificate, or the empty string if there is none.
+	CoveredBy string
+}
+
+// Err returns an error describing why a certificate may not be issued for
+// the DNS name, or nil if it may.
+func (d DomainCheck) Err() error {
+	switch {
+	case d.PolicyErr != nil:
+		return d.PolicyErr
+
+	case d.Claim == nil:
+		return fmt.Errorf("%w covers %s", ErrNoClaim, d.Name)
+
+	case d.Claim.Status != StatusVerified:
+		return fmt.Errorf("%w covers %s: claim for %s is %s",
+			ErrNoClaim, d.Name, d.Claim.Domain, strings.ToLower(d.Claim.Status.String()))
+	}
+
+	return nil
+}
+
+// AllowsDNSName checks whether the validation policy allows a DNS name,
+// which may be a wildcard, as a SAN DNS name, and returns an error
+// describing why not if it does not. Only the DNS name itself is checked,
+// not the number of DNS names in a request.
+func (p *Policy) AllowsDNSName(name string) error {
+	var ascii, err = CheckDNSName(name)
+	if err != nil {
+		return err
+	}
+
+	var pol *ListPolicy
+	if p.SAN != nil {
+		pol = p.SAN.DNSNames
+	}
+
+	if pol == nil || pol.MaxCount == 0 {
+		return errors.New("SAN DNS names not allowed by policy")
+	}
+
+	var match func(string) bool
+	if match, err = pol.matcher(); err != nil {
+		return err
+	}
+
+	if !match(ascii) {
+		if pol.Static {
+			return fmt.Errorf("DNS name %q is not one of the static values %q", ascii, pol.List)
+		}
+
+		return fmt.Errorf("DNS name %q does not match any of the formats %q", ascii, pol.List)
+	}
+
+	return nil
+}
+
+// CheckDomains checks the validation policy and domain claims and reports,
+// for each of the specified DNS names, whether a certificate may be issued
+// for it and which domain claim covers it. DNS names may include wildcards,
+// and any which are covered by a wildcard also specified are identified, to
+// help with planning multi-SAN certificates. An error is returned only if
+// the policy or claims could not be retrieved.
+func (c *Client) CheckDomains(ctx context.Context, names ...string) ([]DomainCheck, error) {
+	var pol, err = c.Policy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't retrieve validation policy: %w", err)
+	}
+
+	var claims []Claim
+	if claims, err = c.ClaimsDomainsAll(ctx, StatusAll); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve domain claims: %w", err)
+	}
+
+	return checkDomains(pol, claims, names), nil
+}
+
+// checkDomains checks each of the DNS names against a validation policy and
+// a list of domain claims.
+func checkDomains(pol *Policy, claims []Claim, names []string) []DomainCheck {
+	var checks = make([]DomainCheck, 0, len(names))
+
+	for _, name := range names {
+		var check = DomainCheck{Name: name, PolicyErr: pol.AllowsDNSName(name)}
+
+		if ascii, err := CheckDNSName(name); err == nil {
+			check.Name = ascii
+			check.Claim = ClaimForDNSName(claims, ascii)
+		}
+
+		checks = append(checks, check)
+	}
+
+	for i := range checks {
+		for j := range checks {
+			if i != j && WildcardCovers(checks[j].Name, checks[i].Name) {
+				checks[i].CoveredBy = checks[j].Name
+				break
+			}
+		}
+	}
+
+	return checks
+}
+
+// ClaimForDNSName returns the domain claim covering a DNS name, which may be
+// a wildcard, or nil if there is none. A claim covers a DNS name if its
+// domain is the DNS name, excluding any wildcard label, or one of its parent
+// domains. A verified claim is preferred to others, and then the claim for
+// the most specific domain is preferred.
+func ClaimForDNSName(claims []Claim, name string) *Claim {
+	var domain = normalizeClaimDomain(strings.TrimPrefix(name, "*."))
+
+	var best *Claim
+	var bestLen int
+
+	for i := range claims {
+		var claimDomain = normalizeClaimDomain(claims[i].Domain)
+		if claimDomain == "" || (domain != claimDomain && !strings.HasSuffix(domain, "."+claimDomain)) {
+			continue
+		}
+
+		var verified = claims[i].Status == StatusVerified
+
+		switch {
+		case best == nil,
+			verified && best.Status != StatusVerified,
+			verified == (best.Status == StatusVerified) && len(claimDomain) > bestLen:
+			best, bestLen = &claims[i], len(claimDomain)
+		}
+	}
+
+	return best
+}
+
+// WildcardCovers reports whether a wildcard DNS name, such as
+// "*.example.com", covers another DNS name. A wildcard covers only names
+// with exactly one label in place of the wildcard, so "*.example.com"
+// covers "www.example.com" but neither "example.com" nor
+// "a.b.example.com". A wildcard does not cover itself, and a DNS name which
+// is not a wildcard covers nothing.
+func WildcardCovers(wildcard, name string) bool {
+	if !strings.HasPrefix(wildcard, "*.") {
+		return false
+	}
+
+	var i = strings.IndexByte(name, '.')
+	if i < 1 || name[:i] == "*" {
+		return false
+	}
+
+	return strings.EqualFold(wildcard[2:], name[i+1:])
+}
@@ -0,0 +1,261 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+)
+
+func TestPolicyAllowsDNSName(t *testing.T) {
+	t.Parallel()
+
+	var pol = hvclient.Policy{
+		SAN: &hvclient.SANPolicy{
+			DNSNames: &hvclient.ListPolicy{
+				MinCount: 1,
+				MaxCount: 10,
+				List:     []string{`^(\*\.)?([a-z0-9-]+\.)*example\.com$`},
+			},
+		},
+	}
+
+	var testcases = []struct {
+		name  string
+		value string
+		err   bool
+	}{
+		{"Specific", "www.example.com", false},
+		{"Wildcard", "*.example.com", false},
+		{"NestedWildcard", "*.dev.example.com", false},
+		{"UpperCase", "WWW.Example.COM", false},
+		{"OtherDomain", "www.example.net", true},
+		{"BadWildcard", "w*.example.com", true},
+		{"Empty", "", true},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := pol.AllowsDNSName(tc.value); (err != nil) != tc.err {
+				t.Errorf("got error %v, want error %t", err, tc.err)
+			}
+		})
+	}
+
+	var static = hvclient.Policy{
+		SAN: &hvclient.SANPolicy{
+			DNSNames: &hvclient.ListPolicy{MaxCount: 1, Static: true, List: []string{"example.com"}},
+		},
+	}
+
+	if err := static.AllowsDNSName("example.com"); err != nil {
+		t.Errorf("static value not allowed: %v", err)
+	}
+
+	if err := static.AllowsDNSName("*.example.com"); err == nil {
+		t.Errorf("non-static value unexpectedly allowed")
+	}
+
+	if err := (&hvclient.Policy{}).AllowsDNSName("example.com"); err == nil {
+		t.Errorf("DNS name unexpectedly allowed by policy without SAN DNS names")
+	}
+}
+
+func TestClaimForDNSName(t *testing.T) {
+	t.Parallel()
+
+	var claims = []hvclient.Claim{
+		{ID: "1", Status: hvclient.StatusVerified, Domain: "example.com."},
+		{ID: "2", Status: hvclient.StatusPending, Domain: "dev.example.com."},
+		{ID: "3", Status: hvclient.StatusVerified, Domain: "prod.example.com."},
+		{ID: "4", Status: hvclient.StatusPending, Domain: "example.net."},
+	}
+
+	var testcases = []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"Exact", "example.com", "1"},
+		{"Subdomain", "www.example.com", "1"},
+		{"Wildcard", "*.example.com", "1"},
+		{"MoreSpecific", "www.prod.example.com", "3"},
+		{"WildcardMoreSpecific", "*.prod.example.com", "3"},
+		{"PreferVerified", "www.dev.example.com", "1"},
+		{"PendingOnly", "www.example.net", "4"},
+		{"CaseInsensitive", "WWW.PROD.EXAMPLE.COM", "3"},
+		{"SuffixNotParent", "notexample.com", ""},
+		{"None", "example.org", ""},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+			if claim := hvclient.ClaimForDNSName(claims, tc.value); claim != nil {
+				got = claim.ID
+			}
+
+			if got != tc.want {
+				t.Errorf("got claim %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWildcardCovers(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		wildcard string
+		name     string
+		want     bool
+	}{
+		{"*.example.com", "www.example.com", true},
+		{"*.example.com", "WWW.EXAMPLE.COM", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "a.b.example.com", false},
+		{"*.example.com", "*.example.com", false},
+		{"*.example.com", "www.example.net", false},
+		{"www.example.com", "www.example.com", false},
+		{"*.example.com", ".example.com", false},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.wildcard+"/"+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := hvclient.WildcardCovers(tc.wildcard, tc.name); got != tc.want {
+				t.Errorf("got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClientCheckDomains(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var checks, err = client.CheckDomains(ctx, "*.fake.com", "WWW.fake.com", "www.pending1.com", "example.org")
+	if err != nil {
+		t.Fatalf("couldn't check domains: %v", err)
+	}
+
+	if len(checks) != 4 {
+		t.Fatalf("got %d results, want 4", len(checks))
+	}
+
+	// The mock validation policy does not allow SAN DNS names.
+	for _, check := range checks {
+		if check.PolicyErr == nil {
+			t.Errorf("%s: unexpectedly allowed by policy", check.Name)
+		}
+	}
+
+	for i, want := range []struct {
+		name      string
+		domain    string
+		coveredBy string
+	}{
+		{"*.fake.com", "fake.com.", ""},
+		{"www.fake.com", "fake.com.", "*.fake.com"},
+		{"www.pending1.com", "pending1.com.", ""},
+		{"example.org", "", ""},
+	} {
+		var got = checks[i]
+
+		if got.Name != want.name {
+			t.Errorf("%d: got name %q, want %q", i, got.Name, want.name)
+		}
+
+		var domain string
+		if got.Claim != nil {
+			domain = got.Claim.Domain
+		}
+
+		if domain != want.domain {
+			t.Errorf("%s: got claim for %q, want %q", got.Name, domain, want.domain)
+		}
+
+		if got.CoveredBy != want.coveredBy {
+			t.Errorf("%s: got covered by %q, want %q", got.Name, got.CoveredBy, want.coveredBy)
+		}
+	}
+
+	if err = checks[0].Err(); err != checks[0].PolicyErr {
+		t.Errorf("got error %v, want %v", err, checks[0].PolicyErr)
+	}
+}
+
+func TestDomainCheckErr(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		check hvclient.DomainCheck
+		want  error
+	}{
+		{
+			name: "Issuable",
+			check: hvclient.DomainCheck{
+				Name:  "www.example.com",
+				Claim: &hvclient.Claim{Status: hvclient.StatusVerified, Domain: "example.com."},
+			},
+		},
+		{
+			name:  "NoClaim",
+			check: hvclient.DomainCheck{Name: "www.example.com"},
+			want:  hvclient.ErrNoClaim,
+		},
+		{
+			name: "PendingClaim",
+			check: hvclient.DomainCheck{
+				Name:  "www.example.com",
+				Claim: &hvclient.Claim{Status: hvclient.StatusPending, Domain: "example.com."},
+			},
+			want: hvclient.ErrNoClaim,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tc.check.Err(); !errors.Is(err, tc.want) || (err == nil) != (tc.want == nil) {
+				t.Errorf("got error %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// CertificateRequestDryRun checks whether a certificate request would be
+// accepted, without submitting it to HVCA or consuming any issuance quota,
+// and returns every problem found. HVCA provides no way to validate a
+// request without issuing a certificate, so the request is checked on the
+// client against the account's validation policy, as for
+// Policy.LintTemplate. If the client was configured with StrictDNSNames,
+// the SAN DNS names are also checked with CheckDNSName, if it was
+// configured with StrictEmails, the SAN email addresses are also checked
+// with CheckEmail, and if it was configured with CheckCAA, the CAA records
+// of the DNS names are also checked.
+//
+// An error is returned only if the check could not be performed, for
+// example because the request could not be marshalled or the validation
+// policy could not be retrieved. An empty list of issues does not guarantee
+// that HVCA will accept the request, since HVCA may apply checks which are
+// not described by the policy.
+func (c *Client) CertificateRequestDryRun(ctx context.Context, req *Request) ([]LintIssue, error) {
+	if req == nil {
+		return nil, errors.New("no request provided")
+	}
+
+	if _, err := json.Marshal(req); err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	var pol, err = c.Policy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []LintIssue
+
+	if c.config.StrictDNSNames && req.SAN != nil {
+		for _, name := range req.SAN.DNSNames {
+			if _, err := CheckDNSName(name); err != nil {
+				issues = append(issues, LintIssue{Field: "san.dns_names", Message: err.Error()})
+			}
+		}
+	}
+
+	if c.config.StrictEmails && req.SAN != nil {
+		for _, addr := range req.SAN.Emails {
+			if _, err := CheckEmail(addr); err != nil {
+				issues = append(issues, LintIssue{Field: "san.emails", Message: err.Error()})
+			}
+		}
+	}
+
+	if c.config.CheckCAA && req.SAN != nil {
+		if err := c.CheckCAA(ctx, req.SAN.DNSNames); err != nil {
+			issues = append(issues, LintIssue{Field: "san.dns_names", Message: err.Error()})
+		}
+	}
+
+	return append(issues, pol.LintTemplate(req)...), nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCertificateRequestDryRun(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name   string
+		strict bool
+		modify func(*hvclient.Request)
+		want   []string
+	}{
+		{
+			name: "Accepted",
+		},
+		{
+			name: "Policy",
+			modify: func(req *hvclient.Request) {
+				req.Subject.CommonName = "John Doe"
+			},
+			want: []string{"subject_dn.common_name"},
+		},
+		{
+			name: "DNSNamesNotStrict",
+			modify: func(req *hvclient.Request) {
+				req.SAN = &hvclient.SAN{DNSNames: []string{"192.0.2.1"}}
+			},
+			want: []string{"san.dns_names"},
+		},
+		{
+			name:   "DNSNamesStrict",
+			strict: true,
+			modify: func(req *hvclient.Request) {
+				req.SAN = &hvclient.SAN{DNSNames: []string{"192.0.2.1", "www.example.com."}}
+			},
+			want: []string{"san.dns_names", "san.dns_names", "san.dns_names"},
+		},
+		{
+			name:   "EmailsStrict",
+			strict: true,
+			modify: func(req *hvclient.Request) {
+				req.SAN = &hvclient.SAN{Emails: []string{"John Doe <john@example.com>"}}
+			},
+			want: []string{"san.emails", "san.emails"},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var clnt, closefunc = newMockClientWithConfig(t, func(conf *hvclient.Config) {
+				conf.StrictDNSNames = tc.strict
+				conf.StrictEmails = tc.strict
+			})
+			defer closefunc()
+
+			var ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+
+			var req = exampleRequest()
+			req.Subject.CommonName = "JohnDoe"
+
+			if tc.modify != nil {
+				tc.modify(req)
+			}
+
+			var issues, err = clnt.CertificateRequestDryRun(ctx, req)
+			if err != nil {
+				t.Fatalf("couldn't perform dry run: %v", err)
+			}
+
+			var got []string
+			for _, issue := range issues {
+				got = append(got, issue.Field)
+			}
+
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("got issues %v, want fields %v", issues, tc.want)
+			}
+		})
+	}
+}
+
+func TestCertificateRequestDryRunFailure(t *testing.T) {
+	t.Parallel()
+
+	var clnt, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := clnt.CertificateRequestDryRun(ctx, nil); err == nil {
+		t.Errorf("unexpectedly performed dry run with no request")
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationUnits maps the unit suffixes accepted by ParseDuration, in upper
+// case, to the duration of one unit.
+var durationUnits = map[string]time.Duration{
+	"S": time.Second, "SEC": time.Second, "SECS": time.Second, "SECOND": time.Second, "SECONDS": time.Second,
+	"M": time.Minute, "MIN": time.Minute, "MINS": time.Minute, "MINUTE": time.Minute, "MINUTES": time.Minute,
+	"H": time.Hour, "HR": time.Hour, "HRS": time.Hour, "HOUR": time.Hour, "HOURS": time.Hour,
+	"D": durationDay, "DAY": durationDay, "DAYS": durationDay,
+	"W": durationWeek, "WK": durationWeek, "WKS": durationWeek, "WEEK": durationWeek, "WEEKS": durationWeek,
+	"Y": durationYear, "YR": durationYear, "YRS": durationYear, "YEAR": durationYear, "YEARS": durationYear,
+}
+
+// The lengths of the calendar units accepted by ParseDuration.
+const (
+	durationDay  = time.Hour * 24
+	durationWeek = durationDay * 7
+	durationYear = durationDay * 365
+)
+
+// ParseDuration parses a duration consisting of a whole number followed by
+// a unit, such as "30d", "12h" or "1y", as accepted for certificate
+// validity periods, renewal windows and claim ages. The units are s, m, h,
+// d, w and y, for seconds, minutes, hours, days, weeks and years, and may
+// also be written as words such as "days", in any case. A day is always 24
+// hours and a year 365 days, regardless of daylight saving time and leap
+// years, so a duration of "1y" starting on or before 29 February in a leap
+// year ends one day before the same date in the following year.
+func ParseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, errors.New("missing duration")
+	}
+
+	// Break string into duration value and units.
+	var i = strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if i == -1 {
+		return 0, fmt.Errorf("missing duration unit in %q, e.g. %sd for days", s, s)
+	}
+
+	var extent, err = strconv.ParseInt(s[:i], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration quantity in %q", s)
+	}
+
+	var unit = s[i:]
+
+	var size, ok = durationUnits[strings.ToUpper(unit)]
+	if !ok {
+		return 0, fmt.Errorf("invalid duration unit %q: use s, m, h, d, w or y", unit)
+	}
+
+	if extent > int64(math.MaxInt64/size) {
+		return 0, fmt.Errorf("duration %q is too long", s)
+	}
+
+	return size * time.Duration(extent), nil
+}
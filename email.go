@@ -0,0 +1,145 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// ErrInvalidEmail is wrapped by errors returned by CheckEmail when an email
+// address would be rejected by HVCA.
+var ErrInvalidEmail = errors.New("invalid email address")
+
+const (
+	// maxEmailLength is the maximum length of an email address, being the
+	// maximum length of the path in an SMTP command less the angle
+	// brackets, as described in RFC 5321 section 4.5.3.1.3.
+	maxEmailLength = 254
+
+	// maxEmailLocalPartLength is the maximum length of the local part of an
+	// email address, as described in RFC 5321 section 4.5.3.1.1.
+	maxEmailLocalPartLength = 64
+)
+
+// CheckEmail checks that an email address is suitable for inclusion in the
+// SAN email addresses of a certificate request, such as for an S/MIME
+// certificate, and returns it in the form HVCA expects. The address must be
+// a bare RFC 5322 address, without a display name or angle brackets, and
+// its domain must be a valid DNS name rather than an address literal. The
+// domain is converted to lower case, and any labels containing Unicode
+// characters are converted to A-labels (punycode), but the local part,
+// which must be ASCII, is left unchanged since it may be case sensitive.
+// An error wrapping ErrInvalidEmail is returned if the address is not
+// valid.
+func CheckEmail(addr string) (string, error) {
+	var invalid = func(reason string) error {
+		return fmt.Errorf("%w %q: %s", ErrInvalidEmail, addr, reason)
+	}
+
+	var trimmed = strings.TrimSpace(addr)
+
+	switch {
+	case trimmed == "":
+		return "", invalid("empty address")
+
+	case strings.ContainsAny(trimmed, "<>"):
+		return "", invalid("display name and angle brackets are not allowed")
+	}
+
+	var parsed, err = mail.ParseAddress(trimmed)
+	if err != nil {
+		return "", invalid(err.Error())
+	} else if parsed.Name != "" {
+		return "", invalid("display name is not allowed")
+	}
+
+	var at = strings.LastIndexByte(trimmed, '@')
+	if at == -1 {
+		return "", invalid("missing @")
+	}
+
+	var local, domain = trimmed[:at], trimmed[at+1:]
+
+	switch {
+	case !isASCII(local):
+		return "", invalid("local part must be ASCII")
+
+	case len(local) > maxEmailLocalPartLength:
+		return "", invalid(fmt.Sprintf("local part longer than %d characters", maxEmailLocalPartLength))
+
+	case strings.HasPrefix(domain, "["):
+		return "", invalid("address literals are not allowed")
+
+	case strings.HasSuffix(domain, "."):
+		return "", invalid("trailing dot in domain")
+
+	case strings.Contains(domain, "*"):
+		return "", invalid("wildcards are not allowed")
+	}
+
+	var ascii string
+	if ascii, err = dnsNameProfile.ToASCII(domain); err != nil {
+		return "", invalid(err.Error())
+	}
+
+	if !strings.Contains(ascii, ".") {
+		return "", invalid("domain must have at least two labels")
+	}
+
+	var checked = local + "@" + ascii
+	if len(checked) > maxEmailLength {
+		return "", invalid(fmt.Sprintf("longer than %d characters", maxEmailLength))
+	}
+
+	return checked, nil
+}
+
+// checkEmails applies CheckEmail to each of a list of email addresses, and
+// returns a new list of the converted addresses.
+func checkEmails(addrs []string) ([]string, error) {
+	if addrs == nil {
+		return nil, nil
+	}
+
+	var checked = make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		var normalized, err = CheckEmail(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		checked = append(checked, normalized)
+	}
+
+	return checked, nil
+}
+
+// CheckEmails applies CheckEmail to each SAN email address, replacing them
+// with their converted forms. The email addresses are left unchanged if any
+// of them is invalid.
+func (s *SAN) CheckEmails() error {
+	var checked, err = checkEmails(s.Emails)
+	if err != nil {
+		return err
+	}
+
+	s.Emails = checked
+
+	return nil
+}
@@ -0,0 +1,173 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckEmail(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "ASCII",
+			value: "john.doe@example.com",
+			want:  "john.doe@example.com",
+		},
+		{
+			name:  "UpperCaseDomain",
+			value: "John.Doe@EXAMPLE.Com",
+			want:  "John.Doe@example.com",
+		},
+		{
+			name:  "UnicodeDomain",
+			value: "info@bücher.example",
+			want:  "info@xn--bcher-kva.example",
+		},
+		{
+			name:  "Whitespace",
+			value: "  john@example.com ",
+			want:  "john@example.com",
+		},
+		{
+			name:  "PlusAddressing",
+			value: "john+smime@example.com",
+			want:  "john+smime@example.com",
+		},
+		{
+			name:  "QuotedLocalPart",
+			value: `"john doe"@example.com`,
+			want:  `"john doe"@example.com`,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = hvclient.CheckEmail(tc.value)
+			if err != nil {
+				t.Fatalf("couldn't check email address: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckEmailFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		value string
+	}{
+		{"Empty", ""},
+		{"NoAt", "john.example.com"},
+		{"NoLocalPart", "@example.com"},
+		{"NoDomain", "john@"},
+		{"DisplayName", "John Doe <john@example.com>"},
+		{"AngleBrackets", "<john@example.com>"},
+		{"AddressLiteral", "john@[192.0.2.1]"},
+		{"TrailingDot", "john@example.com."},
+		{"Wildcard", "john@*.example.com"},
+		{"SingleLabel", "john@localhost"},
+		{"UnicodeLocalPart", "jöhn@example.com"},
+		{"LongLocalPart", strings.Repeat("a", 65) + "@example.com"},
+		{"TooLong", strings.Repeat("a", 64) + "@" + strings.Repeat("b", 62) + "." + strings.Repeat("c", 62) + "." + strings.Repeat("d", 62) + ".com"},
+		{"Space", "john doe@example.com"},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var _, err = hvclient.CheckEmail(tc.value)
+			if !errors.Is(err, hvclient.ErrInvalidEmail) {
+				t.Fatalf("got error %v, want %v", err, hvclient.ErrInvalidEmail)
+			}
+		})
+	}
+}
+
+func TestSANCheckEmails(t *testing.T) {
+	t.Parallel()
+
+	var san = hvclient.SAN{Emails: []string{"info@bücher.example", "John@EXAMPLE.com"}}
+	if err := san.CheckEmails(); err != nil {
+		t.Fatalf("couldn't check email addresses: %v", err)
+	}
+
+	var want = []string{"info@xn--bcher-kva.example", "John@example.com"}
+	if !cmp.Equal(san.Emails, want) {
+		t.Errorf("got %q, want %q", san.Emails, want)
+	}
+
+	san = hvclient.SAN{Emails: []string{"John@EXAMPLE.com", "john@[192.0.2.1]"}}
+	if err := san.CheckEmails(); !errors.Is(err, hvclient.ErrInvalidEmail) {
+		t.Fatalf("got error %v, want %v", err, hvclient.ErrInvalidEmail)
+	}
+
+	if want = []string{"John@EXAMPLE.com", "john@[192.0.2.1]"}; !cmp.Equal(san.Emails, want) {
+		t.Errorf("got %q, want unchanged %q", san.Emails, want)
+	}
+}
+
+func TestClientStrictEmails(t *testing.T) {
+	t.Parallel()
+
+	var clnt, closefunc = newMockClientWithConfig(t, func(conf *hvclient.Config) {
+		conf.StrictEmails = true
+	})
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var req = exampleRequest()
+	req.SAN = &hvclient.SAN{Emails: []string{"John@EXAMPLE.com"}}
+
+	if _, err := clnt.CertificateRequest(ctx, req); err != nil {
+		t.Fatalf("couldn't request certificate: %v", err)
+	}
+
+	if want := []string{"John@EXAMPLE.com"}; !cmp.Equal(req.SAN.Emails, want) {
+		t.Errorf("request was modified: got %q, want %q", req.SAN.Emails, want)
+	}
+
+	req.SAN.Emails = []string{"John Doe <john@example.com>"}
+
+	if _, err := clnt.CertificateRequest(ctx, req); !errors.Is(err, hvclient.ErrInvalidEmail) {
+		t.Errorf("got error %v, want %v", err, hvclient.ErrInvalidEmail)
+	}
+}
@@ -0,0 +1,763 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+)
+
+// exampleClient returns a client connected to a mock HVCA server, and a
+// function to shut the server down. A real application would instead create
+// a client with the URL and credentials of its own HVCA account.
+func exampleClient() (*hvclient.Client, func()) {
+	var server = hvcatest.NewServer()
+
+	var clnt, err = hvclient.NewClient(context.Background(), hvcatest.Config(server.URL))
+	if err != nil {
+		server.Close()
+		log.Fatalf("couldn't create client: %v", err)
+	}
+
+	return clnt, server.Close
+}
+
+// exampleRequest returns a certificate request for a newly generated key.
+func exampleRequest() *hvclient.Request {
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		log.Fatalf("couldn't generate key: %v", err)
+	}
+
+	return &hvclient.Request{
+		Validity: &hvclient.Validity{
+			NotBefore: time.Now(),
+			NotAfter:  time.Now().Add(time.Hour * 24 * 30),
+		},
+		Subject: &hvclient.DN{
+			CommonName: "John Doe",
+		},
+		PrivateKey: key,
+	}
+}
+
+func ExampleNewClient() {
+	var server = hvcatest.NewServer()
+	defer server.Close()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var clnt, err = hvclient.NewClient(ctx, &hvclient.Config{
+		URL:       server.URL,
+		APIKey:    hvcatest.APIKey,
+		APISecret: hvcatest.APISecret,
+		ExtraHeaders: map[string]string{
+			hvcatest.SSLClientSerialHeader: hvcatest.SSLClientSerial,
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(clnt.DefaultTimeout())
+
+	// Output:
+	// 1m0s
+}
+
+func ExampleNew() {
+	var server = hvcatest.NewServer()
+	defer server.Close()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var clnt, err = hvclient.New(ctx,
+		hvclient.WithURL(server.URL),
+		hvclient.WithAPICredentials(hvcatest.APIKey, hvcatest.APISecret),
+		hvclient.WithHeader(hvcatest.SSLClientSerialHeader, hvcatest.SSLClientSerial),
+		hvclient.WithTimeout(time.Second*30),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(clnt.DefaultTimeout())
+
+	// Output:
+	// 30s
+}
+
+func ExampleClient_CertificateRequest() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var serial, err = clnt.CertificateRequest(ctx, exampleRequest())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%X\n", serial)
+
+	// Output:
+	// 741DAF9EC2D5F7DC
+}
+
+func ExampleClient_CertificateRequestWithLocation() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var serial, location, err = clnt.CertificateRequestWithLocation(ctx, exampleRequest())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%X %s\n", serial, location.Path)
+
+	// Output:
+	// 741DAF9EC2D5F7DC /certificates/741DAF9EC2D5F7DC
+}
+
+func ExampleClient_CertificateRetrieve() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var info, err = clnt.CertificateRetrieve(ctx, hvcatest.Cert.SerialNumber)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(info.X509.Subject.CommonName)
+	fmt.Println(info.Status)
+
+	// Output:
+	// John Doe
+	// ISSUED
+}
+
+func ExampleClient_CertificateRetrieveByURL() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var _, location, err = clnt.CertificateRequestWithLocation(ctx, exampleRequest())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var info *hvclient.CertInfo
+	if info, err = clnt.CertificateRetrieveByURL(ctx, location); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%X\n", info.X509.SerialNumber)
+
+	// Output:
+	// 741DAF9EC2D5F7DC
+}
+
+func ExampleClient_CertificateRevoke() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	if err := clnt.CertificateRevoke(ctx, hvcatest.Cert.SerialNumber); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("revoked")
+
+	// Output:
+	// revoked
+}
+
+func ExampleClient_CertificateRevokeWithReason() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var compromised = time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+	if err := clnt.CertificateRevokeWithReason(ctx, hvcatest.Cert.SerialNumber,
+		hvclient.RevocationReasonKeyCompromise, compromised); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("revoked")
+
+	// Output:
+	// revoked
+}
+
+func ExampleClient_CertificateRekey() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var serial *big.Int
+	if serial, err = clnt.CertificateRekey(ctx, hvcatest.Cert.SerialNumber,
+		&hvclient.CertificateRekeyRequest{PrivateKey: key}); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%X\n", serial)
+
+	// Output:
+	// 741DAF9EC2D5F7DC
+}
+
+func ExampleClient_CertificateHistory() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var events, err = clnt.CertificateHistory(ctx, hvcatest.Cert.SerialNumber)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, event := range events {
+		fmt.Println(event.Type, event.Time.UTC().Format(time.RFC3339))
+	}
+
+	// Output:
+	// ISSUED 2021-06-18T16:29:51Z
+	// EXPIRY 2021-09-16T16:29:51Z
+}
+
+func ExampleClient_TrustChain() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var chain, err = clnt.TrustChain(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, cert := range chain {
+		fmt.Println(cert.Subject.CommonName)
+	}
+
+	// Output:
+	// Testing-Only Non-Production Intermediate CA
+	// Testing-Only Non-Production Root CA
+}
+
+func ExampleClient_Policy() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var pol, err = clnt.Policy(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(pol.PublicKey.KeyType, pol.PublicKey.AllowedLengths)
+	fmt.Println(pol.SubjectDN.CommonName.Presence)
+
+	// Output:
+	// ECDSA [256 384 521]
+	// REQUIRED
+}
+
+func ExampleClient_CounterCertsIssued() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var count, err = clnt.CounterCertsIssued(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(count)
+
+	// Output:
+	// 72
+}
+
+func ExampleClient_CounterCertsRevoked() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var count, err = clnt.CounterCertsRevoked(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(count)
+
+	// Output:
+	// 14
+}
+
+func ExampleClient_QuotaIssuance() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var quota, err = clnt.QuotaIssuance(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(quota)
+
+	// Output:
+	// 42
+}
+
+func ExampleClient_Do() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var quota struct {
+		Value int64 `json:"value"`
+	}
+
+	var resp, err = clnt.Do(ctx, http.MethodGet, "/quotas/issuance", nil, &quota)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(resp.StatusCode, quota.Value)
+
+	// Output:
+	// 200 42
+}
+
+func ExampleMustOID() {
+	var attr = hvclient.OIDAndString{OID: hvclient.MustOID("2.5.4.4"), Value: "Doe"}
+
+	var data, err = json.Marshal(attr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(string(data))
+
+	// Output:
+	// {"type":"2.5.4.4","value":"Doe"}
+}
+
+func ExampleClient_StatsIssued() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var from = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	var to = time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	var certs, total, err = clnt.StatsIssued(ctx, 1, 100, from, to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%d of %d certificates:\n", len(certs), total)
+	for _, cert := range certs {
+		fmt.Printf("%X\n", cert.SerialNumber)
+	}
+
+	// Output:
+	// 3 of 3 certificates:
+	// 741DAF9EC2D5F7DC
+	// 87BC1DC5524A2B18
+	// F488BCE14A56CD2A
+}
+
+func ExampleClient_StatsIssuedAll() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var from = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	var to = time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	var certs, err = clnt.StatsIssuedAll(ctx, from, to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, cert := range certs {
+		fmt.Printf("%X %s\n", cert.SerialNumber, cert.NotAfter.UTC().Format("2006-01-02"))
+	}
+
+	// Output:
+	// 741DAF9EC2D5F7DC 2021-09-16
+	// 87BC1DC5524A2B18 2021-09-17
+	// F488BCE14A56CD2A 2021-09-17
+}
+
+func ExampleClient_StatsExpiring() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var from = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	var to = time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	var certs, total, err = clnt.StatsExpiring(ctx, 1, 100, from, to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(len(certs), total)
+
+	// Output:
+	// 4 4
+}
+
+func ExampleClient_StatsExpiringAll() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var from = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	var to = time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	var certs, err = clnt.StatsExpiringAll(ctx, from, to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, cert := range certs {
+		fmt.Printf("%X %s\n", cert.SerialNumber, cert.NotAfter.UTC().Format("2006-01-02"))
+	}
+
+	// Output:
+	// 748BDAE7199CC246 2021-10-10
+	// DEADBEEF44274823 2021-10-12
+	// AA9915DC78BB21FF 2021-10-12
+	// 32897DA7B113DAB6 2021-10-12
+}
+
+func ExampleClient_StatsRevoked() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var from = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	var to = time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	var certs, total, err = clnt.StatsRevoked(ctx, 1, 100, from, to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(len(certs), total)
+
+	// Output:
+	// 2 2
+}
+
+func ExampleClient_StatsRevokedAll() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var from = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	var to = time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	var certs, err = clnt.StatsRevokedAll(ctx, from, to)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(len(certs))
+
+	// Output:
+	// 2
+}
+
+func ExampleClient_ClaimsDomains() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var claims, total, err = clnt.ClaimsDomains(ctx, 1, 100, hvclient.StatusVerified)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%d of %d claims:\n", len(claims), total)
+	for _, claim := range claims {
+		fmt.Println(claim.ID, claim.Domain, claim.Status)
+	}
+
+	// Output:
+	// 1 of 1 claims:
+	// 113FED08 fake.com. VERIFIED
+}
+
+func ExampleClient_ClaimsDomainsAll() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var claims, err = clnt.ClaimsDomainsAll(ctx, hvclient.StatusPending)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, claim := range claims {
+		fmt.Println(claim.ID, claim.Domain, claim.Status)
+	}
+
+	// Output:
+	// pending1 pending1.com. PENDING
+	// pending2 pending2.com. PENDING
+}
+
+func ExampleClient_ClaimSubmit() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var info, err = clnt.ClaimSubmit(ctx, "example.com")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The token should be placed in a DNS TXT record or at the HTTP path
+	// ClaimHTTPPath before the claim is asserted.
+	fmt.Println(info.ID, info.Token)
+	fmt.Println(info.AssertBy.UTC().Format(time.RFC3339))
+
+	// Output:
+	// 113FED08 mock_claim_token
+	// 2021-06-19T13:05:31Z
+}
+
+func ExampleClient_ClaimRetrieve() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var claim, err = clnt.ClaimRetrieve(ctx, hvcatest.ClaimID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(claim.ID, claim.Domain, claim.Status)
+
+	// Output:
+	// 113FED08 fake.com. VERIFIED
+}
+
+func ExampleClient_ClaimDelete() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	if err := clnt.ClaimDelete(ctx, hvcatest.ClaimID); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("deleted")
+
+	// Output:
+	// deleted
+}
+
+func ExampleClient_ClaimDNS() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var verified, err = clnt.ClaimDNS(ctx, hvcatest.ClaimID, hvcatest.ClaimDomainVerified)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(verified)
+
+	// Output:
+	// true
+}
+
+func ExampleClient_ClaimHTTP() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var verified, err = clnt.ClaimHTTP(ctx, hvcatest.ClaimID, hvcatest.ClaimDomainVerified, "HTTPS")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(verified)
+
+	// Output:
+	// true
+}
+
+func ExampleClient_ClaimEmail() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var verified, err = clnt.ClaimEmail(ctx, hvcatest.ClaimID, hvcatest.ClaimEmail)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(verified)
+
+	// Output:
+	// true
+}
+
+func ExampleClient_ClaimEmailRetrieve() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var emails, err = clnt.ClaimEmailRetrieve(ctx, hvcatest.ClaimID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(emails.Constructed[0])
+	fmt.Println(emails.DNS.SOA.Emails)
+
+	// Output:
+	// admin@test.com
+	// [example@test.com]
+}
+
+func ExampleClient_ClaimReassert() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var info, err = clnt.ClaimReassert(ctx, hvcatest.ClaimID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(info.ID, info.Token)
+
+	// Output:
+	// 113FED08 mock_claim_token
+}
+
+func ExampleClient_Reconcile() {
+	var clnt, closefunc = exampleClient()
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	// The deployed certificates would normally be read from disk or from a
+	// certificate store.
+	var findings, err = clnt.Reconcile(ctx, []hvclient.DeployedCertificate{
+		{Path: "/etc/ssl/john.pem", Certificate: hvcatest.Cert},
+	}, time.Time{}, time.Time{}, time.Hour*24*30)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, finding := range findings {
+		fmt.Printf("%v %X\n", finding.Problem, finding.SerialNumber)
+	}
+
+	// Output:
+	// NEAR_EXPIRY 741DAF9EC2D5F7DC
+}
@@ -0,0 +1,154 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+)
+
+// addFuzzSeeds adds the contents of the files matching the specified
+// pattern, and each of the specified values, to the seed corpus.
+func addFuzzSeeds(f *testing.F, pattern string, values ...string) {
+	f.Helper()
+
+	var files, err = filepath.Glob(pattern)
+	if err != nil {
+		f.Fatalf("couldn't find seed files: %v", err)
+	}
+
+	for _, file := range files {
+		var data []byte
+		if data, err = ioutil.ReadFile(file); err != nil {
+			f.Fatalf("couldn't read seed file: %v", err)
+		}
+
+		f.Add(data)
+	}
+
+	for _, value := range values {
+		f.Add([]byte(value))
+	}
+}
+
+// fuzzRoundTrip unmarshals data into the value returned by newValue and, if
+// successful, checks that the value can be marshalled and unmarshalled again
+// without panicking.
+func fuzzRoundTrip(t *testing.T, data []byte, newValue func() interface{}) {
+	var value = newValue()
+	if err := json.Unmarshal(data, value); err != nil {
+		return
+	}
+
+	var encoded, err = json.Marshal(value)
+	if err != nil {
+		return
+	}
+
+	_ = json.Unmarshal(encoded, newValue())
+}
+
+func FuzzRequestUnmarshalJSON(f *testing.F) {
+	addFuzzSeeds(f, "testdata/template_test/*.json",
+		`{"validity":{"not_before":1477958400,"not_after":1477958401}}`,
+		`{"subject_dn":{"common_name":"John Doe","extra_attributes":[{"type":"2.5.4.4","value":"Doe"}]}}`,
+		`{"extended_key_usages":["1.3.6.1.5.5.7.3.2"],"custom_extensions":{"1.2.3.4":"value"}}`,
+		`{"ms_extension_template":{"id":"1.2.3.4","major_version":1,"minor_version":2}}`,
+		`{"subject_dn":{"extra_attributes":[null]},"ms_extension_template":null}`,
+		`null`,
+	)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzRoundTrip(t, data, func() interface{} { return &hvclient.Request{} })
+	})
+}
+
+func FuzzPolicyUnmarshalJSON(f *testing.F) {
+	addFuzzSeeds(f, "testdata/test_validation_policy.json", `null`)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzRoundTrip(t, data, func() interface{} { return &hvclient.Policy{} })
+	})
+}
+
+func FuzzSANUnmarshalJSON(f *testing.F) {
+	addFuzzSeeds(f, "",
+		`{"dns_names":["example.com"],"emails":["jdoe@example.com"],"ip_addresses":["10.0.0.1"]}`,
+		`{"uris":["https://example.com/"],"other_names":[{"type":"1.3.6.1.4.1.311.20.2.3","value":"jdoe"}]}`,
+		`{"other_names":[null]}`,
+	)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzRoundTrip(t, data, func() interface{} { return &hvclient.SAN{} })
+	})
+}
+
+func FuzzDAUnmarshalJSON(f *testing.F) {
+	addFuzzSeeds(f, "",
+		`{"gender":"M","date_of_birth":"1979-01-31","place_of_birth":"London"}`,
+		`{"country_of_citizenship":["GB"],"extra_attributes":[{"type":"1.2.3.4","value":"x"}]}`,
+		`{"extra_attributes":[null]}`,
+	)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzRoundTrip(t, data, func() interface{} { return &hvclient.DA{} })
+	})
+}
+
+func FuzzQualifiedStatementsUnmarshalJSON(f *testing.F) {
+	addFuzzSeeds(f, "",
+		`{"semantics":{"identifier":"0.4.0.194121.1.1","name_authorities":["authority"]},"etsi_qc_compliance":true}`,
+		`{"etsi_qc_type":"0.4.0.1862.1.6.1","etsi_qc_retention_period":5,"etsi_qc_pds":{"EN":"https://example.com/"}}`,
+		`{"semantics":null}`,
+	)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzRoundTrip(t, data, func() interface{} { return &hvclient.QualifiedStatements{} })
+	})
+}
+
+func TestUnmarshalJSONNullElements(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		data  string
+		value interface{}
+	}{
+		{"CertInfo", `[null]`, &[]hvclient.CertInfo{}},
+		{"Config", `[null]`, &[]hvclient.Config{}},
+		{"MSExtension", `[null]`, &[]hvclient.MSExtension{}},
+		{"OIDAndString", `[null]`, &[]hvclient.OIDAndString{}},
+		{"Request", `[null]`, &[]hvclient.Request{}},
+		{"ExtraAttributes", `{"subject_dn":{"extra_attributes":[null]}}`, &hvclient.Request{}},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Only the absence of a panic matters, since some types
+			// legitimately reject an empty value.
+			_ = json.Unmarshal([]byte(tc.data), tc.value)
+		})
+	}
+}
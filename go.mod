@@ -6,9 +6,11 @@ require (
 	github.com/go-chi/chi v4.1.2+incompatible
 	github.com/google/go-cmp v0.5.8
 	golang.org/x/crypto v0.0.0-20220622213112-05595931fe9d
+	golang.org/x/net v0.1.0
+	golang.org/x/sys v0.1.0
 )
 
 require (
-	golang.org/x/sys v0.0.0-20220627191245-f75cf1eec38b // indirect
-	golang.org/x/term v0.0.0-20220526004731-065cf7ba2467 // indirect
+	golang.org/x/term v0.1.0 // indirect
+	golang.org/x/text v0.4.0 // indirect
 )
@@ -0,0 +1,131 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/httputils"
+)
+
+// HealthStatus describes the availability of HVCA as seen by a client.
+type HealthStatus struct {
+	// Available is true if HVCA accepted the client's credentials and
+	// responded successfully.
+	Available bool
+
+	// CheckedAt is the time at which the check was started.
+	CheckedAt time.Time
+
+	// Latency is the time taken by the check, including any login made on
+	// its behalf.
+	Latency time.Duration
+
+	// StatusCode is the HTTP status code of the unsuccessful HVCA response
+	// which made HVCA unavailable, or zero if there was no such response.
+	StatusCode int
+
+	// Err is the error which made HVCA unavailable, or nil if it is
+	// available.
+	Err error
+}
+
+// jsonHealthStatus is used internally for JSON marshalling.
+type jsonHealthStatus struct {
+	Available  bool      `json:"available"`
+	CheckedAt  time.Time `json:"checked_at"`
+	Latency    string    `json:"latency"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// noRetriesKey is the context key which disables retries of HVCA API calls.
+type noRetriesKey struct{}
+
+// CheckHealth checks whether HVCA is available by retrieving the count of
+// certificates issued, which is the cheapest call made by this package, as
+// HVCA has no dedicated health endpoint. Unlike other API calls, a service
+// unavailable response is not retried, so that the result reflects the
+// current state of HVCA and is returned promptly, but the client logs in
+// again if its authentication token has expired.
+func (c *Client) CheckHealth(ctx context.Context) *HealthStatus {
+	var status = HealthStatus{CheckedAt: c.now()}
+
+	var _, err = c.CounterCertsIssued(context.WithValue(ctx, noRetriesKey{}, true))
+
+	status.Latency = c.now().Sub(status.CheckedAt)
+	status.Available = err == nil
+	status.Err = err
+
+	var apiErr APIError
+	if errors.As(err, &apiErr) {
+		status.StatusCode = apiErr.StatusCode
+	}
+
+	return &status
+}
+
+// Health returns nil if HVCA is available, as described for CheckHealth, or
+// the error which made it unavailable.
+func (c *Client) Health(ctx context.Context) error {
+	return c.CheckHealth(ctx).Err
+}
+
+// HealthHandler returns an HTTP handler suitable for use as a readiness
+// probe, which checks the health of HVCA as described for CheckHealth and
+// responds with the JSON-encoded health status, with a 200 OK status code
+// if HVCA is available and a 503 Service Unavailable status code if it is
+// not. The check is made with the context of each incoming request.
+func (c *Client) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var status = c.CheckHealth(r.Context())
+
+		var code = http.StatusOK
+		if !status.Available {
+			code = http.StatusServiceUnavailable
+		}
+
+		var data, err = json.Marshal(status)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set(httputils.ContentTypeHeader, httputils.ContentTypeJSON)
+		w.WriteHeader(code)
+		w.Write(data)
+	})
+}
+
+// MarshalJSON returns the JSON encoding of a health status.
+func (s HealthStatus) MarshalJSON() ([]byte, error) {
+	var obj = jsonHealthStatus{
+		Available:  s.Available,
+		CheckedAt:  s.CheckedAt,
+		Latency:    s.Latency.String(),
+		StatusCode: s.StatusCode,
+	}
+
+	if s.Err != nil {
+		obj.Error = s.Err.Error()
+	}
+
+	return json.Marshal(obj)
+}
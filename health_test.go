@@ -0,0 +1,161 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+)
+
+func TestCheckHealth(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name      string
+		faults    []hvcatest.Fault
+		available bool
+		status    int
+		injected  int
+	}{
+		{
+			name:      "Available",
+			available: true,
+		},
+		{
+			name:     "Unavailable",
+			faults:   []hvcatest.Fault{{Path: "/counters", Status: http.StatusServiceUnavailable}},
+			status:   http.StatusServiceUnavailable,
+			injected: 1,
+		},
+		{
+			name:     "Unauthorized",
+			faults:   []hvcatest.Fault{{Path: "/counters", Status: http.StatusForbidden}},
+			status:   http.StatusForbidden,
+			injected: 1,
+		},
+		{
+			name:      "ExpiredToken",
+			faults:    []hvcatest.Fault{{ExpireToken: true, Count: 1}},
+			available: true,
+			injected:  1,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var clnt, injector = newFaultClient(t, tc.faults...)
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+			defer cancel()
+
+			var got = clnt.CheckHealth(ctx)
+
+			if got.Available != tc.available || (got.Err == nil) != tc.available {
+				t.Errorf("got available %t with error %v, want %t", got.Available, got.Err, tc.available)
+			}
+
+			if got.StatusCode != tc.status {
+				t.Errorf("got status code %d, want %d", got.StatusCode, tc.status)
+			}
+
+			if got.CheckedAt.IsZero() || got.Latency < 0 {
+				t.Errorf("got checked at %v with latency %v", got.CheckedAt, got.Latency)
+			}
+
+			// A service unavailable response must not be retried.
+			if n := injector.Injected(); n != tc.injected {
+				t.Errorf("got %d faults injected, want %d", n, tc.injected)
+			}
+
+			if err := clnt.Health(ctx); (err == nil) != tc.available {
+				t.Errorf("got error %v", err)
+			}
+		})
+	}
+}
+
+func TestHealthClosed(t *testing.T) {
+	t.Parallel()
+
+	var clnt, _ = newFaultClient(t)
+	clnt.Close()
+
+	if err := clnt.Health(context.Background()); !errors.Is(err, hvclient.ErrClientClosed) {
+		t.Errorf("got error %v, want %v", err, hvclient.ErrClientClosed)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name   string
+		faults []hvcatest.Fault
+		want   int
+	}{
+		{
+			name: "Available",
+			want: http.StatusOK,
+		},
+		{
+			name:   "Unavailable",
+			faults: []hvcatest.Fault{{Path: "/counters", Status: http.StatusServiceUnavailable}},
+			want:   http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var clnt, _ = newFaultClient(t, tc.faults...)
+
+			var rec = httptest.NewRecorder()
+			clnt.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tc.want {
+				t.Errorf("got status code %d, want %d", rec.Code, tc.want)
+			}
+
+			var got struct {
+				Available bool   `json:"available"`
+				Latency   string `json:"latency"`
+				Error     string `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("couldn't unmarshal JSON: %v", err)
+			}
+
+			if got.Available != (tc.want == http.StatusOK) || got.Latency == "" || (got.Error == "") != got.Available {
+				t.Errorf("got unexpected health status %s", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CertEventType is the type of an event in the lifecycle of a certificate.
+type CertEventType int
+
+// CertEvent is an event in the lifecycle of a certificate.
+type CertEvent struct {
+	Type CertEventType
+	Time time.Time
+}
+
+// Certificate event type values.
+const (
+	// EventIssued is the issuance of the certificate, dated by its
+	// not-before time.
+	EventIssued CertEventType = iota + 1
+
+	// EventUpdated is the most recent update to an issued certificate
+	// recorded by HVCA, where this differs from its issuance.
+	EventUpdated
+
+	// EventRevoked is the revocation of the certificate, dated by the most
+	// recent update recorded by HVCA.
+	EventRevoked
+
+	// EventExpiry is the expiry of the certificate, dated by its not-after
+	// time, which may be in the future.
+	EventExpiry
+)
+
+// certEventTypeNames maps certificate event type values to their string
+// descriptions.
+var certEventTypeNames = [...]string{
+	EventIssued:  "ISSUED",
+	EventUpdated: "UPDATED",
+	EventRevoked: "REVOKED",
+	EventExpiry:  "EXPIRY",
+}
+
+// certEventTypeCodes maps certificate event type string descriptions to
+// their values.
+var certEventTypeCodes = map[string]CertEventType{
+	"ISSUED":  EventIssued,
+	"UPDATED": EventUpdated,
+	"REVOKED": EventRevoked,
+	"EXPIRY":  EventExpiry,
+}
+
+// isValid checks if a certificate event type value is within a valid range.
+func (t CertEventType) isValid() bool {
+	return t >= EventIssued && t <= EventExpiry
+}
+
+// String returns a description of the certificate event type.
+func (t CertEventType) String() string {
+	if !t.isValid() {
+		return "UNKNOWN EVENT TYPE"
+	}
+
+	return certEventTypeNames[t]
+}
+
+// MarshalJSON returns the JSON encoding of a certificate event type.
+func (t CertEventType) MarshalJSON() ([]byte, error) {
+	if !t.isValid() {
+		return nil, fmt.Errorf("invalid certificate event type value: %d", t)
+	}
+
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON parses a JSON-encoded certificate event type and stores the
+// result in the object.
+func (t *CertEventType) UnmarshalJSON(b []byte) error {
+	var data string
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	var result, ok = certEventTypeCodes[strings.ToUpper(data)]
+	if !ok {
+		return fmt.Errorf("invalid certificate event type value: %s", data)
+	}
+
+	*t = result
+
+	return nil
+}
+
+// CertificateHistory returns the known events in the lifecycle of a
+// certificate in chronological order. HVCA does not currently expose a
+// history of state transitions, so the events are derived from the
+// certificate itself and from its current status and last-updated time.
+func (c *Client) CertificateHistory(ctx context.Context, serial *big.Int) ([]CertEvent, error) {
+	var info, err = c.CertificateRetrieve(ctx, serial)
+	if err != nil {
+		return nil, err
+	}
+
+	return certificateHistory(info), nil
+}
+
+// certificateHistory derives the lifecycle events of a certificate from its
+// retrieved information.
+func certificateHistory(info *CertInfo) []CertEvent {
+	var events []CertEvent
+
+	if info.X509 != nil {
+		events = append(events,
+			CertEvent{Type: EventIssued, Time: info.X509.NotBefore},
+			CertEvent{Type: EventExpiry, Time: info.X509.NotAfter},
+		)
+	}
+
+	switch {
+	case info.UpdatedAt.IsZero():
+
+	case info.Status == StatusRevoked:
+		events = append(events, CertEvent{Type: EventRevoked, Time: info.UpdatedAt})
+
+	case info.X509 == nil || !info.UpdatedAt.Equal(info.X509.NotBefore):
+		events = append(events, CertEvent{Type: EventUpdated, Time: info.UpdatedAt})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+
+	return events
+}
@@ -0,0 +1,119 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCertificateHistory(t *testing.T) {
+	t.Parallel()
+
+	var (
+		notBefore = time.Date(2021, 6, 18, 16, 29, 51, 0, time.UTC)
+		notAfter  = notBefore.Add(time.Hour * 24 * 90)
+		updated   = notBefore.Add(time.Hour * 24 * 7)
+		cert      = &x509.Certificate{NotBefore: notBefore, NotAfter: notAfter}
+	)
+
+	var testcases = []struct {
+		name string
+		info CertInfo
+		want []CertEvent
+	}{
+		{
+			name: "Issued",
+			info: CertInfo{X509: cert, Status: StatusIssued, UpdatedAt: notBefore},
+			want: []CertEvent{
+				{Type: EventIssued, Time: notBefore},
+				{Type: EventExpiry, Time: notAfter},
+			},
+		},
+		{
+			name: "IssuedUpdated",
+			info: CertInfo{X509: cert, Status: StatusIssued, UpdatedAt: updated},
+			want: []CertEvent{
+				{Type: EventIssued, Time: notBefore},
+				{Type: EventUpdated, Time: updated},
+				{Type: EventExpiry, Time: notAfter},
+			},
+		},
+		{
+			name: "Revoked",
+			info: CertInfo{X509: cert, Status: StatusRevoked, UpdatedAt: updated},
+			want: []CertEvent{
+				{Type: EventIssued, Time: notBefore},
+				{Type: EventRevoked, Time: updated},
+				{Type: EventExpiry, Time: notAfter},
+			},
+		},
+		{
+			name: "NoUpdatedAt",
+			info: CertInfo{X509: cert, Status: StatusRevoked},
+			want: []CertEvent{
+				{Type: EventIssued, Time: notBefore},
+				{Type: EventExpiry, Time: notAfter},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got = certificateHistory(&tc.info)
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCertEventTypeJSON(t *testing.T) {
+	t.Parallel()
+
+	for _, want := range []CertEventType{EventIssued, EventUpdated, EventRevoked, EventExpiry} {
+		var data, err = json.Marshal(want)
+		if err != nil {
+			t.Fatalf("couldn't marshal %v: %v", want, err)
+		}
+
+		var got CertEventType
+		if err = json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("couldn't unmarshal %s: %v", data, err)
+		}
+
+		if got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	if _, err := json.Marshal(CertEventType(0)); err == nil {
+		t.Errorf("unexpectedly marshalled invalid event type")
+	}
+
+	var got CertEventType
+	if err := json.Unmarshal([]byte(`"NOT AN EVENT"`), &got); err == nil {
+		t.Errorf("unexpectedly unmarshalled invalid event type")
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalidDNSName is wrapped by errors returned by CheckDNSName when a DNS
+// name would be rejected by HVCA.
+var ErrInvalidDNSName = errors.New("invalid DNS name")
+
+// dnsNameProfile converts DNS names to A-labels using the IDNA lookup rules,
+// rejecting names with invalid characters or label lengths.
+var dnsNameProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.VerifyDNSLength(true),
+	idna.Transitional(false),
+)
+
+// CheckDNSName checks that a DNS name is suitable for inclusion in a
+// certificate request, and returns it in the form HVCA expects. Labels
+// containing Unicode characters are converted to A-labels (punycode), and
+// upper case ASCII characters are converted to lower case. An error
+// wrapping ErrInvalidDNSName is returned if the name has a trailing dot, is
+// an IP address, has a wildcard other than as the entire leftmost label of
+// a name with at least two other labels, or is otherwise not a valid DNS
+// name.
+func CheckDNSName(name string) (string, error) {
+	var invalid = func(reason string) error {
+		return fmt.Errorf("%w %q: %s", ErrInvalidDNSName, name, reason)
+	}
+
+	switch {
+	case name == "":
+		return "", invalid("empty name")
+
+	case strings.HasSuffix(name, "."):
+		return "", invalid("trailing dot")
+
+	case net.ParseIP(name) != nil:
+		return "", invalid("IP address, which must be specified as a SAN IP address")
+	}
+
+	var wildcard bool
+	var rest = name
+
+	if strings.HasPrefix(name, "*.") {
+		wildcard = true
+		rest = strings.TrimPrefix(name, "*.")
+
+		if strings.Count(rest, ".") < 1 {
+			return "", invalid("wildcard must be followed by at least two labels")
+		}
+	}
+
+	if strings.Contains(rest, "*") {
+		return "", invalid("wildcard must be the entire leftmost label")
+	}
+
+	var ascii, err = dnsNameProfile.ToASCII(rest)
+	if err != nil {
+		return "", invalid(err.Error())
+	}
+
+	if wildcard {
+		ascii = "*." + ascii
+	}
+
+	return ascii, nil
+}
+
+// checkDNSNames applies CheckDNSName to each of a list of DNS names, and
+// returns a new list of the converted names.
+func checkDNSNames(names []string) ([]string, error) {
+	if names == nil {
+		return nil, nil
+	}
+
+	var checked = make([]string, 0, len(names))
+	for _, name := range names {
+		var ascii, err = CheckDNSName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		checked = append(checked, ascii)
+	}
+
+	return checked, nil
+}
+
+// CheckDNSNames applies CheckDNSName to each SAN DNS name, replacing them
+// with their converted forms. The DNS names are left unchanged if any of
+// them is invalid.
+func (s *SAN) CheckDNSNames() error {
+	var checked, err = checkDNSNames(s.DNSNames)
+	if err != nil {
+		return err
+	}
+
+	s.DNSNames = checked
+
+	return nil
+}
+
+// DomainToASCII returns a domain name with any labels containing Unicode
+// characters (U-labels) converted to A-labels (punycode), as HVCA requires.
+// A leading wildcard label and a trailing dot are preserved. Domain names
+// which are already ASCII, or which cannot be converted, are returned
+// unchanged, leaving HVCA to report any problem.
+func DomainToASCII(name string) string {
+	if isASCII(name) {
+		return name
+	}
+
+	return convertDomain(name, dnsNameProfile.ToASCII)
+}
+
+// DomainToUnicode returns a domain name with any A-labels converted to
+// U-labels, for display. A leading wildcard label and a trailing dot are
+// preserved. Domain names without A-labels, or which cannot be converted,
+// are returned unchanged.
+func DomainToUnicode(name string) string {
+	if !strings.Contains(strings.ToLower(name), "xn--") {
+		return name
+	}
+
+	// Only accept a conversion which converts back to the same A-labels, to
+	// avoid displaying a name other than the one HVCA will use.
+	return convertDomain(name, func(s string) (string, error) {
+		var unicode, err = idna.Display.ToUnicode(s)
+		if err != nil {
+			return "", err
+		}
+
+		if ascii, err := dnsNameProfile.ToASCII(unicode); err != nil || ascii != strings.ToLower(s) {
+			return "", errors.New("A-labels do not round trip")
+		}
+
+		return unicode, nil
+	})
+}
+
+// convertDomain applies an IDNA conversion function to a domain name,
+// excluding any leading wildcard label or trailing dot, and returns the
+// domain name unchanged if the conversion fails.
+func convertDomain(name string, convert func(string) (string, error)) string {
+	var prefix, rest, suffix string
+
+	rest = name
+	if strings.HasPrefix(rest, "*.") {
+		prefix, rest = "*.", strings.TrimPrefix(rest, "*.")
+	}
+
+	if strings.HasSuffix(rest, ".") {
+		rest, suffix = strings.TrimSuffix(rest, "."), "."
+	}
+
+	var converted, err = convert(rest)
+	if err != nil {
+		return name
+	}
+
+	return prefix + converted + suffix
+}
+
+// domainsToASCII applies DomainToASCII to each of a list of domain names.
+func domainsToASCII(names []string) []string {
+	if names == nil {
+		return nil
+	}
+
+	var converted = make([]string, 0, len(names))
+	for _, name := range names {
+		converted = append(converted, DomainToASCII(name))
+	}
+
+	return converted
+}
+
+// isASCII checks if a string contains only ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+
+	return true
+}
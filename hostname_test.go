@@ -0,0 +1,245 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckDNSName(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "ASCII",
+			value: "www.example.com",
+			want:  "www.example.com",
+		},
+		{
+			name:  "UpperCase",
+			value: "WWW.Example.COM",
+			want:  "www.example.com",
+		},
+		{
+			name:  "Unicode",
+			value: "bücher.example",
+			want:  "xn--bcher-kva.example",
+		},
+		{
+			name:  "ALabel",
+			value: "xn--bcher-kva.example",
+			want:  "xn--bcher-kva.example",
+		},
+		{
+			name:  "Wildcard",
+			value: "*.example.com",
+			want:  "*.example.com",
+		},
+		{
+			name:  "WildcardUnicode",
+			value: "*.bücher.example",
+			want:  "*.xn--bcher-kva.example",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = hvclient.CheckDNSName(tc.value)
+			if err != nil {
+				t.Fatalf("couldn't check DNS name: %v", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckDNSNameFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		value string
+	}{
+		{"Empty", ""},
+		{"TrailingDot", "www.example.com."},
+		{"IPv4", "192.0.2.1"},
+		{"IPv6", "2001:db8::1"},
+		{"WildcardNotLeftmost", "www.*.example.com"},
+		{"WildcardPartialLabel", "w*.example.com"},
+		{"WildcardTooFewLabels", "*.com"},
+		{"WildcardOnly", "*"},
+		{"EmptyLabel", "www..example.com"},
+		{"Underscore", "_acme.example.com"},
+		{"Space", "www example.com"},
+		{"LongLabel", "a123456789012345678901234567890123456789012345678901234567890123.example.com"},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var _, err = hvclient.CheckDNSName(tc.value)
+			if !errors.Is(err, hvclient.ErrInvalidDNSName) {
+				t.Fatalf("got error %v, want %v", err, hvclient.ErrInvalidDNSName)
+			}
+		})
+	}
+}
+
+func TestSANCheckDNSNames(t *testing.T) {
+	t.Parallel()
+
+	var san = hvclient.SAN{DNSNames: []string{"bücher.example", "WWW.example.com"}}
+	if err := san.CheckDNSNames(); err != nil {
+		t.Fatalf("couldn't check DNS names: %v", err)
+	}
+
+	var want = []string{"xn--bcher-kva.example", "www.example.com"}
+	if !cmp.Equal(san.DNSNames, want) {
+		t.Errorf("got %q, want %q", san.DNSNames, want)
+	}
+
+	san = hvclient.SAN{DNSNames: []string{"WWW.example.com", "192.0.2.1"}}
+	if err := san.CheckDNSNames(); !errors.Is(err, hvclient.ErrInvalidDNSName) {
+		t.Fatalf("got error %v, want %v", err, hvclient.ErrInvalidDNSName)
+	}
+
+	if want = []string{"WWW.example.com", "192.0.2.1"}; !cmp.Equal(san.DNSNames, want) {
+		t.Errorf("got %q, want unchanged %q", san.DNSNames, want)
+	}
+}
+
+func TestClientStrictDNSNames(t *testing.T) {
+	t.Parallel()
+
+	var clnt, closefunc = newMockClientWithConfig(t, func(conf *hvclient.Config) {
+		conf.StrictDNSNames = true
+	})
+	defer closefunc()
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var req = exampleRequest()
+	req.SAN = &hvclient.SAN{DNSNames: []string{"bücher.example"}}
+
+	if _, err := clnt.CertificateRequest(ctx, req); err != nil {
+		t.Fatalf("couldn't request certificate: %v", err)
+	}
+
+	if want := []string{"bücher.example"}; !cmp.Equal(req.SAN.DNSNames, want) {
+		t.Errorf("request was modified: got %q, want %q", req.SAN.DNSNames, want)
+	}
+
+	req.SAN.DNSNames = []string{"192.0.2.1"}
+
+	if _, err := clnt.CertificateRequest(ctx, req); !errors.Is(err, hvclient.ErrInvalidDNSName) {
+		t.Errorf("got error %v, want %v", err, hvclient.ErrInvalidDNSName)
+	}
+}
+
+func TestDomainToASCIIAndUnicode(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name    string
+		unicode string
+		ascii   string
+	}{
+		{"ASCII", "www.example.com", "www.example.com"},
+		{"Unicode", "bücher.example", "xn--bcher-kva.example"},
+		{"TrailingDot", "bücher.example.", "xn--bcher-kva.example."},
+		{"Wildcard", "*.bücher.example", "*.xn--bcher-kva.example"},
+		{"Mixed", "www.bücher.example", "www.xn--bcher-kva.example"},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := hvclient.DomainToASCII(tc.unicode); got != tc.ascii {
+				t.Errorf("got A-label form %q, want %q", got, tc.ascii)
+			}
+
+			if got := hvclient.DomainToUnicode(tc.ascii); got != tc.unicode {
+				t.Errorf("got U-label form %q, want %q", got, tc.unicode)
+			}
+		})
+	}
+
+	// Names which can't be converted are returned unchanged.
+	for _, name := range []string{"xn--invalid-.example", "bücher..example"} {
+		if got := hvclient.DomainToASCII(hvclient.DomainToUnicode(name)); got != name {
+			t.Errorf("got %q, want unchanged %q", got, name)
+		}
+	}
+}
+
+func TestSANUnicodeDNSNames(t *testing.T) {
+	t.Parallel()
+
+	var req = exampleRequest()
+	req.SAN = &hvclient.SAN{DNSNames: []string{"bücher.example"}}
+
+	var data, err = json.Marshal(req.SAN)
+	if err != nil {
+		t.Fatalf("couldn't marshal SAN: %v", err)
+	}
+
+	if want := `{"dns_names":["xn--bcher-kva.example"]}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	var csr *x509.CertificateRequest
+	if csr, err = req.PKCS10(); err != nil {
+		t.Fatalf("couldn't create PKCS#10 request: %v", err)
+	}
+
+	if want := []string{"xn--bcher-kva.example"}; !cmp.Equal(csr.DNSNames, want) {
+		t.Errorf("got CSR DNS names %q, want %q", csr.DNSNames, want)
+	}
+
+	if want := []string{"bücher.example"}; !cmp.Equal(req.SAN.DNSNames, want) {
+		t.Errorf("SAN was modified: got %q, want %q", req.SAN.DNSNames, want)
+	}
+
+	var name, _, _ = hvclient.ClaimAssertionInfo{}.DNSRecord("*.bücher.example")
+	if want := "xn--bcher-kva.example."; name != want {
+		t.Errorf("got DNS record name %q, want %q", name, want)
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package hvcatest provides a mock HVCA server for testing code which uses
+hvclient, without the need for an HVCA account.
+
+The mock server implements the HVCA API endpoints with fixed responses. It
+accepts the credentials in APIKey, APISecret and SSLClientSerial, which
+Config populates in a client configuration object. Every certificate
+request is answered with the certificate in Cert, and the TriggerError and
+SerialNotFound values may be used to induce error responses.
+
+Failures such as latency, intermittent 5xx responses, malformed response
+bodies and expired authentication tokens may be injected into the responses
+of the mock server, per endpoint, by wrapping its handler with a
+FaultInjector:
+
+	var faults = hvcatest.NewFaultInjector(hvcatest.NewHandler())
+	faults.Add(hvcatest.Fault{Path: "/certificates", Status: http.StatusServiceUnavailable, Every: 2})
+
+	var server = httptest.NewServer(faults)
+	defer server.Close()
+*/
+package hvcatest
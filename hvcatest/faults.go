@@ -0,0 +1,194 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvcatest
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/httputils"
+)
+
+// Fault is a failure injected by a FaultInjector into the responses to
+// matching requests.
+type Fault struct {
+	// Method is the HTTP method of the requests affected. If this is
+	// omitted, requests with any method are affected.
+	Method string
+
+	// Path is a prefix of the paths of the requests affected, such as
+	// "/certificates". If this is omitted, requests to any endpoint are
+	// affected.
+	Path string
+
+	// Latency is the time for which the response to an affected request is
+	// delayed, or until the request's context is cancelled.
+	Latency time.Duration
+
+	// Status, if non-zero, is the HTTP status code of an error response
+	// written to an affected request in place of the normal response, such
+	// as 503 Service Unavailable.
+	Status int
+
+	// MalformedBody causes affected requests to be answered with a 200 OK
+	// status and a JSON content type, but a body which is not valid JSON.
+	MalformedBody bool
+
+	// ExpireToken causes affected requests, other than login requests, to
+	// be answered with a 401 Unauthorized status, as if the authentication
+	// token had expired.
+	ExpireToken bool
+
+	// Every, if greater than one, causes only every Nth matching request to
+	// be affected, to simulate intermittent failures.
+	Every int
+
+	// Count, if positive, is the number of requests affected, after which
+	// the fault is no longer injected.
+	Count int
+}
+
+// FaultInjector is an HTTP handler which injects faults into the responses
+// of another handler, usually the one returned by NewHandler, so that retry,
+// backoff and token refresh logic can be tested against realistic failure
+// modes. Faults are checked in the order in which they were added, and only
+// the first matching fault is injected into each response. A fault injector
+// is safe for concurrent use, so faults may be added while a server is
+// running.
+type FaultInjector struct {
+	handler  http.Handler
+	mtx      sync.Mutex
+	faults   []*faultState
+	injected int
+}
+
+// faultState is a fault and the number of requests it has matched and
+// affected.
+type faultState struct {
+	Fault
+	matched  int
+	affected int
+}
+
+// NewFaultInjector returns a fault injector, with no faults, which passes
+// requests to the specified handler.
+func NewFaultInjector(handler http.Handler) *FaultInjector {
+	return &FaultInjector{handler: handler}
+}
+
+// Add adds a fault to be injected.
+func (f *FaultInjector) Add(fault Fault) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.faults = append(f.faults, &faultState{Fault: fault})
+}
+
+// Reset removes all faults and resets the count of injected faults.
+func (f *FaultInjector) Reset() {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.faults = nil
+	f.injected = 0
+}
+
+// Injected returns the number of responses into which a fault has been
+// injected.
+func (f *FaultInjector) Injected() int {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	return f.injected
+}
+
+// ServeHTTP injects the first matching fault, if any, into the response to
+// a request, and otherwise passes the request to the wrapped handler.
+func (f *FaultInjector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var fault, ok = f.match(r)
+	if !ok {
+		f.handler.ServeHTTP(w, r)
+		return
+	}
+
+	if fault.Latency > 0 {
+		var timer = time.NewTimer(fault.Latency)
+		defer timer.Stop()
+
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-timer.C:
+		}
+	}
+
+	switch {
+	case fault.ExpireToken:
+		writeError(w, http.StatusUnauthorized)
+
+	case fault.Status != 0:
+		writeError(w, fault.Status)
+
+	case fault.MalformedBody:
+		w.Header().Set(httputils.ContentTypeHeader, httputils.ContentTypeJSON)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"malformed":`))
+
+	default:
+		f.handler.ServeHTTP(w, r)
+	}
+}
+
+// match returns the first fault to be injected into the response to a
+// request, and records that it has been injected.
+func (f *FaultInjector) match(r *http.Request) (Fault, bool) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	for _, state := range f.faults {
+		if state.Method != "" && !strings.EqualFold(state.Method, r.Method) {
+			continue
+		}
+
+		if !strings.HasPrefix(r.URL.Path, state.Path) {
+			continue
+		}
+
+		if state.ExpireToken && strings.HasPrefix(r.URL.Path, "/login") {
+			continue
+		}
+
+		if state.Count > 0 && state.affected >= state.Count {
+			continue
+		}
+
+		state.matched++
+
+		if state.Every > 1 && state.matched%state.Every != 0 {
+			continue
+		}
+
+		state.affected++
+		f.injected++
+
+		return state.Fault, true
+	}
+
+	return Fault{}, false
+}
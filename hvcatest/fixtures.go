@@ -0,0 +1,309 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvcatest
+
+import (
+	"crypto/x509"
+	"embed"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/globalsign/hvclient"
+)
+
+type certInfo struct {
+	PEM       string `json:"certificate"`
+	Status    string `json:"status"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+type certMeta struct {
+	SerialNumber string `json:"serial_number"`
+	NotBefore    int64  `json:"not_before"`
+	NotAfter     int64  `json:"not_after"`
+}
+
+type claim struct {
+	ID        string          `json:"id"`
+	Status    string          `json:"status"`
+	Domain    string          `json:"domain"`
+	CreatedAt int64           `json:"created_at"`
+	ExpiresAt int64           `json:"expires_at"`
+	AssertBy  int64           `json:"assert_by"`
+	Log       []claimLogEntry `json:"log"`
+}
+
+type claimAssertionInfo struct {
+	Token    string `json:"token"`
+	AssertBy int64  `json:"assert_by"`
+	ID       string `json:"id"`
+}
+
+type claimLogEntry struct {
+	Status      string `json:"status"`
+	Description string `json:"description"`
+	TimeStamp   int64  `json:"timestamp"`
+}
+
+type counter struct {
+	Value int `json:"value"`
+}
+
+type dnsRequest struct {
+	AuthorizationDomain string `json:"authorization_domain"`
+}
+
+type httpRequest struct {
+	AuthorizationDomain string `json:"authorization_domain,omitempty"`
+	Scheme              string `json:"scheme"`
+}
+
+type emailRequest struct {
+	Email string `json:"email_address"`
+}
+
+type errorResponse struct {
+	Description string `json:"description"`
+}
+
+type handleLoginRequest struct {
+	APIKey    string `json:"api_key"`
+	APISecret string `json:"api_secret"`
+}
+
+type handleLoginResponse struct {
+	Token string `json:"access_token"`
+}
+
+type authorisedEmails struct {
+	Constructed []string   `json:"constructed"`
+	DNS         dnsResults `json:"DNS"`
+}
+
+type dnsResults struct {
+	SOA soaResults `json:"SOA"`
+}
+
+type soaResults struct {
+	Emails []string `json:"emails,omitempty"`
+}
+
+type revocationBody struct {
+	RevocationReason string `json:"revocation_reason"`
+	RevocationTime   int64  `json:"revocation_time"`
+}
+
+// Credentials accepted by the mock HVCA server. Login requests must carry
+// the SSLClientSerial value in the SSLClientSerialHeader header, standing in
+// for the mutual TLS authentication performed by HVCA.
+const (
+	APIKey                = "mock_api_key"
+	APISecret             = "mock_api_secret"
+	SSLClientSerialHeader = "X-SSL-Client-Serial"
+	SSLClientSerial       = "0123456789"
+	Token                 = "mock_token"
+)
+
+// Values returned by the mock HVCA server.
+const (
+	CertSerial          = "741DAF9EC2D5F7DC"
+	CounterIssued       = 72
+	CounterRevoked      = 14
+	ClaimDomainVerified = "verified.com."
+	ClaimEmail          = "spock@enterprise.org"
+	ClaimID             = "113FED08"
+	ClaimToken          = "mock_claim_token"
+	QuotaIssuance       = 42
+)
+
+// TriggerError causes the mock HVCA server to return an error status when
+// used as a subject common name in a certificate request, as a domain in a
+// claim submission, or as a claim ID.
+const TriggerError = "triggererror"
+
+// SerialNotFound causes the mock HVCA server to return a 404 Not Found
+// status when used as the serial number of a certificate to retrieve or
+// revoke.
+var SerialNotFound = big.NewInt(999999)
+
+// Times used in the domain claims returned by the mock HVCA server.
+var (
+	DateCreated   = time.Date(2021, 6, 16, 4, 19, 25, 0, time.UTC)
+	DateExpiresAt = time.Date(2021, 6, 17, 22, 7, 4, 0, time.UTC)
+	DateUpdated   = time.Date(2021, 6, 18, 16, 29, 51, 0, time.UTC)
+	DateAssertBy  = time.Date(2021, 6, 19, 13, 5, 31, 0, time.UTC)
+)
+
+// Cert is the certificate returned by the mock HVCA server for every
+// certificate request and retrieval. TrustChain is the chain of trust it
+// returns. Neither should be modified.
+var (
+	Cert       = mustParseCert("certs/cert.pem")
+	TrustChain = []*x509.Certificate{
+		mustParseCert("certs/ica_cert.pem"),
+		mustParseCert("certs/root_cert.pem"),
+	}
+)
+
+// Policy is the validation policy returned by the mock HVCA server. It
+// should not be modified.
+var (
+	Policy = hvclient.Policy{
+		Validity: &hvclient.ValidityPolicy{
+			SecondsMin:            3600,
+			SecondsMax:            7776000,
+			NotBeforeNegativeSkew: 120,
+			NotBeforePositiveSkew: 3600,
+		},
+		SubjectDN: &hvclient.SubjectDNPolicy{
+			CommonName: &hvclient.StringPolicy{
+				Presence: hvclient.Required,
+				Format:   `^[a-zA-Z]*$`,
+			},
+		},
+		PublicKey: &hvclient.PublicKeyPolicy{
+			KeyType:        hvclient.ECDSA,
+			AllowedLengths: []int{256, 384, 521},
+			KeyFormat:      hvclient.PKCS10,
+		},
+		PublicKeySignature: hvclient.Required,
+	}
+)
+
+var (
+	claimAssert = claimAssertionInfo{
+		Token:    ClaimToken,
+		AssertBy: DateAssertBy.Unix(),
+		ID:       ClaimID,
+	}
+	claimsEntries = []claim{
+		{
+			ID:        ClaimID,
+			Status:    "VERIFIED",
+			Domain:    "fake.com.",
+			CreatedAt: DateCreated.Unix(),
+			ExpiresAt: DateExpiresAt.Unix(),
+			AssertBy:  DateAssertBy.Unix(),
+			Log: []claimLogEntry{
+				{
+					Status:      "SUCCESS",
+					Description: "domain claim verified",
+					TimeStamp:   DateUpdated.Unix(),
+				},
+			},
+		},
+		{
+			ID:        "pending1",
+			Status:    "PENDING",
+			Domain:    "pending1.com.",
+			CreatedAt: DateCreated.Unix(),
+			ExpiresAt: DateExpiresAt.Unix(),
+			AssertBy:  DateAssertBy.Unix(),
+			Log: []claimLogEntry{
+				{
+					Status:      "ERROR",
+					Description: "error verifying domain claim",
+					TimeStamp:   DateUpdated.Unix(),
+				},
+				{
+					Status:      "ERROR",
+					Description: "error verifying domain claim",
+					TimeStamp:   DateUpdated.Add(time.Hour).Unix(),
+				},
+			},
+		},
+		{
+			ID:        "pending2",
+			Status:    "PENDING",
+			Domain:    "pending2.com.",
+			CreatedAt: DateCreated.Unix(),
+			ExpiresAt: DateExpiresAt.Unix(),
+			AssertBy:  DateAssertBy.Unix(),
+			Log: []claimLogEntry{
+				{
+					Status:      "ERROR",
+					Description: "error verifying domain claim",
+					TimeStamp:   DateUpdated.Unix(),
+				},
+			},
+		},
+	}
+	statsExpiringData = []certMeta{
+		{
+			SerialNumber: "748BDAE7199CC246",
+			NotBefore:    time.Date(2021, 7, 12, 16, 29, 51, 0, time.UTC).Unix(),
+			NotAfter:     time.Date(2021, 10, 10, 16, 29, 51, 0, time.UTC).Unix(),
+		},
+		{
+			SerialNumber: "DEADBEEF44274823",
+			NotBefore:    time.Date(2021, 7, 14, 12, 5, 37, 0, time.UTC).Unix(),
+			NotAfter:     time.Date(2021, 10, 12, 12, 5, 37, 0, time.UTC).Unix(),
+		},
+		{
+			SerialNumber: "AA9915DC78BB21FF",
+			NotBefore:    time.Date(2021, 7, 14, 17, 59, 8, 0, time.UTC).Unix(),
+			NotAfter:     time.Date(2021, 10, 12, 17, 59, 8, 0, time.UTC).Unix(),
+		},
+		{
+			SerialNumber: "32897DA7B113DAB6",
+			NotBefore:    time.Date(2021, 7, 14, 21, 11, 43, 0, time.UTC).Unix(),
+			NotAfter:     time.Date(2021, 10, 12, 21, 11, 43, 0, time.UTC).Unix(),
+		},
+	}
+	statsIssuedData = []certMeta{
+		{
+			SerialNumber: "741DAF9EC2D5F7DC",
+			NotBefore:    time.Date(2021, 6, 18, 16, 29, 51, 0, time.UTC).Unix(),
+			NotAfter:     time.Date(2021, 9, 16, 16, 29, 51, 0, time.UTC).Unix(),
+		},
+		{
+			SerialNumber: "87BC1DC5524A2B18",
+			NotBefore:    time.Date(2021, 6, 19, 12, 5, 37, 0, time.UTC).Unix(),
+			NotAfter:     time.Date(2021, 9, 17, 12, 5, 37, 0, time.UTC).Unix(),
+		},
+		{
+			SerialNumber: "F488BCE14A56CD2A",
+			NotBefore:    time.Date(2021, 6, 19, 17, 59, 8, 0, time.UTC).Unix(),
+			NotAfter:     time.Date(2021, 9, 17, 17, 59, 8, 0, time.UTC).Unix(),
+		},
+	}
+)
+
+//go:embed certs/*.pem
+var certFiles embed.FS
+
+// mustParseCert parses an embedded PEM-encoded certificate, and panics on
+// failure.
+func mustParseCert(name string) *x509.Certificate {
+	var data, err = certFiles.ReadFile(name)
+	if err != nil {
+		panic(fmt.Sprintf("failed to read certificate %s: %v", name, err))
+	}
+
+	var block, _ = pem.Decode(data)
+	if block == nil {
+		panic(fmt.Sprintf("no PEM block found in certificate %s", name))
+	}
+
+	var cert *x509.Certificate
+	if cert, err = x509.ParseCertificate(block.Bytes); err != nil {
+		panic(fmt.Sprintf("failed to parse certificate %s: %v", name, err))
+	}
+
+	return cert
+}
@@ -0,0 +1,536 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvcatest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/httputils"
+	"github.com/globalsign/hvclient/internal/pki"
+	"github.com/go-chi/chi"
+)
+
+// NewServer starts and returns a new mock HVCA server. The caller should
+// call Close when finished, to shut it down.
+func NewServer() *httptest.Server {
+	return httptest.NewServer(NewHandler())
+}
+
+// NewHandler returns an HTTP handler which mocks the HVCA API, for use with
+// a server other than the one returned by NewServer.
+func NewHandler() http.Handler {
+	var r = chi.NewRouter()
+
+	r.Route("/certificates", func(r chi.Router) {
+		r.Post("/", handleCertificatesRequest)
+		r.Route("/{serial}", func(r chi.Router) {
+			r.Get("/", handleCertificatesRetrieve)
+			r.Patch("/", handleCertificatesRevoke)
+		})
+	})
+
+	r.Route("/claims", func(r chi.Router) {
+		r.Route("/domains", func(r chi.Router) {
+			r.Get("/", handleClaimsDomains)
+			r.Route("/{arg}", func(r chi.Router) {
+				r.Post("/", handleClaimsSubmit)
+				r.Get("/", handleClaimsRetrieve)
+				r.Delete("/", handleClaimsDelete)
+				r.Route("/dns", func(r chi.Router) {
+					r.Post("/", handleClaimsDNS)
+				})
+				r.Route("/http", func(r chi.Router) {
+					r.Post("/", handleClaimsHTTP)
+				})
+				r.Route("/email", func(r chi.Router) {
+					r.Get("/", handleClaimsEmailRetrieve)
+					r.Post("/", handleClaimsEmail)
+				})
+				r.Route("/reassert", func(r chi.Router) {
+					r.Post("/", handleClaimsReassert)
+				})
+			})
+		})
+	})
+
+	r.Route("/counters", func(r chi.Router) {
+		r.Route("/certificates", func(r chi.Router) {
+			r.Route("/issued", func(r chi.Router) { r.Get("/", handleCountersIssued) })
+			r.Route("/revoked", func(r chi.Router) { r.Get("/", handleCountersRevoked) })
+		})
+	})
+
+	r.Route("/login", func(r chi.Router) { r.Post("/", handleLogin) })
+
+	r.Route("/quotas", func(r chi.Router) {
+		r.Route("/issuance", func(r chi.Router) { r.Get("/", handleQuotasIssuance) })
+	})
+
+	r.Route("/stats", func(r chi.Router) {
+		r.Route("/expiring", func(r chi.Router) { r.Get("/", handleStatsExpiring) })
+		r.Route("/issued", func(r chi.Router) { r.Get("/", handleStatsIssued) })
+		r.Route("/revoked", func(r chi.Router) { r.Get("/", handleStatsRevoked) })
+	})
+
+	r.Route("/trustchain", func(r chi.Router) { r.Get("/", handleTrustChain) })
+
+	r.Route("/validationpolicy", func(r chi.Router) { r.Get("/", handleValidationPolicy) })
+
+	return withOptions(r)
+}
+
+// withOptions wraps a router to answer OPTIONS requests with an Allow header
+// listing the methods it routes for the requested path.
+func withOptions(routes chi.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodOptions {
+			routes.ServeHTTP(w, r)
+			return
+		}
+
+		var allowed []string
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete} {
+			if routes.Match(chi.NewRouteContext(), method, r.URL.Path) {
+				allowed = append(allowed, method)
+			}
+		}
+
+		if len(allowed) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// Config returns a configuration object for a client of the mock HVCA
+// server at the specified URL, such as the URL of the server returned by
+// NewServer.
+func Config(url string) *hvclient.Config {
+	return &hvclient.Config{
+		URL:          url,
+		APIKey:       APIKey,
+		APISecret:    APISecret,
+		ExtraHeaders: map[string]string{SSLClientSerialHeader: SSLClientSerial},
+	}
+}
+
+// handleCertificatesRequest mocks a POST /certificates operation.
+func handleCertificatesRequest(w http.ResponseWriter, r *http.Request) {
+	var body hvclient.Request
+	var err = unmarshalBody(w, r, &body)
+	if err != nil {
+		return
+	}
+
+	// Trigger 422 for specific common name.
+	if body.Subject != nil && body.Subject.CommonName == TriggerError {
+		writeError(w, http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("http://%s/certificates/%X", r.Host, Cert.SerialNumber))
+	writeResponse(w, http.StatusCreated, nil)
+}
+
+// handleCertificatesRetrieve mocks a GET /certificates operation.
+func handleCertificatesRetrieve(w http.ResponseWriter, r *http.Request) {
+	// Extract serial number from URL.
+	var sn, ok = big.NewInt(0).SetString(chi.URLParam(r, "serial"), 16)
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Trigger 404 for specific serial number.
+	if sn.Cmp(SerialNotFound) == 0 {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeResponse(w, http.StatusOK, certInfo{
+		PEM:       pki.CertToPEMString(Cert),
+		Status:    "ISSUED",
+		UpdatedAt: DateUpdated.Unix(),
+	})
+}
+
+// handleCertificatesRevoke mocks a DELETE /certificates operation.
+func handleCertificatesRevoke(w http.ResponseWriter, r *http.Request) {
+	// Extract serial number from URL.
+	var sn, ok = big.NewInt(0).SetString(chi.URLParam(r, "serial"), 16)
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Unmarshal body.
+	var body revocationBody
+	var err = unmarshalBody(w, r, &body)
+	if err != nil {
+		return
+	}
+
+	// Return 404 for specific serial number.
+	if sn.Cmp(SerialNotFound) == 0 {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeResponse(w, http.StatusNoContent, nil)
+}
+
+// handleClaimsDelete mocks a DELETE /claims/domains/{id} operation.
+func handleClaimsDelete(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "arg")
+
+	// Trigger 404 for specific ID
+	if id == TriggerError {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeResponse(w, http.StatusNoContent, nil)
+}
+
+// handleClaimsDNS mocks a POST /claims/domains/{id}/dns operation.
+func handleClaimsDNS(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "arg")
+
+	// Trigger 404 for specific ID
+	if id == TriggerError {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	// Unmarshal body.
+	var body dnsRequest
+	var err = unmarshalBody(w, r, &body)
+	if err != nil {
+		return
+	}
+
+	if body.AuthorizationDomain == ClaimDomainVerified {
+		writeResponse(w, http.StatusNoContent, nil)
+		return
+	}
+
+	writeResponse(w, http.StatusCreated, nil)
+}
+
+// handleClaimsEmail mocks a POST /claims/domains/{id}/email operation.
+func handleClaimsEmail(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "arg")
+
+	// Trigger 404 for specific ID
+	if id == TriggerError {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	// Unmarshal body.
+	var body emailRequest
+	var err = unmarshalBody(w, r, &body)
+	if err != nil {
+		return
+	}
+
+	if body.Email == ClaimEmail {
+		writeResponse(w, http.StatusNoContent, nil)
+		return
+	}
+
+	writeResponse(w, http.StatusCreated, nil)
+}
+
+// handleClaimsEmailRetrieve mocks a GET /claims/domains/{id}/email operation.
+func handleClaimsEmailRetrieve(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "arg")
+
+	// Trigger 404 for specific ID
+	if id == TriggerError {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	var resp = authorisedEmails{
+		Constructed: []string{
+			"admin@test.com",
+			"administrator@test.com",
+			"webmaster@test.com",
+			"hostmaster@test.com",
+			"postmaster@test.com",
+		},
+		DNS: dnsResults{
+			SOA: soaResults{
+				Emails: []string{
+					"example@test.com",
+				},
+			},
+		},
+	}
+
+	writeResponse(w, http.StatusOK, &resp)
+}
+
+// handleClaimsHTTP mocks a POST /claims/domains/{id}/http operation.
+func handleClaimsHTTP(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "arg")
+
+	// Trigger 404 for specific ID
+	if id == TriggerError {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	// Unmarshal body.
+	var body httpRequest
+	var err = unmarshalBody(w, r, &body)
+	if err != nil {
+		return
+	}
+
+	if body.AuthorizationDomain == ClaimDomainVerified {
+		writeResponse(w, http.StatusNoContent, nil)
+		return
+	}
+
+	writeResponse(w, http.StatusCreated, nil)
+}
+
+// handleClaimsDomains mocks a GET /claims/domains operation.
+func handleClaimsDomains(w http.ResponseWriter, r *http.Request) {
+	// Return all claims if no status is specified.
+	var status string
+	if vals := r.URL.Query()["status"]; len(vals) > 0 {
+		status = vals[0]
+	}
+
+	var entries []claim
+	for _, entry := range claimsEntries {
+		if status == "" || (entry.Status == "VERIFIED") == (status == "VERIFIED") {
+			entries = append(entries, entry)
+		}
+	}
+
+	w.Header().Set("Total-Count", fmt.Sprintf("%d", len(entries)))
+	writeResponse(w, http.StatusOK, entries)
+}
+
+// handleClaimsSubmit mocks a POST /claims/domains/{domain} operation.
+func handleClaimsSubmit(w http.ResponseWriter, r *http.Request) {
+	var domain = chi.URLParam(r, "arg")
+
+	// Trigger 422 for specific domain
+	if domain == TriggerError {
+		writeError(w, http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("http://local/claims/domains/%s", claimAssert.ID))
+	writeResponse(w, http.StatusCreated, claimAssert)
+}
+
+// handleClaimsReassert mocks a POST /claims/domains/{id}/reassert operation.
+func handleClaimsReassert(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "arg")
+
+	// Trigger 422 for specific domain
+	if id == TriggerError {
+		writeError(w, http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("http://local/claims/domains/%s", claimAssert.ID))
+	writeResponse(w, http.StatusOK, claimAssert)
+}
+
+// handleClaimsRetrieve mocks a GET /claims/domains/{id} operation.
+func handleClaimsRetrieve(w http.ResponseWriter, r *http.Request) {
+	var id = chi.URLParam(r, "arg")
+
+	// Trigger 404 for specific ID
+	if id == TriggerError {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	writeResponse(w, http.StatusOK, claimsEntries[0])
+}
+
+// handleCountersIssued mocks a GET /counters/certificates/issued operation.
+func handleCountersIssued(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, counter{Value: CounterIssued})
+}
+
+// handleCountersRevoked mocks a GET /counters/certificates/revoked operation.
+func handleCountersRevoked(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, counter{Value: CounterRevoked})
+}
+
+// handleLogin mocks a POST /login operation.
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	var body handleLoginRequest
+	var err = unmarshalBody(w, r, &body)
+	if err != nil {
+		return
+	}
+
+	// Trivially verify the expected SSL client serial header.
+	var serial = r.Header.Get(SSLClientSerialHeader)
+	if serial != SSLClientSerial {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	// Trivially verify the expected API key.
+	if body.APIKey != APIKey {
+		writeError(w, http.StatusUnauthorized)
+		return
+	}
+
+	writeResponse(w, http.StatusOK, handleLoginResponse{Token: Token})
+}
+
+// handleValidationPolicy mocks a GET /validationpolicy operation.
+func handleValidationPolicy(w http.ResponseWriter, r *http.Request) {
+	writeCacheableResponse(w, r, Policy)
+}
+
+// handleQuotasIssuance mocks a GET /quotas/issuance operation.
+func handleQuotasIssuance(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, counter{Value: QuotaIssuance})
+}
+
+// handleStatsExpiring mocks a GET /stats/expiring operation.
+func handleStatsExpiring(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Total-Count", fmt.Sprintf("%d", len(statsExpiringData)))
+	writeResponse(w, http.StatusOK, statsExpiringData)
+}
+
+// handleStatsIssued mocks a GET /stats/issued operation.
+func handleStatsIssued(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Total-Count", fmt.Sprintf("%d", len(statsIssuedData)))
+	writeResponse(w, http.StatusOK, statsIssuedData)
+}
+
+// handleStatsRevoked mocks a GET /stats/revoked operation.
+func handleStatsRevoked(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Total-Count", fmt.Sprintf("%d", len(statsIssuedData[1:])))
+	writeResponse(w, http.StatusOK, statsIssuedData[1:])
+}
+
+// handleTrustChain mocks a GET /trustchain operation.
+func handleTrustChain(w http.ResponseWriter, r *http.Request) {
+	var chain = make([]string, len(TrustChain))
+	for i := range chain {
+		chain[i] = pki.CertToPEMString(TrustChain[i])
+	}
+
+	writeCacheableResponse(w, r, chain)
+}
+
+// unmarshalBody unmarshals an HTTP request body, and writes an appropriate
+// HTTP error response on failure.
+func unmarshalBody(w http.ResponseWriter, r *http.Request, out interface{}) error {
+	var err = httputils.VerifyRequestContentType(r, httputils.ContentTypeJSON)
+	if err != nil {
+		writeError(w, http.StatusUnsupportedMediaType)
+		return err
+	}
+
+	// Read and parse request body.
+	var data []byte
+	data, err = ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError)
+		return err
+	}
+
+	err = json.Unmarshal(data, &out)
+	if err != nil {
+		writeError(w, http.StatusBadRequest)
+		return err
+	}
+
+	return nil
+}
+
+// writeError writes an error HTTP response.
+func writeError(w http.ResponseWriter, status int) {
+	var data, err = json.Marshal(errorResponse{Description: http.StatusText(status)})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	} else {
+		w.Header().Set(httputils.ContentTypeHeader, httputils.ContentTypeProblemJSON)
+		w.WriteHeader(status)
+		_, _ = w.Write(data)
+	}
+}
+
+// writeResponse writes an HTTP response. If obj is not nil, it will be
+// marshalled to JSON and used as the response body.
+func writeResponse(w http.ResponseWriter, status int, obj interface{}) {
+	if obj != nil {
+		var data, err = json.Marshal(obj)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		w.Header().Set(httputils.ContentTypeHeader, httputils.ContentTypeJSON)
+		w.WriteHeader(status)
+		_, _ = w.Write(data)
+	} else {
+		w.WriteHeader(status)
+	}
+}
+
+// writeCacheableResponse writes an HTTP response with obj marshalled to JSON
+// as the body and an entity tag derived from it, or a not modified response
+// without a body if the request has an If-None-Match header with the same
+// entity tag.
+func writeCacheableResponse(w http.ResponseWriter, r *http.Request, obj interface{}) {
+	var data, err = json.Marshal(obj)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var sum = sha256.Sum256(data)
+	var etag = `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	w.Header().Set(httputils.ETagHeader, etag)
+
+	if r.Header.Get(httputils.IfNoneMatchHeader) == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set(httputils.ContentTypeHeader, httputils.ContentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
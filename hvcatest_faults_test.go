@@ -0,0 +1,209 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+)
+
+// instantClock is a Clock whose timers fire immediately, so that retries
+// are not delayed.
+type instantClock struct{}
+
+func (instantClock) Now() time.Time {
+	return time.Now()
+}
+
+func (instantClock) After(d time.Duration) <-chan time.Time {
+	var ch = make(chan time.Time, 1)
+	ch <- time.Now()
+
+	return ch
+}
+
+// newFaultClient returns a client of a mock HVCA server into which the
+// specified faults are injected once the client has logged in.
+func newFaultClient(t *testing.T, faults ...hvcatest.Fault) (*hvclient.Client, *hvcatest.FaultInjector) {
+	t.Helper()
+
+	var injector = hvcatest.NewFaultInjector(hvcatest.NewHandler())
+	var server = httptest.NewServer(injector)
+	t.Cleanup(server.Close)
+
+	var conf = hvcatest.Config(server.URL)
+	conf.Clock = instantClock{}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var clnt, err = hvclient.NewClient(ctx, conf)
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+
+	for _, fault := range faults {
+		injector.Add(fault)
+	}
+
+	return clnt, injector
+}
+
+func TestFaultInjectorRecovered(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		fault hvcatest.Fault
+		want  int
+	}{
+		{
+			name:  "Intermittent",
+			fault: hvcatest.Fault{Path: "/quotas", Status: http.StatusServiceUnavailable, Count: 2},
+			want:  2,
+		},
+		{
+			name:  "ExpiredToken",
+			fault: hvcatest.Fault{ExpireToken: true, Count: 1},
+			want:  1,
+		},
+		{
+			name:  "Latency",
+			fault: hvcatest.Fault{Method: http.MethodGet, Latency: time.Millisecond * 10},
+			want:  1,
+		},
+		{
+			name:  "OtherEndpoint",
+			fault: hvcatest.Fault{Path: "/certificates", Status: http.StatusServiceUnavailable},
+			want:  0,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var clnt, injector = newFaultClient(t, tc.fault)
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+			defer cancel()
+
+			var got, err = clnt.QuotaIssuance(ctx)
+			if err != nil {
+				t.Fatalf("couldn't get quota: %v", err)
+			}
+
+			if got != hvcatest.QuotaIssuance {
+				t.Errorf("got %d, want %d", got, hvcatest.QuotaIssuance)
+			}
+
+			if n := injector.Injected(); n != tc.want {
+				t.Errorf("got %d faults injected, want %d", n, tc.want)
+			}
+		})
+	}
+}
+
+func TestFaultInjectorFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		fault hvcatest.Fault
+		check func(error) bool
+	}{
+		{
+			name:  "Persistent",
+			fault: hvcatest.Fault{Status: http.StatusServiceUnavailable},
+			check: func(err error) bool {
+				var apiErr hvclient.APIError
+				return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusServiceUnavailable
+			},
+		},
+		{
+			name:  "MalformedBody",
+			fault: hvcatest.Fault{Path: "/quotas/issuance", MalformedBody: true},
+			check: func(err error) bool {
+				var respErr *hvclient.ResponseError
+				return errors.As(err, &respErr)
+			},
+		},
+		{
+			name:  "Timeout",
+			fault: hvcatest.Fault{Latency: time.Second * 10},
+			check: func(err error) bool {
+				return errors.Is(err, context.DeadlineExceeded)
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var clnt, _ = newFaultClient(t, tc.fault)
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*100)
+			defer cancel()
+
+			var _, err = clnt.QuotaIssuance(ctx)
+			if !tc.check(err) {
+				t.Errorf("got unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestFaultInjectorEvery(t *testing.T) {
+	t.Parallel()
+
+	var clnt, injector = newFaultClient(t, hvcatest.Fault{Status: http.StatusBadRequest, Every: 3})
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var failures int
+	for i := 0; i < 6; i++ {
+		if _, err := clnt.QuotaIssuance(ctx); err != nil {
+			failures++
+		}
+	}
+
+	if failures != 2 {
+		t.Errorf("got %d failures, want 2", failures)
+	}
+
+	injector.Reset()
+
+	if _, err := clnt.QuotaIssuance(ctx); err != nil {
+		t.Errorf("couldn't get quota after reset: %v", err)
+	}
+
+	if n := injector.Injected(); n != 0 {
+		t.Errorf("got %d faults injected after reset, want 0", n)
+	}
+}
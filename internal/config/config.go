@@ -17,6 +17,7 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 )
 
@@ -51,8 +52,32 @@ type Config struct {
 	// HVCA server with each request.
 	ExtraHeaders map[string]string `json:"extra_headers,omitempty"`
 
+	// UserAgent is the value of the User-Agent header sent with each
+	// request.
+	UserAgent string `json:"user_agent,omitempty"`
+
 	// Timeout is the maximum time in seconds for an HVCA API request.
 	Timeout int `json:"timeout"`
+
+	// CAFile is the path of a file containing PEM-encoded root certificates
+	// used to validate the HVCA server certificate.
+	CAFile string `json:"ca_file,omitempty"`
+
+	// HTTPProxy is the URL of the proxy server to use for HVCA requests.
+	HTTPProxy string `json:"http_proxy,omitempty"`
+
+	// NoProxy is a comma-separated list of hosts which should not be proxied.
+	NoProxy string `json:"no_proxy,omitempty"`
+
+	// DefaultDuration is the certificate duration, such as "90d", used by
+	// the hvclient command for requests which specify neither a not-after
+	// time nor a duration.
+	DefaultDuration string `json:"default_duration,omitempty"`
+
+	// Profiles contains named sets of settings, for example for different
+	// HVCA accounts. Settings in a profile override those at the top level
+	// of the file.
+	Profiles map[string]json.RawMessage `json:"profiles,omitempty"`
 }
 
 // NewFromFile creates a new Config object from a configuration file.
@@ -70,3 +95,49 @@ func NewFromFile(filename string) (*Config, error) {
 
 	return newConfig, nil
 }
+
+// NewFromFileProfile creates a new Config object from the named profile in a
+// configuration file. Any settings at the top level of the file are used as
+// defaults for settings not specified in the profile. If name is empty, the
+// top level settings are returned, as for NewFromFile.
+func NewFromFileProfile(filename, name string) (*Config, error) {
+	var base, err = NewFromFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		return base, nil
+	}
+
+	if base == nil {
+		return nil, fmt.Errorf("no profile named %q in %s", name, filename)
+	}
+
+	var raw, ok = base.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("no profile named %q in %s", name, filename)
+	}
+
+	var profile = *base
+	profile.Profiles = nil
+
+	// Copy the extra headers so headers added by the profile don't
+	// modify the top level settings.
+	if base.ExtraHeaders != nil {
+		profile.ExtraHeaders = make(map[string]string, len(base.ExtraHeaders))
+		for key, value := range base.ExtraHeaders {
+			profile.ExtraHeaders[key] = value
+		}
+	}
+
+	if err = json.Unmarshal(raw, &profile); err != nil {
+		return nil, fmt.Errorf("invalid profile %q in %s: %w", name, filename, err)
+	}
+
+	if profile.Profiles != nil {
+		return nil, fmt.Errorf("profile %q in %s may not contain profiles", name, filename)
+	}
+
+	return &profile, nil
+}
@@ -111,3 +111,101 @@ func TestConfigNewFromFileError(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigNewFromFileProfile(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		want config.Config
+	}{
+		{
+			name: "prod",
+			want: config.Config{
+				URL:          "https://emea.api.hvca.globalsign.com:8443/v2",
+				APIKey:       "prod api key",
+				APISecret:    "prod api secret",
+				CertFile:     "/home/jdoe/prod/certfile.pem",
+				KeyFile:      "/home/jdoe/prod/keyfile.pem",
+				ExtraHeaders: map[string]string{"X-Shared": "shared"},
+				Timeout:      30,
+			},
+		},
+		{
+			name: "staging",
+			want: config.Config{
+				URL:       "https://staging.example.com:8443/v2",
+				APIKey:    "staging api key",
+				APISecret: "staging api secret",
+				CertFile:  "/home/jdoe/staging/certfile.pem",
+				KeyFile:   "/home/jdoe/staging/keyfile.pem",
+				ExtraHeaders: map[string]string{
+					"X-Shared":  "shared",
+					"X-Staging": "yes",
+				},
+				Timeout: 60,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = config.NewFromFileProfile("testdata/test_profiles.conf", tc.name)
+			if err != nil {
+				t.Fatalf("couldn't get configuration profile from file: %v", err)
+			}
+
+			if !cmp.Equal(*got, tc.want) {
+				t.Errorf("got %v, want %v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfigNewFromFileProfileDefault(t *testing.T) {
+	t.Parallel()
+
+	var got, err = config.NewFromFileProfile("testdata/test_profiles.conf", "")
+	if err != nil {
+		t.Fatalf("couldn't get configuration from file: %v", err)
+	}
+
+	if got.URL != "https://emea.api.hvca.globalsign.com:8443/v2" || got.APIKey != "" {
+		t.Errorf("got %v, want top level settings", *got)
+	}
+
+	if len(got.Profiles) != 4 {
+		t.Errorf("got %d profiles, want %d", len(got.Profiles), 4)
+	}
+}
+
+func TestConfigNewFromFileProfileError(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		filename string
+		name     string
+	}{
+		{"testdata/test_profiles.conf", "missing"},
+		{"testdata/test_profiles.conf", "nested"},
+		{"testdata/test_profiles.conf", "malformed"},
+		{"testdata/test.conf", "prod"},
+		{"there/is/no_such_file.conf", "prod"},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(filepath.Base(tc.filename)+"/"+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, err := config.NewFromFileProfile(tc.filename, tc.name); err == nil {
+				t.Errorf("unexpectedly got configuration profile from file: %v", *got)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fileutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// LockSuffix is appended to the name of a file to obtain the name of the
+// lock file used by WriteFileLocked.
+const LockSuffix = ".lock"
+
+// Lock is an advisory lock held on a lock file. Advisory locks only exclude
+// other processes which acquire the same lock, and are not supported on
+// every platform, on which locking always succeeds immediately.
+type Lock struct {
+	f *os.File
+}
+
+// LockFile acquires an exclusive advisory lock on the named lock file,
+// creating it if necessary, and blocks until the lock is acquired. The lock
+// file is left in place when the lock is released.
+func LockFile(path string) (*Lock, error) {
+	var f, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open lock file: %w", err)
+	}
+
+	if err = lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("couldn't lock %s: %w", path, err)
+	}
+
+	return &Lock{f: f}, nil
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() error {
+	var err = unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// WriteFile writes data to the named file with the specified permissions,
+// replacing it if it already exists. The data is written to a temporary file
+// in the same directory which is then renamed, so a partially-written file
+// is never observed, and the existing file is unchanged if writing fails.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	var f, err = ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// WriteFileLocked writes data to the named file as WriteFile does, while
+// holding an advisory lock on a lock file with the same name plus
+// LockSuffix, so that concurrent writers do not interleave.
+func WriteFileLocked(path string, data []byte, perm os.FileMode) error {
+	var lock, err = LockFile(path + LockSuffix)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return WriteFile(path, data, perm)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fileutils_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/fileutils"
+)
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var path = filepath.Join(dir, "out.pem")
+
+	for _, data := range [][]byte{[]byte("first"), []byte("second")} {
+		if err := fileutils.WriteFile(path, data, 0600); err != nil {
+			t.Fatalf("couldn't write file: %v", err)
+		}
+
+		var got, err = ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("couldn't read file: %v", err)
+		}
+
+		if !bytes.Equal(got, data) {
+			t.Errorf("got %q, want %q", got, data)
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		var info, err = os.Stat(path)
+		if err != nil {
+			t.Fatalf("couldn't stat file: %v", err)
+		}
+
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("got permissions %v, want %v", perm, os.FileMode(0600))
+		}
+	}
+
+	// No temporary files should be left behind.
+	var entries, err = ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("couldn't read directory: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("got %d files in directory, want 1", len(entries))
+	}
+}
+
+func TestWriteFileFailure(t *testing.T) {
+	t.Parallel()
+
+	var path = filepath.Join(t.TempDir(), "missing", "out.pem")
+
+	if err := fileutils.WriteFile(path, []byte("data"), 0600); err == nil {
+		t.Fatalf("unexpectedly wrote file in missing directory")
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	t.Parallel()
+
+	switch runtime.GOOS {
+	case "darwin", "dragonfly", "freebsd", "linux", "netbsd", "openbsd", "windows":
+	default:
+		t.Skipf("advisory locking not supported on %s", runtime.GOOS)
+	}
+
+	var path = filepath.Join(t.TempDir(), "out.pem"+fileutils.LockSuffix)
+
+	var lock, err = fileutils.LockFile(path)
+	if err != nil {
+		t.Fatalf("couldn't lock file: %v", err)
+	}
+
+	var acquired = make(chan struct{})
+
+	go func() {
+		var second, err = fileutils.LockFile(path)
+		if err != nil {
+			t.Errorf("couldn't lock file: %v", err)
+		} else {
+			second.Unlock()
+		}
+
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatalf("acquired lock while it was held")
+
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err = lock.Unlock(); err != nil {
+		t.Fatalf("couldn't unlock file: %v", err)
+	}
+
+	select {
+	case <-acquired:
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("didn't acquire lock after it was released")
+	}
+}
+
+func TestWriteFileLocked(t *testing.T) {
+	t.Parallel()
+
+	var path = filepath.Join(t.TempDir(), "out.pem")
+
+	if err := fileutils.WriteFileLocked(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("couldn't write file: %v", err)
+	}
+
+	for _, name := range []string{path, path + fileutils.LockSuffix} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("couldn't stat %s: %v", filepath.Base(name), err)
+		}
+	}
+}
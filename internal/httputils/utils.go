@@ -27,16 +27,27 @@ import (
 // HTTP header constants.
 const (
 	AuthorizationHeader    = "Authorization"
+	ContentEncodingHeader  = "Content-Encoding"
+	ContentEncodingGzip    = "gzip"
 	ContentTypeHeader      = "Content-Type"
 	ContentTypeJSON        = "application/json"
 	ContentTypeJSONUTF8    = "application/json;charset=utf-8"
 	ContentTypeProblemJSON = "application/problem+json"
+	ETagHeader             = "ETag"
+	IfNoneMatchHeader      = "If-None-Match"
+	UserAgentHeader        = "User-Agent"
 )
 
-// ConsumeAndCloseResponseBody discards any remaining contents in an HTTP
-// response body and closes it.
+// maxDrainSize is the maximum number of bytes discarded from an HTTP
+// response body before it is closed. Discarding a small remainder allows the
+// connection to be reused, but a large one, such as the rest of a body which
+// was rejected for being too large, is not worth reading.
+const maxDrainSize = 64 * 1024
+
+// ConsumeAndCloseResponseBody discards up to maxDrainSize bytes of any
+// remaining contents in an HTTP response body and closes it.
 func ConsumeAndCloseResponseBody(r *http.Response) {
-	_, _ = io.Copy(ioutil.Discard, r.Body)
+	_, _ = io.CopyN(ioutil.Discard, r.Body, maxDrainSize)
 	r.Body.Close()
 }
 
@@ -0,0 +1,23 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package keychain stores and retrieves secrets in the operating system's
+credential store: the Keychain on macOS, the Credential Manager on Windows,
+and a Secret Service provider such as GNOME Keyring or KWallet, through the
+secret-tool command, on Linux and the BSDs.
+*/
+
+package keychain
@@ -0,0 +1,38 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keychain
+
+import "errors"
+
+// ErrNotFound is returned when no secret is stored for a service and
+// account.
+var ErrNotFound = errors.New("secret not found in keychain")
+
+// ErrUnsupported is returned on platforms without a supported credential
+// store.
+var ErrUnsupported = errors.New("keychain not supported on this platform")
+
+// Get returns the secret stored for the specified service and account, or
+// ErrNotFound if there is none.
+func Get(service, account string) (string, error) {
+	return get(service, account)
+}
+
+// Set stores a secret for the specified service and account, replacing any
+// secret already stored for them.
+func Set(service, account, secret string) error {
+	return set(service, account, secret)
+}
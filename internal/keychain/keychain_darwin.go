@@ -0,0 +1,64 @@
+//go:build darwin
+// +build darwin
+
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// securityNotFound is the exit status of the security command when no
+// matching keychain item exists.
+const securityNotFound = 44
+
+// get returns a secret from the macOS Keychain.
+func get(service, account string) (string, error) {
+	var out, err = exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityNotFound {
+		return "", ErrNotFound
+	} else if err != nil {
+		return "", fmt.Errorf("couldn't read from keychain: %w", err)
+	}
+
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// set stores a secret in the macOS Keychain. The command is passed to the
+// security command on its standard input, so the secret does not appear in
+// the process list.
+func set(service, account, secret string) error {
+	var cmd = exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		quote(service), quote(account), quote(secret)))
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("couldn't write to keychain: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// quote quotes a value for the security command's interactive mode.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
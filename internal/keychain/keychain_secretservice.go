@@ -0,0 +1,60 @@
+//go:build dragonfly || freebsd || linux || netbsd || openbsd
+// +build dragonfly freebsd linux netbsd openbsd
+
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keychain
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// secretTool is the name of the command used to access the Secret Service.
+var secretTool = "secret-tool"
+
+// get returns a secret from the Secret Service.
+func get(service, account string) (string, error) {
+	var out, err = exec.Command(secretTool, "lookup", "service", service, "account", account).Output()
+
+	// secret-tool exits with a status of 1 and no output if there is no
+	// matching secret.
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(out) == 0 {
+		return "", ErrNotFound
+	} else if err != nil {
+		return "", fmt.Errorf("couldn't read from keychain: %w", err)
+	}
+
+	return string(out), nil
+}
+
+// set stores a secret in the Secret Service. The secret is passed to
+// secret-tool on its standard input, so it does not appear in the process
+// list.
+func set(service, account, secret string) error {
+	var cmd = exec.Command(secretTool, "store", "--label", service+" "+account,
+		"service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("couldn't write to keychain: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+//go:build dragonfly || freebsd || linux || netbsd || openbsd
+// +build dragonfly freebsd linux netbsd openbsd
+
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keychain
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSecretTool is a shell script which mimics secret-tool, storing each
+// secret in a file alongside the script.
+const fakeSecretTool = `#!/bin/sh
+dir=$(dirname "$0")
+case "$1" in
+store) cat > "$dir/secret-$5-$7" ;;
+lookup) [ -f "$dir/secret-$3-$5" ] || exit 1; cat "$dir/secret-$3-$5" ;;
+*) echo "unknown command" >&2; exit 2 ;;
+esac
+`
+
+func TestSecretService(t *testing.T) {
+	var script = filepath.Join(t.TempDir(), "secret-tool")
+	if err := ioutil.WriteFile(script, []byte(fakeSecretTool), 0700); err != nil {
+		t.Fatalf("couldn't write script: %v", err)
+	}
+
+	var saved = secretTool
+	secretTool = script
+	defer func() { secretTool = saved }()
+
+	if _, err := Get("hvclient", "api_secret"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, ErrNotFound)
+	}
+
+	for _, secret := range []string{"first secret", "second secret"} {
+		if err := Set("hvclient", "api_secret", secret); err != nil {
+			t.Fatalf("couldn't store secret: %v", err)
+		}
+
+		var got, err = Get("hvclient", "api_secret")
+		if err != nil {
+			t.Fatalf("couldn't retrieve secret: %v", err)
+		}
+
+		if got != secret {
+			t.Errorf("got %q, want %q", got, secret)
+		}
+	}
+
+	secretTool = filepath.Join(t.TempDir(), "no-such-command")
+
+	if _, err := Get("hvclient", "api_secret"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, want failure to run command", err)
+	}
+}
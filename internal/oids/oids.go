@@ -17,6 +17,7 @@ package oids
 
 import (
 	"encoding/asn1"
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -25,6 +26,15 @@ import (
 var (
 	OIDKeyUsage                      = asn1.ObjectIdentifier{2, 5, 29, 15}
 	OIDExtendedKeyUsage              = asn1.ObjectIdentifier{2, 5, 29, 37}
+	OIDSubjectAltName                = asn1.ObjectIdentifier{2, 5, 29, 17}
+	OIDSubjectCommonName             = asn1.ObjectIdentifier{2, 5, 4, 3}
+	OIDSubjectSerialNumber           = asn1.ObjectIdentifier{2, 5, 4, 5}
+	OIDSubjectCountry                = asn1.ObjectIdentifier{2, 5, 4, 6}
+	OIDSubjectLocality               = asn1.ObjectIdentifier{2, 5, 4, 7}
+	OIDSubjectState                  = asn1.ObjectIdentifier{2, 5, 4, 8}
+	OIDSubjectStreetAddress          = asn1.ObjectIdentifier{2, 5, 4, 9}
+	OIDSubjectOrganization           = asn1.ObjectIdentifier{2, 5, 4, 10}
+	OIDSubjectOrganizationalUnit     = asn1.ObjectIdentifier{2, 5, 4, 11}
 	OIDSubjectEmail                  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 1}
 	OIDSubjectJOILocality            = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 60, 2, 1, 1}
 	OIDSubjectJOIState               = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 60, 2, 1, 2}
@@ -38,12 +48,45 @@ var (
 	OIDSubjectDACountryOfResidence   = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 9, 5}
 )
 
+// Object identifiers for CMS content types and hash algorithms, as used in
+// RFC 3161 timestamp tokens and PKCS#7 certificate bundles.
+var (
+	OIDContentTypeData       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	OIDContentTypeSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	OIDContentTypeTSTInfo    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	OIDHashSHA1              = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	OIDHashSHA256            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	OIDHashSHA384            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	OIDHashSHA512            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+// Object identifiers for PKCS#12 bag types, attributes and password-based
+// encryption, as used in .p12 and .pfx files.
+var (
+	OIDPKCS12KeyBag            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	OIDPKCS12CertBag           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	OIDPKCS12X509Certificate   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	OIDPKCS12LocalKeyID        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	OIDPKCS12PBEWithSHA3DESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+)
+
+// Object identifiers for certificate transparency extensions, as described
+// in RFC 6962.
+var (
+	OIDCTPoison = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 3}
+)
+
 // StringToOID converts a string representation of an OID to an
-// asn1.ObjectIdentifier object.
+// asn1.ObjectIdentifier object. Each component must be a non-negative
+// decimal integer.
 func StringToOID(s string) (asn1.ObjectIdentifier, error) {
 	var oid = asn1.ObjectIdentifier{}
 
 	for _, n := range strings.Split(strings.TrimSpace(s), ".") {
+		if strings.HasPrefix(n, "-") || strings.HasPrefix(n, "+") {
+			return nil, fmt.Errorf("invalid OID component %q", n)
+		}
+
 		var value, err = strconv.Atoi(n)
 		if err != nil {
 			return nil, err
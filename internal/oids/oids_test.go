@@ -68,6 +68,9 @@ func TestStringToOIDFailure(t *testing.T) {
 		"",
 		"not an oid",
 		"1.2.not_a_digit",
+		"1.-2.3",
+		"1.+2.3",
+		"1.2.99999999999999999999",
 	}
 
 	for _, tc := range testcases {
@@ -0,0 +1,223 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pki
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// jsonWebKey is the JSON encoding of an RSA or elliptic curve JSON Web Key,
+// as described in RFC 7517 and RFC 7518.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	D   string `json:"d"`
+	P   string `json:"p"`
+	Q   string `json:"q"`
+}
+
+// jwkCurves maps JSON Web Key curve names to elliptic curves.
+var jwkCurves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// IsJWK reports whether data appears to be a JSON Web Key rather than PEM
+// data, because it is a JSON object.
+func IsJWK(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
+
+// KeyFromJWK decodes a JSON Web Key and returns the RSA or ECDSA key it
+// contains, as a *rsa.PublicKey, *rsa.PrivateKey, *ecdsa.PublicKey or
+// *ecdsa.PrivateKey.
+func KeyFromJWK(data []byte) (interface{}, error) {
+	var jwk jsonWebKey
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("invalid JWK: %w", err)
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		return rsaKeyFromJWK(jwk)
+
+	case "EC":
+		return ecdsaKeyFromJWK(jwk)
+	}
+
+	return nil, fmt.Errorf("unsupported JWK key type %q", jwk.Kty)
+}
+
+// PublicKeyFromJWK decodes a JSON Web Key and returns the RSA or ECDSA
+// public key it contains. If the JWK contains a private key, its public key
+// is returned.
+func PublicKeyFromJWK(data []byte) (interface{}, error) {
+	var key, err = KeyFromJWK(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey, nil
+
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey, nil
+	}
+
+	return key, nil
+}
+
+// PrivateKeyFromJWK decodes a JSON Web Key and returns the RSA or ECDSA
+// private key it contains. If the JWK does not contain a private key, an
+// error is returned.
+func PrivateKeyFromJWK(data []byte) (interface{}, error) {
+	var key, err = KeyFromJWK(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+		return key, nil
+	}
+
+	return nil, errors.New("JWK does not contain a private key")
+}
+
+// rsaKeyFromJWK returns the RSA public or private key in a JSON Web Key.
+// Private keys must include their prime factors.
+func rsaKeyFromJWK(jwk jsonWebKey) (interface{}, error) {
+	var n, err = jwkInt("n", jwk.N)
+	if err != nil {
+		return nil, err
+	}
+
+	var e *big.Int
+	if e, err = jwkInt("e", jwk.E); err != nil {
+		return nil, err
+	}
+
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid JWK: RSA public exponent too large")
+	}
+
+	var pub = rsa.PublicKey{N: n, E: int(e.Int64())}
+
+	if jwk.D == "" {
+		return &pub, nil
+	}
+
+	var key = &rsa.PrivateKey{PublicKey: pub}
+	if key.D, err = jwkInt("d", jwk.D); err != nil {
+		return nil, err
+	}
+
+	if jwk.P == "" || jwk.Q == "" {
+		return nil, errors.New("unsupported JWK: RSA private key without prime factors")
+	}
+
+	var p, q *big.Int
+	if p, err = jwkInt("p", jwk.P); err != nil {
+		return nil, err
+	}
+
+	if q, err = jwkInt("q", jwk.Q); err != nil {
+		return nil, err
+	}
+
+	key.Primes = []*big.Int{p, q}
+
+	if err = key.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid JWK: %w", err)
+	}
+
+	key.Precompute()
+
+	return key, nil
+}
+
+// ecdsaKeyFromJWK returns the ECDSA public or private key in a JSON Web Key.
+func ecdsaKeyFromJWK(jwk jsonWebKey) (interface{}, error) {
+	var curve, ok = jwkCurves[jwk.Crv]
+	if !ok {
+		return nil, fmt.Errorf("unsupported JWK curve %q", jwk.Crv)
+	}
+
+	var x, err = jwkInt("x", jwk.X)
+	if err != nil {
+		return nil, err
+	}
+
+	var y *big.Int
+	if y, err = jwkInt("y", jwk.Y); err != nil {
+		return nil, err
+	}
+
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("invalid JWK: point is not on curve")
+	}
+
+	var pub = ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+
+	if jwk.D == "" {
+		return &pub, nil
+	}
+
+	var d *big.Int
+	if d, err = jwkInt("d", jwk.D); err != nil {
+		return nil, err
+	}
+
+	if d.Sign() <= 0 || d.Cmp(curve.Params().N) >= 0 {
+		return nil, errors.New("invalid JWK: private key out of range")
+	}
+
+	if px, py := curve.ScalarBaseMult(d.Bytes()); px.Cmp(x) != 0 || py.Cmp(y) != 0 {
+		return nil, errors.New("invalid JWK: private key does not match public key")
+	}
+
+	return &ecdsa.PrivateKey{PublicKey: pub, D: d}, nil
+}
+
+// jwkInt decodes a base64url-encoded big-endian integer parameter of a JSON
+// Web Key.
+func jwkInt(name, value string) (*big.Int, error) {
+	if value == "" {
+		return nil, fmt.Errorf("invalid JWK: missing %q parameter", name)
+	}
+
+	var b, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid JWK: bad %q parameter", name)
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pki_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/globalsign/hvclient/internal/pki"
+	"github.com/globalsign/hvclient/internal/testhelpers"
+)
+
+func TestKeyFromJWK(t *testing.T) {
+	t.Parallel()
+
+	var rsaKey, err = pki.PrivateKeyFromFileWithPassword("testdata/rsa_priv.key", "")
+	if err != nil {
+		t.Fatalf("couldn't read RSA private key: %v", err)
+	}
+
+	var ecKey interface{}
+	if ecKey, err = pki.PrivateKeyFromFileWithPassword("testdata/ec_priv.key", ""); err != nil {
+		t.Fatalf("couldn't read EC private key: %v", err)
+	}
+
+	var testcases = []struct {
+		filename string
+		want     interface{}
+	}{
+		{"testdata/rsa_priv.jwk", rsaKey},
+		{"testdata/ec_priv.jwk", ecKey},
+		{"testdata/ec_pub.jwk", &ecKey.(*ecdsa.PrivateKey).PublicKey},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = pki.KeyFromJWK(testhelpers.MustReadFile(t, tc.filename))
+			if err != nil {
+				t.Fatalf("couldn't get key from JWK: %v", err)
+			}
+
+			var equal bool
+			switch k := got.(type) {
+			case *rsa.PrivateKey:
+				equal = k.Equal(tc.want)
+			case *ecdsa.PrivateKey:
+				equal = k.Equal(tc.want)
+			case *ecdsa.PublicKey:
+				equal = k.Equal(tc.want)
+			}
+
+			if !equal {
+				t.Errorf("got %T %v, want %T %v", got, got, tc.want, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeyFromJWKFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		data string
+	}{
+		{"NotJSON", `{"kty":`},
+		{"UnsupportedType", `{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`},
+		{"UnsupportedCurve", `{"kty":"EC","crv":"P-224","x":"AQ","y":"AQ"}`},
+		{"MissingParameter", `{"kty":"EC","crv":"P-256","x":"9SNIJy83BmOBiwyrVroOE6iBFmnQyaSYLvBLC8j3fig"}`},
+		{"BadBase64", `{"kty":"RSA","n":"!!!","e":"AQAB"}`},
+		{"NotOnCurve", `{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}`},
+		{"PrivateKeyMismatch", `{"kty":"EC","crv":"P-256","x":"9SNIJy83BmOBiwyrVroOE6iBFmnQyaSYLvBLC8j3fig","y":"60IYP4e5eiBh2GSXkcZL0_3bli_2R4XPzfzf2qFExUw","d":"AQ"}`},
+		{"ExponentTooLarge", `{"kty":"RSA","n":"AQAB","e":"AQAAAAAAAAAAAQ"}`},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if key, err := pki.KeyFromJWK([]byte(tc.data)); err == nil {
+				t.Fatalf("unexpectedly got key %v", key)
+			}
+		})
+	}
+}
+
+func TestPrivateKeyFromJWKPublicOnly(t *testing.T) {
+	t.Parallel()
+
+	if key, err := pki.PrivateKeyFromJWK(testhelpers.MustReadFile(t, "testdata/ec_pub.jwk")); err == nil {
+		t.Fatalf("unexpectedly got private key %v", key)
+	}
+}
@@ -25,6 +25,8 @@ import (
 
 var errExtraneousPEMData = errors.New("extraneous data in PEM file")
 
+var errNoPEMData = errors.New("no PEM data found")
+
 // PEMBlockFromFile reads a PEM-encoded file and returns a pem.Block.
 func PEMBlockFromFile(filename string) (*pem.Block, error) {
 	var data, err = ioutil.ReadFile(filename)
@@ -32,7 +34,17 @@ func PEMBlockFromFile(filename string) (*pem.Block, error) {
 		return nil, err
 	}
 
+	return PEMBlockFromBytes(data)
+}
+
+// PEMBlockFromBytes decodes PEM-encoded data containing a single PEM block
+// and returns a pem.Block.
+func PEMBlockFromBytes(data []byte) (*pem.Block, error) {
 	var block, rest = pem.Decode(data)
+	if block == nil {
+		return nil, errNoPEMData
+	}
+
 	if len(rest) != 0 {
 		return nil, errExtraneousPEMData
 	}
@@ -43,24 +55,56 @@ func PEMBlockFromFile(filename string) (*pem.Block, error) {
 // FileIsEncryptedPEMBlock checks if the specified file is an encrypted
 // PEM block.
 func FileIsEncryptedPEMBlock(filename string) bool {
-	var block, err = PEMBlockFromFile(filename)
+	var data, err = ioutil.ReadFile(filename)
 	if err != nil {
 		return false
 	}
 
-	if x509.IsEncryptedPEMBlock(block) {
-		return true
+	return IsEncryptedPEMBlock(data)
+}
+
+// IsEncryptedPEMBlock checks if the specified data is an encrypted PEM
+// block.
+func IsEncryptedPEMBlock(data []byte) bool {
+	var block, err = PEMBlockFromBytes(data)
+	if err != nil {
+		return false
 	}
 
-	return false
+	return x509.IsEncryptedPEMBlock(block)
 }
 
 // PrivateKeyFromFileWithPassword reads a PEM-encoded file and returns the
 // private key it contains, decrypting it with the supplied password if
-// necessary. If the file does not contain a PEM-encoded private key, an error
-// is returned.
+// necessary. A file containing a JSON Web Key is also accepted, in which
+// case the password is ignored. If the file does not contain a private key,
+// an error is returned.
 func PrivateKeyFromFileWithPassword(filename, password string) (interface{}, error) {
-	var block, err = PEMBlockFromFile(filename)
+	var data, err = ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return PrivateKeyFromBytesWithPassword(data, password)
+}
+
+// PrivateKeyFromBytesWithPassword is the same as
+// PrivateKeyFromFileWithPassword, but takes the contents of the file rather
+// than its name.
+func PrivateKeyFromBytesWithPassword(data []byte, password string) (interface{}, error) {
+	if IsJWK(data) {
+		return PrivateKeyFromJWK(data)
+	}
+
+	return PrivateKeyFromPEMWithPassword(data, password)
+}
+
+// PrivateKeyFromPEMWithPassword decodes PEM-encoded data and returns the
+// private key it contains, decrypting it with the supplied password if
+// necessary. If the data is not a PEM-encoded private key, an error is
+// returned.
+func PrivateKeyFromPEMWithPassword(data []byte, password string) (interface{}, error) {
+	var block, err = PEMBlockFromBytes(data)
 	if err != nil {
 		return nil, err
 	}
@@ -91,10 +135,33 @@ func PrivateKeyFromFileWithPassword(filename, password string) (interface{}, err
 }
 
 // PublicKeyFromFile reads a PEM-encoded file and returns the public key it
-// private key it contains. If the file does not contain a PEM-encoded public
-// key, an error is returned.
+// contains. A file containing a JSON Web Key is also accepted, in which case
+// the public key of any private key it contains is returned. If the file
+// does not contain a public key, an error is returned.
 func PublicKeyFromFile(filename string) (interface{}, error) {
-	var block, err = PEMBlockFromFile(filename)
+	var data, err = ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return PublicKeyFromBytes(data)
+}
+
+// PublicKeyFromBytes is the same as PublicKeyFromFile, but takes the
+// contents of the file rather than its name.
+func PublicKeyFromBytes(data []byte) (interface{}, error) {
+	if IsJWK(data) {
+		return PublicKeyFromJWK(data)
+	}
+
+	return PublicKeyFromPEM(data)
+}
+
+// PublicKeyFromPEM decodes PEM-encoded data and returns the public key it
+// contains. If the data is not a PEM-encoded public key, an error is
+// returned.
+func PublicKeyFromPEM(data []byte) (interface{}, error) {
+	var block, err = PEMBlockFromBytes(data)
 	if err != nil {
 		return nil, err
 	}
@@ -114,7 +181,19 @@ func PublicKeyFromFile(filename string) (interface{}, error) {
 // signing request it contains. If the file does not contain a PEM-encoded
 // PKCS#10 certificate signing request, an error is returned.
 func CSRFromFile(filename string) (*x509.CertificateRequest, error) {
-	var block, err = PEMBlockFromFile(filename)
+	var data, err = ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return CSRFromPEM(data)
+}
+
+// CSRFromPEM decodes PEM-encoded data and returns the PKCS#10 certificate
+// signing request it contains. If the data is not a PEM-encoded PKCS#10
+// certificate signing request, an error is returned.
+func CSRFromPEM(data []byte) (*x509.CertificateRequest, error) {
+	var block, err = PEMBlockFromBytes(data)
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +205,19 @@ func CSRFromFile(filename string) (*x509.CertificateRequest, error) {
 // it contains. If the file does not contain a PEM-encoded X509 certificate,
 // an error is returned.
 func CertFromFile(filename string) (*x509.Certificate, error) {
-	var block, err = PEMBlockFromFile(filename)
+	var data, err = ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return CertFromPEM(data)
+}
+
+// CertFromPEM decodes PEM-encoded data and returns the X509 certificate it
+// contains. If the data is not a PEM-encoded X509 certificate, an error is
+// returned.
+func CertFromPEM(data []byte) (*x509.Certificate, error) {
+	var block, err = PEMBlockFromBytes(data)
 	if err != nil {
 		return nil, err
 	}
@@ -134,6 +225,24 @@ func CertFromFile(filename string) (*x509.Certificate, error) {
 	return x509.ParseCertificate(block.Bytes)
 }
 
+// CertPoolFromFile reads a file containing one or more PEM-encoded X509
+// certificates and returns a certificate pool containing them. If the file
+// does not contain at least one PEM-encoded X509 certificate, an error is
+// returned.
+func CertPoolFromFile(filename string) (*x509.CertPool, error) {
+	var data, err = ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var pool = x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", filename)
+	}
+
+	return pool, nil
+}
+
 // CertToPEMString encodes a certificate to a PEM-encoded string.
 func CertToPEMString(cert *x509.Certificate) string {
 	return string(pem.EncodeToMemory(&pem.Block{
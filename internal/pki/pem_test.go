@@ -68,6 +68,8 @@ func TestPrivateKeyFromFileWithPassword(t *testing.T) {
 		{"testdata/rsa_priv_enc.key", "strongpassword", &rsa.PrivateKey{}},
 		{"testdata/ec_priv.key", "", &ecdsa.PrivateKey{}},
 		{"testdata/ec_priv_enc.key", "somesecret", &ecdsa.PrivateKey{}},
+		{"testdata/rsa_priv.jwk", "", &rsa.PrivateKey{}},
+		{"testdata/ec_priv.jwk", "", &ecdsa.PrivateKey{}},
 	}
 
 	for _, tc := range testcases {
@@ -99,6 +101,7 @@ func TestPrivateKeyFromFileWithPasswordBad(t *testing.T) {
 		{"testdata/rsa_priv_enc.key", "wrongpassword"},
 		{"testdata/ec_pub.key", ""},
 		{"testdata/ec_priv_enc.key", "wrongsecret"},
+		{"testdata/ec_pub.jwk", ""},
 	}
 
 	for _, tc := range testcases {
@@ -125,6 +128,9 @@ func TestPublicKeyFromFile(t *testing.T) {
 		{"testdata/rsa_pub.key", "", &rsa.PublicKey{}},
 		{"testdata/rsa_pub_pkcs1.key", "", &rsa.PublicKey{}},
 		{"testdata/ec_pub.key", "", &ecdsa.PublicKey{}},
+		{"testdata/ec_pub.jwk", "", &ecdsa.PublicKey{}},
+		{"testdata/ec_priv.jwk", "", &ecdsa.PublicKey{}},
+		{"testdata/rsa_priv.jwk", "", &rsa.PublicKey{}},
 	}
 
 	for _, tc := range testcases {
@@ -155,7 +161,7 @@ func TestPublicKeyFromFileBad(t *testing.T) {
 	}
 
 	for n, tc := range testcases {
-		var tc = tc
+		var n, tc = n, tc
 
 		t.Run(tc, func(t *testing.T) {
 			t.Parallel()
@@ -176,7 +182,7 @@ func TestCSRFromFile(t *testing.T) {
 	}
 
 	for n, tc := range testcases {
-		var tc = tc
+		var n, tc = n, tc
 
 		t.Run(tc, func(t *testing.T) {
 			t.Parallel()
@@ -197,7 +203,7 @@ func TestCSRFromFileBad(t *testing.T) {
 	}
 
 	for n, tc := range testcases {
-		var tc = tc
+		var n, tc = n, tc
 
 		t.Run(tc, func(t *testing.T) {
 			t.Parallel()
@@ -218,7 +224,7 @@ func TestCertFromFile(t *testing.T) {
 	}
 
 	for n, tc := range testcases {
-		var tc = tc
+		var n, tc = n, tc
 
 		t.Run(tc, func(t *testing.T) {
 			t.Parallel()
@@ -239,7 +245,7 @@ func TestCertFromFileBad(t *testing.T) {
 	}
 
 	for n, tc := range testcases {
-		var tc = tc
+		var n, tc = n, tc
 
 		t.Run(tc, func(t *testing.T) {
 			t.Parallel()
@@ -252,6 +258,49 @@ func TestCertFromFileBad(t *testing.T) {
 	}
 }
 
+func TestCertPoolFromFile(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []string{
+		"testdata/cert.pem",
+	}
+
+	for n, tc := range testcases {
+		var n, tc = n, tc
+
+		t.Run(tc, func(t *testing.T) {
+			t.Parallel()
+
+			var _, err = pki.CertPoolFromFile(tc)
+			if err != nil {
+				t.Fatalf("case %d, couldn't get cert pool from file: %v", n+1, err)
+			}
+		})
+	}
+}
+
+func TestCertPoolFromFileBad(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []string{
+		"testdata/no_such_file.cert",
+		"testdata/rsa_pub.key",
+	}
+
+	for n, tc := range testcases {
+		var n, tc = n, tc
+
+		t.Run(tc, func(t *testing.T) {
+			t.Parallel()
+
+			var _, err = pki.CertPoolFromFile(tc)
+			if err == nil {
+				t.Fatalf("case %d, unexpectedly got cert pool from file", n+1)
+			}
+		})
+	}
+}
+
 func TestCertToPEMString(t *testing.T) {
 	t.Parallel()
 
@@ -351,3 +400,28 @@ func TestPublicKeyToPEMString(t *testing.T) {
 		})
 	}
 }
+
+func TestPEMBlockFromBytesBad(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		data string
+	}{
+		{"Empty", ""},
+		{"NotPEM", "not PEM data"},
+		{"Extraneous", "-----BEGIN X-----\n-----END X-----\ntrailing"},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := pki.PEMBlockFromBytes([]byte(tc.data)); err == nil {
+				t.Fatalf("unexpectedly decoded PEM block")
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pki
+
+import (
+	"crypto"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"unicode/utf16"
+
+	"github.com/globalsign/hvclient/internal/oids"
+)
+
+// pkcs12Iterations is the iteration count used for both key encryption and
+// the integrity MAC. It matches the default used by OpenSSL.
+const pkcs12Iterations = 2048
+
+// pkcs12SaltLength is the length in bytes of the randomly-generated salts.
+const pkcs12SaltLength = 8
+
+// PKCS#12 key derivation IDs, from RFC 7292 Appendix B.3.
+const (
+	pkcs12KeyID byte = iota + 1
+	pkcs12IVID
+	pkcs12MACID
+)
+
+// asn1PKCS12Attribute is the ASN.1 structure of a PKCS#12 bag attribute.
+type asn1PKCS12Attribute struct {
+	ID     asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// asn1SafeBag is the ASN.1 structure of a PKCS#12 SafeBag.
+type asn1SafeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue
+	Attributes []asn1PKCS12Attribute `asn1:"set,optional"`
+}
+
+// asn1PBEParams is the ASN.1 structure of PKCS#12 password-based encryption
+// parameters.
+type asn1PBEParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+// asn1DigestInfo is the ASN.1 structure of a PKCS#12 MAC digest.
+type asn1DigestInfo struct {
+	Algorithm struct {
+		Algorithm  asn1.ObjectIdentifier
+		Parameters asn1.RawValue
+	}
+	Digest []byte
+}
+
+// BuildPKCS12 returns the DER encoding of a PKCS#12 file containing the
+// private key, the certificate and the chain of CA certificates. The private
+// key is encrypted and the file is protected by an integrity MAC, both using
+// the specified password, with pbeWithSHAAnd3-KeyTripleDES-CBC and
+// HMAC-SHA1 respectively for compatibility with the widest range of
+// software. The private key must be an *rsa.PrivateKey, an
+// *ecdsa.PrivateKey or an ed25519.PrivateKey, and must match the public key
+// in the certificate.
+func BuildPKCS12(key interface{}, cert *x509.Certificate, chain []*x509.Certificate, password string) ([]byte, error) {
+	if cert == nil {
+		return nil, errors.New("no certificate")
+	}
+
+	var signer, ok = key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type: %T", key)
+	}
+
+	var pub, isComparable = signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !isComparable || !pub.Equal(cert.PublicKey) {
+		return nil, errors.New("private key does not match certificate")
+	}
+
+	var pw = bmpStringPassword(password)
+
+	var keyID = sha1.Sum(cert.Raw)
+	var attrs, err = localKeyIDAttributes(keyID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the certificate bags, with the local key ID attribute linking
+	// the end-entity certificate to its private key.
+	var certBags []asn1SafeBag
+	for i, c := range append([]*x509.Certificate{cert}, chain...) {
+		var bag asn1SafeBag
+		if bag, err = certBag(c); err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			bag.Attributes = attrs
+		}
+
+		certBags = append(certBags, bag)
+	}
+
+	var keyBag asn1SafeBag
+	if keyBag, err = shroudedKeyBag(key, pw); err != nil {
+		return nil, err
+	}
+
+	keyBag.Attributes = attrs
+
+	// The authenticated safe contains the certificates and the private key
+	// in two separate unencrypted data contents. The private key is already
+	// encrypted within its bag.
+	var certContent, keyContent []byte
+	if certContent, err = dataContentInfo(certBags); err != nil {
+		return nil, err
+	}
+
+	if keyContent, err = dataContentInfo([]asn1SafeBag{keyBag}); err != nil {
+		return nil, err
+	}
+
+	var authSafe []byte
+	if authSafe, err = asn1.Marshal([]asn1.RawValue{{FullBytes: certContent}, {FullBytes: keyContent}}); err != nil {
+		return nil, err
+	}
+
+	var macData []byte
+	if macData, err = pkcs12MAC(authSafe, pw); err != nil {
+		return nil, err
+	}
+
+	var authSafeInfo []byte
+	if authSafeInfo, err = dataContentInfoBytes(authSafe); err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(struct {
+		Version  int
+		AuthSafe asn1.RawValue
+		MacData  asn1.RawValue
+	}{
+		Version:  3,
+		AuthSafe: asn1.RawValue{FullBytes: authSafeInfo},
+		MacData:  asn1.RawValue{FullBytes: macData},
+	})
+}
+
+// localKeyIDAttributes returns bag attributes containing only a local key ID
+// attribute with the specified value.
+func localKeyIDAttributes(id []byte) ([]asn1PKCS12Attribute, error) {
+	var value, err = asn1.Marshal(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return []asn1PKCS12Attribute{
+		{
+			ID:     oids.OIDPKCS12LocalKeyID,
+			Values: []asn1.RawValue{{FullBytes: value}},
+		},
+	}, nil
+}
+
+// certBag returns a PKCS#12 SafeBag containing the specified certificate.
+func certBag(cert *x509.Certificate) (asn1SafeBag, error) {
+	var value, err = asn1.Marshal(cert.Raw)
+	if err != nil {
+		return asn1SafeBag{}, err
+	}
+
+	var bag []byte
+	if bag, err = asn1.Marshal(struct {
+		ID    asn1.ObjectIdentifier
+		Value asn1.RawValue
+	}{
+		ID:    oids.OIDPKCS12X509Certificate,
+		Value: explicitTag0(value),
+	}); err != nil {
+		return asn1SafeBag{}, err
+	}
+
+	return asn1SafeBag{
+		ID:    oids.OIDPKCS12CertBag,
+		Value: explicitTag0(bag),
+	}, nil
+}
+
+// shroudedKeyBag returns a PKCS#12 SafeBag containing the specified private
+// key, encrypted with the specified BMPString-encoded password.
+func shroudedKeyBag(key interface{}, pw []byte) (asn1SafeBag, error) {
+	var der, err = x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return asn1SafeBag{}, err
+	}
+
+	var params = asn1PBEParams{
+		Salt:       make([]byte, pkcs12SaltLength),
+		Iterations: pkcs12Iterations,
+	}
+	if _, err = rand.Read(params.Salt); err != nil {
+		return asn1SafeBag{}, err
+	}
+
+	var block cipher.Block
+	if block, err = des.NewTripleDESCipher(pkcs12KDF(pw, params.Salt, pkcs12KeyID, params.Iterations, 24)); err != nil {
+		return asn1SafeBag{}, err
+	}
+
+	// Apply PKCS#7 padding and encrypt.
+	var padding = block.BlockSize() - len(der)%block.BlockSize()
+	for i := 0; i < padding; i++ {
+		der = append(der, byte(padding))
+	}
+
+	var iv = pkcs12KDF(pw, params.Salt, pkcs12IVID, params.Iterations, block.BlockSize())
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(der, der)
+
+	var encoded []byte
+	if encoded, err = asn1.Marshal(struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1PBEParams
+		}
+		EncryptedData []byte
+	}{
+		Algorithm: struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1PBEParams
+		}{oids.OIDPKCS12PBEWithSHA3DESCBC, params},
+		EncryptedData: der,
+	}); err != nil {
+		return asn1SafeBag{}, err
+	}
+
+	return asn1SafeBag{
+		ID:    oids.OIDPKCS12KeyBag,
+		Value: explicitTag0(encoded),
+	}, nil
+}
+
+// dataContentInfo returns the DER encoding of a PKCS#7 data ContentInfo
+// containing the specified SafeContents.
+func dataContentInfo(bags []asn1SafeBag) ([]byte, error) {
+	var contents, err = asn1.Marshal(bags)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataContentInfoBytes(contents)
+}
+
+// dataContentInfoBytes returns the DER encoding of a PKCS#7 data ContentInfo
+// containing the specified bytes.
+func dataContentInfoBytes(data []byte) ([]byte, error) {
+	var octets, err = asn1.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{
+		ContentType: oids.OIDContentTypeData,
+		Content:     explicitTag0(octets),
+	})
+}
+
+// pkcs12MAC returns the DER encoding of the PKCS#12 MacData for the
+// specified authenticated safe and BMPString-encoded password.
+func pkcs12MAC(authSafe, pw []byte) ([]byte, error) {
+	var salt = make([]byte, pkcs12SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	var mac = hmac.New(sha1.New, pkcs12KDF(pw, salt, pkcs12MACID, pkcs12Iterations, sha1.Size))
+	mac.Write(authSafe)
+
+	var digest asn1DigestInfo
+	digest.Algorithm.Algorithm = oids.OIDHashSHA1
+	digest.Algorithm.Parameters = asn1.NullRawValue
+	digest.Digest = mac.Sum(nil)
+
+	return asn1.Marshal(struct {
+		Mac        asn1DigestInfo
+		MacSalt    []byte
+		Iterations int
+	}{
+		Mac:        digest,
+		MacSalt:    salt,
+		Iterations: pkcs12Iterations,
+	})
+}
+
+// explicitTag0 returns a raw value which encodes the specified DER with an
+// explicit context-specific tag of 0.
+func explicitTag0(der []byte) asn1.RawValue {
+	return asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        0,
+		IsCompound: true,
+		Bytes:      der,
+	}
+}
+
+// bmpStringPassword returns the password encoded as a null-terminated
+// BMPString, as required by the PKCS#12 key derivation function.
+func bmpStringPassword(password string) []byte {
+	var pw []byte
+	for _, r := range utf16.Encode([]rune(password)) {
+		pw = append(pw, byte(r>>8), byte(r))
+	}
+
+	return append(pw, 0, 0)
+}
+
+// pkcs12KDF derives size bytes of keying material of the specified ID type
+// from a BMPString-encoded password and salt, using the SHA-1 based key
+// derivation function in RFC 7292 Appendix B.2.
+func pkcs12KDF(pw, salt []byte, id byte, iterations, size int) []byte {
+	const u = sha1.Size
+	const v = 64
+
+	// Concatenate copies of the salt and the password, each extended to a
+	// multiple of v bytes.
+	var extend = func(b []byte) []byte {
+		var out = make([]byte, v*((len(b)+v-1)/v))
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+
+		return out
+	}
+
+	var d = make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+
+	var ii = append(extend(salt), extend(pw)...)
+	var one = big.NewInt(1)
+	var out []byte
+
+	for len(out) < size {
+		var h = sha1.New()
+		h.Write(d)
+		h.Write(ii)
+		var a = h.Sum(nil)
+
+		for j := 1; j < iterations; j++ {
+			var sum = sha1.Sum(a)
+			a = sum[:]
+		}
+
+		out = append(out, a...)
+
+		if len(out) >= size {
+			break
+		}
+
+		// Treat each v-byte block of I as an integer, and replace it with
+		// (block + B + 1) mod 2^(8v), where B is A extended to v bytes.
+		var b = new(big.Int).SetBytes(extend(a[:u]))
+		b.Add(b, one)
+
+		for j := 0; j < len(ii); j += v {
+			var block = new(big.Int).SetBytes(ii[j : j+v])
+			block.Add(block, b)
+
+			var sum = block.Bytes()
+			if len(sum) > v {
+				sum = sum[len(sum)-v:]
+			}
+
+			var dst = ii[j : j+v]
+			for k := range dst {
+				dst[k] = 0
+			}
+			copy(dst[v-len(sum):], sum)
+		}
+	}
+
+	return out[:size]
+}
@@ -0,0 +1,157 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pki_test
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"golang.org/x/crypto/pkcs12"
+
+	"github.com/globalsign/hvclient/internal/pki"
+	"github.com/globalsign/hvclient/internal/testhelpers"
+)
+
+func TestBuildPKCS12(t *testing.T) {
+	t.Parallel()
+
+	var key, err = pki.PrivateKeyFromFileWithPassword("testdata/rsa_priv.key", "")
+	if err != nil {
+		t.Fatalf("couldn't read private key: %v", err)
+	}
+
+	var cert = testhelpers.MustGetCertFromFile(t, "testdata/cert.pem")
+
+	var testcases = []struct {
+		name     string
+		chain    []*x509.Certificate
+		password string
+	}{
+		{
+			name:     "NoChain",
+			password: "secret",
+		},
+		{
+			name:     "Chain",
+			chain:    []*x509.Certificate{cert, cert},
+			password: "pässwörd",
+		},
+		{
+			name: "EmptyPassword",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var der, err = pki.BuildPKCS12(key, cert, tc.chain, tc.password)
+			if err != nil {
+				t.Fatalf("couldn't build PKCS#12: %v", err)
+			}
+
+			var blocks []*pem.Block
+			if blocks, err = pkcs12.ToPEM(der, tc.password); err != nil {
+				t.Fatalf("couldn't decode PKCS#12: %v", err)
+			}
+
+			var certs []*x509.Certificate
+			var gotKey *rsa.PrivateKey
+
+			for _, block := range blocks {
+				switch block.Type {
+				case "CERTIFICATE":
+					var c *x509.Certificate
+					if c, err = x509.ParseCertificate(block.Bytes); err != nil {
+						t.Fatalf("couldn't parse certificate: %v", err)
+					}
+
+					certs = append(certs, c)
+
+				case "PRIVATE KEY":
+					if gotKey, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+						t.Fatalf("couldn't parse private key: %v", err)
+					}
+				}
+			}
+
+			if want := len(tc.chain) + 1; len(certs) != want {
+				t.Fatalf("got %d certificates, want %d", len(certs), want)
+			}
+
+			for _, c := range certs {
+				if !c.Equal(cert) {
+					t.Errorf("certificate does not match")
+				}
+			}
+
+			if gotKey == nil {
+				t.Fatalf("no private key found")
+			}
+
+			if !key.(*rsa.PrivateKey).Equal(gotKey) {
+				t.Errorf("private key does not match")
+			}
+
+			if _, err = pkcs12.ToPEM(der, tc.password+"wrong"); err == nil {
+				t.Errorf("unexpectedly decoded PKCS#12 with wrong password")
+			}
+		})
+	}
+}
+
+func TestBuildPKCS12Failure(t *testing.T) {
+	t.Parallel()
+
+	var rsaKey, err = pki.PrivateKeyFromFileWithPassword("testdata/rsa_priv.key", "")
+	if err != nil {
+		t.Fatalf("couldn't read private key: %v", err)
+	}
+
+	var ecKey interface{}
+	if ecKey, err = pki.PrivateKeyFromFileWithPassword("testdata/ec_priv.key", ""); err != nil {
+		t.Fatalf("couldn't read private key: %v", err)
+	}
+
+	var cert = testhelpers.MustGetCertFromFile(t, "testdata/cert.pem")
+
+	var testcases = []struct {
+		name string
+		key  interface{}
+		cert *x509.Certificate
+	}{
+		{"NoCertificate", rsaKey, nil},
+		{"NoKey", nil, cert},
+		{"BadKeyType", "not a key", cert},
+		{"KeyMismatch", ecKey, cert},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := pki.BuildPKCS12(tc.key, tc.cert, nil, "secret"); err == nil {
+				t.Fatalf("unexpectedly built PKCS#12")
+			}
+		})
+	}
+}
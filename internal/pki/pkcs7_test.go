@@ -0,0 +1,84 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pki_test
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/globalsign/hvclient/internal/oids"
+	"github.com/globalsign/hvclient/internal/pki"
+	"github.com/globalsign/hvclient/internal/testhelpers"
+)
+
+func TestCertsToPKCS7(t *testing.T) {
+	t.Parallel()
+
+	var cert = testhelpers.MustGetCertFromFile(t, "testdata/cert.pem")
+	var certs = []*x509.Certificate{cert, cert}
+
+	var der, err = pki.CertsToPKCS7(certs)
+	if err != nil {
+		t.Fatalf("couldn't encode PKCS#7: %v", err)
+	}
+
+	var info struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) > 0 {
+		t.Fatalf("couldn't decode content info: %v", err)
+	}
+
+	if !info.ContentType.Equal(oids.OIDContentTypeSignedData) {
+		t.Fatalf("got content type %v, want %v", info.ContentType, oids.OIDContentTypeSignedData)
+	}
+
+	var sd struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      struct{ ContentType asn1.ObjectIdentifier }
+		Certificates     asn1.RawValue
+		SignerInfos      asn1.RawValue
+	}
+	if rest, err := asn1.Unmarshal(info.Content.Bytes, &sd); err != nil || len(rest) > 0 {
+		t.Fatalf("couldn't decode signed data: %v", err)
+	}
+
+	var got []*x509.Certificate
+	if got, err = x509.ParseCertificates(sd.Certificates.Bytes); err != nil {
+		t.Fatalf("couldn't parse certificates: %v", err)
+	}
+
+	if len(got) != len(certs) {
+		t.Fatalf("got %d certificates, want %d", len(got), len(certs))
+	}
+
+	for i := range got {
+		if !got[i].Equal(certs[i]) {
+			t.Errorf("certificate %d does not match", i)
+		}
+	}
+}
+
+func TestCertsToPKCS7Empty(t *testing.T) {
+	t.Parallel()
+
+	if _, err := pki.CertsToPKCS7(nil); err == nil {
+		t.Fatalf("unexpectedly encoded empty PKCS#7")
+	}
+}
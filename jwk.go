@@ -0,0 +1,65 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"crypto"
+
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+// JWK is a JSON Web Key, as described in RFC 7517, containing an RSA or
+// elliptic curve key, such as a key exported from a cloud key management
+// service. A JWK may be assigned to the PublicKey or PrivateKey field of a
+// Request, and is converted to the corresponding RSA or ECDSA key when the
+// request is encoded. A JWK assigned to the PublicKey field may contain a
+// private key, in which case only its public key is used. A JWK assigned to
+// the PrivateKey field must contain a private key, and an RSA private key
+// must include its prime factors.
+type JWK []byte
+
+// PublicKey returns the RSA or ECDSA public key in the JWK, or the public
+// key of the private key it contains.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	return pki.PublicKeyFromJWK(k)
+}
+
+// PrivateKey returns the RSA or ECDSA private key in the JWK, or an error if
+// it does not contain a private key.
+func (k JWK) PrivateKey() (crypto.PrivateKey, error) {
+	return pki.PrivateKeyFromJWK(k)
+}
+
+// requestKeys returns the public and private keys in a request, converting
+// any JWKs to the corresponding RSA or ECDSA keys.
+func (r Request) requestKeys() (interface{}, interface{}, error) {
+	var pub, priv = r.PublicKey, r.PrivateKey
+	var err error
+
+	if k, ok := pub.(JWK); ok {
+		if pub, err = k.PublicKey(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if k, ok := priv.(JWK); ok {
+		if priv, err = k.PrivateKey(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return pub, priv, nil
+}
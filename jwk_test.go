@@ -0,0 +1,123 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/testhelpers"
+)
+
+// ecJWK returns the JWK encoding of an ECDSA key, including the private key
+// if requested.
+func ecJWK(key *ecdsa.PrivateKey, private bool) hvclient.JWK {
+	var size = (key.Curve.Params().BitSize + 7) / 8
+	var encode = func(b []byte) string {
+		return base64.RawURLEncoding.EncodeToString(append(make([]byte, size-len(b)), b...))
+	}
+
+	var d string
+	if private {
+		d = fmt.Sprintf(`,"d":%q`, encode(key.D.Bytes()))
+	}
+
+	return hvclient.JWK(fmt.Sprintf(`{"kty":"EC","crv":%q,"x":%q,"y":%q%s}`,
+		key.Curve.Params().Name, encode(key.X.Bytes()), encode(key.Y.Bytes()), d))
+}
+
+func TestRequestJWK(t *testing.T) {
+	t.Parallel()
+
+	var key = testhelpers.MustParseECPrivateKey(t, testRequestECPrivateKeyPEM)
+
+	var want, err = json.Marshal(hvclient.Request{PublicKey: &key.PublicKey})
+	if err != nil {
+		t.Fatalf("couldn't marshal request: %v", err)
+	}
+
+	for _, jwk := range []hvclient.JWK{ecJWK(key, false), ecJWK(key, true)} {
+		var got []byte
+		if got, err = json.Marshal(hvclient.Request{PublicKey: jwk}); err != nil {
+			t.Fatalf("couldn't marshal request: %v", err)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+
+	// A private key JWK is used to sign the public key.
+	var req = hvclient.Request{PrivateKey: ecJWK(key, true)}
+	var data []byte
+	if data, err = json.Marshal(req); err != nil {
+		t.Fatalf("couldn't marshal request: %v", err)
+	}
+
+	var decoded struct {
+		PublicKey          string `json:"public_key"`
+		PublicKeySignature string `json:"public_key_signature"`
+	}
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("couldn't unmarshal request: %v", err)
+	}
+
+	var wantKey struct {
+		PublicKey string `json:"public_key"`
+	}
+	if err = json.Unmarshal(want, &wantKey); err != nil {
+		t.Fatalf("couldn't unmarshal request: %v", err)
+	}
+
+	if decoded.PublicKey != wantKey.PublicKey || decoded.PublicKeySignature == "" {
+		t.Errorf("got public key %q and signature %q, want public key %q and a signature",
+			decoded.PublicKey, decoded.PublicKeySignature, wantKey.PublicKey)
+	}
+
+	// The signature must verify against the public key.
+	req = hvclient.Request{PublicKey: &key.PublicKey, PublicKeySignature: decoded.PublicKeySignature}
+	if _, err = json.Marshal(req); err != nil {
+		t.Errorf("signature from JWK private key did not verify: %v", err)
+	}
+
+	if _, err = (&hvclient.Request{PrivateKey: ecJWK(key, true)}).PKCS10(); err != nil {
+		t.Errorf("couldn't create PKCS#10 request with JWK private key: %v", err)
+	}
+}
+
+func TestRequestJWKFailure(t *testing.T) {
+	t.Parallel()
+
+	var key = testhelpers.MustParseECPrivateKey(t, testRequestECPrivateKeyPEM)
+
+	for _, req := range []hvclient.Request{
+		{PublicKey: hvclient.JWK(`{"kty":"oct","k":"AQ"}`)},
+		{PrivateKey: ecJWK(key, false)},
+	} {
+		if data, err := json.Marshal(req); err == nil {
+			t.Errorf("unexpectedly marshalled request: %s", data)
+		}
+	}
+
+	if _, err := hvclient.PublicKeySignatureDigest(hvclient.JWK(`{}`)); err == nil {
+		t.Errorf("unexpectedly computed digest for invalid JWK")
+	}
+}
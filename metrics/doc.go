@@ -0,0 +1,28 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package metrics collects metrics from HVCA clients and certificate renewals,
+and serves them in the Prometheus text exposition format, so that
+long-running services built on hvclient can be monitored with standard
+tooling.
+
+A Collector counts HVCA API calls and errors by method and endpoint when set
+as the Observer in a client configuration, counts the renewals recorded with
+its RecordRenewal method, and reports the number of certificates managed and
+the remaining issuance quota when they are set. It serves the metrics as an
+http.Handler, usually at a /metrics endpoint.
+*/
+package metrics
@@ -0,0 +1,208 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/globalsign/hvclient"
+)
+
+// contentType is the media type of the Prometheus text exposition format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// QuotaSource retrieves the remaining issuance quota. It is satisfied by
+// *hvclient.Client.
+type QuotaSource interface {
+	QuotaIssuance(ctx context.Context) (int64, error)
+}
+
+// Collector collects metrics. It implements hvclient.Observer, so it may be
+// set as the Observer in a client configuration to count HVCA API calls,
+// and http.Handler, so it may be served at a /metrics endpoint. A Collector
+// is safe for concurrent use, and the zero value is ready to use.
+type Collector struct {
+	mtx       sync.Mutex
+	calls     map[callKey]uint64
+	errors    map[callKey]uint64
+	succeeded uint64
+	failed    uint64
+	managed   *int
+	quota     *int64
+}
+
+// callKey identifies a counter of HVCA API calls. The status code is zero
+// for error counters.
+type callKey struct {
+	method   string
+	endpoint string
+	code     int
+}
+
+// New returns a new collector.
+func New() *Collector {
+	return &Collector{}
+}
+
+// ObserveCall counts a completed HVCA API call by method, endpoint and
+// status code, and counts it as an error if it failed.
+func (c *Collector) ObserveCall(info hvclient.CallInfo) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.calls == nil {
+		c.calls = make(map[callKey]uint64)
+		c.errors = make(map[callKey]uint64)
+	}
+
+	c.calls[callKey{method: info.Method, endpoint: info.Endpoint, code: info.StatusCode}]++
+
+	if info.Err != nil {
+		c.errors[callKey{method: info.Method, endpoint: info.Endpoint}]++
+	}
+}
+
+// RecordRenewal counts a certificate renewal as succeeded or failed,
+// depending on whether err is nil.
+func (c *Collector) RecordRenewal(err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if err != nil {
+		c.failed++
+	} else {
+		c.succeeded++
+	}
+}
+
+// SetManaged sets the number of certificates currently managed, for example
+// the number being watched for renewal.
+func (c *Collector) SetManaged(n int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.managed = &n
+}
+
+// SetQuota sets the remaining issuance quota.
+func (c *Collector) SetQuota(n int64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.quota = &n
+}
+
+// UpdateQuota retrieves the remaining issuance quota and sets it. The
+// previous value is kept if it cannot be retrieved.
+func (c *Collector) UpdateQuota(ctx context.Context, src QuotaSource) error {
+	var n, err = src.QuotaIssuance(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't retrieve issuance quota: %w", err)
+	}
+
+	c.SetQuota(n)
+
+	return nil
+}
+
+// ServeHTTP writes the collected metrics in the Prometheus text exposition
+// format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	_ = c.Write(w)
+}
+
+// Write writes the collected metrics to w in the Prometheus text
+// exposition format. Metrics which have not been set, such as the quota if
+// it has never been retrieved, are omitted.
+func (c *Collector) Write(w io.Writer) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var p = printer{w: w}
+
+	p.header("hvclient_api_calls_total", "counter", "HVCA API calls by method, endpoint and status code, which is 0 if no response was received.")
+	for _, key := range sortedKeys(c.calls) {
+		p.printf("hvclient_api_calls_total{method=%q,endpoint=%q,code=\"%d\"} %d\n", key.method, key.endpoint, key.code, c.calls[key])
+	}
+
+	p.header("hvclient_api_errors_total", "counter", "HVCA API calls which returned an error, by method and endpoint.")
+	for _, key := range sortedKeys(c.errors) {
+		p.printf("hvclient_api_errors_total{method=%q,endpoint=%q} %d\n", key.method, key.endpoint, c.errors[key])
+	}
+
+	p.header("hvclient_renewals_total", "counter", "Certificate renewals by result.")
+	p.printf("hvclient_renewals_total{result=\"succeeded\"} %d\n", c.succeeded)
+	p.printf("hvclient_renewals_total{result=\"failed\"} %d\n", c.failed)
+
+	if c.managed != nil {
+		p.header("hvclient_certificates_managed", "gauge", "Certificates currently managed.")
+		p.printf("hvclient_certificates_managed %d\n", *c.managed)
+	}
+
+	if c.quota != nil {
+		p.header("hvclient_issuance_quota_remaining", "gauge", "Remaining HVCA certificate issuance quota.")
+		p.printf("hvclient_issuance_quota_remaining %d\n", *c.quota)
+	}
+
+	return p.err
+}
+
+// sortedKeys returns the keys of a map of counters, sorted by method, then
+// endpoint, then status code.
+func sortedKeys(counters map[callKey]uint64) []callKey {
+	var keys = make([]callKey, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		switch {
+		case keys[i].method != keys[j].method:
+			return keys[i].method < keys[j].method
+
+		case keys[i].endpoint != keys[j].endpoint:
+			return keys[i].endpoint < keys[j].endpoint
+		}
+
+		return keys[i].code < keys[j].code
+	})
+
+	return keys
+}
+
+// printer writes formatted output, recording the first error encountered.
+type printer struct {
+	w   io.Writer
+	err error
+}
+
+// header writes the HELP and TYPE lines for a metric.
+func (p *printer) header(name, kind, help string) {
+	p.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// printf writes formatted output, unless an error has already occurred.
+func (p *printer) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/metrics"
+)
+
+// fakeQuota is a QuotaSource which returns a fixed quota or error.
+type fakeQuota struct {
+	quota int64
+	err   error
+}
+
+func (q fakeQuota) QuotaIssuance(context.Context) (int64, error) {
+	return q.quota, q.err
+}
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	var c = metrics.New()
+
+	c.ObserveCall(hvclient.CallInfo{Method: http.MethodGet, Endpoint: "/certificates/{id}", StatusCode: http.StatusOK})
+	c.ObserveCall(hvclient.CallInfo{Method: http.MethodGet, Endpoint: "/certificates/{id}", StatusCode: http.StatusOK})
+	c.ObserveCall(hvclient.CallInfo{Method: http.MethodGet, Endpoint: "/quotas/issuance", StatusCode: http.StatusOK})
+	c.ObserveCall(hvclient.CallInfo{Method: http.MethodPost, Endpoint: "/certificates", StatusCode: http.StatusNotFound, Err: errors.New("not found")})
+	c.ObserveCall(hvclient.CallInfo{Method: http.MethodPost, Endpoint: "/certificates", Err: errors.New("connection refused")})
+	c.ObserveCall(hvclient.CallInfo{Method: http.MethodPost, Endpoint: "/claims/domains/{id}/dns", StatusCode: http.StatusUnprocessableEntity, Err: errors.New("invalid")})
+
+	for _, err := range []error{nil, nil, errors.New("renewal failed")} {
+		c.RecordRenewal(err)
+	}
+
+	c.SetManaged(3)
+
+	if err := c.UpdateQuota(context.Background(), fakeQuota{quota: 999}); err != nil {
+		t.Fatalf("couldn't update quota: %v", err)
+	}
+
+	// A failed update should keep the previous quota.
+	if err := c.UpdateQuota(context.Background(), fakeQuota{err: errors.New("unavailable")}); err == nil {
+		t.Fatalf("unexpectedly updated quota")
+	}
+
+	var rec = httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("got content type %q", got)
+	}
+
+	var body = rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE hvclient_api_calls_total counter\n",
+		`hvclient_api_calls_total{method="GET",endpoint="/certificates/{id}",code="200"} 2` + "\n",
+		`hvclient_api_calls_total{method="GET",endpoint="/quotas/issuance",code="200"} 1` + "\n",
+		`hvclient_api_calls_total{method="POST",endpoint="/certificates",code="0"} 1` + "\n",
+		`hvclient_api_calls_total{method="POST",endpoint="/certificates",code="404"} 1` + "\n",
+		`hvclient_api_errors_total{method="POST",endpoint="/certificates"} 2` + "\n",
+		`hvclient_api_errors_total{method="POST",endpoint="/claims/domains/{id}/dns"} 1` + "\n",
+		`hvclient_renewals_total{result="succeeded"} 2` + "\n",
+		`hvclient_renewals_total{result="failed"} 1` + "\n",
+		"hvclient_certificates_managed 3\n",
+		"# TYPE hvclient_issuance_quota_remaining gauge\n",
+		"hvclient_issuance_quota_remaining 999\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, body)
+		}
+	}
+
+	if strings.Contains(body, `hvclient_api_errors_total{method="GET"`) {
+		t.Errorf("metrics unexpectedly contain errors for successful calls:\n%s", body)
+	}
+}
+
+func TestCollectorUnset(t *testing.T) {
+	t.Parallel()
+
+	var c metrics.Collector
+
+	var buf strings.Builder
+	if err := c.Write(&buf); err != nil {
+		t.Fatalf("couldn't write metrics: %v", err)
+	}
+
+	for _, unwanted := range []string{"hvclient_certificates_managed", "hvclient_issuance_quota_remaining"} {
+		if strings.Contains(buf.String(), unwanted) {
+			t.Errorf("metrics unexpectedly contain %s:\n%s", unwanted, buf.String())
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"strings"
+	"time"
+)
+
+// Observer receives a notification after each HVCA API call completes, so
+// that long-running services can export metrics such as call and error
+// rates. ObserveCall may be called concurrently, so implementations must be
+// safe for concurrent use, and should return quickly.
+type Observer interface {
+	ObserveCall(info CallInfo)
+}
+
+// ObserverFunc is an adapter which allows an ordinary function to be used
+// as an Observer.
+type ObserverFunc func(info CallInfo)
+
+// ObserveCall calls f(info).
+func (f ObserverFunc) ObserveCall(info CallInfo) {
+	f(info)
+}
+
+// CallInfo describes a completed HVCA API call.
+type CallInfo struct {
+	// Method is the HTTP method of the call.
+	Method string
+
+	// Path is the path of the API endpoint, which may contain certificate
+	// serial numbers and domain claim IDs, excluding any query string.
+	Path string
+
+	// Endpoint is Path with each certificate serial number, domain claim ID
+	// and other variable segment replaced with "{id}", for example
+	// "/certificates/{id}", so that calls may be grouped by endpoint.
+	Endpoint string
+
+	// StatusCode is the HTTP status code of the final response, or zero if
+	// no response was received.
+	StatusCode int
+
+	// Duration is the time taken by the call, including any retries and
+	// any login made on its behalf.
+	Duration time.Duration
+
+	// Err is the error returned by the call, if any.
+	Err error
+}
+
+// endpointTemplate returns the path of an API endpoint with its variable
+// segments replaced with "{id}". Since the fixed segments of HVCA endpoint
+// paths consist only of lower case letters, any other segment is taken to
+// be variable.
+func endpointTemplate(path string) string {
+	var segments = strings.Split(path, "/")
+
+	for i, segment := range segments {
+		if strings.TrimLeft(segment, "abcdefghijklmnopqrstuvwxyz") != "" {
+			segments[i] = "{id}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,92 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+)
+
+func TestClientObserver(t *testing.T) {
+	t.Parallel()
+
+	var mtx sync.Mutex
+	var calls []hvclient.CallInfo
+
+	var client, closefunc = newMockClientWithConfig(t, func(conf *hvclient.Config) {
+		conf.Observer = hvclient.ObserverFunc(func(info hvclient.CallInfo) {
+			mtx.Lock()
+			calls = append(calls, info)
+			mtx.Unlock()
+		})
+	})
+	defer closefunc()
+
+	var ctx = context.Background()
+
+	if _, err := client.CertificateRetrieve(ctx, mockCert.SerialNumber); err != nil {
+		t.Fatalf("couldn't retrieve certificate: %v", err)
+	}
+
+	if _, err := client.CertificateRetrieve(ctx, mockBigIntNotFound); err == nil {
+		t.Fatalf("unexpectedly retrieved nonexistent certificate")
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	// Ignore the login made when the client was created.
+	if len(calls) > 0 && strings.HasPrefix(calls[0].Path, "/login") {
+		calls = calls[1:]
+	}
+
+	if len(calls) != 2 {
+		t.Fatalf("got %d observed calls, want 2", len(calls))
+	}
+
+	for i, want := range []struct {
+		status int
+		err    bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusNotFound, true},
+	} {
+		if calls[i].Method != http.MethodGet {
+			t.Errorf("call %d: got method %s, want %s", i, calls[i].Method, http.MethodGet)
+		}
+
+		if calls[i].Endpoint != "/certificates/{id}" {
+			t.Errorf("call %d: got endpoint %s, want /certificates/{id}", i, calls[i].Endpoint)
+		}
+
+		if calls[i].StatusCode != want.status {
+			t.Errorf("call %d: got status code %d, want %d", i, calls[i].StatusCode, want.status)
+		}
+
+		if (calls[i].Err != nil) != want.err {
+			t.Errorf("call %d: got error %v, want error %t", i, calls[i].Err, want.err)
+		}
+
+		if calls[i].Duration < 0 {
+			t.Errorf("call %d: got negative duration %v", i, calls[i].Duration)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/globalsign/hvclient/internal/oids"
+)
+
+// ParseOID parses an ASN.1 object identifier (OID) in dotted-decimal form,
+// such as "2.5.4.4", for use in the OID fields of requests. An error is
+// returned if the OID has fewer than two components, or if its first two
+// components are not valid, since such an OID cannot be DER-encoded.
+func ParseOID(s string) (asn1.ObjectIdentifier, error) {
+	var oid, err = oids.StringToOID(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OID %q: %w", s, err)
+	}
+
+	switch {
+	case len(oid) < 2:
+		return nil, fmt.Errorf("invalid OID %q: must have at least two components", s)
+
+	case oid[0] > 2 || (oid[0] < 2 && oid[1] >= 40):
+		return nil, fmt.Errorf("invalid OID %q: invalid first two components", s)
+	}
+
+	return oid, nil
+}
+
+// MustOID is like ParseOID, but panics if the OID cannot be parsed. It
+// simplifies the construction of requests and the initialization of
+// variables holding OIDs which are known to be valid, such as
+// hvclient.OIDAndString{OID: hvclient.MustOID("2.5.4.4"), Value: "Doe"}.
+func MustOID(s string) asn1.ObjectIdentifier {
+	var oid, err = ParseOID(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return oid
+}
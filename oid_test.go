@@ -0,0 +1,82 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"encoding/asn1"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+)
+
+func TestParseOID(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		value string
+		want  asn1.ObjectIdentifier
+	}{
+		{"2.5.4.4", asn1.ObjectIdentifier{2, 5, 4, 4}},
+		{"1.3.6.1.4.1.311.20.2.3", asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 311, 20, 2, 3}},
+		{" 0.4.0.194121.1.1 ", asn1.ObjectIdentifier{0, 4, 0, 194121, 1, 1}},
+		{"2.999.1", asn1.ObjectIdentifier{2, 999, 1}},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.value, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = hvclient.ParseOID(tc.value)
+			if err != nil {
+				t.Fatalf("couldn't parse OID: %v", err)
+			}
+
+			if !got.Equal(tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+
+			if got = hvclient.MustOID(tc.value); !got.Equal(tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseOIDFailure(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"", "2", "3.1", "1.40", "2.5.-4", "2.5..4", "2.5.4.x", "oid"} {
+		var s = s
+
+		t.Run(s, func(t *testing.T) {
+			t.Parallel()
+
+			if got, err := hvclient.ParseOID(s); err == nil {
+				t.Fatalf("unexpectedly parsed OID: %v", got)
+			}
+
+			defer func() {
+				if recover() == nil {
+					t.Errorf("MustOID unexpectedly did not panic")
+				}
+			}()
+
+			hvclient.MustOID(s)
+		})
+	}
+}
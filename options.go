@@ -0,0 +1,223 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Option is a functional option for configuring a client created with New.
+type Option func(*clientOptions) error
+
+// clientOptions holds the settings accumulated from functional options.
+type clientOptions struct {
+	config        Config
+	httpClient    *http.Client
+	wrapTransport func(http.RoundTripper) http.RoundTripper
+}
+
+// New creates a new HVCA client configured by functional options, as an
+// alternative to populating a Config object and calling NewClient. At least
+// WithURL and WithAPICredentials must be provided. Any setting not specified
+// takes the same default as the corresponding field of a Config object. An
+// initial login is made, and the returned client is immediately ready to
+// make API calls.
+func New(ctx context.Context, opts ...Option) (*Client, error) {
+	var o clientOptions
+
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := o.config.Validate(); err != nil {
+		return nil, err
+	}
+
+	var httpClient, ownsHTTP = o.buildHTTPClient()
+
+	return newClient(ctx, &o.config, httpClient, ownsHTTP)
+}
+
+// buildHTTPClient returns the HTTP client specified by the options, with
+// any transport wrapper applied, or nil if an HTTP client should be built
+// from the connection settings in the validated configuration. It also
+// returns true if the HTTP client is owned by the HVCA client, rather than
+// supplied with WithHTTPClient.
+func (o *clientOptions) buildHTTPClient() (*http.Client, bool) {
+	if o.wrapTransport == nil {
+		return o.httpClient, o.httpClient == nil
+	}
+
+	var httpClient = &http.Client{}
+	var tnspt http.RoundTripper
+
+	if o.httpClient != nil {
+		*httpClient = *o.httpClient
+		tnspt = httpClient.Transport
+	} else {
+		tnspt = newTransport(&o.config)
+	}
+
+	if tnspt == nil {
+		tnspt = http.DefaultTransport
+	}
+
+	httpClient.Transport = o.wrapTransport(tnspt)
+
+	return httpClient, o.httpClient == nil
+}
+
+// WithConfig uses a copy of an existing configuration object as the starting
+// point for the client configuration. Options following it override the
+// corresponding settings.
+func WithConfig(conf *Config) Option {
+	return func(o *clientOptions) error {
+		if conf == nil {
+			return errors.New("no configuration provided")
+		}
+
+		o.config = *conf
+
+		return nil
+	}
+}
+
+// WithURL sets the URL of the HVCA service, including any version number.
+func WithURL(url string) Option {
+	return func(o *clientOptions) error {
+		o.config.URL = url
+		return nil
+	}
+}
+
+// WithAPICredentials sets the API key and API secret for the HVCA account.
+func WithAPICredentials(key, secret string) Option {
+	return func(o *clientOptions) error {
+		o.config.APIKey = key
+		o.config.APISecret = secret
+		return nil
+	}
+}
+
+// WithTLSKeyPair sets the certificate and private key used for mutual TLS
+// authentication to HVCA.
+func WithTLSKeyPair(cert *x509.Certificate, key interface{}) Option {
+	return func(o *clientOptions) error {
+		if cert == nil || key == nil {
+			return errors.New("both an mTLS certificate and an mTLS private key must be provided")
+		}
+
+		o.config.TLSCert = cert
+		o.config.TLSKey = key
+
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to make requests to HVCA. When
+// this option is used, the client's transport is used as-is, and the mutual
+// TLS, TLS roots, proxy and connection settings in the configuration are
+// ignored.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *clientOptions) error {
+		if client == nil {
+			return errors.New("no HTTP client provided")
+		}
+
+		o.httpClient = client
+
+		return nil
+	}
+}
+
+// WithTransportWrapper wraps the HTTP transport used to make requests to
+// HVCA, for example to record or log the requests. The wrapper is called
+// once, with the transport built from the connection settings in the
+// configuration or, if WithHTTPClient is also used, with that client's
+// transport, and the transport it returns is used in its place.
+func WithTransportWrapper(wrap func(http.RoundTripper) http.RoundTripper) Option {
+	return func(o *clientOptions) error {
+		if wrap == nil {
+			return errors.New("no transport wrapper provided")
+		}
+
+		o.wrapTransport = wrap
+
+		return nil
+	}
+}
+
+// WithTimeout sets the default timeout for HVCA API requests.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *clientOptions) error {
+		if timeout < 0 {
+			return errors.New("timeout cannot be negative")
+		}
+
+		o.config.Timeout = timeout
+
+		return nil
+	}
+}
+
+// WithHeader adds a custom HTTP request header to be passed to the HVCA
+// server with each request.
+func WithHeader(name, value string) Option {
+	return func(o *clientOptions) error {
+		var headers = make(map[string]string, len(o.config.ExtraHeaders)+1)
+		for k, v := range o.config.ExtraHeaders {
+			headers[k] = v
+		}
+
+		headers[name] = value
+		o.config.ExtraHeaders = headers
+
+		return nil
+	}
+}
+
+// WithUserAgent sets the value of the User-Agent header sent with each
+// request to HVCA.
+func WithUserAgent(userAgent string) Option {
+	return func(o *clientOptions) error {
+		o.config.UserAgent = userAgent
+		return nil
+	}
+}
+
+// WithClock sets the clock used as the source of the current time and of
+// retry timers, in place of the system clock.
+func WithClock(clock Clock) Option {
+	return func(o *clientOptions) error {
+		o.config.Clock = clock
+		return nil
+	}
+}
+
+// WithProgressReporter sets a progress reporter to receive progress updates
+// from operations which make a number of HVCA API calls.
+func WithProgressReporter(r ProgressReporter) Option {
+	return func(o *clientOptions) error {
+		o.config.Progress = r
+		return nil
+	}
+}
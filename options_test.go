@@ -0,0 +1,212 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/google/go-cmp/cmp"
+)
+
+// roundTripperFunc adapts a function to an http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestNewWithOptions(t *testing.T) {
+	t.Parallel()
+
+	var server = newMockServer(t)
+	defer server.Close()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var client, err = hvclient.New(ctx,
+		hvclient.WithURL(server.URL),
+		hvclient.WithAPICredentials(mockAPIKey, mockAPISecret),
+		hvclient.WithHeader(sslClientSerialHeader, mockSSLClientSerial),
+		hvclient.WithHTTPClient(server.Client()),
+		hvclient.WithTimeout(time.Second*10),
+	)
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+
+	if got := client.DefaultTimeout(); got != time.Second*10 {
+		t.Errorf("got timeout %v, want %v", got, time.Second*10)
+	}
+
+	if _, err = client.QuotaIssuance(ctx); err != nil {
+		t.Errorf("couldn't get quota: %v", err)
+	}
+}
+
+func TestNewWithTransportWrapper(t *testing.T) {
+	t.Parallel()
+
+	var server = newMockServer(t)
+	defer server.Close()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var mtx sync.Mutex
+	var paths []string
+
+	var client, err = hvclient.New(ctx,
+		hvclient.WithURL(server.URL),
+		hvclient.WithAPICredentials(mockAPIKey, mockAPISecret),
+		hvclient.WithHeader(sslClientSerialHeader, mockSSLClientSerial),
+		hvclient.WithHTTPClient(server.Client()),
+		hvclient.WithTransportWrapper(func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				mtx.Lock()
+				paths = append(paths, r.URL.Path)
+				mtx.Unlock()
+
+				return next.RoundTrip(r)
+			})
+		}),
+	)
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+
+	if _, err = client.QuotaIssuance(ctx); err != nil {
+		t.Fatalf("couldn't get quota: %v", err)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if want := []string{"/login", "/quotas/issuance"}; !cmp.Equal(paths, want) {
+		t.Errorf("got paths %q, want %q", paths, want)
+	}
+}
+
+func TestNewWithConfigOption(t *testing.T) {
+	t.Parallel()
+
+	var server = newMockServer(t)
+	defer server.Close()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var conf = &hvclient.Config{
+		URL:       "http://example.com/v2",
+		APIKey:    mockAPIKey,
+		APISecret: mockAPISecret,
+		ExtraHeaders: map[string]string{
+			sslClientSerialHeader: mockSSLClientSerial,
+		},
+	}
+
+	var _, err = hvclient.New(ctx, hvclient.WithConfig(conf), hvclient.WithURL(server.URL))
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+
+	if conf.URL != "http://example.com/v2" {
+		t.Errorf("original configuration was modified")
+	}
+}
+
+func TestNewWithOptionsFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		opts []hvclient.Option
+	}{
+		{
+			name: "NoOptions",
+		},
+		{
+			name: "NoURL",
+			opts: []hvclient.Option{
+				hvclient.WithAPICredentials(mockAPIKey, mockAPISecret),
+			},
+		},
+		{
+			name: "NoCredentials",
+			opts: []hvclient.Option{
+				hvclient.WithURL("http://example.com/v2"),
+			},
+		},
+		{
+			name: "NilTransportWrapper",
+			opts: []hvclient.Option{
+				hvclient.WithURL("http://example.com/v2"),
+				hvclient.WithAPICredentials(mockAPIKey, mockAPISecret),
+				hvclient.WithTransportWrapper(nil),
+			},
+		},
+		{
+			name: "NilConfig",
+			opts: []hvclient.Option{
+				hvclient.WithConfig(nil),
+			},
+		},
+		{
+			name: "NilHTTPClient",
+			opts: []hvclient.Option{
+				hvclient.WithURL("http://example.com/v2"),
+				hvclient.WithAPICredentials(mockAPIKey, mockAPISecret),
+				hvclient.WithHTTPClient(nil),
+			},
+		},
+		{
+			name: "NilTLSKeyPair",
+			opts: []hvclient.Option{
+				hvclient.WithURL("http://example.com/v2"),
+				hvclient.WithAPICredentials(mockAPIKey, mockAPISecret),
+				hvclient.WithTLSKeyPair(nil, nil),
+			},
+		},
+		{
+			name: "NegativeTimeout",
+			opts: []hvclient.Option{
+				hvclient.WithURL("http://example.com/v2"),
+				hvclient.WithAPICredentials(mockAPIKey, mockAPISecret),
+				hvclient.WithTimeout(-time.Second),
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			if _, err := hvclient.New(ctx, tc.opts...); err == nil {
+				t.Fatalf("unexpectedly created client")
+			}
+		})
+	}
+}
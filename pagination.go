@@ -0,0 +1,82 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+// Pagination describes a single page of a listing retrieved from HVCA, such
+// as a page of certificates from StatsIssuedWithPagination.
+type Pagination struct {
+	// Page is the number of the page, starting at 1.
+	Page int
+
+	// PerPage is the number of items per page. HVCA enforces a maximum
+	// number of items per page, which it does not report, so if fewer items
+	// are returned than requested on a page which is not the last, or if no
+	// number was requested, this is the number of items on the page. In that
+	// case TotalPages and HasNext may overestimate for a partial last page,
+	// and the page following it will be empty.
+	PerPage int
+
+	// TotalCount is the total number of items in the listing, as reported
+	// by HVCA.
+	TotalCount int64
+
+	// TotalPages is the total number of pages in the listing, given the
+	// number of items per page.
+	TotalPages int
+
+	// HasNext is true if there are pages after this one.
+	HasNext bool
+}
+
+// NextPage returns the number of the page after this one, and true if there
+// is such a page.
+func (p *Pagination) NextPage() (int, bool) {
+	if !p.HasNext {
+		return 0, false
+	}
+
+	return p.Page + 1, true
+}
+
+// newPagination returns the pagination of a page of a listing, given the
+// page number and number of items per page requested, the number of items
+// returned, and the total count of items reported by HVCA.
+func newPagination(page, perPage, n int, count int64) *Pagination {
+	if page < 1 {
+		page = 1
+	}
+
+	// A page which is smaller than requested, but which does not hold the
+	// last of the items at the requested page size, indicates that HVCA
+	// used a smaller page size.
+	if n > 0 && (perPage <= 0 || (n < perPage && int64((page-1)*perPage+n) != count)) {
+		perPage = n
+	}
+
+	var p = Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		TotalCount: count,
+	}
+
+	if perPage > 0 {
+		p.TotalPages = int((count + int64(perPage) - 1) / int64(perPage))
+	}
+
+	p.HasNext = n > 0 && page < p.TotalPages
+
+	return &p
+}
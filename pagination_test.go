@@ -0,0 +1,152 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewPagination(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name    string
+		page    int
+		perPage int
+		n       int
+		count   int64
+		want    Pagination
+	}{
+		{
+			name:    "First",
+			page:    1,
+			perPage: 10,
+			n:       10,
+			count:   25,
+			want:    Pagination{Page: 1, PerPage: 10, TotalCount: 25, TotalPages: 3, HasNext: true},
+		},
+		{
+			name:    "Last",
+			page:    3,
+			perPage: 10,
+			n:       5,
+			count:   25,
+			want:    Pagination{Page: 3, PerPage: 10, TotalCount: 25, TotalPages: 3},
+		},
+		{
+			name:    "Only",
+			page:    1,
+			perPage: 100,
+			n:       5,
+			count:   5,
+			want:    Pagination{Page: 1, PerPage: 100, TotalCount: 5, TotalPages: 1},
+		},
+		{
+			name:    "Capped",
+			page:    2,
+			perPage: 100,
+			n:       7,
+			count:   50,
+			want:    Pagination{Page: 2, PerPage: 7, TotalCount: 50, TotalPages: 8, HasNext: true},
+		},
+		{
+			name:  "NoPageSize",
+			page:  1,
+			n:     20,
+			count: 45,
+			want:  Pagination{Page: 1, PerPage: 20, TotalCount: 45, TotalPages: 3, HasNext: true},
+		},
+		{
+			name:    "Empty",
+			page:    1,
+			perPage: 10,
+			want:    Pagination{Page: 1, PerPage: 10},
+		},
+		{
+			name:    "BeyondLast",
+			page:    4,
+			perPage: 10,
+			count:   25,
+			want:    Pagination{Page: 4, PerPage: 10, TotalCount: 25, TotalPages: 3},
+		},
+		{
+			name:  "ZeroPage",
+			n:     10,
+			count: 10,
+			want:  Pagination{Page: 1, PerPage: 10, TotalCount: 10, TotalPages: 1},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got = newPagination(tc.page, tc.perPage, tc.n, tc.count)
+
+			if !cmp.Equal(*got, tc.want) {
+				t.Errorf("got %+v, want %+v", *got, tc.want)
+			}
+
+			var next, ok = got.NextPage()
+			if ok != tc.want.HasNext || (ok && next != tc.want.Page+1) {
+				t.Errorf("got next page %d, %t", next, ok)
+			}
+		})
+	}
+}
+
+func TestStatsIssuedWithPagination(t *testing.T) {
+	t.Parallel()
+
+	var inFlight, maxInFlight int32
+	var clnt, _ = newTestClient(t, newPagingHandler(50, 7, 0, &inFlight, &maxInFlight), nil)
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	// Follow the pages until there are no more, which, since the server
+	// uses a smaller page size than requested, is one page after the last.
+	var all []CertMeta
+	var pages int
+
+	for page, ok := 1, true; ok; pages++ {
+		var metas, p, err = clnt.StatsIssuedWithPagination(ctx, page, 100, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("couldn't list certificates: %v", err)
+		}
+
+		if p.Page != page || p.TotalCount != 50 {
+			t.Fatalf("got pagination %+v for page %d", *p, page)
+		}
+
+		all = append(all, metas...)
+		page, ok = p.NextPage()
+	}
+
+	if len(all) != 50 {
+		t.Errorf("got %d certificates, want 50", len(all))
+	}
+
+	if pages != 9 {
+		t.Errorf("got %d pages, want 9", pages)
+	}
+}
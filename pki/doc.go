@@ -0,0 +1,30 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package pki provides helpers for reading and writing the keys, certificate
+signing requests and certificates used when building HVCA certificate
+requests and handling the certificates HVCA issues.
+
+Private keys, public keys, PKCS#10 certificate signing requests and X509
+certificates may be read from PEM-encoded files or data, or parsed from DER,
+keys may also be read from JSON Web Keys, and certificates, requests and keys may be PEM-encoded. Issued certificates
+may also be packaged, with their private keys and chains of trust, as
+PKCS#12 files, or as PKCS#7 certificate bundles.
+
+The API of this package is stable, and is a curated subset of the helpers
+used by hvclient itself.
+*/
+package pki
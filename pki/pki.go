@@ -0,0 +1,215 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pki
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+// KeyFromFile reads a PEM-encoded file and returns the RSA or ECDSA private
+// key it contains, in PKCS#1, PKCS#8 or SEC 1 format, decrypting it with the
+// specified password if it is encrypted. The password is ignored if the key
+// is not encrypted. A file containing a JSON Web Key, as exported by many
+// cloud key management services, is also accepted.
+func KeyFromFile(filename, password string) (crypto.PrivateKey, error) {
+	return pki.PrivateKeyFromFileWithPassword(filename, password)
+}
+
+// KeyFromPEM returns the private key in PEM-encoded data, as for KeyFromFile.
+func KeyFromPEM(data []byte, password string) (crypto.PrivateKey, error) {
+	return pki.PrivateKeyFromPEMWithPassword(data, password)
+}
+
+// KeyFromJWK returns the RSA or ECDSA private key in a JSON Web Key, as
+// described in RFC 7517. RSA private keys must include their prime factors.
+func KeyFromJWK(data []byte) (crypto.PrivateKey, error) {
+	return pki.PrivateKeyFromJWK(data)
+}
+
+// IsEncryptedKeyFile returns true if the specified file contains an
+// encrypted PEM-encoded private key, which requires a password to read.
+func IsEncryptedKeyFile(filename string) bool {
+	return pki.FileIsEncryptedPEMBlock(filename)
+}
+
+// PublicKeyFromFile reads a PEM-encoded file and returns the public key it
+// contains, in PKIX or PKCS#1 format. A file containing a JSON Web Key is
+// also accepted, in which case the public key of any private key it
+// contains is returned.
+func PublicKeyFromFile(filename string) (crypto.PublicKey, error) {
+	return pki.PublicKeyFromFile(filename)
+}
+
+// PublicKeyFromPEM returns the public key in PEM-encoded data, as for
+// PublicKeyFromFile.
+func PublicKeyFromPEM(data []byte) (crypto.PublicKey, error) {
+	return pki.PublicKeyFromPEM(data)
+}
+
+// PublicKeyFromJWK returns the RSA or ECDSA public key in a JSON Web Key,
+// as described in RFC 7517. If the JWK contains a private key, its public
+// key is returned.
+func PublicKeyFromJWK(data []byte) (crypto.PublicKey, error) {
+	return pki.PublicKeyFromJWK(data)
+}
+
+// CSRFromFile reads a PEM-encoded file and returns the PKCS#10 certificate
+// signing request it contains.
+func CSRFromFile(filename string) (*x509.CertificateRequest, error) {
+	return pki.CSRFromFile(filename)
+}
+
+// CSRFromPEM returns the PKCS#10 certificate signing request in PEM-encoded
+// data.
+func CSRFromPEM(data []byte) (*x509.CertificateRequest, error) {
+	return pki.CSRFromPEM(data)
+}
+
+// CSRFromDER returns the PKCS#10 certificate signing request in DER-encoded
+// data.
+func CSRFromDER(der []byte) (*x509.CertificateRequest, error) {
+	return x509.ParseCertificateRequest(der)
+}
+
+// CertFromFile reads a PEM-encoded file and returns the X509 certificate it
+// contains.
+func CertFromFile(filename string) (*x509.Certificate, error) {
+	return pki.CertFromFile(filename)
+}
+
+// CertFromPEM returns the X509 certificate in PEM-encoded data.
+func CertFromPEM(data []byte) (*x509.Certificate, error) {
+	return pki.CertFromPEM(data)
+}
+
+// CertFromDER returns the X509 certificate in DER-encoded data.
+func CertFromDER(der []byte) (*x509.Certificate, error) {
+	return x509.ParseCertificate(der)
+}
+
+// CertsFromFile reads a file containing one or more PEM-encoded X509
+// certificates, such as a chain of trust, and returns them in the order in
+// which they appear.
+func CertsFromFile(filename string) ([]*x509.Certificate, error) {
+	var data, err = ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return CertsFromPEM(data)
+}
+
+// CertsFromPEM returns the X509 certificates in PEM-encoded data, in the
+// order in which they appear. PEM blocks other than certificates are
+// ignored, but an error is returned if there are no certificates.
+func CertsFromPEM(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		var cert, err = x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse certificate %d: %w", len(certs)+1, err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+
+	return certs, nil
+}
+
+// CertPoolFromFile reads a file containing one or more PEM-encoded X509
+// certificates and returns a certificate pool containing them, such as for
+// use as the roots when verifying certificates.
+func CertPoolFromFile(filename string) (*x509.CertPool, error) {
+	return pki.CertPoolFromFile(filename)
+}
+
+// CertToPEM returns the PEM encoding of a certificate.
+func CertToPEM(cert *x509.Certificate) string {
+	return pki.CertToPEMString(cert)
+}
+
+// CertsToPEM returns the concatenated PEM encodings of certificates, such as
+// a certificate followed by its chain of trust.
+func CertsToPEM(certs []*x509.Certificate) string {
+	var b strings.Builder
+	for _, cert := range certs {
+		b.WriteString(pki.CertToPEMString(cert))
+	}
+
+	return b.String()
+}
+
+// CSRToPEM returns the PEM encoding of a PKCS#10 certificate signing
+// request.
+func CSRToPEM(csr *x509.CertificateRequest) string {
+	return pki.CSRToPEMString(csr)
+}
+
+// PublicKeyToPEM returns the PEM encoding of a public key in PKIX format.
+func PublicKeyToPEM(key crypto.PublicKey) (string, error) {
+	return pki.PublicKeyToPEMString(key)
+}
+
+// KeyToPEM returns the unencrypted PEM encoding of a private key in PKCS#8
+// format.
+func KeyToPEM(key crypto.PrivateKey) (string, error) {
+	var der, err = x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal PKCS#8 private key: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PRIVATE KEY",
+		Bytes: der,
+	})), nil
+}
+
+// BuildPKCS12 returns the DER encoding of a PKCS#12 (.p12 or .pfx) file
+// containing a private key, its certificate and the chain of CA
+// certificates, protected with the specified password. The private key must
+// be an *rsa.PrivateKey, an *ecdsa.PrivateKey or an ed25519.PrivateKey, and
+// must match the public key in the certificate.
+func BuildPKCS12(key crypto.PrivateKey, cert *x509.Certificate, chain []*x509.Certificate, password string) ([]byte, error) {
+	return pki.BuildPKCS12(key, cert, chain, password)
+}
+
+// PKCS7 returns the DER encoding of a PKCS#7 (.p7b) certificate bundle
+// containing the specified certificates.
+func PKCS7(certs []*x509.Certificate) ([]byte, error) {
+	return pki.CertsToPKCS7(certs)
+}
@@ -0,0 +1,276 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pki_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/globalsign/hvclient/pki"
+)
+
+func TestKeyFromFile(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		filename, password string
+		want               interface{}
+	}{
+		{"testdata/rsa_priv.key", "", &rsa.PrivateKey{}},
+		{"testdata/rsa_priv_enc.key", "strongpassword", &rsa.PrivateKey{}},
+		{"testdata/ec_priv.key", "", &ecdsa.PrivateKey{}},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			var key, err = pki.KeyFromFile(tc.filename, tc.password)
+			if err != nil {
+				t.Fatalf("couldn't read private key: %v", err)
+			}
+
+			if reflect.TypeOf(key) != reflect.TypeOf(tc.want) {
+				t.Fatalf("got %T, want %T", key, tc.want)
+			}
+
+			// Round-trip the key through PEM.
+			var s string
+			if s, err = pki.KeyToPEM(key); err != nil {
+				t.Fatalf("couldn't encode private key: %v", err)
+			}
+
+			var got interface{}
+			if got, err = pki.KeyFromPEM([]byte(s), ""); err != nil {
+				t.Fatalf("couldn't decode private key: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, key) {
+				t.Errorf("private key changed after round trip")
+			}
+		})
+	}
+}
+
+func TestKeyFromFileFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		filename, password string
+	}{
+		{"testdata/no_such_file.key", ""},
+		{"testdata/rsa_priv_enc.key", "wrongpassword"},
+		{"testdata/rsa_pub.key", ""},
+		{"testdata/cert.pem", ""},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.filename, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := pki.KeyFromFile(tc.filename, tc.password); err == nil {
+				t.Fatalf("unexpectedly read private key")
+			}
+		})
+	}
+
+	if _, err := pki.KeyFromPEM([]byte("not PEM data"), ""); err == nil {
+		t.Errorf("unexpectedly decoded private key from non-PEM data")
+	}
+}
+
+func TestIsEncryptedKeyFile(t *testing.T) {
+	t.Parallel()
+
+	if !pki.IsEncryptedKeyFile("testdata/rsa_priv_enc.key") {
+		t.Errorf("encrypted key not reported as encrypted")
+	}
+
+	if pki.IsEncryptedKeyFile("testdata/rsa_priv.key") {
+		t.Errorf("unencrypted key reported as encrypted")
+	}
+}
+
+func TestPublicKey(t *testing.T) {
+	t.Parallel()
+
+	var key, err = pki.PublicKeyFromFile("testdata/ec_pub.key")
+	if err != nil {
+		t.Fatalf("couldn't read public key: %v", err)
+	}
+
+	var s string
+	if s, err = pki.PublicKeyToPEM(key); err != nil {
+		t.Fatalf("couldn't encode public key: %v", err)
+	}
+
+	var got interface{}
+	if got, err = pki.PublicKeyFromPEM([]byte(s)); err != nil {
+		t.Fatalf("couldn't decode public key: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, key) {
+		t.Errorf("public key changed after round trip")
+	}
+}
+
+func TestCSR(t *testing.T) {
+	t.Parallel()
+
+	var csr, err = pki.CSRFromFile("testdata/request.p10")
+	if err != nil {
+		t.Fatalf("couldn't read CSR: %v", err)
+	}
+
+	var fromPEM, fromDER interface{}
+	if fromPEM, err = pki.CSRFromPEM([]byte(pki.CSRToPEM(csr))); err != nil {
+		t.Fatalf("couldn't decode CSR: %v", err)
+	}
+
+	if fromDER, err = pki.CSRFromDER(csr.Raw); err != nil {
+		t.Fatalf("couldn't parse CSR: %v", err)
+	}
+
+	if !reflect.DeepEqual(fromPEM, csr) || !reflect.DeepEqual(fromDER, csr) {
+		t.Errorf("CSR changed after round trip")
+	}
+
+	if _, err = pki.CSRFromFile("testdata/cert.pem"); err == nil {
+		t.Errorf("unexpectedly read certificate as CSR")
+	}
+}
+
+func TestCert(t *testing.T) {
+	t.Parallel()
+
+	var cert, err = pki.CertFromFile("testdata/cert.pem")
+	if err != nil {
+		t.Fatalf("couldn't read certificate: %v", err)
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadFile("testdata/cert.pem"); err != nil {
+		t.Fatalf("couldn't read file: %v", err)
+	}
+
+	if got := pki.CertToPEM(cert); got != string(data) {
+		t.Errorf("got PEM %q, want %q", got, data)
+	}
+
+	var fromPEM, fromDER interface{}
+	if fromPEM, err = pki.CertFromPEM(data); err != nil {
+		t.Fatalf("couldn't decode certificate: %v", err)
+	}
+
+	if fromDER, err = pki.CertFromDER(cert.Raw); err != nil {
+		t.Fatalf("couldn't parse certificate: %v", err)
+	}
+
+	if !reflect.DeepEqual(fromPEM, cert) || !reflect.DeepEqual(fromDER, cert) {
+		t.Errorf("certificate changed after round trip")
+	}
+
+	if _, err = pki.CertFromFile("testdata/request.p10"); err == nil {
+		t.Errorf("unexpectedly read CSR as certificate")
+	}
+
+	if _, err = pki.CertPoolFromFile("testdata/cert.pem"); err != nil {
+		t.Errorf("couldn't read certificate pool: %v", err)
+	}
+}
+
+func TestCerts(t *testing.T) {
+	t.Parallel()
+
+	var cert, err = pki.CertFromFile("testdata/cert.pem")
+	if err != nil {
+		t.Fatalf("couldn't read certificate: %v", err)
+	}
+
+	var bundle = pki.CertsToPEM([]*x509.Certificate{cert, cert})
+
+	// Non-certificate blocks are skipped.
+	var key []byte
+	if key, err = ioutil.ReadFile("testdata/ec_pub.key"); err != nil {
+		t.Fatalf("couldn't read file: %v", err)
+	}
+
+	var got []*x509.Certificate
+	if got, err = pki.CertsFromPEM(append(key, bundle...)); err != nil {
+		t.Fatalf("couldn't decode certificates: %v", err)
+	}
+
+	if len(got) != 2 || !got[0].Equal(cert) || !got[1].Equal(cert) {
+		t.Errorf("got %d certificates, want 2 copies of the original", len(got))
+	}
+
+	if _, err = pki.CertsFromPEM(key); err == nil {
+		t.Errorf("unexpectedly decoded certificates from public key")
+	}
+
+	if _, err = pki.CertsFromFile("testdata/no_such_file.pem"); err == nil {
+		t.Errorf("unexpectedly read certificates from missing file")
+	}
+}
+
+func TestBuildPKCS12AndPKCS7(t *testing.T) {
+	t.Parallel()
+
+	var cert, err = pki.CertFromFile("testdata/cert.pem")
+	if err != nil {
+		t.Fatalf("couldn't read certificate: %v", err)
+	}
+
+	var der []byte
+	if der, err = pki.PKCS7([]*x509.Certificate{cert}); err != nil {
+		t.Fatalf("couldn't build PKCS#7 bundle: %v", err)
+	}
+
+	if !bytes.Contains(der, cert.Raw) {
+		t.Errorf("PKCS#7 bundle does not contain certificate")
+	}
+
+	var key interface{}
+	if key, err = pki.KeyFromFile("testdata/rsa_priv.key", ""); err != nil {
+		t.Fatalf("couldn't read private key: %v", err)
+	}
+
+	if der, err = pki.BuildPKCS12(key, cert, []*x509.Certificate{cert}, "password"); err != nil {
+		t.Fatalf("couldn't build PKCS#12 file: %v", err)
+	}
+
+	if len(der) == 0 {
+		t.Errorf("got empty PKCS#12 file")
+	}
+
+	// The EC key does not match the certificate.
+	if key, err = pki.KeyFromFile("testdata/ec_priv.key", ""); err != nil {
+		t.Fatalf("couldn't read private key: %v", err)
+	}
+
+	if _, err = pki.BuildPKCS12(key, cert, nil, "password"); err == nil {
+		t.Errorf("unexpectedly built PKCS#12 file with mismatched key")
+	}
+}
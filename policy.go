@@ -16,8 +16,14 @@ limitations under the License.
 package hvclient
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"encoding/asn1"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -126,6 +132,15 @@ type QualifiedStatementsPolicy struct {
 	ETSIQCType            *StringPolicy          `json:"etsi_qc_type"`
 	ETSIQCRetentionPeriod *IntegerPolicy         `json:"etsi_qc_retention_period"`
 	ETSIQCPDs             *ETSIPDsPolicy         `json:"etsi_qc_pds"`
+	ETSIPSD2              *PSD2Policy            `json:"etsi_psd2,omitempty"`
+}
+
+// PSD2Policy is the ETSI PSD2 field in the qualified statements field in a
+// validation policy.
+type PSD2Policy struct {
+	RolesOfPSP *ListPolicy   `json:"roles_of_psp"`
+	NCAName    *StringPolicy `json:"nca_name"`
+	NCAID      *StringPolicy `json:"nca_id"`
 }
 
 // SemanticsPolicy is the semantics field in the qualified statements field
@@ -498,27 +513,21 @@ func (p *SubjectDAPolicy) UnmarshalJSON(b []byte) error {
 }
 
 // MarshalJSON returns the JSON encoding of a list of type and value policies.
+// The policies are keyed and sorted by OID, so the output is stable
+// regardless of the order of the list.
 func (p typeAndValuePolicies) MarshalJSON() ([]byte, error) {
-	var result = []byte("{")
+	var data = make(map[string]TypeAndValuePolicy, len(p))
 
-	for i, val := range p {
-		if i != 0 {
-			result = append(result, byte(','))
+	for _, val := range p {
+		var key = val.OID.String()
+		if _, ok := data[key]; ok {
+			return nil, fmt.Errorf("duplicate type and value policy for OID %s", key)
 		}
 
-		result = append(result, []byte(fmt.Sprintf(`"%s":`, val.OID.String()))...)
-
-		var this, err = json.Marshal(val)
-		if err != nil {
-			return nil, err
-		}
-
-		result = append(result, this...)
+		data[key] = val
 	}
 
-	result = append(result, byte('}'))
-
-	return result, nil
+	return json.Marshal(data)
 }
 
 // UnmarshalJSON parses a JSON-encoded list of type and value policies and
@@ -558,28 +567,21 @@ func (p *typeAndValuePolicies) UnmarshalJSON(b []byte) error {
 }
 
 // MarshalJSON returns the JSON encoding of a list of custom extensions
-// policies.
+// policies. The policies are keyed and sorted by OID, so the output is stable
+// regardless of the order of the list.
 func (p customExtensionsPolicies) MarshalJSON() ([]byte, error) {
-	var result = []byte("{")
-
-	for i, ext := range p {
-		if i != 0 {
-			result = append(result, byte(','))
-		}
+	var data = make(map[string]CustomExtensionsPolicy, len(p))
 
-		result = append(result, []byte(fmt.Sprintf(`"%s":`, ext.OID.String()))...)
-
-		var this, err = json.Marshal(ext)
-		if err != nil {
-			return nil, err
+	for _, ext := range p {
+		var key = ext.OID.String()
+		if _, ok := data[key]; ok {
+			return nil, fmt.Errorf("duplicate custom extensions policy for OID %s", key)
 		}
 
-		result = append(result, this...)
+		data[key] = ext
 	}
 
-	result = append(result, byte('}'))
-
-	return result, nil
+	return json.Marshal(data)
 }
 
 // UnmarshalJSON parses a JSON-encoded list of custom extensions policies
@@ -826,3 +828,184 @@ func (v *OptionalStaticPresence) UnmarshalJSON(b []byte) error {
 
 	return nil
 }
+
+// Validate checks a PSD2 qualified statement against the policy, and returns
+// an error describing the first violation found. The roles of the payment
+// service provider are checked by name against the policy list.
+func (p *PSD2Policy) Validate(psd2 *PSD2) error {
+	if p == nil {
+		if psd2 != nil {
+			return errors.New("PSD2 qualified statement not allowed by policy")
+		}
+
+		return nil
+	}
+
+	if psd2 == nil {
+		psd2 = &PSD2{}
+	}
+
+	var roles = make([]string, 0, len(psd2.RolesOfPSP))
+	for _, role := range psd2.RolesOfPSP {
+		roles = append(roles, role.Name)
+	}
+
+	if err := p.RolesOfPSP.validate(roles); err != nil {
+		return fmt.Errorf("invalid PSD2 roles of PSP: %w", err)
+	}
+
+	if err := p.NCAName.validate(psd2.NCAName); err != nil {
+		return fmt.Errorf("invalid PSD2 NCA name: %w", err)
+	}
+
+	if err := p.NCAID.validate(psd2.NCAID); err != nil {
+		return fmt.Errorf("invalid PSD2 NCA ID: %w", err)
+	}
+
+	return nil
+}
+
+// ErrPublicKeyNotAllowed is matched, using errors.Is, by a *PublicKeyError
+// returned when a public key does not conform to the public key policy.
+var ErrPublicKeyNotAllowed = errors.New("public key not allowed by policy")
+
+// PublicKeyError is returned when a public key does not conform to the
+// public key policy. It matches both ErrPublicKeyNotAllowed and
+// ErrPolicyViolation, using errors.Is.
+type PublicKeyError struct {
+	// KeyType is the type of the public key, or zero if it is neither an
+	// RSA nor an ECDSA key.
+	KeyType KeyType
+
+	// Length is the length of the public key in bits, being the modulus
+	// length for RSA keys and the curve size for ECDSA keys.
+	Length int
+
+	// Policy is the public key policy which the key violates.
+	Policy *PublicKeyPolicy
+}
+
+// Error returns a string representation of the error.
+func (e *PublicKeyError) Error() string {
+	if e.KeyType == 0 {
+		return fmt.Sprintf("%v: unsupported key type", ErrPublicKeyNotAllowed)
+	}
+
+	var want = "key"
+	if e.Policy.KeyType != 0 {
+		want = e.Policy.KeyType.String() + " key"
+	}
+	if len(e.Policy.AllowedLengths) > 0 {
+		var lengths = make([]string, 0, len(e.Policy.AllowedLengths))
+		for _, length := range e.Policy.AllowedLengths {
+			lengths = append(lengths, fmt.Sprint(length))
+		}
+
+		want += " of length " + strings.Join(lengths, ", ")
+	}
+
+	return fmt.Sprintf("%v: got %d-bit %s key, want %s", ErrPublicKeyNotAllowed, e.Length, e.KeyType, want)
+}
+
+// Is reports whether target is ErrPublicKeyNotAllowed or ErrPolicyViolation.
+func (e *PublicKeyError) Is(target error) bool {
+	return target == ErrPublicKeyNotAllowed || target == ErrPolicyViolation
+}
+
+// Validate checks the type and length of an RSA or ECDSA public key against
+// the policy, and returns a *PublicKeyError if it does not conform. The
+// length of an RSA key is its modulus length, and that of an ECDSA key is
+// its curve size. Any key is allowed by a nil policy.
+func (p *PublicKeyPolicy) Validate(pub interface{}) error {
+	if p == nil {
+		return nil
+	}
+
+	var keyType KeyType
+	var length int
+
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		keyType, length = RSA, k.N.BitLen()
+
+	case *ecdsa.PublicKey:
+		keyType, length = ECDSA, k.Curve.Params().BitSize
+
+	default:
+		return &PublicKeyError{Policy: p}
+	}
+
+	if !p.allows(keyType, length) {
+		return &PublicKeyError{KeyType: keyType, Length: length, Policy: p}
+	}
+
+	return nil
+}
+
+// allows returns true if the policy allows a public key of the specified
+// type and length.
+func (p *PublicKeyPolicy) allows(keyType KeyType, length int) bool {
+	if p == nil {
+		return true
+	}
+
+	if p.KeyType != 0 && keyType != p.KeyType {
+		return false
+	}
+
+	if len(p.AllowedLengths) == 0 {
+		return true
+	}
+
+	for _, allowed := range p.AllowedLengths {
+		if length == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// policyKeyTypes are the types and lengths of keys generated to conform to
+// a public key policy, in order of preference.
+var policyKeyTypes = []struct {
+	keyType KeyType
+	length  int
+	curve   elliptic.Curve
+}{
+	{ECDSA, 256, elliptic.P256()},
+	{ECDSA, 384, elliptic.P384()},
+	{ECDSA, 521, elliptic.P521()},
+	{RSA, 2048, nil},
+	{RSA, 3072, nil},
+	{RSA, 4096, nil},
+}
+
+// newPolicyKey generates a new private key of the most preferred type and
+// length allowed by a public key policy.
+func newPolicyKey(p *PublicKeyPolicy) (crypto.Signer, error) {
+	for _, kt := range policyKeyTypes {
+		if !p.allows(kt.keyType, kt.length) {
+			continue
+		}
+
+		var key crypto.Signer
+		var err error
+
+		switch kt.keyType {
+		case ECDSA:
+			key, err = ecdsa.GenerateKey(kt.curve, rand.Reader)
+
+		default:
+			key, err = rsa.GenerateKey(rand.Reader, kt.length)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("couldn't generate private key: %w", err)
+		}
+
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: no supported key type and length allowed", ErrPublicKeyNotAllowed)
+}
@@ -0,0 +1,217 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/globalsign/hvclient/internal/oids"
+)
+
+// ApplyPolicyDefaults fills in the values which are fixed by the validation
+// policy, so that callers need only specify the values which vary between
+// requests. Static subject distinguished name attributes, static lists of
+// organizational units, subject alternative names and extended key usages,
+// static custom extension values, and signature algorithms which the policy
+// requires and for which it allows only one value are applied. Values already present
+// in the request are never changed, so a request which conflicts with the
+// policy will still be rejected by Validate. A nil policy has no effect.
+func (r *Request) ApplyPolicyDefaults(pol *Policy) error {
+	if pol == nil {
+		return nil
+	}
+
+	if pol.SubjectDN != nil {
+		if r.Subject == nil {
+			r.Subject = &DN{}
+		}
+
+		r.Subject.applyPolicyDefaults(pol.SubjectDN)
+
+		if r.Subject.Equal(&DN{}) {
+			r.Subject = nil
+		}
+	}
+
+	if pol.SAN != nil {
+		if r.SAN == nil {
+			r.SAN = &SAN{}
+		}
+
+		if err := r.SAN.applyPolicyDefaults(pol.SAN); err != nil {
+			return err
+		}
+
+		if r.SAN.Equal(&SAN{}) {
+			r.SAN = nil
+		}
+	}
+
+	if pol.EKUs != nil && len(r.EKUs) == 0 {
+		for _, value := range staticValues(&pol.EKUs.EKUs) {
+			var oid, err = oids.StringToOID(value)
+			if err != nil {
+				return fmt.Errorf("invalid static extended key usage in policy: %w", err)
+			}
+
+			r.EKUs = append(r.EKUs, oid)
+		}
+	}
+
+	r.applyCustomExtensionDefaults(pol.CustomExtensions)
+
+	if sig := pol.SignaturePolicy; sig != nil {
+		var alg, hash = staticAlgorithm(sig.Algorithm), staticAlgorithm(sig.HashAlgorithm)
+
+		if alg != "" || hash != "" {
+			if r.Signature == nil {
+				r.Signature = &Signature{}
+			}
+
+			if r.Signature.Algorithm == "" {
+				r.Signature.Algorithm = alg
+			}
+
+			if r.Signature.HashAlgorithm == "" {
+				r.Signature.HashAlgorithm = hash
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyPolicyDefaults fills in any empty attributes which have static values
+// in the policy.
+func (n *DN) applyPolicyDefaults(pol *SubjectDNPolicy) {
+	for _, field := range []struct {
+		pol   *StringPolicy
+		value *string
+	}{
+		{pol.CommonName, &n.CommonName},
+		{pol.Organization, &n.Organization},
+		{pol.Country, &n.Country},
+		{pol.State, &n.State},
+		{pol.Locality, &n.Locality},
+		{pol.StreetAddress, &n.StreetAddress},
+		{pol.Email, &n.Email},
+		{pol.JOILocality, &n.JOILocality},
+		{pol.JOIState, &n.JOIState},
+		{pol.JOICountry, &n.JOICountry},
+		{pol.BusinessCategory, &n.BusinessCategory},
+		{pol.SerialNumber, &n.SerialNumber},
+	} {
+		if *field.value == "" {
+			*field.value = staticValue(field.pol)
+		}
+	}
+
+	if len(n.OrganizationalUnit) == 0 {
+		n.OrganizationalUnit = staticValues(pol.OrganizationalUnit)
+	}
+}
+
+// applyPolicyDefaults fills in any empty lists of names which have static
+// values in the policy.
+func (s *SAN) applyPolicyDefaults(pol *SANPolicy) error {
+	if len(s.DNSNames) == 0 {
+		s.DNSNames = staticValues(pol.DNSNames)
+	}
+
+	if len(s.Emails) == 0 {
+		s.Emails = staticValues(pol.Emails)
+	}
+
+	if len(s.IPAddresses) == 0 {
+		for _, value := range staticValues(pol.IPAddresses) {
+			var ip = net.ParseIP(value)
+			if ip == nil {
+				return fmt.Errorf("invalid static IP address in policy: %q", value)
+			}
+
+			s.IPAddresses = append(s.IPAddresses, ip)
+		}
+	}
+
+	if len(s.URIs) == 0 {
+		for _, value := range staticValues(pol.URIs) {
+			var uri, err = url.Parse(value)
+			if err != nil {
+				return fmt.Errorf("invalid static URI in policy: %w", err)
+			}
+
+			s.URIs = append(s.URIs, uri)
+		}
+	}
+
+	return nil
+}
+
+// applyCustomExtensionDefaults adds any custom extensions with static values
+// in the policy which are not already present in the request.
+func (r *Request) applyCustomExtensionDefaults(pols []CustomExtensionsPolicy) {
+	var present = make(map[string]bool)
+	for _, ext := range r.CustomExtensions {
+		present[ext.OID.String()] = true
+	}
+
+	for _, ext := range r.CustomExtensionValues {
+		present[ext.OID.String()] = true
+	}
+
+	for _, pol := range pols {
+		if pol.Presence != Static || present[pol.OID.String()] {
+			continue
+		}
+
+		r.CustomExtensions = append(r.CustomExtensions, OIDAndString{
+			OID:   pol.OID,
+			Value: pol.ValueFormat,
+		})
+	}
+}
+
+// staticValue returns the static value of a string policy, or the empty
+// string if the value is not static.
+func staticValue(pol *StringPolicy) string {
+	if pol == nil || pol.Presence != Static {
+		return ""
+	}
+
+	return pol.Format
+}
+
+// staticValues returns a copy of the static values of a list policy, or nil
+// if the values are not static.
+func staticValues(pol *ListPolicy) []string {
+	if pol == nil || !pol.Static || len(pol.List) == 0 {
+		return nil
+	}
+
+	return append([]string(nil), pol.List...)
+}
+
+// staticAlgorithm returns the algorithm name of an algorithm policy which
+// requires an algorithm and allows only one, or the empty string otherwise.
+func staticAlgorithm(pol *AlgorithmPolicy) string {
+	if pol == nil || (pol.Presence != Required && pol.Presence != Static) || len(pol.List) != 1 {
+		return ""
+	}
+
+	return pol.List[0]
+}
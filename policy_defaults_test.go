@@ -0,0 +1,253 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"encoding/asn1"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRequestApplyPolicyDefaults(t *testing.T) {
+	t.Parallel()
+
+	var pol = &hvclient.Policy{
+		SubjectDN: &hvclient.SubjectDNPolicy{
+			CommonName: &hvclient.StringPolicy{
+				Presence: hvclient.Required,
+				Format:   "^[A-Za-z ]+$",
+			},
+			Country: &hvclient.StringPolicy{
+				Presence: hvclient.Static,
+				Format:   "GB",
+			},
+			Organization: &hvclient.StringPolicy{
+				Presence: hvclient.Static,
+				Format:   "ACME Inc",
+			},
+			OrganizationalUnit: &hvclient.ListPolicy{
+				Static:   true,
+				List:     []string{"Sales", "Marketing"},
+				MinCount: 2,
+				MaxCount: 2,
+			},
+		},
+		SAN: &hvclient.SANPolicy{
+			DNSNames: &hvclient.ListPolicy{
+				List:     []string{`^.*\.acme\.com$`},
+				MaxCount: 2,
+			},
+			IPAddresses: &hvclient.ListPolicy{
+				Static:   true,
+				List:     []string{"192.0.2.1"},
+				MinCount: 1,
+				MaxCount: 1,
+			},
+			URIs: &hvclient.ListPolicy{
+				Static:   true,
+				List:     []string{"https://acme.com/"},
+				MinCount: 1,
+				MaxCount: 1,
+			},
+		},
+		EKUs: &hvclient.EKUPolicy{
+			EKUs: hvclient.ListPolicy{
+				Static:   true,
+				List:     []string{"1.3.6.1.5.5.7.3.1", "1.3.6.1.5.5.7.3.2"},
+				MinCount: 2,
+				MaxCount: 2,
+			},
+		},
+		CustomExtensions: []hvclient.CustomExtensionsPolicy{
+			{
+				OID:         asn1.ObjectIdentifier{1, 2, 3, 1},
+				Presence:    hvclient.Static,
+				ValueType:   hvclient.UTF8String,
+				ValueFormat: "fixed",
+			},
+			{
+				OID:       asn1.ObjectIdentifier{1, 2, 3, 2},
+				Presence:  hvclient.Optional,
+				ValueType: hvclient.UTF8String,
+			},
+		},
+		SignaturePolicy: &hvclient.SignaturePolicy{
+			Algorithm: &hvclient.AlgorithmPolicy{
+				Presence: hvclient.Required,
+				List:     []string{"RSA"},
+			},
+			HashAlgorithm: &hvclient.AlgorithmPolicy{
+				Presence: hvclient.Optional,
+				List:     []string{"SHA-256", "SHA-384"},
+			},
+		},
+	}
+
+	var testcases = []struct {
+		name string
+		req  hvclient.Request
+		want hvclient.Request
+	}{
+		{
+			name: "Empty",
+			want: hvclient.Request{
+				Subject: &hvclient.DN{
+					Country:            "GB",
+					Organization:       "ACME Inc",
+					OrganizationalUnit: []string{"Sales", "Marketing"},
+				},
+				SAN: &hvclient.SAN{
+					IPAddresses: []net.IP{net.ParseIP("192.0.2.1")},
+					URIs:        []*url.URL{{Scheme: "https", Host: "acme.com", Path: "/"}},
+				},
+				EKUs: []asn1.ObjectIdentifier{
+					{1, 3, 6, 1, 5, 5, 7, 3, 1},
+					{1, 3, 6, 1, 5, 5, 7, 3, 2},
+				},
+				CustomExtensions: []hvclient.OIDAndString{
+					{OID: asn1.ObjectIdentifier{1, 2, 3, 1}, Value: "fixed"},
+				},
+				Signature: &hvclient.Signature{Algorithm: "RSA"},
+			},
+		},
+		{
+			name: "Variable",
+			req: hvclient.Request{
+				Subject: &hvclient.DN{
+					CommonName:   "John Doe",
+					Organization: "Other Inc",
+				},
+				SAN: &hvclient.SAN{
+					DNSNames: []string{"www.acme.com"},
+				},
+				EKUs: []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 1}},
+				CustomExtensions: []hvclient.OIDAndString{
+					{OID: asn1.ObjectIdentifier{1, 2, 3, 1}, Value: "other"},
+				},
+				Signature: &hvclient.Signature{HashAlgorithm: "SHA-384"},
+			},
+			want: hvclient.Request{
+				Subject: &hvclient.DN{
+					CommonName:         "John Doe",
+					Country:            "GB",
+					Organization:       "Other Inc",
+					OrganizationalUnit: []string{"Sales", "Marketing"},
+				},
+				SAN: &hvclient.SAN{
+					DNSNames:    []string{"www.acme.com"},
+					IPAddresses: []net.IP{net.ParseIP("192.0.2.1")},
+					URIs:        []*url.URL{{Scheme: "https", Host: "acme.com", Path: "/"}},
+				},
+				EKUs: []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 1}},
+				CustomExtensions: []hvclient.OIDAndString{
+					{OID: asn1.ObjectIdentifier{1, 2, 3, 1}, Value: "other"},
+				},
+				Signature: &hvclient.Signature{Algorithm: "RSA", HashAlgorithm: "SHA-384"},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got = tc.req
+			if err := got.ApplyPolicyDefaults(pol); err != nil {
+				t.Fatalf("couldn't apply policy defaults: %v", err)
+			}
+
+			if !got.Equal(tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+
+			if !cmp.Equal(got.Signature, tc.want.Signature) {
+				t.Errorf("got signature %v, want %v", got.Signature, tc.want.Signature)
+			}
+		})
+	}
+}
+
+func TestRequestApplyPolicyDefaultsNoPolicy(t *testing.T) {
+	t.Parallel()
+
+	var req = hvclient.Request{Subject: &hvclient.DN{CommonName: "John Doe"}}
+	var want = req
+
+	if err := req.ApplyPolicyDefaults(nil); err != nil {
+		t.Fatalf("couldn't apply policy defaults: %v", err)
+	}
+
+	if !req.Equal(want) {
+		t.Errorf("got %v, want %v", req, want)
+	}
+
+	// Policies with no static values should not add empty objects.
+	if err := req.ApplyPolicyDefaults(&hvclient.Policy{
+		SAN:             &hvclient.SANPolicy{},
+		SignaturePolicy: &hvclient.SignaturePolicy{},
+	}); err != nil {
+		t.Fatalf("couldn't apply policy defaults: %v", err)
+	}
+
+	if req.SAN != nil || req.Signature != nil {
+		t.Errorf("got SAN %v and signature %v, want nil", req.SAN, req.Signature)
+	}
+}
+
+func TestRequestApplyPolicyDefaultsFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		pol  hvclient.Policy
+	}{
+		{
+			name: "BadEKU",
+			pol: hvclient.Policy{
+				EKUs: &hvclient.EKUPolicy{
+					EKUs: hvclient.ListPolicy{Static: true, List: []string{"not an oid"}},
+				},
+			},
+		},
+		{
+			name: "BadIPAddress",
+			pol: hvclient.Policy{
+				SAN: &hvclient.SANPolicy{
+					IPAddresses: &hvclient.ListPolicy{Static: true, List: []string{"not an ip"}},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var req hvclient.Request
+			if err := req.ApplyPolicyDefaults(&tc.pol); err == nil {
+				t.Fatalf("unexpectedly applied policy defaults")
+			}
+		})
+	}
+}
@@ -20,9 +20,11 @@ import (
 	"bytes"
 	"encoding/asn1"
 	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/testhelpers"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -640,6 +642,65 @@ func TestPolicyMarshalJSON(t *testing.T) {
 	}
 }
 
+func TestPolicyMarshalJSONStable(t *testing.T) {
+	t.Parallel()
+
+	var ext = func(oid asn1.ObjectIdentifier, format string) hvclient.CustomExtensionsPolicy {
+		return hvclient.CustomExtensionsPolicy{
+			OID:         oid,
+			Presence:    hvclient.Optional,
+			ValueType:   hvclient.UTF8String,
+			ValueFormat: format,
+		}
+	}
+
+	var policies = []hvclient.Policy{testFullPolicy, testFullPolicy}
+
+	policies[0].CustomExtensions = []hvclient.CustomExtensionsPolicy{
+		ext(asn1.ObjectIdentifier{1, 2, 3, 10}, `^"quoted"$`),
+		ext(asn1.ObjectIdentifier{1, 2, 3, 4}, `^back\\slash$`),
+	}
+
+	policies[1].CustomExtensions = []hvclient.CustomExtensionsPolicy{
+		ext(asn1.ObjectIdentifier{1, 2, 3, 4}, `^back\\slash$`),
+		ext(asn1.ObjectIdentifier{1, 2, 3, 10}, `^"quoted"$`),
+	}
+
+	var want = `{"custom_extensions":{` +
+		`"1.2.3.10":{"presence":"OPTIONAL","critical":false,"value_type":"UTF8STRING","value_format":"^\"quoted\"$"},` +
+		`"1.2.3.4":{"presence":"OPTIONAL","critical":false,"value_type":"UTF8STRING","value_format":"^back\\\\slash$"}}}`
+
+	for i, pol := range policies {
+		var got, err = json.Marshal(pol)
+		if err != nil {
+			t.Fatalf("couldn't marshal JSON: %v", err)
+		}
+
+		var data map[string]json.RawMessage
+		if err = json.Unmarshal(got, &data); err != nil {
+			t.Fatalf("couldn't unmarshal JSON: %v", err)
+		}
+
+		var exts []byte
+		if exts, err = json.Marshal(map[string]json.RawMessage{"custom_extensions": data["custom_extensions"]}); err != nil {
+			t.Fatalf("couldn't marshal JSON: %v", err)
+		}
+
+		if string(exts) != want {
+			t.Errorf("%d: got %s, want %s", i, exts, want)
+		}
+
+		var back hvclient.Policy
+		if err = json.Unmarshal(got, &back); err != nil {
+			t.Fatalf("couldn't unmarshal JSON: %v", err)
+		}
+
+		if back.CustomExtensions[0].ValueFormat != `^"quoted"$` {
+			t.Errorf("got value format %q, want %q", back.CustomExtensions[0].ValueFormat, `^"quoted"$`)
+		}
+	}
+}
+
 func TestPolicyMarshalJSONFailure(t *testing.T) {
 	t.Parallel()
 
@@ -670,6 +731,23 @@ func TestPolicyMarshalJSONFailure(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "DuplicateCustomExtension",
+			policy: hvclient.Policy{
+				CustomExtensions: []hvclient.CustomExtensionsPolicy{
+					{
+						OID:       asn1.ObjectIdentifier{1, 2, 3, 4},
+						Presence:  hvclient.Optional,
+						ValueType: hvclient.IA5String,
+					},
+					{
+						OID:       asn1.ObjectIdentifier{1, 2, 3, 4},
+						Presence:  hvclient.Required,
+						ValueType: hvclient.IA5String,
+					},
+				},
+			},
+		},
 		{
 			name: "BadValueType",
 			policy: hvclient.Policy{
@@ -1002,3 +1080,182 @@ func TestOptionalStaticPresenceString(t *testing.T) {
 		})
 	}
 }
+
+func TestPSD2PolicyValidate(t *testing.T) {
+	t.Parallel()
+
+	var pol = &hvclient.PSD2Policy{
+		RolesOfPSP: &hvclient.ListPolicy{
+			List:     []string{"^PSP_(AS|PI|AI)$"},
+			MinCount: 1,
+			MaxCount: 3,
+		},
+		NCAName: &hvclient.StringPolicy{
+			Presence: hvclient.Required,
+			Format:   "^[A-Za-z ]+$",
+		},
+		NCAID: &hvclient.StringPolicy{
+			Presence: hvclient.Static,
+			Format:   "GB-FCA",
+		},
+	}
+
+	var testcases = []struct {
+		name string
+		pol  *hvclient.PSD2Policy
+		psd2 *hvclient.PSD2
+		ok   bool
+	}{
+		{
+			name: "Valid",
+			pol:  pol,
+			psd2: &hvclient.PSD2{
+				RolesOfPSP: []hvclient.PSPRole{
+					hvclient.PSPRoleAccountServicing,
+					hvclient.PSPRolePaymentInitiation,
+				},
+				NCAName: "Financial Conduct Authority",
+				NCAID:   "GB-FCA",
+			},
+			ok: true,
+		},
+		{
+			name: "NilPolicyNilValue",
+			ok:   true,
+		},
+		{
+			name: "NilPolicy",
+			psd2: &hvclient.PSD2{NCAID: "GB-FCA"},
+		},
+		{
+			name: "Missing",
+			pol:  pol,
+		},
+		{
+			name: "RoleNotAllowed",
+			pol:  pol,
+			psd2: &hvclient.PSD2{
+				RolesOfPSP: []hvclient.PSPRole{hvclient.PSPRoleIssuingCardBasedPay},
+				NCAName:    "Financial Conduct Authority",
+				NCAID:      "GB-FCA",
+			},
+		},
+		{
+			name: "NoRoles",
+			pol:  pol,
+			psd2: &hvclient.PSD2{
+				NCAName: "Financial Conduct Authority",
+				NCAID:   "GB-FCA",
+			},
+		},
+		{
+			name: "BadNCAName",
+			pol:  pol,
+			psd2: &hvclient.PSD2{
+				RolesOfPSP: []hvclient.PSPRole{hvclient.PSPRoleAccountServicing},
+				NCAName:    "FCA-1",
+				NCAID:      "GB-FCA",
+			},
+		},
+		{
+			name: "WrongStaticNCAID",
+			pol:  pol,
+			psd2: &hvclient.PSD2{
+				RolesOfPSP: []hvclient.PSPRole{hvclient.PSPRoleAccountServicing},
+				NCAName:    "Financial Conduct Authority",
+				NCAID:      "DE-BAFIN",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var err = tc.pol.Validate(tc.psd2)
+			if (err == nil) != tc.ok {
+				t.Fatalf("got error %v, want success %t", err, tc.ok)
+			}
+		})
+	}
+}
+
+func TestPublicKeyPolicyValidate(t *testing.T) {
+	t.Parallel()
+
+	var rsaKey = testhelpers.MustGetPublicKeyFromFile(t, "testdata/rsa_pub.key")
+	var ecKey = testhelpers.MustGetPublicKeyFromFile(t, "testdata/ec_pub.key")
+
+	var pol = &hvclient.PublicKeyPolicy{
+		KeyType:        hvclient.ECDSA,
+		AllowedLengths: []int{256, 384},
+	}
+
+	var testcases = []struct {
+		name string
+		pol  *hvclient.PublicKeyPolicy
+		key  interface{}
+		ok   bool
+	}{
+		{
+			name: "Valid",
+			pol:  pol,
+			key:  ecKey,
+			ok:   true,
+		},
+		{
+			name: "NilPolicy",
+			key:  rsaKey,
+			ok:   true,
+		},
+		{
+			name: "AnyLength",
+			pol:  &hvclient.PublicKeyPolicy{KeyType: hvclient.RSA},
+			key:  rsaKey,
+			ok:   true,
+		},
+		{
+			name: "WrongType",
+			pol:  pol,
+			key:  rsaKey,
+		},
+		{
+			name: "WrongLength",
+			pol:  &hvclient.PublicKeyPolicy{KeyType: hvclient.ECDSA, AllowedLengths: []int{384}},
+			key:  ecKey,
+		},
+		{
+			name: "UnsupportedType",
+			pol:  pol,
+			key:  "not a key",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var err = tc.pol.Validate(tc.key)
+			if (err == nil) != tc.ok {
+				t.Fatalf("got error %v, want success %t", err, tc.ok)
+			}
+
+			if err == nil {
+				return
+			}
+
+			var keyErr *hvclient.PublicKeyError
+			if !errors.As(err, &keyErr) {
+				t.Fatalf("got error of type %T, want %T", err, keyErr)
+			}
+
+			if !errors.Is(err, hvclient.ErrPublicKeyNotAllowed) || !errors.Is(err, hvclient.ErrPolicyViolation) {
+				t.Errorf("error %v does not match %v and %v", err, hvclient.ErrPublicKeyNotAllowed, hvclient.ErrPolicyViolation)
+			}
+		})
+	}
+}
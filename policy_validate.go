@@ -0,0 +1,499 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LintIssue is a problem found when checking a certificate request against
+// a validation policy.
+type LintIssue struct {
+	// Field is the JSON path of the offending request field, for example
+	// "subject_dn.common_name".
+	Field string
+
+	// Message describes the problem.
+	Message string
+}
+
+// String returns a description of the lint issue.
+func (i LintIssue) String() string {
+	return i.Message
+}
+
+// Validate performs a client-side check of a certificate request against the
+// validation policy, and returns an error describing the first violation
+// found. It checks the validity period, the subject distinguished name, the
+// subject alternative names, the extended key usages, the custom extensions,
+// the signature algorithms, and any PSD2 qualified statement. A nil error does not
+// guarantee that HVCA will accept the request, since HVCA may apply checks
+// which are not described by the policy.
+func (p *Policy) Validate(req *Request) error {
+	var result error
+
+	p.check(req, func(_ string, err error) bool {
+		result = err
+		return false
+	})
+
+	return result
+}
+
+// LintTemplate performs the same checks as Validate, but returns every
+// violation found rather than only the first, including forbidden fields,
+// missing required fields and values which do not match the formats in the
+// policy. It is intended for checking stored request templates before
+// anything is submitted to HVCA.
+func (p *Policy) LintTemplate(r *Request) []LintIssue {
+	var issues []LintIssue
+
+	p.check(r, func(field string, err error) bool {
+		issues = append(issues, LintIssue{Field: field, Message: err.Error()})
+		return true
+	})
+
+	return issues
+}
+
+// check checks a certificate request against the validation policy, and
+// calls report with the JSON path of the offending field and a description
+// of each violation found. Checking stops if report returns false.
+func (p *Policy) check(req *Request, report func(field string, err error) bool) {
+	if err := p.Validity.validate(req.Validity); err != nil {
+		if !report("validity", fmt.Errorf("invalid validity: %w", err)) {
+			return
+		}
+	}
+
+	var dn = req.Subject
+	if dn == nil {
+		dn = &DN{}
+	}
+
+	var dnPolicy = p.SubjectDN
+	if dnPolicy == nil {
+		dnPolicy = &SubjectDNPolicy{}
+	}
+
+	for _, field := range []struct {
+		name  string
+		path  string
+		pol   *StringPolicy
+		value string
+	}{
+		{"common name", "common_name", dnPolicy.CommonName, dn.CommonName},
+		{"serial number", "serial_number", dnPolicy.SerialNumber, dn.SerialNumber},
+		{"organization", "organization", dnPolicy.Organization, dn.Organization},
+		{"street address", "street_address", dnPolicy.StreetAddress, dn.StreetAddress},
+		{"locality", "locality", dnPolicy.Locality, dn.Locality},
+		{"state", "state", dnPolicy.State, dn.State},
+		{"country", "country", dnPolicy.Country, dn.Country},
+		{"email", "email", dnPolicy.Email, dn.Email},
+		{"jurisdiction locality", "jurisdiction_of_incorporation_locality_name", dnPolicy.JOILocality, dn.JOILocality},
+		{"jurisdiction state or province", "jurisdiction_of_incorporation_state_or_province_name", dnPolicy.JOIState, dn.JOIState},
+		{"jurisdiction country", "jurisdiction_of_incorporation_country_name", dnPolicy.JOICountry, dn.JOICountry},
+		{"business category", "business_category", dnPolicy.BusinessCategory, dn.BusinessCategory},
+	} {
+		if err := field.pol.validate(field.value); err != nil {
+			if !report("subject_dn."+field.path, fmt.Errorf("invalid subject %s: %w", field.name, err)) {
+				return
+			}
+		}
+	}
+
+	if err := dnPolicy.OrganizationalUnit.validate(dn.OrganizationalUnit); err != nil {
+		if !report("subject_dn.organizational_unit", fmt.Errorf("invalid subject organizational units: %w", err)) {
+			return
+		}
+	}
+
+	var san = req.SAN
+	if san == nil {
+		san = &SAN{}
+	}
+
+	var sanPolicy = p.SAN
+	if sanPolicy == nil {
+		sanPolicy = &SANPolicy{}
+	}
+
+	var ips = make([]string, 0, len(san.IPAddresses))
+	for _, ip := range san.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+
+	var uris = make([]string, 0, len(san.URIs))
+	for _, uri := range san.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	for _, field := range []struct {
+		name   string
+		path   string
+		pol    *ListPolicy
+		values []string
+	}{
+		{"DNS names", "dns_names", sanPolicy.DNSNames, san.DNSNames},
+		{"email addresses", "emails", sanPolicy.Emails, san.Emails},
+		{"IP addresses", "ip_addresses", sanPolicy.IPAddresses, ips},
+		{"URIs", "uris", sanPolicy.URIs, uris},
+	} {
+		if err := field.pol.validate(field.values); err != nil {
+			if !report("san."+field.path, fmt.Errorf("invalid SAN %s: %w", field.name, err)) {
+				return
+			}
+		}
+	}
+
+	if p.EKUs != nil {
+		var ekus = make([]string, 0, len(req.EKUs))
+		for _, eku := range req.EKUs {
+			ekus = append(ekus, eku.String())
+		}
+
+		if err := p.EKUs.EKUs.validate(ekus); err != nil {
+			if !report("extended_key_usages", fmt.Errorf("invalid extended key usages: %w", err)) {
+				return
+			}
+		}
+	}
+
+	if !p.checkCustomExtensions(req, report) {
+		return
+	}
+
+	if err := p.SignaturePolicy.Validate(req.Signature); err != nil {
+		if !report("signature", err) {
+			return
+		}
+	}
+
+	var psd2 *PSD2
+	if req.QualifiedStatements != nil {
+		psd2 = req.QualifiedStatements.PSD2
+	}
+
+	var psd2Policy *PSD2Policy
+	if p.QualifiedStatements != nil {
+		psd2Policy = p.QualifiedStatements.ETSIPSD2
+	}
+
+	if err := psd2Policy.Validate(psd2); err != nil {
+		report("qualified_statements.etsi_psd2", err)
+	}
+}
+
+// checkCustomExtensions checks the custom extensions in a certificate
+// request against the custom extension policies, calling report for each
+// violation found. It returns false if report returned false.
+func (p *Policy) checkCustomExtensions(req *Request, report func(field string, err error) bool) bool {
+	var policies = make(map[string]*CustomExtensionsPolicy, len(p.CustomExtensions))
+	for i := range p.CustomExtensions {
+		policies[p.CustomExtensions[i].OID.String()] = &p.CustomExtensions[i]
+	}
+
+	type extension struct {
+		oid   string
+		value string
+		typed interface{}
+	}
+
+	var exts = make([]extension, 0, len(req.CustomExtensions)+len(req.CustomExtensionValues))
+	for _, ext := range req.CustomExtensions {
+		exts = append(exts, extension{oid: ext.OID.String(), value: ext.Value})
+	}
+
+	for _, ext := range req.CustomExtensionValues {
+		var str, err = ext.OIDAndString()
+		if err != nil {
+			if !report("custom_extensions."+ext.OID.String(), err) {
+				return false
+			}
+
+			continue
+		}
+
+		exts = append(exts, extension{oid: str.OID.String(), value: str.Value, typed: ext.Value})
+	}
+
+	var present = make(map[string]bool, len(exts))
+
+	for _, ext := range exts {
+		present[ext.oid] = true
+
+		var err error
+		if pol, ok := policies[ext.oid]; !ok {
+			err = errors.New("value not allowed by policy")
+		} else {
+			err = pol.validate(ext.value, ext.typed)
+		}
+
+		if err != nil {
+			if !report("custom_extensions."+ext.oid, fmt.Errorf("invalid custom extension %s: %w", ext.oid, err)) {
+				return false
+			}
+		}
+	}
+
+	for _, pol := range p.CustomExtensions {
+		if pol.Presence == Required && !present[pol.OID.String()] {
+			if !report("custom_extensions."+pol.OID.String(), fmt.Errorf("invalid custom extension %s: value is required", pol.OID)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// validate checks the string representation of a custom extension value
+// against the policy's presence, format and value type. If the value was
+// supplied as a typed value rather than as a string, typed is that value,
+// and its Go type is also checked against the value type.
+func (p *CustomExtensionsPolicy) validate(value string, typed interface{}) error {
+	var err = (&StringPolicy{Presence: p.Presence, Format: p.ValueFormat}).validate(value)
+	if err != nil || value == "" {
+		return err
+	}
+
+	switch p.ValueType {
+	case Integer:
+		switch typed.(type) {
+		case nil, int, int64, *big.Int:
+		default:
+			return fmt.Errorf("value of type %T is not an integer", typed)
+		}
+
+		if _, ok := big.NewInt(0).SetString(value, 10); !ok {
+			return fmt.Errorf("value %q is not a decimal integer", value)
+		}
+
+	case DER:
+		switch typed.(type) {
+		case nil, asn1.RawValue:
+		default:
+			return fmt.Errorf("value of type %T is not a DER encoding", typed)
+		}
+
+		var der []byte
+		if der, err = base64.StdEncoding.DecodeString(value); err != nil {
+			return fmt.Errorf("value %q is not base64-encoded: %v", value, err)
+		}
+
+		var raw asn1.RawValue
+		if rest, err := asn1.Unmarshal(der, &raw); err != nil || len(rest) > 0 {
+			return fmt.Errorf("value %q is not a single DER encoding", value)
+		}
+
+	case IA5String, PrintableString, UTF8String:
+		if _, ok := typed.(string); typed != nil && !ok {
+			return fmt.Errorf("value of type %T is not a string", typed)
+		}
+
+		if !p.ValueType.allows(value) {
+			return fmt.Errorf("value %q is not a valid %s", value, p.ValueType)
+		}
+	}
+
+	return nil
+}
+
+// allows reports whether a string contains only characters allowed by a
+// string value type.
+func (v ValueType) allows(s string) bool {
+	switch v {
+	case IA5String:
+		for _, r := range s {
+			if r > unicode.MaxASCII {
+				return false
+			}
+		}
+
+	case PrintableString:
+		for _, r := range s {
+			if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' ||
+				strings.ContainsRune(" '()+,-./:=?", r)) {
+				return false
+			}
+		}
+
+	case UTF8String:
+		return utf8.ValidString(s)
+	}
+
+	return true
+}
+
+// validate checks the duration of a validity period against the policy. A
+// missing validity period or not-after time is always accepted, since HVCA
+// then applies the maximum duration allowed by the policy.
+func (p *ValidityPolicy) validate(validity *Validity) error {
+	if p == nil || validity == nil || validity.NotAfter.IsZero() || validity.NotAfter.Equal(time.Unix(0, 0)) {
+		return nil
+	}
+
+	var seconds = int64(validity.NotAfter.Sub(validity.NotBefore) / time.Second)
+	if seconds < p.SecondsMin || (p.SecondsMax > 0 && seconds > p.SecondsMax) {
+		return fmt.Errorf("duration of %d seconds is not between %d and %d", seconds, p.SecondsMin, p.SecondsMax)
+	}
+
+	return nil
+}
+
+// validate checks a string value against a string policy. A nil policy
+// forbids the value.
+func (p *StringPolicy) validate(value string) error {
+	if p == nil {
+		if value != "" {
+			return errors.New("value not allowed by policy")
+		}
+
+		return nil
+	}
+
+	switch {
+	case value == "" && p.Presence == Required:
+		return errors.New("value is required")
+
+	case value == "":
+		return nil
+
+	case p.Presence == Forbidden:
+		return errors.New("value is forbidden")
+
+	case p.Presence == Static && value != p.Format:
+		return fmt.Errorf("value %q does not match static value %q", value, p.Format)
+
+	case p.Presence != Static && p.Format != "":
+		var re, err = regexp.Compile(p.Format)
+		if err != nil {
+			return fmt.Errorf("invalid format %q in policy: %v", p.Format, err)
+		}
+
+		if !re.MatchString(value) {
+			return fmt.Errorf("value %q does not match format %q", value, p.Format)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks a list of values, such as the SAN email addresses in a
+// certificate request, against the list policy, and returns an error
+// describing the first violation found. The number of values is checked,
+// and each value must match one of the regular expressions in the policy
+// or, for a static policy, be one of its values. A nil policy forbids any
+// values.
+func (p *ListPolicy) Validate(values []string) error {
+	return p.validate(values)
+}
+
+// validate checks a list of values against a list policy. A nil policy
+// forbids any values.
+func (p *ListPolicy) validate(values []string) error {
+	if p == nil {
+		if len(values) != 0 {
+			return errors.New("values not allowed by policy")
+		}
+
+		return nil
+	}
+
+	if len(values) < p.MinCount || len(values) > p.MaxCount {
+		return fmt.Errorf("got %d values, want between %d and %d", len(values), p.MinCount, p.MaxCount)
+	}
+
+	if p.Static {
+		for _, value := range values {
+			if !stringInSlice(value, p.List) {
+				return fmt.Errorf("value %q is not one of the static values %q", value, p.List)
+			}
+		}
+
+		return nil
+	}
+
+	var match, err = p.matcher()
+	if err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		if !match(value) {
+			return fmt.Errorf("value %q does not match any of the formats %q", value, p.List)
+		}
+	}
+
+	return nil
+}
+
+// matcher returns a function which reports whether a single value is
+// allowed by a non-nil list policy, being one of its values for a static
+// policy, or matching one of its regular expressions otherwise. Any value
+// is allowed by a policy which is not static and has no regular
+// expressions.
+func (p *ListPolicy) matcher() (func(string) bool, error) {
+	if p.Static {
+		return func(value string) bool {
+			return stringInSlice(value, p.List)
+		}, nil
+	}
+
+	var res = make([]*regexp.Regexp, 0, len(p.List))
+	for _, format := range p.List {
+		var re, err = regexp.Compile(format)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format %q in policy: %v", format, err)
+		}
+
+		res = append(res, re)
+	}
+
+	return func(value string) bool {
+		if len(res) == 0 {
+			return true
+		}
+
+		for _, re := range res {
+			if re.MatchString(value) {
+				return true
+			}
+		}
+
+		return false
+	}, nil
+}
+
+// stringInSlice reports whether a string is present in a slice of strings.
+func stringInSlice(s string, list []string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,417 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"encoding/asn1"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	t.Parallel()
+
+	var pol = hvclient.Policy{
+		Validity: &hvclient.ValidityPolicy{
+			SecondsMin: 3600,
+			SecondsMax: 86400,
+		},
+		SubjectDN: &hvclient.SubjectDNPolicy{
+			CommonName: &hvclient.StringPolicy{
+				Presence: hvclient.Required,
+				Format:   "^[A-Za-z ]+$",
+			},
+			Country: &hvclient.StringPolicy{
+				Presence: hvclient.Static,
+				Format:   "GB",
+			},
+			OrganizationalUnit: &hvclient.ListPolicy{
+				List:     []string{"^.*$"},
+				MaxCount: 1,
+			},
+		},
+		SAN: &hvclient.SANPolicy{
+			DNSNames: &hvclient.ListPolicy{
+				List:     []string{`^.*\.acme\.com$`},
+				MinCount: 1,
+				MaxCount: 2,
+			},
+		},
+		EKUs: &hvclient.EKUPolicy{
+			EKUs: hvclient.ListPolicy{
+				List:     []string{"^1.3.6.1.5.5.7.3.[1-2]$"},
+				MaxCount: 2,
+			},
+		},
+		CustomExtensions: []hvclient.CustomExtensionsPolicy{
+			{
+				OID:       asn1.ObjectIdentifier{1, 2, 3, 1},
+				Presence:  hvclient.Optional,
+				ValueType: hvclient.Integer,
+			},
+			{
+				OID:       asn1.ObjectIdentifier{1, 2, 3, 2},
+				Presence:  hvclient.Optional,
+				ValueType: hvclient.DER,
+			},
+			{
+				OID:         asn1.ObjectIdentifier{1, 2, 3, 3},
+				Presence:    hvclient.Optional,
+				ValueType:   hvclient.PrintableString,
+				ValueFormat: "^[A-Z]+$",
+			},
+			{
+				OID:       asn1.ObjectIdentifier{1, 2, 3, 4},
+				Presence:  hvclient.Optional,
+				ValueType: hvclient.IA5String,
+			},
+		},
+	}
+
+	var notBefore = time.Date(2021, 6, 18, 0, 0, 0, 0, time.UTC)
+
+	var valid = func() hvclient.Request {
+		return hvclient.Request{
+			Validity: &hvclient.Validity{
+				NotBefore: notBefore,
+				NotAfter:  notBefore.Add(time.Hour * 2),
+			},
+			Subject: &hvclient.DN{
+				CommonName: "John Doe",
+				Country:    "GB",
+			},
+			SAN: &hvclient.SAN{
+				DNSNames: []string{"www.acme.com"},
+			},
+			EKUs: []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 2}},
+		}
+	}
+
+	var testcases = []struct {
+		name   string
+		modify func(*hvclient.Request)
+		ok     bool
+	}{
+		{
+			name:   "Valid",
+			modify: func(r *hvclient.Request) {},
+			ok:     true,
+		},
+		{
+			name:   "MaximumValidity",
+			modify: func(r *hvclient.Request) { r.Validity.NotAfter = time.Unix(0, 0) },
+			ok:     true,
+		},
+		{
+			name:   "ValidityTooShort",
+			modify: func(r *hvclient.Request) { r.Validity.NotAfter = notBefore.Add(time.Minute) },
+		},
+		{
+			name:   "ValidityTooLong",
+			modify: func(r *hvclient.Request) { r.Validity.NotAfter = notBefore.Add(time.Hour * 48) },
+		},
+		{
+			name:   "MissingCommonName",
+			modify: func(r *hvclient.Request) { r.Subject.CommonName = "" },
+		},
+		{
+			name:   "BadCommonName",
+			modify: func(r *hvclient.Request) { r.Subject.CommonName = "John Doe 3rd" },
+		},
+		{
+			name:   "WrongStaticCountry",
+			modify: func(r *hvclient.Request) { r.Subject.Country = "US" },
+		},
+		{
+			name:   "ForbiddenOrganization",
+			modify: func(r *hvclient.Request) { r.Subject.Organization = "ACME Inc" },
+		},
+		{
+			name:   "TooManyOrganizationalUnits",
+			modify: func(r *hvclient.Request) { r.Subject.OrganizationalUnit = []string{"Sales", "Marketing"} },
+		},
+		{
+			name:   "MissingDNSNames",
+			modify: func(r *hvclient.Request) { r.SAN = nil },
+		},
+		{
+			name:   "BadDNSName",
+			modify: func(r *hvclient.Request) { r.SAN.DNSNames = []string{"www.example.com"} },
+		},
+		{
+			name:   "ForbiddenIPAddress",
+			modify: func(r *hvclient.Request) { r.SAN.IPAddresses = []net.IP{net.ParseIP("10.0.0.1")} },
+		},
+		{
+			name:   "BadEKU",
+			modify: func(r *hvclient.Request) { r.EKUs = []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 4}} },
+		},
+		{
+			name: "CustomExtensions",
+			modify: func(r *hvclient.Request) {
+				r.CustomExtensions = []hvclient.OIDAndString{{OID: asn1.ObjectIdentifier{1, 2, 3, 3}, Value: "ABC"}}
+				r.CustomExtensionValues = []hvclient.CustomExtensionValue{
+					{OID: asn1.ObjectIdentifier{1, 2, 3, 1}, Value: 42},
+					{OID: asn1.ObjectIdentifier{1, 2, 3, 2}, Value: asn1.RawValue{FullBytes: []byte{0x05, 0x00}}},
+				}
+			},
+			ok: true,
+		},
+		{
+			name: "CustomExtensionStrings",
+			modify: func(r *hvclient.Request) {
+				r.CustomExtensions = []hvclient.OIDAndString{
+					{OID: asn1.ObjectIdentifier{1, 2, 3, 1}, Value: "-12345678901234567890"},
+					{OID: asn1.ObjectIdentifier{1, 2, 3, 2}, Value: "BQA="},
+				}
+			},
+			ok: true,
+		},
+		{
+			name: "CustomExtensionNewOIDAndDER",
+			modify: func(r *hvclient.Request) {
+				r.CustomExtensions = []hvclient.OIDAndString{
+					hvclient.NewOIDAndDER(asn1.ObjectIdentifier{1, 2, 3, 2}, []byte{0x02, 0x01, 0x2a}),
+				}
+			},
+			ok: true,
+		},
+		{
+			name: "ForbiddenCustomExtension",
+			modify: func(r *hvclient.Request) {
+				r.CustomExtensions = []hvclient.OIDAndString{{OID: asn1.ObjectIdentifier{1, 2, 3, 5}, Value: "ABC"}}
+			},
+		},
+		{
+			name: "BadCustomExtensionInteger",
+			modify: func(r *hvclient.Request) {
+				r.CustomExtensions = []hvclient.OIDAndString{{OID: asn1.ObjectIdentifier{1, 2, 3, 1}, Value: "forty-two"}}
+			},
+		},
+		{
+			name: "BadCustomExtensionIntegerType",
+			modify: func(r *hvclient.Request) {
+				r.CustomExtensionValues = []hvclient.CustomExtensionValue{{OID: asn1.ObjectIdentifier{1, 2, 3, 1}, Value: "42"}}
+			},
+		},
+		{
+			name: "BadCustomExtensionBase64",
+			modify: func(r *hvclient.Request) {
+				r.CustomExtensions = []hvclient.OIDAndString{{OID: asn1.ObjectIdentifier{1, 2, 3, 2}, Value: "not base64"}}
+			},
+		},
+		{
+			name: "BadCustomExtensionDER",
+			modify: func(r *hvclient.Request) {
+				r.CustomExtensions = []hvclient.OIDAndString{{OID: asn1.ObjectIdentifier{1, 2, 3, 2}, Value: "BQAA"}}
+			},
+		},
+		{
+			name: "BadCustomExtensionDERType",
+			modify: func(r *hvclient.Request) {
+				r.CustomExtensionValues = []hvclient.CustomExtensionValue{{OID: asn1.ObjectIdentifier{1, 2, 3, 2}, Value: 5}}
+			},
+		},
+		{
+			name: "BadCustomExtensionFormat",
+			modify: func(r *hvclient.Request) {
+				r.CustomExtensions = []hvclient.OIDAndString{{OID: asn1.ObjectIdentifier{1, 2, 3, 3}, Value: "abc"}}
+			},
+		},
+		{
+			name: "BadCustomExtensionIA5String",
+			modify: func(r *hvclient.Request) {
+				r.CustomExtensions = []hvclient.OIDAndString{{OID: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: "café"}}
+			},
+		},
+		{
+			name: "BadCustomExtensionStringType",
+			modify: func(r *hvclient.Request) {
+				r.CustomExtensionValues = []hvclient.CustomExtensionValue{{OID: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: 42}}
+			},
+		},
+		{
+			name: "ForbiddenPSD2",
+			modify: func(r *hvclient.Request) {
+				r.QualifiedStatements = &hvclient.QualifiedStatements{PSD2: &hvclient.PSD2{NCAID: "GB-FCA"}}
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var req = valid()
+			tc.modify(&req)
+
+			var err = pol.Validate(&req)
+			if (err == nil) != tc.ok {
+				t.Fatalf("got error %v, want success %t", err, tc.ok)
+			}
+		})
+	}
+}
+
+func TestPolicyLintTemplate(t *testing.T) {
+	t.Parallel()
+
+	var pol = hvclient.Policy{
+		SubjectDN: &hvclient.SubjectDNPolicy{
+			CommonName: &hvclient.StringPolicy{
+				Presence: hvclient.Required,
+				Format:   "^[A-Za-z ]+$",
+			},
+			Organization: &hvclient.StringPolicy{
+				Presence: hvclient.Required,
+			},
+		},
+		SAN: &hvclient.SANPolicy{
+			DNSNames: &hvclient.ListPolicy{
+				List:     []string{`^.*\.acme\.com$`},
+				MaxCount: 2,
+			},
+		},
+	}
+
+	var testcases = []struct {
+		name    string
+		request hvclient.Request
+		want    []string
+	}{
+		{
+			name: "Clean",
+			request: hvclient.Request{
+				Subject: &hvclient.DN{CommonName: "John Doe", Organization: "ACME"},
+				SAN:     &hvclient.SAN{DNSNames: []string{"www.acme.com"}},
+			},
+		},
+		{
+			name: "MultipleIssues",
+			request: hvclient.Request{
+				Subject: &hvclient.DN{CommonName: "John Doe 3rd", Locality: "London"},
+				SAN: &hvclient.SAN{
+					DNSNames: []string{"www.example.com"},
+					Emails:   []string{"john@acme.com"},
+				},
+			},
+			want: []string{
+				"subject_dn.common_name",
+				"subject_dn.organization",
+				"subject_dn.locality",
+				"san.dns_names",
+				"san.emails",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var issues = pol.LintTemplate(&tc.request)
+
+			var got []string
+			for _, issue := range issues {
+				if issue.Message == "" {
+					t.Errorf("no message for issue with field %s", issue.Field)
+				}
+
+				got = append(got, issue.Field)
+			}
+
+			if !cmp.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+
+			if err := pol.Validate(&tc.request); (err == nil) != (len(issues) == 0) || (err != nil && err.Error() != issues[0].String()) {
+				t.Errorf("got validation error %v, want first issue %v", err, issues)
+			}
+		})
+	}
+}
+
+func TestListPolicyValidate(t *testing.T) {
+	t.Parallel()
+
+	var pol = &hvclient.ListPolicy{
+		List:     []string{`^[a-z.]+@example\.com$`},
+		MinCount: 1,
+		MaxCount: 2,
+	}
+
+	var testcases = []struct {
+		name   string
+		pol    *hvclient.ListPolicy
+		values []string
+		ok     bool
+	}{
+		{
+			name:   "Valid",
+			pol:    pol,
+			values: []string{"john@example.com", "jane.doe@example.com"},
+			ok:     true,
+		},
+		{
+			name: "NilPolicyNoValues",
+			ok:   true,
+		},
+		{
+			name:   "NilPolicy",
+			values: []string{"john@example.com"},
+		},
+		{
+			name: "TooFew",
+			pol:  pol,
+		},
+		{
+			name:   "TooMany",
+			pol:    pol,
+			values: []string{"a@example.com", "b@example.com", "c@example.com"},
+		},
+		{
+			name:   "NoMatch",
+			pol:    pol,
+			values: []string{"john@example.org"},
+		},
+		{
+			name:   "NotStatic",
+			pol:    &hvclient.ListPolicy{List: []string{"john@example.com"}, Static: true, MaxCount: 1},
+			values: []string{"jane@example.com"},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var err = tc.pol.Validate(tc.values)
+			if (err == nil) != tc.ok {
+				t.Fatalf("got error %v, want success %t", err, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import "sync"
+
+// ProgressReporter receives progress updates from operations which make a
+// number of HVCA API calls, such as retrieving every page of a listing,
+// so that progress bars and metrics can be driven without polling. Calls
+// to Progress are serialized, even if the work is performed concurrently,
+// so implementations need not be safe for concurrent use.
+type ProgressReporter interface {
+	// Progress is called each time a unit of work completes, successfully
+	// or otherwise, with the number of units completed so far, the total
+	// number of units, and the error, if any, encountered by the unit which
+	// just completed. The total is zero if it is not yet known.
+	Progress(completed, total int, lastErr error)
+}
+
+// ProgressFunc is an adapter which allows an ordinary function to be used
+// as a ProgressReporter.
+type ProgressFunc func(completed, total int, lastErr error)
+
+// Progress calls f(completed, total, lastErr).
+func (f ProgressFunc) Progress(completed, total int, lastErr error) {
+	f(completed, total, lastErr)
+}
+
+// progressTracker counts completed units of work and reports each one to a
+// ProgressReporter. A nil tracker, or one with no reporter, does nothing.
+type progressTracker struct {
+	mtx       sync.Mutex
+	reporter  ProgressReporter
+	completed int
+	total     int
+}
+
+// newProgressTracker returns a tracker which reports to r, or nil if r is
+// nil.
+func newProgressTracker(r ProgressReporter, total int) *progressTracker {
+	if r == nil {
+		return nil
+	}
+
+	return &progressTracker{reporter: r, total: total}
+}
+
+// done records the completion of a unit of work and reports it.
+func (p *progressTracker) done(err error) {
+	if p == nil {
+		return
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.completed++
+	p.reporter.Progress(p.completed, p.total, err)
+}
@@ -0,0 +1,310 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DeployedCertificate is a certificate deployed locally, for reconciliation
+// against the certificates issued and revoked by HVCA.
+type DeployedCertificate struct {
+	Path        string
+	Certificate *x509.Certificate
+}
+
+// ReconcileProblem is the type of discrepancy found when reconciling
+// deployed certificates against HVCA.
+type ReconcileProblem int
+
+// Reconciliation problem constants.
+const (
+	IssuedNotDeployed ReconcileProblem = iota + 1
+	DeployedRevoked
+	NearExpiry
+)
+
+// reconcileProblemNames maps reconciliation problem values to their
+// descriptions.
+var reconcileProblemNames = [...]string{
+	IssuedNotDeployed: "ISSUED_NOT_DEPLOYED",
+	DeployedRevoked:   "DEPLOYED_REVOKED",
+	NearExpiry:        "NEAR_EXPIRY",
+}
+
+// reconcileProblemCodes maps reconciliation problem descriptions to their
+// values.
+var reconcileProblemCodes = map[string]ReconcileProblem{
+	"ISSUED_NOT_DEPLOYED": IssuedNotDeployed,
+	"DEPLOYED_REVOKED":    DeployedRevoked,
+	"NEAR_EXPIRY":         NearExpiry,
+}
+
+// ReconcileFinding is a single discrepancy found when reconciling deployed
+// certificates against HVCA. Path is empty if the certificate is not
+// deployed.
+type ReconcileFinding struct {
+	Problem      ReconcileProblem
+	SerialNumber *big.Int
+	NotAfter     time.Time
+	Path         string
+}
+
+// jsonReconcileFinding is used internally for JSON marshalling/unmarshalling.
+type jsonReconcileFinding struct {
+	Problem      ReconcileProblem `json:"problem"`
+	SerialNumber string           `json:"serial_number"`
+	NotAfter     int64            `json:"not_after"`
+	Path         string           `json:"path,omitempty"`
+}
+
+// isValid checks if a reconciliation problem value is within a valid range.
+func (p ReconcileProblem) isValid() bool {
+	return p >= IssuedNotDeployed && p <= NearExpiry
+}
+
+// String returns a description of the reconciliation problem.
+func (p ReconcileProblem) String() string {
+	if !p.isValid() {
+		return "ERROR: UNKNOWN PROBLEM"
+	}
+
+	return reconcileProblemNames[p]
+}
+
+// MarshalJSON returns the JSON encoding of a reconciliation problem value.
+func (p ReconcileProblem) MarshalJSON() ([]byte, error) {
+	if !p.isValid() {
+		return nil, fmt.Errorf("invalid reconciliation problem value: %d", p)
+	}
+
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON parses a JSON-encoded reconciliation problem value and
+// stores the result in the object.
+func (p *ReconcileProblem) UnmarshalJSON(b []byte) error {
+	var data string
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	var result, ok = reconcileProblemCodes[strings.ToUpper(data)]
+	if !ok {
+		return fmt.Errorf("invalid reconciliation problem value: %s", data)
+	}
+
+	*p = result
+
+	return nil
+}
+
+// Equal checks if two reconciliation findings are equivalent.
+func (f ReconcileFinding) Equal(other ReconcileFinding) bool {
+	if (f.SerialNumber == nil) != (other.SerialNumber == nil) {
+		return false
+	}
+
+	if f.SerialNumber != nil && f.SerialNumber.Cmp(other.SerialNumber) != 0 {
+		return false
+	}
+
+	return f.Problem == other.Problem &&
+		f.NotAfter.Equal(other.NotAfter) &&
+		f.Path == other.Path
+}
+
+// MarshalJSON returns the JSON encoding of a reconciliation finding.
+func (f ReconcileFinding) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonReconcileFinding{
+		Problem:      f.Problem,
+		SerialNumber: NewSerialNumber(f.SerialNumber).Hex(),
+		NotAfter:     f.NotAfter.Unix(),
+		Path:         f.Path,
+	})
+}
+
+// UnmarshalJSON parses a JSON-encoded reconciliation finding and stores the
+// result in the object.
+func (f *ReconcileFinding) UnmarshalJSON(b []byte) error {
+	var data jsonReconcileFinding
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	var sn, err = ParseSerialNumber(data.SerialNumber)
+	if err != nil {
+		return err
+	}
+
+	*f = ReconcileFinding{
+		Problem:      data.Problem,
+		SerialNumber: sn.Big(),
+		NotAfter:     time.Unix(data.NotAfter, 0).UTC(),
+		Path:         data.Path,
+	}
+
+	return nil
+}
+
+// Reconcile compares locally deployed certificates with the certificates
+// issued and revoked by HVCA during the specified time window, and returns
+// a list of findings sorted by problem and serial number. Certificates
+// issued during the window which are neither deployed nor revoked, deployed
+// certificates which were revoked during the window, and deployed
+// certificates which are not revoked but which expire within the specified
+// period from the current time, are all reported.
+func (c *Client) Reconcile(
+	ctx context.Context,
+	deployed []DeployedCertificate,
+	from, to time.Time,
+	expiryWindow time.Duration,
+) ([]ReconcileFinding, error) {
+	var issued, err = c.statsAll(ctx, endpointStatsIssued, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't retrieve issued certificates: %w", err)
+	}
+
+	var revoked []CertMeta
+	if revoked, err = c.statsAll(ctx, endpointStatsRevoked, from, to); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve revoked certificates: %w", err)
+	}
+
+	return reconcile(deployed, issued, revoked, c.now().Add(expiryWindow)), nil
+}
+
+// DeployedCertificatesFromDir reads the PEM-encoded certificates in the
+// specified directory and its subdirectories, for use with Reconcile. Only
+// the first certificate in each file is read, since any others are assumed
+// to be intermediate certificates. Files which do not contain a PEM-encoded
+// certificate are ignored.
+func DeployedCertificatesFromDir(dir string) ([]DeployedCertificate, error) {
+	var deployed []DeployedCertificate
+
+	var err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		var data []byte
+		if data, err = ioutil.ReadFile(path); err != nil {
+			return err
+		}
+
+		for {
+			var block *pem.Block
+			if block, data = pem.Decode(data); block == nil {
+				return nil
+			}
+
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err != nil {
+				return fmt.Errorf("couldn't parse certificate in %s: %w", path, err)
+			}
+
+			deployed = append(deployed, DeployedCertificate{Path: path, Certificate: cert})
+
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deployed, nil
+}
+
+// reconcile compares deployed certificates with issued and revoked
+// certificates, treating deployed certificates which expire before the
+// specified time as near expiry.
+func reconcile(deployed []DeployedCertificate, issued, revoked []CertMeta, expiresBefore time.Time) []ReconcileFinding {
+	var isRevoked = make(map[string]bool)
+	for _, meta := range revoked {
+		isRevoked[meta.SerialNumber.Text(16)] = true
+	}
+
+	var isDeployed = make(map[string]bool)
+	var findings []ReconcileFinding
+
+	for _, dep := range deployed {
+		var cert = dep.Certificate
+		var key = cert.SerialNumber.Text(16)
+
+		isDeployed[key] = true
+
+		var finding = ReconcileFinding{
+			SerialNumber: cert.SerialNumber,
+			NotAfter:     cert.NotAfter,
+			Path:         dep.Path,
+		}
+
+		switch {
+		case isRevoked[key]:
+			finding.Problem = DeployedRevoked
+
+		case cert.NotAfter.Before(expiresBefore):
+			finding.Problem = NearExpiry
+
+		default:
+			continue
+		}
+
+		findings = append(findings, finding)
+	}
+
+	for _, meta := range issued {
+		var key = meta.SerialNumber.Text(16)
+
+		if isDeployed[key] || isRevoked[key] {
+			continue
+		}
+
+		findings = append(findings, ReconcileFinding{
+			Problem:      IssuedNotDeployed,
+			SerialNumber: meta.SerialNumber,
+			NotAfter:     meta.NotAfter,
+		})
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Problem != findings[j].Problem {
+			return findings[i].Problem < findings[j].Problem
+		}
+
+		return findings[i].SerialNumber.Cmp(findings[j].SerialNumber) < 0
+	})
+
+	return findings
+}
@@ -0,0 +1,162 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var deployed = []DeployedCertificate{
+		{
+			Path:        "/certs/ok.pem",
+			Certificate: &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: now.Add(time.Hour * 24 * 60)},
+		},
+		{
+			Path:        "/certs/revoked.pem",
+			Certificate: &x509.Certificate{SerialNumber: big.NewInt(2), NotAfter: now.Add(time.Hour * 24 * 60)},
+		},
+		{
+			Path:        "/certs/expiring.pem",
+			Certificate: &x509.Certificate{SerialNumber: big.NewInt(3), NotAfter: now.Add(time.Hour * 24 * 10)},
+		},
+		{
+			Path:        "/certs/expired.pem",
+			Certificate: &x509.Certificate{SerialNumber: big.NewInt(4), NotAfter: now.Add(-time.Hour)},
+		},
+	}
+
+	var issued = []CertMeta{
+		{SerialNumber: big.NewInt(1), NotAfter: now.Add(time.Hour * 24 * 60)},
+		{SerialNumber: big.NewInt(2), NotAfter: now.Add(time.Hour * 24 * 60)},
+		{SerialNumber: big.NewInt(6), NotAfter: now.Add(time.Hour * 24 * 90)},
+		{SerialNumber: big.NewInt(5), NotAfter: now.Add(time.Hour * 24 * 90)},
+		{SerialNumber: big.NewInt(7), NotAfter: now.Add(time.Hour * 24 * 90)},
+	}
+
+	var revoked = []CertMeta{
+		{SerialNumber: big.NewInt(2)},
+		{SerialNumber: big.NewInt(7)},
+	}
+
+	var want = []ReconcileFinding{
+		{Problem: IssuedNotDeployed, SerialNumber: big.NewInt(5), NotAfter: now.Add(time.Hour * 24 * 90)},
+		{Problem: IssuedNotDeployed, SerialNumber: big.NewInt(6), NotAfter: now.Add(time.Hour * 24 * 90)},
+		{Problem: DeployedRevoked, SerialNumber: big.NewInt(2), NotAfter: now.Add(time.Hour * 24 * 60), Path: "/certs/revoked.pem"},
+		{Problem: NearExpiry, SerialNumber: big.NewInt(3), NotAfter: now.Add(time.Hour * 24 * 10), Path: "/certs/expiring.pem"},
+		{Problem: NearExpiry, SerialNumber: big.NewInt(4), NotAfter: now.Add(-time.Hour), Path: "/certs/expired.pem"},
+	}
+
+	var got = reconcile(deployed, issued, revoked, now.Add(time.Hour*24*30))
+	if !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestReconcileFindingJSON(t *testing.T) {
+	t.Parallel()
+
+	var finding = ReconcileFinding{
+		Problem:      DeployedRevoked,
+		SerialNumber: big.NewInt(0x741DAF9EC2D5F7DC),
+		NotAfter:     time.Date(2021, 9, 16, 16, 29, 51, 0, time.UTC),
+		Path:         "/certs/revoked.pem",
+	}
+
+	var want = `{"problem":"DEPLOYED_REVOKED","serial_number":"741DAF9EC2D5F7DC","not_after":1631809791,"path":"/certs/revoked.pem"}`
+
+	var data, err = json.Marshal(finding)
+	if err != nil {
+		t.Fatalf("couldn't marshal JSON: %v", err)
+	}
+
+	if string(data) != want {
+		t.Fatalf("got %s, want %s", data, want)
+	}
+
+	var got ReconcileFinding
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("couldn't unmarshal JSON: %v", err)
+	}
+
+	if !got.Equal(finding) {
+		t.Errorf("got %v, want %v", got, finding)
+	}
+
+	if _, err = json.Marshal(ReconcileFinding{SerialNumber: big.NewInt(1)}); err == nil {
+		t.Errorf("unexpectedly marshalled invalid problem")
+	}
+}
+
+func TestDeployedCertificatesFromDir(t *testing.T) {
+	t.Parallel()
+
+	var certPEM, err = ioutil.ReadFile("testdata/test_cert.pem")
+	if err != nil {
+		t.Fatalf("couldn't read certificate: %v", err)
+	}
+
+	var chainPEM []byte
+	if chainPEM, err = ioutil.ReadFile("testdata/test_ica_cert.pem"); err != nil {
+		t.Fatalf("couldn't read certificate: %v", err)
+	}
+
+	var dir = t.TempDir()
+
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"leaf.pem", certPEM},
+		{"fullchain.pem", append(append([]byte{}, certPEM...), chainPEM...)},
+		{"README", []byte("not a certificate")},
+	} {
+		if err = ioutil.WriteFile(filepath.Join(dir, file.name), file.data, 0600); err != nil {
+			t.Fatalf("couldn't write file: %v", err)
+		}
+	}
+
+	var got []DeployedCertificate
+	if got, err = DeployedCertificatesFromDir(dir); err != nil {
+		t.Fatalf("couldn't read deployed certificates: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got %d certificates, want 2", len(got))
+	}
+
+	for _, dep := range got {
+		if dep.Certificate.SerialNumber.Text(16) != "741daf9ec2d5f7dc" {
+			t.Errorf("got serial number %X from %s", dep.Certificate.SerialNumber, dep.Path)
+		}
+	}
+
+	if _, err = DeployedCertificatesFromDir(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("unexpectedly read certificates from missing directory")
+	}
+}
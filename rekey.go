@@ -0,0 +1,242 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/oids"
+)
+
+// CertificateRekeyRequest contains the new key for a certificate rekey. As
+// with a Request, the key may be supplied in one of three ways, and exactly
+// one of the fields should be set:
+//
+// 1. For HVCA accounts which do not require proof-of-possession of the
+// private key, assign the new public key to the PublicKey field;
+//
+// 2. For HVCA accounts which require proof-of-possession, assign the new
+// private key to the PrivateKey field, and the public key will be extracted
+// and the appropriate signature generated automatically; or
+//
+// 3. Assign a PKCS#10 certificate signing request containing the new public
+// key to the CSR field. As with a Request, none of the fields in the CSR
+// other than the public key and the signature are used.
+type CertificateRekeyRequest struct {
+	PublicKey  interface{}
+	PrivateKey interface{}
+	CSR        *x509.CertificateRequest
+}
+
+// ErrRekeySameKey is wrapped by the error returned by CertificateRekey when
+// the new key is the same as the key of the certificate being replaced.
+var ErrRekeySameKey = errors.New("new key is the same as the certificate's current key")
+
+// CertificateRekey requests a new certificate to replace the certificate
+// with the specified serial number, with the same subject, subject
+// alternative names and extended key usages and the same validity duration,
+// but with a new key. The existing certificate is not revoked. The serial
+// number of the new certificate is returned.
+//
+// Before the request is submitted, the new key is checked against the
+// certificate's current key and against the public key policy in the
+// account's validation policy, so that a compromised key is not silently
+// certified again. An error wrapping ErrRekeySameKey is returned if the keys
+// are the same, and a *PublicKeyError if the new key is not of a type and
+// length allowed by the policy.
+func (c *Client) CertificateRekey(
+	ctx context.Context,
+	serial *big.Int,
+	rekey *CertificateRekeyRequest,
+) (*big.Int, error) {
+	var info, err = c.CertificateRetrieve(ctx, serial)
+	if err != nil {
+		return nil, err
+	}
+
+	var req *Request
+	if req, err = newRekeyRequest(info.X509, rekey, c.now()); err != nil {
+		return nil, err
+	}
+
+	if err = c.checkRekeyKey(ctx, info.X509, req); err != nil {
+		return nil, err
+	}
+
+	var sn *big.Int
+	if sn, err = c.CertificateRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return sn, nil
+}
+
+// checkRekeyKey checks that the new key in a rekey request differs from the
+// key of the certificate being replaced, and is allowed by the public key
+// policy.
+func (c *Client) checkRekeyKey(ctx context.Context, cert *x509.Certificate, req *Request) error {
+	var pub = req.requestPublicKey()
+	if pub == nil {
+		return errors.New("couldn't determine the new public key")
+	}
+
+	var fingerprint = publicKeyFingerprint(pub)
+	if fingerprint == nil {
+		return fmt.Errorf("couldn't encode new public key of type %T", pub)
+	}
+
+	if bytes.Equal(fingerprint, publicKeyFingerprint(cert.PublicKey)) {
+		return fmt.Errorf("%w: certificate %X", ErrRekeySameKey, cert.SerialNumber)
+	}
+
+	var pol, err = c.Policy(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't retrieve validation policy: %w", err)
+	}
+
+	return pol.PublicKey.Validate(pub)
+}
+
+// newRekeyRequest builds a certificate request to rekey the specified
+// certificate, with a validity period of the same duration starting at the
+// specified time.
+func newRekeyRequest(cert *x509.Certificate, rekey *CertificateRekeyRequest, now time.Time) (*Request, error) {
+	if rekey == nil {
+		return nil, errors.New("no rekey request provided")
+	}
+
+	var numKeys int
+	for _, present := range []bool{rekey.PublicKey != nil, rekey.PrivateKey != nil, rekey.CSR != nil} {
+		if present {
+			numKeys++
+		}
+	}
+
+	if numKeys != 1 {
+		return nil, errors.New("exactly one of public key, private key or CSR must be provided")
+	}
+
+	if cert == nil {
+		return nil, errors.New("no certificate to rekey")
+	}
+
+	var ekus, err = certificateEKUs(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	var req = &Request{
+		Validity: &Validity{
+			NotBefore: now,
+			NotAfter:  now.Add(cert.NotAfter.Sub(cert.NotBefore)),
+		},
+		Subject:    dnFromPKIXName(cert.Subject),
+		EKUs:       ekus,
+		PublicKey:  rekey.PublicKey,
+		PrivateKey: rekey.PrivateKey,
+		CSR:        rekey.CSR,
+	}
+
+	if len(cert.DNSNames) > 0 || len(cert.EmailAddresses) > 0 ||
+		len(cert.IPAddresses) > 0 || len(cert.URIs) > 0 {
+		req.SAN = &SAN{
+			DNSNames:    cert.DNSNames,
+			Emails:      cert.EmailAddresses,
+			IPAddresses: cert.IPAddresses,
+			URIs:        cert.URIs,
+		}
+	}
+
+	return req, nil
+}
+
+// certificateEKUs returns the OIDs in the extended key usage extension of a
+// certificate, or nil if it has no such extension.
+func certificateEKUs(cert *x509.Certificate) ([]asn1.ObjectIdentifier, error) {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oids.OIDExtendedKeyUsage) {
+			continue
+		}
+
+		var ekus []asn1.ObjectIdentifier
+		if rest, err := asn1.Unmarshal(ext.Value, &ekus); err != nil {
+			return nil, fmt.Errorf("invalid extended key usage extension: %w", err)
+		} else if len(rest) != 0 {
+			return nil, errors.New("trailing data after extended key usage extension")
+		}
+
+		return ekus, nil
+	}
+
+	return nil, nil
+}
+
+// dnFromPKIXName converts a pkix.Name, such as the subject of a parsed
+// certificate, to a DN. Attributes with no corresponding DN field are
+// added to the extra attributes.
+func dnFromPKIXName(name pkix.Name) *DN {
+	var dn = &DN{}
+
+	for _, atv := range name.Names {
+		var value, ok = atv.Value.(string)
+		if !ok {
+			continue
+		}
+
+		var oid = atv.Type
+
+		switch {
+		case oid.Equal(oids.OIDSubjectCountry):
+			dn.Country = value
+		case oid.Equal(oids.OIDSubjectState):
+			dn.State = value
+		case oid.Equal(oids.OIDSubjectLocality):
+			dn.Locality = value
+		case oid.Equal(oids.OIDSubjectStreetAddress):
+			dn.StreetAddress = value
+		case oid.Equal(oids.OIDSubjectOrganization):
+			dn.Organization = value
+		case oid.Equal(oids.OIDSubjectOrganizationalUnit):
+			dn.OrganizationalUnit = append(dn.OrganizationalUnit, value)
+		case oid.Equal(oids.OIDSubjectCommonName):
+			dn.CommonName = value
+		case oid.Equal(oids.OIDSubjectSerialNumber):
+			dn.SerialNumber = value
+		case oid.Equal(oids.OIDSubjectEmail):
+			dn.Email = value
+		case oid.Equal(oids.OIDSubjectJOILocality):
+			dn.JOILocality = value
+		case oid.Equal(oids.OIDSubjectJOIState):
+			dn.JOIState = value
+		case oid.Equal(oids.OIDSubjectJOICountry):
+			dn.JOICountry = value
+		case oid.Equal(oids.OIDSubjectBusinessCategory):
+			dn.BusinessCategory = value
+		default:
+			dn.ExtraAttributes = append(dn.ExtraAttributes, OIDAndString{OID: oid, Value: value})
+		}
+	}
+
+	return dn
+}
@@ -0,0 +1,164 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/oids"
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+func TestNewRekeyRequest(t *testing.T) {
+	t.Parallel()
+
+	var cert, err = pki.CertFromFile("testdata/test_cert.pem")
+	if err != nil {
+		t.Fatalf("couldn't read certificate: %v", err)
+	}
+
+	var csr *x509.CertificateRequest
+	if csr, err = pki.CSRFromFile("testdata/test_csr.pem"); err != nil {
+		t.Fatalf("couldn't read CSR: %v", err)
+	}
+
+	var now = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var got *Request
+	if got, err = newRekeyRequest(cert, &CertificateRekeyRequest{CSR: csr}, now); err != nil {
+		t.Fatalf("couldn't build rekey request: %v", err)
+	}
+
+	var want = Request{
+		Validity: &Validity{
+			NotBefore: now,
+			NotAfter:  now.Add(time.Hour * 24 * 90),
+		},
+		Subject: &DN{CommonName: "John Doe"},
+		EKUs:    []asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 2}},
+		CSR:     csr,
+	}
+
+	if !got.Equal(want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestNewRekeyRequestFailure(t *testing.T) {
+	t.Parallel()
+
+	var cert, err = pki.CertFromFile("testdata/test_cert.pem")
+	if err != nil {
+		t.Fatalf("couldn't read certificate: %v", err)
+	}
+
+	var key interface{}
+	if key, err = pki.PublicKeyFromFile("testdata/rsa_pub.key"); err != nil {
+		t.Fatalf("couldn't read public key: %v", err)
+	}
+
+	var testcases = []struct {
+		name  string
+		cert  *x509.Certificate
+		rekey *CertificateRekeyRequest
+	}{
+		{
+			name: "NoRequest",
+			cert: cert,
+		},
+		{
+			name:  "NoKey",
+			cert:  cert,
+			rekey: &CertificateRekeyRequest{},
+		},
+		{
+			name: "TwoKeys",
+			cert: cert,
+			rekey: &CertificateRekeyRequest{
+				PublicKey: key,
+				CSR:       &x509.CertificateRequest{},
+			},
+		},
+		{
+			name:  "NoCertificate",
+			rekey: &CertificateRekeyRequest{PublicKey: key},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := newRekeyRequest(tc.cert, tc.rekey, time.Now()); err == nil {
+				t.Fatalf("unexpectedly built rekey request")
+			}
+		})
+	}
+}
+
+func TestDNFromPKIXName(t *testing.T) {
+	t.Parallel()
+
+	var name = pkix.Name{
+		Names: []pkix.AttributeTypeAndValue{
+			{Type: oids.OIDSubjectCountry, Value: "GB"},
+			{Type: oids.OIDSubjectState, Value: "London"},
+			{Type: oids.OIDSubjectLocality, Value: "London"},
+			{Type: oids.OIDSubjectStreetAddress, Value: "1 GlobalSign Road"},
+			{Type: oids.OIDSubjectOrganization, Value: "GMO GlobalSign"},
+			{Type: oids.OIDSubjectOrganizationalUnit, Value: "Operations"},
+			{Type: oids.OIDSubjectOrganizationalUnit, Value: "Sales"},
+			{Type: oids.OIDSubjectCommonName, Value: "John Doe"},
+			{Type: oids.OIDSubjectSerialNumber, Value: "1234"},
+			{Type: oids.OIDSubjectEmail, Value: "john.doe@example.com"},
+			{Type: oids.OIDSubjectJOILocality, Value: "Dublin"},
+			{Type: oids.OIDSubjectJOIState, Value: "Leinster"},
+			{Type: oids.OIDSubjectJOICountry, Value: "IE"},
+			{Type: oids.OIDSubjectBusinessCategory, Value: "Private Organization"},
+			{Type: asn1.ObjectIdentifier{2, 5, 4, 4}, Value: "Doe"},
+			{Type: asn1.ObjectIdentifier{2, 5, 4, 42}, Value: 42},
+		},
+	}
+
+	var want = DN{
+		Country:            "GB",
+		State:              "London",
+		Locality:           "London",
+		StreetAddress:      "1 GlobalSign Road",
+		Organization:       "GMO GlobalSign",
+		OrganizationalUnit: []string{"Operations", "Sales"},
+		CommonName:         "John Doe",
+		SerialNumber:       "1234",
+		Email:              "john.doe@example.com",
+		JOILocality:        "Dublin",
+		JOIState:           "Leinster",
+		JOICountry:         "IE",
+		BusinessCategory:   "Private Organization",
+		ExtraAttributes: []OIDAndString{
+			{OID: asn1.ObjectIdentifier{2, 5, 4, 4}, Value: "Doe"},
+		},
+	}
+
+	if got := dnFromPKIXName(name); !got.Equal(&want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
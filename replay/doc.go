@@ -0,0 +1,56 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package replay provides an HTTP transport which records HVCA API
+interactions to a fixture file and replays them, so that code which uses
+hvclient can be tested deterministically, for example in CI, without live
+HVCA credentials.
+
+In Record mode, a Transport passes each request to the underlying transport,
+usually the one built by hvclient from the connection settings in the
+configuration, and records the request and its response. Save writes the
+recorded interactions to the fixture file, with API keys, API secrets and
+authentication tokens redacted, so the file may be committed alongside the
+tests. In Replay mode, a Transport answers each request with the first
+unused recorded interaction with the same method and path, without making
+any network connections:
+
+	var tnspt, err = replay.New("testdata/issue.json", replay.ModeFromEnv("HVCLIENT_RECORD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var clnt *hvclient.Client
+	if clnt, err = hvclient.New(ctx,
+		hvclient.WithConfig(conf),
+		hvclient.WithTransportWrapper(tnspt.Wrap),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// Exercise code which uses clnt.
+
+	if err = tnspt.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+Query strings and request bodies are recorded for reference but are not
+matched on replay, since they often contain the current time. Since
+credentials are redacted, the configuration used to replay a fixture file
+need only contain placeholder credentials, together with the same URL used
+to record it.
+*/
+package replay
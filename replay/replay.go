@@ -0,0 +1,359 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/globalsign/hvclient/internal/fileutils"
+	"github.com/globalsign/hvclient/internal/httputils"
+)
+
+// Mode is the mode of operation of a Transport.
+type Mode int
+
+// Mode values.
+const (
+	// Replay answers requests with recorded interactions read from the
+	// fixture file, without making any network connections.
+	Replay Mode = iota
+
+	// Record passes requests to the underlying transport, and records
+	// them and their responses to be written to the fixture file.
+	Record
+)
+
+// ErrNoInteraction is wrapped by the error returned in Replay mode when no
+// unused recorded interaction matches a request.
+var ErrNoInteraction = errors.New("no recorded interaction matches request")
+
+// Interaction is a recorded HTTP request and its response.
+type Interaction struct {
+	Method       string      `json:"method"`
+	Path         string      `json:"path"`
+	Query        string      `json:"query,omitempty"`
+	RequestBody  string      `json:"request_body,omitempty"`
+	Status       int         `json:"status"`
+	Header       http.Header `json:"header,omitempty"`
+	ResponseBody string      `json:"response_body,omitempty"`
+}
+
+// fixture is the format of a fixture file.
+type fixture struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Transport is an http.RoundTripper which records or replays HTTP
+// interactions. A transport is safe for concurrent use, but interactions
+// recorded concurrently are replayed in the order in which they completed.
+type Transport struct {
+	path         string
+	mode         Mode
+	next         http.RoundTripper
+	mtx          sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// redacted replaces sensitive values in recorded interactions.
+const redacted = "REDACTED"
+
+// redactedFields are the names of JSON object fields in request and
+// response bodies whose values are redacted in recorded interactions.
+var redactedFields = []string{"api_key", "api_secret", "access_token"}
+
+// omittedHeaders are the response headers which are not recorded, either
+// because they are sensitive or because they vary between otherwise
+// identical responses.
+var omittedHeaders = []string{"Content-Length", "Date", "Set-Cookie"}
+
+// New returns a transport which records interactions to, or replays them
+// from, the specified fixture file. In Replay mode the fixture file is read
+// immediately, and an error is returned if it cannot be read.
+func New(path string, mode Mode) (*Transport, error) {
+	var t = &Transport{
+		path: path,
+		mode: mode,
+	}
+
+	if mode != Replay {
+		return t, nil
+	}
+
+	var data, err = ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read fixture file: %w", err)
+	}
+
+	var f fixture
+	if err = json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("couldn't parse fixture file %s: %w", path, err)
+	}
+
+	t.interactions = f.Interactions
+	t.used = make([]bool, len(f.Interactions))
+
+	return t, nil
+}
+
+// ModeFromEnv returns Record if the named environment variable is set to a
+// true value as understood by strconv.ParseBool, and Replay otherwise, so
+// that fixture files may be refreshed by setting the variable while tests
+// run in Replay mode by default.
+func ModeFromEnv(name string) Mode {
+	if record, err := strconv.ParseBool(os.Getenv(name)); err == nil && record {
+		return Record
+	}
+
+	return Replay
+}
+
+// Mode returns the mode of the transport.
+func (t *Transport) Mode() Mode {
+	return t.mode
+}
+
+// Wrap sets the underlying transport to which requests are passed in Record
+// mode, and returns the transport itself. It has the signature expected by
+// hvclient.WithTransportWrapper, so that the transport built by hvclient,
+// including any mutual TLS settings, is used to record interactions. If Wrap
+// is not called, http.DefaultTransport is used.
+func (t *Transport) Wrap(next http.RoundTripper) http.RoundTripper {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.next = next
+
+	return t
+}
+
+// RoundTrip records or replays a single HTTP interaction.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body, err = readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.mode == Replay {
+		return t.replay(req)
+	}
+
+	return t.record(req, body)
+}
+
+// Interactions returns a copy of the recorded interactions, with sensitive
+// values redacted.
+func (t *Transport) Interactions() []Interaction {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	return append([]Interaction(nil), t.interactions...)
+}
+
+// Save writes the recorded interactions to the fixture file in Record mode.
+// It does nothing in Replay mode.
+func (t *Transport) Save() error {
+	if t.mode == Replay {
+		return nil
+	}
+
+	var data, err = json.MarshalIndent(fixture{Interactions: t.Interactions()}, "", "    ")
+	if err != nil {
+		return fmt.Errorf("couldn't encode fixture file: %w", err)
+	}
+
+	if err = fileutils.WriteFile(t.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("couldn't write fixture file: %w", err)
+	}
+
+	return nil
+}
+
+// Unused returns the recorded interactions which have not been replayed,
+// which may be used to check that code under test made all the requests
+// it made when the fixture file was recorded.
+func (t *Transport) Unused() []Interaction {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	var unused []Interaction
+	for i, interaction := range t.interactions {
+		if !t.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+
+	return unused
+}
+
+// record passes a request to the underlying transport and records it and
+// its response.
+func (t *Transport) record(req *http.Request, body []byte) (*http.Response, error) {
+	t.mtx.Lock()
+	var next = t.next
+	t.mtx.Unlock()
+
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	var resp, err = next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var respBody []byte
+	respBody, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read response body: %w", err)
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	var header = resp.Header.Clone()
+	for _, name := range omittedHeaders {
+		header.Del(name)
+	}
+
+	var interaction = Interaction{
+		Method:       req.Method,
+		Path:         req.URL.Path,
+		Query:        req.URL.RawQuery,
+		RequestBody:  string(redactBody(body)),
+		Status:       resp.StatusCode,
+		Header:       header,
+		ResponseBody: string(redactBody(respBody)),
+	}
+
+	t.mtx.Lock()
+	t.interactions = append(t.interactions, interaction)
+	t.used = append(t.used, true)
+	t.mtx.Unlock()
+
+	return resp, nil
+}
+
+// replay answers a request with the first unused recorded interaction with
+// the same method and path.
+func (t *Transport) replay(req *http.Request) (*http.Response, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for i, interaction := range t.interactions {
+		if t.used[i] || !strings.EqualFold(interaction.Method, req.Method) || interaction.Path != req.URL.Path {
+			continue
+		}
+
+		t.used[i] = true
+
+		var header = make(http.Header, len(interaction.Header))
+		for name, values := range interaction.Header {
+			header[name] = append([]string(nil), values...)
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+			StatusCode:    interaction.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(interaction.ResponseBody)),
+			ContentLength: int64(len(interaction.ResponseBody)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL.Path)
+}
+
+// readRequestBody reads and closes the body of a request, if any, and
+// replaces it so that it may be read again. A gzip-compressed body is
+// returned decompressed.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	var body, err = ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read request body: %w", err)
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if req.Header.Get(httputils.ContentEncodingHeader) != httputils.ContentEncodingGzip {
+		return body, nil
+	}
+
+	var r *gzip.Reader
+	if r, err = gzip.NewReader(bytes.NewReader(body)); err != nil {
+		return nil, fmt.Errorf("couldn't decompress request body: %w", err)
+	}
+
+	var decompressed []byte
+	if decompressed, err = ioutil.ReadAll(io.LimitReader(r, maxDecompressedSize)); err != nil {
+		return nil, fmt.Errorf("couldn't decompress request body: %w", err)
+	}
+
+	return decompressed, nil
+}
+
+// maxDecompressedSize is the maximum size of a decompressed request body
+// which will be recorded.
+const maxDecompressedSize = 16 * 1024 * 1024
+
+// redactBody returns a copy of a body with the values of any sensitive
+// top-level JSON object fields redacted. Bodies which are not JSON objects,
+// or which contain no sensitive fields, are returned unchanged.
+func redactBody(body []byte) []byte {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return body
+	}
+
+	var changed bool
+	for _, field := range redactedFields {
+		if _, ok := obj[field]; ok {
+			obj[field] = json.RawMessage(`"` + redacted + `"`)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return body
+	}
+
+	var data, err = json.Marshal(obj)
+	if err != nil {
+		return body
+	}
+
+	return data
+}
@@ -0,0 +1,161 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+	"github.com/globalsign/hvclient/replay"
+)
+
+// newReplayClient returns a client of the mock HVCA server at the specified
+// URL which uses the specified transport.
+func newReplayClient(ctx context.Context, t *testing.T, url string, tnspt *replay.Transport) *hvclient.Client {
+	t.Helper()
+
+	var clnt, err = hvclient.New(ctx,
+		hvclient.WithConfig(hvcatest.Config(url)),
+		hvclient.WithTransportWrapper(tnspt.Wrap),
+	)
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+
+	return clnt
+}
+
+func TestRecordReplay(t *testing.T) {
+	t.Parallel()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var path = filepath.Join(t.TempDir(), "fixture.json")
+	var server = hvcatest.NewServer()
+
+	var recorder, err = replay.New(path, replay.Record)
+	if err != nil {
+		t.Fatalf("couldn't create recording transport: %v", err)
+	}
+
+	var clnt = newReplayClient(ctx, t, server.URL, recorder)
+
+	var wantQuota int64
+	if wantQuota, err = clnt.QuotaIssuance(ctx); err != nil {
+		t.Fatalf("couldn't get quota: %v", err)
+	}
+
+	var wantInfo *hvclient.CertInfo
+	if wantInfo, err = clnt.CertificateRetrieve(ctx, hvcatest.Cert.SerialNumber); err != nil {
+		t.Fatalf("couldn't retrieve certificate: %v", err)
+	}
+
+	if err = recorder.Save(); err != nil {
+		t.Fatalf("couldn't save fixture file: %v", err)
+	}
+
+	server.Close()
+
+	var data []byte
+	if data, err = ioutil.ReadFile(path); err != nil {
+		t.Fatalf("couldn't read fixture file: %v", err)
+	}
+
+	for _, secret := range []string{hvcatest.APIKey, hvcatest.APISecret, hvcatest.Token} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("fixture file contains secret %q", secret)
+		}
+	}
+
+	var player *replay.Transport
+	if player, err = replay.New(path, replay.Replay); err != nil {
+		t.Fatalf("couldn't create replaying transport: %v", err)
+	}
+
+	clnt = newReplayClient(ctx, t, server.URL, player)
+
+	var quota int64
+	if quota, err = clnt.QuotaIssuance(ctx); err != nil {
+		t.Fatalf("couldn't get quota: %v", err)
+	} else if quota != wantQuota {
+		t.Errorf("got quota %d, want %d", quota, wantQuota)
+	}
+
+	var info *hvclient.CertInfo
+	if info, err = clnt.CertificateRetrieve(ctx, hvcatest.Cert.SerialNumber); err != nil {
+		t.Fatalf("couldn't retrieve certificate: %v", err)
+	} else if !info.Equal(*wantInfo) {
+		t.Errorf("got %v, want %v", info, wantInfo)
+	}
+
+	if unused := player.Unused(); len(unused) != 0 {
+		t.Errorf("got %d unused interactions, want none", len(unused))
+	}
+
+	if _, err = clnt.QuotaIssuance(ctx); !errors.Is(err, replay.ErrNoInteraction) {
+		t.Errorf("got error %v, want %v", err, replay.ErrNoInteraction)
+	}
+}
+
+func TestNewFailure(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+	var malformed = filepath.Join(dir, "malformed.json")
+	if err := ioutil.WriteFile(malformed, []byte(`{"interactions":`), 0644); err != nil {
+		t.Fatalf("couldn't write file: %v", err)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing.json"), malformed} {
+		if _, err := replay.New(path, replay.Replay); err == nil {
+			t.Errorf("%s: unexpectedly created replaying transport", path)
+		}
+	}
+
+	if _, err := replay.New(filepath.Join(dir, "missing.json"), replay.Record); err != nil {
+		t.Errorf("couldn't create recording transport: %v", err)
+	}
+}
+
+func TestModeFromEnv(t *testing.T) {
+	var testcases = []struct {
+		value string
+		want  replay.Mode
+	}{
+		{"", replay.Replay},
+		{"0", replay.Replay},
+		{"false", replay.Replay},
+		{"maybe", replay.Replay},
+		{"1", replay.Record},
+		{"true", replay.Record},
+	}
+
+	for _, tc := range testcases {
+		t.Setenv("HVCLIENT_RECORD_TEST", tc.value)
+
+		if got := replay.ModeFromEnv("HVCLIENT_RECORD_TEST"); got != tc.want {
+			t.Errorf("%q: got %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package hvclient
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/rand"
@@ -32,12 +33,18 @@ import (
 	"net"
 	"net/url"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/globalsign/hvclient/internal/oids"
 	"github.com/globalsign/hvclient/internal/pki"
 )
 
+// ErrInvalidPublicKeySignature is returned, possibly wrapped, when encoding a
+// request whose PublicKeySignature is not a valid signature of its public
+// key.
+var ErrInvalidPublicKeySignature = errors.New("public key signature does not match public key")
+
 // Request is a request to HVCA for the issuance of a new certificate.
 //
 // An HVCA account will be set up with one of three options regarding
@@ -53,33 +60,63 @@ import (
 // For case 1, simply assign the public key in question to the PublicKey field
 // of the Request. For case 2, leave the PublicKey field empty and assign the
 // private key to the PrivateKey field of the Request, and the public key will
-// be automatically extracted and the appropriate signature generated. For case
+// be automatically extracted and the appropriate signature generated.
+// Alternatively, for case 2, when the private key is held elsewhere such as
+// in a hardware security module, assign the public key to the PublicKey field
+// and the base64-encoded signature of the digest returned for it by
+// PublicKeySignatureDigest to the PublicKeySignature field, and the signature
+// will be checked against the public key before the request is submitted. For case
 // 3, leave both the PublicKey and PrivateKey fields empty and assign the
 // PKCS#10 certificate signed request to the CSR field. Note that when providing
 // a PKCS#10 certificate signing request, none of the fields in the CSR are
 // examined by HVCA except for the public key and the signature, and none of
-// the fields in the CSR are automatically copied to the Request object.
+// the fields in the CSR are automatically copied to the Request object. In
+// cases 1 and 2, a key in JSON Web Key format may be assigned as a JWK.
+//
+// Extra contains additional top-level fields to include in the JSON encoding
+// of the request, keyed by field name, which allows fields supported by an
+// HVCA deployment but not yet modelled by this package to be used. When a
+// request is unmarshalled from JSON, any unrecognized top-level fields are
+// stored in Extra. A field in Extra may not have the same name as one of the
+// standard fields.
+//
+// CustomExtensionValues contains custom extensions with typed values, for
+// extensions whose value type in the validation policy is INTEGER or DER.
+// When encoded to JSON, they are appended to the custom extensions, so they
+// are always decoded as OIDAndString values in CustomExtensions.
 type Request struct {
-	Validity            *Validity
-	Subject             *DN
-	SAN                 *SAN
-	EKUs                []asn1.ObjectIdentifier
-	DA                  *DA
-	QualifiedStatements *QualifiedStatements
-	MSExtension         *MSExtension
-	CustomExtensions    []OIDAndString
-	Signature           *Signature
-	CSR                 *x509.CertificateRequest
-	PrivateKey          interface{}
-	PublicKey           interface{}
+	Validity              *Validity
+	Subject               *DN
+	SAN                   *SAN
+	EKUs                  []asn1.ObjectIdentifier
+	DA                    *DA
+	QualifiedStatements   *QualifiedStatements
+	MSExtension           *MSExtension
+	CustomExtensions      []OIDAndString
+	CustomExtensionValues []CustomExtensionValue
+	Signature             *Signature
+	CSR                   *x509.CertificateRequest
+	PrivateKey            interface{}
+	PublicKey             interface{}
+	PublicKeySignature    string
+	Extra                 map[string]json.RawMessage
 }
 
 // Validity contains the requested not-before and not-after times for a
 // certificate. If NotAfter is set to time.Unix(0, 0), the maximum duration
 // allowed by the validation policy will be applied.
+//
+// When unmarshalled from JSON, each time may be either an integer number of
+// seconds since the Unix epoch, as used by HVCA, or an RFC 3339 string.
 type Validity struct {
 	NotBefore time.Time
 	NotAfter  time.Time
+
+	// RFC3339 causes the times to be encoded as RFC 3339 strings rather than
+	// as seconds since the Unix epoch when marshalled to JSON. HVCA accepts
+	// only the latter, so this should be set only when writing request
+	// templates or other files intended to be read by humans.
+	RFC3339 bool
 }
 
 // DN is a list of Distinguished Name attributes to include in a
@@ -99,6 +136,12 @@ type DN struct {
 	JOICountry         string         `json:"jurisdiction_of_incorporation_country_name,omitempty"`
 	BusinessCategory   string         `json:"business_category,omitempty"`
 	ExtraAttributes    []OIDAndString `json:"extra_attributes,omitempty"`
+
+	// ExtraAttributeValues contains extra attributes which have more than
+	// one value, or which have values which are not strings. When encoded
+	// to JSON, they are appended to the extra attributes, so they are
+	// always decoded as OIDAndString values.
+	ExtraAttributeValues []OIDAndValues `json:"-"`
 }
 
 // OIDAndString is an ASN.1 object identifier (OID) together with an
@@ -108,14 +151,39 @@ type OIDAndString struct {
 	Value string
 }
 
+// OIDAndValues is an ASN.1 object identifier (OID) together with one or more
+// associated values. Each value must be a string, an int, an int64, a
+// *big.Int, or an asn1.RawValue containing a complete DER encoding in its
+// FullBytes field. In HVCA requests, integers are represented as decimal
+// strings and DER encodings as base64 strings.
+type OIDAndValues struct {
+	OID    asn1.ObjectIdentifier
+	Values []interface{}
+}
+
+// CustomExtensionValue is a custom extension OID together with a typed
+// value. The value must be a string, an int, an int64, a *big.Int, or an
+// asn1.RawValue containing a complete DER encoding in its FullBytes field.
+// In HVCA requests, integers are represented as decimal strings and DER
+// encodings as base64 strings, as produced by NewOIDAndDER.
+type CustomExtensionValue struct {
+	OID   asn1.ObjectIdentifier
+	Value interface{}
+}
+
 // SAN is a list of Subject Alternative Name attributes to include in a
-// certificate. See RFC 5280 4.2.1.6.
+// certificate. See RFC 5280 4.2.1.6. DNS names may contain Unicode
+// characters, and are converted to A-labels (punycode) when the request is
+// marshalled or encoded in a PKCS#10 certificate signing request, leaving
+// the names in the SAN itself unchanged.
 type SAN struct {
-	DNSNames    []string
-	Emails      []string
-	IPAddresses []net.IP
-	URIs        []*url.URL
-	OtherNames  []OIDAndString
+	DNSNames       []string
+	Emails         []string
+	IPAddresses    []net.IP
+	URIs           []*url.URL
+	OtherNames     []OIDAndString
+	RegisteredIDs  []asn1.ObjectIdentifier
+	DirectoryNames []DN
 }
 
 // DA is a list of Subject Directory Attributes to include in a
@@ -138,8 +206,33 @@ type QualifiedStatements struct {
 	QCType            asn1.ObjectIdentifier
 	QCRetentionPeriod int
 	QCPDs             map[string]string
+	PSD2              *PSD2
 }
 
+// PSD2 contains the values of an ETSI PSD2 qualified certificate statement,
+// as used in certificates issued to payment service providers under the EU
+// Payment Services Directive. See ETSI TS 119 495.
+type PSD2 struct {
+	RolesOfPSP []PSPRole
+	NCAName    string
+	NCAID      string
+}
+
+// PSPRole is the role of a payment service provider in a PSD2 qualified
+// certificate statement.
+type PSPRole struct {
+	OID  asn1.ObjectIdentifier
+	Name string
+}
+
+// Payment service provider roles defined in ETSI TS 119 495.
+var (
+	PSPRoleAccountServicing    = PSPRole{OID: asn1.ObjectIdentifier{0, 4, 0, 19495, 1, 1}, Name: "PSP_AS"}
+	PSPRolePaymentInitiation   = PSPRole{OID: asn1.ObjectIdentifier{0, 4, 0, 19495, 1, 2}, Name: "PSP_PI"}
+	PSPRoleAccountInformation  = PSPRole{OID: asn1.ObjectIdentifier{0, 4, 0, 19495, 1, 3}, Name: "PSP_AI"}
+	PSPRoleIssuingCardBasedPay = PSPRole{OID: asn1.ObjectIdentifier{0, 4, 0, 19495, 1, 4}, Name: "PSP_IC"}
+)
+
 // Semantics is the OID and optional name authorities for a qualified
 // certificate statement. See RFC 3739 3.2.6.1.
 type Semantics struct {
@@ -176,6 +269,22 @@ type jsonRequest struct {
 	PublicKeySignature  string               `json:"public_key_signature,omitempty"`
 }
 
+// requestJSONFields contains the names of the top-level fields in the JSON
+// encoding of a certificate request which are modelled by jsonRequest.
+var requestJSONFields = map[string]bool{
+	"validity":              true,
+	"subject_dn":            true,
+	"san":                   true,
+	"extended_key_usages":   true,
+	"subject_da":            true,
+	"qualified_statements":  true,
+	"ms_extension_template": true,
+	"custom_extensions":     true,
+	"signature":             true,
+	"public_key":            true,
+	"public_key_signature":  true,
+}
+
 // jsonOID is used internally for JSON marshalling/unmarshalling of
 // asn1.ObjectIdentifier values.
 type jsonOID asn1.ObjectIdentifier
@@ -186,13 +295,29 @@ type jsonValidity struct {
 	NotAfter  int64 `json:"not_after"`
 }
 
+// jsonValidityRFC3339 is used internally for JSON marshalling validity
+// objects with RFC 3339 times.
+type jsonValidityRFC3339 struct {
+	NotBefore string `json:"not_before"`
+	NotAfter  string `json:"not_after"`
+}
+
+// jsonValidityRaw is used internally for JSON unmarshalling validity
+// objects, the times of which may be either integers or strings.
+type jsonValidityRaw struct {
+	NotBefore json.RawMessage `json:"not_before"`
+	NotAfter  json.RawMessage `json:"not_after"`
+}
+
 // jsonSAN is used internally for JSON marshalling/unmarshalling.
 type jsonSAN struct {
-	DNSNames    []string       `json:"dns_names,omitempty"`
-	Emails      []string       `json:"emails,omitempty"`
-	IPAddresses []string       `json:"ip_addresses,omitempty"`
-	URIs        []string       `json:"uris,omitempty"`
-	OtherNames  []OIDAndString `json:"other_names,omitempty"`
+	DNSNames       []string       `json:"dns_names,omitempty"`
+	Emails         []string       `json:"emails,omitempty"`
+	IPAddresses    []string       `json:"ip_addresses,omitempty"`
+	URIs           []string       `json:"uris,omitempty"`
+	OtherNames     []OIDAndString `json:"other_names,omitempty"`
+	RegisteredIDs  []jsonOID      `json:"registered_ids,omitempty"`
+	DirectoryNames []DN           `json:"directory_names,omitempty"`
 }
 
 // jsonOIDAndString is used internally for JSON marshalling/unmarshalling.
@@ -219,6 +344,20 @@ type jsonQS struct {
 	QCType            jsonOID         `json:"etsi_qc_type,omitempty"`
 	QCRetentionPeriod int             `json:"etsi_qc_retention_period"`
 	QCPDs             json.RawMessage `json:"etsi_qc_pds,omitempty"`
+	PSD2              *jsonPSD2       `json:"etsi_psd2,omitempty"`
+}
+
+// jsonPSD2 is used internally for JSON marshalling/unmarshalling.
+type jsonPSD2 struct {
+	RolesOfPSP []jsonPSPRole `json:"roles_of_psp"`
+	NCAName    string        `json:"nca_name"`
+	NCAID      string        `json:"nca_id"`
+}
+
+// jsonPSPRole is used internally for JSON marshalling/unmarshalling.
+type jsonPSPRole struct {
+	OID  jsonOID `json:"oid"`
+	Name string  `json:"name"`
 }
 
 // jsonSemantics is used internally for JSON marshalling/unmarshalling.
@@ -261,8 +400,30 @@ func (r Request) Equal(other Request) bool {
 		}
 	}
 
+	if len(r.CustomExtensionValues) != len(other.CustomExtensionValues) {
+		return false
+	}
+
+	for i := range r.CustomExtensionValues {
+		if !r.CustomExtensionValues[i].Equal(other.CustomExtensionValues[i]) {
+			return false
+		}
+	}
+
+	// Check for equality of extra fields.
+	if len(r.Extra) != len(other.Extra) {
+		return false
+	}
+
+	for key, value := range r.Extra {
+		if otherValue, ok := other.Extra[key]; !ok || !rawJSONEqual(value, otherValue) {
+			return false
+		}
+	}
+
 	// Check for equality of other fields.
-	return r.Validity.Equal(other.Validity) &&
+	return r.PublicKeySignature == other.PublicKeySignature &&
+		r.Validity.Equal(other.Validity) &&
 		r.Subject.Equal(other.Subject) &&
 		r.SAN.Equal(other.SAN) &&
 		r.DA.Equal(other.DA) &&
@@ -272,23 +433,24 @@ func (r Request) Equal(other Request) bool {
 
 // MarshalJSON returns the JSON encoding of a certificate request.
 func (r Request) MarshalJSON() ([]byte, error) {
-	// Marshal the custom extensions if any are present.
+	// Marshal the custom extensions if any are present, appending those
+	// with typed values to those with string values.
 	var raw json.RawMessage
-	if len(r.CustomExtensions) > 0 {
-		raw = json.RawMessage("{")
-
-		for i, ext := range r.CustomExtensions {
-			var item string
+	if len(r.CustomExtensions) > 0 || len(r.CustomExtensionValues) > 0 {
+		var exts = append([]OIDAndString(nil), r.CustomExtensions...)
 
-			if i+1 == len(r.CustomExtensions) {
-				// Close object encoding if this is the last extension.
-				item = fmt.Sprintf(`"%s":"%s"}`, ext.OID.String(), ext.Value)
-			} else {
-				// Otherwise add a trailing comma.
-				item = fmt.Sprintf(`"%s":"%s",`, ext.OID.String(), ext.Value)
+		for _, ext := range r.CustomExtensionValues {
+			var str, err = ext.OIDAndString()
+			if err != nil {
+				return nil, err
 			}
 
-			raw = append(raw, []byte(item)...)
+			exts = append(exts, str)
+		}
+
+		var err error
+		if raw, err = marshalCustomExtensions(exts); err != nil {
+			return nil, err
 		}
 	}
 
@@ -301,35 +463,57 @@ func (r Request) MarshalJSON() ([]byte, error) {
 	// Convert PKCS#10 certificate request, if present.
 	var publicKey string
 	var publicKeySig string
-	var err error
+
+	var pubKey, privKey, err = r.requestKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.PublicKeySignature != "" && pubKey == nil {
+		return nil, errors.New("public key signature provided without public key")
+	}
 
 	switch {
-	case r.PublicKey != nil:
-		switch k := r.PublicKey.(type) {
+	case pubKey != nil:
+		var pubKeyBytes []byte
+
+		switch k := pubKey.(type) {
+		case redactedKey:
+			publicKey = k.String()
 		case rsa.PublicKey:
-			if _, publicKey, err = publicKeyBytesAndString(&k); err != nil {
+			if pubKeyBytes, publicKey, err = publicKeyBytesAndString(&k); err != nil {
 				return nil, err
 			}
 		case *rsa.PublicKey:
-			if _, publicKey, err = publicKeyBytesAndString(k); err != nil {
+			if pubKeyBytes, publicKey, err = publicKeyBytesAndString(k); err != nil {
 				return nil, err
 			}
 		case ecdsa.PublicKey:
-			if _, publicKey, err = publicKeyBytesAndString(&k); err != nil {
+			if pubKeyBytes, publicKey, err = publicKeyBytesAndString(&k); err != nil {
 				return nil, err
 			}
 		case *ecdsa.PublicKey:
-			if _, publicKey, err = publicKeyBytesAndString(k); err != nil {
+			if pubKeyBytes, publicKey, err = publicKeyBytesAndString(k); err != nil {
 				return nil, err
 			}
 		default:
-			if _, publicKey, err = publicKeyBytesAndString(k); err != nil {
+			if pubKeyBytes, publicKey, err = publicKeyBytesAndString(k); err != nil {
+				return nil, err
+			}
+		}
+
+		// Use a precomputed signature only if it matches the public key,
+		// since HVCA would otherwise reject the request.
+		if r.PublicKeySignature != "" {
+			if err = verifyPublicKeySignature(pubKeyBytes, r.PublicKeySignature); err != nil {
 				return nil, err
 			}
+
+			publicKeySig = r.PublicKeySignature
 		}
 
-	case r.PrivateKey != nil:
-		switch k := r.PrivateKey.(type) {
+	case privKey != nil:
+		switch k := privKey.(type) {
 		case *rsa.PrivateKey:
 			var pubKeyBytes []byte
 			var err error
@@ -383,7 +567,8 @@ func (r Request) MarshalJSON() ([]byte, error) {
 
 	}
 
-	return json.Marshal(jsonRequest{
+	var data []byte
+	if data, err = json.Marshal(jsonRequest{
 		Validity:            r.Validity,
 		Subject:             r.Subject,
 		SAN:                 r.SAN,
@@ -395,13 +580,67 @@ func (r Request) MarshalJSON() ([]byte, error) {
 		Signature:           r.Signature,
 		PublicKey:           publicKey,
 		PublicKeySignature:  publicKeySig,
-	})
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(r.Extra) == 0 {
+		return data, nil
+	}
+
+	// Merge in any extra fields.
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for key, value := range r.Extra {
+		if requestJSONFields[key] {
+			return nil, fmt.Errorf("extra field %q conflicts with a standard request field", key)
+		}
+
+		fields[key] = value
+	}
+
+	return json.Marshal(fields)
+}
+
+// marshalCustomExtensions returns the JSON encoding of a list of custom
+// extensions as an object mapping OIDs to values, preserving the order of
+// the list.
+func marshalCustomExtensions(exts []OIDAndString) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+
+	for i, ext := range exts {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		var key, err = json.Marshal(ext.OID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		var value []byte
+		if value, err = json.Marshal(ext.Value); err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
 }
 
 // UnmarshalJSON parses a JSON-encoded certificate request and stores the
 // result in the object.
 func (r *Request) UnmarshalJSON(b []byte) error {
-	var jsonreq *jsonRequest
+	var jsonreq jsonRequest
 	var err = json.Unmarshal(b, &jsonreq)
 	if err != nil {
 		return err
@@ -460,19 +699,58 @@ func (r *Request) UnmarshalJSON(b []byte) error {
 		Signature:           jsonreq.Signature,
 	}
 
+	// Capture any unrecognized fields.
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+
+	for key, value := range fields {
+		if requestJSONFields[key] {
+			continue
+		}
+
+		if r.Extra == nil {
+			r.Extra = make(map[string]json.RawMessage)
+		}
+
+		r.Extra[key] = value
+	}
+
 	return nil
 }
 
+// rawJSONEqual checks if two JSON values are equivalent, disregarding any
+// insignificant whitespace.
+func rawJSONEqual(a, b json.RawMessage) bool {
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return bytes.Equal(a, b)
+	}
+
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
+
 // PKCS10 converts a Request object into a PKCS#10 certificate signing request.
 //
 // BUG(paul): Not all fields are currently marshalled into the PKCS#10 request.
 // The fields currently marshalled include: subject distinguished name (all
 // fields, including extra attributes); subject alternative names (excluding
-// other names); and extended key usages.
-func (r *Request) PKCS10() (*x509.CertificateRequest, error) {
+// other names, but including registered IDs and directory names); and
+// extended key usages.
+//
+// The arrangement of the subject distinguished name into relative
+// distinguished names may be controlled with RDN options, as described for
+// DN.RDNSequence.
+func (r *Request) PKCS10(opts ...RDNOption) (*x509.CertificateRequest, error) {
 	// We need a private key to sign the CSR, so abandon immediately if
 	// the request doesn't contain one.
-	if r.PrivateKey == nil {
+	var _, privKey, err = r.requestKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	if privKey == nil {
 		return nil, errors.New("no private key in request")
 	}
 
@@ -481,13 +759,33 @@ func (r *Request) PKCS10() (*x509.CertificateRequest, error) {
 
 	if r.Subject != nil {
 		csrtemplate.Subject = r.Subject.PKIXName()
+
+		if len(opts) > 0 {
+			var der, err = asn1.Marshal(r.Subject.RDNSequence(opts...))
+			if err != nil {
+				return nil, fmt.Errorf("couldn't marshal subject distinguished name: %v", err)
+			}
+
+			csrtemplate.RawSubject = der
+		}
 	}
 
 	if r.SAN != nil {
-		csrtemplate.DNSNames = r.SAN.DNSNames
-		csrtemplate.EmailAddresses = r.SAN.Emails
-		csrtemplate.IPAddresses = r.SAN.IPAddresses
-		csrtemplate.URIs = r.SAN.URIs
+		if len(r.SAN.RegisteredIDs) > 0 || len(r.SAN.DirectoryNames) > 0 {
+			// The x509 package doesn't support registered IDs or directory
+			// names, so we must build the whole extension ourselves.
+			var ext, err = r.SAN.pkixExtension()
+			if err != nil {
+				return nil, fmt.Errorf("couldn't marshal subject alternative names: %v", err)
+			}
+
+			csrtemplate.ExtraExtensions = append(csrtemplate.ExtraExtensions, ext)
+		} else {
+			csrtemplate.DNSNames = domainsToASCII(r.SAN.DNSNames)
+			csrtemplate.EmailAddresses = r.SAN.Emails
+			csrtemplate.IPAddresses = r.SAN.IPAddresses
+			csrtemplate.URIs = r.SAN.URIs
+		}
 	}
 
 	if len(r.EKUs) > 0 {
@@ -510,10 +808,11 @@ func (r *Request) PKCS10() (*x509.CertificateRequest, error) {
 	}
 
 	// Create and marshal the PKCS#10 certificate signing request.
-	var data, err = x509.CreateCertificateRequest(
+	var data []byte
+	data, err = x509.CreateCertificateRequest(
 		rand.Reader,
 		csrtemplate,
-		r.PrivateKey,
+		privKey,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create PKCS#10 CSR: %v", err)
@@ -546,6 +845,13 @@ func (v *Validity) Equal(other *Validity) bool {
 
 // MarshalJSON returns the JSON encoding of a validity object.
 func (v *Validity) MarshalJSON() ([]byte, error) {
+	if v.RFC3339 {
+		return json.Marshal(&jsonValidityRFC3339{
+			NotBefore: v.NotBefore.UTC().Format(time.RFC3339),
+			NotAfter:  v.NotAfter.UTC().Format(time.RFC3339),
+		})
+	}
+
 	return json.Marshal(&jsonValidity{
 		NotBefore: v.NotBefore.Unix(),
 		NotAfter:  v.NotAfter.Unix(),
@@ -555,20 +861,55 @@ func (v *Validity) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON parses a JSON-encoded validity object and stores the result in
 // the object.
 func (v *Validity) UnmarshalJSON(b []byte) error {
-	var jsonobj jsonValidity
+	var jsonobj jsonValidityRaw
 	if err := json.Unmarshal(b, &jsonobj); err != nil {
 		return err
 	}
 
+	var notBefore, err = parseJSONTime(jsonobj.NotBefore)
+	if err != nil {
+		return fmt.Errorf("invalid not before time: %w", err)
+	}
+
+	var notAfter time.Time
+	if notAfter, err = parseJSONTime(jsonobj.NotAfter); err != nil {
+		return fmt.Errorf("invalid not after time: %w", err)
+	}
+
 	// Store result in object.
 	*v = Validity{
-		NotBefore: time.Unix(jsonobj.NotBefore, 0),
-		NotAfter:  time.Unix(jsonobj.NotAfter, 0),
+		NotBefore: notBefore,
+		NotAfter:  notAfter,
 	}
 
 	return nil
 }
 
+// parseJSONTime parses a JSON-encoded time which is either an integer number
+// of seconds since the Unix epoch, or an RFC 3339 string. A missing or null
+// value is treated as zero seconds since the Unix epoch.
+func parseJSONTime(b json.RawMessage) (time.Time, error) {
+	if len(b) == 0 || string(b) == "null" {
+		return time.Unix(0, 0), nil
+	}
+
+	if b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return time.Time{}, err
+		}
+
+		return time.Parse(time.RFC3339, s)
+	}
+
+	var secs int64
+	if err := json.Unmarshal(b, &secs); err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(secs, 0), nil
+}
+
 // Equal checks if two subject distinguished names are equivalent.
 func (n *DN) Equal(other *DN) bool {
 	// Check for nil in both objects.
@@ -602,6 +943,16 @@ func (n *DN) Equal(other *DN) bool {
 		}
 	}
 
+	if len(n.ExtraAttributeValues) != len(other.ExtraAttributeValues) {
+		return false
+	}
+
+	for i := range n.ExtraAttributeValues {
+		if !n.ExtraAttributeValues[i].Equal(other.ExtraAttributeValues[i]) {
+			return false
+		}
+	}
+
 	// Check equality of other fields.
 	return n.Country == other.Country &&
 		n.State == other.State &&
@@ -618,7 +969,22 @@ func (n *DN) Equal(other *DN) bool {
 }
 
 // PKIXName converts a subject distinguished name into a pkix.Name object.
-func (n *DN) PKIXName() pkix.Name {
+// If any options are specified, every attribute is instead stored in the
+// ExtraNames field in the order they determine, so that the ToRDNSequence
+// method of the result preserves that order. Since a pkix.Name cannot
+// represent multi-valued RDNs, the attributes of any multi-valued RDN are
+// then stored as consecutive single-valued RDNs; use RDNSequence to preserve
+// them.
+func (n *DN) PKIXName(opts ...RDNOption) pkix.Name {
+	if len(opts) > 0 {
+		var name pkix.Name
+		for _, rdn := range n.RDNSequence(opts...) {
+			name.ExtraNames = append(name.ExtraNames, rdn...)
+		}
+
+		return name
+	}
+
 	// Initialize name with all fields that are single-value in both structs.
 	var name = pkix.Name{
 		CommonName:   n.CommonName,
@@ -671,9 +1037,36 @@ func (n *DN) PKIXName() pkix.Name {
 		name.ExtraNames = append(name.ExtraNames, ea.AttributeTypeAndValue())
 	}
 
+	for _, ea := range n.ExtraAttributeValues {
+		name.ExtraNames = append(name.ExtraNames, ea.AttributeTypeAndValues()...)
+	}
+
 	return name
 }
 
+// MarshalJSON returns the JSON encoding of a subject distinguished name.
+func (n DN) MarshalJSON() ([]byte, error) {
+	// Use a type without a MarshalJSON method to avoid infinite recursion.
+	type plainDN DN
+
+	var plain = plainDN(n)
+
+	if len(n.ExtraAttributeValues) > 0 {
+		plain.ExtraAttributes = append([]OIDAndString(nil), n.ExtraAttributes...)
+
+		for _, ea := range n.ExtraAttributeValues {
+			var strs, err = ea.OIDAndStrings()
+			if err != nil {
+				return nil, err
+			}
+
+			plain.ExtraAttributes = append(plain.ExtraAttributes, strs...)
+		}
+	}
+
+	return json.Marshal(plain)
+}
+
 // MarshalJSON returns the JSON encoding of a subject distinguished name.
 func (o jsonOID) MarshalJSON() ([]byte, error) {
 	return json.Marshal(asn1.ObjectIdentifier(o).String())
@@ -691,7 +1084,7 @@ func (o *jsonOID) UnmarshalJSON(b []byte) error {
 	var newOID asn1.ObjectIdentifier
 	newOID, err = oids.StringToOID(oidvalue)
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid OID %q: %w", oidvalue, err)
 	}
 
 	*o = jsonOID(newOID)
@@ -716,7 +1109,7 @@ func (o OIDAndString) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON parses a JSON-encoded OID and string and stores the result
 // in the object.
 func (o *OIDAndString) UnmarshalJSON(b []byte) error {
-	var jsonObj *jsonOIDAndString
+	var jsonObj jsonOIDAndString
 	if err := json.Unmarshal(b, &jsonObj); err != nil {
 		return err
 	}
@@ -730,6 +1123,27 @@ func (o *OIDAndString) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// NewOIDAndDER returns an OID and string object whose value is the base64
+// encoding of a DER encoding, for values such as custom extensions which are
+// not representable as text.
+func NewOIDAndDER(oid asn1.ObjectIdentifier, der []byte) OIDAndString {
+	return OIDAndString{
+		OID:   oid,
+		Value: base64.StdEncoding.EncodeToString(der),
+	}
+}
+
+// DER returns the DER encoding contained in the value of an OID and string
+// object created with NewOIDAndDER.
+func (o OIDAndString) DER() ([]byte, error) {
+	var der, err = base64.StdEncoding.DecodeString(o.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 DER value for OID %s: %w", o.OID, err)
+	}
+
+	return der, nil
+}
+
 // AttributeTypeAndValue converts an OIDAndString object into a
 // pkix.AttributeTypeAndValue object.
 func (o OIDAndString) AttributeTypeAndValue() pkix.AttributeTypeAndValue {
@@ -739,6 +1153,138 @@ func (o OIDAndString) AttributeTypeAndValue() pkix.AttributeTypeAndValue {
 	}
 }
 
+// Equal checks if two OID and values objects are equivalent.
+func (o OIDAndValues) Equal(other OIDAndValues) bool {
+	if !o.OID.Equal(other.OID) || len(o.Values) != len(other.Values) {
+		return false
+	}
+
+	for i := range o.Values {
+		var first, err = attributeValueString(o.Values[i])
+		if err != nil {
+			return false
+		}
+
+		var second string
+		if second, err = attributeValueString(other.Values[i]); err != nil {
+			return false
+		}
+
+		if first != second || fmt.Sprintf("%T", o.Values[i]) != fmt.Sprintf("%T", other.Values[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// OIDAndStrings converts an OIDAndValues object into a list of OIDAndString
+// objects, one for each value, using the string representations of the
+// values used in HVCA requests.
+func (o OIDAndValues) OIDAndStrings() ([]OIDAndString, error) {
+	var result = make([]OIDAndString, 0, len(o.Values))
+
+	for _, value := range o.Values {
+		var str, err = attributeValueString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for attribute %s: %w", o.OID, err)
+		}
+
+		result = append(result, OIDAndString{OID: o.OID, Value: str})
+	}
+
+	return result, nil
+}
+
+// AttributeTypeAndValues converts an OIDAndValues object into a list of
+// pkix.AttributeTypeAndValue objects, one for each value.
+func (o OIDAndValues) AttributeTypeAndValues() []pkix.AttributeTypeAndValue {
+	var result = make([]pkix.AttributeTypeAndValue, 0, len(o.Values))
+
+	for _, value := range o.Values {
+		result = append(result, pkix.AttributeTypeAndValue{
+			Type:  o.OID,
+			Value: value,
+		})
+	}
+
+	return result
+}
+
+// Equal checks if two custom extension values are equivalent.
+func (v CustomExtensionValue) Equal(other CustomExtensionValue) bool {
+	if !v.OID.Equal(other.OID) || fmt.Sprintf("%T", v.Value) != fmt.Sprintf("%T", other.Value) {
+		return false
+	}
+
+	var first, err = v.OIDAndString()
+	if err != nil {
+		return false
+	}
+
+	var second OIDAndString
+	if second, err = other.OIDAndString(); err != nil {
+		return false
+	}
+
+	return first.Value == second.Value
+}
+
+// OIDAndString converts a custom extension value into an OIDAndString
+// object, using the string representation of the value used in HVCA
+// requests.
+func (v CustomExtensionValue) OIDAndString() (OIDAndString, error) {
+	var str string
+	var err error
+
+	if raw, ok := v.Value.(asn1.RawValue); ok {
+		if len(raw.FullBytes) == 0 {
+			err = errors.New("DER value has no encoding")
+		}
+
+		str = base64.StdEncoding.EncodeToString(raw.FullBytes)
+	} else {
+		str, err = attributeValueString(v.Value)
+	}
+
+	if err != nil {
+		return OIDAndString{}, fmt.Errorf("invalid value for custom extension %s: %w", v.OID, err)
+	}
+
+	return OIDAndString{OID: v.OID, Value: str}, nil
+}
+
+// attributeValueString returns the string representation of an extra
+// attribute value used in HVCA requests.
+func attributeValueString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+
+	case int:
+		return strconv.Itoa(v), nil
+
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+
+	case *big.Int:
+		if v == nil {
+			return "", errors.New("nil integer value")
+		}
+
+		return v.String(), nil
+
+	case asn1.RawValue:
+		if len(v.FullBytes) == 0 {
+			return "", errors.New("DER value has no encoding")
+		}
+
+		return base64.StdEncoding.EncodeToString(v.FullBytes), nil
+	}
+
+	return "", fmt.Errorf("unsupported value type %T", value)
+}
+
 // Equal checks if two subject alternative names lists are equivalent.
 func (s *SAN) Equal(other *SAN) bool {
 	// Check for nil in both objects.
@@ -805,6 +1351,28 @@ func (s *SAN) Equal(other *SAN) bool {
 		}
 	}
 
+	// Check equality of registered IDs.
+	if len(s.RegisteredIDs) != len(other.RegisteredIDs) {
+		return false
+	}
+
+	for i := range s.RegisteredIDs {
+		if !s.RegisteredIDs[i].Equal(other.RegisteredIDs[i]) {
+			return false
+		}
+	}
+
+	// Check equality of directory names.
+	if len(s.DirectoryNames) != len(other.DirectoryNames) {
+		return false
+	}
+
+	for i := range s.DirectoryNames {
+		if !s.DirectoryNames[i].Equal(&other.DirectoryNames[i]) {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -822,12 +1390,20 @@ func (s *SAN) MarshalJSON() ([]byte, error) {
 		uris = append(uris, uri.String())
 	}
 
+	// Convert registered IDs.
+	var regIDs = make([]jsonOID, 0, len(s.RegisteredIDs))
+	for _, oid := range s.RegisteredIDs {
+		regIDs = append(regIDs, jsonOID(oid))
+	}
+
 	return json.Marshal(jsonSAN{
-		DNSNames:    s.DNSNames,
-		Emails:      s.Emails,
-		IPAddresses: ips,
-		URIs:        uris,
-		OtherNames:  s.OtherNames,
+		DNSNames:       domainsToASCII(s.DNSNames),
+		Emails:         s.Emails,
+		IPAddresses:    ips,
+		URIs:           uris,
+		OtherNames:     s.OtherNames,
+		RegisteredIDs:  regIDs,
+		DirectoryNames: s.DirectoryNames,
 	})
 }
 
@@ -857,18 +1433,99 @@ func (s *SAN) UnmarshalJSON(b []byte) error {
 		uris = append(uris, uri)
 	}
 
+	// Convert registered IDs.
+	var regIDs []asn1.ObjectIdentifier
+	for _, oid := range jsonsan.RegisteredIDs {
+		regIDs = append(regIDs, asn1.ObjectIdentifier(oid))
+	}
+
 	// Store result in object.
 	*s = SAN{
-		DNSNames:    jsonsan.DNSNames,
-		Emails:      jsonsan.Emails,
-		IPAddresses: ips,
-		URIs:        uris,
-		OtherNames:  jsonsan.OtherNames,
+		DNSNames:       jsonsan.DNSNames,
+		Emails:         jsonsan.Emails,
+		IPAddresses:    ips,
+		URIs:           uris,
+		OtherNames:     jsonsan.OtherNames,
+		RegisteredIDs:  regIDs,
+		DirectoryNames: jsonsan.DirectoryNames,
 	}
 
 	return nil
 }
 
+// pkixExtension builds a subject alternative names extension containing
+// the DNS names, email addresses, IP addresses, URIs, registered IDs and
+// directory names in the list. Other names are not included.
+func (s *SAN) pkixExtension() (pkix.Extension, error) {
+	// GeneralName context-specific tags. See RFC 5280 4.2.1.6.
+	const (
+		tagEmail         = 1
+		tagDNSName       = 2
+		tagDirectoryName = 4
+		tagURI           = 6
+		tagIPAddress     = 7
+		tagRegisteredID  = 8
+	)
+
+	var names []asn1.RawValue
+
+	for _, name := range s.DNSNames {
+		names = append(names, asn1.RawValue{Tag: tagDNSName, Class: asn1.ClassContextSpecific, Bytes: []byte(DomainToASCII(name))})
+	}
+
+	for _, email := range s.Emails {
+		names = append(names, asn1.RawValue{Tag: tagEmail, Class: asn1.ClassContextSpecific, Bytes: []byte(email)})
+	}
+
+	for _, dn := range s.DirectoryNames {
+		var der, err = asn1.Marshal(dn.PKIXName().ToRDNSequence())
+		if err != nil {
+			return pkix.Extension{}, err
+		}
+
+		// Name is a CHOICE, so the directoryName tag is explicit.
+		names = append(names, asn1.RawValue{Tag: tagDirectoryName, Class: asn1.ClassContextSpecific, IsCompound: true, Bytes: der})
+	}
+
+	for _, uri := range s.URIs {
+		names = append(names, asn1.RawValue{Tag: tagURI, Class: asn1.ClassContextSpecific, Bytes: []byte(uri.String())})
+	}
+
+	for _, ip := range s.IPAddresses {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+
+		names = append(names, asn1.RawValue{Tag: tagIPAddress, Class: asn1.ClassContextSpecific, Bytes: ip})
+	}
+
+	for _, oid := range s.RegisteredIDs {
+		var der, err = asn1.Marshal(oid)
+		if err != nil {
+			return pkix.Extension{}, err
+		}
+
+		// The registeredID tag is implicit, so replace the universal OBJECT
+		// IDENTIFIER tag with the context-specific one.
+		var raw asn1.RawValue
+		if _, err = asn1.Unmarshal(der, &raw); err != nil {
+			return pkix.Extension{}, err
+		}
+
+		names = append(names, asn1.RawValue{Tag: tagRegisteredID, Class: asn1.ClassContextSpecific, Bytes: raw.Bytes})
+	}
+
+	var value, err = asn1.Marshal(names)
+	if err != nil {
+		return pkix.Extension{}, err
+	}
+
+	return pkix.Extension{
+		Id:    oids.OIDSubjectAltName,
+		Value: value,
+	}, nil
+}
+
 // Equal checks if two subject directory attributes lists are equivalent.
 func (d *DA) Equal(other *DA) bool {
 	// Check for nil in both objects.
@@ -988,7 +1645,8 @@ func (q *QualifiedStatements) Equal(other *QualifiedStatements) bool {
 		q.QCCompliance == other.QCCompliance &&
 		q.QCSSCDCompliance == other.QCSSCDCompliance &&
 		q.QCType.Equal(other.QCType) &&
-		q.QCRetentionPeriod == other.QCRetentionPeriod
+		q.QCRetentionPeriod == other.QCRetentionPeriod &&
+		q.PSD2.Equal(other.PSD2)
 }
 
 // MarshalJSON returns the JSON encoding of a qualified statements list.
@@ -1031,6 +1689,7 @@ func (q *QualifiedStatements) MarshalJSON() ([]byte, error) {
 		QCType:            jsonOID(q.QCType),
 		QCRetentionPeriod: q.QCRetentionPeriod,
 		QCPDs:             raw,
+		PSD2:              q.PSD2.toJSON(),
 	})
 }
 
@@ -1061,11 +1720,80 @@ func (q *QualifiedStatements) UnmarshalJSON(b []byte) error {
 		QCType:            asn1.ObjectIdentifier(jsonqs.QCType),
 		QCRetentionPeriod: jsonqs.QCRetentionPeriod,
 		QCPDs:             pds,
+		PSD2:              jsonqs.PSD2.fromJSON(),
 	}
 
 	return nil
 }
 
+// Equal checks if two PSD2 qualified statements are equivalent.
+func (p *PSD2) Equal(other *PSD2) bool {
+	// Check for nil in both objects.
+	if p == nil {
+		return other == nil
+	}
+
+	if other == nil {
+		return false
+	}
+
+	// Check equality of roles.
+	if len(p.RolesOfPSP) != len(other.RolesOfPSP) {
+		return false
+	}
+
+	for i := range p.RolesOfPSP {
+		if !p.RolesOfPSP[i].Equal(other.RolesOfPSP[i]) {
+			return false
+		}
+	}
+
+	// Check equality of other fields.
+	return p.NCAName == other.NCAName &&
+		p.NCAID == other.NCAID
+}
+
+// toJSON converts a PSD2 qualified statement to its JSON representation.
+func (p *PSD2) toJSON() *jsonPSD2 {
+	if p == nil {
+		return nil
+	}
+
+	var roles = make([]jsonPSPRole, 0, len(p.RolesOfPSP))
+	for _, role := range p.RolesOfPSP {
+		roles = append(roles, jsonPSPRole{OID: jsonOID(role.OID), Name: role.Name})
+	}
+
+	return &jsonPSD2{
+		RolesOfPSP: roles,
+		NCAName:    p.NCAName,
+		NCAID:      p.NCAID,
+	}
+}
+
+// fromJSON converts the JSON representation of a PSD2 qualified statement.
+func (p *jsonPSD2) fromJSON() *PSD2 {
+	if p == nil {
+		return nil
+	}
+
+	var roles []PSPRole
+	for _, role := range p.RolesOfPSP {
+		roles = append(roles, PSPRole{OID: asn1.ObjectIdentifier(role.OID), Name: role.Name})
+	}
+
+	return &PSD2{
+		RolesOfPSP: roles,
+		NCAName:    p.NCAName,
+		NCAID:      p.NCAID,
+	}
+}
+
+// Equal checks if two payment service provider roles are equivalent.
+func (r PSPRole) Equal(other PSPRole) bool {
+	return r.OID.Equal(other.OID) && r.Name == other.Name
+}
+
 // Equal checks if two semantics objects are equivalent.
 func (s Semantics) Equal(other Semantics) bool {
 	// Check equality of name authorities.
@@ -1137,7 +1865,7 @@ func (m *MSExtension) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON parses a JSON-encoded MS template extension and stores the
 // result in the object.
 func (m *MSExtension) UnmarshalJSON(b []byte) error {
-	var jsonext *jsonMSExtension
+	var jsonext jsonMSExtension
 	if err := json.Unmarshal(b, &jsonext); err != nil {
 		return err
 	}
@@ -1173,3 +1901,78 @@ func publicKeyBytesAndString(key interface{}) ([]byte, string, error) {
 
 	return keyBytes, keyString, nil
 }
+
+// PublicKeySignatureHash is the hash function used to compute the digest
+// returned by PublicKeySignatureDigest, and is suitable for use as the
+// options when signing that digest with a crypto.Signer.
+const PublicKeySignatureHash = crypto.SHA256
+
+// PublicKeySignatureDigest returns the digest which must be signed by the
+// private key corresponding to an RSA or ECDSA public key to prove possession
+// of it, being the SHA-256 hash of the DER encoding of the public key. The
+// digest must be signed using PKCS#1 v1.5 for an RSA key, or ECDSA with an
+// ASN.1-encoded signature for an ECDSA key, which is what the Sign method of
+// the keys in the standard library does when PublicKeySignatureHash is passed
+// as the options. The base64-encoded signature may then be assigned to the
+// PublicKeySignature field of a Request, along with the public key.
+func PublicKeySignatureDigest(pub interface{}) ([]byte, error) {
+	if k, ok := pub.(JWK); ok {
+		var err error
+		if pub, err = k.PublicKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch k := pub.(type) {
+	case rsa.PublicKey:
+		pub = &k
+	case ecdsa.PublicKey:
+		pub = &k
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported public key type for public key signature: %T", k)
+	}
+
+	var der, err = x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	var h = sha256.Sum256(der)
+
+	return h[:], nil
+}
+
+// verifyPublicKeySignature checks that a base64-encoded signature is a valid
+// signature of the SHA-256 hash of the DER-encoded public key, as required
+// for proof-of-possession of the private key.
+func verifyPublicKeySignature(pubKeyBytes []byte, sig string) error {
+	var signed, err = base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPublicKeySignature, err)
+	}
+
+	var pub interface{}
+	if pub, err = x509.ParsePKIXPublicKey(pubKeyBytes); err != nil {
+		return err
+	}
+
+	var h = sha256.Sum256(pubKeyBytes)
+
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if err = rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], signed); err != nil {
+			return ErrInvalidPublicKeySignature
+		}
+
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, h[:], signed) {
+			return ErrInvalidPublicKeySignature
+		}
+
+	default:
+		return fmt.Errorf("unsupported public key type for public key signature: %T", k)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the HTTP request header in which a request ID is sent
+// with every HVCA API call. Quoting the request ID of a failed call allows
+// GlobalSign support to identify it.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the context key for a request ID.
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of the context carrying the specified request
+// ID, which is sent in the X-Request-ID header with every HVCA API call made
+// with the returned context, including any login made on its behalf. This
+// allows a request ID from an incoming request to be propagated to HVCA. If
+// a context carries no request ID, a new one is generated for each call.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by the context, or the
+// empty string if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	var id, _ = ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
+// newRequestID returns a new random request ID.
+func newRequestID() string {
+	var b = make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// requestIDOf returns the request ID sent with the request which produced
+// an HTTP response, or the empty string if there is none.
+func requestIDOf(response *http.Response) string {
+	if response == nil || response.Request == nil {
+		return ""
+	}
+
+	return response.Request.Header.Get(RequestIDHeader)
+}
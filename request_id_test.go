@@ -0,0 +1,143 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newHeaderTestClient returns a client of a test server which records the
+// User-Agent and X-Request-ID headers of each request, and which responds
+// with the specified status codes in turn, followed by 200 OK.
+func newHeaderTestClient(t *testing.T, conf *Config, statuses ...int) (*Client, func() [][2]string) {
+	t.Helper()
+
+	var mtx sync.Mutex
+	var seen [][2]string
+
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		seen = append(seen, [2]string{r.Header.Get("User-Agent"), r.Header.Get(RequestIDHeader)})
+		var n = len(seen)
+		mtx.Unlock()
+
+		if n <= len(statuses) {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(statuses[n-1])
+			fmt.Fprintf(w, `{"description":"failed"}`)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"value":42}`)
+	})
+
+	conf.Clock = &fakeClock{now: time.Now()}
+
+	var clnt, _ = newTestClient(t, handler, conf)
+
+	return clnt, func() [][2]string {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		return seen
+	}
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	t.Parallel()
+
+	var clnt, seen = newHeaderTestClient(t, &Config{UserAgent: "test-agent/1.0", Retries: 1})
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := clnt.QuotaIssuance(WithRequestID(ctx, "abc-123")); err != nil {
+		t.Fatalf("couldn't get quota: %v", err)
+	}
+
+	if got, want := seen(), [][2]string{{"test-agent/1.0", "abc-123"}}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got headers %q, want %q", got, want)
+	}
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	t.Parallel()
+
+	var clnt, seen = newHeaderTestClient(t, &Config{Retries: 1}, http.StatusServiceUnavailable)
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := clnt.QuotaIssuance(ctx); err != nil {
+		t.Fatalf("couldn't get quota: %v", err)
+	}
+
+	var got = seen()
+	if len(got) != 2 {
+		t.Fatalf("got %d requests, want 2", len(got))
+	}
+
+	// The retry should carry the same generated request ID.
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(got[0][1]) || got[1][1] != got[0][1] {
+		t.Errorf("got request IDs %q and %q, want the same generated ID", got[0][1], got[1][1])
+	}
+
+	if strings.HasPrefix(got[0][0], "test-agent") {
+		t.Errorf("got user agent %q, want default", got[0][0])
+	}
+
+	// Each call should have a new request ID.
+	if _, err := clnt.QuotaIssuance(ctx); err != nil {
+		t.Fatalf("couldn't get quota: %v", err)
+	}
+
+	if got = seen(); got[2][1] == got[0][1] {
+		t.Errorf("got same request ID %q for separate calls", got[2][1])
+	}
+}
+
+func TestRequestIDInErrors(t *testing.T) {
+	t.Parallel()
+
+	var clnt, _ = newHeaderTestClient(t, &Config{}, http.StatusNotFound)
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var _, err = clnt.QuotaIssuance(WithRequestID(ctx, "abc-123"))
+	if err == nil {
+		t.Fatalf("unexpectedly got quota")
+	}
+
+	var apiErr APIError
+	if !errors.As(err, &apiErr) || apiErr.RequestID != "abc-123" {
+		t.Fatalf("got error %v, want API error with request ID abc-123", err)
+	}
+
+	if want := "404: failed (request ID abc-123)"; err.Error() != want {
+		t.Errorf("got error %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// requestOptionsKey is the context key for per-call request options.
+type requestOptionsKey struct{}
+
+// requestOptions are the extra headers and query parameters carried by a
+// context.
+type requestOptions struct {
+	header http.Header
+	query  url.Values
+}
+
+// WithRequestHeader returns a copy of the context carrying a custom HTTP
+// request header, which is added to every HVCA API call made with the
+// returned context, including any login made on its behalf. It may be called
+// more than once to add several headers, or several values of the same
+// header. Unlike the ExtraHeaders configuration field, which applies to every
+// call made by a client, this allows headers required by a gateway or proxy
+// to be supplied for individual calls.
+func WithRequestHeader(ctx context.Context, name, value string) context.Context {
+	var opts = requestOptionsFromContext(ctx).clone()
+	opts.header.Add(name, value)
+
+	return context.WithValue(ctx, requestOptionsKey{}, opts)
+}
+
+// WithQueryParam returns a copy of the context carrying a URL query
+// parameter, which is added to every HVCA API call made with the returned
+// context, other than any login made on its behalf. It may be called more
+// than once to add several parameters, or several values of the same
+// parameter. This allows newly introduced API parameters to be used before
+// the client supports them directly.
+func WithQueryParam(ctx context.Context, name, value string) context.Context {
+	var opts = requestOptionsFromContext(ctx).clone()
+	opts.query.Add(name, value)
+
+	return context.WithValue(ctx, requestOptionsKey{}, opts)
+}
+
+// requestOptionsFromContext returns the request options carried by the
+// context, or nil if there are none.
+func requestOptionsFromContext(ctx context.Context) *requestOptions {
+	var opts, _ = ctx.Value(requestOptionsKey{}).(*requestOptions)
+
+	return opts
+}
+
+// clone returns a deep copy of the request options, so that options added to
+// a derived context do not affect its parent. A nil receiver returns empty
+// options.
+func (o *requestOptions) clone() *requestOptions {
+	var c = &requestOptions{
+		header: make(http.Header),
+		query:  make(url.Values),
+	}
+
+	if o == nil {
+		return c
+	}
+
+	for k, v := range o.header {
+		c.header[k] = append([]string(nil), v...)
+	}
+
+	for k, v := range o.query {
+		c.query[k] = append([]string(nil), v...)
+	}
+
+	return c
+}
+
+// addQuery returns the request path with the query parameters appended to
+// any query it already has.
+func (o *requestOptions) addQuery(path string) string {
+	if o == nil || len(o.query) == 0 {
+		return path
+	}
+
+	var sep = "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+
+	return path + sep + o.query.Encode()
+}
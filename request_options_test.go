@@ -0,0 +1,102 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRequestOptions(t *testing.T) {
+	t.Parallel()
+
+	var gotHeader http.Header
+	var gotQuery url.Values
+
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Clone()
+		gotQuery = r.URL.Query()
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"value":42}`)
+	})
+
+	var clnt, _ = newTestClient(t, handler, nil)
+
+	var parent = WithRequestHeader(context.Background(), "X-Gateway-Key", "abc")
+	var ctx = WithRequestHeader(parent, "X-Gateway-Key", "def")
+	ctx = WithQueryParam(ctx, "tenant", "one")
+	ctx = WithQueryParam(ctx, "tenant", "two")
+
+	if _, err := clnt.CounterCertsIssued(ctx); err != nil {
+		t.Fatalf("couldn't get counter: %v", err)
+	}
+
+	if got, want := gotHeader.Values("X-Gateway-Key"), []string{"abc", "def"}; !cmp.Equal(got, want) {
+		t.Errorf("got header values %q, want %q", got, want)
+	}
+
+	if got, want := gotQuery["tenant"], []string{"one", "two"}; !cmp.Equal(got, want) {
+		t.Errorf("got query values %q, want %q", got, want)
+	}
+
+	// Options added to a derived context must not leak into its parent.
+	if _, err := clnt.CounterCertsIssued(parent); err != nil {
+		t.Fatalf("couldn't get counter: %v", err)
+	}
+
+	if got, want := gotHeader.Values("X-Gateway-Key"), []string{"abc"}; !cmp.Equal(got, want) {
+		t.Errorf("got header values %q, want %q", got, want)
+	}
+
+	if len(gotQuery) != 0 {
+		t.Errorf("got query %v, want none", gotQuery)
+	}
+}
+
+func TestRequestOptionsAddQuery(t *testing.T) {
+	t.Parallel()
+
+	var opts = requestOptionsFromContext(WithQueryParam(context.Background(), "a", "b c"))
+
+	var testcases = []struct {
+		name string
+		opts *requestOptions
+		path string
+		want string
+	}{
+		{"NoOptions", nil, "/stats/issued?from=1", "/stats/issued?from=1"},
+		{"NoQuery", opts, "/counters/certificates/issued", "/counters/certificates/issued?a=b+c"},
+		{"ExistingQuery", opts, "/stats/issued?from=1", "/stats/issued?from=1&a=b+c"},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.opts.addQuery(tc.path); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+// redactedKey replaces the key material of a redacted request, and is
+// encoded in JSON as the SHA-256 fingerprint of the public key.
+type redactedKey struct {
+	fingerprint Fingerprint
+}
+
+// String returns the fingerprint of the key, or the redaction placeholder
+// if the fingerprint could not be computed.
+func (k redactedKey) String() string {
+	if len(k.fingerprint) == 0 {
+		return redacted
+	}
+
+	return "SHA256:" + k.fingerprint.String()
+}
+
+// Redacted returns a copy of the request which is safe to log. The private
+// key and any public key signature are removed, the public key or PKCS#10
+// certificate signing request is replaced by the SHA-256 fingerprint of the
+// public key, and the personal data in the subject directory attributes is
+// masked. The copy is encoded in JSON in the same way as the request, but
+// cannot be submitted to HVCA.
+func (r Request) Redacted() *Request {
+	var c = r
+
+	c.PrivateKey = nil
+	c.PublicKey = nil
+	c.CSR = nil
+	c.PublicKeySignature = ""
+
+	if k, ok := r.PublicKey.(redactedKey); ok {
+		c.PublicKey = k
+	} else if pub := r.requestPublicKey(); pub != nil {
+		c.PublicKey = redactedKey{fingerprint: publicKeyFingerprint(pub)}
+	}
+
+	if r.DA != nil {
+		c.DA = r.DA.redacted()
+	}
+
+	return &c
+}
+
+// requestPublicKey returns the public key for which a certificate is
+// requested, or nil if there is none.
+func (r Request) requestPublicKey() interface{} {
+	var pub, priv, err = r.requestKeys()
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case pub != nil:
+		switch k := pub.(type) {
+		case rsa.PublicKey:
+			return &k
+
+		case ecdsa.PublicKey:
+			return &k
+		}
+
+		return pub
+
+	case priv != nil:
+		if signer, ok := priv.(crypto.Signer); ok {
+			return signer.Public()
+		}
+
+	case r.CSR != nil:
+		return r.CSR.PublicKey
+	}
+
+	return nil
+}
+
+// publicKeyFingerprint returns the SHA-256 fingerprint of the DER-encoded
+// public key, or nil if it could not be encoded.
+func publicKeyFingerprint(pub interface{}) Fingerprint {
+	var der, err = x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil
+	}
+
+	var sum = sha256.Sum256(der)
+
+	return Fingerprint(sum[:])
+}
+
+// redacted returns a copy of the subject directory attributes with the
+// gender, place of birth, countries and extra attribute values replaced by
+// the redaction placeholder and the date of birth cleared, so that only
+// which attributes are present is revealed.
+func (d *DA) redacted() *DA {
+	var mask = func(s string) string {
+		if s == "" {
+			return ""
+		}
+
+		return redacted
+	}
+
+	var maskAll = func(list []string) []string {
+		if list == nil {
+			return nil
+		}
+
+		var masked = make([]string, len(list))
+		for i := range list {
+			masked[i] = mask(list[i])
+		}
+
+		return masked
+	}
+
+	var c = &DA{
+		Gender:               mask(d.Gender),
+		DateOfBirth:          time.Time{},
+		PlaceOfBirth:         mask(d.PlaceOfBirth),
+		CountryOfCitizenship: maskAll(d.CountryOfCitizenship),
+		CountryOfResidence:   maskAll(d.CountryOfResidence),
+	}
+
+	for _, attr := range d.ExtraAttributes {
+		c.ExtraAttributes = append(c.ExtraAttributes, OIDAndString{OID: attr.OID, Value: mask(attr.Value)})
+	}
+
+	return c
+}
+
+// redactRequestFields replaces the key material and subject directory
+// attributes in the top-level fields of a JSON-encoded certificate request
+// in the same way as Request.Redacted, and returns true if any were
+// replaced.
+func redactRequestFields(obj map[string]json.RawMessage) bool {
+	var changed bool
+
+	if raw, ok := obj["public_key"]; ok {
+		var key = redactedKey{}
+
+		var data string
+		if err := json.Unmarshal(raw, &data); err == nil {
+			if pub, err := pki.PublicKeyFromPEM([]byte(data)); err == nil {
+				key.fingerprint = publicKeyFingerprint(pub)
+			} else if csr, err := pki.CSRFromPEM([]byte(data)); err == nil {
+				key.fingerprint = publicKeyFingerprint(csr.PublicKey)
+			}
+		}
+
+		obj["public_key"], _ = json.Marshal(key.String())
+		changed = true
+	}
+
+	if _, ok := obj["public_key_signature"]; ok {
+		obj["public_key_signature"] = json.RawMessage(`"` + redacted + `"`)
+		changed = true
+	}
+
+	if raw, ok := obj["subject_da"]; ok {
+		var da DA
+		var data []byte
+		var err = json.Unmarshal(raw, &da)
+		if err == nil {
+			data, err = json.Marshal(da.redacted())
+		}
+
+		if err != nil {
+			data = []byte(`"` + redacted + `"`)
+		}
+
+		obj["subject_da"] = data
+		changed = true
+	}
+
+	return changed
+}
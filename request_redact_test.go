@@ -0,0 +1,164 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/testhelpers"
+	"github.com/google/go-cmp/cmp"
+)
+
+func testKeyFingerprint(t *testing.T, pub interface{}) string {
+	t.Helper()
+
+	var der, err = x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatalf("couldn't marshal public key: %v", err)
+	}
+
+	var sum = sha256.Sum256(der)
+
+	return "SHA256:" + hvclient.Fingerprint(sum[:]).String()
+}
+
+func TestRequestRedacted(t *testing.T) {
+	t.Parallel()
+
+	var priv = testhelpers.MustGetPrivateKeyFromFile(t, "testdata/rsa_priv.key").(crypto.Signer)
+	var pub = testhelpers.MustGetPublicKeyFromFile(t, "testdata/ec_pub.key")
+	var csr = testhelpers.MustGetCSRFromFile(t, "testdata/test_csr.pem")
+
+	var testcases = []struct {
+		name    string
+		request hvclient.Request
+		want    string
+	}{
+		{
+			name:    "PrivateKey",
+			request: hvclient.Request{PrivateKey: priv},
+			want:    testKeyFingerprint(t, priv.Public()),
+		},
+		{
+			name:    "PublicKey",
+			request: hvclient.Request{PublicKey: pub, PublicKeySignature: "c2lnbmF0dXJl"},
+			want:    testKeyFingerprint(t, pub),
+		},
+		{
+			name:    "CSR",
+			request: hvclient.Request{CSR: csr},
+			want:    testKeyFingerprint(t, csr.PublicKey),
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var redacted = tc.request.Redacted()
+			if redacted.PrivateKey != nil || redacted.CSR != nil || redacted.PublicKeySignature != "" {
+				t.Fatalf("redacted request contains key material: %+v", redacted)
+			}
+
+			// Redacting should not modify the original request.
+			if tc.request.PrivateKey == nil && tc.request.PublicKey == nil && tc.request.CSR == nil {
+				t.Fatalf("original request was modified")
+			}
+
+			for _, r := range []*hvclient.Request{redacted, redacted.Redacted()} {
+				var data, err = json.Marshal(r)
+				if err != nil {
+					t.Fatalf("couldn't marshal redacted request: %v", err)
+				}
+
+				var got struct {
+					PublicKey    string `json:"public_key"`
+					PublicKeySig string `json:"public_key_signature"`
+				}
+				if err = json.Unmarshal(data, &got); err != nil {
+					t.Fatalf("couldn't unmarshal redacted request: %v", err)
+				}
+
+				if got.PublicKey != tc.want || got.PublicKeySig != "" {
+					t.Errorf("got public key %q and signature %q, want %q and none", got.PublicKey, got.PublicKeySig, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestRedactedDA(t *testing.T) {
+	t.Parallel()
+
+	var request = hvclient.Request{
+		Subject: &hvclient.DN{CommonName: "John Doe"},
+		DA: &hvclient.DA{
+			Gender:               "M",
+			DateOfBirth:          time.Date(1979, 1, 31, 12, 0, 0, 0, time.UTC),
+			PlaceOfBirth:         "London",
+			CountryOfCitizenship: []string{"GB", "US"},
+			CountryOfResidence:   []string{"GB"},
+			ExtraAttributes: []hvclient.OIDAndString{
+				{OID: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: "secret"},
+			},
+		},
+	}
+
+	var redacted = request.Redacted()
+
+	var want = &hvclient.DA{
+		Gender:               "REDACTED",
+		PlaceOfBirth:         "REDACTED",
+		CountryOfCitizenship: []string{"REDACTED", "REDACTED"},
+		CountryOfResidence:   []string{"REDACTED"},
+		ExtraAttributes: []hvclient.OIDAndString{
+			{OID: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: "REDACTED"},
+		},
+	}
+
+	if !cmp.Equal(redacted.DA, want) {
+		t.Errorf("got %+v, want %+v", redacted.DA, want)
+	}
+
+	if request.DA.Gender != "M" || request.DA.PlaceOfBirth != "London" {
+		t.Errorf("original subject directory attributes were modified")
+	}
+
+	var data, err = json.Marshal(redacted)
+	if err != nil {
+		t.Fatalf("couldn't marshal redacted request: %v", err)
+	}
+
+	for _, secret := range []string{"London", "1979", `"GB"`, "secret"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("redacted request contains %q: %s", secret, data)
+		}
+	}
+
+	if !strings.Contains(string(data), "John Doe") {
+		t.Errorf("redacted request does not contain subject: %s", data)
+	}
+}
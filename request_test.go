@@ -16,9 +16,19 @@ limitations under the License.
 package hvclient_test
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/asn1"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
 	"net"
 	"net/url"
 	"testing"
@@ -26,6 +36,7 @@ import (
 
 	"github.com/globalsign/hvclient"
 	"github.com/globalsign/hvclient/internal/testhelpers"
+	"github.com/google/go-cmp/cmp"
 )
 
 const testRequestCSRPEM = `-----BEGIN CERTIFICATE REQUEST-----
@@ -135,6 +146,15 @@ const testRequestFullJSON = `{
                 "type": "1.3.6.1.4.1.311.20.2.3",
                 "value": "upn@demo.hvca.globalsign.com"
             }
+        ],
+        "registered_ids": [
+            "1.3.6.1.4.1.4146.1.1"
+        ],
+        "directory_names": [
+            {
+                "organization": "GMO GlobalSign",
+                "common_name": "Jane Doe"
+            }
         ]
     },
     "extended_key_usages": [
@@ -176,6 +196,20 @@ const testRequestFullJSON = `{
         "etsi_qc_pds": {
             "EN": "https://demo.hvsign.globalsign.com/en/pds",
             "RU": "https://demo.hvsign.globalsign.com/ru/pds"
+        },
+        "etsi_psd2": {
+            "roles_of_psp": [
+                {
+                    "oid": "0.4.0.19495.1.1",
+                    "name": "PSP_AS"
+                },
+                {
+                    "oid": "0.4.0.19495.1.3",
+                    "name": "PSP_AI"
+                }
+            ],
+            "nca_name": "Financial Conduct Authority",
+            "nca_id": "GB-FCA"
         }
     },
     "ms_extension_template": {
@@ -243,6 +277,15 @@ var testRequestFullRequest = hvclient.Request{
 				Value: "upn@demo.hvca.globalsign.com",
 			},
 		},
+		RegisteredIDs: []asn1.ObjectIdentifier{
+			{1, 3, 6, 1, 4, 1, 4146, 1, 1},
+		},
+		DirectoryNames: []hvclient.DN{
+			{
+				Organization: "GMO GlobalSign",
+				CommonName:   "Jane Doe",
+			},
+		},
 	},
 	EKUs: []asn1.ObjectIdentifier{
 		{1, 3, 6, 1, 5, 5, 7, 3, 1},
@@ -277,6 +320,14 @@ var testRequestFullRequest = hvclient.Request{
 			"EN": "https://demo.hvsign.globalsign.com/en/pds",
 			"RU": "https://demo.hvsign.globalsign.com/ru/pds",
 		},
+		PSD2: &hvclient.PSD2{
+			RolesOfPSP: []hvclient.PSPRole{
+				hvclient.PSPRoleAccountServicing,
+				hvclient.PSPRoleAccountInformation,
+			},
+			NCAName: "Financial Conduct Authority",
+			NCAID:   "GB-FCA",
+		},
 	},
 	MSExtension: &hvclient.MSExtension{
 		OID:          asn1.ObjectIdentifier{1, 2, 3, 4},
@@ -312,6 +363,23 @@ func TestRequestMarshalJSON(t *testing.T) {
 			req:  testRequestFullRequest,
 			want: testRequestFullJSON,
 		},
+		{
+			name: "Extra",
+			req: hvclient.Request{
+				Subject: &hvclient.DN{CommonName: "John Doe"},
+				Extra: map[string]json.RawMessage{
+					"future_field": json.RawMessage(`{"enabled":true}`),
+				},
+			},
+			want: `{
+    "future_field": {
+        "enabled": true
+    },
+    "subject_dn": {
+        "common_name": "John Doe"
+    }
+}`,
+		},
 		{
 			name: "CSR",
 			req: hvclient.Request{
@@ -365,6 +433,109 @@ func TestRequestMarshalJSON(t *testing.T) {
 			want: `{
     "public_key": "-----BEGIN PUBLIC KEY-----\nMIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA7s0nIwA4nzrc5az0iD6F\n710WI2BnabCVe1wNXUckq7RdWXtshlQODZow+M6t7P2FLolYYyhT9vD5hFlMNBKY\nFqAAkauGlmx12luVyURRLW0ht9Piu41MaLnLCCMM7tQ/5lixMHkT86sX/wX8q32Z\nOuatyUgVQUV1hKXZCH12y9VK9U3pQGoPgG15SbCo6yfUYvYLp7NmNEb55Gz4I1xf\n4PBaRvynr0dtwbFXQOQAfg+q29sm+elYnAQLvtVVyYmfn+jqK9u1Ey+X2sNns3HW\nz9OSQt7e9lFIKMlospQPl4YuGhfcID/xC1gZLV5wlvghFJx/1QUW/yI3MZGXpIav\njwIDAQAB\n-----END PUBLIC KEY-----",
     "public_key_signature": "rJy3l3t5ZcaN33b3cIAkVGVeef9B4hh+5m2Os5cJBkZGy6pcb+PXSZeqoRfNDUu4VhAt5vvloPe2Xo6qT4iEQ82qNl+exbpnV5ou/id6O8P2FYB2+tETDFjotMMlNYKiqPRBesVivbqhwUd91btOQHNd6t2qAWIcDioAZBwnjLJPNjPtK5In1Y1+CGvCLNdtRKB0g783mpxn7PzRAKUzimj9imPmo8cCWcgySvIK6fs8VoZU38dSgKuWCpEFfFaB5/EkXHcFC9BfJm3e4J69kZtnMJAbHwAXW23azcOuXIi8n4vZWoo4pQgZhSksXG8Ibx08hh65wZ+i6HqT5Zf71w=="
+}`,
+		},
+		{
+			name: "ExtraAttributeValues",
+			req: hvclient.Request{
+				Subject: &hvclient.DN{
+					CommonName: "John Doe",
+					ExtraAttributes: []hvclient.OIDAndString{
+						{
+							OID:   asn1.ObjectIdentifier{2, 5, 4, 42},
+							Value: "John",
+						},
+					},
+					ExtraAttributeValues: []hvclient.OIDAndValues{
+						{
+							OID:    asn1.ObjectIdentifier{2, 5, 4, 4},
+							Values: []interface{}{"Doe", "Smith"},
+						},
+						{
+							OID:    asn1.ObjectIdentifier{1, 2, 3, 4},
+							Values: []interface{}{42, big.NewInt(-7)},
+						},
+						{
+							OID:    asn1.ObjectIdentifier{1, 2, 3, 5},
+							Values: []interface{}{asn1.RawValue{FullBytes: []byte{0x05, 0x00}}},
+						},
+					},
+				},
+			},
+			want: `{
+    "subject_dn": {
+        "common_name": "John Doe",
+        "extra_attributes": [
+            {
+                "type": "2.5.4.42",
+                "value": "John"
+            },
+            {
+                "type": "2.5.4.4",
+                "value": "Doe"
+            },
+            {
+                "type": "2.5.4.4",
+                "value": "Smith"
+            },
+            {
+                "type": "1.2.3.4",
+                "value": "42"
+            },
+            {
+                "type": "1.2.3.4",
+                "value": "-7"
+            },
+            {
+                "type": "1.2.3.5",
+                "value": "BQA="
+            }
+        ]
+    }
+}`,
+		},
+		{
+			name: "CustomExtensionValues",
+			req: hvclient.Request{
+				CustomExtensions: []hvclient.OIDAndString{
+					{
+						OID:   asn1.ObjectIdentifier{2, 5, 29, 99, 2},
+						Value: "SOME TEXT",
+					},
+				},
+				CustomExtensionValues: []hvclient.CustomExtensionValue{
+					{
+						OID:   asn1.ObjectIdentifier{2, 5, 29, 99, 3},
+						Value: big.NewInt(-42),
+					},
+					{
+						OID:   asn1.ObjectIdentifier{2, 5, 29, 99, 1},
+						Value: asn1.RawValue{FullBytes: []byte{0x02, 0x01, 0x2a}},
+					},
+				},
+			},
+			want: `{
+    "custom_extensions": {
+        "2.5.29.99.2": "SOME TEXT",
+        "2.5.29.99.3": "-42",
+        "2.5.29.99.1": "AgEq"
+    }
+}`,
+		},
+		{
+			name: "ValidityRFC3339",
+			req: hvclient.Request{
+				Validity: &hvclient.Validity{
+					NotBefore: time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC),
+					NotAfter:  time.Date(2025, 4, 2, 15, 4, 5, 0, time.FixedZone("X", 3600)),
+					RFC3339:   true,
+				},
+			},
+			want: `{
+    "validity": {
+        "not_before": "2025-01-02T15:04:05Z",
+        "not_after": "2025-04-02T14:04:05Z"
+    }
 }`,
 		},
 	}
@@ -406,6 +577,38 @@ func TestRequestMarshalJSONFailure(t *testing.T) {
 				PrivateKey: "not a private key",
 			},
 		},
+		{
+			name: "BadExtraAttributeValue",
+			req: hvclient.Request{
+				Subject: &hvclient.DN{
+					ExtraAttributeValues: []hvclient.OIDAndValues{
+						{
+							OID:    asn1.ObjectIdentifier{1, 2, 3, 4},
+							Values: []interface{}{3.14},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "BadCustomExtensionValue",
+			req: hvclient.Request{
+				CustomExtensionValues: []hvclient.CustomExtensionValue{
+					{
+						OID:   asn1.ObjectIdentifier{1, 2, 3, 4},
+						Value: asn1.RawValue{Tag: asn1.TagNull},
+					},
+				},
+			},
+		},
+		{
+			name: "ExtraConflict",
+			req: hvclient.Request{
+				Extra: map[string]json.RawMessage{
+					"validity": json.RawMessage(`{}`),
+				},
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -444,6 +647,36 @@ func TestRequestUnmarshalJSON(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "ValidityRFC3339",
+			json: `{"validity":{"not_before":"2019-02-12T19:33:20Z","not_after":"2019-06-08T15:20:00+02:00"}}`,
+			want: hvclient.Request{
+				Validity: &hvclient.Validity{
+					NotBefore: time.Unix(1550000000, 0),
+					NotAfter:  time.Unix(1560000000, 0),
+				},
+			},
+		},
+		{
+			name: "ValidityMixed",
+			json: `{"validity":{"not_before":"2019-02-12T19:33:20Z","not_after":null}}`,
+			want: hvclient.Request{
+				Validity: &hvclient.Validity{
+					NotBefore: time.Unix(1550000000, 0),
+					NotAfter:  time.Unix(0, 0),
+				},
+			},
+		},
+		{
+			name: "Extra",
+			json: `{"subject_dn":{"common_name":"John Doe"},"future_field":{ "enabled": true }}`,
+			want: hvclient.Request{
+				Subject: &hvclient.DN{CommonName: "John Doe"},
+				Extra: map[string]json.RawMessage{
+					"future_field": json.RawMessage(`{"enabled":true}`),
+				},
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -470,6 +703,8 @@ func TestRequestUnmarshalJSONFailure(t *testing.T) {
 
 	var testcases = []string{
 		`{"validity":1234}`,
+		`{"validity":{"not_before":"2019-02-12"}}`,
+		`{"validity":{"not_after":true}}`,
 		`{"custom_extensions":{"not.numbers":"NIL"}}`,
 		`{"san":{"uris":["$http://bad.url"]}}`,
 		`{"san":{"other_names":[{"type":"a.b.c","value":"value"}]}}`,
@@ -505,6 +740,15 @@ func TestRequestEqual(t *testing.T) {
 			first:  hvclient.Request{},
 			second: hvclient.Request{},
 		},
+		{
+			name: "ExtraWhitespace",
+			first: hvclient.Request{
+				Extra: map[string]json.RawMessage{"a": json.RawMessage(`[1,2]`)},
+			},
+			second: hvclient.Request{
+				Extra: map[string]json.RawMessage{"a": json.RawMessage(`[ 1, 2 ]`)},
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -527,6 +771,31 @@ func TestRequestNotEqual(t *testing.T) {
 		name          string
 		first, second hvclient.Request
 	}{
+		{
+			name:  "ExtraFirstNil",
+			first: hvclient.Request{},
+			second: hvclient.Request{
+				Extra: map[string]json.RawMessage{"a": json.RawMessage(`1`)},
+			},
+		},
+		{
+			name: "ExtraKey",
+			first: hvclient.Request{
+				Extra: map[string]json.RawMessage{"a": json.RawMessage(`1`)},
+			},
+			second: hvclient.Request{
+				Extra: map[string]json.RawMessage{"b": json.RawMessage(`1`)},
+			},
+		},
+		{
+			name: "ExtraValue",
+			first: hvclient.Request{
+				Extra: map[string]json.RawMessage{"a": json.RawMessage(`1`)},
+			},
+			second: hvclient.Request{
+				Extra: map[string]json.RawMessage{"a": json.RawMessage(`2`)},
+			},
+		},
 		{
 			name: "ValidityFirstNil",
 			first: hvclient.Request{
@@ -975,6 +1244,52 @@ func TestRequestNotEqual(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "SubjectExtraAttributeValuesLength",
+			first: hvclient.Request{
+				Subject: &hvclient.DN{
+					ExtraAttributeValues: []hvclient.OIDAndValues{
+						{
+							OID:    asn1.ObjectIdentifier{1, 2, 3, 4},
+							Values: []interface{}{"a value", "another value"},
+						},
+					},
+				},
+			},
+			second: hvclient.Request{
+				Subject: &hvclient.DN{
+					ExtraAttributeValues: []hvclient.OIDAndValues{
+						{
+							OID:    asn1.ObjectIdentifier{1, 2, 3, 4},
+							Values: []interface{}{"a value"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "SubjectExtraAttributeValuesType",
+			first: hvclient.Request{
+				Subject: &hvclient.DN{
+					ExtraAttributeValues: []hvclient.OIDAndValues{
+						{
+							OID:    asn1.ObjectIdentifier{1, 2, 3, 4},
+							Values: []interface{}{42},
+						},
+					},
+				},
+			},
+			second: hvclient.Request{
+				Subject: &hvclient.DN{
+					ExtraAttributeValues: []hvclient.OIDAndValues{
+						{
+							OID:    asn1.ObjectIdentifier{1, 2, 3, 4},
+							Values: []interface{}{"42"},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "SANOtherNamesLength",
 			first: hvclient.Request{
@@ -1025,6 +1340,76 @@ func TestRequestNotEqual(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "SANRegisteredIDsLength",
+			first: hvclient.Request{
+				SAN: &hvclient.SAN{
+					RegisteredIDs: []asn1.ObjectIdentifier{
+						{1, 2, 3, 4},
+						{1, 2, 3, 5},
+					},
+				},
+			},
+			second: hvclient.Request{
+				SAN: &hvclient.SAN{
+					RegisteredIDs: []asn1.ObjectIdentifier{
+						{1, 2, 3, 4},
+					},
+				},
+			},
+		},
+		{
+			name: "SANRegisteredIDsValue",
+			first: hvclient.Request{
+				SAN: &hvclient.SAN{
+					RegisteredIDs: []asn1.ObjectIdentifier{
+						{1, 2, 3, 4},
+					},
+				},
+			},
+			second: hvclient.Request{
+				SAN: &hvclient.SAN{
+					RegisteredIDs: []asn1.ObjectIdentifier{
+						{1, 2, 3, 5},
+					},
+				},
+			},
+		},
+		{
+			name: "SANDirectoryNamesLength",
+			first: hvclient.Request{
+				SAN: &hvclient.SAN{
+					DirectoryNames: []hvclient.DN{
+						{CommonName: "John Doe"},
+						{CommonName: "Jane Doe"},
+					},
+				},
+			},
+			second: hvclient.Request{
+				SAN: &hvclient.SAN{
+					DirectoryNames: []hvclient.DN{
+						{CommonName: "John Doe"},
+					},
+				},
+			},
+		},
+		{
+			name: "SANDirectoryNamesValue",
+			first: hvclient.Request{
+				SAN: &hvclient.SAN{
+					DirectoryNames: []hvclient.DN{
+						{CommonName: "John Doe"},
+					},
+				},
+			},
+			second: hvclient.Request{
+				SAN: &hvclient.SAN{
+					DirectoryNames: []hvclient.DN{
+						{CommonName: "Jane Doe"},
+					},
+				},
+			},
+		},
 		{
 			name:  "DAFirstNil",
 			first: hvclient.Request{},
@@ -1340,6 +1725,60 @@ func TestRequestNotEqual(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "QualifiedStatementsPSD2FirstNil",
+			first: hvclient.Request{QualifiedStatements: &hvclient.QualifiedStatements{}},
+			second: hvclient.Request{
+				QualifiedStatements: &hvclient.QualifiedStatements{
+					PSD2: &hvclient.PSD2{NCAID: "GB-FCA"},
+				},
+			},
+		},
+		{
+			name: "QualifiedStatementsPSD2RolesLength",
+			first: hvclient.Request{
+				QualifiedStatements: &hvclient.QualifiedStatements{
+					PSD2: &hvclient.PSD2{
+						RolesOfPSP: []hvclient.PSPRole{hvclient.PSPRolePaymentInitiation},
+					},
+				},
+			},
+			second: hvclient.Request{
+				QualifiedStatements: &hvclient.QualifiedStatements{
+					PSD2: &hvclient.PSD2{},
+				},
+			},
+		},
+		{
+			name: "QualifiedStatementsPSD2RolesValue",
+			first: hvclient.Request{
+				QualifiedStatements: &hvclient.QualifiedStatements{
+					PSD2: &hvclient.PSD2{
+						RolesOfPSP: []hvclient.PSPRole{hvclient.PSPRolePaymentInitiation},
+					},
+				},
+			},
+			second: hvclient.Request{
+				QualifiedStatements: &hvclient.QualifiedStatements{
+					PSD2: &hvclient.PSD2{
+						RolesOfPSP: []hvclient.PSPRole{hvclient.PSPRoleIssuingCardBasedPay},
+					},
+				},
+			},
+		},
+		{
+			name: "QualifiedStatementsPSD2NCAName",
+			first: hvclient.Request{
+				QualifiedStatements: &hvclient.QualifiedStatements{
+					PSD2: &hvclient.PSD2{NCAName: "Financial Conduct Authority"},
+				},
+			},
+			second: hvclient.Request{
+				QualifiedStatements: &hvclient.QualifiedStatements{
+					PSD2: &hvclient.PSD2{NCAName: "Bank of England"},
+				},
+			},
+		},
 		{
 			name:  "MSExtensionFirstNil",
 			first: hvclient.Request{},
@@ -1466,6 +1905,37 @@ func TestRequestNotEqual(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "CustomExtensionValuesDifferentLength",
+			first: hvclient.Request{
+				CustomExtensionValues: []hvclient.CustomExtensionValue{
+					{
+						OID:   asn1.ObjectIdentifier{2, 5, 29, 99, 1},
+						Value: 42,
+					},
+				},
+			},
+			second: hvclient.Request{},
+		},
+		{
+			name: "CustomExtensionValuesDifferentType",
+			first: hvclient.Request{
+				CustomExtensionValues: []hvclient.CustomExtensionValue{
+					{
+						OID:   asn1.ObjectIdentifier{2, 5, 29, 99, 1},
+						Value: 42,
+					},
+				},
+			},
+			second: hvclient.Request{
+				CustomExtensionValues: []hvclient.CustomExtensionValue{
+					{
+						OID:   asn1.ObjectIdentifier{2, 5, 29, 99, 1},
+						Value: "42",
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -1532,6 +2002,40 @@ func TestRequestPKCS10(t *testing.T) {
 				PrivateKey: testhelpers.MustGetPrivateKeyFromFile(t, "testdata/rsa_priv.key"),
 			},
 		},
+		{
+			name: "RegisteredIDsAndDirectoryNames",
+			request: hvclient.Request{
+				Subject: &hvclient.DN{
+					CommonName: "John Doe",
+					ExtraAttributeValues: []hvclient.OIDAndValues{
+						{
+							OID:    asn1.ObjectIdentifier{2, 5, 4, 4},
+							Values: []interface{}{"Doe", "Smith"},
+						},
+						{
+							OID:    asn1.ObjectIdentifier{1, 2, 3, 4},
+							Values: []interface{}{42},
+						},
+					},
+				},
+				SAN: &hvclient.SAN{
+					DNSNames:    []string{"domain1.acme.com"},
+					Emails:      []string{"jdoe@acme.com"},
+					IPAddresses: []net.IP{net.ParseIP("192.168.1.1")},
+					URIs:        []*url.URL{testhelpers.MustParseURI(t, "http://badger.acme.com")},
+					RegisteredIDs: []asn1.ObjectIdentifier{
+						{1, 3, 6, 1, 4, 1, 4146, 1, 1},
+					},
+					DirectoryNames: []hvclient.DN{
+						{
+							Organization: "ACME Inc",
+							CommonName:   "Jane Doe",
+						},
+					},
+				},
+				PrivateKey: testhelpers.MustGetPrivateKeyFromFile(t, "testdata/rsa_priv.key"),
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -1548,6 +2052,23 @@ func TestRequestPKCS10(t *testing.T) {
 			if err = got.CheckSignature(); err != nil {
 				t.Errorf("signature check failed: %v", err)
 			}
+
+			// Verify the names the x509 package understands made it into
+			// the request, whichever way the extension was built.
+			var san = &hvclient.SAN{
+				DNSNames:    got.DNSNames,
+				Emails:      got.EmailAddresses,
+				IPAddresses: got.IPAddresses,
+				URIs:        got.URIs,
+			}
+
+			var want = *tc.request.SAN
+			want.RegisteredIDs = nil
+			want.DirectoryNames = nil
+
+			if !san.Equal(&want) {
+				t.Errorf("got SAN %v, want %v", san, want)
+			}
 		})
 	}
 }
@@ -1591,3 +2112,305 @@ func mustParseURI(uri string) *url.URL {
 
 	return parsed
 }
+
+func TestDNPKIXNameExtraAttributeValues(t *testing.T) {
+	t.Parallel()
+
+	var dn = hvclient.DN{
+		CommonName: "John Doe",
+		ExtraAttributeValues: []hvclient.OIDAndValues{
+			{
+				OID:    asn1.ObjectIdentifier{2, 5, 4, 4},
+				Values: []interface{}{"Doe", "Smith"},
+			},
+			{
+				OID:    asn1.ObjectIdentifier{1, 2, 3, 4},
+				Values: []interface{}{42},
+			},
+			{
+				OID:    asn1.ObjectIdentifier{1, 2, 3, 5},
+				Values: []interface{}{asn1.RawValue{FullBytes: []byte{0x05, 0x00}}},
+			},
+		},
+	}
+
+	var der, err = asn1.Marshal(dn.PKIXName().ToRDNSequence())
+	if err != nil {
+		t.Fatalf("couldn't marshal name: %v", err)
+	}
+
+	var rdns pkix.RDNSequence
+	if _, err = asn1.Unmarshal(der, &rdns); err != nil {
+		t.Fatalf("couldn't unmarshal name: %v", err)
+	}
+
+	var got []interface{}
+	for _, rdn := range rdns {
+		for _, atv := range rdn {
+			got = append(got, atv.Value)
+		}
+	}
+
+	var want = []interface{}{"John Doe", "Doe", "Smith", int64(42), nil}
+	if !cmp.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRequestCustomExtensionsEscaping(t *testing.T) {
+	t.Parallel()
+
+	var req = hvclient.Request{
+		CustomExtensions: []hvclient.OIDAndString{
+			{OID: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: `quoted "value" with \ backslash`},
+			{OID: asn1.ObjectIdentifier{1, 2, 3, 5}, Value: "multi\nline"},
+			hvclient.NewOIDAndDER(asn1.ObjectIdentifier{1, 2, 3, 6}, []byte{0x30, 0x03, 0x02, 0x01, 0xff}),
+		},
+	}
+
+	var data, err = json.Marshal(req)
+	if err != nil {
+		t.Fatalf("couldn't marshal JSON: %v", err)
+	}
+
+	var want = `{"custom_extensions":{"1.2.3.4":"quoted \"value\" with \\ backslash","1.2.3.5":"multi\nline","1.2.3.6":"MAMCAf8="}}`
+	if string(data) != want {
+		t.Fatalf("got %s, want %s", data, want)
+	}
+
+	var got hvclient.Request
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("couldn't unmarshal JSON: %v", err)
+	}
+
+	if !got.Equal(req) {
+		t.Fatalf("got %v, want %v", got, req)
+	}
+
+	var der []byte
+	if der, err = got.CustomExtensions[2].DER(); err != nil {
+		t.Fatalf("couldn't decode DER value: %v", err)
+	}
+
+	if !bytes.Equal(der, []byte{0x30, 0x03, 0x02, 0x01, 0xff}) {
+		t.Errorf("got DER %x, want %x", der, []byte{0x30, 0x03, 0x02, 0x01, 0xff})
+	}
+
+	if _, err = got.CustomExtensions[0].DER(); err == nil {
+		t.Errorf("unexpectedly decoded DER value")
+	}
+}
+
+func TestRequestPublicKeySignature(t *testing.T) {
+	t.Parallel()
+
+	var rsaKey = testhelpers.MustParseRSAPrivateKey(t, testRequestRSAPrivateKeyPEM)
+	var ecKey = testhelpers.MustParseECPrivateKey(t, testRequestECPrivateKeyPEM)
+
+	var testcases = []struct {
+		name string
+		key  crypto.Signer
+	}{
+		{
+			name: "RSA",
+			key:  rsaKey,
+		},
+		{
+			name: "EC",
+			key:  ecKey,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var sig = mustSignPublicKey(t, tc.key)
+
+			var data, err = json.Marshal(hvclient.Request{
+				PublicKey:          tc.key.Public(),
+				PublicKeySignature: sig,
+			})
+			if err != nil {
+				t.Fatalf("couldn't marshal JSON: %v", err)
+			}
+
+			var got struct {
+				PublicKeySignature string `json:"public_key_signature"`
+			}
+			if err = json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("couldn't unmarshal JSON: %v", err)
+			}
+
+			if got.PublicKeySignature != sig {
+				t.Errorf("got public key signature %q, want %q", got.PublicKeySignature, sig)
+			}
+		})
+	}
+}
+
+func TestRequestPublicKeySignatureFailure(t *testing.T) {
+	t.Parallel()
+
+	var rsaKey = testhelpers.MustParseRSAPrivateKey(t, testRequestRSAPrivateKeyPEM)
+	var ecKey = testhelpers.MustParseECPrivateKey(t, testRequestECPrivateKeyPEM)
+
+	var testcases = []struct {
+		name string
+		req  hvclient.Request
+		err  error
+	}{
+		{
+			name: "NoPublicKey",
+			req: hvclient.Request{
+				PrivateKey:         rsaKey,
+				PublicKeySignature: mustSignPublicKey(t, rsaKey),
+			},
+		},
+		{
+			name: "WrongKey",
+			req: hvclient.Request{
+				PublicKey:          rsaKey.Public(),
+				PublicKeySignature: mustSignPublicKey(t, ecKey),
+			},
+			err: hvclient.ErrInvalidPublicKeySignature,
+		},
+		{
+			name: "WrongKeyEC",
+			req: hvclient.Request{
+				PublicKey:          ecKey.Public(),
+				PublicKeySignature: mustSignPublicKey(t, rsaKey),
+			},
+			err: hvclient.ErrInvalidPublicKeySignature,
+		},
+		{
+			name: "NotBase64",
+			req: hvclient.Request{
+				PublicKey:          rsaKey.Public(),
+				PublicKeySignature: "not base64!",
+			},
+			err: hvclient.ErrInvalidPublicKeySignature,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var _, err = json.Marshal(tc.req)
+			if err == nil {
+				t.Fatalf("unexpectedly marshalled JSON")
+			}
+
+			if tc.err != nil && !errors.Is(err, tc.err) {
+				t.Errorf("got error %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
+
+// mustSignPublicKey returns the base64-encoded signature of the SHA-256 hash
+// of the DER-encoded public key of the specified signer, as would be computed
+// outside the process by a hardware security module.
+func TestPublicKeySignatureDigest(t *testing.T) {
+	t.Parallel()
+
+	var rsaKey = testhelpers.MustParseRSAPrivateKey(t, testRequestRSAPrivateKeyPEM)
+	var ecKey = testhelpers.MustParseECPrivateKey(t, testRequestECPrivateKeyPEM)
+
+	var testcases = []struct {
+		name string
+		key  crypto.Signer
+		pub  interface{}
+	}{
+		{
+			name: "RSA",
+			key:  rsaKey,
+			pub:  &rsaKey.PublicKey,
+		},
+		{
+			name: "RSAValue",
+			key:  rsaKey,
+			pub:  rsaKey.PublicKey,
+		},
+		{
+			name: "EC",
+			key:  ecKey,
+			pub:  &ecKey.PublicKey,
+		},
+		{
+			name: "ECValue",
+			key:  ecKey,
+			pub:  ecKey.PublicKey,
+		},
+		{
+			name: "JWK",
+			key:  ecKey,
+			pub:  ecJWK(ecKey, false),
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var digest, err = hvclient.PublicKeySignatureDigest(tc.pub)
+			if err != nil {
+				t.Fatalf("couldn't compute digest: %v", err)
+			}
+
+			var sig []byte
+			if sig, err = tc.key.Sign(rand.Reader, digest, hvclient.PublicKeySignatureHash); err != nil {
+				t.Fatalf("couldn't sign digest: %v", err)
+			}
+
+			// The request will fail to marshal if the signature does not
+			// match the public key.
+			if _, err = json.Marshal(hvclient.Request{
+				PublicKey:          tc.pub,
+				PublicKeySignature: base64.StdEncoding.EncodeToString(sig),
+			}); err != nil {
+				t.Fatalf("couldn't marshal JSON: %v", err)
+			}
+		})
+	}
+}
+
+func TestPublicKeySignatureDigestFailure(t *testing.T) {
+	t.Parallel()
+
+	var _, edKey, err = ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("couldn't generate key: %v", err)
+	}
+
+	for _, pub := range []interface{}{nil, "not a key", edKey.Public()} {
+		if got, err := hvclient.PublicKeySignatureDigest(pub); err == nil {
+			t.Errorf("unexpectedly computed digest for %T: %x", pub, got)
+		}
+	}
+}
+
+func mustSignPublicKey(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+
+	var der, err = x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatalf("couldn't marshal public key: %v", err)
+	}
+
+	var h = sha256.Sum256(der)
+
+	var sig []byte
+	if sig, err = key.Sign(rand.Reader, h[:], crypto.SHA256); err != nil {
+		t.Fatalf("couldn't sign public key: %v", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(sig)
+}
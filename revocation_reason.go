@@ -0,0 +1,94 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidRevocationReason is matched, using errors.Is, by errors returned
+// when a revocation reason is not one of the allowed revocation reasons.
+var ErrInvalidRevocationReason = errors.New("invalid revocation reason")
+
+// InvalidRevocationReasonError is returned when a revocation reason is not
+// one of the allowed revocation reasons.
+type InvalidRevocationReasonError struct {
+	// Reason is the rejected revocation reason.
+	Reason RevocationReason
+
+	// Allowed are the allowed revocation reasons.
+	Allowed []RevocationReason
+}
+
+// revocationReasons are the allowed revocation reasons, in the order in
+// which they are listed in errors. The removeFromCRL reason code is not
+// included, since it is only used in CRL entries for certificates released
+// from hold, and cannot be the reason for a revocation.
+var revocationReasons = []RevocationReason{
+	RevocationReasonUnspecified,
+	RevocationReasonKeyCompromise,
+	RevocationReasonAffiliationChanged,
+	RevocationReasonSuperseded,
+	RevocationReasonCessationOfOperation,
+	RevocationReasonCertificateHold,
+	RevocationReasonPrivilegeWithdrawn,
+}
+
+// RevocationReasons returns the allowed revocation reasons.
+func RevocationReasons() []RevocationReason {
+	return append([]RevocationReason(nil), revocationReasons...)
+}
+
+// ParseRevocationReason returns the revocation reason with the specified
+// name, or an *InvalidRevocationReasonError if it is not one of the allowed
+// revocation reasons.
+func ParseRevocationReason(s string) (RevocationReason, error) {
+	var reason = RevocationReason(s)
+	if err := reason.Validate(); err != nil {
+		return "", err
+	}
+
+	return reason, nil
+}
+
+// Validate returns an *InvalidRevocationReasonError if the revocation reason
+// is not one of the allowed revocation reasons.
+func (r RevocationReason) Validate() error {
+	for _, reason := range revocationReasons {
+		if r == reason {
+			return nil
+		}
+	}
+
+	return &InvalidRevocationReasonError{Reason: r, Allowed: RevocationReasons()}
+}
+
+// Error returns a string representation of the error.
+func (e *InvalidRevocationReasonError) Error() string {
+	var allowed = make([]string, 0, len(e.Allowed))
+	for _, reason := range e.Allowed {
+		allowed = append(allowed, string(reason))
+	}
+
+	return fmt.Sprintf("%v %q: must be one of %s", ErrInvalidRevocationReason, e.Reason, strings.Join(allowed, ", "))
+}
+
+// Is reports whether target is ErrInvalidRevocationReason.
+func (e *InvalidRevocationReasonError) Is(target error) bool {
+	return target == ErrInvalidRevocationReason
+}
@@ -0,0 +1,88 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+)
+
+func TestParseRevocationReason(t *testing.T) {
+	t.Parallel()
+
+	for _, reason := range hvclient.RevocationReasons() {
+		var got, err = hvclient.ParseRevocationReason(string(reason))
+		if err != nil {
+			t.Errorf("couldn't parse revocation reason %q: %v", reason, err)
+		}
+
+		if got != reason {
+			t.Errorf("got %q, want %q", got, reason)
+		}
+	}
+}
+
+func TestParseRevocationReasonFailure(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"", "bored", "KeyCompromise", "aACompromise", "removeFromCRL"} {
+		var s = s
+
+		t.Run(s, func(t *testing.T) {
+			t.Parallel()
+
+			var _, err = hvclient.ParseRevocationReason(s)
+			if !errors.Is(err, hvclient.ErrInvalidRevocationReason) {
+				t.Fatalf("got error %v, want %v", err, hvclient.ErrInvalidRevocationReason)
+			}
+
+			var reasonErr *hvclient.InvalidRevocationReasonError
+			if !errors.As(err, &reasonErr) {
+				t.Fatalf("got error %T, want %T", err, reasonErr)
+			}
+
+			if reasonErr.Reason != hvclient.RevocationReason(s) {
+				t.Errorf("got reason %q, want %q", reasonErr.Reason, s)
+			}
+
+			if len(reasonErr.Allowed) != len(hvclient.RevocationReasons()) {
+				t.Errorf("got allowed reasons %q, want %q", reasonErr.Allowed, hvclient.RevocationReasons())
+			}
+		})
+	}
+}
+
+func TestClientMockCertificateRevokeInvalidReason(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, reason := range []hvclient.RevocationReason{"bored", "removeFromCRL"} {
+		var err = client.CertificateRevokeWithReason(ctx, big.NewInt(0x741daf9ec2d5f7dc), reason, 0)
+		if !errors.Is(err, hvclient.ErrInvalidRevocationReason) {
+			t.Errorf("%s: got error %v, want %v", reason, err, hvclient.ErrInvalidRevocationReason)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package revoke queues certificate revocations to be made at a later time,
+such as during a change window when decommissioning a service.
+
+HVCA revokes certificates immediately, and the revocation time accepted by
+the revocation API records when a private key was compromised rather than
+scheduling the revocation. A Scheduler therefore holds queued revocations in
+a state file, so they survive restarts, and revokes each certificate once
+its scheduled time has passed, either when RevokeDue is called or
+periodically while Run is running.
+*/
+package revoke
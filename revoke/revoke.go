@@ -0,0 +1,360 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revoke
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/fileutils"
+)
+
+// Revoker is the subset of HVCA client functionality used to revoke
+// certificates. It is satisfied by *hvclient.Client.
+type Revoker interface {
+	CertificateRevokeWithReason(ctx context.Context, serial *big.Int, reason hvclient.RevocationReason, time int64) error
+}
+
+// Revocation is a queued certificate revocation.
+type Revocation struct {
+	SerialNumber *big.Int
+	Reason       hvclient.RevocationReason
+	At           time.Time
+}
+
+// jsonRevocation is used internally for JSON marshalling/unmarshalling.
+type jsonRevocation struct {
+	SerialNumber string                    `json:"serial_number"`
+	Reason       hvclient.RevocationReason `json:"reason"`
+	At           int64                     `json:"revoke_at"`
+}
+
+// Config is a configuration object for a revocation scheduler.
+type Config struct {
+	// Client is used to revoke certificates, and is usually an
+	// *hvclient.Client. It is required.
+	Client Revoker
+
+	// StateFile is the path to the file in which queued revocations are
+	// stored. The file is created when the first revocation is queued, and
+	// is read afresh by each operation, so more than one scheduler, such as
+	// one queueing revocations and another making them, may share it, even
+	// from different processes. Operations which change the queue hold an
+	// advisory lock on a lock file alongside it. It is required.
+	StateFile string
+
+	// Interval is the period between checks for due revocations when the
+	// scheduler is run. If this is omitted or set to zero, a default of one
+	// minute is used.
+	Interval time.Duration
+
+	// OnError, if not nil, is called with any error encountered when
+	// revoking a certificate while the scheduler is run.
+	OnError func(serial *big.Int, err error)
+
+	// Clock, if not nil, is used as the source of the current time and of
+	// the timer between checks, in place of the system clock.
+	Clock hvclient.Clock
+}
+
+// Scheduler queues certificate revocations and makes them once they are
+// due. A scheduler is safe for concurrent use.
+type Scheduler struct {
+	config Config
+	mtx    sync.Mutex
+}
+
+// defaultInterval is the period between checks if none is specified in the
+// configuration.
+var defaultInterval = time.Minute
+
+// New returns a new revocation scheduler.
+func New(conf Config) (*Scheduler, error) {
+	if conf.Client == nil {
+		return nil, errors.New("no client provided")
+	}
+
+	if conf.StateFile == "" {
+		return nil, errors.New("no state file provided")
+	}
+
+	if conf.Clock == nil {
+		conf.Clock = hvclient.SystemClock
+	}
+
+	if conf.Interval == 0 {
+		conf.Interval = defaultInterval
+	} else if conf.Interval < 0 {
+		return nil, errors.New("interval cannot be negative")
+	}
+
+	return &Scheduler{config: conf}, nil
+}
+
+// Schedule queues the revocation of the certificate with the specified
+// serial number at the specified time, replacing any revocation already
+// queued for that certificate.
+func (s *Scheduler) Schedule(serial *big.Int, reason hvclient.RevocationReason, at time.Time) error {
+	if serial == nil {
+		return errors.New("no serial number provided")
+	}
+
+	if err := reason.Validate(); err != nil {
+		return err
+	}
+
+	var unlock, err = s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var queue []Revocation
+	if queue, err = s.load(); err != nil {
+		return err
+	}
+
+	queue = remove(queue, serial)
+	queue = append(queue, Revocation{
+		SerialNumber: big.NewInt(0).Set(serial),
+		Reason:       reason,
+		At:           at,
+	})
+
+	return s.save(queue)
+}
+
+// Cancel removes the queued revocation of the certificate with the
+// specified serial number.
+func (s *Scheduler) Cancel(serial *big.Int) error {
+	var unlock, err = s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var queue []Revocation
+	if queue, err = s.load(); err != nil {
+		return err
+	}
+
+	var remaining = remove(queue, serial)
+	if len(remaining) == len(queue) {
+		return fmt.Errorf("no revocation queued for certificate %X", serial)
+	}
+
+	return s.save(remaining)
+}
+
+// Pending returns the queued revocations, in order of scheduled time.
+func (s *Scheduler) Pending() ([]Revocation, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.load()
+}
+
+// RevokeDue revokes each queued certificate whose scheduled time has
+// passed, in order of scheduled time, and removes it from the queue. A
+// revocation which fails remains queued and is retried by the next call.
+// The first error encountered is returned after all due revocations have
+// been attempted.
+func (s *Scheduler) RevokeDue(ctx context.Context) error {
+	var first error
+
+	s.revokeDue(ctx, func(serial *big.Int, err error) {
+		if first == nil {
+			first = err
+		}
+	})
+
+	return first
+}
+
+// Run revokes due certificates immediately and then at the configured
+// interval until the context is cancelled, at which point the context's
+// error is returned. Errors encountered are passed to the configured error
+// function, if any.
+func (s *Scheduler) Run(ctx context.Context) error {
+	var onError = s.config.OnError
+	if onError == nil {
+		onError = func(*big.Int, error) {}
+	}
+
+	for {
+		s.revokeDue(ctx, onError)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-s.config.Clock.After(s.config.Interval):
+		}
+	}
+}
+
+// revokeDue revokes each queued certificate whose scheduled time has
+// passed, and passes any errors to the specified function.
+func (s *Scheduler) revokeDue(ctx context.Context, onError func(*big.Int, error)) {
+	var unlock, err = s.lock()
+	if err != nil {
+		onError(nil, err)
+		return
+	}
+	defer unlock()
+
+	var queue []Revocation
+	if queue, err = s.load(); err != nil {
+		onError(nil, err)
+		return
+	}
+
+	var now = s.config.Clock.Now()
+	var remaining = make([]Revocation, 0, len(queue))
+
+	for _, rev := range queue {
+		if rev.At.After(now) || ctx.Err() != nil {
+			remaining = append(remaining, rev)
+			continue
+		}
+
+		if err = s.config.Client.CertificateRevokeWithReason(ctx, rev.SerialNumber, rev.Reason, 0); err != nil {
+			onError(rev.SerialNumber, fmt.Errorf("couldn't revoke certificate %X: %w", rev.SerialNumber, err))
+			remaining = append(remaining, rev)
+		}
+	}
+
+	if len(remaining) == len(queue) {
+		return
+	}
+
+	if err = s.save(remaining); err != nil {
+		onError(nil, err)
+	}
+}
+
+// load reads the queued revocations from the state file, in order of
+// scheduled time. A missing state file is treated as an empty queue.
+func (s *Scheduler) load() ([]Revocation, error) {
+	var data, err = ioutil.ReadFile(s.config.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("couldn't read state file: %w", err)
+	}
+
+	var entries []jsonRevocation
+	if err = json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("couldn't parse state file: %w", err)
+	}
+
+	var queue = make([]Revocation, 0, len(entries))
+	for _, entry := range entries {
+		var serial, err = hvclient.ParseSerialNumber(entry.SerialNumber)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse state file: %w", err)
+		}
+
+		queue = append(queue, Revocation{
+			SerialNumber: serial.Big(),
+			Reason:       entry.Reason,
+			At:           time.Unix(entry.At, 0).UTC(),
+		})
+	}
+
+	sortQueue(queue)
+
+	return queue, nil
+}
+
+// save writes the queued revocations to the state file. The file is
+// replaced atomically, so a failure part way through does not lose the
+// existing queue.
+func (s *Scheduler) save(queue []Revocation) error {
+	sortQueue(queue)
+
+	var entries = make([]jsonRevocation, 0, len(queue))
+	for _, rev := range queue {
+		entries = append(entries, jsonRevocation{
+			SerialNumber: hvclient.NewSerialNumber(rev.SerialNumber).Hex(),
+			Reason:       rev.Reason,
+			At:           rev.At.Unix(),
+		})
+	}
+
+	var data, err = json.MarshalIndent(entries, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err = fileutils.WriteFile(s.config.StateFile, data, 0600); err != nil {
+		return fmt.Errorf("couldn't write state file: %w", err)
+	}
+
+	return nil
+}
+
+// lock excludes other goroutines and, through an advisory lock on the lock
+// file alongside the state file, other processes from changing the queue. It
+// returns a function which releases the lock.
+func (s *Scheduler) lock() (func(), error) {
+	s.mtx.Lock()
+
+	var lock, err = fileutils.LockFile(s.config.StateFile + fileutils.LockSuffix)
+	if err != nil {
+		s.mtx.Unlock()
+		return nil, err
+	}
+
+	return func() {
+		lock.Unlock()
+		s.mtx.Unlock()
+	}, nil
+}
+
+// remove returns the queued revocations other than the one for the
+// certificate with the specified serial number.
+func remove(queue []Revocation, serial *big.Int) []Revocation {
+	var remaining = make([]Revocation, 0, len(queue))
+	for _, rev := range queue {
+		if rev.SerialNumber.Cmp(serial) != 0 {
+			remaining = append(remaining, rev)
+		}
+	}
+
+	return remaining
+}
+
+// sortQueue sorts queued revocations in order of scheduled time, and then
+// of serial number.
+func sortQueue(queue []Revocation) {
+	sort.Slice(queue, func(i, j int) bool {
+		if !queue[i].At.Equal(queue[j].At) {
+			return queue[i].At.Before(queue[j].At)
+		}
+
+		return queue[i].SerialNumber.Cmp(queue[j].SerialNumber) < 0
+	})
+}
@@ -0,0 +1,247 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revoke_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/revoke"
+	"github.com/google/go-cmp/cmp"
+)
+
+// revokeRecorder is a Revoker which records the serial numbers and reasons
+// of the certificates it revokes, and fails for any serial number in fail.
+type revokeRecorder struct {
+	mtx     sync.Mutex
+	revoked []string
+	fail    map[string]bool
+}
+
+func (r *revokeRecorder) CertificateRevokeWithReason(
+	_ context.Context,
+	serial *big.Int,
+	reason hvclient.RevocationReason,
+	_ int64,
+) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var sn = serial.Text(16)
+	if r.fail[sn] {
+		return errors.New("revocation failed")
+	}
+
+	r.revoked = append(r.revoked, sn+":"+string(reason))
+
+	return nil
+}
+
+// fixedClock is a Clock which always returns the same time.
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func (c fixedClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+var testNow = time.Date(2021, 6, 18, 12, 0, 0, 0, time.UTC)
+
+func newTestScheduler(t *testing.T, clnt revoke.Revoker, now time.Time) (*revoke.Scheduler, string) {
+	t.Helper()
+
+	var path = filepath.Join(t.TempDir(), "revocations.json")
+
+	var s, err = revoke.New(revoke.Config{
+		Client:    clnt,
+		StateFile: path,
+		Clock:     fixedClock{now: now},
+	})
+	if err != nil {
+		t.Fatalf("couldn't create scheduler: %v", err)
+	}
+
+	return s, path
+}
+
+func TestNewFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		conf revoke.Config
+	}{
+		{
+			name: "NoClient",
+			conf: revoke.Config{StateFile: "revocations.json"},
+		},
+		{
+			name: "NoStateFile",
+			conf: revoke.Config{Client: &revokeRecorder{}},
+		},
+		{
+			name: "NegativeInterval",
+			conf: revoke.Config{Client: &revokeRecorder{}, StateFile: "revocations.json", Interval: -time.Second},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := revoke.New(tc.conf); err == nil {
+				t.Fatalf("unexpectedly created scheduler")
+			}
+		})
+	}
+}
+
+func TestSchedulerScheduleAndCancel(t *testing.T) {
+	t.Parallel()
+
+	var s, path = newTestScheduler(t, &revokeRecorder{}, testNow)
+
+	var later = testNow.Add(time.Hour)
+
+	for _, rev := range []revoke.Revocation{
+		{SerialNumber: big.NewInt(0x3), Reason: hvclient.RevocationReasonSuperseded, At: later},
+		{SerialNumber: big.NewInt(0x1), Reason: hvclient.RevocationReasonCessationOfOperation, At: later},
+		{SerialNumber: big.NewInt(0x2), Reason: hvclient.RevocationReasonUnspecified, At: testNow},
+		{SerialNumber: big.NewInt(0x3), Reason: hvclient.RevocationReasonKeyCompromise, At: testNow},
+	} {
+		if err := s.Schedule(rev.SerialNumber, rev.Reason, rev.At); err != nil {
+			t.Fatalf("couldn't schedule revocation: %v", err)
+		}
+	}
+
+	if err := s.Schedule(big.NewInt(0x4), "bored", later); !errors.Is(err, hvclient.ErrInvalidRevocationReason) {
+		t.Fatalf("got error %v, want %v", err, hvclient.ErrInvalidRevocationReason)
+	}
+
+	if err := s.Cancel(big.NewInt(0x2)); err != nil {
+		t.Fatalf("couldn't cancel revocation: %v", err)
+	}
+
+	if err := s.Cancel(big.NewInt(0x2)); err == nil {
+		t.Fatalf("unexpectedly cancelled revocation twice")
+	}
+
+	// Read the queue with a second scheduler sharing the state file, to
+	// check that it was stored durably.
+	var other, err = revoke.New(revoke.Config{Client: &revokeRecorder{}, StateFile: path})
+	if err != nil {
+		t.Fatalf("couldn't create scheduler: %v", err)
+	}
+
+	var got []revoke.Revocation
+	if got, err = other.Pending(); err != nil {
+		t.Fatalf("couldn't get pending revocations: %v", err)
+	}
+
+	var want = []revoke.Revocation{
+		{SerialNumber: big.NewInt(0x3), Reason: hvclient.RevocationReasonKeyCompromise, At: testNow},
+		{SerialNumber: big.NewInt(0x1), Reason: hvclient.RevocationReasonCessationOfOperation, At: later},
+	}
+
+	if !cmp.Equal(got, want, cmp.Comparer(func(a, b *big.Int) bool { return a.Cmp(b) == 0 })) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSchedulerRevokeDue(t *testing.T) {
+	t.Parallel()
+
+	var recorder = &revokeRecorder{fail: map[string]bool{"2": true}}
+	var s, _ = newTestScheduler(t, recorder, testNow)
+
+	for _, rev := range []revoke.Revocation{
+		{SerialNumber: big.NewInt(0x1), Reason: hvclient.RevocationReasonSuperseded, At: testNow.Add(-time.Hour)},
+		{SerialNumber: big.NewInt(0x2), Reason: hvclient.RevocationReasonSuperseded, At: testNow.Add(-time.Minute)},
+		{SerialNumber: big.NewInt(0x3), Reason: hvclient.RevocationReasonCessationOfOperation, At: testNow},
+		{SerialNumber: big.NewInt(0x4), Reason: hvclient.RevocationReasonSuperseded, At: testNow.Add(time.Second)},
+	} {
+		if err := s.Schedule(rev.SerialNumber, rev.Reason, rev.At); err != nil {
+			t.Fatalf("couldn't schedule revocation: %v", err)
+		}
+	}
+
+	if err := s.RevokeDue(context.Background()); err == nil {
+		t.Fatalf("unexpectedly revoked all due certificates")
+	}
+
+	var want = []string{"1:superseded", "3:cessationOfOperation"}
+	if !cmp.Equal(recorder.revoked, want) {
+		t.Errorf("got revoked %v, want %v", recorder.revoked, want)
+	}
+
+	var pending, err = s.Pending()
+	if err != nil {
+		t.Fatalf("couldn't get pending revocations: %v", err)
+	}
+
+	var got []string
+	for _, rev := range pending {
+		got = append(got, rev.SerialNumber.Text(16))
+	}
+
+	if want = []string{"2", "4"}; !cmp.Equal(got, want) {
+		t.Errorf("got pending %v, want %v", got, want)
+	}
+}
+
+func TestSchedulerRun(t *testing.T) {
+	t.Parallel()
+
+	var recorder = &revokeRecorder{}
+	var path = filepath.Join(t.TempDir(), "revocations.json")
+
+	var s, err = revoke.New(revoke.Config{
+		Client:    recorder,
+		StateFile: path,
+		Interval:  time.Millisecond * 10,
+	})
+	if err != nil {
+		t.Fatalf("couldn't create scheduler: %v", err)
+	}
+
+	if err = s.Schedule(big.NewInt(0xABC), hvclient.RevocationReasonSuperseded, time.Now()); err != nil {
+		t.Fatalf("couldn't schedule revocation: %v", err)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	if err = s.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if want := []string{"abc:superseded"}; !cmp.Equal(recorder.revoked, want) {
+		t.Errorf("got revoked %v, want %v", recorder.revoked, want)
+	}
+}
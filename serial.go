@@ -0,0 +1,172 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// SerialNumber is a certificate serial number. HVCA represents serial
+// numbers as hexadecimal strings, while the original Client methods which
+// issue, retrieve, rekey and revoke certificates use *big.Int values.
+// SerialNumber converts between the two, and is accepted and returned by
+// the equivalent methods CertificateRequestSerial, CertificateRetrieveSerial,
+// CertificateRekeySerial and CertificateRevokeSerial. The *big.Int methods
+// remain for compatibility. The zero value is not a valid serial number.
+type SerialNumber struct {
+	value *big.Int
+}
+
+// NewSerialNumber returns a serial number with the specified value, such as
+// the value returned by CertificateRequest.
+func NewSerialNumber(n *big.Int) SerialNumber {
+	if n == nil {
+		return SerialNumber{}
+	}
+
+	return SerialNumber{value: big.NewInt(0).Set(n)}
+}
+
+// ParseSerialNumber parses a serial number from a hexadecimal string. An
+// optional 0x prefix and colon separators between bytes, as output by some
+// tools, are accepted.
+func ParseSerialNumber(s string) (SerialNumber, error) {
+	var digits = strings.ReplaceAll(strings.TrimSpace(s), ":", "")
+	digits = strings.TrimPrefix(strings.TrimPrefix(digits, "0x"), "0X")
+
+	var n, ok = big.NewInt(0).SetString(digits, 16)
+	if !ok || digits == "" || n.Sign() < 0 {
+		return SerialNumber{}, fmt.Errorf("invalid serial number: %q", s)
+	}
+
+	return SerialNumber{value: n}, nil
+}
+
+// Big returns a copy of the value of the serial number, suitable for passing
+// to methods such as CertificateRetrieve, or nil if the serial number is the
+// zero value.
+func (s SerialNumber) Big() *big.Int {
+	if s.value == nil {
+		return nil
+	}
+
+	return big.NewInt(0).Set(s.value)
+}
+
+// Hex returns the serial number as an uppercase hexadecimal string, as used
+// by HVCA, or the empty string if the serial number is the zero value.
+func (s SerialNumber) Hex() string {
+	if s.value == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%X", s.value)
+}
+
+// String returns the serial number as an uppercase hexadecimal string.
+func (s SerialNumber) String() string {
+	return s.Hex()
+}
+
+// IsZero reports whether the serial number is the zero value.
+func (s SerialNumber) IsZero() bool {
+	return s.value == nil
+}
+
+// Equal checks if two serial numbers are equivalent.
+func (s SerialNumber) Equal(other SerialNumber) bool {
+	if s.value == nil || other.value == nil {
+		return s.value == nil && other.value == nil
+	}
+
+	return s.value.Cmp(other.value) == 0
+}
+
+// MarshalJSON returns the JSON encoding of a serial number as a hexadecimal
+// string.
+func (s SerialNumber) MarshalJSON() ([]byte, error) {
+	if s.value == nil {
+		return nil, errors.New("invalid zero value serial number")
+	}
+
+	return json.Marshal(s.Hex())
+}
+
+// UnmarshalJSON parses a JSON-encoded hexadecimal serial number and stores
+// the result in the object.
+func (s *SerialNumber) UnmarshalJSON(b []byte) error {
+	var data string
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	var result, err = ParseSerialNumber(data)
+	if err != nil {
+		return err
+	}
+
+	*s = result
+
+	return nil
+}
+
+// CertificateRequestSerial is the same as CertificateRequest, but returns
+// the serial number of the new certificate as a SerialNumber.
+func (c *Client) CertificateRequestSerial(ctx context.Context, req *Request) (SerialNumber, error) {
+	var sn, err = c.CertificateRequest(ctx, req)
+	if err != nil {
+		return SerialNumber{}, err
+	}
+
+	return NewSerialNumber(sn), nil
+}
+
+// CertificateRetrieveSerial is the same as CertificateRetrieve, but accepts
+// the serial number as a SerialNumber.
+func (c *Client) CertificateRetrieveSerial(ctx context.Context, serial SerialNumber) (*CertInfo, error) {
+	return c.CertificateRetrieve(ctx, serial.Big())
+}
+
+// CertificateRekeySerial is the same as CertificateRekey, but accepts and
+// returns serial numbers as SerialNumber values.
+func (c *Client) CertificateRekeySerial(
+	ctx context.Context,
+	serial SerialNumber,
+	rekey *CertificateRekeyRequest,
+) (SerialNumber, error) {
+	var sn, err = c.CertificateRekey(ctx, serial.Big(), rekey)
+	if err != nil {
+		return SerialNumber{}, err
+	}
+
+	return NewSerialNumber(sn), nil
+}
+
+// CertificateRevokeSerial is the same as CertificateRevokeWithReason, but
+// accepts the serial number as a SerialNumber.
+func (c *Client) CertificateRevokeSerial(
+	ctx context.Context,
+	serial SerialNumber,
+	reason RevocationReason,
+	time int64,
+) error {
+	return c.CertificateRevokeWithReason(ctx, serial.Big(), reason, time)
+}
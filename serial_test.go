@@ -0,0 +1,175 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+func TestParseSerialNumber(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		in   string
+		want string
+	}{
+		{"741DAF9EC2D5F7DC", "741DAF9EC2D5F7DC"},
+		{"741daf9ec2d5f7dc", "741DAF9EC2D5F7DC"},
+		{"0x741daf9ec2d5f7dc", "741DAF9EC2D5F7DC"},
+		{"74:1d:af:9e:c2:d5:f7:dc", "741DAF9EC2D5F7DC"},
+		{" 01 ", "1"},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = hvclient.ParseSerialNumber(tc.in)
+			if err != nil {
+				t.Fatalf("couldn't parse serial number: %v", err)
+			}
+
+			if got.Hex() != tc.want {
+				t.Errorf("got %s, want %s", got.Hex(), tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSerialNumberFailure(t *testing.T) {
+	t.Parallel()
+
+	for _, in := range []string{"", "0x", "not hex", "-1A"} {
+		if _, err := hvclient.ParseSerialNumber(in); err == nil {
+			t.Errorf("unexpectedly parsed serial number %q", in)
+		}
+	}
+}
+
+func TestSerialNumber(t *testing.T) {
+	t.Parallel()
+
+	var n = big.NewInt(0x741daf9ec2d5f7dc)
+	var sn = hvclient.NewSerialNumber(n)
+
+	// The serial number must not share storage with the original value.
+	n.SetInt64(1)
+
+	if got := sn.Big(); got.Cmp(big.NewInt(0x741daf9ec2d5f7dc)) != 0 {
+		t.Errorf("got %X, want %X", got, 0x741daf9ec2d5f7dc)
+	}
+
+	sn.Big().SetInt64(2)
+
+	if got := sn.String(); got != "741DAF9EC2D5F7DC" {
+		t.Errorf("got %s, want %s", got, "741DAF9EC2D5F7DC")
+	}
+
+	var data, err = json.Marshal(sn)
+	if err != nil {
+		t.Fatalf("couldn't marshal JSON: %v", err)
+	}
+
+	if string(data) != `"741DAF9EC2D5F7DC"` {
+		t.Errorf("got %s, want %s", data, `"741DAF9EC2D5F7DC"`)
+	}
+
+	var back hvclient.SerialNumber
+	if err = json.Unmarshal(data, &back); err != nil {
+		t.Fatalf("couldn't unmarshal JSON: %v", err)
+	}
+
+	if !back.Equal(sn) {
+		t.Errorf("got %v, want %v", back, sn)
+	}
+
+	var zero hvclient.SerialNumber
+	if !zero.IsZero() || zero.Big() != nil || zero.Hex() != "" || zero.Equal(sn) || !zero.Equal(hvclient.NewSerialNumber(nil)) {
+		t.Errorf("unexpected behaviour for zero value serial number")
+	}
+
+	if _, err = json.Marshal(zero); err == nil {
+		t.Errorf("unexpectedly marshalled zero value serial number")
+	}
+}
+
+func TestClientMockSerialNumberMethods(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var csr, err = pki.CSRFromFile("testdata/test_csr.pem")
+	if err != nil {
+		t.Fatalf("failed to read CSR: %v", err)
+	}
+
+	var sn hvclient.SerialNumber
+	if sn, err = client.CertificateRequestSerial(ctx, &hvclient.Request{
+		Subject: &hvclient.DN{CommonName: "John Doe"},
+		CSR:     csr,
+	}); err != nil {
+		t.Fatalf("failed to request certificate: %v", err)
+	}
+
+	if sn.Hex() != mockCertSerial {
+		t.Fatalf("got serial number %s, want %s", sn, mockCertSerial)
+	}
+
+	var info *hvclient.CertInfo
+	if info, err = client.CertificateRetrieveSerial(ctx, sn); err != nil {
+		t.Fatalf("failed to retrieve certificate: %v", err)
+	}
+
+	if !info.SerialNumber().Equal(sn) {
+		t.Errorf("got serial number %s, want %s", info.SerialNumber(), sn)
+	}
+
+	var rekeyed hvclient.SerialNumber
+	if rekeyed, err = client.CertificateRekeySerial(ctx, sn, &hvclient.CertificateRekeyRequest{CSR: csr}); err != nil {
+		t.Fatalf("failed to rekey certificate: %v", err)
+	}
+
+	if rekeyed.Hex() != mockCertSerial {
+		t.Errorf("got rekeyed serial number %s, want %s", rekeyed, mockCertSerial)
+	}
+
+	if err = client.CertificateRevokeSerial(ctx, sn, hvclient.RevocationReasonSuperseded, 0); err != nil {
+		t.Errorf("failed to revoke certificate: %v", err)
+	}
+
+	err = client.CertificateRevokeSerial(ctx, hvclient.NewSerialNumber(mockBigIntNotFound), hvclient.RevocationReasonSuperseded, 0)
+	if !errors.Is(err, hvclient.ErrNotFound) {
+		t.Errorf("got error %v, want %v", err, hvclient.ErrNotFound)
+	}
+
+	if _, err = client.CertificateRetrieveSerial(ctx, hvclient.SerialNumber{}); err == nil {
+		t.Errorf("unexpectedly retrieved certificate with zero value serial number")
+	}
+}
@@ -0,0 +1,223 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SignatureAlgorithm is an algorithm with which HVCA may sign a certificate.
+type SignatureAlgorithm int
+
+// HashAlgorithm is a hash algorithm with which HVCA may sign a certificate.
+type HashAlgorithm int
+
+// Signature algorithm constants.
+const (
+	SigAlgRSA SignatureAlgorithm = iota + 1
+	SigAlgRSAPSS
+	SigAlgECDSA
+)
+
+// Hash algorithm constants.
+const (
+	HashSHA256 HashAlgorithm = iota + 1
+	HashSHA384
+	HashSHA512
+)
+
+// signatureAlgorithmDescriptions maps signature algorithm values to their
+// string descriptions.
+var signatureAlgorithmDescriptions = [...]string{
+	SigAlgRSA:    "RSA",
+	SigAlgRSAPSS: "RSA-PSS",
+	SigAlgECDSA:  "ECDSA",
+}
+
+// signatureAlgorithmCodes maps signature algorithm string descriptions to
+// their values.
+var signatureAlgorithmCodes = map[string]SignatureAlgorithm{
+	"RSA":     SigAlgRSA,
+	"RSA-PSS": SigAlgRSAPSS,
+	"ECDSA":   SigAlgECDSA,
+}
+
+// hashAlgorithmDescriptions maps hash algorithm values to their string
+// descriptions.
+var hashAlgorithmDescriptions = [...]string{
+	HashSHA256: "SHA-256",
+	HashSHA384: "SHA-384",
+	HashSHA512: "SHA-512",
+}
+
+// hashAlgorithmCodes maps hash algorithm string descriptions to their values.
+var hashAlgorithmCodes = map[string]HashAlgorithm{
+	"SHA-256": HashSHA256,
+	"SHA-384": HashSHA384,
+	"SHA-512": HashSHA512,
+}
+
+// isValid checks if a signature algorithm value is within a valid range.
+func (a SignatureAlgorithm) isValid() bool {
+	return a >= SigAlgRSA && a <= SigAlgECDSA
+}
+
+// String returns the name of the signature algorithm, as used by HVCA.
+func (a SignatureAlgorithm) String() string {
+	if !a.isValid() {
+		return "UNKNOWN SIGNATURE ALGORITHM"
+	}
+
+	return signatureAlgorithmDescriptions[a]
+}
+
+// ParseSignatureAlgorithm parses a case-insensitive signature algorithm
+// name, such as "RSA", "RSA-PSS" or "ECDSA".
+func ParseSignatureAlgorithm(s string) (SignatureAlgorithm, error) {
+	var result, ok = signatureAlgorithmCodes[strings.ToUpper(s)]
+	if !ok {
+		return 0, fmt.Errorf("invalid signature algorithm: %s", s)
+	}
+
+	return result, nil
+}
+
+// isValid checks if a hash algorithm value is within a valid range.
+func (a HashAlgorithm) isValid() bool {
+	return a >= HashSHA256 && a <= HashSHA512
+}
+
+// String returns the name of the hash algorithm, as used by HVCA.
+func (a HashAlgorithm) String() string {
+	if !a.isValid() {
+		return "UNKNOWN HASH ALGORITHM"
+	}
+
+	return hashAlgorithmDescriptions[a]
+}
+
+// ParseHashAlgorithm parses a case-insensitive hash algorithm name, such as
+// "SHA-256", "SHA-384" or "SHA-512".
+func ParseHashAlgorithm(s string) (HashAlgorithm, error) {
+	var result, ok = hashAlgorithmCodes[strings.ToUpper(s)]
+	if !ok {
+		return 0, fmt.Errorf("invalid hash algorithm: %s", s)
+	}
+
+	return result, nil
+}
+
+// NewSignature returns a signature specifying the provided algorithms. A
+// zero value for either algorithm leaves it unspecified, so that HVCA will
+// apply the policy default.
+func NewSignature(alg SignatureAlgorithm, hash HashAlgorithm) *Signature {
+	var sig = &Signature{}
+
+	if alg.isValid() {
+		sig.Algorithm = alg.String()
+	}
+
+	if hash.isValid() {
+		sig.HashAlgorithm = hash.String()
+	}
+
+	return sig
+}
+
+// Validate checks that any algorithms specified in the signature are known.
+func (s *Signature) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	if s.Algorithm != "" {
+		if _, err := ParseSignatureAlgorithm(s.Algorithm); err != nil {
+			return err
+		}
+	}
+
+	if s.HashAlgorithm != "" {
+		if _, err := ParseHashAlgorithm(s.HashAlgorithm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate checks a signature against the policy, and returns an error
+// describing the first violation found. Algorithm names are compared
+// case-insensitively.
+func (p *SignaturePolicy) Validate(sig *Signature) error {
+	if err := sig.Validate(); err != nil {
+		return err
+	}
+
+	if p == nil {
+		if sig != nil && (sig.Algorithm != "" || sig.HashAlgorithm != "") {
+			return errors.New("signature not allowed by policy")
+		}
+
+		return nil
+	}
+
+	if sig == nil {
+		sig = &Signature{}
+	}
+
+	if err := p.Algorithm.validate(sig.Algorithm); err != nil {
+		return fmt.Errorf("invalid signature algorithm: %w", err)
+	}
+
+	if err := p.HashAlgorithm.validate(sig.HashAlgorithm); err != nil {
+		return fmt.Errorf("invalid signature hash algorithm: %w", err)
+	}
+
+	return nil
+}
+
+// validate checks an algorithm name against an algorithm policy. A nil
+// policy forbids the algorithm from being specified.
+func (p *AlgorithmPolicy) validate(name string) error {
+	if p == nil {
+		if name != "" {
+			return errors.New("value not allowed by policy")
+		}
+
+		return nil
+	}
+
+	switch {
+	case name == "" && p.Presence == Required:
+		return errors.New("value is required")
+
+	case name == "":
+		return nil
+
+	case p.Presence == Forbidden:
+		return errors.New("value is forbidden")
+	}
+
+	for _, allowed := range p.List {
+		if strings.EqualFold(name, allowed) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("value %q is not one of %q", name, p.List)
+}
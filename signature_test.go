@@ -0,0 +1,207 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"testing"
+
+	"github.com/globalsign/hvclient"
+)
+
+func TestSignatureAlgorithmString(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		value hvclient.SignatureAlgorithm
+		want  string
+	}{
+		{hvclient.SignatureAlgorithm(0), "UNKNOWN SIGNATURE ALGORITHM"},
+		{hvclient.SigAlgRSA, "RSA"},
+		{hvclient.SigAlgRSAPSS, "RSA-PSS"},
+		{hvclient.SigAlgECDSA, "ECDSA"},
+		{hvclient.SignatureAlgorithm(4), "UNKNOWN SIGNATURE ALGORITHM"},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.want, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.value.String(); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHashAlgorithmString(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		value hvclient.HashAlgorithm
+		want  string
+	}{
+		{hvclient.HashAlgorithm(0), "UNKNOWN HASH ALGORITHM"},
+		{hvclient.HashSHA256, "SHA-256"},
+		{hvclient.HashSHA384, "SHA-384"},
+		{hvclient.HashSHA512, "SHA-512"},
+		{hvclient.HashAlgorithm(4), "UNKNOWN HASH ALGORITHM"},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.want, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.value.String(); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	if got, err := hvclient.ParseSignatureAlgorithm("rsa-pss"); err != nil || got != hvclient.SigAlgRSAPSS {
+		t.Errorf("got %v, %v, want %v", got, err, hvclient.SigAlgRSAPSS)
+	}
+
+	if _, err := hvclient.ParseSignatureAlgorithm("DSA"); err == nil {
+		t.Errorf("unexpectedly parsed signature algorithm")
+	}
+
+	if got, err := hvclient.ParseHashAlgorithm("sha-384"); err != nil || got != hvclient.HashSHA384 {
+		t.Errorf("got %v, %v, want %v", got, err, hvclient.HashSHA384)
+	}
+
+	if _, err := hvclient.ParseHashAlgorithm("SHA-1"); err == nil {
+		t.Errorf("unexpectedly parsed hash algorithm")
+	}
+}
+
+func TestNewSignature(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name string
+		alg  hvclient.SignatureAlgorithm
+		hash hvclient.HashAlgorithm
+		want hvclient.Signature
+	}{
+		{
+			name: "Both",
+			alg:  hvclient.SigAlgECDSA,
+			hash: hvclient.HashSHA512,
+			want: hvclient.Signature{Algorithm: "ECDSA", HashAlgorithm: "SHA-512"},
+		},
+		{
+			name: "HashOnly",
+			hash: hvclient.HashSHA256,
+			want: hvclient.Signature{HashAlgorithm: "SHA-256"},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := hvclient.NewSignature(tc.alg, tc.hash); *got != tc.want {
+				t.Errorf("got %+v, want %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSignaturePolicyValidate(t *testing.T) {
+	t.Parallel()
+
+	var pol = &hvclient.SignaturePolicy{
+		Algorithm: &hvclient.AlgorithmPolicy{
+			Presence: hvclient.Optional,
+			List:     []string{"RSA", "RSA-PSS"},
+		},
+		HashAlgorithm: &hvclient.AlgorithmPolicy{
+			Presence: hvclient.Required,
+			List:     []string{"SHA-256"},
+		},
+	}
+
+	var testcases = []struct {
+		name string
+		pol  *hvclient.SignaturePolicy
+		sig  *hvclient.Signature
+		ok   bool
+	}{
+		{
+			name: "Valid",
+			pol:  pol,
+			sig:  &hvclient.Signature{Algorithm: "RSA-PSS", HashAlgorithm: "SHA-256"},
+			ok:   true,
+		},
+		{
+			name: "CaseInsensitive",
+			pol:  pol,
+			sig:  &hvclient.Signature{HashAlgorithm: "sha-256"},
+			ok:   true,
+		},
+		{
+			name: "NilPolicyNilSignature",
+			ok:   true,
+		},
+		{
+			name: "NilPolicy",
+			sig:  &hvclient.Signature{Algorithm: "RSA"},
+		},
+		{
+			name: "UnknownAlgorithm",
+			pol:  pol,
+			sig:  &hvclient.Signature{Algorithm: "DSA", HashAlgorithm: "SHA-256"},
+		},
+		{
+			name: "AlgorithmNotAllowed",
+			pol:  pol,
+			sig:  &hvclient.Signature{Algorithm: "ECDSA", HashAlgorithm: "SHA-256"},
+		},
+		{
+			name: "HashNotAllowed",
+			pol:  pol,
+			sig:  &hvclient.Signature{HashAlgorithm: "SHA-512"},
+		},
+		{
+			name: "HashMissing",
+			pol:  pol,
+			sig:  &hvclient.Signature{Algorithm: "RSA"},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var err = tc.pol.Validate(tc.sig)
+			if (err == nil) != tc.ok {
+				t.Fatalf("got error %v, want success %t", err, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+// OIDEmailProtection is the extended key usage OID for email protection,
+// which S/MIME certificates must include. See RFC 5280 4.2.1.12.
+var OIDEmailProtection = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 4}
+
+// SMIMECertificate is an issued S/MIME certificate together with its
+// private key and the chain of trust for it.
+type SMIMECertificate struct {
+	PrivateKey  crypto.Signer
+	Certificate *x509.Certificate
+	Chain       []*x509.Certificate
+}
+
+// NewSMIMERequest returns a request for an S/MIME certificate for the
+// specified email address and private key, in the form accepted by typical
+// S/MIME validation policies. The address is checked and normalized with
+// CheckEmail and included as the single SAN email address, the name, if not
+// empty, is included as the subject common name, and the email protection
+// extended key usage is included. The maximum validity period allowed by the
+// validation policy is requested, starting at the specified time, which is
+// usually the current time.
+func NewSMIMERequest(email, name string, key crypto.Signer, now time.Time) (*Request, error) {
+	var addr, err = CheckEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	var req = &Request{
+		Validity: &Validity{
+			NotBefore: now,
+			NotAfter:  time.Unix(0, 0),
+		},
+		SAN:        &SAN{Emails: []string{addr}},
+		EKUs:       []asn1.ObjectIdentifier{OIDEmailProtection},
+		PrivateKey: key,
+	}
+
+	if name != "" {
+		req.Subject = &DN{CommonName: name}
+	}
+
+	return req, nil
+}
+
+// IssueSMIME generates a new private key of the most preferred type and
+// length allowed by the validation policy, requests an S/MIME certificate
+// for it as for NewSMIMERequest, and waits for the certificate to be
+// issued. Values fixed by the validation policy are applied to the request,
+// the email address is also included in the subject if the policy requires
+// it, and a PKCS#10 certificate signing request is submitted if the policy
+// requires one. The returned certificate may be exported for import into a
+// mail client with its PKCS12 method.
+func (c *Client) IssueSMIME(ctx context.Context, email, name string) (*SMIMECertificate, error) {
+	var pol, err = c.Policy(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't retrieve validation policy: %w", err)
+	}
+
+	var key crypto.Signer
+	if key, err = newPolicyKey(pol.PublicKey); err != nil {
+		return nil, err
+	}
+
+	var req *Request
+	if req, err = NewSMIMERequest(email, name, key, c.now()); err != nil {
+		return nil, err
+	}
+
+	if pol.SubjectDN != nil && pol.SubjectDN.Email != nil && pol.SubjectDN.Email.Presence == Required {
+		if req.Subject == nil {
+			req.Subject = &DN{}
+		}
+
+		req.Subject.Email = req.SAN.Emails[0]
+	}
+
+	if err = req.ApplyPolicyDefaults(pol); err != nil {
+		return nil, fmt.Errorf("couldn't apply validation policy defaults: %w", err)
+	}
+
+	if pol.PublicKey != nil && pol.PublicKey.KeyFormat == PKCS10 {
+		if req.CSR, err = req.PKCS10(); err != nil {
+			return nil, fmt.Errorf("couldn't generate PKCS#10 request: %w", err)
+		}
+
+		req.PrivateKey = nil
+	}
+
+	var serial *big.Int
+	if serial, err = c.CertificateRequest(ctx, req); err != nil {
+		return nil, fmt.Errorf("couldn't request certificate: %w", err)
+	}
+
+	var info *CertInfo
+	if info, err = c.CertificateRetrieve(ctx, serial); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve certificate %X: %w", serial, err)
+	}
+
+	if info.X509 == nil {
+		return nil, fmt.Errorf("no certificate returned for serial number %X", serial)
+	}
+
+	var chain []*x509.Certificate
+	if chain, err = c.TrustChain(ctx); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve trust chain: %w", err)
+	}
+
+	return &SMIMECertificate{
+		PrivateKey:  key,
+		Certificate: info.X509,
+		Chain:       chain,
+	}, nil
+}
+
+// PKCS12 returns the DER encoding of a PKCS#12 (.p12 or .pfx) file
+// containing the private key, the certificate and its chain of trust,
+// protected with the specified password, suitable for import into a mail
+// client.
+func (s *SMIMECertificate) PKCS12(password string) ([]byte, error) {
+	return pki.BuildPKCS12(s.PrivateKey, s.Certificate, s.Chain, password)
+}
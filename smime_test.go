@@ -0,0 +1,210 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewSMIMERequest(t *testing.T) {
+	t.Parallel()
+
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("couldn't generate key: %v", err)
+	}
+
+	var testcases = []struct {
+		name        string
+		email       string
+		commonName  string
+		wantEmail   string
+		wantSubject *hvclient.DN
+	}{
+		{
+			name:        "WithName",
+			email:       "alice@example.com",
+			commonName:  "Alice",
+			wantEmail:   "alice@example.com",
+			wantSubject: &hvclient.DN{CommonName: "Alice"},
+		},
+		{
+			name:      "WithoutName",
+			email:     "Alice@Example.COM",
+			wantEmail: "Alice@example.com",
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var now = time.Date(2021, 6, 16, 4, 19, 25, 0, time.UTC)
+
+			var req, err = hvclient.NewSMIMERequest(tc.email, tc.commonName, key, now)
+			if err != nil {
+				t.Fatalf("couldn't create request: %v", err)
+			}
+
+			if got := req.SAN.Emails; !cmp.Equal(got, []string{tc.wantEmail}) {
+				t.Errorf("got SAN emails %q, want %q", got, tc.wantEmail)
+			}
+
+			if !cmp.Equal(req.Subject, tc.wantSubject) {
+				t.Errorf("got subject %v, want %v", req.Subject, tc.wantSubject)
+			}
+
+			if want := []asn1.ObjectIdentifier{hvclient.OIDEmailProtection}; !cmp.Equal(req.EKUs, want) {
+				t.Errorf("got EKUs %v, want %v", req.EKUs, want)
+			}
+
+			if req.PrivateKey != key {
+				t.Errorf("private key not set in request")
+			}
+
+			if req.Validity == nil || !req.Validity.NotAfter.Equal(time.Unix(0, 0)) {
+				t.Fatalf("got validity %v, want maximum allowed by policy", req.Validity)
+			}
+
+			if !req.Validity.NotBefore.Equal(now) {
+				t.Errorf("got not before %v, want %v", req.Validity.NotBefore, now)
+			}
+		})
+	}
+}
+
+func TestNewSMIMERequestFailure(t *testing.T) {
+	t.Parallel()
+
+	for _, email := range []string{"", "Alice <alice@example.com>", "alice@localhost"} {
+		var _, err = hvclient.NewSMIMERequest(email, "Alice", nil, time.Now())
+		if !errors.Is(err, hvclient.ErrInvalidEmail) {
+			t.Errorf("%q: got error %v, want %v", email, err, hvclient.ErrInvalidEmail)
+		}
+	}
+}
+
+func TestSMIMECertificatePKCS12(t *testing.T) {
+	t.Parallel()
+
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("couldn't generate key: %v", err)
+	}
+
+	var tmpl = &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+	}
+
+	var der []byte
+	if der, err = x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key); err != nil {
+		t.Fatalf("couldn't create certificate: %v", err)
+	}
+
+	var cert *x509.Certificate
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatalf("couldn't parse certificate: %v", err)
+	}
+
+	var smime = &hvclient.SMIMECertificate{PrivateKey: key, Certificate: cert}
+
+	var p12 []byte
+	if p12, err = smime.PKCS12("password"); err != nil {
+		t.Fatalf("couldn't build PKCS#12 file: %v", err)
+	}
+
+	if len(p12) == 0 {
+		t.Errorf("got empty PKCS#12 file")
+	}
+
+	// The certificate must match the private key.
+	var other *ecdsa.PrivateKey
+	if other, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatalf("couldn't generate key: %v", err)
+	}
+
+	smime.PrivateKey = other
+
+	if _, err = smime.PKCS12("password"); err == nil {
+		t.Errorf("unexpectedly built PKCS#12 file with mismatched key")
+	}
+}
+
+func TestClientMockIssueSMIME(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var got, err = client.IssueSMIME(ctx, "alice@example.com", "Alice")
+	if err != nil {
+		t.Fatalf("couldn't issue S/MIME certificate: %v", err)
+	}
+
+	if !got.Certificate.Equal(mockCert) {
+		t.Errorf("got certificate with serial number %X, want %X",
+			got.Certificate.SerialNumber, mockCert.SerialNumber)
+	}
+
+	if len(got.Chain) != len(mockTrustChainCerts) {
+		t.Errorf("got %d chain certificates, want %d", len(got.Chain), len(mockTrustChainCerts))
+	}
+
+	// The mock validation policy prefers ECDSA keys.
+	var key, ok = got.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		t.Fatalf("got private key of type %T, want *ecdsa.PrivateKey", got.PrivateKey)
+	}
+
+	if err = mockPolicy.PublicKey.Validate(key.Public()); err != nil {
+		t.Errorf("generated key not allowed by policy: %v", err)
+	}
+}
+
+func TestClientMockIssueSMIMEFailure(t *testing.T) {
+	t.Parallel()
+
+	var client, closefunc = newMockClient(t)
+	defer closefunc()
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var _, err = client.IssueSMIME(ctx, "alice@localhost", "Alice")
+	if !errors.Is(err, hvclient.ErrInvalidEmail) {
+		t.Errorf("got error %v, want %v", err, hvclient.ErrInvalidEmail)
+	}
+}
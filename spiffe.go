@@ -0,0 +1,189 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidSPIFFEID is wrapped by errors returned when a SPIFFE ID does
+// not conform to the SPIFFE ID specification.
+var ErrInvalidSPIFFEID = errors.New("invalid SPIFFE ID")
+
+// spiffePrefix is the scheme and authority separator with which every
+// SPIFFE ID begins.
+const spiffePrefix = "spiffe://"
+
+// maxSPIFFEIDLength is the maximum length in bytes of a SPIFFE ID.
+const maxSPIFFEIDLength = 2048
+
+// ParseSPIFFEID checks that a string is a valid SPIFFE ID, such as
+// "spiffe://example.org/ns/prod/sa/web", suitable for the single URI SAN of
+// an X.509-SVID workload identity certificate, and returns it as a URL. The
+// trust domain must be non-empty and consist only of lower case letters,
+// digits, dots, hyphens and underscores, with no port or user information.
+// The path, if any, must consist of non-empty segments of letters, digits,
+// dots, hyphens and underscores, other than "." and "..", with no trailing
+// slash, and a query or fragment is not allowed. An error wrapping
+// ErrInvalidSPIFFEID is returned if the ID is not valid.
+func ParseSPIFFEID(id string) (*url.URL, error) {
+	var invalid = func(reason string) error {
+		return fmt.Errorf("%w %q: %s", ErrInvalidSPIFFEID, id, reason)
+	}
+
+	switch {
+	case len(id) > maxSPIFFEIDLength:
+		return nil, invalid(fmt.Sprintf("longer than %d bytes", maxSPIFFEIDLength))
+
+	case !strings.HasPrefix(id, spiffePrefix):
+		return nil, invalid("scheme must be " + strings.TrimSuffix(spiffePrefix, "//"))
+
+	case strings.ContainsAny(id, "?#"):
+		return nil, invalid("query and fragment are not allowed")
+	}
+
+	var rest = strings.TrimPrefix(id, spiffePrefix)
+	var trustDomain, path = rest, ""
+	if i := strings.IndexByte(rest, '/'); i != -1 {
+		trustDomain, path = rest[:i], rest[i:]
+	}
+
+	if trustDomain == "" {
+		return nil, invalid("missing trust domain")
+	}
+
+	for _, c := range trustDomain {
+		if !isSPIFFEChar(c) || ('A' <= c && c <= 'Z') {
+			return nil, invalid(fmt.Sprintf("trust domain contains invalid character %q", c))
+		}
+	}
+
+	if path != "" {
+		for _, segment := range strings.Split(path[1:], "/") {
+			if err := checkSPIFFESegment(segment); err != nil {
+				return nil, invalid(err.Error())
+			}
+		}
+	}
+
+	var u, err = url.Parse(id)
+	if err != nil {
+		return nil, invalid(err.Error())
+	}
+
+	return u, nil
+}
+
+// NewSPIFFEID returns the SPIFFE ID with the specified trust domain and path
+// segments, for example "spiffe://example.org/ns/prod" for the trust domain
+// "example.org" and the segments "ns" and "prod". An error wrapping
+// ErrInvalidSPIFFEID is returned if the resulting ID would not be valid, as
+// described for ParseSPIFFEID.
+func NewSPIFFEID(trustDomain string, segments ...string) (*url.URL, error) {
+	var id = spiffePrefix + trustDomain
+
+	for _, segment := range segments {
+		if err := checkSPIFFESegment(segment); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSPIFFEID, err)
+		}
+
+		id += "/" + segment
+	}
+
+	return ParseSPIFFEID(id)
+}
+
+// checkSPIFFESegment checks that a SPIFFE ID path segment is valid.
+func checkSPIFFESegment(segment string) error {
+	switch segment {
+	case "":
+		return errors.New("empty path segment")
+
+	case ".", "..":
+		return fmt.Errorf("path segment %q is not allowed", segment)
+	}
+
+	for _, c := range segment {
+		if !isSPIFFEChar(c) {
+			return fmt.Errorf("path contains invalid character %q", c)
+		}
+	}
+
+	return nil
+}
+
+// isSPIFFEChar reports whether a character may appear in the trust domain,
+// if lower case, or in a path segment of a SPIFFE ID.
+func isSPIFFEChar(c rune) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		c == '.' || c == '-' || c == '_'
+}
+
+// isSPIFFEURI reports whether a URI has the SPIFFE scheme.
+func isSPIFFEURI(u *url.URL) bool {
+	return u != nil && strings.EqualFold(u.Scheme, "spiffe")
+}
+
+// SPIFFEID returns the SPIFFE ID among the SAN URIs, or nil if there is
+// none. An error wrapping ErrInvalidSPIFFEID is returned if there is more
+// than one URI with the SPIFFE scheme, since an X.509-SVID must contain
+// exactly one, or if the SPIFFE ID is not valid.
+func (s *SAN) SPIFFEID() (*url.URL, error) {
+	var found *url.URL
+
+	for _, u := range s.URIs {
+		if !isSPIFFEURI(u) {
+			continue
+		}
+
+		if found != nil {
+			return nil, fmt.Errorf("%w: more than one SPIFFE ID in SAN URIs", ErrInvalidSPIFFEID)
+		}
+
+		found = u
+	}
+
+	if found == nil {
+		return nil, nil
+	}
+
+	return ParseSPIFFEID(found.String())
+}
+
+// SetSPIFFEID checks that a SPIFFE ID is valid, as described for
+// ParseSPIFFEID, and adds it to the SAN URIs, replacing any existing URIs
+// with the SPIFFE scheme, since an X.509-SVID must contain exactly one. The
+// SAN is left unchanged if the SPIFFE ID is not valid.
+func (s *SAN) SetSPIFFEID(id string) error {
+	var u, err = ParseSPIFFEID(id)
+	if err != nil {
+		return err
+	}
+
+	var uris = make([]*url.URL, 0, len(s.URIs)+1)
+	for _, existing := range s.URIs {
+		if !isSPIFFEURI(existing) {
+			uris = append(uris, existing)
+		}
+	}
+
+	s.URIs = append(uris, u)
+
+	return nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient_test
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/testhelpers"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSPIFFEID(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		value string
+	}{
+		{"TrustDomainOnly", "spiffe://example.org"},
+		{"Path", "spiffe://example.org/ns/prod/sa/web"},
+		{"Characters", "spiffe://my-trust_domain.example.org/Service.v1/a-b_c"},
+		{"MaximumLength", "spiffe://example.org/" + strings.Repeat("a", 2048-len("spiffe://example.org/"))},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = hvclient.ParseSPIFFEID(tc.value)
+			if err != nil {
+				t.Fatalf("couldn't parse SPIFFE ID: %v", err)
+			}
+
+			if got.String() != tc.value {
+				t.Errorf("got %q, want %q", got, tc.value)
+			}
+		})
+	}
+}
+
+func TestParseSPIFFEIDFailure(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		name  string
+		value string
+	}{
+		{"Empty", ""},
+		{"WrongScheme", "https://example.org/ns/prod"},
+		{"UpperCaseScheme", "SPIFFE://example.org/ns/prod"},
+		{"MissingTrustDomain", "spiffe:///ns/prod"},
+		{"UpperCaseTrustDomain", "spiffe://Example.org/ns/prod"},
+		{"Port", "spiffe://example.org:8443/ns/prod"},
+		{"UserInfo", "spiffe://user@example.org/ns/prod"},
+		{"Query", "spiffe://example.org/ns/prod?x=1"},
+		{"Fragment", "spiffe://example.org/ns/prod#web"},
+		{"TrailingSlash", "spiffe://example.org/ns/prod/"},
+		{"EmptySegment", "spiffe://example.org/ns//prod"},
+		{"DotSegment", "spiffe://example.org/ns/./prod"},
+		{"DotDotSegment", "spiffe://example.org/ns/../prod"},
+		{"PercentEncoded", "spiffe://example.org/ns/pr%6fd"},
+		{"TooLong", "spiffe://example.org/" + strings.Repeat("a", 2048)},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var _, err = hvclient.ParseSPIFFEID(tc.value)
+			if !errors.Is(err, hvclient.ErrInvalidSPIFFEID) {
+				t.Fatalf("got error %v, want %v", err, hvclient.ErrInvalidSPIFFEID)
+			}
+		})
+	}
+}
+
+func TestNewSPIFFEID(t *testing.T) {
+	t.Parallel()
+
+	var got, err = hvclient.NewSPIFFEID("example.org", "ns", "prod", "sa", "web")
+	if err != nil {
+		t.Fatalf("couldn't create SPIFFE ID: %v", err)
+	}
+
+	if want := "spiffe://example.org/ns/prod/sa/web"; got.String() != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, args := range [][]string{
+		{""},
+		{"Example.org"},
+		{"example.org", "ns/prod"},
+		{"example.org", ""},
+		{"example.org", ".."},
+		{"example.org", "prod?x=1"},
+	} {
+		if _, err = hvclient.NewSPIFFEID(args[0], args[1:]...); !errors.Is(err, hvclient.ErrInvalidSPIFFEID) {
+			t.Errorf("%q: got error %v, want %v", args, err, hvclient.ErrInvalidSPIFFEID)
+		}
+	}
+}
+
+func TestSANSPIFFEID(t *testing.T) {
+	t.Parallel()
+
+	var san = hvclient.SAN{
+		URIs: []*url.URL{
+			testhelpers.MustParseURI(t, "spiffe://example.org/old"),
+			testhelpers.MustParseURI(t, "https://example.org/"),
+		},
+	}
+
+	if err := san.SetSPIFFEID("spiffe://example.org/ns/prod/sa/web"); err != nil {
+		t.Fatalf("couldn't set SPIFFE ID: %v", err)
+	}
+
+	var got, err = san.SPIFFEID()
+	if err != nil {
+		t.Fatalf("couldn't get SPIFFE ID: %v", err)
+	}
+
+	if want := "spiffe://example.org/ns/prod/sa/web"; got.String() != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var want = []string{"https://example.org/", "spiffe://example.org/ns/prod/sa/web"}
+	var uris []string
+	for _, u := range san.URIs {
+		uris = append(uris, u.String())
+	}
+
+	if !cmp.Equal(uris, want) {
+		t.Errorf("got URIs %q, want %q", uris, want)
+	}
+
+	if err = san.SetSPIFFEID("spiffe://example.org/ns/prod/"); !errors.Is(err, hvclient.ErrInvalidSPIFFEID) {
+		t.Fatalf("got error %v, want %v", err, hvclient.ErrInvalidSPIFFEID)
+	}
+
+	if len(san.URIs) != 2 {
+		t.Errorf("got %d URIs, want unchanged 2", len(san.URIs))
+	}
+}
+
+func TestSANSPIFFEIDFailure(t *testing.T) {
+	t.Parallel()
+
+	var san = hvclient.SAN{URIs: []*url.URL{testhelpers.MustParseURI(t, "https://example.org/")}}
+	if got, err := san.SPIFFEID(); got != nil || err != nil {
+		t.Fatalf("got %v, %v, want no SPIFFE ID", got, err)
+	}
+
+	san.URIs = append(san.URIs,
+		testhelpers.MustParseURI(t, "spiffe://example.org/a"),
+		testhelpers.MustParseURI(t, "spiffe://example.org/b"),
+	)
+
+	if _, err := san.SPIFFEID(); !errors.Is(err, hvclient.ErrInvalidSPIFFEID) {
+		t.Fatalf("got error %v, want %v", err, hvclient.ErrInvalidSPIFFEID)
+	}
+}
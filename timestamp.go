@@ -0,0 +1,282 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/oids"
+)
+
+// TimestampToken is an RFC 3161 timestamp token. Note that parsing a token
+// does not verify its signature.
+type TimestampToken struct {
+	// Raw is the DER encoding of the complete token, a CMS ContentInfo.
+	Raw []byte
+
+	// Policy is the timestamping policy under which the token was issued.
+	Policy asn1.ObjectIdentifier
+
+	// HashAlgorithm and HashedMessage are the message imprint, i.e. the
+	// digest of the timestamped data and the hash function used to
+	// compute it.
+	HashAlgorithm crypto.Hash
+	HashedMessage []byte
+
+	// SerialNumber is the serial number assigned to the token by the
+	// timestamping authority.
+	SerialNumber *big.Int
+
+	// Time is the time at which the token was generated.
+	Time time.Time
+
+	// Accuracy is the accuracy of Time, or zero if not specified.
+	Accuracy time.Duration
+
+	// Ordering is true if tokens from the same timestamping authority can
+	// be ordered by Time alone.
+	Ordering bool
+
+	// Nonce is the nonce from the timestamp request, or nil if none.
+	Nonce *big.Int
+
+	// Certificates are any certificates included in the token, usually
+	// the timestamping authority's signing certificate and possibly its
+	// chain.
+	Certificates []*x509.Certificate
+}
+
+// asn1ContentInfo is used internally for ASN.1 decoding of a CMS
+// ContentInfo.
+type asn1ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+// asn1SignedData is used internally for ASN.1 decoding of a CMS SignedData.
+type asn1SignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo asn1EncapContentInfo
+	Certificates     asn1RawContent `asn1:"optional,tag:0"`
+	CRLs             asn1RawContent `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+// asn1EncapContentInfo is used internally for ASN.1 decoding of a CMS
+// EncapsulatedContentInfo.
+type asn1EncapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+// asn1RawContent is used internally to capture an optional implicitly-tagged
+// ASN.1 value without decoding it. Unlike asn1.RawValue, a struct type causes
+// the tag to be checked before the value is consumed.
+type asn1RawContent struct {
+	Raw asn1.RawContent
+}
+
+// asn1TSTInfo is used internally for ASN.1 decoding of an RFC 3161 TSTInfo.
+// The optional tsa and extensions fields are not decoded. The generation
+// time is decoded manually, since encoding/asn1 does not accept fractional
+// seconds in a GeneralizedTime.
+type asn1TSTInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint asn1MessageImprint
+	SerialNumber   *big.Int
+	GenTime        asn1.RawValue
+	Accuracy       asn1Accuracy `asn1:"optional"`
+	Ordering       bool         `asn1:"optional"`
+	Nonce          *big.Int     `asn1:"optional"`
+}
+
+// asn1MessageImprint is used internally for ASN.1 decoding of an RFC 3161
+// MessageImprint.
+type asn1MessageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// asn1Accuracy is used internally for ASN.1 decoding of an RFC 3161
+// Accuracy.
+type asn1Accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// jsonTimestamp is used internally for JSON unmarshalling.
+type jsonTimestamp struct {
+	Token []byte `json:"token"`
+}
+
+// timestampHashes maps the hash functions supported for timestamping to
+// their object identifiers.
+var timestampHashes = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA1:   oids.OIDHashSHA1,
+	crypto.SHA256: oids.OIDHashSHA256,
+	crypto.SHA384: oids.OIDHashSHA384,
+	crypto.SHA512: oids.OIDHashSHA512,
+}
+
+// Timestamp requests an RFC 3161 timestamp token for a digest computed with
+// the specified hash function, which must be SHA-256, SHA-384 or SHA-512.
+// The calling account must be licensed for timestamping, and a
+// *NotSupportedError is returned if the timestamp endpoint is not available.
+// The message imprint in the returned token is checked against the digest,
+// but the token's signature is not verified.
+func (c *Client) Timestamp(ctx context.Context, digest []byte, hash crypto.Hash) (*TimestampToken, error) {
+	switch hash {
+	case crypto.SHA256, crypto.SHA384, crypto.SHA512:
+
+	default:
+		return nil, fmt.Errorf("unsupported hash function for timestamping: %v", hash)
+	}
+
+	if len(digest) != hash.Size() {
+		return nil, fmt.Errorf("digest length %d does not match %v", len(digest), hash)
+	}
+
+	var body jsonTimestamp
+	var _, err = c.makeRequest(
+		ctx,
+		endpointTimestamp+"/"+hex.EncodeToString(digest),
+		http.MethodGet,
+		nil,
+		&body,
+	)
+	if err != nil {
+		return nil, notSupported(opTimestamp, err)
+	}
+
+	var token *TimestampToken
+	if token, err = ParseTimestampToken(body.Token); err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp token in response: %w", err)
+	}
+
+	if token.HashAlgorithm != hash || !bytes.Equal(token.HashedMessage, digest) {
+		return nil, errors.New("timestamp token message imprint does not match digest")
+	}
+
+	return token, nil
+}
+
+// ParseTimestampToken parses a DER-encoded RFC 3161 timestamp token. Note
+// that the token's signature is not verified.
+func ParseTimestampToken(der []byte) (*TimestampToken, error) {
+	var info asn1ContentInfo
+	if err := unmarshalDER(der, &info); err != nil {
+		return nil, fmt.Errorf("invalid content info: %w", err)
+	}
+
+	if !info.ContentType.Equal(oids.OIDContentTypeSignedData) {
+		return nil, fmt.Errorf("unexpected content type: %v", info.ContentType)
+	}
+
+	var sd asn1SignedData
+	if err := unmarshalDER(info.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("invalid signed data: %w", err)
+	}
+
+	if !sd.EncapContentInfo.EContentType.Equal(oids.OIDContentTypeTSTInfo) {
+		return nil, fmt.Errorf("unexpected encapsulated content type: %v", sd.EncapContentInfo.EContentType)
+	}
+
+	var tst asn1TSTInfo
+	if err := unmarshalDER(sd.EncapContentInfo.EContent, &tst); err != nil {
+		return nil, fmt.Errorf("invalid TSTInfo: %w", err)
+	}
+
+	var hash, ok = hashFromOID(tst.MessageImprint.HashAlgorithm.Algorithm)
+	if !ok {
+		return nil, fmt.Errorf("unsupported message imprint hash algorithm: %v", tst.MessageImprint.HashAlgorithm.Algorithm)
+	}
+
+	if tst.GenTime.Class != asn1.ClassUniversal || tst.GenTime.Tag != asn1.TagGeneralizedTime {
+		return nil, errors.New("invalid generation time")
+	}
+
+	var genTime, err = time.Parse("20060102150405Z0700", string(tst.GenTime.Bytes))
+	if err != nil {
+		return nil, fmt.Errorf("invalid generation time: %w", err)
+	}
+
+	var token = &TimestampToken{
+		Raw:           append([]byte(nil), der...),
+		Policy:        tst.Policy,
+		HashAlgorithm: hash,
+		HashedMessage: tst.MessageImprint.HashedMessage,
+		SerialNumber:  tst.SerialNumber,
+		Time:          genTime.UTC(),
+		Accuracy: time.Duration(tst.Accuracy.Seconds)*time.Second +
+			time.Duration(tst.Accuracy.Millis)*time.Millisecond +
+			time.Duration(tst.Accuracy.Micros)*time.Microsecond,
+		Ordering: tst.Ordering,
+		Nonce:    tst.Nonce,
+	}
+
+	if len(sd.Certificates.Raw) > 0 {
+		var certs asn1.RawValue
+		if err := unmarshalDER(sd.Certificates.Raw, &certs); err != nil {
+			return nil, fmt.Errorf("invalid certificates: %w", err)
+		}
+
+		if token.Certificates, err = x509.ParseCertificates(certs.Bytes); err != nil {
+			return nil, fmt.Errorf("invalid certificates: %w", err)
+		}
+	}
+
+	return token, nil
+}
+
+// unmarshalDER parses a DER-encoded ASN.1 value, returning an error if there
+// is any trailing data.
+func unmarshalDER(der []byte, out interface{}) error {
+	var rest, err = asn1.Unmarshal(der, out)
+	if err != nil {
+		return err
+	}
+
+	if len(rest) > 0 {
+		return errors.New("trailing data")
+	}
+
+	return nil
+}
+
+// hashFromOID returns the hash function identified by an object identifier.
+func hashFromOID(oid asn1.ObjectIdentifier) (crypto.Hash, bool) {
+	for hash, id := range timestampHashes {
+		if id.Equal(oid) {
+			return hash, true
+		}
+	}
+
+	return 0, false
+}
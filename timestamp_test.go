@@ -0,0 +1,360 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hvclient
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient/internal/oids"
+)
+
+var testTimestampPolicy = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 4146, 2, 3}
+
+// testTimestampTime is the generation time encoded in test timestamp tokens.
+var testTimestampTime = time.Date(2021, 3, 4, 5, 6, 7, 250000000, time.UTC)
+
+// makeTestTimestampToken returns a DER-encoded timestamp token with the
+// specified message imprint, optionally including a certificate.
+func makeTestTimestampToken(t *testing.T, hashOID asn1.ObjectIdentifier, digest []byte, withCert bool) []byte {
+	t.Helper()
+
+	var tst, err = asn1.Marshal(struct {
+		Version        int
+		Policy         asn1.ObjectIdentifier
+		MessageImprint asn1MessageImprint
+		SerialNumber   *big.Int
+		GenTime        asn1.RawValue
+		Accuracy       asn1Accuracy
+		Ordering       bool     `asn1:"optional"`
+		Nonce          *big.Int `asn1:"optional"`
+	}{
+		Version: 1,
+		Policy:  testTimestampPolicy,
+		MessageImprint: asn1MessageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: hashOID},
+			HashedMessage: digest,
+		},
+		SerialNumber: big.NewInt(12345),
+		GenTime: asn1.RawValue{
+			Tag:   asn1.TagGeneralizedTime,
+			Bytes: []byte("20210304050607.25Z"),
+		},
+		Accuracy: asn1Accuracy{Seconds: 1, Millis: 500},
+		Nonce:    big.NewInt(42),
+	})
+	if err != nil {
+		t.Fatalf("couldn't marshal TSTInfo: %v", err)
+	}
+
+	var certs asn1.RawValue
+	if withCert {
+		var data, err = ioutil.ReadFile("testdata/test_cert.pem")
+		if err != nil {
+			t.Fatalf("couldn't read certificate: %v", err)
+		}
+
+		var block, _ = pem.Decode(data)
+		if block == nil {
+			t.Fatalf("couldn't decode certificate PEM")
+		}
+
+		if certs.FullBytes, err = asn1.Marshal(asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      block.Bytes,
+		}); err != nil {
+			t.Fatalf("couldn't marshal certificates: %v", err)
+		}
+	}
+
+	var signerInfos []byte
+	if signerInfos, err = asn1.Marshal(asn1.RawValue{Tag: asn1.TagSet, IsCompound: true}); err != nil {
+		t.Fatalf("couldn't marshal signer infos: %v", err)
+	}
+
+	var sd []byte
+	if sd, err = asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+		EncapContentInfo asn1EncapContentInfo
+		Certificates     asn1.RawValue `asn1:"optional"`
+		SignerInfos      asn1.RawValue
+	}{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: hashOID}},
+		EncapContentInfo: asn1EncapContentInfo{
+			EContentType: oids.OIDContentTypeTSTInfo,
+			EContent:     tst,
+		},
+		Certificates: certs,
+		SignerInfos:  asn1.RawValue{FullBytes: signerInfos},
+	}); err != nil {
+		t.Fatalf("couldn't marshal signed data: %v", err)
+	}
+
+	var der []byte
+	if der, err = marshalTestContentInfo(oids.OIDContentTypeSignedData, sd); err != nil {
+		t.Fatalf("couldn't marshal content info: %v", err)
+	}
+
+	return der
+}
+
+// marshalTestContentInfo returns a DER-encoded CMS ContentInfo with the
+// specified content type and DER-encoded content.
+func marshalTestContentInfo(contentType asn1.ObjectIdentifier, content []byte) ([]byte, error) {
+	return asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{
+		ContentType: contentType,
+		Content: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      content,
+		},
+	})
+}
+
+func TestParseTimestampToken(t *testing.T) {
+	t.Parallel()
+
+	var digest = sha256.Sum256([]byte("hello, world"))
+
+	for _, withCert := range []bool{false, true} {
+		var withCert = withCert
+
+		t.Run(fmt.Sprintf("WithCert%t", withCert), func(t *testing.T) {
+			t.Parallel()
+
+			var got, err = ParseTimestampToken(makeTestTimestampToken(t, oids.OIDHashSHA256, digest[:], withCert))
+			if err != nil {
+				t.Fatalf("couldn't parse timestamp token: %v", err)
+			}
+
+			if !got.Policy.Equal(testTimestampPolicy) {
+				t.Errorf("got policy %v, want %v", got.Policy, testTimestampPolicy)
+			}
+
+			if got.HashAlgorithm != crypto.SHA256 {
+				t.Errorf("got hash algorithm %v, want %v", got.HashAlgorithm, crypto.SHA256)
+			}
+
+			if string(got.HashedMessage) != string(digest[:]) {
+				t.Errorf("got hashed message %x, want %x", got.HashedMessage, digest)
+			}
+
+			if got.SerialNumber.Cmp(big.NewInt(12345)) != 0 {
+				t.Errorf("got serial number %v, want %d", got.SerialNumber, 12345)
+			}
+
+			if !got.Time.Equal(testTimestampTime) {
+				t.Errorf("got time %v, want %v", got.Time, testTimestampTime)
+			}
+
+			if want := time.Millisecond * 1500; got.Accuracy != want {
+				t.Errorf("got accuracy %v, want %v", got.Accuracy, want)
+			}
+
+			if got.Nonce == nil || got.Nonce.Cmp(big.NewInt(42)) != 0 {
+				t.Errorf("got nonce %v, want %d", got.Nonce, 42)
+			}
+
+			if want := map[bool]int{false: 0, true: 1}[withCert]; len(got.Certificates) != want {
+				t.Errorf("got %d certificates, want %d", len(got.Certificates), want)
+			}
+		})
+	}
+}
+
+func TestParseTimestampTokenFailure(t *testing.T) {
+	t.Parallel()
+
+	var digest = sha256.Sum256([]byte("hello, world"))
+	var valid = makeTestTimestampToken(t, oids.OIDHashSHA256, digest[:], false)
+
+	var wrongType, err = marshalTestContentInfo(oids.OIDContentTypeTSTInfo, []byte{0x05, 0x00})
+	if err != nil {
+		t.Fatalf("couldn't marshal content info: %v", err)
+	}
+
+	var testcases = []struct {
+		name string
+		der  []byte
+	}{
+		{
+			name: "Empty",
+		},
+		{
+			name: "TrailingData",
+			der:  append(append([]byte(nil), valid...), 0x00),
+		},
+		{
+			name: "WrongContentType",
+			der:  wrongType,
+		},
+		{
+			name: "UnknownHash",
+			der:  makeTestTimestampToken(t, asn1.ObjectIdentifier{1, 2, 3, 4}, digest[:], false),
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got, err := ParseTimestampToken(tc.der); err == nil {
+				t.Fatalf("unexpectedly parsed timestamp token: %v", got)
+			}
+		})
+	}
+}
+
+func TestClientTimestamp(t *testing.T) {
+	t.Parallel()
+
+	var digest = sha256.Sum256([]byte("hello, world"))
+	var other = sha256.Sum256([]byte("goodbye, world"))
+
+	var testcases = []struct {
+		name   string
+		digest []byte
+		hash   crypto.Hash
+		token  []byte
+		err    bool
+	}{
+		{
+			name:   "OK",
+			digest: digest[:],
+			hash:   crypto.SHA256,
+			token:  makeTestTimestampToken(t, oids.OIDHashSHA256, digest[:], true),
+		},
+		{
+			name:   "ImprintMismatch",
+			digest: digest[:],
+			hash:   crypto.SHA256,
+			token:  makeTestTimestampToken(t, oids.OIDHashSHA256, other[:], false),
+			err:    true,
+		},
+		{
+			name:   "UnsupportedHash",
+			digest: digest[:20],
+			hash:   crypto.SHA1,
+			err:    true,
+		},
+		{
+			name:   "BadDigestLength",
+			digest: digest[:20],
+			hash:   crypto.SHA256,
+			err:    true,
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gotPath string
+
+			var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"token":%q}`, base64.StdEncoding.EncodeToString(tc.token))
+			})
+
+			var clnt, _ = newTestClient(t, handler, nil)
+
+			var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+			defer cancel()
+
+			var got, err = clnt.Timestamp(ctx, tc.digest, tc.hash)
+			if (err != nil) != tc.err {
+				t.Fatalf("got error %v, want error %t", err, tc.err)
+			}
+
+			if tc.err {
+				return
+			}
+
+			if want := fmt.Sprintf("/timestamp/%x", tc.digest); gotPath != want {
+				t.Errorf("got path %q, want %q", gotPath, want)
+			}
+
+			if string(got.Raw) != string(tc.token) {
+				t.Errorf("got raw token %x, want %x", got.Raw, tc.token)
+			}
+		})
+	}
+}
+
+func TestClientTimestampSample(t *testing.T) {
+	t.Parallel()
+
+	var handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var digest, err = hex.DecodeString(strings.TrimPrefix(r.URL.Path, "/timestamp/"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"token":%q}`, base64.StdEncoding.EncodeToString(makeTestTimestampToken(t, oids.OIDHashSHA256, digest, false)))
+	})
+
+	var clnt, _ = newTestClient(t, handler, nil)
+
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("couldn't generate key: %v", err)
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	var got *TimestampToken
+	if got, err = clnt.timestampSample(ctx, key, &x509.Certificate{Raw: []byte("certificate")}); err != nil {
+		t.Fatalf("couldn't timestamp sample signature: %v", err)
+	}
+
+	if got.HashAlgorithm != crypto.SHA256 {
+		t.Errorf("got hash algorithm %v, want %v", got.HashAlgorithm, crypto.SHA256)
+	}
+}
@@ -0,0 +1,453 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+// Client is the subset of HVCA client functionality used by a backend. It
+// is satisfied by *hvclient.Client.
+type Client interface {
+	Policy(ctx context.Context) (*hvclient.Policy, error)
+	CertificateRequest(ctx context.Context, req *hvclient.Request) (*big.Int, error)
+	CertificateRetrieve(ctx context.Context, serial *big.Int) (*hvclient.CertInfo, error)
+	CertificateRevokeWithReason(ctx context.Context, serial *big.Int, reason hvclient.RevocationReason, time int64) error
+	TrustChain(ctx context.Context) ([]*x509.Certificate, error)
+}
+
+// Config is a configuration object for a backend.
+type Config struct {
+	// Client is used to request and revoke certificates, and is usually an
+	// *hvclient.Client. It is required.
+	Client Client
+
+	// NoPolicyDefaults, if true, prevents values which are fixed by the
+	// validation policy, such as static subject attributes, from being
+	// added to requests.
+	NoPolicyDefaults bool
+
+	// Clock, if not nil, is used as the source of the current time for the
+	// not-before time of requested certificates and for revocation times,
+	// in place of the system clock.
+	Clock hvclient.Clock
+}
+
+// Backend performs Vault PKI secrets engine operations using HVCA. A backend
+// is safe for concurrent use.
+type Backend struct {
+	config Config
+}
+
+// ErrInvalidRequest is wrapped by errors returned by a backend when the
+// request fields are missing or invalid.
+var ErrInvalidRequest = errors.New("invalid request")
+
+// Default key parameters for the issue operation.
+const (
+	defaultKeyType    = "ec"
+	defaultRSAKeyBits = 2048
+	defaultECKeyBits  = 256
+)
+
+// New returns a new backend.
+func New(conf Config) (*Backend, error) {
+	if conf.Client == nil {
+		return nil, errors.New("no client provided")
+	}
+
+	if conf.Clock == nil {
+		conf.Clock = hvclient.SystemClock
+	}
+
+	return &Backend{config: conf}, nil
+}
+
+// Issue generates a new private key and obtains a certificate for it from
+// HVCA, as for the issue operation of the Vault PKI secrets engine. The
+// fields common_name, alt_names, ip_sans, uri_sans, ttl, key_type and
+// key_bits are recognised. The response contains the certificate, the
+// issuing CA, the CA chain, the serial number, the expiration time, and the
+// private key and its type.
+func (b *Backend) Issue(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+	var key, keyType, err = generateKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var pol *hvclient.Policy
+	if pol, err = b.config.Client.Policy(ctx); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve validation policy: %w", err)
+	}
+
+	var req *hvclient.Request
+	if req, err = b.newRequest(data, pol); err != nil {
+		return nil, err
+	}
+
+	req.PrivateKey = key
+
+	if pol.PublicKey != nil && pol.PublicKey.KeyFormat == hvclient.PKCS10 {
+		if req.CSR, err = req.PKCS10(); err != nil {
+			return nil, fmt.Errorf("couldn't create certificate signing request: %w", err)
+		}
+
+		req.PrivateKey = nil
+	}
+
+	var resp map[string]interface{}
+	if resp, err = b.request(ctx, req); err != nil {
+		return nil, err
+	}
+
+	var der []byte
+	if der, err = x509.MarshalPKCS8PrivateKey(key); err != nil {
+		return nil, fmt.Errorf("couldn't marshal private key: %w", err)
+	}
+
+	resp["private_key"] = strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+	resp["private_key_type"] = keyType
+
+	return resp, nil
+}
+
+// Sign obtains a certificate from HVCA for the PEM-encoded PKCS#10
+// certificate signing request in the csr field, as for the sign operation
+// of the Vault PKI secrets engine. The fields common_name, alt_names,
+// ip_sans, uri_sans and ttl are also recognised, and are added to the
+// subject and subject alternative names in the certificate signing request.
+// The request itself is submitted if the validation policy requires PKCS#10
+// proof-of-possession, and otherwise only its public key is submitted, after
+// its signature has been checked. The response contains the certificate,
+// the issuing CA, the CA chain, the serial number and the expiration time.
+func (b *Backend) Sign(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+	var text, err = stringField(data, "csr")
+	if err != nil {
+		return nil, err
+	}
+
+	var block, _ = pem.Decode([]byte(text))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("%w: csr: no PEM-encoded certificate signing request found", ErrInvalidRequest)
+	}
+
+	var csr *x509.CertificateRequest
+	if csr, err = x509.ParseCertificateRequest(block.Bytes); err != nil {
+		return nil, fmt.Errorf("%w: csr: %v", ErrInvalidRequest, err)
+	}
+
+	if err = csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: csr: bad signature: %v", ErrInvalidRequest, err)
+	}
+
+	var pol *hvclient.Policy
+	if pol, err = b.config.Client.Policy(ctx); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve validation policy: %w", err)
+	}
+
+	var req *hvclient.Request
+	if req, err = b.newRequest(data, pol); err != nil {
+		return nil, err
+	}
+
+	if len(csr.Subject.Names) > 0 {
+		var dn *hvclient.DN
+		if dn, err = hvclient.ParseDN(csr.Subject.String()); err != nil {
+			return nil, fmt.Errorf("%w: csr: unsupported subject: %v", ErrInvalidRequest, err)
+		}
+
+		if req.Subject != nil && req.Subject.CommonName != "" {
+			dn.CommonName = req.Subject.CommonName
+		}
+
+		req.Subject = dn
+	}
+
+	if len(csr.DNSNames) > 0 || len(csr.EmailAddresses) > 0 ||
+		len(csr.IPAddresses) > 0 || len(csr.URIs) > 0 {
+		if req.SAN == nil {
+			req.SAN = &hvclient.SAN{}
+		}
+
+		req.SAN.DNSNames = append(csr.DNSNames, req.SAN.DNSNames...)
+		req.SAN.Emails = append(csr.EmailAddresses, req.SAN.Emails...)
+		req.SAN.IPAddresses = append(csr.IPAddresses, req.SAN.IPAddresses...)
+		req.SAN.URIs = append(csr.URIs, req.SAN.URIs...)
+	}
+
+	if pol.PublicKey != nil && pol.PublicKey.KeyFormat == hvclient.PKCS10 {
+		req.CSR = csr
+	} else {
+		req.PublicKey = csr.PublicKey
+	}
+
+	return b.request(ctx, req)
+}
+
+// Revoke revokes the certificate with the serial number in the
+// serial_number field, as for the revoke operation of the Vault PKI secrets
+// engine. The serial number may be in Vault's colon-separated format or in
+// plain hexadecimal. An optional reason field specifies the revocation
+// reason, which defaults to unspecified. The response contains the
+// revocation time.
+func (b *Backend) Revoke(ctx context.Context, data map[string]interface{}) (map[string]interface{}, error) {
+	var text, err = stringField(data, "serial_number")
+	if err != nil {
+		return nil, err
+	}
+
+	var serial, ok = big.NewInt(0).SetString(strings.NewReplacer(":", "", "-", "").Replace(text), 16)
+	if !ok {
+		return nil, fmt.Errorf("%w: serial_number: invalid serial number %q", ErrInvalidRequest, text)
+	}
+
+	var reason = hvclient.RevocationReasonUnspecified
+	if value, ok := data["reason"]; ok {
+		var s, ok = value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: reason: got %T, want string", ErrInvalidRequest, value)
+		}
+
+		if reason, err = hvclient.ParseRevocationReason(s); err != nil {
+			return nil, fmt.Errorf("%w: reason: %v", ErrInvalidRequest, err)
+		}
+	}
+
+	var now = b.config.Clock.Now()
+	if err = b.config.Client.CertificateRevokeWithReason(ctx, serial, reason, 0); err != nil {
+		return nil, fmt.Errorf("couldn't revoke certificate %X: %w", serial, err)
+	}
+
+	return map[string]interface{}{
+		"revocation_time":         now.Unix(),
+		"revocation_time_rfc3339": now.UTC().Format(time.RFC3339),
+	}, nil
+}
+
+// newRequest builds a certificate request from the common name, subject
+// alternative name and TTL fields, without a key.
+func (b *Backend) newRequest(data map[string]interface{}, pol *hvclient.Policy) (*hvclient.Request, error) {
+	var now = b.config.Clock.Now()
+
+	var req = &hvclient.Request{
+		Validity: &hvclient.Validity{
+			NotBefore: now,
+			NotAfter:  time.Unix(0, 0),
+		},
+	}
+
+	var ttl, err = durationField(data, "ttl")
+	if err != nil {
+		return nil, err
+	} else if ttl > 0 {
+		req.Validity.NotAfter = now.Add(ttl)
+	}
+
+	if _, ok := data["common_name"]; ok {
+		var cn string
+		if cn, err = stringField(data, "common_name"); err != nil {
+			return nil, err
+		}
+
+		req.Subject = &hvclient.DN{CommonName: cn}
+	}
+
+	var san hvclient.SAN
+
+	var names []string
+	if names, err = listField(data, "alt_names"); err != nil {
+		return nil, err
+	}
+
+	// Vault accepts email addresses among the alternative names.
+	for _, name := range names {
+		if strings.Contains(name, "@") {
+			san.Emails = append(san.Emails, name)
+		} else {
+			san.DNSNames = append(san.DNSNames, name)
+		}
+	}
+
+	if names, err = listField(data, "ip_sans"); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		var ip = net.ParseIP(name)
+		if ip == nil {
+			return nil, fmt.Errorf("%w: ip_sans: invalid IP address %q", ErrInvalidRequest, name)
+		}
+
+		san.IPAddresses = append(san.IPAddresses, ip)
+	}
+
+	if names, err = listField(data, "uri_sans"); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		var uri, err = url.Parse(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: uri_sans: %v", ErrInvalidRequest, err)
+		}
+
+		san.URIs = append(san.URIs, uri)
+	}
+
+	if !san.Equal(&hvclient.SAN{}) {
+		req.SAN = &san
+	}
+
+	if !b.config.NoPolicyDefaults {
+		if err = req.ApplyPolicyDefaults(pol); err != nil {
+			return nil, fmt.Errorf("couldn't apply validation policy defaults: %w", err)
+		}
+	}
+
+	return req, nil
+}
+
+// request submits a certificate request to HVCA, and returns the response
+// data for the issued certificate.
+func (b *Backend) request(ctx context.Context, req *hvclient.Request) (map[string]interface{}, error) {
+	var serial, err = b.config.Client.CertificateRequest(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't request certificate: %w", err)
+	}
+
+	var info *hvclient.CertInfo
+	if info, err = b.config.Client.CertificateRetrieve(ctx, serial); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve certificate %X: %w", serial, err)
+	}
+
+	if info.X509 == nil {
+		return nil, fmt.Errorf("no certificate returned for serial number %X", serial)
+	}
+
+	var chain []*x509.Certificate
+	if chain, err = b.config.Client.TrustChain(ctx); err != nil {
+		return nil, fmt.Errorf("couldn't retrieve trust chain: %w", err)
+	}
+
+	var caChain = make([]string, 0, len(chain))
+	for _, cert := range chain {
+		caChain = append(caChain, strings.TrimSpace(pki.CertToPEMString(cert)))
+	}
+
+	var resp = map[string]interface{}{
+		"certificate":   strings.TrimSpace(pki.CertToPEMString(info.X509)),
+		"ca_chain":      caChain,
+		"serial_number": FormatSerialNumber(serial),
+		"expiration":    info.X509.NotAfter.Unix(),
+	}
+
+	if len(caChain) > 0 {
+		resp["issuing_ca"] = caChain[0]
+	}
+
+	return resp, nil
+}
+
+// FormatSerialNumber formats a serial number in the colon-separated
+// hexadecimal format used by Vault, for example "01:f6:17:50".
+func FormatSerialNumber(serial *big.Int) string {
+	var hex = fmt.Sprintf("%x", serial)
+	if len(hex)%2 != 0 {
+		hex = "0" + hex
+	}
+
+	var pairs = make([]string, 0, len(hex)/2)
+	for i := 0; i < len(hex); i += 2 {
+		pairs = append(pairs, hex[i:i+2])
+	}
+
+	return strings.Join(pairs, ":")
+}
+
+// generateKey generates a private key of the type and size specified by the
+// key_type and key_bits fields, and returns it with its type.
+func generateKey(data map[string]interface{}) (crypto.Signer, string, error) {
+	var keyType = defaultKeyType
+	if _, ok := data["key_type"]; ok {
+		var err error
+		if keyType, err = stringField(data, "key_type"); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var bits, err = intField(data, "key_bits")
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch keyType {
+	case "rsa":
+		if bits == 0 {
+			bits = defaultRSAKeyBits
+		}
+
+		if bits < 2048 {
+			return nil, "", fmt.Errorf("%w: key_bits: RSA keys must be at least 2048 bits", ErrInvalidRequest)
+		}
+
+		var key, err = rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, "", fmt.Errorf("couldn't generate private key: %w", err)
+		}
+
+		return key, keyType, nil
+
+	case "ec":
+		var curve elliptic.Curve
+
+		switch bits {
+		case 0, defaultECKeyBits:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, "", fmt.Errorf("%w: key_bits: unsupported EC key size %d", ErrInvalidRequest, bits)
+		}
+
+		var key, err = ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, "", fmt.Errorf("couldn't generate private key: %w", err)
+		}
+
+		return key, keyType, nil
+	}
+
+	return nil, "", fmt.Errorf("%w: key_type: unsupported key type %q", ErrInvalidRequest, keyType)
+}
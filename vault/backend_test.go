@@ -0,0 +1,370 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/hvcatest"
+	"github.com/globalsign/hvclient/internal/pki"
+	"github.com/globalsign/hvclient/vault"
+	"github.com/google/go-cmp/cmp"
+)
+
+// recordingClient is a vault.Client which records the last certificate
+// request before passing it to an HVCA client.
+type recordingClient struct {
+	*hvclient.Client
+	req *hvclient.Request
+}
+
+func (c *recordingClient) CertificateRequest(ctx context.Context, req *hvclient.Request) (*big.Int, error) {
+	c.req = req
+
+	return c.Client.CertificateRequest(ctx, req)
+}
+
+func newBackend(t *testing.T, now time.Time) (*vault.Backend, *recordingClient) {
+	t.Helper()
+
+	var server = httptest.NewServer(hvcatest.NewHandler())
+	t.Cleanup(server.Close)
+
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var clnt, err = hvclient.NewClient(ctx, hvcatest.Config(server.URL))
+	if err != nil {
+		t.Fatalf("couldn't create client: %v", err)
+	}
+
+	var rec = &recordingClient{Client: clnt}
+
+	var backend *vault.Backend
+	if backend, err = vault.New(vault.Config{Client: rec, Clock: fakeClock{now}}); err != nil {
+		t.Fatalf("couldn't create backend: %v", err)
+	}
+
+	return backend, rec
+}
+
+func TestBackendIssue(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Date(2021, 6, 18, 16, 29, 51, 0, time.UTC)
+	var backend, clnt = newBackend(t, now)
+
+	var resp, err = backend.Issue(context.Background(), map[string]interface{}{
+		"common_name": "JohnDoe",
+		"ttl":         "24h",
+	})
+	if err != nil {
+		t.Fatalf("couldn't issue certificate: %v", err)
+	}
+
+	if got, want := resp["serial_number"], vault.FormatSerialNumber(hvcatest.Cert.SerialNumber); got != want {
+		t.Errorf("got serial number %v, want %s", got, want)
+	}
+
+	if got, want := resp["certificate"], trimmedPEM(hvcatest.Cert); got != want {
+		t.Errorf("got certificate %v, want %s", got, want)
+	}
+
+	if got, want := resp["issuing_ca"], trimmedPEM(hvcatest.TrustChain[0]); got != want {
+		t.Errorf("got issuing CA %v, want %s", got, want)
+	}
+
+	var wantChain = []string{trimmedPEM(hvcatest.TrustChain[0]), trimmedPEM(hvcatest.TrustChain[1])}
+	if !cmp.Equal(resp["ca_chain"], wantChain) {
+		t.Errorf("got CA chain %v, want %v", resp["ca_chain"], wantChain)
+	}
+
+	if got, want := resp["expiration"], hvcatest.Cert.NotAfter.Unix(); got != want {
+		t.Errorf("got expiration %v, want %d", got, want)
+	}
+
+	if got := resp["private_key_type"]; got != "ec" {
+		t.Errorf("got private key type %v, want ec", got)
+	}
+
+	var text, _ = resp["private_key"].(string)
+	var block, _ = pem.Decode([]byte(text))
+	if block == nil {
+		t.Fatalf("no PEM-encoded private key returned")
+	}
+
+	var key interface{}
+	if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		t.Fatalf("couldn't parse private key: %v", err)
+	}
+
+	// The mock policy requires PKCS#10 proof-of-possession, so the request
+	// should carry a CSR for the returned key.
+	if clnt.req.CSR == nil || clnt.req.PrivateKey != nil {
+		t.Fatalf("got CSR %v and private key %v, want CSR only", clnt.req.CSR, clnt.req.PrivateKey)
+	}
+
+	if !key.(*ecdsa.PrivateKey).PublicKey.Equal(clnt.req.CSR.PublicKey) {
+		t.Errorf("CSR public key does not match returned private key")
+	}
+
+	if clnt.req.Subject == nil || clnt.req.Subject.CommonName != "JohnDoe" {
+		t.Errorf("got subject %v, want common name JohnDoe", clnt.req.Subject)
+	}
+
+	if want := now.Add(24 * time.Hour); !clnt.req.Validity.NotAfter.Equal(want) {
+		t.Errorf("got not-after time %v, want %v", clnt.req.Validity.NotAfter, want)
+	}
+}
+
+func TestBackendIssueCalendarTTL(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Date(2021, 6, 18, 16, 29, 51, 0, time.UTC)
+	var backend, clnt = newBackend(t, now)
+
+	if _, err := backend.Issue(context.Background(), map[string]interface{}{
+		"common_name": "JohnDoe",
+		"ttl":         "30d",
+	}); err != nil {
+		t.Fatalf("couldn't issue certificate: %v", err)
+	}
+
+	if want := now.Add(30 * 24 * time.Hour); !clnt.req.Validity.NotAfter.Equal(want) {
+		t.Errorf("got not after %v, want %v", clnt.req.Validity.NotAfter, want)
+	}
+}
+func TestBackendSign(t *testing.T) {
+	t.Parallel()
+
+	var backend, clnt = newBackend(t, time.Now())
+
+	var key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("couldn't generate key: %v", err)
+	}
+
+	var der []byte
+	if der, err = x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "JohnDoe"},
+	}, key); err != nil {
+		t.Fatalf("couldn't create certificate signing request: %v", err)
+	}
+
+	var resp map[string]interface{}
+	if resp, err = backend.Sign(context.Background(), map[string]interface{}{
+		"csr": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})),
+		"ttl": 3600,
+	}); err != nil {
+		t.Fatalf("couldn't sign certificate request: %v", err)
+	}
+
+	if got, want := resp["certificate"], trimmedPEM(hvcatest.Cert); got != want {
+		t.Errorf("got certificate %v, want %s", got, want)
+	}
+
+	if _, ok := resp["private_key"]; ok {
+		t.Errorf("unexpectedly returned private key")
+	}
+
+	if clnt.req.CSR == nil || clnt.req.Subject == nil || clnt.req.Subject.CommonName != "JohnDoe" {
+		t.Errorf("got CSR %v and subject %v, want CSR with common name JohnDoe", clnt.req.CSR, clnt.req.Subject)
+	}
+
+	if got, want := clnt.req.Validity.NotAfter.Sub(clnt.req.Validity.NotBefore), time.Hour; got != want {
+		t.Errorf("got validity period %v, want %v", got, want)
+	}
+}
+
+func TestBackendRevoke(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Date(2021, 6, 18, 16, 29, 51, 0, time.UTC)
+	var backend, _ = newBackend(t, now)
+
+	var resp, err = backend.Revoke(context.Background(), map[string]interface{}{
+		"serial_number": vault.FormatSerialNumber(hvcatest.Cert.SerialNumber),
+		"reason":        string(hvclient.RevocationReasonKeyCompromise),
+	})
+	if err != nil {
+		t.Fatalf("couldn't revoke certificate: %v", err)
+	}
+
+	if got, want := resp["revocation_time"], now.Unix(); got != want {
+		t.Errorf("got revocation time %v, want %d", got, want)
+	}
+}
+
+func TestBackendFailure(t *testing.T) {
+	t.Parallel()
+
+	var backend, _ = newBackend(t, time.Now())
+
+	var testcases = []struct {
+		name string
+		op   func(context.Context, map[string]interface{}) (map[string]interface{}, error)
+		data map[string]interface{}
+		err  error
+	}{
+		{
+			name: "IssueBadKeyType",
+			op:   backend.Issue,
+			data: map[string]interface{}{"common_name": "JohnDoe", "key_type": "dsa"},
+			err:  vault.ErrInvalidRequest,
+		},
+		{
+			name: "IssueBadKeyBits",
+			op:   backend.Issue,
+			data: map[string]interface{}{"common_name": "JohnDoe", "key_type": "rsa", "key_bits": 1024},
+			err:  vault.ErrInvalidRequest,
+		},
+		{
+			name: "IssueBadTTL",
+			op:   backend.Issue,
+			data: map[string]interface{}{"common_name": "JohnDoe", "ttl": "forever"},
+			err:  vault.ErrInvalidRequest,
+		},
+		{
+			name: "IssueBadIPSAN",
+			op:   backend.Issue,
+			data: map[string]interface{}{"common_name": "JohnDoe", "ip_sans": "10.0.0.256"},
+			err:  vault.ErrInvalidRequest,
+		},
+		{
+			name: "IssueBadAltNames",
+			op:   backend.Issue,
+			data: map[string]interface{}{"common_name": "JohnDoe", "alt_names": 42},
+			err:  vault.ErrInvalidRequest,
+		},
+		{
+			name: "IssueRejected",
+			op:   backend.Issue,
+			data: map[string]interface{}{"common_name": hvcatest.TriggerError},
+		},
+		{
+			name: "SignNoCSR",
+			op:   backend.Sign,
+			data: map[string]interface{}{},
+			err:  vault.ErrInvalidRequest,
+		},
+		{
+			name: "SignNotCSR",
+			op:   backend.Sign,
+			data: map[string]interface{}{"csr": "not a CSR"},
+			err:  vault.ErrInvalidRequest,
+		},
+		{
+			name: "RevokeNoSerial",
+			op:   backend.Revoke,
+			data: map[string]interface{}{},
+			err:  vault.ErrInvalidRequest,
+		},
+		{
+			name: "RevokeBadSerial",
+			op:   backend.Revoke,
+			data: map[string]interface{}{"serial_number": "zz:zz"},
+			err:  vault.ErrInvalidRequest,
+		},
+		{
+			name: "RevokeBadReason",
+			op:   backend.Revoke,
+			data: map[string]interface{}{"serial_number": "01", "reason": "bored"},
+			err:  vault.ErrInvalidRequest,
+		},
+		{
+			name: "RevokeNotFound",
+			op:   backend.Revoke,
+			data: map[string]interface{}{"serial_number": vault.FormatSerialNumber(hvcatest.SerialNotFound)},
+		},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var _, err = tc.op(context.Background(), tc.data)
+			if err == nil {
+				t.Fatalf("unexpectedly succeeded")
+			}
+
+			if errors.Is(err, vault.ErrInvalidRequest) != (tc.err != nil) {
+				t.Errorf("got error %v, want %v", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestFormatSerialNumber(t *testing.T) {
+	t.Parallel()
+
+	var testcases = []struct {
+		serial *big.Int
+		want   string
+	}{
+		{big.NewInt(0), "00"},
+		{big.NewInt(0x1f61750), "01:f6:17:50"},
+		{big.NewInt(0x7a3c), "7a:3c"},
+	}
+
+	for _, tc := range testcases {
+		if got := vault.FormatSerialNumber(tc.serial); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestNewFailure(t *testing.T) {
+	t.Parallel()
+
+	if _, err := vault.New(vault.Config{}); err == nil {
+		t.Fatalf("unexpectedly created backend with no client")
+	}
+}
+
+// fakeClock is an hvclient.Clock which always reports the same time.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c fakeClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// trimmedPEM returns a PEM-encoded certificate without a trailing
+// newline, as returned in response data.
+func trimmedPEM(cert *x509.Certificate) string {
+	var s = pki.CertToPEMString(cert)
+
+	return s[:len(s)-1]
+}
@@ -0,0 +1,33 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package vault adapts HVCA to the issue, sign and revoke operations of a
+HashiCorp Vault secrets engine, so that organisations which have standardised
+on Vault can front HVCA with their existing Vault workflows.
+
+A Vault plugin built with the Vault plugin SDK registers paths such as
+"issue/:role", "sign/:role" and "revoke", and its path callbacks receive the
+request fields as a map. This package provides the part of such a plugin
+which does not depend on the Vault SDK: a Backend whose Issue, Sign and
+Revoke methods accept the raw field map, with the same field names as
+Vault's built-in PKI secrets engine, and return the response data map, so a
+path callback needs only to pass the fields in and return the result as the
+response data. Role parameters, such as a default TTL or allowed key type,
+may be merged into the field map before it is passed in. Errors which wrap
+ErrInvalidRequest should be reported to the Vault client as invalid requests,
+while other errors indicate a problem communicating with HVCA.
+*/
+package vault
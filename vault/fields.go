@@ -0,0 +1,152 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/globalsign/hvclient"
+)
+
+// stringField returns the value of a required string field.
+func stringField(data map[string]interface{}, name string) (string, error) {
+	var value, ok = data[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s: missing required field", ErrInvalidRequest, name)
+	}
+
+	var s string
+	if s, ok = value.(string); !ok {
+		return "", fmt.Errorf("%w: %s: got %T, want string", ErrInvalidRequest, name, value)
+	} else if s == "" {
+		return "", fmt.Errorf("%w: %s: missing required field", ErrInvalidRequest, name)
+	}
+
+	return s, nil
+}
+
+// listField returns the values of an optional list field, which may be a
+// comma-separated string or a list of strings.
+func listField(data map[string]interface{}, name string) ([]string, error) {
+	var list []string
+
+	switch value := data[name].(type) {
+	case nil:
+		return nil, nil
+
+	case string:
+		if value != "" {
+			list = strings.Split(value, ",")
+		}
+
+	case []string:
+		list = value
+
+	case []interface{}:
+		for _, elem := range value {
+			var s, ok = elem.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s: got element of type %T, want string", ErrInvalidRequest, name, elem)
+			}
+
+			list = append(list, s)
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: %s: got %T, want string or list of strings", ErrInvalidRequest, name, value)
+	}
+
+	var result = make([]string, 0, len(list))
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+
+	return result, nil
+}
+
+// intField returns the value of an optional integer field, or zero if it is
+// not present.
+func intField(data map[string]interface{}, name string) (int, error) {
+	switch value := data[name].(type) {
+	case nil:
+		return 0, nil
+
+	case int:
+		return value, nil
+
+	case int64:
+		return int(value), nil
+
+	case float64:
+		if value == float64(int(value)) {
+			return int(value), nil
+		}
+
+	case json.Number:
+		if n, err := strconv.Atoi(value.String()); err == nil {
+			return n, nil
+		}
+
+	case string:
+		if n, err := strconv.Atoi(value); err == nil {
+			return n, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %s: invalid integer %v", ErrInvalidRequest, name, data[name])
+}
+
+// durationField returns the value of an optional duration field, which may
+// be a Go duration string such as "72h", a duration with a calendar unit as
+// accepted by hvclient.ParseDuration such as "30d" or "1y", or a number of
+// seconds, or zero if it is not present.
+func durationField(data map[string]interface{}, name string) (time.Duration, error) {
+	var d time.Duration
+
+	if s, ok := data[name].(string); ok && s != "" {
+		if _, err := strconv.Atoi(s); err != nil {
+			if d, err = time.ParseDuration(s); err != nil {
+				if d, err = hvclient.ParseDuration(s); err != nil {
+					return 0, fmt.Errorf("%w: %s: %v", ErrInvalidRequest, name, err)
+				}
+			}
+
+			return checkDuration(name, d)
+		}
+	}
+
+	var seconds, err = intField(data, name)
+	if err != nil {
+		return 0, err
+	}
+
+	return checkDuration(name, time.Duration(seconds)*time.Second)
+}
+
+// checkDuration checks that a duration field is not negative.
+func checkDuration(name string, d time.Duration) (time.Duration, error) {
+	if d < 0 {
+		return 0, fmt.Errorf("%w: %s: negative duration %v", ErrInvalidRequest, name, d)
+	}
+
+	return d, nil
+}
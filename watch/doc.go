@@ -0,0 +1,25 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package watch monitors certificates held in files or in memory and invokes a
+renewal callback when each certificate reaches its renewal deadline. It is a
+building block for embedding automatic certificate renewal in Go services.
+
+A renewal deadline is a configurable fraction of the way through the
+certificate's lifetime. The ClientRenewer function returns a renewal callback
+which requests a replacement certificate from HVCA.
+*/
+package watch
@@ -0,0 +1,75 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+
+	"github.com/globalsign/hvclient"
+)
+
+// Requester is the subset of HVCA client functionality used to renew
+// certificates. It is satisfied by *hvclient.Client.
+type Requester interface {
+	CertificateRequest(ctx context.Context, req *hvclient.Request) (*big.Int, error)
+	CertificateRetrieve(ctx context.Context, serial *big.Int) (*hvclient.CertInfo, error)
+}
+
+// RequestFunc returns the certificate request with which to renew the
+// certificate with the specified name.
+type RequestFunc func(name string, cert *x509.Certificate) (*hvclient.Request, error)
+
+// StoreFunc stores a renewed certificate with the specified name, for
+// instance by writing it to the watched file.
+type StoreFunc func(name string, info *hvclient.CertInfo) error
+
+// ClientRenewer returns a renewal function which builds a certificate
+// request, submits it to HVCA and retrieves the issued certificate. If store
+// is not nil, it is called with the issued certificate before the renewal
+// function returns.
+func ClientRenewer(clnt Requester, build RequestFunc, store StoreFunc) RenewFunc {
+	return func(ctx context.Context, name string, cert *x509.Certificate) (*x509.Certificate, error) {
+		var req, err = build(name, cert)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't build certificate request: %w", err)
+		}
+
+		var serial *big.Int
+		if serial, err = clnt.CertificateRequest(ctx, req); err != nil {
+			return nil, fmt.Errorf("couldn't request certificate: %w", err)
+		}
+
+		var info *hvclient.CertInfo
+		if info, err = clnt.CertificateRetrieve(ctx, serial); err != nil {
+			return nil, fmt.Errorf("couldn't retrieve certificate %X: %w", serial, err)
+		}
+
+		if info.X509 == nil {
+			return nil, fmt.Errorf("no certificate returned for serial number %X", serial)
+		}
+
+		if store != nil {
+			if err = store(name, info); err != nil {
+				return nil, fmt.Errorf("couldn't store certificate %X: %w", serial, err)
+			}
+		}
+
+		return info.X509, nil
+	}
+}
@@ -0,0 +1,319 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/internal/pki"
+)
+
+// RenewFunc is called to renew the certificate with the specified name. It
+// should return the replacement certificate. For certificates watched in
+// files, it is also responsible for writing the replacement to the file.
+type RenewFunc func(ctx context.Context, name string, cert *x509.Certificate) (*x509.Certificate, error)
+
+// Config is a configuration object for a watcher.
+type Config struct {
+	// Renew is called for each certificate which has reached its renewal
+	// deadline. It is required.
+	Renew RenewFunc
+
+	// RenewFraction is the fraction of a certificate's lifetime after which
+	// it is due for renewal, and must be between 0 and 1. If this is omitted
+	// or set to zero, a default of two thirds is used.
+	RenewFraction float64
+
+	// Interval is the period between checks of the watched certificates when
+	// the watcher is run. If this is omitted or set to zero, a default of one
+	// minute is used.
+	Interval time.Duration
+
+	// OnError, if not nil, is called with any error encountered when reading
+	// or renewing a certificate while the watcher is run.
+	OnError func(name string, err error)
+
+	// Progress, if not nil, is called after each watched certificate is
+	// checked, and renewed if necessary, with the number of certificates
+	// checked so far in the current pass, the number being checked, and
+	// the error, if any, encountered for that certificate.
+	Progress hvclient.ProgressReporter
+
+	// Clock, if not nil, is used as the source of the current time and of
+	// the timer between checks, in place of the system clock.
+	Clock hvclient.Clock
+}
+
+// Watcher watches a set of certificates and renews each one when it reaches
+// its renewal deadline. A watcher is safe for concurrent use.
+type Watcher struct {
+	config  Config
+	mtx     sync.Mutex
+	sources map[string]*source
+}
+
+// source is a watched certificate, held either in a file or in memory.
+type source struct {
+	path    string
+	cert    *x509.Certificate
+	renewed *x509.Certificate
+}
+
+// defaultRenewFraction is the fraction of a certificate's lifetime after
+// which it is due for renewal if none is specified in the configuration.
+var defaultRenewFraction = 2.0 / 3.0
+
+// defaultInterval is the period between checks if none is specified in the
+// configuration.
+var defaultInterval = time.Minute
+
+// New returns a new watcher with no watched certificates.
+func New(conf Config) (*Watcher, error) {
+	if conf.Renew == nil {
+		return nil, errors.New("no renewal function provided")
+	}
+
+	if conf.RenewFraction == 0 {
+		conf.RenewFraction = defaultRenewFraction
+	} else if conf.RenewFraction < 0 || conf.RenewFraction > 1 {
+		return nil, fmt.Errorf("renewal fraction %v is not between 0 and 1", conf.RenewFraction)
+	}
+
+	if conf.Clock == nil {
+		conf.Clock = hvclient.SystemClock
+	}
+
+	if conf.Interval == 0 {
+		conf.Interval = defaultInterval
+	} else if conf.Interval < 0 {
+		return nil, errors.New("interval cannot be negative")
+	}
+
+	return &Watcher{
+		config:  conf,
+		sources: make(map[string]*source),
+	}, nil
+}
+
+// RenewalDeadline returns the time after which a certificate is due for
+// renewal, being the specified fraction of the way through its lifetime.
+func RenewalDeadline(cert *x509.Certificate, fraction float64) time.Time {
+	var lifetime = cert.NotAfter.Sub(cert.NotBefore)
+
+	return cert.NotBefore.Add(time.Duration(float64(lifetime) * fraction))
+}
+
+// AddFile watches the PEM-encoded certificate in the specified file under
+// the specified name, replacing any certificate already watched under that
+// name. The file is read afresh at each check, so the renewal function
+// should write the replacement certificate to it.
+func (w *Watcher) AddFile(name, path string) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.sources[name] = &source{path: path}
+}
+
+// AddCertificate watches an in-memory certificate under the specified name,
+// replacing any certificate already watched under that name. The watched
+// certificate is replaced by the one returned from the renewal function.
+func (w *Watcher) AddCertificate(name string, cert *x509.Certificate) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.sources[name] = &source{cert: cert}
+}
+
+// Remove stops watching the certificate with the specified name.
+func (w *Watcher) Remove(name string) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	delete(w.sources, name)
+}
+
+// Certificate returns the current certificate watched under the specified
+// name, reading it from its file if necessary.
+func (w *Watcher) Certificate(name string) (*x509.Certificate, error) {
+	w.mtx.Lock()
+	var src, ok = w.sources[name]
+	w.mtx.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no certificate watched with name %q", name)
+	}
+
+	return w.certificate(src)
+}
+
+// Check checks each watched certificate once, in order of name, and renews
+// any which have reached their renewal deadline. A certificate is renewed
+// at most once, even if a file is not updated with the replacement, unless
+// the renewal fails. The first error encountered is returned after all
+// certificates have been checked, or as soon as a renewal fails because the
+// HVCA issuance quota is exhausted, in which case the remaining certificates
+// are not checked and the error reports how far the check progressed.
+func (w *Watcher) Check(ctx context.Context) error {
+	var first error
+
+	w.check(ctx, w.config.Clock.Now(), func(name string, err error) {
+		if first == nil {
+			first = fmt.Errorf("%s: %w", name, err)
+		}
+	})
+
+	return first
+}
+
+// Run checks the watched certificates immediately and then at the
+// configured interval until the context is cancelled, at which point the
+// context's error is returned. Errors encountered when reading or renewing
+// certificates are passed to the configured error function, if any.
+func (w *Watcher) Run(ctx context.Context) error {
+	var onError = w.config.OnError
+	if onError == nil {
+		onError = func(string, error) {}
+	}
+
+	for {
+		w.check(ctx, w.config.Clock.Now(), onError)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-w.config.Clock.After(w.config.Interval):
+		}
+	}
+}
+
+// check checks each watched certificate against the specified time, and
+// renews any which have reached their renewal deadline. Checking stops early
+// if a renewal fails because the HVCA issuance quota is exhausted.
+func (w *Watcher) check(ctx context.Context, now time.Time, onError func(string, error)) {
+	w.mtx.Lock()
+	var names = make([]string, 0, len(w.sources))
+	for name := range w.sources {
+		names = append(names, name)
+	}
+	w.mtx.Unlock()
+
+	sort.Strings(names)
+
+	var renewals int
+
+	for i, name := range names {
+		if ctx.Err() != nil {
+			return
+		}
+
+		w.mtx.Lock()
+		var src, ok = w.sources[name]
+		w.mtx.Unlock()
+
+		var renewed bool
+		var err error
+
+		if ok {
+			renewed, err = w.checkSource(ctx, now, name, src)
+		}
+
+		if w.config.Progress != nil {
+			w.config.Progress.Progress(i+1, len(names), err)
+		}
+
+		if renewed {
+			renewals++
+		}
+
+		if err == nil {
+			continue
+		}
+
+		// Further renewals are bound to fail if the issuance quota is
+		// exhausted, so stop rather than hammering HVCA.
+		if errors.Is(err, hvclient.ErrQuotaExceeded) {
+			onError(name, fmt.Errorf("stopping after %d renewals with %d certificates unchecked: %w",
+				renewals, len(names)-i-1, err))
+			return
+		}
+
+		onError(name, err)
+	}
+}
+
+// checkSource checks a single watched certificate against the specified
+// time, and renews it if it has reached its renewal deadline. It returns
+// true if the certificate was renewed.
+func (w *Watcher) checkSource(ctx context.Context, now time.Time, name string, src *source) (bool, error) {
+	var cert, err = w.certificate(src)
+	if err != nil {
+		return false, err
+	}
+
+	if now.Before(RenewalDeadline(cert, w.config.RenewFraction)) {
+		return false, nil
+	}
+
+	// Don't renew the same certificate twice if its file was not updated
+	// with the replacement.
+	w.mtx.Lock()
+	var renewed = src.renewed != nil && src.renewed.Equal(cert)
+	w.mtx.Unlock()
+
+	if renewed {
+		return false, nil
+	}
+
+	var replacement *x509.Certificate
+	if replacement, err = w.config.Renew(ctx, name, cert); err != nil {
+		return false, fmt.Errorf("couldn't renew certificate: %w", err)
+	}
+
+	w.mtx.Lock()
+	src.renewed = cert
+	if src.path == "" && replacement != nil {
+		src.cert = replacement
+	}
+	w.mtx.Unlock()
+
+	return true, nil
+}
+
+// certificate returns the current certificate for a source.
+func (w *Watcher) certificate(src *source) (*x509.Certificate, error) {
+	w.mtx.Lock()
+	var path, cert = src.path, src.cert
+	w.mtx.Unlock()
+
+	if path == "" {
+		return cert, nil
+	}
+
+	var err error
+	if cert, err = pki.CertFromFile(path); err != nil {
+		return nil, fmt.Errorf("couldn't read certificate: %w", err)
+	}
+
+	return cert, nil
+}
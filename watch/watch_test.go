@@ -0,0 +1,380 @@
+/*
+Copyright (c) 2019-2021 GMO GlobalSign Pte. Ltd.
+
+Licensed under the MIT License (the "License"); you may not use this file except
+in compliance with the License. You may obtain a copy of the License at
+
+https://opensource.org/licenses/MIT
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/globalsign/hvclient"
+	"github.com/globalsign/hvclient/watch"
+)
+
+// testCert returns a certificate with the specified validity period, and
+// with a distinct raw encoding so certificates can be compared.
+func testCert(raw string, notBefore, notAfter time.Time) *x509.Certificate {
+	return &x509.Certificate{
+		Raw:       []byte(raw),
+		NotBefore: notBefore,
+		NotAfter:  notAfter,
+	}
+}
+
+// renewRecorder is a renewal function which records the names of the
+// certificates it renews.
+type renewRecorder struct {
+	mtx     sync.Mutex
+	names   []string
+	err     error
+	replace *x509.Certificate
+}
+
+func (r *renewRecorder) renew(_ context.Context, name string, _ *x509.Certificate) (*x509.Certificate, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.names = append(r.names, name)
+
+	return r.replace, r.err
+}
+
+func TestRenewalDeadline(t *testing.T) {
+	t.Parallel()
+
+	var notBefore = time.Date(2021, 6, 18, 0, 0, 0, 0, time.UTC)
+	var cert = testCert("a", notBefore, notBefore.Add(time.Hour*90))
+
+	var testcases = []struct {
+		fraction float64
+		want     time.Time
+	}{
+		{0, notBefore},
+		{2.0 / 3.0, notBefore.Add(time.Hour * 60)},
+		{1, notBefore.Add(time.Hour * 90)},
+	}
+
+	for _, tc := range testcases {
+		if got := watch.RenewalDeadline(cert, tc.fraction); !got.Equal(tc.want) {
+			t.Errorf("got %v for fraction %v, want %v", got, tc.fraction, tc.want)
+		}
+	}
+}
+
+func TestNewFailure(t *testing.T) {
+	t.Parallel()
+
+	var renew = (&renewRecorder{}).renew
+
+	var testcases = []struct {
+		name string
+		conf watch.Config
+	}{
+		{"NoRenew", watch.Config{}},
+		{"NegativeFraction", watch.Config{Renew: renew, RenewFraction: -0.5}},
+		{"LargeFraction", watch.Config{Renew: renew, RenewFraction: 1.5}},
+		{"NegativeInterval", watch.Config{Renew: renew, Interval: -time.Second}},
+	}
+
+	for _, tc := range testcases {
+		var tc = tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := watch.New(tc.conf); err == nil {
+				t.Fatalf("unexpectedly created watcher")
+			}
+		})
+	}
+}
+
+func TestWatcherCheck(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Now()
+	var due = testCert("due", now.Add(-time.Hour*80), now.Add(time.Hour*10))
+	var fresh = testCert("fresh", now.Add(-time.Hour), now.Add(time.Hour*89))
+	var replacement = testCert("replacement", now, now.Add(time.Hour*90))
+
+	var rec = &renewRecorder{replace: replacement}
+
+	var w, err = watch.New(watch.Config{Renew: rec.renew})
+	if err != nil {
+		t.Fatalf("couldn't create watcher: %v", err)
+	}
+
+	w.AddCertificate("due", due)
+	w.AddCertificate("fresh", fresh)
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		if err = w.Check(ctx); err != nil {
+			t.Fatalf("couldn't check certificates: %v", err)
+		}
+	}
+
+	if len(rec.names) != 1 || rec.names[0] != "due" {
+		t.Fatalf("got renewals %v, want [due]", rec.names)
+	}
+
+	var got *x509.Certificate
+	if got, err = w.Certificate("due"); err != nil {
+		t.Fatalf("couldn't get certificate: %v", err)
+	}
+
+	if !got.Equal(replacement) {
+		t.Errorf("watched certificate was not replaced")
+	}
+
+	w.Remove("fresh")
+
+	if _, err = w.Certificate("fresh"); err == nil {
+		t.Errorf("unexpectedly got removed certificate")
+	}
+}
+
+func TestWatcherCheckFile(t *testing.T) {
+	t.Parallel()
+
+	var data, err = ioutil.ReadFile("../testdata/test_cert.pem")
+	if err != nil {
+		t.Fatalf("couldn't read certificate: %v", err)
+	}
+
+	var path = filepath.Join(t.TempDir(), "cert.pem")
+	if err = ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("couldn't write certificate: %v", err)
+	}
+
+	var rec = &renewRecorder{}
+
+	var w *watch.Watcher
+	if w, err = watch.New(watch.Config{Renew: rec.renew}); err != nil {
+		t.Fatalf("couldn't create watcher: %v", err)
+	}
+
+	w.AddFile("file", path)
+	w.AddFile("missing", filepath.Join(t.TempDir(), "missing.pem"))
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The test certificate has expired so is due for renewal, but since the
+	// renewal function doesn't update the file it should only be renewed
+	// once. The missing file should be reported as an error each time.
+	for i := 0; i < 2; i++ {
+		if err = w.Check(ctx); err == nil {
+			t.Fatalf("unexpectedly checked missing certificate file")
+		}
+	}
+
+	if len(rec.names) != 1 || rec.names[0] != "file" {
+		t.Fatalf("got renewals %v, want [file]", rec.names)
+	}
+}
+
+func TestWatcherCheckRenewFailure(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Now()
+	var rec = &renewRecorder{err: errors.New("renewal failed")}
+
+	var w, err = watch.New(watch.Config{Renew: rec.renew, RenewFraction: 0.5})
+	if err != nil {
+		t.Fatalf("couldn't create watcher: %v", err)
+	}
+
+	w.AddCertificate("due", testCert("due", now.Add(-time.Hour*60), now.Add(time.Hour*30)))
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// A failed renewal should be retried at the next check.
+	for i := 0; i < 2; i++ {
+		if err = w.Check(ctx); err == nil {
+			t.Fatalf("unexpectedly renewed certificate")
+		}
+	}
+
+	if len(rec.names) != 2 {
+		t.Fatalf("got renewals %v, want two attempts", rec.names)
+	}
+}
+
+func TestWatcherCheckQuotaExceeded(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Now()
+	var rec = &renewRecorder{err: &hvclient.QuotaError{Remaining: 0, Err: errors.New("forbidden")}}
+
+	var w, err = watch.New(watch.Config{Renew: rec.renew, RenewFraction: 0.5})
+	if err != nil {
+		t.Fatalf("couldn't create watcher: %v", err)
+	}
+
+	for _, name := range []string{"a", "b", "c"} {
+		w.AddCertificate(name, testCert(name, now.Add(-time.Hour*60), now.Add(time.Hour*30)))
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err = w.Check(ctx); !errors.Is(err, hvclient.ErrQuotaExceeded) {
+		t.Fatalf("got error %v, want %v", err, hvclient.ErrQuotaExceeded)
+	}
+
+	// No further renewals should be attempted once the quota is exhausted.
+	if len(rec.names) != 1 || rec.names[0] != "a" {
+		t.Fatalf("got renewals %v, want only %q", rec.names, "a")
+	}
+}
+
+func TestWatcherCheckProgress(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Now()
+	var rec = &renewRecorder{err: &hvclient.QuotaError{Remaining: 0, Err: errors.New("forbidden")}}
+
+	type update struct {
+		completed, total int
+		failed           bool
+	}
+
+	var got []update
+	var progress = hvclient.ProgressFunc(func(completed, total int, err error) {
+		got = append(got, update{completed, total, err != nil})
+	})
+
+	var w, err = watch.New(watch.Config{Renew: rec.renew, RenewFraction: 0.5, Progress: progress})
+	if err != nil {
+		t.Fatalf("couldn't create watcher: %v", err)
+	}
+
+	w.AddCertificate("a", testCert("a", now.Add(-time.Hour), now.Add(time.Hour*30)))
+	w.AddCertificate("b", testCert("b", now.Add(-time.Hour*60), now.Add(time.Hour*30)))
+	w.AddCertificate("c", testCert("c", now.Add(-time.Hour*60), now.Add(time.Hour*30)))
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err = w.Check(ctx); !errors.Is(err, hvclient.ErrQuotaExceeded) {
+		t.Fatalf("got error %v, want %v", err, hvclient.ErrQuotaExceeded)
+	}
+
+	// Progress should be reported for the certificate which was not due
+	// and for the failed renewal, but not for the unchecked certificate.
+	var want = []update{{1, 3, false}, {2, 3, true}}
+
+	if len(got) != len(want) {
+		t.Fatalf("got progress %v, want %v", got, want)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got progress %v, want %v", got, want)
+		}
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Now()
+	var rec = &renewRecorder{replace: testCert("replacement", now, now.Add(time.Hour))}
+
+	var w, err = watch.New(watch.Config{Renew: rec.renew, Interval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("couldn't create watcher: %v", err)
+	}
+
+	w.AddCertificate("due", testCert("due", now.Add(-time.Hour), now))
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	if err = w.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	rec.mtx.Lock()
+	defer rec.mtx.Unlock()
+
+	if len(rec.names) != 1 {
+		t.Fatalf("got renewals %v, want one", rec.names)
+	}
+}
+
+// fakeRequester is a Requester which issues a fixed certificate.
+type fakeRequester struct {
+	cert *x509.Certificate
+	err  error
+}
+
+func (f *fakeRequester) CertificateRequest(context.Context, *hvclient.Request) (*big.Int, error) {
+	return big.NewInt(0x1234), f.err
+}
+
+func (f *fakeRequester) CertificateRetrieve(_ context.Context, serial *big.Int) (*hvclient.CertInfo, error) {
+	return &hvclient.CertInfo{X509: f.cert, Status: hvclient.StatusIssued}, nil
+}
+
+func TestClientRenewer(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Now()
+	var old = testCert("old", now.Add(-time.Hour), now)
+	var issued = testCert("issued", now, now.Add(time.Hour))
+
+	var build = func(name string, cert *x509.Certificate) (*hvclient.Request, error) {
+		return &hvclient.Request{Subject: &hvclient.DN{CommonName: name}}, nil
+	}
+
+	var stored []string
+	var store = func(name string, info *hvclient.CertInfo) error {
+		stored = append(stored, name)
+		return nil
+	}
+
+	var ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var got, err = watch.ClientRenewer(&fakeRequester{cert: issued}, build, store)(ctx, "www", old)
+	if err != nil {
+		t.Fatalf("couldn't renew certificate: %v", err)
+	}
+
+	if !got.Equal(issued) {
+		t.Errorf("got %v, want issued certificate", got)
+	}
+
+	if len(stored) != 1 || stored[0] != "www" {
+		t.Errorf("got stored %v, want [www]", stored)
+	}
+
+	var fail = &fakeRequester{err: errors.New("request failed")}
+	if _, err = watch.ClientRenewer(fail, build, store)(ctx, "www", old); err == nil {
+		t.Errorf("unexpectedly renewed certificate")
+	}
+}